@@ -2,24 +2,60 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/tls"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/aalobaidi/ggRMCP/pkg/anomaly"
+	"github.com/aalobaidi/ggRMCP/pkg/arguments"
+	"github.com/aalobaidi/ggRMCP/pkg/audit"
+	"github.com/aalobaidi/ggRMCP/pkg/auth"
+	"github.com/aalobaidi/ggRMCP/pkg/bench"
+	"github.com/aalobaidi/ggRMCP/pkg/breaker"
+	"github.com/aalobaidi/ggRMCP/pkg/catalog"
+	"github.com/aalobaidi/ggRMCP/pkg/chaos"
+	"github.com/aalobaidi/ggRMCP/pkg/composite"
 	appconfig "github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/contract"
+	"github.com/aalobaidi/ggRMCP/pkg/descriptors"
+	"github.com/aalobaidi/ggRMCP/pkg/examples"
+	"github.com/aalobaidi/ggRMCP/pkg/federation"
 	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"github.com/aalobaidi/ggRMCP/pkg/health"
+	"github.com/aalobaidi/ggRMCP/pkg/hedge"
+	"github.com/aalobaidi/ggRMCP/pkg/longrunning"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/mirror"
+	"github.com/aalobaidi/ggRMCP/pkg/mockserver"
+	"github.com/aalobaidi/ggRMCP/pkg/pagination"
+	"github.com/aalobaidi/ggRMCP/pkg/ratelimit"
+	"github.com/aalobaidi/ggRMCP/pkg/replay"
+	"github.com/aalobaidi/ggRMCP/pkg/routing"
+	"github.com/aalobaidi/ggRMCP/pkg/selftest"
 	"github.com/aalobaidi/ggRMCP/pkg/server"
 	"github.com/aalobaidi/ggRMCP/pkg/session"
 	"github.com/aalobaidi/ggRMCP/pkg/tools"
+	"github.com/aalobaidi/ggRMCP/pkg/transform"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// version is the gateway version reported in serverInfo and by the gateway
+// status tool, set at build time with -ldflags "-X main.version=..."
+// 网关版本，构建时通过 -ldflags 注入
+var version = "1.0.0"
+
 // Config holds application configuration
 type Config struct {
 	GRPCHost       string
@@ -28,6 +64,12 @@ type Config struct {
 	LogLevel       string
 	Development    bool
 	DescriptorPath string
+	RecordPath     string
+	ReplayPath     string
+	SnapshotPath   string
+	ExportSnapshot string
+	ConfigPath     string
+	Secure         bool
 }
 
 // parseFlags parses command line flags
@@ -40,14 +82,84 @@ func parseFlags() *Config {
 	flag.StringVar(&config.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
 	flag.BoolVar(&config.Development, "dev", false, "Enable development mode")
 	flag.StringVar(&config.DescriptorPath, "descriptor", "", "Path to protobuf descriptor file (optional)")
+	flag.StringVar(&config.RecordPath, "record", "", "Record tool calls to this file (JSON lines)")
+	flag.StringVar(&config.ReplayPath, "replay", "", "Serve recorded tool calls from this file instead of the upstream (requires --descriptor or --snapshot)")
+	flag.StringVar(&config.SnapshotPath, "snapshot", "", "Boot from a discovery snapshot instead of discovering services")
+	flag.StringVar(&config.ExportSnapshot, "export-snapshot", "", "Discover services, write a discovery snapshot to this file and exit")
+	flag.StringVar(&config.ConfigPath, "config", "", "Path to a YAML configuration file (flags that are set explicitly take precedence)")
+	flag.BoolVar(&config.Secure, "secure", false, "Apply the secure profile: require TLS and auth, validate origins, redact errors and logs, strict header filtering")
 
 	flag.Parse()
 
 	return config
 }
 
+// loadAppConfig builds the application configuration from the optional
+// config file and the command line flags. Without a config file every flag
+// (including its default) applies; with one, only explicitly set flags
+// override the file.
+func loadAppConfig(config *Config) (*appconfig.Config, error) {
+	cfg := appconfig.Default()
+	if config.ConfigPath != "" {
+		loaded, err := appconfig.ReadFile(config.ConfigPath)
+		if err != nil {
+			return nil, err
+		}
+		cfg = loaded
+	}
+
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	override := func(name string) bool {
+		return config.ConfigPath == "" || set[name]
+	}
+
+	if override("grpc-host") {
+		cfg.GRPC.Host = config.GRPCHost
+	}
+	if override("grpc-port") {
+		cfg.GRPC.Port = config.GRPCPort
+	}
+	if override("http-port") {
+		cfg.Server.Port = config.HTTPPort
+	}
+	if override("log-level") {
+		cfg.Logging.Level = config.LogLevel
+	}
+	if override("dev") {
+		cfg.Logging.Development = config.Development
+	}
+	if override("descriptor") {
+		cfg.GRPC.DescriptorSet.Enabled = config.DescriptorPath != ""
+		cfg.GRPC.DescriptorSet.Path = config.DescriptorPath
+	}
+
+	if override("snapshot") {
+		cfg.GRPC.SnapshotPath = config.SnapshotPath
+	}
+
+	switch {
+	case config.RecordPath != "" && config.ReplayPath != "":
+		return nil, fmt.Errorf("--record and --replay are mutually exclusive")
+	case config.ReplayPath != "":
+		cfg.Replay = appconfig.ReplayConfig{Mode: appconfig.ReplayModeReplay, Path: config.ReplayPath}
+	case config.RecordPath != "":
+		cfg.Replay = appconfig.ReplayConfig{Mode: appconfig.ReplayModeRecord, Path: config.RecordPath}
+	}
+
+	if config.Secure {
+		cfg.ApplySecureProfile()
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return cfg, nil
+}
+
 // setupLogger creates a configured logger
-func setupLogger(config *Config) (*zap.Logger, error) {
+func setupLogger(config appconfig.LoggingConfig) (*zap.Logger, error) {
 	var zapConfig zap.Config
 
 	if config.Development {
@@ -58,7 +170,7 @@ func setupLogger(config *Config) (*zap.Logger, error) {
 	}
 
 	// Set log level
-	switch config.LogLevel {
+	switch config.Level {
 	case "debug":
 		zapConfig.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
 	case "info":
@@ -74,12 +186,211 @@ func setupLogger(config *Config) (*zap.Logger, error) {
 	return zapConfig.Build()
 }
 
-// setupRouter creates the HTTP router with all routes
-func setupRouter(handler *server.Handler) *mux.Router {
+// upstreamOptions returns the discoverer options shared by all upstreams plus
+// the connection settings of one upstream
+func upstreamOptions(shared []grpc.DiscovererOption, conn appconfig.ConnectionConfig) []grpc.DiscovererOption {
+	opts := make([]grpc.DiscovererOption, 0, len(shared)+1)
+	opts = append(opts, shared...)
+	return append(opts, grpc.WithConnection(conn))
+}
+
+// upstreamTLSEnabled reports whether the primary upstream, the mirror or any
+// replica connects over TLS
+func upstreamTLSEnabled(config *appconfig.Config) bool {
+	if config.GRPC.TLS.Enabled || (config.Mirror.Enabled && config.Mirror.Connection.TLS.Enabled) {
+		return true
+	}
+	for _, replica := range config.Routing.Replicas {
+		if replica.Connection.TLS.Enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// warmUp prepares an upstream for its first tool call when warm-up is
+// enabled. Failures are only logged: a cold upstream still serves calls.
+func warmUp(discoverer grpc.ServiceDiscoverer, name string, cfg appconfig.WarmUpConfig, logger *zap.Logger) {
+	if !cfg.Enabled {
+		return
+	}
+	warmUpper, ok := grpc.Find[grpc.WarmUpper](discoverer)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	start := time.Now()
+	if err := warmUpper.WarmUp(ctx); err != nil {
+		logger.Warn("Upstream warm-up failed", zap.String("upstream", name), zap.Error(err))
+		return
+	}
+	logger.Info("Upstream warmed up", zap.String("upstream", name), zap.Duration("duration", time.Since(start)))
+}
+
+// setupRouting connects to the configured replicas and pools them with the
+// primary discoverer. Replicas that cannot be reached are left out of the pool;
+// when circuit breakers are enabled each replica gets its own upstream circuit.
+func setupRouting(ctx context.Context, primary grpc.ServiceDiscoverer, breakers *breaker.Breakers, dialOpts []grpc.DiscovererOption, config *appconfig.Config, logger *zap.Logger) grpc.ServiceDiscoverer {
+	backends := []*routing.Backend{{
+		Name:       fmt.Sprintf("%s:%d", config.GRPC.Host, config.GRPC.Port),
+		Discoverer: primary,
+	}}
+
+	for _, replica := range config.Routing.Replicas {
+		discoverer, err := grpc.NewServiceDiscoverer(replica.Host, replica.Port, logger.Named("replica"), config.GRPC.DescriptorSet,
+			upstreamOptions(dialOpts, config.GRPC.Connection(replica.Connection))...)
+		if err != nil {
+			logger.Warn("Failed to create replica discoverer", zap.String("replica", replica.Address()), zap.Error(err))
+			continue
+		}
+		if err := discoverer.Connect(ctx); err != nil {
+			logger.Warn("Failed to connect to replica", zap.String("replica", replica.Address()), zap.Error(err))
+			continue
+		}
+		if err := discoverer.DiscoverServices(ctx); err != nil {
+			logger.Warn("Failed to discover replica services", zap.String("replica", replica.Address()), zap.Error(err))
+			_ = discoverer.Close()
+			continue
+		}
+		warmUp(discoverer, replica.Address(), config.GRPC.WarmUp, logger)
+		var replicaDiscoverer grpc.ServiceDiscoverer = discoverer
+		if breakers != nil {
+			replicaDiscoverer = breakers.Wrap(discoverer, replica.Address())
+		}
+		backends = append(backends, &routing.Backend{Name: replica.Address(), Discoverer: replicaDiscoverer})
+	}
+
+	pool, err := routing.NewPool(backends, config.Routing.Sticky, logger)
+	if err != nil {
+		logger.Warn("Failed to create routing pool, using primary only", zap.Error(err))
+		return primary
+	}
+
+	if config.Routing.OutlierDetection.Enabled {
+		if err := pool.EnableOutlierDetection(config.Routing.OutlierDetection); err != nil {
+			logger.Fatal("Invalid outlier detection config", zap.Error(err))
+		}
+	}
+
+	logger.Info("Routing tool calls over replicas",
+		zap.Int("backends", len(backends)),
+		zap.Bool("sticky", config.Routing.Sticky),
+		zap.Bool("outlier_detection", config.Routing.OutlierDetection.Enabled))
+	return pool
+}
+
+// setupUpstreamRegistration wraps the discoverer so that upstreams can be
+// registered at runtime through POST /admin/upstreams. Registered upstreams
+// share the dial options of the primary, are discovered through reflection
+// and get their own upstream circuit when circuit breakers are enabled.
+func setupUpstreamRegistration(primary grpc.ServiceDiscoverer, breakers *breaker.Breakers, dialOpts []grpc.DiscovererOption, config *appconfig.Config, logger *zap.Logger) *federation.Federation {
+	connect := func(ctx context.Context, spec grpc.UpstreamSpec) (grpc.ServiceDiscoverer, error) {
+		conn := config.GRPC.Connection(appconfig.ConnectionConfig{})
+		if spec.TLS != nil {
+			conn.TLS = *spec.TLS
+		}
+		name := fmt.Sprintf("%s:%d", spec.Host, spec.Port)
+		discoverer, err := grpc.NewServiceDiscoverer(spec.Host, spec.Port, logger.Named("upstream"), appconfig.DescriptorSetConfig{},
+			upstreamOptions(dialOpts, conn)...)
+		if err != nil {
+			return nil, err
+		}
+		if err := discoverer.Connect(ctx); err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", name, err)
+		}
+		if err := discoverer.DiscoverServices(ctx); err != nil {
+			_ = discoverer.Close()
+			return nil, fmt.Errorf("failed to discover services of %s: %w", name, err)
+		}
+		warmUp(discoverer, name, config.GRPC.WarmUp, logger)
+		if breakers != nil {
+			return breakers.Wrap(discoverer, name), nil
+		}
+		return discoverer, nil
+	}
+
+	logger.Info("Runtime upstream registration enabled",
+		zap.Int("max_upstreams", config.UpstreamRegistration.MaxUpstreams))
+	return federation.New(primary, connect, config.UpstreamRegistration.MaxUpstreams, config.UpstreamRegistration.DrainTimeout, logger)
+}
+
+// setupMirror connects to the secondary upstream and wraps the discoverer
+// with a shadow traffic mirror. Mirroring is best effort: if the secondary is
+// unavailable the primary discoverer is returned unchanged.
+func setupMirror(ctx context.Context, primary grpc.ServiceDiscoverer, dialOpts []grpc.DiscovererOption, config *appconfig.Config, logger *zap.Logger) grpc.ServiceDiscoverer {
+	secondary, err := grpc.NewServiceDiscoverer(config.Mirror.Host, config.Mirror.Port, logger.Named("mirror"), config.GRPC.DescriptorSet,
+		upstreamOptions(dialOpts, config.GRPC.Connection(config.Mirror.Connection))...)
+	if err != nil {
+		logger.Warn("Failed to create mirror discoverer, mirroring disabled", zap.Error(err))
+		return primary
+	}
+
+	if err := secondary.Connect(ctx); err != nil {
+		logger.Warn("Failed to connect to mirror upstream, mirroring disabled", zap.Error(err))
+		return primary
+	}
+
+	if err := secondary.DiscoverServices(ctx); err != nil {
+		logger.Warn("Failed to discover mirror services, mirroring disabled", zap.Error(err))
+		_ = secondary.Close()
+		return primary
+	}
+	warmUp(secondary, fmt.Sprintf("%s:%d", config.Mirror.Host, config.Mirror.Port), config.GRPC.WarmUp, logger)
+
+	m, err := mirror.New(primary, secondary, config.Mirror, logger)
+	if err != nil {
+		logger.Warn("Failed to create mirror, mirroring disabled", zap.Error(err))
+		_ = secondary.Close()
+		return primary
+	}
+
+	logger.Info("Mirroring tool calls",
+		zap.String("target", fmt.Sprintf("%s:%d", config.Mirror.Host, config.Mirror.Port)),
+		zap.Float64("percent", config.Mirror.Percent))
+	return m
+}
+
+// loadSnapshot boots the discoverer from the snapshot file at path
+func loadSnapshot(sd grpc.ServiceDiscoverer, path string) error {
+	snapshotter, ok := grpc.Find[grpc.Snapshotter](sd)
+	if !ok {
+		return fmt.Errorf("discoverer does not support snapshots")
+	}
+	snapshot, err := grpc.ReadSnapshot(path)
+	if err != nil {
+		return err
+	}
+	return snapshotter.LoadSnapshot(snapshot)
+}
+
+// exportSnapshot writes a snapshot of the discovered services to path
+func exportSnapshot(sd grpc.ServiceDiscoverer, path string) error {
+	snapshotter, ok := grpc.Find[grpc.Snapshotter](sd)
+	if !ok {
+		return fmt.Errorf("discoverer does not support snapshots")
+	}
+	snapshot, err := snapshotter.Snapshot()
+	if err != nil {
+		return err
+	}
+	return grpc.WriteSnapshot(path, snapshot)
+}
+
+// setupRouter creates the HTTP router with all routes; adminAuth protects
+// the /admin endpoints
+func setupRouter(handler *server.Handler, adminAuth server.Middleware) *mux.Router {
 	router := mux.NewRouter()
 
 	// Main MCP endpoint
-	router.HandleFunc("/", handler.ServeHTTP).Methods("GET", "POST", "OPTIONS")
+	router.HandleFunc("/", handler.ServeHTTP).Methods("GET", "POST", "DELETE", "OPTIONS")
+
+	// Legacy HTTP+SSE transport (protocol version 2024-11-05)
+	router.HandleFunc("/sse", handler.LegacySSEHandler).Methods("GET")
+	router.HandleFunc("/messages", handler.LegacyMessagesHandler).Methods("POST")
+
+	// Client-streaming and bidirectional methods bridged over HTTP streams
+	router.PathPrefix("/streams").HandlerFunc(handler.StreamsHandler)
 
 	// Health check endpoint
 	router.HandleFunc("/health", handler.HealthHandler).Methods("GET", "HEAD")
@@ -87,6 +398,31 @@ func setupRouter(handler *server.Handler) *mux.Router {
 	// Metrics endpoint
 	router.HandleFunc("/metrics", handler.MetricsHandler).Methods("GET")
 
+	// Upstream connection introspection (channelz)
+	router.Handle("/admin/channelz", adminAuth(http.HandlerFunc(handler.ChannelzHandler))).Methods("GET")
+
+	// Discovery report with skipped services
+	router.Handle("/admin/discovery", adminAuth(http.HandlerFunc(handler.DiscoveryReportHandler))).Methods("GET")
+
+	// Discovery snapshot export
+	router.Handle("/admin/snapshot", adminAuth(http.HandlerFunc(handler.SnapshotHandler))).Methods("GET")
+
+	// Blue/green upstream switch
+	router.Handle("/admin/upstream/switch", adminAuth(http.HandlerFunc(handler.SwitchUpstreamHandler))).Methods("POST")
+
+	// Runtime upstream registration
+	router.Handle("/admin/upstreams", adminAuth(http.HandlerFunc(handler.UpstreamsHandler))).Methods("GET", "POST")
+	router.Handle("/admin/upstreams/{name}", adminAuth(http.HandlerFunc(handler.RemoveUpstreamHandler))).Methods("DELETE")
+
+	// Per-tool usage statistics
+	router.Handle("/admin/tools/usage", adminAuth(http.HandlerFunc(handler.ToolUsageHandler))).Methods("GET")
+
+	// Example arguments generated from tool schemas
+	router.Handle("/admin/tools/example", adminAuth(http.HandlerFunc(handler.ToolExampleHandler))).Methods("GET")
+
+	// Header filter dry run
+	router.Handle("/admin/headers/dry-run", adminAuth(http.HandlerFunc(handler.HeaderFilterDryRunHandler))).Methods("POST")
+
 	return router
 }
 
@@ -111,12 +447,626 @@ func gracefulShutdown(server *http.Server, logger *zap.Logger) {
 	logger.Info("Server exited")
 }
 
+// validateDescriptor implements "grmcp validate-descriptor file.binpb": it
+// reports problems in a FileDescriptorSet and returns the process exit code
+func validateDescriptor(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: grmcp validate-descriptor file.binpb")
+		return 2
+	}
+
+	fdSet, err := descriptors.NewLoader(zap.NewNop()).LoadFromFile(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	issues := descriptors.ValidateDescriptorSet(fdSet)
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if descriptors.HasErrors(issues) {
+		return 1
+	}
+	fmt.Printf("%s: %d files OK", args[0], len(fdSet.File))
+	if len(issues) > 0 {
+		fmt.Printf(" (%d warnings)", len(issues))
+	}
+	fmt.Println()
+	return 0
+}
+
+// verifyAudit implements "grmcp verify-audit file.jsonl [public-key.pem]": it
+// checks the hash chain (and signatures, given the public key) of an audit log
+// and returns the process exit code
+func verifyAudit(args []string) int {
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Fprintln(os.Stderr, "usage: grmcp verify-audit file.jsonl [public-key.pem]")
+		return 2
+	}
+
+	var publicKey ed25519.PublicKey
+	if len(args) == 2 {
+		key, err := audit.LoadPublicKey(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		publicKey = key
+	}
+
+	count, err := audit.Verify(args[0], publicKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", args[0], err)
+		return 1
+	}
+	fmt.Printf("%s: %d records OK", args[0], count)
+	if publicKey != nil {
+		fmt.Print(" (signatures verified)")
+	}
+	fmt.Println()
+	return 0
+}
+
+// upstreamFlags are the flags of the subcommands that discover an upstream
+// without starting the gateway
+type upstreamFlags struct {
+	configPath string
+	host       string
+	port       int
+	descriptor string
+	timeout    time.Duration
+
+	// offline discovers from the descriptor set without connecting, for
+	// commands that never call the upstream
+	offline bool
+}
+
+// addUpstreamFlags registers the upstream flags on fs
+func addUpstreamFlags(fs *flag.FlagSet) *upstreamFlags {
+	f := &upstreamFlags{}
+	fs.StringVar(&f.configPath, "config", "", "Path to a YAML configuration file")
+	fs.StringVar(&f.host, "grpc-host", "", "gRPC server host (overrides the config)")
+	fs.IntVar(&f.port, "grpc-port", 0, "gRPC server port (overrides the config)")
+	fs.StringVar(&f.descriptor, "descriptor", "", "Path to protobuf descriptor file (overrides the config)")
+	fs.DurationVar(&f.timeout, "timeout", 30*time.Second, "Time allowed for connecting and discovery")
+	return f
+}
+
+// discover loads the configuration, connects to the upstream and discovers
+// its services. The caller closes the returned discoverer.
+func (f *upstreamFlags) discover() (*appconfig.Config, grpc.ServiceDiscoverer, error) {
+	cfg := appconfig.Default()
+	if f.configPath != "" {
+		loaded, err := appconfig.ReadFile(f.configPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg = loaded
+	}
+	if f.host != "" {
+		cfg.GRPC.Host = f.host
+	}
+	if f.port != 0 {
+		cfg.GRPC.Port = f.port
+	}
+	if f.descriptor != "" {
+		cfg.GRPC.DescriptorSet.Enabled = true
+		cfg.GRPC.DescriptorSet.Path = f.descriptor
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	var opts []grpc.DiscovererOption
+	if cfg.Tools.ServerStreaming.Enabled {
+		opts = append(opts, grpc.WithServerStreaming(cfg.Tools.ServerStreaming))
+	}
+	discoverer, err := grpc.NewServiceDiscoverer(cfg.GRPC.Host, cfg.GRPC.Port, zap.NewNop(), cfg.GRPC.DescriptorSet,
+		upstreamOptions(opts, cfg.GRPC.Connection(appconfig.ConnectionConfig{}))...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), f.timeout)
+	defer cancel()
+	if f.offline {
+		if !cfg.GRPC.DescriptorSet.Enabled {
+			_ = discoverer.Close()
+			return nil, nil, fmt.Errorf("a descriptor set is required without an upstream connection")
+		}
+	} else if err := discoverer.Connect(ctx); err != nil {
+		_ = discoverer.Close()
+		return nil, nil, fmt.Errorf("failed to connect to %s:%d: %w", cfg.GRPC.Host, cfg.GRPC.Port, err)
+	}
+	if err := discoverer.DiscoverServices(ctx); err != nil {
+		_ = discoverer.Close()
+		return nil, nil, fmt.Errorf("failed to discover services: %w", err)
+	}
+	return cfg, discoverer, nil
+}
+
+// selfTest implements "grmcp selftest [flags]": it connects to the upstream,
+// discovers its services, builds and checks every tool and returns the
+// process exit code, so CI can gate backend proto changes on it
+func selfTest(args []string) int {
+	fs := flag.NewFlagSet("selftest", flag.ContinueOnError)
+	upstream := addUpstreamFlags(fs)
+	roundTrip := fs.Bool("round-trip", false, "Dry-run a protojson round trip of a sample input generated for every tool")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, discoverer, err := upstream.discover()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	defer func() { _ = discoverer.Close() }()
+
+	builder := newToolBuilder(zap.NewNop(), cfg)
+	report := selftest.Run(discoverer.GetMethods(), builder, selftest.Options{
+		RoundTrip:       *roundTrip,
+		ServerStreaming: cfg.Tools.ServerStreaming.Enabled,
+	})
+	for _, issue := range report.Issues {
+		fmt.Println(issue)
+	}
+	if report.HasErrors() {
+		return 1
+	}
+	fmt.Printf("%s:%d: %d methods, %d tools OK", cfg.GRPC.Host, cfg.GRPC.Port, report.Methods, report.Tools)
+	if len(report.Issues) > 0 {
+		fmt.Printf(" (%d warnings)", len(report.Issues))
+	}
+	fmt.Println()
+	return 0
+}
+
+// catalogDiff implements "grmcp catalog-diff [flags] golden.json": it
+// compares the tool catalog built from the upstream against a golden file and
+// returns exit code 1 when a change breaks existing callers. With --update
+// the golden file is rewritten instead.
+func catalogDiff(args []string) int {
+	fs := flag.NewFlagSet("catalog-diff", flag.ContinueOnError)
+	upstream := addUpstreamFlags(fs)
+	update := fs.Bool("update", false, "Write the current catalog to the golden file instead of comparing")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: grmcp catalog-diff [flags] golden.json")
+		return 2
+	}
+	goldenPath := fs.Arg(0)
+
+	cfg, discoverer, err := upstream.discover()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	defer func() { _ = discoverer.Close() }()
+
+	builder := newToolBuilder(zap.NewNop(), cfg)
+	current, err := catalog.Build(discoverer.GetMethods(), builder)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	if *update {
+		if err := catalog.Write(goldenPath, current); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("%s: wrote %d tools\n", goldenPath, len(current))
+		return 0
+	}
+
+	golden, err := catalog.Load(goldenPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	changes, err := catalog.Diff(golden, current)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+	if catalog.HasBreaking(changes) {
+		return 1
+	}
+	fmt.Printf("%s: %d tools, no breaking changes", goldenPath, len(current))
+	if len(changes) > 0 {
+		fmt.Printf(" (%d compatible changes)", len(changes))
+	}
+	fmt.Println()
+	return 0
+}
+
+// contractTest implements "grmcp test [flags] fixtures.yaml...": it runs the
+// tool calls in the fixture files against the upstream, or against a replay
+// recording with --replay, and returns exit code 1 when a call does not
+// match its expected status and fields
+func contractTest(args []string) int {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	upstream := addUpstreamFlags(fs)
+	replayPath := fs.String("replay", "", "Serve the calls from this recording instead of the upstream (requires --descriptor)")
+	callTimeout := fs.Duration("call-timeout", 30*time.Second, "Time allowed for each tool call")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: grmcp test [flags] fixtures.yaml...")
+		return 2
+	}
+
+	var fixtures []contract.Fixture
+	for _, path := range fs.Args() {
+		loaded, err := contract.Load(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		fixtures = append(fixtures, loaded...)
+	}
+
+	upstream.offline = *replayPath != ""
+	_, discoverer, err := upstream.discover()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	defer func() { _ = discoverer.Close() }()
+	if *replayPath != "" {
+		player, err := replay.NewPlayer(discoverer, *replayPath, zap.NewNop())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		discoverer = player
+	}
+
+	results := contract.Run(context.Background(), discoverer, fixtures, *callTimeout)
+	failed := 0
+	for _, result := range results {
+		fmt.Println(result)
+		if !result.Passed() {
+			failed++
+		}
+	}
+	fmt.Printf("%d fixtures, %d passed, %d failed\n", len(results), len(results)-failed, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// mockUpstream implements "grmcp mock [flags] file.binpb": it serves fake
+// implementations of the services in a descriptor set, answering every call
+// with a sample response, so MCP clients can be developed without a backend.
+// Point the gateway at it like at any other upstream.
+func mockUpstream(args []string) int {
+	fs := flag.NewFlagSet("mock", flag.ContinueOnError)
+	listen := fs.String("listen", "localhost:50051", "Address to serve the mock services on")
+	verbose := fs.Bool("verbose", false, "Log every call")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: grmcp mock [flags] file.binpb")
+		return 2
+	}
+
+	logger := zap.NewNop()
+	if *verbose {
+		var err error
+		if logger, err = setupLogger(appconfig.LoggingConfig{Level: "debug", Development: true}); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+	}
+	loader := descriptors.NewLoader(logger)
+	fdSet, err := loader.LoadFromFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	files, err := loader.BuildRegistry(fdSet)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	mock, err := mockserver.New(files, logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	lis, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		mock.Stop()
+	}()
+	fmt.Printf("Serving %d mock services on %s: %s\n", len(mock.Services()), lis.Addr(), strings.Join(mock.Services(), ", "))
+	if err := mock.Serve(lis); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// toolExamples implements "grmcp example [flags] [tool...]": it prints
+// example arguments generated from the input schema of the named tools, or of
+// every tool, as a JSON object keyed by tool name
+func toolExamples(args []string) int {
+	fs := flag.NewFlagSet("example", flag.ContinueOnError)
+	upstream := addUpstreamFlags(fs)
+	requiredOnly := fs.Bool("required-only", false, "Only generate required arguments")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, discoverer, err := upstream.discover()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	defer func() { _ = discoverer.Close() }()
+
+	toolList, err := newToolBuilder(zap.NewNop(), cfg).BuildTools(discoverer.GetMethods())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	schemas := make(map[string]interface{}, len(toolList))
+	for _, tool := range toolList {
+		schemas[tool.Name] = tool.InputSchema
+	}
+	names := fs.Args()
+	if len(names) == 0 {
+		for name := range schemas {
+			names = append(names, name)
+		}
+	}
+
+	result := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		schema, ok := schemas[name]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "error: tool %s not found\n", name)
+			return 1
+		}
+		result[name] = examples.Arguments(schema, examples.Options{RequiredOnly: *requiredOnly})
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	fmt.Println(string(data))
+	return 0
+}
+
+// configHandlerOptions returns the handler options taken directly from the
+// configuration, shared by the gateway and "grmcp bench"
+func configHandlerOptions(config *appconfig.Config) []server.HandlerOption {
+	return []server.HandlerOption{
+		server.WithSessionMetadata(config.Session.Metadata),
+		server.WithMaxConcurrentCalls(config.Session.MaxConcurrentCalls),
+		server.WithSessionHistory(config.Session.History),
+		server.WithBackpressure(config.Server.Backpressure),
+		server.WithBatch(config.Server.Batch),
+		server.WithUpstreamSwitch(config.UpstreamSwitch),
+		server.WithToolAliases(config.Tools.Aliases),
+		server.WithToolGrouping(config.Tools.Grouping),
+		server.WithToolOutput(config.Tools.Output),
+		server.WithToolInputLimits(config.Tools.InputLimits),
+		server.WithToolFlattening(config.Tools.Flattening),
+		server.WithDescribeTool(config.Tools.DescribeTool.Enabled),
+		server.WithIntrospectionTools(config.Tools.Introspection.Enabled),
+		server.WithArgumentCoercion(config.Tools.Coercion),
+		server.WithEnumNormalization(config.Tools.Enums),
+		server.WithResultSummary(config.Tools.Summary),
+		server.WithVersion(version),
+		server.WithToolHints(config.Tools.Hints),
+		server.WithToolUsage(config.Tools.Usage),
+		server.WithErrorDetail(mcp.ErrorDetail(config.MCP.ErrorDetail)),
+		server.WithLogRedaction(config.Logging.Redact),
+		server.WithBaggage(config.GRPC.Baggage),
+		server.WithBidiStreaming(config.Tools.BidiStreaming),
+	}
+}
+
+// benchmark implements "grmcp bench --tool X --rps N [flags]": it serves the
+// gateway handler in process, calls the tool at the given rate through the
+// full middleware and handler stack and prints latency percentiles and error
+// rates. Without --arguments the calls use example arguments generated from
+// the tool's input schema.
+func benchmark(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	upstream := addUpstreamFlags(fs)
+	tool := fs.String("tool", "", "Tool to call (required)")
+	rps := fs.Int("rps", 10, "Calls started per second")
+	duration := fs.Duration("duration", 30*time.Second, "Length of the run")
+	concurrency := fs.Int("concurrency", 100, "Maximum calls in flight; calls due beyond it are dropped")
+	argumentsJSON := fs.String("arguments", "", "Arguments of every call as a JSON object")
+	jsonOutput := fs.Bool("json", false, "Print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *tool == "" || *rps <= 0 || *duration <= 0 || *concurrency <= 0 || fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "usage: grmcp bench --tool name [--rps 10] [--duration 30s] [flags]")
+		return 2
+	}
+
+	cfg, discoverer, err := upstream.discover()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	defer func() { _ = discoverer.Close() }()
+
+	logger := zap.NewNop()
+	argumentInjector, err := arguments.NewInjector(cfg.Tools.Arguments)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	argumentSanitizer, err := arguments.NewSanitizer(cfg.Tools.Sanitizers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	sessionManager := session.NewManager(logger)
+	defer func() { _ = sessionManager.Close() }()
+	builder := newToolBuilder(logger, cfg)
+	handlerOpts := append([]server.HandlerOption{
+		server.WithArgumentInjector(argumentInjector),
+		server.WithArgumentSanitizers(argumentSanitizer),
+	}, configHandlerOptions(cfg)...)
+	handler := server.NewHandler(logger, discoverer, sessionManager, builder, cfg.GRPC.HeaderForwarding, handlerOpts...)
+	adminAuth, err := server.AdminAuthMiddleware(cfg.Server.Admin, logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	stack := server.ChainMiddleware(server.DefaultMiddleware(logger)...)(setupRouter(handler, adminAuth))
+
+	var callArgs map[string]interface{}
+	if *argumentsJSON != "" {
+		if err := json.Unmarshal([]byte(*argumentsJSON), &callArgs); err != nil {
+			fmt.Fprintf(os.Stderr, "error: invalid --arguments: %v\n", err)
+			return 2
+		}
+	} else {
+		toolList, err := builder.BuildTools(discoverer.GetMethods())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		for _, candidate := range toolList {
+			if candidate.Name == *tool {
+				callArgs = examples.Arguments(candidate.InputSchema, examples.Options{})
+				break
+			}
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	report, err := bench.Run(ctx, stack, bench.Options{
+		Tool:        *tool,
+		Arguments:   callArgs,
+		RPS:         *rps,
+		Duration:    *duration,
+		Concurrency: *concurrency,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if *jsonOutput {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+	} else {
+		fmt.Println(report)
+	}
+	return 0
+}
+
+// newToolBuilder creates the tool builder configured by the tools section
+// 按 tools 配置创建工具构建器
+func newToolBuilder(logger *zap.Logger, cfg *appconfig.Config) *tools.MCPToolBuilder {
+	builder := tools.NewMCPToolBuilder(logger)
+	builder.SetServerStreaming(cfg.Tools.ServerStreaming.Enabled)
+	builder.SetCommentFormat(descriptors.CommentFormat{
+		Normalize: cfg.Tools.Comments.Normalize,
+		Detached:  cfg.Tools.Comments.Detached,
+		MaxLength: cfg.Tools.Comments.MaxLength,
+	})
+	builder.SetConstraintSummary(cfg.Tools.Constraints.Summary)
+	return builder
+}
+
+// watchReload re-reads the configuration on SIGHUP and atomically swaps in
+// the header forwarding and argument sanitizer rules. Other settings need a
+// restart. An invalid configuration leaves the current rules in effect.
+func watchReload(flags *Config, handler *server.Handler, sanitizer *arguments.RuleSanitizer, logger *zap.Logger) {
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			cfg, err := loadAppConfig(flags)
+			if err != nil {
+				logger.Error("Failed to reload configuration, keeping current rules", zap.Error(err))
+				continue
+			}
+			if err := sanitizer.Update(cfg.Tools.Sanitizers); err != nil {
+				logger.Error("Invalid sanitizer rules, keeping current rules", zap.Error(err))
+				continue
+			}
+			handler.UpdateHeaderFilter(cfg.GRPC.HeaderForwarding)
+			logger.Info("Reloaded header forwarding and sanitizer rules",
+				zap.Strings("allowedHeaders", cfg.GRPC.HeaderForwarding.AllowedHeaders),
+				zap.Strings("blockedHeaders", cfg.GRPC.HeaderForwarding.BlockedHeaders),
+				zap.Int("sanitizers", len(cfg.Tools.Sanitizers)))
+		}
+	}()
+}
+
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "validate-descriptor" {
+		os.Exit(validateDescriptor(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "verify-audit" {
+		os.Exit(verifyAudit(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "selftest" {
+		os.Exit(selfTest(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "catalog-diff" {
+		os.Exit(catalogDiff(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "test" {
+		os.Exit(contractTest(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "mock" {
+		os.Exit(mockUpstream(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "example" {
+		os.Exit(toolExamples(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		os.Exit(benchmark(os.Args[2:]))
+	}
+
 	// Parse command line flags
-	config := parseFlags()
+	flags := parseFlags()
+
+	// Load configuration (config file + flags)
+	config, err := loadAppConfig(flags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Setup logger
-	logger, err := setupLogger(config)
+	logger, err := setupLogger(config.Logging)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to setup logger: %v\n", err)
 		os.Exit(1)
@@ -128,39 +1078,68 @@ func main() {
 	}()
 
 	logger.Info("Starting GrMCP Gateway",
-		zap.String("grpc_host", config.GRPCHost),
-		zap.Int("grpc_port", config.GRPCPort),
-		zap.Int("http_port", config.HTTPPort),
-		zap.String("log_level", config.LogLevel),
-		zap.Bool("development", config.Development))
+		zap.String("grpc_host", config.GRPC.Host),
+		zap.Int("grpc_port", config.GRPC.Port),
+		zap.Int("http_port", config.Server.Port),
+		zap.String("log_level", config.Logging.Level),
+		zap.Bool("development", config.Logging.Development))
 
 	// Create service discoverer with FileDescriptorSet support
 	// 创建服务发现器，支持FileDescriptorSet
-	descriptorConfig := appconfig.DescriptorSetConfig{
-		Enabled:              config.DescriptorPath != "",
-		Path:                 config.DescriptorPath,
-		PreferOverReflection: false, // Use reflection as primary, descriptor as enhancement
-		IncludeSourceInfo:    true,
+	descriptorConfig := config.GRPC.DescriptorSet
+
+	// Resolve upstream targets through the configured resolver
+	// 通过配置的解析器解析上游目标（主上游、副本和镜像共用）
+	resolver, err := grpc.ResolverFromConfig(config.GRPC.Resolver)
+	if err != nil {
+		logger.Fatal("Invalid resolver config", zap.Error(err))
+	}
+	var dialOpts []grpc.DiscovererOption
+	if resolver != nil {
+		dialOpts = append(dialOpts, grpc.WithResolver(resolver, config.GRPC.Resolver.RefreshInterval))
+		logger.Info("Resolving upstream targets",
+			zap.String("resolver", config.GRPC.Resolver.Name),
+			zap.Duration("refresh_interval", config.GRPC.Resolver.RefreshInterval))
+	}
+	if len(config.Tools.ReadyModes) > 0 {
+		dialOpts = append(dialOpts, grpc.WithToolReadyModes(config.Tools.ReadyModes))
+	}
+	if config.Tools.ServerStreaming.Enabled {
+		dialOpts = append(dialOpts, grpc.WithServerStreaming(config.Tools.ServerStreaming))
+	}
+	if config.Tools.BidiStreaming.Enabled {
+		dialOpts = append(dialOpts, grpc.WithBidiStreaming(config.Tools.BidiStreaming))
+	}
+	// Track the certificates of TLS upstreams to warn before they expire
+	var certMonitor *grpc.CertificateMonitor
+	if upstreamTLSEnabled(config) {
+		certMonitor = grpc.NewCertificateMonitor(config.GRPC.CertExpiryWarning, logger)
+		dialOpts = append(dialOpts, grpc.WithCertificateMonitor(certMonitor))
 	}
 
 	// 创建服务发现器
 	serviceDiscoverer, err := grpc.NewServiceDiscoverer(
-		config.GRPCHost,
-		config.GRPCPort,
+		config.GRPC.Host,
+		config.GRPC.Port,
 		logger,
 		descriptorConfig,
+		upstreamOptions(dialOpts, config.GRPC.Connection(appconfig.ConnectionConfig{}))...,
 	)
 	if err != nil {
 		logger.Fatal("Failed to create service discoverer", zap.Error(err))
 	}
 
+	replayCfg := config.Replay
+
 	// Connect to gRPC server
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// 连接到gRPC服务器
-	if err := serviceDiscoverer.Connect(ctx); err != nil {
-		logger.Fatal("Failed to connect to gRPC server", zap.Error(err))
+	// 连接到gRPC服务器（回放模式下不连接上游）
+	if replayCfg.Mode != appconfig.ReplayModeReplay {
+		if err := serviceDiscoverer.Connect(ctx); err != nil {
+			logger.Fatal("Failed to connect to gRPC server", zap.Error(err))
+		}
 	}
 	defer func() {
 		if err := serviceDiscoverer.Close(); err != nil {
@@ -170,10 +1149,173 @@ func main() {
 
 	// Discover services (will use FileDescriptorSet if available, fallback to reflection)
 	// 发现服务（如果可用，将使用FileDescriptorSet，否则回退到反射）
-	if err := serviceDiscoverer.DiscoverServices(ctx); err != nil {
+	// 配置了快照时直接从快照加载；后台发现时网关立即启动，工具随发现进度逐批可用
+	if config.GRPC.SnapshotPath != "" {
+		if err := loadSnapshot(serviceDiscoverer, config.GRPC.SnapshotPath); err != nil {
+			logger.Fatal("Failed to boot from snapshot", zap.Error(err))
+		}
+	} else if config.GRPC.BackgroundDiscovery && replayCfg.Mode != appconfig.ReplayModeReplay {
+		discoveryCtx, stopDiscovery := context.WithCancel(context.Background())
+		defer stopDiscovery()
+		go func(discoverer grpc.ServiceDiscoverer) {
+			start := time.Now()
+			if err := discoverer.DiscoverServices(discoveryCtx); err != nil {
+				logger.Error("Background service discovery failed", zap.Error(err))
+				return
+			}
+			logger.Info("Background service discovery completed",
+				zap.Int("methodCount", discoverer.GetMethodCount()),
+				zap.Duration("duration", time.Since(start)))
+			warmUp(discoverer, fmt.Sprintf("%s:%d", config.GRPC.Host, config.GRPC.Port), config.GRPC.WarmUp, logger)
+		}(serviceDiscoverer)
+		logger.Info("Discovering services in the background")
+	} else if err := serviceDiscoverer.DiscoverServices(ctx); err != nil {
 		logger.Fatal("Failed to discover services", zap.Error(err))
 	}
 
+	// Warm up the primary upstream once its services are known
+	// 服务发现完成后预热主上游（后台发现时在发现完成后进行，回放模式下没有上游）
+	if !config.GRPC.BackgroundDiscovery && replayCfg.Mode != appconfig.ReplayModeReplay && flags.ExportSnapshot == "" {
+		warmUp(serviceDiscoverer, fmt.Sprintf("%s:%d", config.GRPC.Host, config.GRPC.Port), config.GRPC.WarmUp, logger)
+	}
+
+	// Write a discovery snapshot and exit when requested
+	// 指定 --export-snapshot 时写出服务发现快照后退出
+	if flags.ExportSnapshot != "" {
+		if err := exportSnapshot(serviceDiscoverer, flags.ExportSnapshot); err != nil {
+			logger.Fatal("Failed to export snapshot", zap.Error(err))
+		}
+		logger.Info("Discovery snapshot written", zap.String("path", flags.ExportSnapshot))
+		return
+	}
+
+	// Guard each upstream with circuit breakers when enabled
+	// 启用时为每个上游及其工具添加熔断器（回放模式下没有上游）
+	var breakers *breaker.Breakers
+	if config.CircuitBreaker.Enabled && replayCfg.Mode != appconfig.ReplayModeReplay {
+		breakers, err = breaker.New(config.CircuitBreaker, logger)
+		if err != nil {
+			logger.Fatal("Invalid circuit breaker config", zap.Error(err))
+		}
+		serviceDiscoverer = breakers.Wrap(serviceDiscoverer, fmt.Sprintf("%s:%d", config.GRPC.Host, config.GRPC.Port))
+		logger.Info("Circuit breakers enabled",
+			zap.Duration("window", config.CircuitBreaker.Window),
+			zap.Float64("failure_percent", config.CircuitBreaker.FailurePercent),
+			zap.Duration("cooldown", config.CircuitBreaker.Cooldown))
+	}
+
+	// Route calls over replicas of the primary upstream when configured
+	// 配置了副本时，在主上游及其副本之间路由调用
+	if len(config.Routing.Replicas) > 0 && replayCfg.Mode != appconfig.ReplayModeReplay {
+		serviceDiscoverer = setupRouting(ctx, serviceDiscoverer, breakers, dialOpts, config, logger)
+	}
+
+	// Hedge slow calls of idempotent tools when enabled
+	// 启用时对幂等工具的慢调用发送对冲请求（回放模式下没有上游）
+	if config.Hedging.Enabled && replayCfg.Mode != appconfig.ReplayModeReplay {
+		serviceDiscoverer = hedge.New(serviceDiscoverer, config.Hedging, logger)
+		logger.Info("Request hedging enabled",
+			zap.Duration("delay", config.Hedging.Delay),
+			zap.Int("max_attempts", config.Hedging.MaxAttempts),
+			zap.Float64("budget_percent", config.Hedging.BudgetPercent),
+			zap.Strings("exclude_tools", config.Hedging.ExcludeTools))
+	}
+
+	// Serve upstreams registered at runtime when enabled
+	// 启用时允许在运行时注册新上游并合并其工具（回放模式下没有上游）
+	if config.UpstreamRegistration.Enabled && replayCfg.Mode != appconfig.ReplayModeReplay {
+		serviceDiscoverer = setupUpstreamRegistration(serviceDiscoverer, breakers, dialOpts, config, logger)
+	}
+
+	// Wrap the discoverer for record/replay mode
+	// 根据录制/回放模式包装服务发现器
+	switch replayCfg.Mode {
+	case appconfig.ReplayModeRecord:
+		recorder, err := replay.NewRecorder(serviceDiscoverer, replayCfg.Path, logger)
+		if err != nil {
+			logger.Fatal("Failed to create recorder", zap.Error(err))
+		}
+		serviceDiscoverer = recorder
+		logger.Info("Recording tool calls", zap.String("path", replayCfg.Path))
+	case appconfig.ReplayModeReplay:
+		player, err := replay.NewPlayer(serviceDiscoverer, replayCfg.Path, logger)
+		if err != nil {
+			logger.Fatal("Failed to load recording", zap.Error(err))
+		}
+		serviceDiscoverer = player
+		logger.Info("Replaying recorded tool calls", zap.String("path", replayCfg.Path))
+	}
+
+	// Mirror tool calls to a secondary upstream when enabled
+	// 启用时将工具调用镜像到备用上游
+	if config.Mirror.Enabled {
+		serviceDiscoverer = setupMirror(ctx, serviceDiscoverer, dialOpts, config, logger)
+	}
+
+	// Wrap the discoverer with fault injection when enabled
+	// 启用时为服务发现器添加故障注入
+	if config.Chaos.Enabled {
+		injector, err := chaos.NewInjector(serviceDiscoverer, config.Chaos, logger)
+		if err != nil {
+			logger.Fatal("Failed to create fault injector", zap.Error(err))
+		}
+		serviceDiscoverer = injector
+		logger.Warn("Fault injection enabled",
+			zap.Float64("delay_percent", config.Chaos.DelayPercent),
+			zap.Float64("error_percent", config.Chaos.ErrorPercent),
+			zap.Float64("truncate_percent", config.Chaos.TruncatePercent))
+	}
+
+	// Follow page_token pagination of list RPCs when enabled
+	// 启用时自动遍历 list 类 RPC 的分页并合并结果
+	if config.Tools.Pagination.Enabled {
+		serviceDiscoverer = pagination.New(serviceDiscoverer, config.Tools.Pagination, logger)
+		logger.Info("Automatic pagination enabled",
+			zap.Int("max_pages", config.Tools.Pagination.MaxPages),
+			zap.Int("max_items", config.Tools.Pagination.MaxItems),
+			zap.Strings("tools", config.Tools.Pagination.Tools))
+	}
+
+	// Wait for google.longrunning operations to complete when enabled
+	// 启用时轮询 google.longrunning 操作直至完成，返回解包后的结果
+	if config.Tools.LongRunning.Enabled {
+		serviceDiscoverer = longrunning.New(serviceDiscoverer, config.Tools.LongRunning, logger)
+		logger.Info("Long-running operation polling enabled",
+			zap.Duration("poll_interval", config.Tools.LongRunning.PollInterval),
+			zap.Duration("max_wait", config.Tools.LongRunning.MaxWait),
+			zap.Strings("tools", config.Tools.LongRunning.Tools))
+	}
+
+	// Hide or mark tools of services reporting NOT_SERVING when enabled
+	// 启用时隐藏或标记上游报告 NOT_SERVING 的服务的工具（回放模式下没有上游）
+	if config.Tools.HealthGating.Mode != appconfig.HealthGatingOff && replayCfg.Mode != appconfig.ReplayModeReplay {
+		gate, err := health.NewGate(serviceDiscoverer, config.Tools.HealthGating, logger)
+		if err != nil {
+			logger.Fatal("Failed to enable health gating", zap.Error(err))
+		}
+		gate.Start(ctx)
+		serviceDiscoverer = gate
+		logger.Info("Health gating enabled",
+			zap.String("mode", config.Tools.HealthGating.Mode),
+			zap.Duration("interval", config.Tools.HealthGating.Interval),
+			zap.Strings("notServing", gate.NotServing()))
+	}
+
+	// Apply scripts and response transformation rules around tool calls
+	// 在工具调用前后应用脚本和响应转换规则
+	var responseRules *transform.Rules
+	if len(config.Tools.Responses) > 0 || len(config.Tools.Scripts) > 0 {
+		transformer, err := transform.NewTransformer(serviceDiscoverer, config.Tools, logger)
+		if err != nil {
+			logger.Fatal("Invalid tool transformation config", zap.Error(err))
+		}
+		serviceDiscoverer = transformer
+		responseRules = transformer.Rules()
+		logger.Info("Tool call transformation enabled",
+			zap.Int("responseRules", len(config.Tools.Responses)),
+			zap.Int("scripts", len(config.Tools.Scripts)))
+	}
+
 	// Log service discovery completion
 	// 记录服务发现完成
 	stats := serviceDiscoverer.GetServiceStats()
@@ -192,37 +1334,254 @@ func main() {
 
 	// Create tool builder
 	// 创建工具构建器
-	toolBuilder := tools.NewMCPToolBuilder(logger)
+	toolBuilder := newToolBuilder(logger, config)
+
+	// Pre-build the schemas of the discovered tools when warm-up is enabled
+	// 启用预热时预先构建已发现工具的模式
+	if config.GRPC.WarmUp.Enabled {
+		if _, err := toolBuilder.BuildTools(serviceDiscoverer.GetMethods()); err != nil {
+			logger.Warn("Failed to pre-build tool schemas", zap.Error(err))
+		}
+	}
 
-	// Create HTTP handler with default header forwarding config
-	// 使用默认的头转发配置创建HTTP处理程序
-	defaultConfig := appconfig.Default()
-	handler := server.NewHandler(logger, serviceDiscoverer, sessionManager, toolBuilder, defaultConfig.GRPC.HeaderForwarding)
+	// Create HTTP handler with the configured header forwarding
+	// 使用配置的头转发设置创建HTTP处理程序
+	// Compile per-tool argument defaults and fixed values
+	argumentInjector, err := arguments.NewInjector(config.Tools.Arguments)
+	if err != nil {
+		logger.Fatal("Invalid tool argument rules", zap.Error(err))
+	}
+	argumentSanitizer, err := arguments.NewSanitizer(config.Tools.Sanitizers)
+	if err != nil {
+		logger.Fatal("Invalid tool argument sanitizers", zap.Error(err))
+	}
+
+	// Load markdown documentation appended to tool descriptions
+	toolDocs, err := server.LoadToolDocs(config.Tools.Docs.Dir, config.Tools.Docs.MaxLength)
+	if err != nil {
+		logger.Fatal("Failed to load tool docs", zap.Error(err))
+	}
+
+	// Compile composite tools and warn about steps that reference unknown tools
+	composites, err := composite.NewRegistry(config.Tools.Composites)
+	if err != nil {
+		logger.Fatal("Invalid composite tools", zap.Error(err))
+	}
+	for _, tool := range composites.Tools() {
+		if _, exists := serviceDiscoverer.GetMethod(tool.Name()); exists {
+			logger.Warn("Composite tool shadows a discovered tool", zap.String("toolName", tool.Name()))
+		}
+	}
+	if missing := composites.MissingTools(func(name string) bool {
+		_, exists := serviceDiscoverer.GetMethod(name)
+		return exists
+	}); len(missing) > 0 {
+		logger.Warn("Composite tools reference undiscovered tools", zap.Strings("tools", missing))
+	}
+
+	handlerOpts := append([]server.HandlerOption{
+		server.WithArgumentInjector(argumentInjector),
+		server.WithArgumentSanitizers(argumentSanitizer),
+		server.WithResponseRules(responseRules),
+		server.WithComposites(composites),
+		server.WithToolDocs(toolDocs),
+	}, configHandlerOptions(config)...)
+
+	// Validate bearer tokens and forward identity claims as gRPC metadata
+	if config.Server.Security.Auth.Enabled {
+		verifier, err := auth.NewVerifier(config.Server.Security.Auth)
+		if err != nil {
+			logger.Fatal("Invalid auth configuration", zap.Error(err))
+		}
+		handlerOpts = append(handlerOpts, server.WithIdentity(verifier, config.Server.Security.Auth))
+	}
+
+	if certMonitor != nil {
+		handlerOpts = append(handlerOpts, server.WithCertificateMonitor(certMonitor))
+	}
+
+	// Alert on unusual tool-call patterns
+	if config.Anomaly.Enabled {
+		handlerOpts = append(handlerOpts, server.WithAnomalyDetector(anomaly.New(config.Anomaly, logger)))
+	}
+
+	// Append every upstream tool call to the (optionally signed) audit log
+	if config.Audit.Enabled {
+		auditLog, err := audit.Open(config.Audit)
+		if err != nil {
+			logger.Fatal("Failed to open audit log", zap.Error(err))
+		}
+		defer func() {
+			if err := auditLog.Close(); err != nil {
+				logger.Warn("Failed to close audit log", zap.Error(err))
+			}
+		}()
+		handlerOpts = append(handlerOpts, server.WithAuditLog(auditLog))
+	}
+
+	handler := server.NewHandler(logger, serviceDiscoverer, sessionManager, toolBuilder, config.GRPC.HeaderForwarding, handlerOpts...)
+	watchReload(flags, handler, argumentSanitizer, logger)
 
 	// Setup router
-	router := setupRouter(handler)
+	adminAuth, err := server.AdminAuthMiddleware(config.Server.Admin, logger)
+	if err != nil {
+		logger.Fatal("Failed to configure admin authentication", zap.Error(err))
+	}
+	router := setupRouter(handler, adminAuth)
 
 	// Apply middleware
-	middlewares := server.DefaultMiddleware(logger)
+	// Resolve client IPs behind trusted proxies and apply IP access control
+	// before anything else sees the request
+	middlewares, err := server.NetworkMiddleware(config.Server.Security.Network, logger)
+	if err != nil {
+		logger.Fatal("Invalid network configuration", zap.Error(err))
+	}
+	// Keep clients on the replica holding their session, forwarding requests
+	// pinned to a peer before this replica handles them
+	if affinity := config.Server.Affinity; affinity.Enabled {
+		if affinity.ReplicaID == "" {
+			affinity.ReplicaID, _ = os.Hostname()
+		}
+		affinityMiddleware, err := server.AffinityMiddleware(affinity, logger)
+		if err != nil {
+			logger.Fatal("Invalid affinity configuration", zap.Error(err))
+		}
+		middlewares = append(middlewares, affinityMiddleware)
+		logger.Info("Session affinity enabled",
+			zap.String("replicaId", affinity.ReplicaID),
+			zap.Int("peers", len(affinity.Peers)))
+	}
+	middlewares = append(middlewares, server.DefaultMiddleware(logger)...)
+	// Per-session rate limits, shared across replicas with the redis backend
+	if rateLimit := config.Server.Security.RateLimit; rateLimit.Enabled {
+		limiter, err := ratelimit.New(rateLimit)
+		if err != nil {
+			logger.Fatal("Invalid rate limit configuration", zap.Error(err))
+		}
+		if closer, ok := limiter.(io.Closer); ok {
+			defer func() { _ = closer.Close() }()
+		}
+		middlewares = append(middlewares, server.LimiterMiddleware(limiter, float64(rateLimit.RequestsPerMinute)/60, rateLimit.FailOpen, logger))
+		logger.Info("Session rate limiting enabled",
+			zap.String("backend", rateLimit.Backend),
+			zap.Int("requestsPerMinute", rateLimit.RequestsPerMinute),
+			zap.Int("burst", rateLimit.BurstSize))
+	}
+	if config.Server.Security.ValidateOrigin {
+		middlewares = append(middlewares, server.OriginMiddleware(config.Server.Security.CORS.AllowedOrigins))
+	}
 	finalHandler := server.ChainMiddleware(middlewares...)(router)
 
 	// Create HTTP server
 	httpServer := &http.Server{
-		Addr:         fmt.Sprintf(":%d", config.HTTPPort),
+		Addr:         fmt.Sprintf(":%d", config.Server.Port),
 		Handler:      finalHandler,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Load the certificate and bind the port before serving, so that the
+	// ready event is only reported once connections are accepted
+	scheme := "http"
+	if config.Server.TLS.Enabled() {
+		certificate, err := tls.LoadX509KeyPair(config.Server.TLS.CertFile, config.Server.TLS.KeyFile)
+		if err != nil {
+			logger.Fatal("Failed to load TLS certificate", zap.Error(err))
+		}
+		httpServer.TLSConfig = &tls.Config{Certificates: []tls.Certificate{certificate}}
+		scheme = "https"
+	}
+	listener, err := net.Listen("tcp", httpServer.Addr)
+	if err != nil {
+		logger.Fatal("Failed to start HTTP server", zap.Error(err))
+	}
+
 	// Start server in a goroutine
 	go func() {
-		logger.Info("Starting HTTP server", zap.Int("port", config.HTTPPort))
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Info("Starting HTTP server", zap.Int("port", config.Server.Port), zap.Bool("tls", config.Server.TLS.Enabled()))
+		var err error
+		if config.Server.TLS.Enabled() {
+			err = httpServer.ServeTLS(listener, "", "")
+		} else {
+			err = httpServer.Serve(listener)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Failed to start HTTP server", zap.Error(err))
 		}
 	}()
 
+	// Report readiness for deployment automation
+	ready := readiness{
+		Version:             version,
+		Addresses:           []string{scheme + "://" + listener.Addr().String()},
+		Upstreams:           startupUpstreams(config),
+		ToolCount:           serviceDiscoverer.GetMethodCount() + len(composites.Tools()),
+		ProtocolVersions:    server.ProtocolVersions(),
+		BackgroundDiscovery: config.GRPC.BackgroundDiscovery && config.GRPC.SnapshotPath == "",
+	}
+	announceReady(ready, config.Server.ReadyFile, logger)
+	if config.Server.ReadyFile != "" {
+		defer func() {
+			if err := os.Remove(config.Server.ReadyFile); err != nil && !os.IsNotExist(err) {
+				logger.Warn("Failed to remove ready file", zap.Error(err))
+			}
+		}()
+	}
+
 	// Wait for shutdown signal
 	gracefulShutdown(httpServer, logger)
 }
+
+// readiness is reported once the gateway accepts connections: as the
+// "ready" log event and, when configured, as the content of the ready file
+type readiness struct {
+	Version          string   `json:"version"`
+	Addresses        []string `json:"addresses"`
+	Upstreams        []string `json:"upstreams"`
+	ToolCount        int      `json:"toolCount"`
+	ProtocolVersions []string `json:"protocolVersions"`
+	// Tools are still being discovered; ToolCount covers those found so far
+	BackgroundDiscovery bool `json:"backgroundDiscovery,omitempty"`
+}
+
+// startupUpstreams lists the upstreams tool calls are routed to at startup:
+// the primary and its replicas. Replay mode has none.
+func startupUpstreams(config *appconfig.Config) []string {
+	if config.Replay.Mode == appconfig.ReplayModeReplay {
+		return []string{}
+	}
+	upstreams := []string{fmt.Sprintf("%s:%d", config.GRPC.Host, config.GRPC.Port)}
+	for _, replica := range config.Routing.Replicas {
+		upstreams = append(upstreams, replica.Address())
+	}
+	return upstreams
+}
+
+// announceReady logs the single "ready" event automation can wait on, with
+// event=ready, and writes it to readyFile when one is configured. The file is
+// written to a temporary name and renamed, so it never appears half-written.
+func announceReady(ready readiness, readyFile string, logger *zap.Logger) {
+	logger.Info("Gateway ready",
+		zap.String("event", "ready"),
+		zap.String("version", ready.Version),
+		zap.Strings("addresses", ready.Addresses),
+		zap.Strings("upstreams", ready.Upstreams),
+		zap.Int("toolCount", ready.ToolCount),
+		zap.Strings("protocolVersions", ready.ProtocolVersions),
+		zap.Bool("backgroundDiscovery", ready.BackgroundDiscovery))
+
+	if readyFile == "" {
+		return
+	}
+	data, err := json.MarshalIndent(ready, "", "  ")
+	if err == nil {
+		tmp := readyFile + ".tmp"
+		if err = os.WriteFile(tmp, append(data, '\n'), 0o644); err == nil {
+			err = os.Rename(tmp, readyFile)
+		}
+	}
+	if err != nil {
+		logger.Warn("Failed to write ready file", zap.String("path", readyFile), zap.Error(err))
+	}
+}
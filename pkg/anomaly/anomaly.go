@@ -0,0 +1,283 @@
+// Package anomaly detects unusual tool-call patterns that point to a
+// misbehaving agent: a sudden burst of calls to a destructive tool, or the
+// same call failing over and over. Alerts are logged and optionally posted to
+// a webhook, giving security teams early warning.
+package anomaly
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"go.uber.org/zap"
+)
+
+// Alert kinds
+const (
+	KindDestructiveSpike = "destructive_spike"
+	KindRepeatedFailures = "repeated_failures"
+)
+
+const (
+	// maxTrackedKeys bounds the windows kept before expired ones are pruned
+	maxTrackedKeys = 10000
+	// maxPendingWebhooks bounds concurrent webhook deliveries; further alerts
+	// are only logged
+	maxPendingWebhooks = 16
+)
+
+// Call is the outcome of one tool call as seen by the detector
+type Call struct {
+	Tool      string
+	SessionID string
+	Arguments string
+	Failed    bool
+}
+
+// Alert describes a detected anomaly. It is the body posted to the webhook.
+type Alert struct {
+	Kind      string    `json:"kind"`
+	Tool      string    `json:"tool"`
+	SessionID string    `json:"sessionId,omitempty"`
+	Count     int       `json:"count"`
+	Window    string    `json:"window"`
+	Message   string    `json:"message"`
+	Time      time.Time `json:"time"`
+}
+
+// Stats reports alerting counters
+type Stats struct {
+	// Alerts raised by kind
+	Alerts map[string]int64 `json:"alerts"`
+	// Webhook deliveries that failed
+	WebhookFailures int64 `json:"webhookFailures"`
+	// Alerts not posted because too many deliveries were pending
+	WebhookDropped int64 `json:"webhookDropped"`
+}
+
+// window counts events within a fixed window starting at the first event
+type window struct {
+	start time.Time
+	count int
+}
+
+// add counts an event, starting a new window when the current one has passed
+func (w *window) add(now time.Time, length time.Duration) int {
+	if now.Sub(w.start) > length {
+		w.start = now
+		w.count = 0
+	}
+	w.count++
+	return w.count
+}
+
+// Detector watches tool calls for anomalies
+type Detector struct {
+	config config.AnomalyConfig
+	logger *zap.Logger
+	client *http.Client
+	now    func() time.Time
+
+	mu        sync.Mutex
+	spikes    map[string]*window
+	failures  map[string]*window
+	lastAlert map[string]time.Time
+	stats     Stats
+
+	pending chan struct{}
+}
+
+// New creates a detector
+func New(cfg config.AnomalyConfig, logger *zap.Logger) *Detector {
+	return &Detector{
+		config:    cfg,
+		logger:    logger,
+		client:    &http.Client{Timeout: cfg.WebhookTimeout},
+		now:       time.Now,
+		spikes:    make(map[string]*window),
+		failures:  make(map[string]*window),
+		lastAlert: make(map[string]time.Time),
+		stats:     Stats{Alerts: make(map[string]int64)},
+		pending:   make(chan struct{}, maxPendingWebhooks),
+	}
+}
+
+// Observe records a tool call and raises the alerts it triggers. A nil
+// detector ignores calls.
+func (d *Detector) Observe(call Call) {
+	if d == nil {
+		return
+	}
+	alerts := d.detect(call)
+	for _, alert := range alerts {
+		d.emit(alert)
+	}
+}
+
+// detect updates the windows and returns the alerts to raise
+func (d *Detector) detect(call Call) []Alert {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	if len(d.spikes)+len(d.failures) >= maxTrackedKeys {
+		d.prune(now)
+	}
+
+	var alerts []Alert
+	if d.destructive(call.Tool) {
+		w, ok := d.spikes[call.Tool]
+		if !ok {
+			w = &window{start: now}
+			d.spikes[call.Tool] = w
+		}
+		if count := w.add(now, d.config.SpikeWindow); count >= d.config.SpikeThreshold {
+			alerts = d.raise(alerts, now, "spike:"+call.Tool, Alert{
+				Kind:    KindDestructiveSpike,
+				Tool:    call.Tool,
+				Count:   count,
+				Window:  d.config.SpikeWindow.String(),
+				Message: fmt.Sprintf("destructive tool %s called %d times within %s", call.Tool, count, d.config.SpikeWindow),
+			})
+		}
+	}
+
+	sum := sha256.Sum256([]byte(call.Arguments))
+	key := call.SessionID + "\x00" + call.Tool + "\x00" + hex.EncodeToString(sum[:8])
+	if !call.Failed {
+		delete(d.failures, key)
+		return alerts
+	}
+	w, ok := d.failures[key]
+	if !ok {
+		w = &window{start: now}
+		d.failures[key] = w
+	}
+	if count := w.add(now, d.config.FailureWindow); count >= d.config.RepeatedFailures {
+		alerts = d.raise(alerts, now, "failures:"+key, Alert{
+			Kind:      KindRepeatedFailures,
+			Tool:      call.Tool,
+			SessionID: call.SessionID,
+			Count:     count,
+			Window:    d.config.FailureWindow.String(),
+			Message:   fmt.Sprintf("the same call of %s failed %d times within %s", call.Tool, count, d.config.FailureWindow),
+		})
+	}
+	return alerts
+}
+
+// raise appends the alert unless one was raised for the subject within the cooldown
+func (d *Detector) raise(alerts []Alert, now time.Time, subject string, alert Alert) []Alert {
+	if last, ok := d.lastAlert[subject]; ok && now.Sub(last) < d.config.Cooldown {
+		return alerts
+	}
+	d.lastAlert[subject] = now
+	d.stats.Alerts[alert.Kind]++
+	alert.Time = now
+	return append(alerts, alert)
+}
+
+// destructive reports whether the tool matches a destructive pattern
+func (d *Detector) destructive(toolName string) bool {
+	for _, pattern := range d.config.DestructiveTools {
+		if matched, err := path.Match(pattern, toolName); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// prune drops windows and cooldowns that have expired
+func (d *Detector) prune(now time.Time) {
+	for key, w := range d.spikes {
+		if now.Sub(w.start) > d.config.SpikeWindow {
+			delete(d.spikes, key)
+		}
+	}
+	for key, w := range d.failures {
+		if now.Sub(w.start) > d.config.FailureWindow {
+			delete(d.failures, key)
+		}
+	}
+	for subject, last := range d.lastAlert {
+		if now.Sub(last) >= d.config.Cooldown {
+			delete(d.lastAlert, subject)
+		}
+	}
+}
+
+// emit logs the alert and posts it to the webhook in the background
+func (d *Detector) emit(alert Alert) {
+	d.logger.Warn("Anomaly detected",
+		zap.String("kind", alert.Kind),
+		zap.String("tool", alert.Tool),
+		zap.String("sessionId", alert.SessionID),
+		zap.Int("count", alert.Count),
+		zap.String("window", alert.Window))
+
+	if d.config.WebhookURL == "" {
+		return
+	}
+	select {
+	case d.pending <- struct{}{}:
+	default:
+		d.mu.Lock()
+		d.stats.WebhookDropped++
+		d.mu.Unlock()
+		return
+	}
+	go func() {
+		defer func() { <-d.pending }()
+		if err := d.post(alert); err != nil {
+			d.mu.Lock()
+			d.stats.WebhookFailures++
+			d.mu.Unlock()
+			d.logger.Error("Failed to deliver anomaly alert", zap.String("kind", alert.Kind), zap.Error(err))
+		}
+	}()
+}
+
+// post delivers the alert to the webhook
+func (d *Detector) post(alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), d.config.WebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.config.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Stats returns the alerting counters
+func (d *Detector) Stats() Stats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats := d.stats
+	stats.Alerts = make(map[string]int64, len(d.stats.Alerts))
+	for kind, count := range d.stats.Alerts {
+		stats.Alerts[kind] = count
+	}
+	return stats
+}
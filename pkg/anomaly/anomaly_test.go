@@ -0,0 +1,97 @@
+package anomaly
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func testConfig() config.AnomalyConfig {
+	cfg := config.Default().Anomaly
+	cfg.Enabled = true
+	cfg.DestructiveTools = []string{"*_delete*"}
+	cfg.SpikeThreshold = 3
+	cfg.RepeatedFailures = 2
+	return cfg
+}
+
+func TestDetector_DestructiveSpike(t *testing.T) {
+	d := New(testConfig(), zap.NewNop())
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		assert.Empty(t, d.detect(Call{Tool: "users_delete", SessionID: "s1"}))
+	}
+	// Non-destructive tools are not counted
+	assert.Empty(t, d.detect(Call{Tool: "users_get", SessionID: "s1"}))
+
+	alerts := d.detect(Call{Tool: "users_delete", SessionID: "s2"})
+	require.Len(t, alerts, 1)
+	assert.Equal(t, KindDestructiveSpike, alerts[0].Kind)
+	assert.Equal(t, 3, alerts[0].Count)
+
+	// Further calls within the cooldown do not raise another alert
+	assert.Empty(t, d.detect(Call{Tool: "users_delete"}))
+
+	// A new window after the cooldown starts counting again
+	now = now.Add(d.config.Cooldown + time.Second)
+	assert.Empty(t, d.detect(Call{Tool: "users_delete"}))
+	assert.Equal(t, int64(1), d.Stats().Alerts[KindDestructiveSpike])
+}
+
+func TestDetector_RepeatedFailures(t *testing.T) {
+	d := New(testConfig(), zap.NewNop())
+
+	failed := Call{Tool: "orders_get", SessionID: "s1", Arguments: `{"id":1}`, Failed: true}
+	assert.Empty(t, d.detect(failed))
+
+	// Different arguments, sessions and a success in between reset the count
+	assert.Empty(t, d.detect(Call{Tool: "orders_get", SessionID: "s1", Arguments: `{"id":2}`, Failed: true}))
+	assert.Empty(t, d.detect(Call{Tool: "orders_get", SessionID: "s2", Arguments: `{"id":1}`, Failed: true}))
+	assert.Empty(t, d.detect(Call{Tool: "orders_get", SessionID: "s1", Arguments: `{"id":1}`}))
+	assert.Empty(t, d.detect(failed))
+
+	alerts := d.detect(failed)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, KindRepeatedFailures, alerts[0].Kind)
+	assert.Equal(t, "s1", alerts[0].SessionID)
+}
+
+func TestDetector_Webhook(t *testing.T) {
+	received := make(chan Alert, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var alert Alert
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&alert))
+		received <- alert
+	}))
+	defer server.Close()
+
+	cfg := testConfig()
+	cfg.WebhookURL = server.URL
+	d := New(cfg, zap.NewNop())
+	failed := Call{Tool: "orders_get", SessionID: "s1", Failed: true}
+	d.Observe(failed)
+	d.Observe(failed)
+
+	select {
+	case alert := <-received:
+		assert.Equal(t, KindRepeatedFailures, alert.Kind)
+		assert.Equal(t, "orders_get", alert.Tool)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
+
+func TestDetector_NilIgnoresCalls(t *testing.T) {
+	var d *Detector
+	d.Observe(Call{Tool: "users_delete", Failed: true})
+}
@@ -0,0 +1,456 @@
+package arguments
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// jsonNumber matches a JSON number literal. Other strings ("NaN", "0x10",
+// "1,000") are left for protojson to accept or reject.
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// Options selects the coercions applied by Coerce
+type Options struct {
+	// Types coerces numeric and boolean strings and single values of repeated fields
+	Types bool
+	// Enums controls how loosely written enum names are matched
+	Enums EnumOptions
+	// Times accepts common formats for Timestamp ("2024-01-02") and Duration
+	// ("5m") fields
+	Times bool
+	// Bytes accepts data: URIs and resource URIs for bytes fields
+	Bytes bool
+	// ReadResource returns the content of a resource referenced by a bytes
+	// field; resource URIs are left alone when it is nil
+	ReadResource func(uri string) ([]byte, error)
+}
+
+// EnumOptions controls matching of enum names that are not spelled exactly
+// as declared
+type EnumOptions struct {
+	// CaseInsensitive matches "active" to ACTIVE
+	CaseInsensitive bool
+	// StripPrefix matches "ACTIVE" to STATUS_ACTIVE in enum Status
+	StripPrefix bool
+}
+
+// Enabled reports whether any coercion is selected
+func (o Options) Enabled() bool {
+	return o.Types || o.Times || o.Bytes || o.Enums.CaseInsensitive || o.Enums.StripPrefix
+}
+
+// Coercion records an argument value rewritten by Coerce
+type Coercion struct {
+	// Path of the argument, e.g. "book.pages" or "tags[0]"
+	Path string `json:"path"`
+	// From is the JSON type sent by the client, the value sent for enums,
+	// timestamps and durations, "data URI" or the URI of a resource
+	From string `json:"from"`
+	// To is the JSON type the value was coerced to, or the rewritten value
+	To string `json:"to"`
+}
+
+// Coerce fixes common mistakes LLM clients make in tool arguments before they
+// are unmarshaled into the request message desc. With opts.Types:
+//
+//   - numeric strings for 32-bit integer and floating point fields become numbers
+//   - "true" and "false" strings for bool fields become booleans
+//   - a single value for a repeated field becomes a one-element array
+//
+// With opts.Enums, enum names are matched ignoring case and/or the enum's
+// prefix ("active" becomes STATUS_ACTIVE); ambiguous names are left alone.
+// With opts.Times, dates and date-times without a zone become RFC 3339
+// timestamps in UTC and Go durations ("1h30m") become protobuf durations.
+// With opts.Bytes, data: URIs and resource URIs (read with opts.ReadResource)
+// for bytes fields become base64; base64 never contains ':', so the two
+// cannot be confused.
+//
+// 64-bit integers are left alone since protojson accepts them as strings.
+// Values that cannot be coerced are kept, so protojson still reports them.
+// The input map is not modified; it is returned as is when nothing changed.
+func Coerce(desc protoreflect.MessageDescriptor, args map[string]interface{}, opts Options) (map[string]interface{}, []Coercion) {
+	if desc == nil || args == nil || !opts.Enabled() {
+		return args, nil
+	}
+	c := &coercer{opts: opts}
+	coerced, changed := c.message(desc, args, "")
+	if !changed {
+		return args, nil
+	}
+	sort.Slice(c.applied, func(i, j int) bool { return c.applied[i].Path < c.applied[j].Path })
+	return coerced, c.applied
+}
+
+type coercer struct {
+	opts    Options
+	applied []Coercion
+}
+
+// message coerces the fields of a JSON object, copying it only when a field changes
+func (c *coercer) message(desc protoreflect.MessageDescriptor, object map[string]interface{}, prefix string) (map[string]interface{}, bool) {
+	var result map[string]interface{}
+	for name, value := range object {
+		field := desc.Fields().ByName(protoreflect.Name(name))
+		if field == nil {
+			field = desc.Fields().ByJSONName(name)
+		}
+		if field == nil {
+			continue
+		}
+		coerced, changed := c.field(field, value, prefix+name)
+		if !changed {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]interface{}, len(object))
+			for k, v := range object {
+				result[k] = v
+			}
+		}
+		result[name] = coerced
+	}
+	if result == nil {
+		return object, false
+	}
+	return result, true
+}
+
+// field coerces the value of a field, wrapping single values of repeated fields
+func (c *coercer) field(field protoreflect.FieldDescriptor, value interface{}, path string) (interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+
+	if field.IsMap() {
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return value, false
+		}
+		var result map[string]interface{}
+		for key, entry := range entries {
+			coerced, changed := c.single(field.MapValue(), entry, fmt.Sprintf("%s[%s]", path, key))
+			if !changed {
+				continue
+			}
+			if result == nil {
+				result = make(map[string]interface{}, len(entries))
+				for k, v := range entries {
+					result[k] = v
+				}
+			}
+			result[key] = coerced
+		}
+		if result == nil {
+			return value, false
+		}
+		return result, true
+	}
+
+	if field.IsList() {
+		items, ok := value.([]interface{})
+		changed := false
+		if !ok {
+			if !c.opts.Types {
+				return value, false
+			}
+			c.applied = append(c.applied, Coercion{Path: path, From: jsonType(value), To: "array"})
+			items = []interface{}{value}
+			changed = true
+		}
+		var result []interface{}
+		for i, item := range items {
+			coerced, itemChanged := c.single(field, item, fmt.Sprintf("%s[%d]", path, i))
+			if !itemChanged {
+				continue
+			}
+			if result == nil {
+				result = append([]interface{}(nil), items...)
+			}
+			result[i] = coerced
+		}
+		if result != nil {
+			return result, true
+		}
+		return items, changed
+	}
+
+	return c.single(field, value, path)
+}
+
+// single coerces one (non-repeated) value of a field
+func (c *coercer) single(field protoreflect.FieldDescriptor, value interface{}, path string) (interface{}, bool) {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if isWrapper(field.Message()) {
+			return c.single(field.Message().Fields().ByName("value"), value, path)
+		}
+		switch field.Message().FullName() {
+		case "google.protobuf.Timestamp":
+			return c.text(value, path, normalizeTimestamp)
+		case "google.protobuf.Duration":
+			return c.text(value, path, normalizeDuration)
+		}
+		if strings.HasPrefix(string(field.Message().FullName()), "google.protobuf.") {
+			// Other well-known types have their own JSON mappings
+			return value, false
+		}
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return value, false
+		}
+		return c.message(field.Message(), object, path+".")
+
+	case protoreflect.EnumKind:
+		s, ok := value.(string)
+		if !ok {
+			return value, false
+		}
+		name, ok := matchEnum(field.Enum(), s, c.opts.Enums)
+		if !ok {
+			return value, false
+		}
+		c.applied = append(c.applied, Coercion{Path: path, From: s, To: name})
+		return name, true
+
+	case protoreflect.BytesKind:
+		s, ok := value.(string)
+		if !ok || !c.opts.Bytes || !strings.Contains(s, ":") {
+			return value, false
+		}
+		var data []byte
+		var err error
+		from := "data URI"
+		if strings.HasPrefix(s, "data:") {
+			data, err = decodeDataURI(s)
+		} else if c.opts.ReadResource != nil {
+			from = s
+			data, err = c.opts.ReadResource(s)
+		} else {
+			return value, false
+		}
+		if err != nil {
+			// protojson reports the value as invalid base64
+			return value, false
+		}
+		c.applied = append(c.applied, Coercion{Path: path, From: from, To: "base64"})
+		return base64.StdEncoding.EncodeToString(data), true
+
+	case protoreflect.BoolKind:
+		s, ok := value.(string)
+		if !ok || !c.opts.Types {
+			return value, false
+		}
+		var b bool
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "true":
+			b = true
+		case "false":
+		default:
+			return value, false
+		}
+		c.applied = append(c.applied, Coercion{Path: path, From: "string", To: "boolean"})
+		return b, true
+
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.FloatKind, protoreflect.DoubleKind:
+		s, ok := value.(string)
+		if !ok || !c.opts.Types {
+			return value, false
+		}
+		number, ok := parseNumber(field.Kind(), strings.TrimSpace(s))
+		if !ok {
+			return value, false
+		}
+		c.applied = append(c.applied, Coercion{Path: path, From: "string", To: "number"})
+		return number, true
+	}
+	return value, false
+}
+
+// text rewrites a string value with normalize when time coercion is selected
+func (c *coercer) text(value interface{}, path string, normalize func(string) (string, bool)) (interface{}, bool) {
+	s, ok := value.(string)
+	if !ok || !c.opts.Times {
+		return value, false
+	}
+	normalized, ok := normalize(strings.TrimSpace(s))
+	if !ok || normalized == s {
+		return value, false
+	}
+	c.applied = append(c.applied, Coercion{Path: path, From: s, To: normalized})
+	return normalized, true
+}
+
+// timestampLayouts are the formats accepted for Timestamp fields besides RFC
+// 3339. Values without a zone are taken as UTC.
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// normalizeTimestamp converts s to an RFC 3339 timestamp in UTC. Valid RFC
+// 3339 values are kept as sent.
+func normalizeTimestamp(s string) (string, bool) {
+	if _, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return s, true
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC().Format(time.RFC3339Nano), true
+		}
+	}
+	return "", false
+}
+
+// protoDuration matches the JSON form of google.protobuf.Duration
+var protoDuration = regexp.MustCompile(`^-?[0-9]+(\.[0-9]{1,9})?s$`)
+
+// normalizeDuration converts a Go duration ("5m", "1h30m", "250ms") to the
+// protobuf form ("300s"). Protobuf durations are kept as sent.
+func normalizeDuration(s string) (string, bool) {
+	if protoDuration.MatchString(s) {
+		return s, true
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return "", false
+	}
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	seconds := strconv.FormatInt(int64(d/time.Second), 10)
+	if nanos := d % time.Second; nanos != 0 {
+		seconds += strings.TrimRight(fmt.Sprintf(".%09d", nanos), "0")
+	}
+	return sign + seconds + "s", true
+}
+
+// decodeDataURI returns the data of an RFC 2397 data: URI
+// ("data:image/png;base64,iVBOR..." or "data:,hello%20world")
+func decodeDataURI(uri string) ([]byte, error) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok {
+		return nil, fmt.Errorf("data URI has no data")
+	}
+	if strings.HasSuffix(header, ";base64") {
+		return base64.StdEncoding.DecodeString(payload)
+	}
+	decoded, err := url.PathUnescape(payload)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(decoded), nil
+}
+
+// parseNumber checks that s is a JSON number in the range of the field kind
+func parseNumber(kind protoreflect.Kind, s string) (json.Number, bool) {
+	if !jsonNumber.MatchString(s) {
+		return "", false
+	}
+	var err error
+	switch kind {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		_, err = strconv.ParseInt(s, 10, 32)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		_, err = strconv.ParseUint(s, 10, 32)
+	}
+	return json.Number(s), err == nil
+}
+
+// matchEnum finds the declared name of enum value s. Exact names are not
+// rewritten, and names matching more than one value are left alone.
+func matchEnum(enum protoreflect.EnumDescriptor, s string, opts EnumOptions) (string, bool) {
+	if !opts.CaseInsensitive && !opts.StripPrefix {
+		return "", false
+	}
+	values := enum.Values()
+	if values.ByName(protoreflect.Name(s)) != nil {
+		return "", false
+	}
+	equal := func(a, b string) bool {
+		if opts.CaseInsensitive {
+			return strings.EqualFold(a, b)
+		}
+		return a == b
+	}
+
+	prefix := enumPrefix(enum.Name())
+	s = strings.TrimSpace(s)
+	match := ""
+	for i := 0; i < values.Len(); i++ {
+		name := string(values.Get(i).Name())
+		matched := equal(name, s)
+		if !matched && opts.StripPrefix && len(name) > len(prefix) && strings.HasPrefix(name, prefix) {
+			matched = equal(name[len(prefix):], s)
+		}
+		if !matched || name == match {
+			continue
+		}
+		if match != "" {
+			return "", false
+		}
+		match = name
+	}
+	return match, match != ""
+}
+
+// enumPrefix returns the value prefix the protobuf style guide recommends for
+// an enum, e.g. "ORDER_STATUS_" for OrderStatus
+func enumPrefix(name protoreflect.Name) string {
+	runes := []rune(string(name))
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	b.WriteByte('_')
+	return b.String()
+}
+
+// isWrapper reports whether desc is one of the google.protobuf wrapper types
+func isWrapper(desc protoreflect.MessageDescriptor) bool {
+	switch desc.FullName() {
+	case "google.protobuf.BoolValue", "google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.FloatValue", "google.protobuf.DoubleValue", "google.protobuf.BytesValue":
+		return true
+	}
+	return false
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, json.Number:
+		return "number"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
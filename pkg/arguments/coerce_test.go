@@ -0,0 +1,258 @@
+package arguments
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// coerceMessage builds a request message with fields of the coerced kinds
+func coerceMessage(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	field := func(name string, number int32, label *descriptorpb.FieldDescriptorProto_Label, kind descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Label:  label,
+			Type:   kind.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("shop/search.proto"),
+		Package: proto.String("shop"),
+		// proto2 allows enum names that differ only by case or prefix
+		Syntax:     proto.String("proto2"),
+		Dependency: []string{"google/protobuf/wrappers.proto", "google/protobuf/timestamp.proto", "google/protobuf/duration.proto"},
+		EnumType: []*descriptorpb.EnumDescriptorProto{
+			{Name: proto.String("OrderStatus"), Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("ORDER_STATUS_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("ORDER_STATUS_ACTIVE"), Number: proto.Int32(1)},
+				{Name: proto.String("ORDER_STATUS_SHIPPED"), Number: proto.Int32(2)},
+			}},
+			{Name: proto.String("Mode"), Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("MODE_FAST"), Number: proto.Int32(0)},
+				{Name: proto.String("FAST"), Number: proto.Int32(1)},
+			}},
+		},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Filter"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("in_stock", 1, optional, descriptorpb.FieldDescriptorProto_TYPE_BOOL, ""),
+				field("max_price", 2, optional, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, ""),
+			}},
+			{Name: proto.String("SearchRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("query", 1, optional, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				field("page_size", 2, optional, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+				field("offset", 3, optional, descriptorpb.FieldDescriptorProto_TYPE_INT64, ""),
+				field("tags", 4, repeated, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				field("ids", 5, repeated, descriptorpb.FieldDescriptorProto_TYPE_UINT32, ""),
+				field("filter", 6, optional, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".shop.Filter"),
+				field("exact", 7, optional, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.BoolValue"),
+				field("status", 8, optional, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".shop.OrderStatus"),
+				field("statuses", 9, repeated, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".shop.OrderStatus"),
+				field("mode", 10, optional, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".shop.Mode"),
+				field("since", 11, optional, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Timestamp"),
+				field("timeout", 12, optional, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Duration"),
+				field("windows", 13, repeated, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Duration"),
+				field("image", 14, optional, descriptorpb.FieldDescriptorProto_TYPE_BYTES, ""),
+				field("attachments", 15, repeated, descriptorpb.FieldDescriptorProto_TYPE_BYTES, ""),
+			}},
+		},
+	}, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	return fd.Messages().ByName("SearchRequest")
+}
+
+func TestCoerce(t *testing.T) {
+	desc := coerceMessage(t)
+	args := map[string]interface{}{
+		"query":    "boots",
+		"pageSize": "20",
+		"offset":   "100",
+		"tags":     "winter",
+		"ids":      []interface{}{"1", float64(2)},
+		"filter":   map[string]interface{}{"in_stock": "TRUE", "max_price": "99.5"},
+		"exact":    "false",
+	}
+	coerced, coercions := Coerce(desc, args, Options{Types: true})
+
+	data, err := json.Marshal(coerced)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"query": "boots",
+		"pageSize": 20,
+		"offset": "100",
+		"tags": ["winter"],
+		"ids": [1, 2],
+		"filter": {"in_stock": true, "max_price": 99.5},
+		"exact": false
+	}`, string(data))
+	assert.Equal(t, []Coercion{
+		{Path: "exact", From: "string", To: "boolean"},
+		{Path: "filter.in_stock", From: "string", To: "boolean"},
+		{Path: "filter.max_price", From: "string", To: "number"},
+		{Path: "ids[0]", From: "string", To: "number"},
+		{Path: "pageSize", From: "string", To: "number"},
+		{Path: "tags", From: "string", To: "array"},
+	}, coercions)
+
+	// The client's arguments are not modified
+	assert.Equal(t, "20", args["pageSize"])
+	assert.Equal(t, "TRUE", args["filter"].(map[string]interface{})["in_stock"])
+}
+
+func TestCoerce_LeavesInvalidValues(t *testing.T) {
+	desc := coerceMessage(t)
+	args := map[string]interface{}{
+		"page_size": "twenty",
+		"ids":       []interface{}{"-1", "4294967296", "0x10"},
+		"filter":    map[string]interface{}{"in_stock": "yes", "max_price": "NaN"},
+		"unknown":   "1",
+	}
+	coerced, coercions := Coerce(desc, args, Options{Types: true})
+	assert.Empty(t, coercions)
+	assert.Equal(t, args, coerced)
+
+	coerced, coercions = Coerce(desc, nil, Options{Types: true})
+	assert.Nil(t, coerced)
+	assert.Empty(t, coercions)
+}
+
+func TestCoerce_Enums(t *testing.T) {
+	desc := coerceMessage(t)
+	args := map[string]interface{}{
+		"status":   "active",
+		"statuses": []interface{}{"Shipped", "ORDER_STATUS_ACTIVE", "order_status_unspecified"},
+		"mode":     "fast",
+		"pageSize": "20",
+	}
+
+	coerced, coercions := Coerce(desc, args, Options{Enums: EnumOptions{CaseInsensitive: true, StripPrefix: true}})
+	assert.Equal(t, map[string]interface{}{
+		"status":   "ORDER_STATUS_ACTIVE",
+		"statuses": []interface{}{"ORDER_STATUS_SHIPPED", "ORDER_STATUS_ACTIVE", "ORDER_STATUS_UNSPECIFIED"},
+		// "fast" matches both MODE_FAST and FAST
+		"mode": "fast",
+		// Type coercion is not selected
+		"pageSize": "20",
+	}, coerced)
+	assert.Equal(t, []Coercion{
+		{Path: "status", From: "active", To: "ORDER_STATUS_ACTIVE"},
+		{Path: "statuses[0]", From: "Shipped", To: "ORDER_STATUS_SHIPPED"},
+		{Path: "statuses[2]", From: "order_status_unspecified", To: "ORDER_STATUS_UNSPECIFIED"},
+	}, coercions)
+
+	// Without case-insensitive matching only the prefix may be left out
+	coerced, _ = Coerce(desc, map[string]interface{}{"status": "ACTIVE", "statuses": []interface{}{"shipped"}},
+		Options{Enums: EnumOptions{StripPrefix: true}})
+	assert.Equal(t, map[string]interface{}{"status": "ORDER_STATUS_ACTIVE", "statuses": []interface{}{"shipped"}}, coerced)
+
+	// Nothing is selected
+	coerced, coercions = Coerce(desc, args, Options{})
+	assert.Equal(t, args, coerced)
+	assert.Empty(t, coercions)
+}
+
+func TestEnumPrefix(t *testing.T) {
+	assert.Equal(t, "STATUS_", enumPrefix("Status"))
+	assert.Equal(t, "ORDER_STATUS_", enumPrefix("OrderStatus"))
+	assert.Equal(t, "HTTP_METHOD_", enumPrefix("HTTPMethod"))
+	assert.Equal(t, "V2_MODE_", enumPrefix("V2Mode"))
+}
+
+func TestCoerce_Times(t *testing.T) {
+	desc := coerceMessage(t)
+	args := map[string]interface{}{
+		"since":   "2024-01-02",
+		"timeout": "1h30m",
+		"windows": []interface{}{"250ms", "-1.5s", "90s", "soon"},
+	}
+	coerced, coercions := Coerce(desc, args, Options{Times: true})
+	assert.Equal(t, map[string]interface{}{
+		"since":   "2024-01-02T00:00:00Z",
+		"timeout": "5400s",
+		"windows": []interface{}{"0.25s", "-1.5s", "90s", "soon"},
+	}, coerced)
+	assert.Equal(t, []Coercion{
+		{Path: "since", From: "2024-01-02", To: "2024-01-02T00:00:00Z"},
+		{Path: "timeout", From: "1h30m", To: "5400s"},
+		{Path: "windows[0]", From: "250ms", To: "0.25s"},
+	}, coercions)
+
+	// Values already in the protobuf JSON form are kept
+	args = map[string]interface{}{"since": "2024-01-02T10:00:00+02:00", "timeout": "1.000000001s"}
+	coerced, coercions = Coerce(desc, args, Options{Times: true})
+	assert.Equal(t, args, coerced)
+	assert.Empty(t, coercions)
+}
+
+func TestNormalizeTimestamp(t *testing.T) {
+	for input, expected := range map[string]string{
+		"2024-01-02T03:04:05.5":     "2024-01-02T03:04:05.5Z",
+		"2024-01-02 03:04:05":       "2024-01-02T03:04:05Z",
+		"2024-01-02 03:04":          "2024-01-02T03:04:00Z",
+		"2024-01-02 03:04:05+02:00": "2024-01-02T01:04:05Z",
+	} {
+		normalized, ok := normalizeTimestamp(input)
+		assert.True(t, ok, input)
+		assert.Equal(t, expected, normalized, input)
+	}
+	_, ok := normalizeTimestamp("yesterday")
+	assert.False(t, ok)
+}
+
+func TestCoerce_Bytes(t *testing.T) {
+	desc := coerceMessage(t)
+	args := map[string]interface{}{
+		"image": "data:image/png;base64,iVBORw0K",
+		"attachments": []interface{}{
+			"data:,hello%20world",
+			"ggrmcp://uploads/report",
+			"ggrmcp://uploads/missing",
+			"aGVsbG8=",
+		},
+	}
+	read := func(uri string) ([]byte, error) {
+		if uri == "ggrmcp://uploads/report" {
+			return []byte("report"), nil
+		}
+		return nil, errors.New("resource not found")
+	}
+
+	coerced, coercions := Coerce(desc, args, Options{Bytes: true, ReadResource: read})
+	assert.Equal(t, map[string]interface{}{
+		"image": "iVBORw0K",
+		"attachments": []interface{}{
+			"aGVsbG8gd29ybGQ=",
+			"cmVwb3J0",
+			// Unknown resources are left for protojson to reject
+			"ggrmcp://uploads/missing",
+			"aGVsbG8=",
+		},
+	}, coerced)
+	assert.Equal(t, []Coercion{
+		{Path: "attachments[0]", From: "data URI", To: "base64"},
+		{Path: "attachments[1]", From: "ggrmcp://uploads/report", To: "base64"},
+		{Path: "image", From: "data URI", To: "base64"},
+	}, coercions)
+
+	// Without a reader only data URIs are decoded
+	coerced, _ = Coerce(desc, map[string]interface{}{"image": "ggrmcp://uploads/report"}, Options{Bytes: true})
+	assert.Equal(t, "ggrmcp://uploads/report", coerced["image"])
+}
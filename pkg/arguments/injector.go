@@ -0,0 +1,239 @@
+// Package arguments applies operator-configured argument values to tool
+// calls: defaults fill in arguments the client left out, fixed values are
+// always sent and hidden from the tool's input schema. String values can be
+// templates rendered per call from the session context.
+package arguments
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+)
+
+// Vars is the data available to argument templates
+type Vars struct {
+	Tool      string
+	SessionID string
+	Metadata  map[string]string
+	Headers   map[string]string
+	Now       time.Time
+}
+
+type rule struct {
+	pattern  string
+	defaults map[string]value
+	fixed    map[string]value
+}
+
+// value is a configured argument value; strings containing "{{" are templates
+type value struct {
+	raw  interface{}
+	tmpl *template.Template
+}
+
+// Injector applies argument rules to tool calls and tool schemas
+type Injector struct {
+	rules []rule
+}
+
+// NewInjector compiles the configured rules. Rules are applied in order, so
+// a later rule overrides values set by an earlier one for the same argument.
+func NewInjector(configs []config.ToolArgumentsConfig) (*Injector, error) {
+	injector := &Injector{}
+	for _, cfg := range configs {
+		if _, err := path.Match(cfg.Tool, ""); err != nil {
+			return nil, fmt.Errorf("invalid tool pattern %q: %w", cfg.Tool, err)
+		}
+		defaults, err := compileValues(cfg.Tool, cfg.Defaults)
+		if err != nil {
+			return nil, err
+		}
+		fixed, err := compileValues(cfg.Tool, cfg.Fixed)
+		if err != nil {
+			return nil, err
+		}
+		injector.rules = append(injector.rules, rule{pattern: cfg.Tool, defaults: defaults, fixed: fixed})
+	}
+	return injector, nil
+}
+
+func compileValues(tool string, values map[string]interface{}) (map[string]value, error) {
+	compiled := make(map[string]value, len(values))
+	for name, raw := range values {
+		v := value{raw: raw}
+		if s, ok := raw.(string); ok && strings.Contains(s, "{{") {
+			tmpl, err := template.New(tool + "." + name).Option("missingkey=zero").Parse(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid template for %s argument %q: %w", tool, name, err)
+			}
+			v.tmpl = tmpl
+		}
+		compiled[name] = v
+	}
+	return compiled, nil
+}
+
+func (v value) render(vars Vars) (interface{}, error) {
+	if v.tmpl == nil {
+		return v.raw, nil
+	}
+	var b strings.Builder
+	if err := v.tmpl.Execute(&b, vars); err != nil {
+		return nil, err
+	}
+	return b.String(), nil
+}
+
+func (r rule) matches(toolName string) bool {
+	if r.pattern == toolName {
+		return true
+	}
+	matched, err := path.Match(r.pattern, toolName)
+	return err == nil && matched
+}
+
+// Apply returns the arguments with defaults and fixed values applied. The
+// input map is not modified; it is returned as is when no rule matches.
+func (i *Injector) Apply(toolName string, args map[string]interface{}, vars Vars) (map[string]interface{}, error) {
+	if i == nil {
+		return args, nil
+	}
+	vars.Tool = toolName
+
+	var result map[string]interface{}
+	set := func(name string, v value) error {
+		rendered, err := v.render(vars)
+		if err != nil {
+			return fmt.Errorf("failed to render argument %q for %s: %w", name, toolName, err)
+		}
+		if result == nil {
+			result = make(map[string]interface{}, len(args)+1)
+			for k, existing := range args {
+				result[k] = existing
+			}
+		}
+		result[name] = rendered
+		return nil
+	}
+
+	for _, r := range i.rules {
+		if !r.matches(toolName) {
+			continue
+		}
+		for name, v := range r.defaults {
+			if _, provided := args[name]; provided {
+				continue
+			}
+			if err := set(name, v); err != nil {
+				return nil, err
+			}
+		}
+		for name, v := range r.fixed {
+			if err := set(name, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if result == nil {
+		return args, nil
+	}
+	return result, nil
+}
+
+// AdjustSchema returns a copy of a tool input schema reflecting the rules:
+// fixed arguments are removed, defaulted arguments become optional and carry
+// their default when it is not a template.
+func (i *Injector) AdjustSchema(toolName string, schema interface{}) interface{} {
+	if i == nil {
+		return schema
+	}
+	original, ok := schema.(map[string]interface{})
+	if !ok {
+		return schema
+	}
+
+	var adjusted, properties map[string]interface{}
+	var required []string
+	ensureCopy := func() {
+		if adjusted != nil {
+			return
+		}
+		adjusted = make(map[string]interface{}, len(original))
+		for k, v := range original {
+			adjusted[k] = v
+		}
+		properties = make(map[string]interface{})
+		if props, ok := original["properties"].(map[string]interface{}); ok {
+			for k, v := range props {
+				properties[k] = v
+			}
+		}
+		adjusted["properties"] = properties
+		required = requiredFields(original["required"])
+	}
+
+	for _, r := range i.rules {
+		if !r.matches(toolName) {
+			continue
+		}
+		for name, v := range r.defaults {
+			ensureCopy()
+			required = remove(required, name)
+			if v.tmpl != nil {
+				continue
+			}
+			if prop, ok := properties[name].(map[string]interface{}); ok {
+				withDefault := make(map[string]interface{}, len(prop)+1)
+				for k, pv := range prop {
+					withDefault[k] = pv
+				}
+				withDefault["default"] = v.raw
+				properties[name] = withDefault
+			}
+		}
+		for name := range r.fixed {
+			ensureCopy()
+			delete(properties, name)
+			required = remove(required, name)
+		}
+	}
+
+	if adjusted == nil {
+		return schema
+	}
+	if _, had := original["required"]; had {
+		adjusted["required"] = required
+	}
+	return adjusted
+}
+
+func requiredFields(v interface{}) []string {
+	switch fields := v.(type) {
+	case []string:
+		return append([]string{}, fields...)
+	case []interface{}:
+		var names []string
+		for _, f := range fields {
+			if s, ok := f.(string); ok {
+				names = append(names, s)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+func remove(names []string, name string) []string {
+	result := names[:0]
+	for _, n := range names {
+		if n != name {
+			result = append(result, n)
+		}
+	}
+	return result
+}
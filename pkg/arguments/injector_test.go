@@ -0,0 +1,97 @@
+package arguments
+
+import (
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testInjector(t *testing.T) *Injector {
+	t.Helper()
+	injector, err := NewInjector([]config.ToolArgumentsConfig{
+		{
+			Tool:     "orders_*",
+			Defaults: map[string]interface{}{"page_size": 50},
+			Fixed:    map[string]interface{}{"caller": "mcp-gateway"},
+		},
+		{
+			Tool:     "orders_service_list",
+			Defaults: map[string]interface{}{"tenant": `{{index .Metadata "tenant-id"}}`},
+			Fixed:    map[string]interface{}{"trace": "{{.Tool}}/{{.SessionID}}"},
+		},
+	})
+	require.NoError(t, err)
+	return injector
+}
+
+func TestInjector_Apply(t *testing.T) {
+	injector := testInjector(t)
+	vars := Vars{SessionID: "s1", Metadata: map[string]string{"tenant-id": "acme"}}
+
+	args := map[string]interface{}{"page_size": 10, "caller": "agent"}
+	result, err := injector.Apply("orders_service_list", args, vars)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"page_size": 10,
+		"caller":    "mcp-gateway",
+		"tenant":    "acme",
+		"trace":     "orders_service_list/s1",
+	}, result)
+
+	// The client's arguments are not modified
+	assert.Equal(t, map[string]interface{}{"page_size": 10, "caller": "agent"}, args)
+
+	// Missing arguments get defaults, even when the client sent none
+	result, err = injector.Apply("orders_service_get", nil, vars)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"page_size": 50, "caller": "mcp-gateway"}, result)
+
+	// Tools without rules are untouched
+	result, err = injector.Apply("users_service_get", nil, vars)
+	require.NoError(t, err)
+	assert.Nil(t, result)
+
+	// A nil injector is a no-op
+	var none *Injector
+	result, err = none.Apply("orders_service_get", args, vars)
+	require.NoError(t, err)
+	assert.Equal(t, args, result)
+}
+
+func TestInjector_AdjustSchema(t *testing.T) {
+	injector := testInjector(t)
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"page_size": map[string]interface{}{"type": "integer"},
+			"caller":    map[string]interface{}{"type": "string"},
+			"tenant":    map[string]interface{}{"type": "string"},
+			"filter":    map[string]interface{}{"type": "string"},
+		},
+		"required": []string{"page_size", "caller", "tenant", "filter"},
+	}
+
+	adjusted := injector.AdjustSchema("orders_service_list", schema).(map[string]interface{})
+	properties := adjusted["properties"].(map[string]interface{})
+	assert.NotContains(t, properties, "caller")
+	assert.Equal(t, 50, properties["page_size"].(map[string]interface{})["default"])
+	assert.NotContains(t, properties["tenant"], "default", "templated defaults are not advertised")
+	assert.Equal(t, []string{"filter"}, adjusted["required"])
+
+	// The original schema is left intact
+	assert.Contains(t, schema["properties"], "caller")
+	assert.Equal(t, []string{"page_size", "caller", "tenant", "filter"}, schema["required"])
+	assert.NotContains(t, schema["properties"].(map[string]interface{})["page_size"], "default")
+
+	assert.Equal(t, schema, injector.AdjustSchema("users_service_get", schema))
+}
+
+func TestNewInjector_InvalidRules(t *testing.T) {
+	_, err := NewInjector([]config.ToolArgumentsConfig{{Tool: "orders_[", Defaults: map[string]interface{}{"a": 1}}})
+	assert.Error(t, err)
+
+	_, err = NewInjector([]config.ToolArgumentsConfig{{Tool: "orders_*", Fixed: map[string]interface{}{"a": "{{.Tool"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,211 @@
+package arguments
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+)
+
+// ErrRejected is returned (wrapped) by sanitizers that refuse an argument
+var ErrRejected = errors.New("argument rejected")
+
+// Sanitizer checks and cleans the arguments of a tool call before they reach
+// the upstream. It returns the arguments to send, without modifying the input
+// map, or an error wrapping ErrRejected to refuse the call.
+type Sanitizer interface {
+	Sanitize(toolName string, args map[string]interface{}) (map[string]interface{}, error)
+}
+
+// sanitizeRule is a compiled config.ToolSanitizerConfig
+type sanitizeRule struct {
+	pattern        string
+	fields         map[string]bool
+	maxLength      int
+	allowed        *regexp.Regexp
+	allowedChar    *regexp.Regexp
+	deny           []*regexp.Regexp
+	stripInvisible bool
+	strip          bool
+}
+
+// RuleSanitizer applies the configured sanitizer rules. The rules can be
+// replaced at runtime with Update.
+type RuleSanitizer struct {
+	rules atomic.Pointer[[]sanitizeRule]
+}
+
+// NewSanitizer compiles the configured rules. Every matching rule applies,
+// in order, to the output of the previous one.
+func NewSanitizer(configs []config.ToolSanitizerConfig) (*RuleSanitizer, error) {
+	sanitizer := &RuleSanitizer{}
+	if err := sanitizer.Update(configs); err != nil {
+		return nil, err
+	}
+	return sanitizer, nil
+}
+
+// Update compiles the rules and atomically replaces the current ones. On
+// error the current rules stay in effect.
+func (s *RuleSanitizer) Update(configs []config.ToolSanitizerConfig) error {
+	rules, err := compileRules(configs)
+	if err != nil {
+		return err
+	}
+	s.rules.Store(&rules)
+	return nil
+}
+
+// compileRules compiles the configured rules
+func compileRules(configs []config.ToolSanitizerConfig) ([]sanitizeRule, error) {
+	rules := make([]sanitizeRule, 0, len(configs))
+	for _, cfg := range configs {
+		if _, err := path.Match(cfg.Tool, ""); err != nil {
+			return nil, fmt.Errorf("invalid tool pattern %q: %w", cfg.Tool, err)
+		}
+		r := sanitizeRule{
+			pattern:        cfg.Tool,
+			maxLength:      cfg.MaxLength,
+			stripInvisible: cfg.StripInvisible,
+			strip:          cfg.Action == config.SanitizeStrip,
+		}
+		if len(cfg.Fields) > 0 {
+			r.fields = make(map[string]bool, len(cfg.Fields))
+			for _, field := range cfg.Fields {
+				r.fields[field] = true
+			}
+		}
+		if cfg.AllowedChars != "" {
+			allowed, err := regexp.Compile(`^(?:` + cfg.AllowedChars + `)*$`)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowed_chars for %s: %w", cfg.Tool, err)
+			}
+			r.allowed = allowed
+			// Matches a single allowed character, used when stripping
+			r.allowedChar = regexp.MustCompile(`^(?:` + cfg.AllowedChars + `)$`)
+		}
+		for _, expr := range cfg.Deny {
+			deny, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid deny pattern for %s: %w", cfg.Tool, err)
+			}
+			r.deny = append(r.deny, deny)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func (r sanitizeRule) matches(toolName string) bool {
+	if r.pattern == toolName {
+		return true
+	}
+	matched, err := path.Match(r.pattern, toolName)
+	return err == nil && matched
+}
+
+// Sanitize implements Sanitizer
+func (s *RuleSanitizer) Sanitize(toolName string, args map[string]interface{}) (map[string]interface{}, error) {
+	if s == nil || args == nil {
+		return args, nil
+	}
+	for _, r := range *s.rules.Load() {
+		if !r.matches(toolName) {
+			continue
+		}
+		sanitized, err := r.walk("", args)
+		if err != nil {
+			return nil, err
+		}
+		args = sanitized.(map[string]interface{})
+	}
+	return args, nil
+}
+
+// walk returns a copy of v with the selected string values sanitized
+func (r sanitizeRule) walk(fieldPath string, v interface{}) (interface{}, error) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for k, child := range value {
+			childPath := k
+			if fieldPath != "" {
+				childPath = fieldPath + "." + k
+			}
+			sanitized, err := r.walk(childPath, child)
+			if err != nil {
+				return nil, err
+			}
+			result[k] = sanitized
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, child := range value {
+			sanitized, err := r.walk(fieldPath, child)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = sanitized
+		}
+		return result, nil
+	case string:
+		if r.fields != nil && !r.fields[fieldPath] {
+			return value, nil
+		}
+		return r.sanitize(fieldPath, value)
+	}
+	return v, nil
+}
+
+// sanitize checks one string value, or cleans it when the rule strips
+func (r sanitizeRule) sanitize(field, value string) (string, error) {
+	if r.stripInvisible {
+		value = strings.Map(func(c rune) rune {
+			if c == '\n' || c == '\t' || c == '\r' {
+				return c
+			}
+			if unicode.IsControl(c) || unicode.Is(unicode.Cf, c) {
+				return -1
+			}
+			return c
+		}, value)
+	}
+
+	for _, deny := range r.deny {
+		if !deny.MatchString(value) {
+			continue
+		}
+		if !r.strip {
+			return "", fmt.Errorf("%w: %s matches a denied pattern", ErrRejected, field)
+		}
+		value = deny.ReplaceAllString(value, "")
+	}
+
+	if r.allowed != nil && !r.allowed.MatchString(value) {
+		if !r.strip {
+			return "", fmt.Errorf("%w: %s contains disallowed characters", ErrRejected, field)
+		}
+		var b strings.Builder
+		for _, c := range value {
+			if r.allowedChar.MatchString(string(c)) {
+				b.WriteRune(c)
+			}
+		}
+		value = b.String()
+	}
+
+	if r.maxLength > 0 && utf8.RuneCountInString(value) > r.maxLength {
+		if !r.strip {
+			return "", fmt.Errorf("%w: %s is longer than %d characters", ErrRejected, field, r.maxLength)
+		}
+		value = string([]rune(value)[:r.maxLength])
+	}
+	return value, nil
+}
@@ -0,0 +1,89 @@
+package arguments
+
+import (
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizer_Reject(t *testing.T) {
+	s, err := NewSanitizer([]config.ToolSanitizerConfig{{
+		Tool:         "tickets_*",
+		Fields:       []string{"title", "comments.body"},
+		MaxLength:    10,
+		AllowedChars: `[\p{L}\p{N} ]`,
+		Deny:         []string{`(?i)system prompt`},
+	}})
+	require.NoError(t, err)
+
+	args := map[string]interface{}{
+		"title":    "Printer",
+		"comments": []interface{}{map[string]interface{}{"body": "broken", "raw": "<script>"}},
+		"other":    "{not checked}",
+	}
+	sanitized, err := s.Sanitize("tickets_create", args)
+	require.NoError(t, err)
+	assert.Equal(t, args, sanitized)
+
+	tests := map[string]map[string]interface{}{
+		"too long":   {"title": "Printer is on fire"},
+		"disallowed": {"title": "<b>x</b>"},
+		"denied":     {"comments": []interface{}{map[string]interface{}{"body": "System Prompt"}}},
+	}
+	for name, args := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := s.Sanitize("tickets_create", args)
+			assert.ErrorIs(t, err, ErrRejected)
+		})
+	}
+
+	// Other tools are not checked
+	_, err = s.Sanitize("users_get", map[string]interface{}{"title": "Printer is on fire"})
+	assert.NoError(t, err)
+}
+
+func TestSanitizer_Strip(t *testing.T) {
+	s, err := NewSanitizer([]config.ToolSanitizerConfig{{
+		Tool:           "*",
+		MaxLength:      12,
+		AllowedChars:   `[a-z ]`,
+		Deny:           []string{`ignore previous instructions\s*`},
+		StripInvisible: true,
+		Action:         config.SanitizeStrip,
+	}})
+	require.NoError(t, err)
+
+	args := map[string]interface{}{
+		"query": "ignore\u200b previous instructions find ME now please",
+		"limit": float64(5),
+	}
+	sanitized, err := s.Sanitize("search", args)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"query": "find  now pl", "limit": float64(5)}, sanitized)
+	// The input is not modified
+	assert.Equal(t, "ignore\u200b previous instructions find ME now please", args["query"])
+}
+
+func TestNewSanitizer_InvalidPattern(t *testing.T) {
+	_, err := NewSanitizer([]config.ToolSanitizerConfig{{Tool: "*", Deny: []string{"("}}})
+	assert.Error(t, err)
+}
+
+func TestSanitizer_Update(t *testing.T) {
+	s, err := NewSanitizer(nil)
+	require.NoError(t, err)
+	args := map[string]interface{}{"query": "drop table users"}
+	_, err = s.Sanitize("search", args)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Update([]config.ToolSanitizerConfig{{Tool: "search", Deny: []string{`(?i)drop table`}}}))
+	_, err = s.Sanitize("search", args)
+	assert.ErrorIs(t, err, ErrRejected)
+
+	// Invalid rules leave the current ones in effect
+	assert.Error(t, s.Update([]config.ToolSanitizerConfig{{Tool: "*", Deny: []string{"("}}}))
+	_, err = s.Sanitize("search", args)
+	assert.ErrorIs(t, err, ErrRejected)
+}
@@ -0,0 +1,292 @@
+// Package audit writes an append-only log of tool calls as JSON lines.
+//
+// Records can be hash-chained: each record carries the hash of the previous
+// one, so removing, reordering or editing a record breaks the chain. With a
+// signing key every hash is also signed with Ed25519, so a verifier holding
+// only the public key can prove the log was written by the gateway and not
+// altered since. Verify checks a log file.
+package audit
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+)
+
+// Outcomes of an audited call
+const (
+	OutcomeOK       = "ok"
+	OutcomeError    = "error"
+	OutcomeCanceled = "canceled"
+)
+
+// Record is one audited tool call
+type Record struct {
+	Seq       uint64    `json:"seq"`
+	Time      time.Time `json:"time"`
+	SessionID string    `json:"sessionId"`
+	Tool      string    `json:"tool"`
+	// Arguments are only kept with include_arguments; their hash always is
+	Arguments       json.RawMessage `json:"arguments,omitempty"`
+	ArgumentsSHA256 string          `json:"argumentsSha256"`
+	Outcome         string          `json:"outcome"`
+	Error           string          `json:"error,omitempty"`
+	DurationMs      int64           `json:"durationMs"`
+
+	// Chain fields, empty when chaining is off
+	PrevHash  string `json:"prevHash,omitempty"`
+	Hash      string `json:"hash,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// digest returns the chain hash of the record: SHA-256 over its JSON encoding
+// without the hash and signature, which includes the previous hash
+func (r Record) digest() ([]byte, error) {
+	r.Hash = ""
+	r.Signature = ""
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
+
+// Log appends records to an audit file
+type Log struct {
+	includeArguments bool
+	chain            bool
+	key              ed25519.PrivateKey
+
+	mu       sync.Mutex
+	file     *os.File
+	seq      uint64
+	lastHash string
+}
+
+// Open opens (or creates) the audit file. When chaining, the chain continues
+// from the last record already in the file.
+func Open(cfg config.AuditConfig) (*Log, error) {
+	l := &Log{
+		includeArguments: cfg.IncludeArguments,
+		chain:            cfg.Chain || cfg.SigningKeyFile != "",
+	}
+	if cfg.SigningKeyFile != "" {
+		key, err := LoadPrivateKey(cfg.SigningKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		l.key = key
+	}
+
+	last, err := lastRecord(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	if last != nil {
+		l.seq = last.Seq
+		l.lastHash = last.Hash
+	}
+
+	file, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	l.file = file
+	return l, nil
+}
+
+// Append completes the record (sequence number, argument hash, chain fields)
+// and writes it. The caller sets the call fields and the raw arguments.
+func (l *Log) Append(record Record) error {
+	if l == nil {
+		return nil
+	}
+	sum := sha256.Sum256(record.Arguments)
+	record.ArgumentsSHA256 = hex.EncodeToString(sum[:])
+	if !l.includeArguments {
+		record.Arguments = nil
+	} else if len(record.Arguments) == 0 || !json.Valid(record.Arguments) {
+		quoted, _ := json.Marshal(string(record.Arguments))
+		record.Arguments = quoted
+	}
+	record.Time = record.Time.UTC()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record.Seq = l.seq + 1
+	if l.chain {
+		record.PrevHash = l.lastHash
+		digest, err := record.digest()
+		if err != nil {
+			return fmt.Errorf("failed to hash audit record: %w", err)
+		}
+		record.Hash = hex.EncodeToString(digest)
+		if l.key != nil {
+			record.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(l.key, digest))
+		}
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	l.seq = record.Seq
+	l.lastHash = record.Hash
+	return nil
+}
+
+// Close closes the audit file
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// readRecords calls fn for each record of the audit file with its line number
+func readRecords(path string, fn func(line int, record Record) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("line %d: invalid record: %w", line, err)
+		}
+		if err := fn(line, record); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// lastRecord returns the last record of the audit file, or nil if there is none
+func lastRecord(path string) (*Record, error) {
+	var last *Record
+	err := readRecords(path, func(_ int, record Record) error {
+		last = &record
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return last, nil
+}
+
+// Verify checks the hash chain of the audit file and, with a public key, the
+// signature of every record. It returns the number of records verified, or
+// an error naming the first line that does not verify.
+func Verify(path string, publicKey ed25519.PublicKey) (int, error) {
+	var (
+		count    int
+		seq      uint64
+		lastHash string
+	)
+	err := readRecords(path, func(line int, record Record) error {
+		if record.Seq != seq+1 {
+			return fmt.Errorf("line %d: sequence %d follows %d", line, record.Seq, seq)
+		}
+		if record.Hash == "" {
+			return fmt.Errorf("line %d: record is not chained", line)
+		}
+		if record.PrevHash != lastHash {
+			return fmt.Errorf("line %d: previous hash does not match record %d", line, seq)
+		}
+		digest, err := record.digest()
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if hex.EncodeToString(digest) != record.Hash {
+			return fmt.Errorf("line %d: hash mismatch, record was modified", line)
+		}
+		if publicKey != nil {
+			signature, err := base64.StdEncoding.DecodeString(record.Signature)
+			if err != nil || !ed25519.Verify(publicKey, digest, signature) {
+				return fmt.Errorf("line %d: invalid signature", line)
+			}
+		}
+		seq = record.Seq
+		lastHash = record.Hash
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// LoadPrivateKey reads a PEM encoded PKCS #8 Ed25519 private key
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit signing key: %w", err)
+	}
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("audit signing key must be an Ed25519 key")
+	}
+	return private, nil
+}
+
+// LoadPublicKey reads a PEM encoded PKIX Ed25519 public key
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit public key: %w", err)
+	}
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("audit public key must be an Ed25519 key")
+	}
+	return public, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+	return block, nil
+}
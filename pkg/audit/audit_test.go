@@ -0,0 +1,131 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeys generates an Ed25519 key pair and writes it as PEM files
+func writeKeys(t *testing.T, dir string) (privatePath, publicPath string) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+
+	privatePath = filepath.Join(dir, "audit.key")
+	publicPath = filepath.Join(dir, "audit.pub")
+	require.NoError(t, os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0o600))
+	require.NoError(t, os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o644))
+	return privatePath, publicPath
+}
+
+func appendCalls(t *testing.T, cfg config.AuditConfig, tools ...string) {
+	t.Helper()
+	l, err := Open(cfg)
+	require.NoError(t, err)
+	for _, tool := range tools {
+		require.NoError(t, l.Append(Record{
+			Time:      time.Now(),
+			SessionID: "s1",
+			Tool:      tool,
+			Arguments: []byte(`{"id":1}`),
+			Outcome:   OutcomeOK,
+		}))
+	}
+	require.NoError(t, l.Close())
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestLog_SignedChainVerifies(t *testing.T) {
+	dir := t.TempDir()
+	privatePath, publicPath := writeKeys(t, dir)
+	cfg := config.AuditConfig{Enabled: true, Path: filepath.Join(dir, "audit.jsonl"), SigningKeyFile: privatePath}
+
+	appendCalls(t, cfg, "users_get", "users_delete")
+	// Reopening continues the chain
+	appendCalls(t, cfg, "users_get")
+
+	public, err := LoadPublicKey(publicPath)
+	require.NoError(t, err)
+	count, err := Verify(cfg.Path, public)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// Arguments are only kept as a hash by default
+	var record Record
+	require.NoError(t, json.Unmarshal([]byte(readLines(t, cfg.Path)[2]), &record))
+	assert.Equal(t, uint64(3), record.Seq)
+	assert.Empty(t, record.Arguments)
+	assert.NotEmpty(t, record.ArgumentsSHA256)
+	assert.NotEmpty(t, record.Signature)
+
+	// A different key does not verify the signatures
+	otherPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, err = Verify(cfg.Path, otherPublic)
+	assert.ErrorContains(t, err, "line 1: invalid signature")
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.AuditConfig{Enabled: true, Path: filepath.Join(dir, "audit.jsonl"), Chain: true, IncludeArguments: true}
+	appendCalls(t, cfg, "a", "b", "c")
+	lines := readLines(t, cfg.Path)
+
+	count, err := Verify(cfg.Path, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	tests := []struct {
+		name   string
+		lines  []string
+		errMsg string
+	}{
+		{"edited", []string{lines[0], strings.Replace(lines[1], `"tool":"b"`, `"tool":"x"`, 1), lines[2]}, "line 2: hash mismatch"},
+		{"removed", []string{lines[0], lines[2]}, "line 2: sequence 3 follows 1"},
+		{"truncated head", []string{lines[1], lines[2]}, "line 1: sequence 2 follows 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "_")+".jsonl")
+			require.NoError(t, os.WriteFile(path, []byte(strings.Join(tt.lines, "\n")+"\n"), 0o600))
+			_, err := Verify(path, nil)
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestVerify_UnchainedLog(t *testing.T) {
+	cfg := config.AuditConfig{Enabled: true, Path: filepath.Join(t.TempDir(), "audit.jsonl"), IncludeArguments: true}
+	appendCalls(t, cfg, "a")
+
+	var record Record
+	require.NoError(t, json.Unmarshal([]byte(readLines(t, cfg.Path)[0]), &record))
+	assert.JSONEq(t, `{"id":1}`, string(record.Arguments))
+	assert.Empty(t, record.Hash)
+
+	_, err := Verify(cfg.Path, nil)
+	assert.ErrorContains(t, err, "not chained")
+}
@@ -0,0 +1,289 @@
+// Package auth validates bearer tokens (JWT) and maps their claims to gRPC
+// metadata, so upstreams receive identity derived by the gateway rather than
+// headers chosen by the client.
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+)
+
+// Errors returned by Verify
+var (
+	ErrNoToken      = errors.New("no bearer token")
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// Claims are the claims of a validated token
+type Claims map[string]interface{}
+
+// Verifier validates JWTs signed with an HMAC secret or a public key
+type Verifier struct {
+	config    config.AuthConfig
+	secret    []byte
+	publicKey crypto.PublicKey
+	now       func() time.Time
+}
+
+// NewVerifier loads the configured keys
+func NewVerifier(cfg config.AuthConfig) (*Verifier, error) {
+	v := &Verifier{config: cfg, now: time.Now}
+	if cfg.HMACSecretEnv != "" {
+		secret := os.Getenv(cfg.HMACSecretEnv)
+		if secret == "" {
+			return nil, fmt.Errorf("environment variable %s is empty", cfg.HMACSecretEnv)
+		}
+		v.secret = []byte(secret)
+	}
+	if cfg.PublicKeyFile != "" {
+		data, err := os.ReadFile(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public key: %w", err)
+		}
+		key, err := parsePublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key %s: %w", cfg.PublicKeyFile, err)
+		}
+		v.publicKey = key
+	}
+	return v, nil
+}
+
+func parsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+		return cert.PublicKey, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// VerifyAuthorization validates the bearer token of an Authorization header value
+func (v *Verifier) VerifyAuthorization(authorization string) (Claims, error) {
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok || token == "" {
+		return nil, ErrNoToken
+	}
+	return v.Verify(token)
+}
+
+// Verify checks the signature and the registered claims (exp, nbf, iss, aud)
+// of a compact JWT and returns its claims
+func (v *Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+	if err := v.verifySignature(header.Alg, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+func decodeSegment(segment string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (v *Verifier) verifySignature(alg, signingInput string, signature []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	var hash crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	switch alg[:2] {
+	case "HS":
+		if v.secret == nil {
+			return fmt.Errorf("no secret configured for %s", alg)
+		}
+		mac := hmac.New(hash.New, v.secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	case "RS", "PS":
+		key, ok := v.publicKey.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("no RSA public key configured for %s", alg)
+		}
+		hashed := digest(hash, signingInput)
+		if alg[:2] == "PS" {
+			return rsa.VerifyPSS(key, hash, hashed, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		return rsa.VerifyPKCS1v15(key, hash, hashed, signature)
+	case "ES":
+		key, ok := v.publicKey.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("no ECDSA public key configured for %s", alg)
+		}
+		// JWS encodes ECDSA signatures as r || s
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("signature mismatch")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest(hash, signingInput), r, s) {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+}
+
+func digest(hash crypto.Hash, input string) []byte {
+	h := hash.New()
+	h.Write([]byte(input))
+	return h.Sum(nil)
+}
+
+func (v *Verifier) checkClaims(claims Claims) error {
+	now := v.now()
+	leeway := v.config.Leeway
+	exp, ok := claims["exp"].(float64)
+	if !ok && v.config.RequireExp {
+		return errors.New("token has no expiry")
+	}
+	if ok && now.After(time.Unix(int64(exp), 0).Add(leeway)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(leeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token not valid yet")
+	}
+	if v.config.Issuer != "" && claims["iss"] != v.config.Issuer {
+		return errors.New("unexpected issuer")
+	}
+	if v.config.Audience != "" && !hasAudience(claims["aud"], v.config.Audience) {
+		return errors.New("unexpected audience")
+	}
+	return nil
+}
+
+func hasAudience(aud interface{}, expected string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == expected
+	case []interface{}:
+		for _, a := range aud {
+			if a == expected {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Lookup returns a claim as a metadata value. Nested claims use dots; lists
+// of scalars are joined with commas. Objects are not forwarded.
+func (c Claims) Lookup(name string) (string, bool) {
+	var value interface{} = map[string]interface{}(c)
+	for _, part := range strings.Split(name, ".") {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if value, ok = obj[part]; !ok {
+			return "", false
+		}
+	}
+
+	if list, ok := value.([]interface{}); ok {
+		items := make([]string, 0, len(list))
+		for _, item := range list {
+			s, ok := scalar(item)
+			if !ok {
+				return "", false
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), len(items) > 0
+	}
+	return scalar(value)
+}
+
+func scalar(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, v != ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+// ClaimHeaders maps claims to metadata according to the configured mappings;
+// claims that are missing are skipped
+func ClaimHeaders(claims Claims, mappings []config.ClaimHeaderConfig) map[string]string {
+	headers := make(map[string]string, len(mappings))
+	for _, mapping := range mappings {
+		if value, ok := claims.Lookup(mapping.Claim); ok {
+			headers[strings.ToLower(mapping.Header)] = value
+		}
+	}
+	return headers
+}
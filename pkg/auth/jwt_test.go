@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signingInput encodes the header and claims of a test token
+func signingInput(t *testing.T, alg string, claims map[string]interface{}) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func signHS256(t *testing.T, secret string, claims map[string]interface{}) string {
+	t.Helper()
+	input := signingInput(t, "HS256", claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifier_HMAC(t *testing.T) {
+	t.Setenv("TEST_JWT_SECRET", "s3cret")
+	v, err := NewVerifier(config.AuthConfig{
+		Enabled:       true,
+		HMACSecretEnv: "TEST_JWT_SECRET",
+		Issuer:        "https://idp.example.com",
+		Audience:      "ggrmcp",
+	})
+	require.NoError(t, err)
+
+	valid := map[string]interface{}{
+		"sub": "user-1",
+		"iss": "https://idp.example.com",
+		"aud": []string{"other", "ggrmcp"},
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	claims, err := v.VerifyAuthorization("Bearer " + signHS256(t, "s3cret", valid))
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims["sub"])
+
+	tests := map[string]string{
+		"wrong secret": "Bearer " + signHS256(t, "other", valid),
+		"expired": "Bearer " + signHS256(t, "s3cret", map[string]interface{}{
+			"iss": "https://idp.example.com", "aud": "ggrmcp", "exp": time.Now().Add(-time.Hour).Unix(),
+		}),
+		"wrong audience": "Bearer " + signHS256(t, "s3cret", map[string]interface{}{
+			"iss": "https://idp.example.com", "aud": "other",
+		}),
+		"unsigned":  "Bearer " + signingInput(t, "none", valid) + ".",
+		"malformed": "Bearer abc",
+	}
+	for name, authorization := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := v.VerifyAuthorization(authorization)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+
+	_, err = v.VerifyAuthorization("Basic dXNlcjpwYXNz")
+	assert.ErrorIs(t, err, ErrNoToken)
+}
+
+func TestVerifier_RequireExp(t *testing.T) {
+	t.Setenv("TEST_JWT_SECRET", "s3cret")
+	cfg := config.Default().Server.Security.Auth
+	cfg.Enabled = true
+	cfg.HMACSecretEnv = "TEST_JWT_SECRET"
+	v, err := NewVerifier(cfg)
+	require.NoError(t, err)
+
+	// Tokens without exp are rejected by default
+	_, err = v.Verify(signHS256(t, "s3cret", map[string]interface{}{"sub": "user-1"}))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = v.Verify(signHS256(t, "s3cret", map[string]interface{}{"sub": "user-1", "exp": time.Now().Add(time.Hour).Unix()}))
+	assert.NoError(t, err)
+
+	cfg.RequireExp = false
+	v, err = NewVerifier(cfg)
+	require.NoError(t, err)
+	_, err = v.Verify(signHS256(t, "s3cret", map[string]interface{}{"sub": "user-1"}))
+	assert.NoError(t, err)
+}
+
+func TestVerifier_ECDSAPublicKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	v, err := NewVerifier(config.AuthConfig{Enabled: true, PublicKeyFile: keyFile})
+	require.NoError(t, err)
+
+	input := signingInput(t, "ES256", map[string]interface{}{"sub": "svc"})
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest(crypto.SHA256, input))
+	require.NoError(t, err)
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	claims, err := v.Verify(input + "." + base64.RawURLEncoding.EncodeToString(signature))
+	require.NoError(t, err)
+	assert.Equal(t, "svc", claims["sub"])
+
+	// HMAC tokens are rejected when no secret is configured
+	_, err = v.Verify(signHS256(t, "", map[string]interface{}{"sub": "svc"}))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestClaimHeaders(t *testing.T) {
+	claims := Claims{
+		"sub":    "user-1",
+		"org":    map[string]interface{}{"id": "acme"},
+		"roles":  []interface{}{"admin", "dev"},
+		"level":  float64(3),
+		"nested": map[string]interface{}{"a": "b"},
+	}
+	headers := ClaimHeaders(claims, []config.ClaimHeaderConfig{
+		{Claim: "sub", Header: "X-User-Id"},
+		{Claim: "org.id", Header: "x-tenant-id"},
+		{Claim: "roles", Header: "x-roles"},
+		{Claim: "level", Header: "x-level"},
+		{Claim: "nested", Header: "x-nested"},
+		{Claim: "missing", Header: "x-missing"},
+	})
+	assert.Equal(t, map[string]string{
+		"x-user-id":   "user-1",
+		"x-tenant-id": "acme",
+		"x-roles":     "admin,dev",
+		"x-level":     "3",
+	}, headers)
+}
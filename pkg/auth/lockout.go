@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+)
+
+// maxTrackedKeys bounds the keys a Lockout keeps before it prunes idle ones
+const maxTrackedKeys = 10000
+
+// LockoutStats reports brute-force protection counters
+type LockoutStats struct {
+	// Authentication failures recorded
+	Failures int64 `json:"failures"`
+	// Lockouts started
+	Lockouts int64 `json:"lockouts"`
+	// Requests rejected because a key was locked out
+	Rejected int64 `json:"rejected"`
+	// Keys currently locked out
+	Locked int `json:"locked"`
+}
+
+// lockoutEntry tracks the failures of one key
+type lockoutEntry struct {
+	failures    int
+	windowStart time.Time
+	lockedUntil time.Time
+	// lockouts is the number of consecutive lockouts, which sets the next duration
+	lockouts int
+}
+
+// Lockout tracks authentication failures per key (client IP or credential)
+// and locks a key out after too many failures, with exponential backoff
+type Lockout struct {
+	config config.LockoutConfig
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*lockoutEntry
+	stats   LockoutStats
+}
+
+// NewLockout creates a lockout tracker
+func NewLockout(cfg config.LockoutConfig) *Lockout {
+	return &Lockout{
+		config:  cfg,
+		now:     time.Now,
+		entries: make(map[string]*lockoutEntry),
+	}
+}
+
+// Locked reports whether any of the keys is locked out and for how long.
+// A locked request counts as rejected.
+func (l *Lockout) Locked(keys ...string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	var wait time.Duration
+	for _, key := range keys {
+		if entry, ok := l.entries[key]; ok && entry.lockedUntil.After(now) {
+			wait = max(wait, entry.lockedUntil.Sub(now))
+		}
+	}
+	if wait > 0 {
+		l.stats.Rejected++
+	}
+	return wait, wait > 0
+}
+
+// Failure records a failed attempt for each key and returns the longest
+// lockout it started, or zero
+func (l *Lockout) Failure(keys ...string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if len(l.entries) >= maxTrackedKeys {
+		l.prune(now)
+	}
+	l.stats.Failures++
+
+	var started time.Duration
+	for _, key := range keys {
+		entry, ok := l.entries[key]
+		if !ok {
+			entry = &lockoutEntry{}
+			l.entries[key] = entry
+		}
+		// Consecutive lockouts are forgotten after a quiet period
+		if entry.lockouts > 0 && now.Sub(entry.lockedUntil) > l.config.MaxDuration {
+			entry.lockouts = 0
+		}
+		if now.Sub(entry.windowStart) > l.config.Window {
+			entry.windowStart = now
+			entry.failures = 0
+		}
+		entry.failures++
+		if entry.failures < l.config.MaxFailures {
+			continue
+		}
+
+		duration := l.config.Duration << entry.lockouts
+		if duration > l.config.MaxDuration || duration <= 0 {
+			duration = l.config.MaxDuration
+		}
+		entry.lockouts++
+		entry.failures = 0
+		entry.lockedUntil = now.Add(duration)
+		l.stats.Lockouts++
+		started = max(started, duration)
+	}
+	return started
+}
+
+// Success forgets the failures of the keys
+func (l *Lockout) Success(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		if entry, ok := l.entries[key]; ok && !entry.lockedUntil.After(l.now()) {
+			delete(l.entries, key)
+		}
+	}
+}
+
+// prune drops keys that are neither locked nor within a failure window
+func (l *Lockout) prune(now time.Time) {
+	for key, entry := range l.entries {
+		if now.Sub(entry.windowStart) > l.config.Window && now.Sub(entry.lockedUntil) > l.config.MaxDuration {
+			delete(l.entries, key)
+		}
+	}
+}
+
+// Stats returns the lockout counters
+func (l *Lockout) Stats() LockoutStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := l.stats
+	now := l.now()
+	for _, entry := range l.entries {
+		if entry.lockedUntil.After(now) {
+			stats.Locked++
+		}
+	}
+	return stats
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockout_ExponentialBackoff(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := NewLockout(config.LockoutConfig{
+		Enabled:     true,
+		MaxFailures: 3,
+		Window:      time.Minute,
+		Duration:    10 * time.Second,
+		MaxDuration: 30 * time.Second,
+	})
+	l.now = func() time.Time { return now }
+
+	fail := func() time.Duration {
+		var started time.Duration
+		for i := 0; i < 3; i++ {
+			started = l.Failure("ip:1.2.3.4")
+		}
+		return started
+	}
+
+	assert.Equal(t, 10*time.Second, fail())
+	wait, locked := l.Locked("ip:1.2.3.4", "ip:5.6.7.8")
+	assert.True(t, locked)
+	assert.Equal(t, 10*time.Second, wait)
+	_, locked = l.Locked("ip:5.6.7.8")
+	assert.False(t, locked)
+
+	// Each further lockout doubles, up to the maximum
+	now = now.Add(11 * time.Second)
+	assert.Equal(t, 20*time.Second, fail())
+	now = now.Add(21 * time.Second)
+	assert.Equal(t, 30*time.Second, fail())
+
+	// Success does not lift an active lockout
+	l.Success("ip:1.2.3.4")
+	_, locked = l.Locked("ip:1.2.3.4")
+	assert.True(t, locked)
+
+	stats := l.Stats()
+	assert.Equal(t, int64(9), stats.Failures)
+	assert.Equal(t, int64(3), stats.Lockouts)
+	assert.Equal(t, int64(2), stats.Rejected)
+	assert.Equal(t, 1, stats.Locked)
+
+	// After a quiet period the backoff starts over
+	now = now.Add(time.Hour)
+	assert.Equal(t, 10*time.Second, fail())
+}
+
+func TestLockout_FailuresOutsideWindowAreForgotten(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := NewLockout(config.LockoutConfig{
+		Enabled:     true,
+		MaxFailures: 2,
+		Window:      time.Minute,
+		Duration:    time.Second,
+		MaxDuration: time.Minute,
+	})
+	l.now = func() time.Time { return now }
+
+	assert.Zero(t, l.Failure("credential:a"))
+	now = now.Add(2 * time.Minute)
+	assert.Zero(t, l.Failure("credential:a"))
+	l.Success("credential:a")
+	assert.Zero(t, l.Failure("credential:a"))
+	assert.Equal(t, 0, l.Stats().Locked)
+}
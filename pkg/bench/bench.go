@@ -0,0 +1,251 @@
+// Package bench drives synthetic tool calls through the gateway's HTTP
+// handler at a fixed rate and reports latency percentiles and error rates.
+// Calls go through the same middleware, session handling and tool pipeline
+// as client traffic, so the numbers include the gateway's own overhead. It
+// backs "grmcp bench", which gives operators capacity numbers before a
+// production rollout.
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+)
+
+// Options controls a benchmark run
+type Options struct {
+	// Tool called by every request
+	Tool string
+
+	// Arguments of every call
+	Arguments map[string]interface{}
+
+	// RPS is the rate at which calls are started
+	RPS int
+
+	// Duration of the run
+	Duration time.Duration
+
+	// Concurrency caps the calls in flight; calls due while the cap is
+	// reached are dropped and counted, so a slow upstream shows up as drops
+	// instead of a lower rate
+	Concurrency int
+}
+
+// Report is the outcome of a benchmark run
+type Report struct {
+	Tool     string        `json:"tool"`
+	Duration time.Duration `json:"duration"`
+
+	// Requests is the number of completed calls
+	Requests int `json:"requests"`
+	// Errors counts failed calls by reason, e.g. "http 429" or "tool error"
+	Errors map[string]int `json:"errors,omitempty"`
+	// Dropped is the number of calls not started because Concurrency calls
+	// were in flight
+	Dropped int `json:"dropped"`
+
+	ErrorRate  float64 `json:"errorRate"`
+	Throughput float64 `json:"throughput"`
+
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+// String returns the report formatted for command line output
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d calls in %s (%.1f/s), %d dropped\n", r.Tool, r.Requests, r.Duration.Round(time.Millisecond), r.Throughput, r.Dropped)
+	fmt.Fprintf(&b, "latency: p50 %s, p90 %s, p99 %s, max %s\n", r.P50, r.P90, r.P99, r.Max)
+	fmt.Fprintf(&b, "errors: %.2f%%", r.ErrorRate*100)
+	reasons := make([]string, 0, len(r.Errors))
+	for reason := range r.Errors {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		fmt.Fprintf(&b, ", %s: %d", reason, r.Errors[reason])
+	}
+	return b.String()
+}
+
+// Run calls opts.Tool through handler at opts.RPS for opts.Duration, or until
+// ctx is done, and reports the results. A session is initialized first;
+// failing to initialize it is an error.
+func Run(ctx context.Context, handler http.Handler, opts Options) (Report, error) {
+	if opts.RPS <= 0 {
+		return Report{}, errors.New("rps must be positive")
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	arguments := opts.Arguments
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+	sessionID, err := initialize(handler)
+	if err != nil {
+		return Report{}, err
+	}
+	body, err := json.Marshal(mcp.JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      mcp.RequestID{Value: 1},
+		Method:  "tools/call",
+		Params:  map[string]interface{}{"name": opts.Tool, "arguments": arguments},
+	})
+	if err != nil {
+		return Report{}, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		failures  = make(map[string]int)
+		dropped   int
+		wg        sync.WaitGroup
+	)
+	inFlight := make(chan struct{}, opts.Concurrency)
+	ticker := time.NewTicker(time.Second / time.Duration(opts.RPS))
+	defer ticker.Stop()
+	timer := time.NewTimer(opts.Duration)
+	defer timer.Stop()
+
+	start := time.Now()
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-timer.C:
+			break loop
+		case <-ticker.C:
+		}
+		select {
+		case inFlight <- struct{}{}:
+		default:
+			mu.Lock()
+			dropped++
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-inFlight }()
+			callStart := time.Now()
+			reason := call(handler, sessionID, body)
+			latency := time.Since(callStart)
+			mu.Lock()
+			defer mu.Unlock()
+			latencies = append(latencies, latency)
+			if reason != "" {
+				failures[reason]++
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report(opts.Tool, time.Since(start), latencies, failures, dropped), nil
+}
+
+// initialize opens the session used by the calls and returns its ID
+func initialize(handler http.Handler) (string, error) {
+	body, err := json.Marshal(mcp.JSONRPCRequest{JSONRPC: "2.0", ID: mcp.RequestID{Value: 0}, Method: "initialize"})
+	if err != nil {
+		return "", err
+	}
+	w := serve(handler, "", body)
+	if w.Code != http.StatusOK {
+		return "", fmt.Errorf("initialize failed: HTTP %d: %s", w.Code, strings.TrimSpace(w.Body.String()))
+	}
+	sessionID := w.Header().Get("Mcp-Session-Id")
+	if sessionID == "" {
+		return "", errors.New("initialize returned no session ID")
+	}
+	return sessionID, nil
+}
+
+// call performs one tool call and returns the failure reason, empty when
+// the call succeeded
+func call(handler http.Handler, sessionID string, body []byte) string {
+	w := serve(handler, sessionID, body)
+	if w.Code != http.StatusOK {
+		return fmt.Sprintf("http %d", w.Code)
+	}
+	var resp struct {
+		Error  *mcp.RPCError `json:"error"`
+		Result struct {
+			IsError bool `json:"isError"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		return "invalid response"
+	}
+	if resp.Error != nil {
+		return fmt.Sprintf("rpc %d", resp.Error.Code)
+	}
+	if resp.Result.IsError {
+		return "tool error"
+	}
+	return ""
+}
+
+// serve posts a JSON-RPC request to handler
+func serve(handler http.Handler, sessionID string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", sessionID)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+// report computes the statistics of a run
+func report(tool string, elapsed time.Duration, latencies []time.Duration, failures map[string]int, dropped int) Report {
+	r := Report{
+		Tool:     tool,
+		Duration: elapsed,
+		Requests: len(latencies),
+		Dropped:  dropped,
+	}
+	if len(failures) > 0 {
+		r.Errors = failures
+	}
+	if len(latencies) == 0 {
+		return r
+	}
+	errorCount := 0
+	for _, n := range failures {
+		errorCount += n
+	}
+	r.ErrorRate = float64(errorCount) / float64(len(latencies))
+	r.Throughput = float64(len(latencies)) / elapsed.Seconds()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	r.P50 = percentile(latencies, 0.50)
+	r.P90 = percentile(latencies, 0.90)
+	r.P99 = percentile(latencies, 0.99)
+	r.Max = latencies[len(latencies)-1]
+	return r
+}
+
+// percentile returns the p-th percentile of sorted latencies (nearest rank)
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(p*float64(len(sorted))+0.5) - 1
+	return sorted[max(0, min(i, len(sorted)-1))]
+}
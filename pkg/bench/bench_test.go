@@ -0,0 +1,87 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGateway answers initialize with a session and fails every third call
+type fakeGateway struct {
+	calls atomic.Int64
+}
+
+func (g *fakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req mcp.JSONRPCRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Method == "initialize" {
+		w.Header().Set("Mcp-Session-Id", "session-1")
+		_ = json.NewEncoder(w).Encode(mcp.JSONRPCResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{}})
+		return
+	}
+	if r.Header.Get("Mcp-Session-Id") != "session-1" || req.Params["name"] != "orders_get" {
+		http.Error(w, "unexpected call", http.StatusBadRequest)
+		return
+	}
+	result := mcp.ToolCallResult{IsError: g.calls.Add(1)%3 == 0}
+	_ = json.NewEncoder(w).Encode(mcp.JSONRPCResponse{JSONRPC: "2.0", ID: req.ID, Result: result})
+}
+
+func TestRun(t *testing.T) {
+	gateway := &fakeGateway{}
+
+	report, err := Run(context.Background(), gateway, Options{
+		Tool:        "orders_get",
+		RPS:         200,
+		Duration:    200 * time.Millisecond,
+		Concurrency: 10,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "orders_get", report.Tool)
+	assert.Greater(t, report.Requests, 10)
+	assert.Equal(t, int(gateway.calls.Load()), report.Requests)
+	assert.Equal(t, report.Requests/3, report.Errors["tool error"])
+	assert.InDelta(t, 1.0/3, report.ErrorRate, 0.1)
+	assert.LessOrEqual(t, report.P50, report.P99)
+	assert.LessOrEqual(t, report.P99, report.Max)
+}
+
+func TestRun_InitializeFailure(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+
+	_, err := Run(context.Background(), handler, Options{Tool: "orders_get", RPS: 10, Duration: time.Second})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "HTTP 503")
+}
+
+func TestReport(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	r := report("orders_get", 10*time.Second, latencies, map[string]int{"http 429": 5}, 2)
+
+	assert.Equal(t, 100, r.Requests)
+	assert.Equal(t, 2, r.Dropped)
+	assert.Equal(t, 0.05, r.ErrorRate)
+	assert.Equal(t, 10.0, r.Throughput)
+	assert.Equal(t, 50*time.Millisecond, r.P50)
+	assert.Equal(t, 90*time.Millisecond, r.P90)
+	assert.Equal(t, 99*time.Millisecond, r.P99)
+	assert.Equal(t, 100*time.Millisecond, r.Max)
+	assert.Contains(t, r.String(), "http 429: 5")
+}
@@ -0,0 +1,370 @@
+// Package breaker implements circuit breaking on the tool invoke path. Every
+// upstream and every tool on it has a circuit that tracks the rolling error
+// rate of its calls; once a circuit trips, calls fail fast with an OpenError
+// for a cooldown window instead of piling agent retries onto an unhealthy
+// backend.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/chaos"
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// State is the state of a circuit
+type State string
+
+const (
+	// StateClosed lets calls through and tracks their outcome
+	StateClosed State = "closed"
+	// StateOpen rejects calls until the cooldown has passed
+	StateOpen State = "open"
+	// StateHalfOpen lets a single probe call through
+	StateHalfOpen State = "half_open"
+)
+
+// Scopes of a circuit
+const (
+	ScopeUpstream = "upstream"
+	ScopeTool     = "tool"
+)
+
+// buckets is the number of slots the rolling window is divided into
+const buckets = 10
+
+// OpenError is returned for calls rejected by an open circuit
+type OpenError struct {
+	Scope      string
+	Name       string
+	Upstream   string
+	RetryAfter time.Duration
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("circuit open for %s %q, retry after %s", e.Scope, e.Name, e.RetryAfter.Round(time.Second))
+}
+
+// GRPCStatus reports rejected calls as UNAVAILABLE
+func (e *OpenError) GRPCStatus() *status.Status {
+	return status.New(codes.Unavailable, e.Error())
+}
+
+// CircuitStats reports the state of one circuit
+type CircuitStats struct {
+	Scope    string `json:"scope"`
+	Name     string `json:"name"`
+	Upstream string `json:"upstream"`
+	State    State  `json:"state"`
+	Requests int    `json:"requests"`
+	Failures int    `json:"failures"`
+	Trips    int64  `json:"trips"`
+	Rejected int64  `json:"rejected"`
+}
+
+// Breakers holds the circuits of all upstreams
+type Breakers struct {
+	config   config.CircuitBreakerConfig
+	failures map[codes.Code]bool
+	logger   *zap.Logger
+	now      func() time.Time
+
+	mu       sync.Mutex
+	circuits map[circuitKey]*circuit
+}
+
+type circuitKey struct {
+	upstream string
+	scope    string
+	name     string
+}
+
+// New creates the circuit breakers from the given configuration
+func New(cfg config.CircuitBreakerConfig, logger *zap.Logger) (*Breakers, error) {
+	parsed, err := chaos.ParseCodes(cfg.FailureCodes)
+	if err != nil {
+		return nil, err
+	}
+	failures := make(map[codes.Code]bool, len(parsed))
+	for _, code := range parsed {
+		failures[code] = true
+	}
+	return &Breakers{
+		config:   cfg,
+		failures: failures,
+		logger:   logger.Named("breaker"),
+		now:      time.Now,
+		circuits: make(map[circuitKey]*circuit),
+	}, nil
+}
+
+// Wrap guards calls to the upstream served by inner
+func (b *Breakers) Wrap(inner grpc.ServiceDiscoverer, upstream string) *Guard {
+	return &Guard{ServiceDiscoverer: inner, breakers: b, upstream: upstream}
+}
+
+// circuitFor returns the circuit for the key, creating it on first use
+func (b *Breakers) circuitFor(key circuitKey) *circuit {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{key: key, state: StateClosed}
+		b.circuits[key] = c
+	}
+	return c
+}
+
+// Stats returns the state of all circuits, ordered by upstream, scope and name
+func (b *Breakers) Stats() []CircuitStats {
+	b.mu.Lock()
+	circuits := make([]*circuit, 0, len(b.circuits))
+	for _, c := range b.circuits {
+		circuits = append(circuits, c)
+	}
+	b.mu.Unlock()
+
+	now := b.now()
+	stats := make([]CircuitStats, 0, len(circuits))
+	for _, c := range circuits {
+		stats = append(stats, c.stats(now, b.config))
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Upstream != stats[j].Upstream {
+			return stats[i].Upstream < stats[j].Upstream
+		}
+		if stats[i].Scope != stats[j].Scope {
+			return stats[i].Scope > stats[j].Scope
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// isFailure reports whether the outcome of a call counts against the circuit.
+// Calls abandoned by the client are ignored: they say nothing about the upstream.
+func (b *Breakers) isFailure(ctx context.Context, err error) (failure bool, ignore bool) {
+	if err == nil {
+		return false, false
+	}
+	if ctx.Err() != nil && errors.Is(ctx.Err(), context.Canceled) {
+		return false, true
+	}
+	return b.failures[status.Code(err)], false
+}
+
+// Guard wraps the discoverer of one upstream and applies its circuits
+type Guard struct {
+	grpc.ServiceDiscoverer
+
+	breakers *Breakers
+	upstream string
+}
+
+// InvokeMethodByTool rejects the call while the upstream or tool circuit is
+// open and records the outcome of calls let through
+func (g *Guard) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	b := g.breakers
+	upstream := b.circuitFor(circuitKey{upstream: g.upstream, scope: ScopeUpstream, name: g.upstream})
+	tool := b.circuitFor(circuitKey{upstream: g.upstream, scope: ScopeTool, name: toolName})
+
+	now := b.now()
+	if err := tool.allow(now, b.config.Cooldown); err != nil {
+		return "", err
+	}
+	if err := upstream.allow(now, b.config.Cooldown); err != nil {
+		tool.abort()
+		return "", err
+	}
+
+	result, err := g.ServiceDiscoverer.InvokeMethodByTool(ctx, headers, toolName, inputJSON)
+
+	failure, ignore := b.isFailure(ctx, err)
+	if ignore {
+		tool.abort()
+		upstream.abort()
+		return result, err
+	}
+	now = b.now()
+	for _, c := range []*circuit{tool, upstream} {
+		if from, to, changed := c.record(now, failure, b.config); changed {
+			g.logTransition(c, from, to, err)
+		}
+	}
+	return result, err
+}
+
+func (g *Guard) logTransition(c *circuit, from, to State, err error) {
+	fields := []zap.Field{
+		zap.String("upstream", c.key.upstream),
+		zap.String("scope", c.key.scope),
+		zap.String("name", c.key.name),
+		zap.String("from", string(from)),
+		zap.String("to", string(to)),
+	}
+	if to == StateOpen {
+		g.breakers.logger.Warn("Circuit opened", append(fields, zap.Error(err))...)
+		return
+	}
+	g.breakers.logger.Info("Circuit closed", fields...)
+}
+
+// GetServiceStats adds the circuit states to the wrapped stats
+func (g *Guard) GetServiceStats() map[string]interface{} {
+	stats := g.ServiceDiscoverer.GetServiceStats()
+	if stats == nil {
+		stats = make(map[string]interface{})
+	}
+	stats["circuitBreaker"] = g.breakers.Stats()
+	return stats
+}
+
+// Unwrap returns the wrapped discoverer
+func (g *Guard) Unwrap() grpc.ServiceDiscoverer {
+	return g.ServiceDiscoverer
+}
+
+// circuit is a single breaker with a rolling window of call outcomes
+type circuit struct {
+	key circuitKey
+
+	mu       sync.Mutex
+	state    State
+	window   [buckets]bucket
+	openedAt time.Time
+	probing  bool
+	trips    int64
+	rejected int64
+}
+
+// bucket counts the outcomes of one slot of the rolling window
+type bucket struct {
+	start     int64
+	successes int
+	failures  int
+}
+
+// allow returns an OpenError when the circuit rejects the call. After the
+// cooldown a single probe call is let through.
+func (c *circuit) allow(now time.Time, cooldown time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == StateOpen && now.Sub(c.openedAt) >= cooldown {
+		c.state = StateHalfOpen
+	}
+	switch c.state {
+	case StateOpen:
+		c.rejected++
+		return c.openError(cooldown - now.Sub(c.openedAt))
+	case StateHalfOpen:
+		if c.probing {
+			c.rejected++
+			return c.openError(time.Second)
+		}
+		c.probing = true
+	}
+	return nil
+}
+
+// abort releases the probe slot of a call that was not made or whose outcome
+// is not recorded
+func (c *circuit) abort() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.probing = false
+}
+
+func (c *circuit) openError(retryAfter time.Duration) *OpenError {
+	if retryAfter < time.Second {
+		retryAfter = time.Second
+	}
+	return &OpenError{Scope: c.key.scope, Name: c.key.name, Upstream: c.key.upstream, RetryAfter: retryAfter}
+}
+
+// record adds the outcome of a call and reports a state change
+func (c *circuit) record(now time.Time, failure bool, cfg config.CircuitBreakerConfig) (from, to State, changed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	from = c.state
+	switch c.state {
+	case StateHalfOpen:
+		c.probing = false
+		if failure {
+			c.open(now)
+		} else {
+			c.state = StateClosed
+			c.window = [buckets]bucket{}
+		}
+	case StateClosed:
+		b := c.bucketAt(now, cfg.Window)
+		if failure {
+			b.failures++
+		} else {
+			b.successes++
+		}
+		requests, failures := c.totals(now, cfg.Window)
+		if failure && requests >= cfg.MinRequests && float64(failures)*100 >= cfg.FailurePercent*float64(requests) {
+			c.open(now)
+		}
+	}
+	return from, c.state, from != c.state
+}
+
+func (c *circuit) open(now time.Time) {
+	c.state = StateOpen
+	c.openedAt = now
+	c.trips++
+}
+
+// bucketAt returns the bucket for now, resetting it when it holds an older slot
+func (c *circuit) bucketAt(now time.Time, window time.Duration) *bucket {
+	slot := now.UnixNano() / int64(window/buckets)
+	b := &c.window[slot%buckets]
+	if b.start != slot {
+		*b = bucket{start: slot}
+	}
+	return b
+}
+
+// totals sums the buckets that are still within the window
+func (c *circuit) totals(now time.Time, window time.Duration) (requests, failures int) {
+	current := now.UnixNano() / int64(window/buckets)
+	for _, b := range c.window {
+		if current-b.start < buckets {
+			requests += b.successes + b.failures
+			failures += b.failures
+		}
+	}
+	return requests, failures
+}
+
+func (c *circuit) stats(now time.Time, cfg config.CircuitBreakerConfig) CircuitStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := c.state
+	if state == StateOpen && now.Sub(c.openedAt) >= cfg.Cooldown {
+		state = StateHalfOpen
+	}
+	requests, failures := c.totals(now, cfg.Window)
+	return CircuitStats{
+		Scope:    c.key.scope,
+		Name:     c.key.name,
+		Upstream: c.key.upstream,
+		State:    state,
+		Requests: requests,
+		Failures: failures,
+		Trips:    c.trips,
+		Rejected: c.rejected,
+	}
+}
@@ -0,0 +1,173 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type stubDiscoverer struct {
+	calls int
+	err   error
+}
+
+func (s *stubDiscoverer) Connect(ctx context.Context) error          { return nil }
+func (s *stubDiscoverer) DiscoverServices(ctx context.Context) error { return nil }
+func (s *stubDiscoverer) GetMethods() []types.MethodInfo             { return nil }
+func (s *stubDiscoverer) HealthCheck(ctx context.Context) error      { return nil }
+func (s *stubDiscoverer) GetMethod(string) (types.MethodInfo, bool)  { return types.MethodInfo{}, false }
+func (s *stubDiscoverer) Close() error                               { return nil }
+func (s *stubDiscoverer) GetMethodCount() int                        { return 0 }
+func (s *stubDiscoverer) GetServiceStats() map[string]interface{}    { return nil }
+
+func (s *stubDiscoverer) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	s.calls++
+	if s.err != nil {
+		return "", s.err
+	}
+	return `{}`, nil
+}
+
+type clock struct{ now time.Time }
+
+func (c *clock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestBreakers(t *testing.T, minRequests int) (*Breakers, *clock) {
+	cfg := config.Default().CircuitBreaker
+	cfg.MinRequests = minRequests
+	b, err := New(cfg, zap.NewNop())
+	require.NoError(t, err)
+	c := &clock{now: time.Unix(1700000000, 0)}
+	b.now = func() time.Time { return c.now }
+	return b, c
+}
+
+func call(g *Guard, tool string) error {
+	_, err := g.InvokeMethodByTool(context.Background(), nil, tool, `{}`)
+	return err
+}
+
+func TestGuard_OpensAndRecovers(t *testing.T) {
+	b, clk := newTestBreakers(t, 4)
+	stub := &stubDiscoverer{}
+	guard := b.Wrap(stub, "primary:50051")
+
+	require.NoError(t, call(guard, "svc_a"))
+	require.NoError(t, call(guard, "svc_a"))
+
+	stub.err = status.Error(codes.Unavailable, "down")
+	require.Error(t, call(guard, "svc_a"))
+	require.Error(t, call(guard, "svc_a")) // 2 of 4 failed: trips
+
+	// Open: calls fail fast without reaching the upstream
+	stub.calls = 0
+	err := call(guard, "svc_a")
+	var open *OpenError
+	require.ErrorAs(t, err, &open)
+	assert.Equal(t, ScopeTool, open.Scope)
+	assert.Equal(t, "svc_a", open.Name)
+	assert.Equal(t, 30*time.Second, open.RetryAfter)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 0, stub.calls)
+
+	// After the cooldown a failing probe opens the circuit again
+	clk.advance(30 * time.Second)
+	require.Error(t, call(guard, "svc_a"))
+	assert.Equal(t, 1, stub.calls)
+	require.ErrorAs(t, call(guard, "svc_a"), &open)
+
+	// A successful probe closes it
+	clk.advance(30 * time.Second)
+	stub.err = nil
+	require.NoError(t, call(guard, "svc_a"))
+	require.NoError(t, call(guard, "svc_a"))
+
+	stats := b.Stats()
+	require.Len(t, stats, 2)
+	assert.Equal(t, ScopeUpstream, stats[0].Scope)
+	assert.Equal(t, StateClosed, stats[0].State)
+	assert.Equal(t, CircuitStats{
+		Scope: ScopeTool, Name: "svc_a", Upstream: "primary:50051",
+		State: StateClosed, Requests: 1, Failures: 0, Trips: 2, Rejected: 2,
+	}, stats[1])
+}
+
+func TestGuard_UpstreamCircuitSpansTools(t *testing.T) {
+	b, _ := newTestBreakers(t, 4)
+	stub := &stubDiscoverer{err: status.Error(codes.Unavailable, "down")}
+	guard := b.Wrap(stub, "primary:50051")
+
+	// Failures spread over tools trip the upstream but no single tool
+	for _, tool := range []string{"svc_a", "svc_b", "svc_c", "svc_d"} {
+		require.Error(t, call(guard, tool))
+	}
+	var open *OpenError
+	require.ErrorAs(t, call(guard, "svc_e"), &open)
+	assert.Equal(t, ScopeUpstream, open.Scope)
+	assert.Equal(t, "primary:50051", open.Name)
+
+	// Other upstreams are not affected
+	other := b.Wrap(&stubDiscoverer{}, "replica:50051")
+	assert.NoError(t, call(other, "svc_e"))
+}
+
+func TestGuard_IgnoresClientErrors(t *testing.T) {
+	b, _ := newTestBreakers(t, 2)
+	stub := &stubDiscoverer{err: status.Error(codes.InvalidArgument, "bad input")}
+	guard := b.Wrap(stub, "primary:50051")
+
+	for i := 0; i < 5; i++ {
+		err := call(guard, "svc_a")
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+
+	// Calls abandoned by the client are not counted either
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stub.err = status.Error(codes.Canceled, "canceled")
+	for i := 0; i < 5; i++ {
+		_, err := guard.InvokeMethodByTool(ctx, nil, "svc_a", `{}`)
+		var open *OpenError
+		assert.False(t, errors.As(err, &open))
+	}
+	assert.Equal(t, 10, stub.calls)
+}
+
+func TestGuard_WindowExpires(t *testing.T) {
+	b, clk := newTestBreakers(t, 2)
+	stub := &stubDiscoverer{err: status.Error(codes.Unavailable, "down")}
+	guard := b.Wrap(stub, "primary:50051")
+
+	require.Error(t, call(guard, "svc_a"))
+	clk.advance(2 * time.Minute)
+	require.Error(t, call(guard, "svc_a"))
+
+	// The first failure left the window, so the circuit is still closed
+	var open *OpenError
+	assert.False(t, errors.As(call(guard, "svc_b"), &open))
+}
+
+func TestGuard_Stats(t *testing.T) {
+	b, _ := newTestBreakers(t, 2)
+	guard := b.Wrap(&stubDiscoverer{}, "primary:50051")
+	require.NoError(t, call(guard, "svc_a"))
+
+	stats := guard.GetServiceStats()
+	assert.Len(t, stats["circuitBreaker"], 2)
+}
+
+func TestNew_InvalidCode(t *testing.T) {
+	cfg := config.Default().CircuitBreaker
+	cfg.FailureCodes = []string{"NOPE"}
+	_, err := New(cfg, zap.NewNop())
+	assert.Error(t, err)
+}
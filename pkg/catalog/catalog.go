@@ -0,0 +1,230 @@
+// Package catalog records the MCP tool surface built from a backend in a
+// golden file and reports how a newer surface differs from it. Changes that
+// break existing callers (removed tools, newly required or removed input
+// fields, changed field types) are marked as breaking, so backend pull
+// requests that change the MCP surface can be caught in CI.
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/tools"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+)
+
+// Change is one difference between the golden and the current catalog
+type Change struct {
+	Tool     string `json:"tool"`
+	Breaking bool   `json:"breaking"`
+	Message  string `json:"message"`
+}
+
+// String returns the change formatted for command line output
+func (c Change) String() string {
+	kind := "change"
+	if c.Breaking {
+		kind = "breaking"
+	}
+	return fmt.Sprintf("%s: %s: %s", kind, c.Tool, c.Message)
+}
+
+// HasBreaking reports whether any change breaks existing callers
+func HasBreaking(changes []Change) bool {
+	for _, change := range changes {
+		if change.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// Build builds the tools of the methods, sorted by name
+func Build(methods []types.MethodInfo, builder *tools.MCPToolBuilder) ([]mcp.Tool, error) {
+	toolList, err := builder.BuildTools(methods)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(toolList, func(i, j int) bool { return toolList[i].Name < toolList[j].Name })
+	return toolList, nil
+}
+
+// Write writes the catalog to path in the tools/list result format
+func Write(path string, toolList []mcp.Tool) error {
+	data, err := json.MarshalIndent(mcp.ToolsListResult{Tools: toolList}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode catalog: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Load reads a catalog written by Write
+func Load(path string) ([]mcp.Tool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var result mcp.ToolsListResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse catalog %s: %w", path, err)
+	}
+	return result.Tools, nil
+}
+
+// Diff compares the current catalog against the golden one. Changes are
+// sorted by tool name, with breaking changes first within a tool.
+func Diff(golden, current []mcp.Tool) ([]Change, error) {
+	// Compare the JSON form, as stored in golden files
+	golden, err := normalize(golden)
+	if err != nil {
+		return nil, err
+	}
+	current, err = normalize(current)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	currentByName := make(map[string]mcp.Tool, len(current))
+	for _, tool := range current {
+		currentByName[tool.Name] = tool
+	}
+	goldenNames := make(map[string]bool, len(golden))
+	for _, old := range golden {
+		goldenNames[old.Name] = true
+		tool, ok := currentByName[old.Name]
+		if !ok {
+			changes = append(changes, Change{Tool: old.Name, Breaking: true, Message: "tool removed"})
+			continue
+		}
+		d := &differ{tool: old.Name}
+		d.schema("input", old.InputSchema, tool.InputSchema, true)
+		d.schema("output", old.OutputSchema, tool.OutputSchema, false)
+		changes = append(changes, d.changes...)
+	}
+	for _, tool := range current {
+		if !goldenNames[tool.Name] {
+			changes = append(changes, Change{Tool: tool.Name, Message: "tool added"})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Tool != changes[j].Tool {
+			return changes[i].Tool < changes[j].Tool
+		}
+		return changes[i].Breaking && !changes[j].Breaking
+	})
+	return changes, nil
+}
+
+// normalize round-trips the tools through JSON so schemas built in memory
+// compare equal to schemas read from a golden file
+func normalize(toolList []mcp.Tool) ([]mcp.Tool, error) {
+	data, err := json.Marshal(toolList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode catalog: %w", err)
+	}
+	var normalized []mcp.Tool
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, fmt.Errorf("failed to decode catalog: %w", err)
+	}
+	return normalized, nil
+}
+
+// differ collects the changes of one tool
+type differ struct {
+	tool    string
+	changes []Change
+}
+
+func (d *differ) add(breaking bool, format string, args ...interface{}) {
+	d.changes = append(d.changes, Change{Tool: d.tool, Breaking: breaking, Message: fmt.Sprintf(format, args...)})
+}
+
+// schema compares two schemas at path. In input schemas removing a field or
+// requiring a new one breaks callers; in output schemas removing a field
+// breaks consumers of the result. Type changes break both.
+func (d *differ) schema(path string, old, current interface{}, input bool) {
+	o, _ := old.(map[string]interface{})
+	c, _ := current.(map[string]interface{})
+	switch {
+	case o == nil && c == nil:
+		return
+	case c == nil:
+		d.add(true, "%s schema removed", path)
+		return
+	case o == nil:
+		d.add(false, "%s schema added", path)
+		return
+	}
+
+	if o["type"] != c["type"] {
+		d.add(true, "%s type changed from %v to %v", path, o["type"], c["type"])
+		return
+	}
+
+	oldProps, _ := o["properties"].(map[string]interface{})
+	currentProps, _ := c["properties"].(map[string]interface{})
+	for _, name := range sortedKeys(oldProps) {
+		fieldPath := path + "." + name
+		if _, ok := currentProps[name]; !ok {
+			d.add(true, "field %s removed", fieldPath)
+			continue
+		}
+		d.schema(fieldPath, oldProps[name], currentProps[name], input)
+	}
+
+	oldRequired := stringSet(o["required"])
+	currentRequired := stringSet(c["required"])
+	for _, name := range sortedKeys(currentProps) {
+		fieldPath := path + "." + name
+		_, existed := oldProps[name]
+		switch {
+		case !existed && input && currentRequired[name]:
+			d.add(true, "required field %s added", fieldPath)
+		case !existed:
+			d.add(false, "field %s added", fieldPath)
+		case input && currentRequired[name] && !oldRequired[name]:
+			d.add(true, "field %s is now required", fieldPath)
+		case input && oldRequired[name] && !currentRequired[name]:
+			d.add(false, "field %s is no longer required", fieldPath)
+		}
+	}
+
+	if oldEnum, ok := o["enum"].([]interface{}); ok {
+		currentEnum := stringSet(c["enum"])
+		for _, value := range oldEnum {
+			if name, _ := value.(string); !currentEnum[name] {
+				d.add(input, "%s value %v removed", path, value)
+			}
+		}
+	}
+
+	if _, ok := o["items"]; ok {
+		d.schema(path+"[]", o["items"], c["items"], input)
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// stringSet returns the strings of a JSON array as a set
+func stringSet(v interface{}) map[string]bool {
+	set := make(map[string]bool)
+	values, _ := v.([]interface{})
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			set[s] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,112 @@
+package catalog
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func object(required []string, properties map[string]interface{}) map[string]interface{} {
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if required != nil {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func typed(t string) map[string]interface{} {
+	return map[string]interface{}{"type": t}
+}
+
+func TestDiff(t *testing.T) {
+	golden := []mcp.Tool{
+		{
+			Name: "books_get",
+			InputSchema: object([]string{"id"}, map[string]interface{}{
+				"id":     typed("string"),
+				"fields": map[string]interface{}{"type": "array", "items": typed("string")},
+				"view":   map[string]interface{}{"type": "string", "enum": []interface{}{"BASIC", "FULL"}},
+			}),
+			OutputSchema: object(nil, map[string]interface{}{
+				"title": typed("string"),
+				"pages": typed("integer"),
+				"isbn":  typed("string"),
+			}),
+		},
+		{Name: "books_delete", InputSchema: object([]string{"id"}, map[string]interface{}{"id": typed("string")})},
+		{Name: "books_list", InputSchema: object(nil, map[string]interface{}{"parent": typed("string")})},
+	}
+	current := []mcp.Tool{
+		{
+			Name: "books_get",
+			InputSchema: object([]string{"id", "shelf"}, map[string]interface{}{
+				"id":     typed("string"),
+				"shelf":  typed("string"),
+				"fields": map[string]interface{}{"type": "array", "items": typed("integer")},
+				"view":   map[string]interface{}{"type": "string", "enum": []interface{}{"BASIC"}},
+				"locale": typed("string"),
+			}),
+			OutputSchema: object(nil, map[string]interface{}{
+				"title":   typed("string"),
+				"pages":   typed("string"),
+				"summary": typed("string"),
+			}),
+		},
+		{Name: "books_list", InputSchema: object([]string{"parent"}, map[string]interface{}{"parent": typed("string")})},
+		{Name: "books_search", InputSchema: object(nil, map[string]interface{}{})},
+	}
+
+	changes, err := Diff(golden, current)
+	require.NoError(t, err)
+
+	var lines []string
+	for _, change := range changes {
+		lines = append(lines, change.String())
+	}
+	assert.Equal(t, []string{
+		"breaking: books_delete: tool removed",
+		"breaking: books_get: input.fields[] type changed from string to integer",
+		"breaking: books_get: input.view value FULL removed",
+		"breaking: books_get: required field input.shelf added",
+		"breaking: books_get: field output.isbn removed",
+		"breaking: books_get: output.pages type changed from integer to string",
+		"change: books_get: field input.locale added",
+		"change: books_get: field output.summary added",
+		"breaking: books_list: field input.parent is now required",
+		"change: books_search: tool added",
+	}, lines)
+	assert.True(t, HasBreaking(changes))
+
+	// An unchanged catalog has no changes
+	changes, err = Diff(current, current)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.False(t, HasBreaking(changes))
+}
+
+func TestWriteLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	toolList := []mcp.Tool{{
+		Name:         "books_get",
+		Description:  "Gets a book",
+		InputSchema:  object([]string{"id"}, map[string]interface{}{"id": typed("string")}),
+		OutputSchema: object(nil, map[string]interface{}{"title": typed("string")}),
+	}}
+	require.NoError(t, Write(path, toolList))
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, loaded, 1)
+	assert.Equal(t, "Gets a book", loaded[0].Description)
+
+	// Schemas built in memory compare equal to the ones read back
+	changes, err := Diff(loaded, toolList)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
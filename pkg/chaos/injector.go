@@ -0,0 +1,142 @@
+// Package chaos implements config-driven fault injection on the tool invoke
+// path, so agents and retry policies can be exercised against upstream
+// failures without breaking a real backend.
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Injector wraps a ServiceDiscoverer and injects delays, errors and truncated
+// responses into a configurable share of tool calls
+type Injector struct {
+	grpc.ServiceDiscoverer
+
+	logger *zap.Logger
+	config config.ChaosConfig
+	codes  []codes.Code
+	tools  map[string]bool
+
+	mu   sync.Mutex
+	rand func() float64
+}
+
+// NewInjector creates a fault injector from the given configuration
+func NewInjector(inner grpc.ServiceDiscoverer, cfg config.ChaosConfig, logger *zap.Logger) (*Injector, error) {
+	parsed, err := ParseCodes(cfg.ErrorCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	var tools map[string]bool
+	if len(cfg.Tools) > 0 {
+		tools = make(map[string]bool, len(cfg.Tools))
+		for _, tool := range cfg.Tools {
+			tools[tool] = true
+		}
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return &Injector{
+		ServiceDiscoverer: inner,
+		logger:            logger.Named("chaos"),
+		config:            cfg,
+		codes:             parsed,
+		tools:             tools,
+		rand:              rng.Float64,
+	}, nil
+}
+
+// ParseCodes converts gRPC code names (e.g. "UNAVAILABLE", "Unavailable" or
+// "resource_exhausted") to codes
+func ParseCodes(names []string) ([]codes.Code, error) {
+	known := make(map[string]codes.Code, 17)
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		known[normalizeCodeName(c.String())] = c
+	}
+
+	parsed := make([]codes.Code, 0, len(names))
+	for _, name := range names {
+		code, ok := known[normalizeCodeName(name)]
+		if !ok {
+			return nil, fmt.Errorf("invalid gRPC code %q", name)
+		}
+		parsed = append(parsed, code)
+	}
+	return parsed, nil
+}
+
+func normalizeCodeName(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(name), "_", ""))
+}
+
+// InvokeMethodByTool applies the configured faults around the upstream call
+func (i *Injector) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	if !i.config.Enabled || (i.tools != nil && !i.tools[toolName]) {
+		return i.ServiceDiscoverer.InvokeMethodByTool(ctx, headers, toolName, inputJSON)
+	}
+
+	if i.roll(i.config.DelayPercent) {
+		i.logger.Debug("Injecting delay", zap.String("tool", toolName), zap.Duration("delay", i.config.Delay))
+		timer := time.NewTimer(i.config.Delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return "", status.FromContextError(ctx.Err()).Err()
+		}
+	}
+
+	if len(i.codes) > 0 && i.roll(i.config.ErrorPercent) {
+		code := i.pickCode()
+		i.logger.Debug("Injecting error", zap.String("tool", toolName), zap.Stringer("code", code))
+		return "", status.Errorf(code, "chaos: injected %s fault", code)
+	}
+
+	result, err := i.ServiceDiscoverer.InvokeMethodByTool(ctx, headers, toolName, inputJSON)
+	if err != nil {
+		return result, err
+	}
+
+	if i.roll(i.config.TruncatePercent) {
+		n := i.config.TruncateBytes
+		if n <= 0 || n > len(result) {
+			n = len(result) / 2
+		}
+		i.logger.Debug("Injecting truncated response", zap.String("tool", toolName), zap.Int("bytes", n))
+		result = result[:n]
+	}
+
+	return result, nil
+}
+
+// Unwrap returns the wrapped discoverer
+func (i *Injector) Unwrap() grpc.ServiceDiscoverer {
+	return i.ServiceDiscoverer
+}
+
+func (i *Injector) roll(percent float64) bool {
+	if percent <= 0 {
+		return false
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rand()*100 < percent
+}
+
+func (i *Injector) pickCode() codes.Code {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.codes[int(i.rand()*float64(len(i.codes)))%len(i.codes)]
+}
@@ -0,0 +1,117 @@
+package chaos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type stubDiscoverer struct {
+	calls int
+}
+
+func (s *stubDiscoverer) Connect(ctx context.Context) error          { return nil }
+func (s *stubDiscoverer) DiscoverServices(ctx context.Context) error { return nil }
+func (s *stubDiscoverer) GetMethods() []types.MethodInfo             { return nil }
+func (s *stubDiscoverer) HealthCheck(ctx context.Context) error      { return nil }
+func (s *stubDiscoverer) GetMethod(string) (types.MethodInfo, bool)  { return types.MethodInfo{}, false }
+func (s *stubDiscoverer) Close() error                               { return nil }
+func (s *stubDiscoverer) GetMethodCount() int                        { return 0 }
+func (s *stubDiscoverer) GetServiceStats() map[string]interface{}    { return nil }
+
+func (s *stubDiscoverer) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	s.calls++
+	return `{"message":"hello world"}`, nil
+}
+
+func newTestInjector(t *testing.T, cfg config.ChaosConfig, roll float64) (*Injector, *stubDiscoverer) {
+	stub := &stubDiscoverer{}
+	injector, err := NewInjector(stub, cfg, zap.NewNop())
+	require.NoError(t, err)
+	injector.rand = func() float64 { return roll }
+	return injector, stub
+}
+
+func TestInjector_Error(t *testing.T) {
+	injector, stub := newTestInjector(t, config.ChaosConfig{
+		Enabled:      true,
+		ErrorPercent: 50,
+		ErrorCodes:   []string{"UNAVAILABLE"},
+	}, 0.1)
+
+	_, err := injector.InvokeMethodByTool(context.Background(), nil, "svc_method", `{}`)
+	require.Error(t, err)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 0, stub.calls)
+}
+
+func TestInjector_NotTriggered(t *testing.T) {
+	injector, stub := newTestInjector(t, config.ChaosConfig{
+		Enabled:         true,
+		ErrorPercent:    50,
+		ErrorCodes:      []string{"Internal"},
+		TruncatePercent: 50,
+	}, 0.9)
+
+	result, err := injector.InvokeMethodByTool(context.Background(), nil, "svc_method", `{}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"message":"hello world"}`, result)
+	assert.Equal(t, 1, stub.calls)
+}
+
+func TestInjector_Truncate(t *testing.T) {
+	injector, _ := newTestInjector(t, config.ChaosConfig{
+		Enabled:         true,
+		TruncatePercent: 100,
+		TruncateBytes:   5,
+	}, 0.5)
+
+	result, err := injector.InvokeMethodByTool(context.Background(), nil, "svc_method", `{}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"mes`, result)
+}
+
+func TestInjector_DelayRespectsContext(t *testing.T) {
+	injector, stub := newTestInjector(t, config.ChaosConfig{
+		Enabled:      true,
+		DelayPercent: 100,
+		Delay:        time.Minute,
+	}, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := injector.InvokeMethodByTool(ctx, nil, "svc_method", `{}`)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Equal(t, 0, stub.calls)
+}
+
+func TestInjector_ToolFilter(t *testing.T) {
+	injector, stub := newTestInjector(t, config.ChaosConfig{
+		Enabled:      true,
+		Tools:        []string{"other_tool"},
+		ErrorPercent: 100,
+		ErrorCodes:   []string{"UNAVAILABLE"},
+	}, 0)
+
+	_, err := injector.InvokeMethodByTool(context.Background(), nil, "svc_method", `{}`)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stub.calls)
+}
+
+func TestParseCodes(t *testing.T) {
+	parsed, err := ParseCodes([]string{"UNAVAILABLE", "deadline_exceeded", "ResourceExhausted"})
+	require.NoError(t, err)
+	assert.Equal(t, []codes.Code{codes.Unavailable, codes.DeadlineExceeded}, parsed[:2])
+
+	_, err = ParseCodes([]string{"NOPE"})
+	assert.Error(t, err)
+}
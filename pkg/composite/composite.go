@@ -0,0 +1,495 @@
+// Package composite implements config-defined workflow tools: a composite
+// tool runs several discovered tools in sequence, mapping its arguments and
+// earlier step outputs into the arguments of later steps, so common
+// multi-step operations don't require the client to orchestrate every hop.
+//
+// Steps may name a compensation, a call that undoes them. When a step fails,
+// the compensations of the steps completed before it run in reverse order,
+// as in a saga, so a failed composite does not leave backend state half
+// changed.
+package composite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+)
+
+// compensationTimeout bounds the compensations of a failed call; they run
+// even when the call's own context is canceled or expired
+const compensationTimeout = 30 * time.Second
+
+// InvokeFunc calls a discovered tool with JSON arguments and returns its JSON output
+type InvokeFunc func(ctx context.Context, toolName string, inputJSON string) (string, error)
+
+// Tool is a compiled composite tool
+type Tool struct {
+	name        string
+	description string
+	inputSchema map[string]interface{}
+	steps       []step
+	result      node
+}
+
+type step struct {
+	id         string
+	tool       string
+	arguments  node
+	compensate *compensation
+}
+
+// compensation is the compiled call that undoes a step
+type compensation struct {
+	tool      string
+	arguments node
+}
+
+// Compensation is the outcome of undoing a completed step
+type Compensation struct {
+	Step   string `json:"step"`
+	Tool   string `json:"tool"`
+	Failed bool   `json:"failed,omitempty"`
+
+	// Err is the failure of the compensation call
+	Err error `json:"-"`
+}
+
+// CompensatedError is returned when a step fails after steps with
+// compensations completed. It wraps the step's failure and reports the
+// compensations in the order they ran.
+type CompensatedError struct {
+	Err           error
+	Compensations []Compensation
+}
+
+func (e *CompensatedError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Err.Error())
+	var undone []string
+	for _, c := range e.Compensations {
+		if !c.Failed {
+			undone = append(undone, c.Step)
+		}
+	}
+	if len(undone) > 0 {
+		fmt.Fprintf(&b, "; compensated steps %s", strings.Join(undone, ", "))
+	}
+	for _, c := range e.Compensations {
+		if c.Failed {
+			fmt.Fprintf(&b, "; compensation of step %q (%s) failed: %v", c.Step, c.Tool, c.Err)
+		}
+	}
+	return b.String()
+}
+
+func (e *CompensatedError) Unwrap() error {
+	return e.Err
+}
+
+// node is a compiled argument value: a literal, a reference or a template,
+// possibly nested in objects and arrays
+type node struct {
+	literal   interface{}
+	reference []string
+	tmpl      *template.Template
+	object    map[string]node
+	array     []node
+	isRef     bool
+	isObject  bool
+	isArray   bool
+}
+
+// Registry holds the configured composite tools
+type Registry struct {
+	tools map[string]*Tool
+	order []string
+}
+
+// NewRegistry compiles the composite tool definitions
+func NewRegistry(configs []config.CompositeToolConfig) (*Registry, error) {
+	registry := &Registry{tools: make(map[string]*Tool, len(configs))}
+	for _, cfg := range configs {
+		if _, exists := registry.tools[cfg.Name]; exists {
+			return nil, fmt.Errorf("duplicate composite tool %q", cfg.Name)
+		}
+		tool, err := compileTool(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("composite tool %q: %w", cfg.Name, err)
+		}
+		registry.tools[cfg.Name] = tool
+		registry.order = append(registry.order, cfg.Name)
+	}
+	return registry, nil
+}
+
+func compileTool(cfg config.CompositeToolConfig) (*Tool, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if len(cfg.Steps) == 0 {
+		return nil, fmt.Errorf("at least one step is required")
+	}
+
+	tool := &Tool{
+		name:        cfg.Name,
+		description: cfg.Description,
+		inputSchema: cfg.InputSchema,
+	}
+	if tool.description == "" {
+		tool.description = fmt.Sprintf("Runs %d steps in sequence", len(cfg.Steps))
+	}
+	if tool.inputSchema == nil {
+		tool.inputSchema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+
+	known := make(map[string]bool, len(cfg.Steps))
+	for i, stepCfg := range cfg.Steps {
+		id := stepCfg.ID
+		if id == "" {
+			id = fmt.Sprintf("step%d", i+1)
+		}
+		if known[id] {
+			return nil, fmt.Errorf("duplicate step id %q", id)
+		}
+		if stepCfg.Tool == "" {
+			return nil, fmt.Errorf("step %q requires a tool", id)
+		}
+
+		var arguments interface{} = map[string]interface{}{}
+		if stepCfg.Arguments != nil {
+			arguments = stepCfg.Arguments
+		}
+		compiled, err := compileNode(arguments, known)
+		if err != nil {
+			return nil, fmt.Errorf("step %q: %w", id, err)
+		}
+		known[id] = true
+
+		// Compensations may also reference the output of the step they undo
+		var compensate *compensation
+		if stepCfg.Compensate != nil {
+			if stepCfg.Compensate.Tool == "" {
+				return nil, fmt.Errorf("compensation of step %q requires a tool", id)
+			}
+			var arguments interface{} = map[string]interface{}{}
+			if stepCfg.Compensate.Arguments != nil {
+				arguments = stepCfg.Compensate.Arguments
+			}
+			compiledCompensation, err := compileNode(arguments, known)
+			if err != nil {
+				return nil, fmt.Errorf("compensation of step %q: %w", id, err)
+			}
+			compensate = &compensation{tool: stepCfg.Compensate.Tool, arguments: compiledCompensation}
+		}
+		tool.steps = append(tool.steps, step{id: id, tool: stepCfg.Tool, arguments: compiled, compensate: compensate})
+	}
+
+	if cfg.Result != nil {
+		compiled, err := compileNode(cfg.Result, known)
+		if err != nil {
+			return nil, fmt.Errorf("result: %w", err)
+		}
+		tool.result = compiled
+	} else {
+		last := tool.steps[len(tool.steps)-1].id
+		tool.result = node{isRef: true, reference: []string{"steps", last}}
+	}
+	return tool, nil
+}
+
+// compileNode compiles a configured value; references may only point to the
+// composite's arguments and to steps that run earlier
+func compileNode(value interface{}, knownSteps map[string]bool) (node, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		n := node{isObject: true, object: make(map[string]node, len(v))}
+		for key, elem := range v {
+			compiled, err := compileNode(elem, knownSteps)
+			if err != nil {
+				return node{}, fmt.Errorf("%s: %w", key, err)
+			}
+			n.object[key] = compiled
+		}
+		return n, nil
+	case []interface{}:
+		n := node{isArray: true, array: make([]node, len(v))}
+		for i, elem := range v {
+			compiled, err := compileNode(elem, knownSteps)
+			if err != nil {
+				return node{}, fmt.Errorf("[%d]: %w", i, err)
+			}
+			n.array[i] = compiled
+		}
+		return n, nil
+	case string:
+		switch {
+		case strings.HasPrefix(v, "$$"):
+			return node{literal: v[1:]}, nil
+		case strings.HasPrefix(v, "$"):
+			ref := strings.Split(v[1:], ".")
+			if err := checkReference(ref, knownSteps); err != nil {
+				return node{}, fmt.Errorf("invalid reference %q: %w", v, err)
+			}
+			return node{isRef: true, reference: ref}, nil
+		case strings.Contains(v, "{{"):
+			tmpl, err := template.New("").Option("missingkey=zero").Parse(v)
+			if err != nil {
+				return node{}, fmt.Errorf("invalid template %q: %w", v, err)
+			}
+			return node{tmpl: tmpl}, nil
+		}
+	}
+	return node{literal: value}, nil
+}
+
+func checkReference(ref []string, knownSteps map[string]bool) error {
+	switch ref[0] {
+	case "args":
+		return nil
+	case "steps":
+		if len(ref) < 2 {
+			return fmt.Errorf("missing step id")
+		}
+		if !knownSteps[ref[1]] {
+			return fmt.Errorf("unknown or later step %q", ref[1])
+		}
+		return nil
+	}
+	return fmt.Errorf("references must start with $args or $steps")
+}
+
+// scope is the data available while resolving a composite call
+type scope struct {
+	Args  map[string]interface{}
+	Steps map[string]interface{}
+}
+
+func (n node) resolve(s scope) (interface{}, error) {
+	switch {
+	case n.isObject:
+		out := make(map[string]interface{}, len(n.object))
+		for key, elem := range n.object {
+			v, err := elem.resolve(s)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = v
+		}
+		return out, nil
+	case n.isArray:
+		out := make([]interface{}, len(n.array))
+		for i, elem := range n.array {
+			v, err := elem.resolve(s)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	case n.isRef:
+		return lookup(s, n.reference), nil
+	case n.tmpl != nil:
+		var b strings.Builder
+		if err := n.tmpl.Execute(&b, s); err != nil {
+			return nil, err
+		}
+		return b.String(), nil
+	}
+	return n.literal, nil
+}
+
+// lookup walks a reference; missing keys resolve to null
+func lookup(s scope, ref []string) interface{} {
+	var current interface{} = map[string]interface{}{"args": s.Args, "steps": s.Steps}
+	for _, key := range ref {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = obj[key]
+	}
+	return current
+}
+
+// Name returns the tool name
+func (t *Tool) Name() string {
+	return t.name
+}
+
+// StepTools returns the discovered tools the composite calls, in order,
+// followed by the compensation tools
+func (t *Tool) StepTools() []string {
+	tools := make([]string, 0, len(t.steps))
+	for _, s := range t.steps {
+		tools = append(tools, s.tool)
+	}
+	for _, s := range t.steps {
+		if s.compensate != nil {
+			tools = append(tools, s.compensate.tool)
+		}
+	}
+	return tools
+}
+
+// MCPTool returns the tool definition advertised to clients
+func (t *Tool) MCPTool() mcp.Tool {
+	return mcp.Tool{
+		Name:        t.name,
+		Description: t.description,
+		InputSchema: t.inputSchema,
+	}
+}
+
+// Execute runs the steps in order and returns the result as JSON
+func (t *Tool) Execute(ctx context.Context, argumentsJSON string, invoke InvokeFunc) (string, error) {
+	s := scope{Args: map[string]interface{}{}, Steps: make(map[string]interface{}, len(t.steps))}
+	if strings.TrimSpace(argumentsJSON) != "" {
+		args, err := decodeJSON(argumentsJSON)
+		if err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+		obj, ok := args.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("invalid arguments: expected a JSON object")
+		}
+		s.Args = obj
+	}
+
+	for i, st := range t.steps {
+		if err := runStep(ctx, st, s, invoke); err != nil {
+			return "", compensate(ctx, t.steps[:i], s, err, invoke)
+		}
+	}
+
+	result, err := t.result.resolve(s)
+	if err != nil {
+		return "", fmt.Errorf("failed to build result: %w", err)
+	}
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode result: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// runStep calls the step's tool and stores its output in the scope
+func runStep(ctx context.Context, st step, s scope, invoke InvokeFunc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	arguments, err := st.arguments.resolve(s)
+	if err != nil {
+		return fmt.Errorf("step %q: failed to build arguments: %w", st.id, err)
+	}
+	inputJSON, err := json.Marshal(arguments)
+	if err != nil {
+		return fmt.Errorf("step %q: failed to encode arguments: %w", st.id, err)
+	}
+
+	output, err := invoke(ctx, st.tool, string(inputJSON))
+	if err != nil {
+		return fmt.Errorf("step %q (%s) failed: %w", st.id, st.tool, err)
+	}
+
+	decoded, err := decodeJSON(output)
+	if err != nil {
+		// Keep non-JSON outputs addressable as plain strings
+		decoded = output
+	}
+	s.Steps[st.id] = decoded
+	return nil
+}
+
+// compensate undoes the completed steps in reverse order after a step failed
+// with err. A failing compensation does not stop the ones before it. Without
+// compensations err is returned unchanged.
+func compensate(ctx context.Context, completed []step, s scope, err error, invoke InvokeFunc) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), compensationTimeout)
+	defer cancel()
+
+	var compensations []Compensation
+	for i := len(completed) - 1; i >= 0; i-- {
+		st := completed[i]
+		if st.compensate == nil {
+			continue
+		}
+		outcome := Compensation{Step: st.id, Tool: st.compensate.tool}
+		if cerr := st.compensate.run(ctx, s, invoke); cerr != nil {
+			outcome.Failed = true
+			outcome.Err = cerr
+		}
+		compensations = append(compensations, outcome)
+	}
+	if len(compensations) == 0 {
+		return err
+	}
+	return &CompensatedError{Err: err, Compensations: compensations}
+}
+
+// run calls the compensation tool
+func (c *compensation) run(ctx context.Context, s scope, invoke InvokeFunc) error {
+	arguments, err := c.arguments.resolve(s)
+	if err != nil {
+		return fmt.Errorf("failed to build arguments: %w", err)
+	}
+	inputJSON, err := json.Marshal(arguments)
+	if err != nil {
+		return fmt.Errorf("failed to encode arguments: %w", err)
+	}
+	_, err = invoke(ctx, c.tool, string(inputJSON))
+	return err
+}
+
+func decodeJSON(data string) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Lookup returns the composite tool with the given name
+func (r *Registry) Lookup(name string) (*Tool, bool) {
+	if r == nil {
+		return nil, false
+	}
+	tool, ok := r.tools[name]
+	return tool, ok
+}
+
+// Tools returns all composite tools in configuration order
+func (r *Registry) Tools() []*Tool {
+	if r == nil {
+		return nil
+	}
+	tools := make([]*Tool, 0, len(r.order))
+	for _, name := range r.order {
+		tools = append(tools, r.tools[name])
+	}
+	return tools
+}
+
+// MissingTools returns step tools for which exists reports false, so callers
+// can warn about composites that reference undiscovered tools
+func (r *Registry) MissingTools(exists func(toolName string) bool) []string {
+	var missing []string
+	seen := make(map[string]bool)
+	for _, tool := range r.Tools() {
+		for _, name := range tool.StepTools() {
+			if !seen[name] && !exists(name) {
+				missing = append(missing, name)
+			}
+			seen[name] = true
+		}
+	}
+	return missing
+}
@@ -0,0 +1,231 @@
+package composite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type call struct {
+	tool  string
+	input string
+}
+
+func recordingInvoke(calls *[]call, outputs map[string]string) InvokeFunc {
+	return func(ctx context.Context, toolName string, inputJSON string) (string, error) {
+		*calls = append(*calls, call{tool: toolName, input: inputJSON})
+		output, ok := outputs[toolName]
+		if !ok {
+			return "", errors.New("tool " + toolName + " not found")
+		}
+		return output, nil
+	}
+}
+
+func TestExecute_ChainsSteps(t *testing.T) {
+	registry, err := NewRegistry([]config.CompositeToolConfig{{
+		Name: "order_summary",
+		Steps: []config.CompositeStepConfig{
+			{ID: "order", Tool: "orders_getorder", Arguments: map[string]interface{}{"id": "$args.order_id"}},
+			{ID: "customer", Tool: "customers_getcustomer", Arguments: map[string]interface{}{
+				"id":   "$steps.order.customer.id",
+				"note": "order {{.Args.order_id}}",
+				"cost": "$$5",
+			}},
+		},
+		Result: map[string]interface{}{
+			"total": "$steps.order.total",
+			"name":  "$steps.customer.name",
+		},
+	}})
+	require.NoError(t, err)
+
+	tool, ok := registry.Lookup("order_summary")
+	require.True(t, ok)
+
+	var calls []call
+	result, err := tool.Execute(context.Background(), `{"order_id":"o-1"}`, recordingInvoke(&calls, map[string]string{
+		"orders_getorder":       `{"customer":{"id":12345678901234567},"total":42.5}`,
+		"customers_getcustomer": `{"name":"Ada"}`,
+	}))
+	require.NoError(t, err)
+
+	require.Len(t, calls, 2)
+	assert.JSONEq(t, `{"id":"o-1"}`, calls[0].input)
+	// Large numbers pass between steps without losing precision
+	assert.JSONEq(t, `{"id":12345678901234567,"note":"order o-1","cost":"$5"}`, calls[1].input)
+	assert.JSONEq(t, `{"total":42.5,"name":"Ada"}`, result)
+}
+
+func TestExecute_DefaultResultIsLastStep(t *testing.T) {
+	registry, err := NewRegistry([]config.CompositeToolConfig{{
+		Name:  "single",
+		Steps: []config.CompositeStepConfig{{Tool: "a"}, {Tool: "b"}},
+	}})
+	require.NoError(t, err)
+
+	tool, _ := registry.Lookup("single")
+	var calls []call
+	result, err := tool.Execute(context.Background(), "", recordingInvoke(&calls, map[string]string{"a": `{"x":1}`, "b": `{"y":2}`}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"y":2}`, result)
+	assert.JSONEq(t, `{}`, calls[0].input)
+}
+
+func TestExecute_StepFailureStopsChain(t *testing.T) {
+	registry, err := NewRegistry([]config.CompositeToolConfig{{
+		Name: "broken",
+		Steps: []config.CompositeStepConfig{
+			{ID: "first", Tool: "missing"},
+			{ID: "second", Tool: "b"},
+		},
+	}})
+	require.NoError(t, err)
+
+	tool, _ := registry.Lookup("broken")
+	var calls []call
+	_, err = tool.Execute(context.Background(), "{}", recordingInvoke(&calls, map[string]string{"b": "{}"}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `step "first" (missing) failed`)
+	assert.Len(t, calls, 1)
+}
+
+func TestExecute_CompensatesCompletedSteps(t *testing.T) {
+	registry, err := NewRegistry([]config.CompositeToolConfig{{
+		Name: "place_order",
+		Steps: []config.CompositeStepConfig{
+			{ID: "reserve", Tool: "inventory_reserve", Arguments: map[string]interface{}{"sku": "$args.sku"},
+				Compensate: &config.CompensationConfig{Tool: "inventory_release", Arguments: map[string]interface{}{"id": "$steps.reserve.id"}}},
+			{ID: "notify", Tool: "mail_send"},
+			{ID: "hold", Tool: "payments_hold",
+				Compensate: &config.CompensationConfig{Tool: "payments_void"}},
+			{ID: "charge", Tool: "payments_charge",
+				Compensate: &config.CompensationConfig{Tool: "payments_refund"}},
+		},
+	}})
+	require.NoError(t, err)
+	tool, _ := registry.Lookup("place_order")
+	assert.Equal(t, []string{"inventory_reserve", "mail_send", "payments_hold", "payments_charge", "inventory_release", "payments_void", "payments_refund"}, tool.StepTools())
+
+	// The completed steps are undone in reverse order; a failing compensation
+	// does not stop the earlier ones
+	var calls []call
+	_, err = tool.Execute(context.Background(), `{"sku":"A-1"}`, recordingInvoke(&calls, map[string]string{
+		"inventory_reserve": `{"id":"r-7"}`,
+		"mail_send":         `{}`,
+		"payments_hold":     `{}`,
+		"inventory_release": `{}`,
+	}))
+	var compensated *CompensatedError
+	require.ErrorAs(t, err, &compensated)
+	assert.Contains(t, compensated.Err.Error(), `step "charge" (payments_charge) failed`)
+	assert.Equal(t, []Compensation{
+		{Step: "hold", Tool: "payments_void", Failed: true, Err: compensated.Compensations[0].Err},
+		{Step: "reserve", Tool: "inventory_release"},
+	}, compensated.Compensations)
+	assert.Contains(t, err.Error(), `compensated steps reserve; compensation of step "hold" (payments_void) failed`)
+
+	var tools []string
+	for _, c := range calls {
+		tools = append(tools, c.tool)
+	}
+	assert.Equal(t, []string{"inventory_reserve", "mail_send", "payments_hold", "payments_charge", "payments_void", "inventory_release"}, tools)
+	assert.JSONEq(t, `{"id":"r-7"}`, calls[5].input)
+
+	// Compensations run even after the call's context is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	var compensationErr error
+	_, err = tool.Execute(ctx, `{}`, func(ctx context.Context, toolName string, inputJSON string) (string, error) {
+		switch toolName {
+		case "inventory_reserve":
+			cancel()
+			return `{"id":"r-8"}`, nil
+		case "inventory_release":
+			compensationErr = ctx.Err()
+			return `{}`, nil
+		}
+		return "", errors.New("unexpected call")
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.ErrorAs(t, err, &compensated)
+	assert.NoError(t, compensationErr)
+}
+
+func TestNewRegistry_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  []config.CompositeToolConfig
+		want string
+	}{
+		{
+			name: "forward reference",
+			cfg: []config.CompositeToolConfig{{Name: "c", Steps: []config.CompositeStepConfig{
+				{ID: "a", Tool: "x", Arguments: map[string]interface{}{"v": "$steps.b.v"}},
+				{ID: "b", Tool: "y"},
+			}}},
+			want: "unknown or later step",
+		},
+		{
+			name: "unknown root",
+			cfg: []config.CompositeToolConfig{{Name: "c", Steps: []config.CompositeStepConfig{
+				{Tool: "x", Arguments: map[string]interface{}{"v": "$env.HOME"}},
+			}}},
+			want: "references must start with",
+		},
+		{
+			name: "duplicate step",
+			cfg: []config.CompositeToolConfig{{Name: "c", Steps: []config.CompositeStepConfig{
+				{ID: "a", Tool: "x"}, {ID: "a", Tool: "y"},
+			}}},
+			want: "duplicate step id",
+		},
+		{
+			name: "duplicate tool",
+			cfg: []config.CompositeToolConfig{
+				{Name: "c", Steps: []config.CompositeStepConfig{{Tool: "x"}}},
+				{Name: "c", Steps: []config.CompositeStepConfig{{Tool: "y"}}},
+			},
+			want: "duplicate composite tool",
+		},
+		{
+			name: "compensation without tool",
+			cfg: []config.CompositeToolConfig{{Name: "c", Steps: []config.CompositeStepConfig{
+				{ID: "a", Tool: "x", Compensate: &config.CompensationConfig{}},
+			}}},
+			want: `compensation of step "a" requires a tool`,
+		},
+		{
+			name: "bad template",
+			cfg: []config.CompositeToolConfig{{Name: "c", Steps: []config.CompositeStepConfig{
+				{Tool: "x", Arguments: map[string]interface{}{"v": "{{.Args"}},
+			}}},
+			want: "invalid template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRegistry(tt.cfg)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestRegistry_MissingTools(t *testing.T) {
+	registry, err := NewRegistry([]config.CompositeToolConfig{
+		{Name: "one", Steps: []config.CompositeStepConfig{{Tool: "a"}, {Tool: "b"}}},
+		{Name: "two", Steps: []config.CompositeStepConfig{{Tool: "b"}, {Tool: "c"}}},
+	})
+	require.NoError(t, err)
+
+	missing := registry.MissingTools(func(name string) bool { return name == "a" })
+	assert.Equal(t, []string{"b", "c"}, missing)
+
+	var nilRegistry *Registry
+	assert.Empty(t, nilRegistry.Tools())
+}
@@ -2,9 +2,17 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
 	"time"
 )
 
+// aliasPattern matches the characters allowed in tool names
+var aliasPattern = regexp.MustCompile(`^[a-zA-Z0-9_.]+$`)
+
 // Config holds all configuration for the ggRMCP application
 type Config struct {
 	// Server configuration
@@ -24,6 +32,36 @@ type Config struct {
 
 	// Logging configuration
 	Logging LoggingConfig `json:"logging" yaml:"logging"`
+
+	// Record/replay configuration
+	Replay ReplayConfig `json:"replay" yaml:"replay"`
+
+	// Fault injection configuration
+	Chaos ChaosConfig `json:"chaos" yaml:"chaos"`
+
+	// Shadow traffic mirroring configuration
+	Mirror MirrorConfig `json:"mirror" yaml:"mirror"`
+
+	// Multi-replica routing configuration
+	Routing RoutingConfig `json:"routing" yaml:"routing"`
+
+	// Circuit breaker configuration
+	CircuitBreaker CircuitBreakerConfig `json:"circuit_breaker" yaml:"circuit_breaker"`
+
+	// Request hedging configuration
+	Hedging HedgingConfig `json:"hedging" yaml:"hedging"`
+
+	// Blue/green upstream switch configuration
+	UpstreamSwitch UpstreamSwitchConfig `json:"upstream_switch" yaml:"upstream_switch"`
+
+	// Runtime upstream registration configuration
+	UpstreamRegistration UpstreamRegistrationConfig `json:"upstream_registration" yaml:"upstream_registration"`
+
+	// Anomaly alerting configuration
+	Anomaly AnomalyConfig `json:"anomaly" yaml:"anomaly"`
+
+	// Tool call audit log configuration
+	Audit AuditConfig `json:"audit" yaml:"audit"`
 }
 
 // ServerConfig contains HTTP server settings
@@ -39,6 +77,107 @@ type ServerConfig struct {
 
 	// Security headers configuration
 	Security SecurityConfig `json:"security" yaml:"security"`
+
+	// Serve HTTPS with this certificate
+	TLS ServerTLSConfig `json:"tls" yaml:"tls"`
+
+	// Load shedding for tool calls under overload
+	Backpressure BackpressureConfig `json:"backpressure" yaml:"backpressure"`
+
+	// Session affinity for load-balanced deployments
+	Affinity AffinityConfig `json:"affinity" yaml:"affinity"`
+
+	// JSON-RPC batch requests
+	Batch BatchConfig `json:"batch" yaml:"batch"`
+
+	// Write the readiness event as JSON to this file once the gateway accepts
+	// connections; removed on shutdown
+	ReadyFile string `json:"ready_file" yaml:"ready_file"`
+
+	// Authentication of the /admin endpoints
+	Admin AdminConfig `json:"admin" yaml:"admin"`
+}
+
+// AdminConfig protects the /admin endpoints. Requests must carry the admin
+// token as a bearer token; without a token the endpoints are disabled.
+type AdminConfig struct {
+	// Environment variable holding the admin token
+	TokenEnv string `json:"token_env" yaml:"token_env"`
+}
+
+// BatchConfig controls JSON-RPC batch requests: a POST whose body is an
+// array of requests gets an array of responses in the same order
+type BatchConfig struct {
+	// Accept batch requests
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Maximum requests in one batch
+	MaxRequests int `json:"max_requests" yaml:"max_requests"`
+
+	// Requests of a batch handled at once (1 handles them in order)
+	MaxConcurrency int `json:"max_concurrency" yaml:"max_concurrency"`
+}
+
+// AffinityConfig pins clients to the replica holding their session. Sessions
+// live in the memory of the replica that created them, so every response
+// names that replica in a cookie and a header; load balancers can route on
+// either, and replicas forward requests pinned to a known peer themselves.
+type AffinityConfig struct {
+	// Emit and honor the affinity cookie and header
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// This replica's ID (defaults to the host name)
+	ReplicaID string `json:"replica_id" yaml:"replica_id"`
+
+	// Cookie and header carrying the replica ID; an empty name disables it
+	Cookie string `json:"cookie" yaml:"cookie"`
+	Header string `json:"header" yaml:"header"`
+
+	// Base URLs of the other replicas by replica ID; requests pinned to one
+	// of them are forwarded there
+	Peers map[string]string `json:"peers" yaml:"peers"`
+}
+
+func (c AffinityConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Cookie == "" && c.Header == "" {
+		return fmt.Errorf("cookie or header must be set")
+	}
+	for id, peer := range c.Peers {
+		u, err := url.Parse(peer)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid peer URL for %s: %s", id, peer)
+		}
+	}
+	return nil
+}
+
+// ServerTLSConfig contains the certificate the gateway serves HTTPS with;
+// without one it serves plain HTTP
+type ServerTLSConfig struct {
+	CertFile string `json:"cert_file" yaml:"cert_file"`
+	KeyFile  string `json:"key_file" yaml:"key_file"`
+}
+
+// Enabled reports whether a certificate is configured
+func (c ServerTLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// BackpressureConfig bounds the tool calls the gateway executes and queues
+// at once; calls beyond both limits, or that wait longer than QueueTimeout,
+// fail fast with an overloaded error instead of piling up until they time out
+type BackpressureConfig struct {
+	// Maximum concurrently executing tool calls (0 disables load shedding)
+	MaxInFlight int `json:"max_in_flight" yaml:"max_in_flight"`
+
+	// Maximum tool calls waiting for a slot
+	MaxQueue int `json:"max_queue" yaml:"max_queue"`
+
+	// Maximum time a call waits for a slot
+	QueueTimeout time.Duration `json:"queue_timeout" yaml:"queue_timeout"`
 }
 
 // SecurityConfig contains security-related settings
@@ -51,6 +190,165 @@ type SecurityConfig struct {
 
 	// Rate limiting
 	RateLimit RateLimitConfig `json:"rate_limit" yaml:"rate_limit"`
+
+	// Bearer token validation and identity forwarding
+	Auth AuthConfig `json:"auth" yaml:"auth"`
+
+	// Refuse to start without server TLS
+	RequireTLS bool `json:"require_tls" yaml:"require_tls"`
+
+	// Reject requests whose Origin header is not in CORS allowed_origins,
+	// protecting against DNS rebinding; requests without Origin are allowed
+	ValidateOrigin bool `json:"validate_origin" yaml:"validate_origin"`
+
+	// Client IP access control and trusted proxies
+	Network NetworkConfig `json:"network" yaml:"network"`
+}
+
+// NetworkConfig contains IP-based access control. Entries are IP addresses or
+// CIDR ranges. The client IP is the connection's remote address, or, when that
+// is a trusted proxy, the last X-Forwarded-For address not belonging to one.
+type NetworkConfig struct {
+	// Client IPs allowed to connect (empty allows all)
+	Allow []string `json:"allow" yaml:"allow"`
+
+	// Client IPs rejected; takes precedence over Allow
+	Deny []string `json:"deny" yaml:"deny"`
+
+	// Proxies whose X-Forwarded-For header is trusted
+	TrustedProxies []string `json:"trusted_proxies" yaml:"trusted_proxies"`
+}
+
+// ParsePrefixes parses IP addresses and CIDR ranges; a bare address becomes
+// a single-address prefix
+func ParsePrefixes(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address %q: %w", entry, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// validate checks that every entry parses
+func (c NetworkConfig) validate() error {
+	for _, entries := range [][]string{c.Allow, c.Deny, c.TrustedProxies} {
+		if _, err := ParsePrefixes(entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AuthConfig contains JWT validation settings. Claims of a validated token
+// can be forwarded to upstreams as gRPC metadata.
+type AuthConfig struct {
+	// Enable JWT validation
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Environment variable holding the secret for HS256/HS384/HS512 tokens
+	HMACSecretEnv string `json:"hmac_secret_env" yaml:"hmac_secret_env"`
+
+	// PEM file with the RSA or ECDSA public key for RS*/PS*/ES* tokens
+	PublicKeyFile string `json:"public_key_file" yaml:"public_key_file"`
+
+	// Expected iss and aud claims (empty skips the check)
+	Issuer   string `json:"issuer" yaml:"issuer"`
+	Audience string `json:"audience" yaml:"audience"`
+
+	// Allowed clock skew when checking exp and nbf
+	Leeway time.Duration `json:"leeway" yaml:"leeway"`
+
+	// Reject tokens without an exp claim, which would never expire
+	RequireExp bool `json:"require_exp" yaml:"require_exp"`
+
+	// Reject tool calls whose session has no valid token
+	Required bool `json:"required" yaml:"required"`
+
+	// Claims forwarded as gRPC metadata
+	ClaimHeaders []ClaimHeaderConfig `json:"claim_headers" yaml:"claim_headers"`
+
+	// Lockout after repeated authentication failures
+	Lockout LockoutConfig `json:"lockout" yaml:"lockout"`
+}
+
+// LockoutConfig contains brute-force protection settings. A client IP or
+// credential that fails authentication MaxFailures times within Window is
+// locked out for Duration; each further lockout doubles the duration up to
+// MaxDuration.
+type LockoutConfig struct {
+	// Enable lockout
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Failures within Window that trigger a lockout
+	MaxFailures int           `json:"max_failures" yaml:"max_failures"`
+	Window      time.Duration `json:"window" yaml:"window"`
+
+	// First lockout duration and upper bound for later ones
+	Duration    time.Duration `json:"duration" yaml:"duration"`
+	MaxDuration time.Duration `json:"max_duration" yaml:"max_duration"`
+}
+
+// ClaimHeaderConfig maps a token claim to a gRPC metadata key. Client-supplied
+// headers of the same name are never forwarded, so the upstream can trust it.
+type ClaimHeaderConfig struct {
+	// Claim name; nested claims use dots, e.g. "org.id"
+	Claim string `json:"claim" yaml:"claim"`
+
+	// gRPC metadata key, e.g. "x-user-id"
+	Header string `json:"header" yaml:"header"`
+}
+
+// validate checks the key sources and the claim mappings
+func (c AuthConfig) validate() error {
+	if !c.Enabled {
+		if len(c.ClaimHeaders) > 0 {
+			return fmt.Errorf("claim headers require auth to be enabled")
+		}
+		return nil
+	}
+	if c.HMACSecretEnv == "" && c.PublicKeyFile == "" {
+		return fmt.Errorf("auth requires hmac_secret_env or public_key_file")
+	}
+	if c.Leeway < 0 {
+		return fmt.Errorf("auth leeway cannot be negative")
+	}
+	if c.Lockout.Enabled {
+		if c.Lockout.MaxFailures <= 0 || c.Lockout.Window <= 0 || c.Lockout.Duration <= 0 {
+			return fmt.Errorf("auth lockout max failures, window and duration must be positive")
+		}
+		if c.Lockout.MaxDuration < c.Lockout.Duration {
+			return fmt.Errorf("auth lockout max duration must be at least duration")
+		}
+	}
+	seen := make(map[string]bool)
+	for _, mapping := range c.ClaimHeaders {
+		header := strings.ToLower(mapping.Header)
+		if mapping.Claim == "" || header == "" {
+			return fmt.Errorf("claim header mapping requires claim and header")
+		}
+		if IsUnsafeHeader(header) || strings.HasPrefix(header, "grpc-") || strings.HasPrefix(header, ":") {
+			return fmt.Errorf("claim header %q is reserved", mapping.Header)
+		}
+		if seen[header] {
+			return fmt.Errorf("duplicate claim header %q", mapping.Header)
+		}
+		seen[header] = true
+	}
+	return nil
 }
 
 // CORSConfig contains CORS settings
@@ -60,11 +358,86 @@ type CORSConfig struct {
 	AllowedHeaders []string `json:"allowed_headers" yaml:"allowed_headers"`
 }
 
+// Rate limiter backends
+const (
+	RateLimitBackendMemory = "memory"
+	RateLimitBackendRedis  = "redis"
+)
+
 // RateLimitConfig contains rate limiting settings
 type RateLimitConfig struct {
+	// Enforce RequestsPerMinute and BurstSize per session (per client IP for
+	// requests without a session)
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
 	RequestsPerMinute int           `json:"requests_per_minute" yaml:"requests_per_minute"`
 	BurstSize         int           `json:"burst_size" yaml:"burst_size"`
 	WindowSize        time.Duration `json:"window_size" yaml:"window_size"`
+
+	// Where limiter state lives: "memory" (per replica) or "redis" (shared by
+	// all replicas)
+	Backend string `json:"backend" yaml:"backend"`
+
+	// Redis connection for the redis backend
+	Redis RedisConfig `json:"redis" yaml:"redis"`
+
+	// Allow requests when the backend cannot be reached; otherwise reject them
+	FailOpen bool `json:"fail_open" yaml:"fail_open"`
+}
+
+// RedisConfig contains a Redis connection
+type RedisConfig struct {
+	// Address as host:port
+	Address string `json:"address" yaml:"address"`
+
+	// AUTH credentials (Username requires Redis 6 ACLs)
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+
+	// Environment variable holding the AUTH password; keeps the password out
+	// of the configuration file
+	PasswordEnv string `json:"password_env" yaml:"password_env"`
+
+	// Database index selected after connecting
+	DB int `json:"db" yaml:"db"`
+
+	// Prefix of every key written
+	KeyPrefix string `json:"key_prefix" yaml:"key_prefix"`
+
+	// Connect to Redis over TLS
+	TLS bool `json:"tls" yaml:"tls"`
+
+	// Timeout of connecting and of each command
+	DialTimeout time.Duration `json:"dial_timeout" yaml:"dial_timeout"`
+	Timeout     time.Duration `json:"timeout" yaml:"timeout"`
+
+	// Maximum idle connections kept open
+	PoolSize int `json:"pool_size" yaml:"pool_size"`
+}
+
+func (c RateLimitConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.RequestsPerMinute <= 0 || c.BurstSize <= 0 {
+		return fmt.Errorf("requests_per_minute and burst_size must be positive")
+	}
+	switch c.Backend {
+	case RateLimitBackendMemory:
+	case RateLimitBackendRedis:
+		if c.Redis.Address == "" {
+			return fmt.Errorf("redis address must be specified for the redis backend")
+		}
+		if c.Redis.DB < 0 || c.Redis.DialTimeout <= 0 || c.Redis.Timeout <= 0 || c.Redis.PoolSize <= 0 {
+			return fmt.Errorf("redis db must not be negative, dial_timeout, timeout and pool_size must be positive")
+		}
+		if c.Redis.Password != "" && c.Redis.PasswordEnv != "" {
+			return fmt.Errorf("redis password and password_env are mutually exclusive")
+		}
+	default:
+		return fmt.Errorf("invalid backend: %s", c.Backend)
+	}
+	return nil
 }
 
 // GRPCConfig contains gRPC client settings
@@ -87,14 +460,146 @@ type GRPCConfig struct {
 	// Reconnection settings
 	Reconnect ReconnectConfig `json:"reconnect" yaml:"reconnect"`
 
-	// Message size limits
-	MaxMessageSize int `json:"max_message_size" yaml:"max_message_size"`
+	// Message size limits; the send and receive limits default to MaxMessageSize
+	MaxMessageSize     int `json:"max_message_size" yaml:"max_message_size"`
+	MaxRecvMessageSize int `json:"max_recv_message_size" yaml:"max_recv_message_size"`
+	MaxSendMessageSize int `json:"max_send_message_size" yaml:"max_send_message_size"`
+
+	// Compression of requests sent to the upstream: "none" (default) or "gzip"
+	Compression string `json:"compression" yaml:"compression"`
+
+	// Behavior of calls while the upstream is not ready: "fail_fast"
+	// (default) errors immediately, "wait_for_ready" queues calls until the
+	// connection is ready or the request deadline expires
+	ReadyMode string `json:"ready_mode" yaml:"ready_mode"`
+
+	// :authority sent to the upstream instead of host:port (e.g. the
+	// virtual host a service mesh routes on)
+	Authority string `json:"authority" yaml:"authority"`
+
+	// Proxy used to reach the upstream: "http://[user:pass@]host:port" for
+	// HTTP CONNECT, "socks5://[user:pass@]host:port" for SOCKS5, "none" to
+	// ignore HTTPS_PROXY; empty uses HTTPS_PROXY from the environment
+	Proxy string `json:"proxy" yaml:"proxy"`
+
+	// TLS to the upstream; the gateway connects in plaintext by default
+	TLS UpstreamTLSConfig `json:"tls" yaml:"tls"`
+
+	// Warn on /health and /metrics when an upstream TLS certificate expires
+	// within this window
+	CertExpiryWarning time.Duration `json:"cert_expiry_warning" yaml:"cert_expiry_warning"`
 
 	// Header forwarding configuration
 	HeaderForwarding HeaderForwardingConfig `json:"header_forwarding" yaml:"header_forwarding"`
 
 	// FileDescriptorSet configuration
 	DescriptorSet DescriptorSetConfig `json:"descriptor_set" yaml:"descriptor_set"`
+
+	// Discover services in the background after startup; tools become
+	// available as they are discovered instead of after the whole pass
+	BackgroundDiscovery bool `json:"background_discovery" yaml:"background_discovery"`
+
+	// Boot from a discovery snapshot instead of discovering services
+	SnapshotPath string `json:"snapshot_path" yaml:"snapshot_path"`
+
+	// Upstream target resolution
+	Resolver ResolverConfig `json:"resolver" yaml:"resolver"`
+
+	// W3C baggage entries added to every upstream call
+	Baggage BaggageConfig `json:"baggage" yaml:"baggage"`
+
+	// Connection warm-up after discovery
+	WarmUp WarmUpConfig `json:"warm_up" yaml:"warm_up"`
+}
+
+// WarmUpConfig prepares each upstream after discovery so the first tool call
+// does not pay cold-start latency: message type resolvers and tool schemas
+// are built ahead of time and a grpc.health.v1 check is sent over every
+// upstream connection. Warm-up failures are logged and never block startup.
+type WarmUpConfig struct {
+	// Enable warm-up
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Time allowed for the health RPC of each upstream
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+// Baggage entry sources; header:, metadata: and static: are followed by a
+// header name, session metadata key or literal value
+const (
+	BaggageSourceSessionIDHash = "session_id_hash"
+	BaggageSourceTool          = "tool"
+	BaggageSourceHeader        = "header:"
+	BaggageSourceMetadata      = "metadata:"
+	BaggageSourceStatic        = "static:"
+)
+
+// BaggageConfig propagates W3C baggage (the "baggage" metadata key) on every
+// upstream call, so backend traces and logs can be segmented by MCP session
+// and tenant. Entries are merged into baggage forwarded from the client,
+// replacing client entries with the same key.
+type BaggageConfig struct {
+	// Enable baggage propagation
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Entries added to the baggage, in order
+	Entries []BaggageEntryConfig `json:"entries" yaml:"entries"`
+
+	// Also send every entry as its own metadata key with this prefix
+	// (e.g. "x-mcp-"); empty sends the baggage header only
+	MetadataPrefix string `json:"metadata_prefix" yaml:"metadata_prefix"`
+
+	// Salt mixed into session_id_hash, so backends cannot match hashes
+	// against session IDs seen elsewhere
+	HashSalt string `json:"hash_salt" yaml:"hash_salt"`
+}
+
+// BaggageEntryConfig names a baggage entry and where its value comes from:
+// "session_id_hash" (a truncated SHA-256 of the session ID, never the ID
+// itself), "tool", "header:<name>" (a header captured at initialize),
+// "metadata:<key>" (session metadata) or "static:<value>". Entries without
+// a value for the call are omitted.
+type BaggageEntryConfig struct {
+	Key    string `json:"key" yaml:"key"`
+	Source string `json:"source" yaml:"source"`
+}
+
+// validate checks the baggage entries
+func (b BaggageConfig) validate() error {
+	if !b.Enabled {
+		return nil
+	}
+	if len(b.Entries) == 0 {
+		return fmt.Errorf("baggage requires at least one entry")
+	}
+	for _, entry := range b.Entries {
+		if entry.Key == "" || strings.ContainsAny(entry.Key, " \t,;=\"") {
+			return fmt.Errorf("invalid baggage key %q", entry.Key)
+		}
+		switch {
+		case entry.Source == BaggageSourceSessionIDHash, entry.Source == BaggageSourceTool:
+		case strings.HasPrefix(entry.Source, BaggageSourceHeader) && len(entry.Source) > len(BaggageSourceHeader):
+		case strings.HasPrefix(entry.Source, BaggageSourceMetadata) && len(entry.Source) > len(BaggageSourceMetadata):
+		case strings.HasPrefix(entry.Source, BaggageSourceStatic):
+		default:
+			return fmt.Errorf("invalid source %q for baggage key %s", entry.Source, entry.Key)
+		}
+	}
+	return nil
+}
+
+// ResolverConfig selects how upstream targets (the primary, replicas and the
+// mirror) are resolved to the addresses the gateway connects to
+type ResolverConfig struct {
+	// Resolver name: "" dials host:port directly; "static", "dns", "k8s" or
+	// a resolver registered with grpc.RegisterResolver
+	Name string `json:"name" yaml:"name"`
+
+	// Addresses (host:port) returned by the static resolver
+	Addresses []string `json:"addresses" yaml:"addresses"`
+
+	// How often targets are re-resolved
+	RefreshInterval time.Duration `json:"refresh_interval" yaml:"refresh_interval"`
 }
 
 // KeepAliveConfig contains keep-alive settings
@@ -104,6 +609,146 @@ type KeepAliveConfig struct {
 	PermitWithoutStream bool          `json:"permit_without_stream" yaml:"permit_without_stream"`
 }
 
+// ConnectionConfig contains connection settings of one upstream. Zero values
+// inherit the settings of the grpc section; a keep-alive override applies as
+// a whole when its Time is set.
+type ConnectionConfig struct {
+	ConnectTimeout     time.Duration     `json:"connect_timeout" yaml:"connect_timeout"`
+	KeepAlive          KeepAliveConfig   `json:"keep_alive" yaml:"keep_alive"`
+	MaxRecvMessageSize int               `json:"max_recv_message_size" yaml:"max_recv_message_size"`
+	MaxSendMessageSize int               `json:"max_send_message_size" yaml:"max_send_message_size"`
+	Compression        string            `json:"compression" yaml:"compression"`
+	ReadyMode          string            `json:"ready_mode" yaml:"ready_mode"`
+	Authority          string            `json:"authority" yaml:"authority"`
+	Proxy              string            `json:"proxy" yaml:"proxy"`
+	TLS                UpstreamTLSConfig `json:"tls" yaml:"tls"`
+}
+
+// UpstreamTLSConfig secures the connection to an upstream. Without a CA file
+// the upstream certificate is verified against the system roots; a client
+// certificate enables mutual TLS.
+type UpstreamTLSConfig struct {
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	CAFile   string `json:"ca_file" yaml:"ca_file"`
+	CertFile string `json:"cert_file" yaml:"cert_file"`
+	KeyFile  string `json:"key_file" yaml:"key_file"`
+
+	// Name verified against the upstream certificate (default: the
+	// authority, i.e. the upstream host)
+	ServerName string `json:"server_name" yaml:"server_name"`
+}
+
+// Upstream compression settings
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+)
+
+// ProxyNone disables proxying, including HTTPS_PROXY from the environment
+const ProxyNone = "none"
+
+// Ready modes of upstream calls
+const (
+	ReadyModeFailFast     = "fail_fast"
+	ReadyModeWaitForReady = "wait_for_ready"
+)
+
+// Connection returns the effective connection settings of an upstream with
+// the given overrides
+func (g GRPCConfig) Connection(override ConnectionConfig) ConnectionConfig {
+	conn := ConnectionConfig{
+		ConnectTimeout:     g.ConnectTimeout,
+		KeepAlive:          g.KeepAlive,
+		MaxRecvMessageSize: g.MaxRecvMessageSize,
+		MaxSendMessageSize: g.MaxSendMessageSize,
+		Compression:        g.Compression,
+		ReadyMode:          g.ReadyMode,
+		Authority:          g.Authority,
+		Proxy:              g.Proxy,
+		TLS:                g.TLS,
+	}
+	if conn.MaxRecvMessageSize == 0 {
+		conn.MaxRecvMessageSize = g.MaxMessageSize
+	}
+	if conn.MaxSendMessageSize == 0 {
+		conn.MaxSendMessageSize = g.MaxMessageSize
+	}
+
+	if override.ConnectTimeout > 0 {
+		conn.ConnectTimeout = override.ConnectTimeout
+	}
+	if override.KeepAlive.Time > 0 {
+		conn.KeepAlive = override.KeepAlive
+	}
+	if override.MaxRecvMessageSize > 0 {
+		conn.MaxRecvMessageSize = override.MaxRecvMessageSize
+	}
+	if override.MaxSendMessageSize > 0 {
+		conn.MaxSendMessageSize = override.MaxSendMessageSize
+	}
+	if override.Compression != "" {
+		conn.Compression = override.Compression
+	}
+	if override.ReadyMode != "" {
+		conn.ReadyMode = override.ReadyMode
+	}
+	if override.Authority != "" {
+		conn.Authority = override.Authority
+	}
+	if override.Proxy != "" {
+		conn.Proxy = override.Proxy
+	}
+	if override.TLS.Enabled {
+		conn.TLS = override.TLS
+	}
+	return conn
+}
+
+func (c ConnectionConfig) validate() error {
+	if c.MaxRecvMessageSize <= 0 || c.MaxSendMessageSize <= 0 {
+		return fmt.Errorf("max message sizes must be positive")
+	}
+	if c.KeepAlive.Time < 0 || c.KeepAlive.Timeout < 0 {
+		return fmt.Errorf("keep-alive durations cannot be negative")
+	}
+	switch c.Compression {
+	case "", CompressionNone, CompressionGzip:
+	default:
+		return fmt.Errorf("invalid compression: %s", c.Compression)
+	}
+	if err := validateReadyMode(c.ReadyMode); err != nil {
+		return err
+	}
+	if c.Proxy != "" && c.Proxy != ProxyNone {
+		u, err := url.Parse(c.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		switch u.Scheme {
+		case "http", "socks5", "socks5h":
+		default:
+			return fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+		}
+		if u.Host == "" {
+			return fmt.Errorf("proxy URL must include a host")
+		}
+	}
+	if c.TLS.Enabled && (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		return fmt.Errorf("upstream TLS requires both cert_file and key_file for a client certificate")
+	}
+	return nil
+}
+
+// validateReadyMode checks a ready mode; empty keeps the default
+func validateReadyMode(mode string) error {
+	switch mode {
+	case "", ReadyModeFailFast, ReadyModeWaitForReady:
+		return nil
+	default:
+		return fmt.Errorf("invalid ready mode: %s", mode)
+	}
+}
+
 // ReconnectConfig contains reconnection settings
 type ReconnectConfig struct {
 	Interval    time.Duration `json:"interval" yaml:"interval"`
@@ -126,6 +771,47 @@ type HeaderForwardingConfig struct {
 
 	// Case sensitive header matching
 	CaseSensitive bool `json:"case_sensitive" yaml:"case_sensitive"`
+
+	// Allow forwarding unsafe headers (see IsUnsafeHeader) through
+	// allowed_headers or forward_all. Without it they are always dropped.
+	AllowUnsafeHeaders bool `json:"allow_unsafe_headers" yaml:"allow_unsafe_headers"`
+}
+
+// unsafeHeaders are never forwarded unless AllowUnsafeHeaders is set: hop-by-hop
+// headers describe the client's connection to the gateway, and the rest carry
+// credentials or framing that belong to the gateway rather than the upstream
+var unsafeHeaders = map[string]bool{
+	"connection":        true,
+	"keep-alive":        true,
+	"te":                true,
+	"trailer":           true,
+	"transfer-encoding": true,
+	"upgrade":           true,
+	"host":              true,
+	"content-length":    true,
+	"cookie":            true,
+	"set-cookie":        true,
+	"mcp-session-id":    true,
+}
+
+// IsUnsafeHeader reports whether a header is unsafe to forward. Matching is
+// case-insensitive; every Proxy-* header is unsafe.
+func IsUnsafeHeader(name string) bool {
+	name = strings.ToLower(name)
+	return unsafeHeaders[name] || strings.HasPrefix(name, "proxy-")
+}
+
+// validate checks that unsafe headers are only allowed explicitly
+func (c HeaderForwardingConfig) validate() error {
+	if c.AllowUnsafeHeaders {
+		return nil
+	}
+	for _, name := range c.AllowedHeaders {
+		if IsUnsafeHeader(name) {
+			return fmt.Errorf("header %q is unsafe to forward; set allow_unsafe_headers to allow it", name)
+		}
+	}
+	return nil
 }
 
 // DescriptorSetConfig contains FileDescriptorSet settings
@@ -150,6 +836,11 @@ type MCPConfig struct {
 
 	// Protocol version
 	ProtocolVersion string `json:"protocol_version" yaml:"protocol_version"`
+
+	// ErrorDetail is how much of a failed tool call's error reaches the
+	// client: "verbose" (full upstream error), "standard" (sensitive words
+	// redacted) or "strict" (generic message, full error only in the logs)
+	ErrorDetail string `json:"error_detail" yaml:"error_detail"`
 }
 
 // ValidationConfig contains validation limits
@@ -173,6 +864,54 @@ type SessionConfig struct {
 
 	// Session rate limiting
 	RateLimit SessionRateLimitConfig `json:"rate_limit" yaml:"rate_limit"`
+
+	// Maximum tool calls executing concurrently per session (0 = unlimited)
+	MaxConcurrentCalls int `json:"max_concurrent_calls" yaml:"max_concurrent_calls"`
+
+	// Session metadata injection
+	Metadata SessionMetadataConfig `json:"metadata" yaml:"metadata"`
+
+	// Tool-call history readable as the ggrmcp://session/history resource
+	History SessionHistoryConfig `json:"history" yaml:"history"`
+}
+
+// SessionHistoryConfig keeps the latest tool calls of each session, with
+// their arguments and results, so a client that resumes the session can read
+// them back as the ggrmcp://session/history resource. The history lives with
+// the session in memory and ends when the session does.
+type SessionHistoryConfig struct {
+	// Enable the session history
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Calls kept per session, oldest dropped first
+	MaxCalls int `json:"max_calls" yaml:"max_calls"`
+
+	// Results longer than this many bytes are cut in the history
+	MaxResultBytes int `json:"max_result_bytes" yaml:"max_result_bytes"`
+}
+
+// SessionMetadataConfig controls client-supplied session metadata (e.g. tenant
+// ID, locale) that is injected into every tool call of the session
+type SessionMetadataConfig struct {
+	// Enable session metadata
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Headers with this prefix set session metadata (e.g. Mcp-Meta-Tenant-Id)
+	HeaderPrefix string `json:"header_prefix" yaml:"header_prefix"`
+
+	// Inject metadata as gRPC metadata on every call
+	InjectMetadata bool `json:"inject_metadata" yaml:"inject_metadata"`
+
+	// Prefix added to gRPC metadata keys; the prefixed keys must pass the
+	// header forwarding rules
+	MetadataKeyPrefix string `json:"metadata_key_prefix" yaml:"metadata_key_prefix"`
+
+	// Use metadata values as defaults for matching top-level string arguments
+	InjectArguments bool `json:"inject_arguments" yaml:"inject_arguments"`
+
+	// Limits on client-supplied metadata
+	MaxEntries     int `json:"max_entries" yaml:"max_entries"`
+	MaxValueLength int `json:"max_value_length" yaml:"max_value_length"`
 }
 
 // SessionRateLimitConfig contains session-specific rate limiting
@@ -191,42 +930,979 @@ type ToolsConfig struct {
 	MaxDepth      int `json:"max_depth" yaml:"max_depth"`
 	MaxFields     int `json:"max_fields" yaml:"max_fields"`
 	MaxEnumValues int `json:"max_enum_values" yaml:"max_enum_values"`
-}
 
-// CacheConfig contains caching settings
-type CacheConfig struct {
-	Enabled    bool          `json:"enabled" yaml:"enabled"`
-	TTL        time.Duration `json:"ttl" yaml:"ttl"`
-	MaxEntries int           `json:"max_entries" yaml:"max_entries"`
-}
+	// Per-tool argument defaults and fixed values
+	Arguments []ToolArgumentsConfig `json:"arguments" yaml:"arguments"`
 
-// LoggingConfig contains logging settings
-type LoggingConfig struct {
-	Level       string `json:"level" yaml:"level"`
-	Format      string `json:"format" yaml:"format"`
-	Development bool   `json:"development" yaml:"development"`
-}
+	// Per-tool response transformation rules
+	Responses []ToolResponseConfig `json:"responses" yaml:"responses"`
 
-// Default returns a configuration with sensible defaults
-func Default() *Config {
-	return &Config{
-		Server: ServerConfig{
-			Port:           50053,
-			Timeout:        30 * time.Second,
-			MaxRequestSize: 4 * 1024 * 1024, // 4MB
-			Security: SecurityConfig{
-				EnableHeaders: true,
-				CORS: CORSConfig{
-					AllowedOrigins: []string{"*"},
-					AllowedMethods: []string{"GET", "POST", "OPTIONS"},
-					AllowedHeaders: []string{"Content-Type", "Authorization", "Mcp-Session-Id"},
-				},
-				RateLimit: RateLimitConfig{
-					RequestsPerMinute: 1000,
-					BurstSize:         100,
+	// Per-tool CEL scripts computing argument and response fields
+	Scripts []ToolScriptConfig `json:"scripts" yaml:"scripts"`
+
+	// Composite tools chaining several discovered tools
+	Composites []CompositeToolConfig `json:"composites" yaml:"composites"`
+
+	// Short names and display titles for tools
+	Aliases []ToolAliasConfig `json:"aliases" yaml:"aliases"`
+
+	// Grouping hints emitted in tools/list
+	Grouping ToolGroupingConfig `json:"grouping" yaml:"grouping"`
+
+	// Markdown documentation appended to tool descriptions
+	Docs ToolDocsConfig `json:"docs" yaml:"docs"`
+
+	// Hiding or marking tools of services reported as NOT_SERVING
+	HealthGating HealthGatingConfig `json:"health_gating" yaml:"health_gating"`
+
+	// Formatting of tool call results
+	Output ToolOutputConfig `json:"output" yaml:"output"`
+
+	// Per-tool ready modes overriding the upstream's
+	ReadyModes []ToolReadyModeConfig `json:"ready_modes" yaml:"ready_modes"`
+
+	// Per-tool ceilings on the size of call arguments
+	InputLimits []ToolInputLimitConfig `json:"input_limits" yaml:"input_limits"`
+
+	// Cost, latency and side-effect hints emitted in tools/list
+	Hints ToolHintsConfig `json:"hints" yaml:"hints"`
+
+	// Per-tool usage statistics
+	Usage ToolUsageConfig `json:"usage" yaml:"usage"`
+
+	// Per-tool argument validation and sanitizing
+	Sanitizers []ToolSanitizerConfig `json:"sanitizers" yaml:"sanitizers"`
+
+	// Automatic iteration of paginated list RPCs
+	Pagination ToolPaginationConfig `json:"pagination" yaml:"pagination"`
+
+	// Waiting for google.longrunning operations to complete
+	LongRunning LongRunningConfig `json:"long_running" yaml:"long_running"`
+
+	// Server-streaming methods exposed as tools with aggregated results
+	ServerStreaming ServerStreamingConfig `json:"server_streaming" yaml:"server_streaming"`
+
+	// Client-streaming and bidirectional methods bridged over HTTP streams
+	BidiStreaming BidiStreamingConfig `json:"bidi_streaming" yaml:"bidi_streaming"`
+
+	// Formatting of proto comments used as descriptions
+	Comments ToolCommentsConfig `json:"comments" yaml:"comments"`
+
+	// Constraint summaries appended to field descriptions
+	Constraints ToolConstraintsConfig `json:"constraints" yaml:"constraints"`
+
+	// Flattening of request wrappers holding a single nested message
+	Flattening ToolFlatteningConfig `json:"flattening" yaml:"flattening"`
+
+	// Built-in tool returning the documentation of another tool
+	DescribeTool ToolDescribeConfig `json:"describe_tool" yaml:"describe_tool"`
+
+	// Built-in tools listing services and reporting gateway status
+	Introspection ToolIntrospectionConfig `json:"introspection" yaml:"introspection"`
+
+	// Coercion of mistyped tool arguments before they are unmarshaled
+	Coercion ToolCoercionConfig `json:"coercion" yaml:"coercion"`
+
+	// Matching of loosely written enum names in tool arguments
+	Enums ToolEnumsConfig `json:"enums" yaml:"enums"`
+
+	// Summarization of results that exceed a size budget
+	Summary ToolSummaryConfig `json:"summary" yaml:"summary"`
+}
+
+// Sanitizer actions
+const (
+	SanitizeReject = "reject"
+	SanitizeStrip  = "strip"
+)
+
+// ToolSanitizerConfig checks the string arguments of tools matching a name or
+// glob before they reach the upstream, e.g. to keep prompt-injection payloads
+// out of backends. All matching rules apply, in order.
+type ToolSanitizerConfig struct {
+	// Tool name or path.Match glob (e.g. "tickets_*")
+	Tool string `json:"tool" yaml:"tool"`
+
+	// Dot-separated argument paths to check (arrays are traversed element by
+	// element); empty checks every string argument
+	Fields []string `json:"fields" yaml:"fields"`
+
+	// Maximum length in characters
+	MaxLength int `json:"max_length" yaml:"max_length"`
+
+	// Regular expression character class every character must belong to,
+	// e.g. "[\p{L}\p{N} .,_-]"
+	AllowedChars string `json:"allowed_chars" yaml:"allowed_chars"`
+
+	// Regular expressions a value must not match
+	Deny []string `json:"deny" yaml:"deny"`
+
+	// Remove control and invisible format characters (zero-width, bidi
+	// overrides) before the other checks; newlines and tabs are kept
+	StripInvisible bool `json:"strip_invisible" yaml:"strip_invisible"`
+
+	// "reject" (default) fails the call, "strip" removes denied matches and
+	// disallowed characters and truncates to MaxLength
+	Action string `json:"action" yaml:"action"`
+}
+
+// ToolPaginationConfig makes the gateway follow pagination of list RPCs so
+// agents get one combined result instead of managing a page_token loop. A
+// method is paginated when its request has a page_token field and its
+// response a next_page_token field and exactly one repeated field holding the
+// items. Iteration stops at the last page or after the page that reaches a
+// cap; the combined result then keeps the next token so the agent can resume.
+type ToolPaginationConfig struct {
+	// Enable automatic pagination
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Tools to paginate (names or path.Match globs); empty means every
+	// paginated method
+	Tools []string `json:"tools" yaml:"tools"`
+
+	// Maximum pages fetched per call
+	MaxPages int `json:"max_pages" yaml:"max_pages"`
+
+	// Items after which no further page is fetched
+	MaxItems int `json:"max_items" yaml:"max_items"`
+}
+
+// LongRunningConfig makes the gateway wait for methods returning a
+// google.longrunning.Operation: the operation is polled with
+// Operations.GetOperation on the same upstream until it is done, and the
+// unpacked response (or the operation's error) is returned instead of the
+// operation envelope. An operation still running after MaxWait is returned
+// as is, so the agent can check on it later.
+type LongRunningConfig struct {
+	// Enable waiting for long-running operations
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Tools to wait for (names or path.Match globs); empty means every
+	// method returning an operation
+	Tools []string `json:"tools" yaml:"tools"`
+
+	// Delay before the first poll; it doubles after each poll up to
+	// MaxPollInterval
+	PollInterval    time.Duration `json:"poll_interval" yaml:"poll_interval"`
+	MaxPollInterval time.Duration `json:"max_poll_interval" yaml:"max_poll_interval"`
+
+	// How long to wait for an operation; the tool call timeout also applies
+	MaxWait time.Duration `json:"max_wait" yaml:"max_wait"`
+}
+
+// ServerStreamingConfig exposes server-streaming methods as tools. The
+// streamed messages are collected and returned together as
+// {"messages": [...]}. A stream cut short by the call deadline or by
+// MaxMessages returns the messages received so far, marked with
+// "truncated": true and a "truncationReason". Clients that send a progress
+// token and accept server-sent events also receive each message as it
+// arrives, as a progress notification.
+type ServerStreamingConfig struct {
+	// Enable server-streaming tools
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Messages after which the stream is cancelled (0 means no limit)
+	MaxMessages int `json:"max_messages" yaml:"max_messages"`
+
+	// Time reserved before the call deadline for returning partial results;
+	// the stream itself is stopped this much earlier
+	DeadlineMargin time.Duration `json:"deadline_margin" yaml:"deadline_margin"`
+}
+
+// BidiStreamingConfig bridges client-streaming and bidirectional methods,
+// which cannot be expressed as a single tool call, over long-lived HTTP
+// streams: a client opens a stream for a method, posts messages to it and
+// reads the responses as server-sent events. Streams belong to the MCP
+// session that opened them.
+type BidiStreamingConfig struct {
+	// Enable the /streams endpoints
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Maximum number of open streams across all sessions
+	MaxStreams int `json:"max_streams" yaml:"max_streams"`
+
+	// Maximum number of open streams of one session
+	MaxStreamsPerSession int `json:"max_streams_per_session" yaml:"max_streams_per_session"`
+
+	// Messages buffered in each direction; when the responses are not read,
+	// the gateway stops reading from the upstream and gRPC flow control
+	// slows the upstream down
+	Buffer int `json:"buffer" yaml:"buffer"`
+
+	// Streams without messages or a reader for this long are cancelled
+	IdleTimeout time.Duration `json:"idle_timeout" yaml:"idle_timeout"`
+}
+
+// ToolCommentsConfig controls how proto comments become tool, schema and
+// field descriptions
+type ToolCommentsConfig struct {
+	// Strip comment markers and leading '*', join wrapped lines into
+	// paragraphs and collapse whitespace
+	Normalize bool `json:"normalize" yaml:"normalize"`
+
+	// Include detached comments (separated from the definition by a blank
+	// line) before the leading comment of messages, fields and enums
+	Detached bool `json:"detached" yaml:"detached"`
+
+	// Maximum length in characters of a description (0 means no limit)
+	MaxLength int `json:"max_length" yaml:"max_length"`
+}
+
+// ToolConstraintsConfig controls the constraint summaries appended to field
+// descriptions. Some models weigh descriptions more than schema keywords, so
+// known constraints (protovalidate and protoc-gen-validate rules, enum values,
+// formats) are repeated there in words, e.g. "Constraints: 1–64 chars".
+type ToolConstraintsConfig struct {
+	Summary bool `json:"summary" yaml:"summary"`
+}
+
+// ToolUsageConfig controls the per-tool usage statistics served on
+// /admin/tools/usage
+type ToolUsageConfig struct {
+	// Latencies kept per tool for the p50/p95 estimates
+	Samples int `json:"samples" yaml:"samples"`
+
+	// Also expose the statistics to MCP clients as the ggrmcp://usage/tools resource
+	Resource bool `json:"resource" yaml:"resource"`
+}
+
+// Latency classes
+const (
+	LatencyFast     = "fast"
+	LatencyModerate = "moderate"
+	LatencySlow     = "slow"
+)
+
+// Cost tiers
+const (
+	CostLow    = "low"
+	CostMedium = "medium"
+	CostHigh   = "high"
+)
+
+// Side-effect levels
+const (
+	SideEffectsNone       = "none"
+	SideEffectsIdempotent = "idempotent"
+	SideEffectsMutating   = "mutating"
+)
+
+// ToolHintsConfig controls the planning hints (latency class, cost tier and
+// side-effect level) emitted in each tool's _meta, which let agent planners
+// prefer cheap, fast and safe tools
+type ToolHintsConfig struct {
+	// Emit the hints in tools/list
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Derive the latency class of tools without a configured one from the
+	// observed duration of successful calls
+	Observed bool `json:"observed" yaml:"observed"`
+
+	// Calls observed before a latency class is derived
+	MinSamples int `json:"min_samples" yaml:"min_samples"`
+
+	// Observed latencies below FastThreshold are "fast", above SlowThreshold "slow"
+	FastThreshold time.Duration `json:"fast_threshold" yaml:"fast_threshold"`
+	SlowThreshold time.Duration `json:"slow_threshold" yaml:"slow_threshold"`
+
+	// Hints set by tool name or glob; for each hint the first rule setting it wins
+	Rules []ToolHintConfig `json:"rules" yaml:"rules"`
+}
+
+// ToolHintConfig sets hints for tools matching a name or glob. Empty fields
+// are left to later rules or, for latency, to observation.
+type ToolHintConfig struct {
+	// Tool name or path.Match glob (e.g. "reports_*")
+	Tool string `json:"tool" yaml:"tool"`
+
+	// "fast", "moderate" or "slow"
+	Latency string `json:"latency" yaml:"latency"`
+
+	// "low", "medium" or "high"
+	Cost string `json:"cost" yaml:"cost"`
+
+	// "none", "idempotent" or "mutating"
+	SideEffects string `json:"side_effects" yaml:"side_effects"`
+}
+
+// validate checks the hint levels and observation thresholds
+func (c ToolHintsConfig) validate() error {
+	if c.Observed {
+		if c.MinSamples < 1 {
+			return fmt.Errorf("tool hints min_samples must be at least 1")
+		}
+		if c.FastThreshold <= 0 || c.SlowThreshold <= c.FastThreshold {
+			return fmt.Errorf("tool hints thresholds must be positive with slow_threshold above fast_threshold")
+		}
+	}
+	for _, rule := range c.Rules {
+		if _, err := path.Match(rule.Tool, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", rule.Tool, err)
+		}
+		switch rule.Latency {
+		case "", LatencyFast, LatencyModerate, LatencySlow:
+		default:
+			return fmt.Errorf("invalid latency class for %q: %s", rule.Tool, rule.Latency)
+		}
+		switch rule.Cost {
+		case "", CostLow, CostMedium, CostHigh:
+		default:
+			return fmt.Errorf("invalid cost tier for %q: %s", rule.Tool, rule.Cost)
+		}
+		switch rule.SideEffects {
+		case "", SideEffectsNone, SideEffectsIdempotent, SideEffectsMutating:
+		default:
+			return fmt.Errorf("invalid side-effect level for %q: %s", rule.Tool, rule.SideEffects)
+		}
+	}
+	return nil
+}
+
+// ToolInputLimitConfig caps the size of the arguments accepted by tools
+// matching a name or glob, for upstream methods that take payloads far larger
+// than an agent should ever construct. The first matching rule wins.
+type ToolInputLimitConfig struct {
+	// Tool name or path.Match glob (e.g. "uploads_*")
+	Tool string `json:"tool" yaml:"tool"`
+
+	// Maximum size in bytes of the JSON-encoded arguments
+	MaxBytes int `json:"max_bytes" yaml:"max_bytes"`
+}
+
+// ToolReadyModeConfig sets the ready mode of tools matching a name or glob,
+// e.g. wait_for_ready for idempotent reads and fail_fast for calls that
+// should rather be retried by the agent
+type ToolReadyModeConfig struct {
+	// Tool name or path.Match glob (e.g. "orders_*")
+	Tool string `json:"tool" yaml:"tool"`
+
+	// "fail_fast" or "wait_for_ready"
+	Mode string `json:"mode" yaml:"mode"`
+}
+
+// ToolOutputConfig controls how tool call results are serialized
+type ToolOutputConfig struct {
+	// Canonical re-encodes results with sorted object keys and no
+	// insignificant whitespace, so equal responses are byte-identical
+	Canonical bool `json:"canonical" yaml:"canonical"`
+
+	// NonFinite controls how NaN and ±Infinity in float/double fields
+	// (rendered by protojson as the strings "NaN", "Infinity", "-Infinity")
+	// are returned: "" keeps the strings, "null" replaces them with null,
+	// "annotate" replaces them with null and lists the original values in
+	// the result _meta
+	NonFinite string `json:"nonFinite" yaml:"non_finite"`
+
+	// FloatPrecision rounds float/double fields to this many significant
+	// digits (0 leaves them unchanged)
+	FloatPrecision int `json:"floatPrecision" yaml:"float_precision"`
+
+	// Result composes successful tools/call results: "text" (default) returns
+	// the JSON as a text content block, "structured" only as structuredContent,
+	// "both" as both, and "auto" as both for clients that negotiated protocol
+	// version 2025-06-18 or later and as text otherwise. Clients can pick
+	// text, structured or both for their session in initialize.
+	Result string `json:"result" yaml:"result"`
+
+	// TextFormats picks the format of the text content block per tool; tools
+	// without a matching rule get the JSON. Later rules override earlier
+	// ones. structuredContent is always JSON.
+	TextFormats []ToolTextFormatConfig `json:"textFormats" yaml:"text_formats"`
+}
+
+// ToolTextFormatConfig selects the text format of a tool's results
+type ToolTextFormatConfig struct {
+	// Tool name or path.Match glob (e.g. "orders_*")
+	Tool string `json:"tool" yaml:"tool"`
+
+	// "json" (the JSON as serialized above), "pretty" (indented JSON), "yaml",
+	// or "csv" (one row per item, for results that are a list of messages or
+	// hold nothing but one; other results stay JSON)
+	Format string `json:"format" yaml:"format"`
+}
+
+// Tool result compositions
+const (
+	ResultText       = "text"
+	ResultStructured = "structured"
+	ResultBoth       = "both"
+	ResultAuto       = "auto"
+)
+
+// Tool result text formats
+const (
+	TextFormatJSON   = "json"
+	TextFormatPretty = "pretty"
+	TextFormatYAML   = "yaml"
+	TextFormatCSV    = "csv"
+)
+
+// Non-finite float handling modes
+const (
+	NonFiniteKeep     = ""
+	NonFiniteNull     = "null"
+	NonFiniteAnnotate = "annotate"
+)
+
+// Health gating modes
+const (
+	HealthGatingOff  = ""
+	HealthGatingHide = "hide"
+	HealthGatingMark = "mark"
+)
+
+// HealthGatingConfig controls how tools react to the per-service
+// grpc.health.v1 status of the upstream. Only an explicit NOT_SERVING status
+// gates a tool; services without health reporting are treated as serving.
+type HealthGatingConfig struct {
+	// Mode is one of "" (off), "hide" (remove from tools/list and reject
+	// calls) or "mark" (flag in tools/list, calls still go through)
+	Mode string `json:"mode" yaml:"mode"`
+
+	// Interval between health checks
+	Interval time.Duration `json:"interval" yaml:"interval"`
+}
+
+// ToolDocsConfig points to a directory of markdown files named after the
+// generated tool names (e.g. "hello_helloservice_sayhello.md"); a file's
+// content is appended to the tool's description
+type ToolDocsConfig struct {
+	// Directory containing the markdown files (empty disables docs)
+	Dir string `json:"dir" yaml:"dir"`
+
+	// Maximum length in characters of the appended documentation
+	MaxLength int `json:"max_length" yaml:"max_length"`
+}
+
+// ToolGroupingConfig controls the grouping hints (service, package and
+// category) that let clients organize large tool catalogs
+type ToolGroupingConfig struct {
+	// Emit the hints in each tool's _meta
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Also prefix titles with the group, for clients that ignore _meta
+	TitlePrefix bool `json:"title_prefix" yaml:"title_prefix"`
+
+	// Categories assigned by tool name or glob; the first match wins
+	Categories []ToolCategoryConfig `json:"categories" yaml:"categories"`
+}
+
+// ToolCategoryConfig assigns a category to tools matching a name or glob
+type ToolCategoryConfig struct {
+	// Tool name or path.Match glob (e.g. "billing_*")
+	Tool string `json:"tool" yaml:"tool"`
+
+	// Category name (e.g. "Billing")
+	Category string `json:"category" yaml:"category"`
+}
+
+// ToolFlatteningConfig controls flattening of request wrappers. A request
+// message whose only field is a nested message (the common *Request wrapper
+// around a resource) is exposed with the nested message's fields at the top
+// level of the input schema; the gateway re-nests the arguments before the
+// call.
+type ToolFlatteningConfig struct {
+	// Flatten request wrappers
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Tool names or globs to flatten (empty means every tool)
+	Tools []string `json:"tools" yaml:"tools"`
+}
+
+// ToolDescribeConfig controls the built-in ggrmcp_describe_tool tool, which
+// returns the full schema, comments and example arguments of another tool so
+// agents can look up documentation on demand
+type ToolDescribeConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+}
+
+// ToolIntrospectionConfig controls the built-in ggrmcp_list_services and
+// ggrmcp_gateway_status tools, which let agents and users diagnose missing
+// tools (scope, health, discovery failures) and upstream connectivity
+type ToolIntrospectionConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+}
+
+// ToolCoercionConfig controls coercion of common argument mistakes made by
+// LLM clients: numeric strings become numbers, "true"/"false" strings become
+// booleans and single values of repeated fields become one-element arrays.
+// The coercions applied are reported in the result _meta.
+type ToolCoercionConfig struct {
+	// Coerce numeric and boolean strings and single values of repeated fields
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Accept dates and date-times without a zone ("2024-01-02") for Timestamp
+	// fields and Go durations ("5m") for Duration fields
+	TimeFormats bool `json:"time_formats" yaml:"time_formats"`
+
+	// Accept data: URIs and URIs of resources served by the gateway for bytes
+	// fields instead of base64
+	ByteInputs bool `json:"byte_inputs" yaml:"byte_inputs"`
+}
+
+// ToolSummaryConfig controls summarization of huge tool results. A result
+// larger than MaxBytes is returned with every list cut to its first MaxItems
+// items and the counts in the result _meta; the full result can be read as a
+// ggrmcp://results/<id> resource linked from the result.
+type ToolSummaryConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Results larger than this many bytes are summarized
+	MaxBytes int `json:"max_bytes" yaml:"max_bytes"`
+
+	// Items kept of each list in a summary
+	MaxItems int `json:"max_items" yaml:"max_items"`
+
+	// Full results kept for reading as resources, oldest dropped first (0
+	// keeps none and links nothing)
+	KeepResults int `json:"keep_results" yaml:"keep_results"`
+}
+
+// ToolEnumsConfig controls normalization of enum names in tool arguments.
+// Strict name matching is a frequent cause of InvalidArgument errors for LLM
+// generated input; with both options "active" is sent as STATUS_ACTIVE. Names
+// matching more than one value are passed on unchanged.
+type ToolEnumsConfig struct {
+	// Match enum names ignoring case
+	CaseInsensitive bool `json:"case_insensitive" yaml:"case_insensitive"`
+
+	// Accept names without the enum's prefix (ACTIVE for STATUS_ACTIVE in
+	// enum Status)
+	StripPrefix bool `json:"strip_prefix" yaml:"strip_prefix"`
+}
+
+// ToolAliasConfig gives a tool a short name and a display title. The tool is
+// listed under its alias, and both the alias and the generated name can be
+// called.
+type ToolAliasConfig struct {
+	// Generated tool name (e.g. "com_example_orders_orderservice_getorder")
+	Tool string `json:"tool" yaml:"tool"`
+
+	// Short name clients use instead (e.g. "get_order"); optional
+	Alias string `json:"alias" yaml:"alias"`
+
+	// Human-readable title shown by clients (e.g. "Get Order"); optional
+	Title string `json:"title" yaml:"title"`
+}
+
+// CompositeToolConfig defines a tool that runs several discovered tools in
+// sequence. Step argument values may reference the composite's arguments and
+// earlier step outputs: "$args.user_id" or "$steps.lookup.user.id" insert the
+// referenced JSON value as is, and strings containing "{{" are Go templates
+// over .Args and .Steps (e.g. "order-{{.Args.id}}").
+type CompositeToolConfig struct {
+	// Tool name exposed to MCP clients
+	Name string `json:"name" yaml:"name"`
+
+	// Tool description
+	Description string `json:"description" yaml:"description"`
+
+	// JSON schema of the composite's arguments (defaults to any object)
+	InputSchema map[string]interface{} `json:"input_schema" yaml:"input_schema"`
+
+	// Steps run in order; a failing step aborts the tool
+	Steps []CompositeStepConfig `json:"steps" yaml:"steps"`
+
+	// Result built from arguments and step outputs (defaults to the last step's output)
+	Result interface{} `json:"result" yaml:"result"`
+}
+
+// CompositeStepConfig is one call of a composite tool
+type CompositeStepConfig struct {
+	// Step identifier used in references (defaults to step1, step2, ...)
+	ID string `json:"id" yaml:"id"`
+
+	// Discovered tool to call
+	Tool string `json:"tool" yaml:"tool"`
+
+	// Arguments of the call
+	Arguments map[string]interface{} `json:"arguments" yaml:"arguments"`
+
+	// Call that undoes the step when a later step fails (optional)
+	Compensate *CompensationConfig `json:"compensate" yaml:"compensate"`
+}
+
+// CompensationConfig undoes a completed composite step (e.g. releases a
+// reservation) when a later step fails. Compensations run in reverse step
+// order; their arguments may reference the arguments and the outputs of the
+// step and the steps before it.
+type CompensationConfig struct {
+	// Discovered tool to call
+	Tool string `json:"tool" yaml:"tool"`
+
+	// Arguments of the call
+	Arguments map[string]interface{} `json:"arguments" yaml:"arguments"`
+}
+
+// ToolScriptConfig computes top-level argument and response fields with CEL
+// expressions for tools matching a name or glob. Expressions can use args,
+// response (output only), tool, session_id and now; a null result removes
+// the field. All expressions of a stage see the same, untransformed input.
+type ToolScriptConfig struct {
+	// Tool name or path.Match glob (e.g. "orders_*")
+	Tool string `json:"tool" yaml:"tool"`
+
+	// Argument fields computed before the call
+	Input map[string]string `json:"input" yaml:"input"`
+
+	// Response fields computed after the call, before response rules apply
+	Output map[string]string `json:"output" yaml:"output"`
+}
+
+// ToolResponseConfig transforms the JSON responses of tools matching a name or
+// glob before they reach the client. Paths are dot-separated JSON keys; "*"
+// matches any key and arrays are traversed element by element. Keys match
+// both the JSON name and the proto field name (e.g. "cardNumber" or
+// "card_number").
+type ToolResponseConfig struct {
+	// Tool name or path.Match glob (e.g. "payments_*")
+	Tool string `json:"tool" yaml:"tool"`
+
+	// Fields removed from the response
+	Drop []string `json:"drop" yaml:"drop"`
+
+	// Field names or globs (e.g. "*_at", "internal_*") removed at any depth
+	// of the response and from the tool's output schema, for noisy fields
+	// that only cost tokens in LLM contexts
+	Prune []string `json:"prune" yaml:"prune"`
+
+	// Fields renamed in place (path -> new key)
+	Rename map[string]string `json:"rename" yaml:"rename"`
+
+	// Fields whose values are masked
+	Mask []MaskConfig `json:"mask" yaml:"mask"`
+}
+
+// MaskConfig masks the value at a path, optionally keeping its last characters
+type MaskConfig struct {
+	Path string `json:"path" yaml:"path"`
+
+	// Number of trailing characters left visible (e.g. 4 for card numbers)
+	KeepLast int `json:"keep_last" yaml:"keep_last"`
+}
+
+// ToolArgumentsConfig sets argument values for tools matching a name or glob.
+// String values may be Go templates, e.g. "{{.SessionID}}" or
+// `{{index .Metadata "tenant-id"}}`.
+type ToolArgumentsConfig struct {
+	// Tool name or path.Match glob (e.g. "orders_*")
+	Tool string `json:"tool" yaml:"tool"`
+
+	// Values used when the client does not provide the argument
+	Defaults map[string]interface{} `json:"defaults" yaml:"defaults"`
+
+	// Values always sent, overriding the client; hidden from the input schema
+	Fixed map[string]interface{} `json:"fixed" yaml:"fixed"`
+}
+
+// CacheConfig contains caching settings
+type CacheConfig struct {
+	Enabled    bool          `json:"enabled" yaml:"enabled"`
+	TTL        time.Duration `json:"ttl" yaml:"ttl"`
+	MaxEntries int           `json:"max_entries" yaml:"max_entries"`
+}
+
+// LoggingConfig contains logging settings
+type LoggingConfig struct {
+	Level       string `json:"level" yaml:"level"`
+	Format      string `json:"format" yaml:"format"`
+	Development bool   `json:"development" yaml:"development"`
+
+	// Keep request parameters, tool arguments and header values out of the logs
+	Redact bool `json:"redact" yaml:"redact"`
+}
+
+// Replay modes
+const (
+	ReplayModeOff    = ""
+	ReplayModeRecord = "record"
+	ReplayModeReplay = "replay"
+)
+
+// ReplayConfig contains record-and-replay settings for tool calls
+type ReplayConfig struct {
+	// Mode is one of "", "record" or "replay"
+	Mode string `json:"mode" yaml:"mode"`
+
+	// Path to the recording file (JSON lines)
+	Path string `json:"path" yaml:"path"`
+}
+
+// ChaosConfig contains fault injection settings for the invoke path.
+// Percentages are in the range 0-100 and are evaluated independently per call.
+type ChaosConfig struct {
+	// Enable fault injection
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Restrict fault injection to these tools (empty means all tools)
+	Tools []string `json:"tools" yaml:"tools"`
+
+	// Percentage of calls delayed and the delay applied
+	DelayPercent float64       `json:"delay_percent" yaml:"delay_percent"`
+	Delay        time.Duration `json:"delay" yaml:"delay"`
+
+	// Percentage of calls failed with one of the given gRPC codes (e.g. UNAVAILABLE)
+	ErrorPercent float64  `json:"error_percent" yaml:"error_percent"`
+	ErrorCodes   []string `json:"error_codes" yaml:"error_codes"`
+
+	// Percentage of responses truncated to TruncateBytes (half the response when zero)
+	TruncatePercent float64 `json:"truncate_percent" yaml:"truncate_percent"`
+	TruncateBytes   int     `json:"truncate_bytes" yaml:"truncate_bytes"`
+}
+
+// CircuitBreakerConfig contains circuit breaker settings. Each upstream and
+// each tool on it has its own circuit; a circuit opens when the share of
+// failed calls within the rolling window reaches FailurePercent, rejects calls
+// for Cooldown and then lets a single probe call decide whether to close again.
+type CircuitBreakerConfig struct {
+	// Enable circuit breaking
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Rolling window over which error rates are computed
+	Window time.Duration `json:"window" yaml:"window"`
+
+	// Minimum calls within the window before a circuit may open
+	MinRequests int `json:"min_requests" yaml:"min_requests"`
+
+	// Percentage of failed calls (0-100) that opens a circuit
+	FailurePercent float64 `json:"failure_percent" yaml:"failure_percent"`
+
+	// How long an open circuit rejects calls before probing the upstream
+	Cooldown time.Duration `json:"cooldown" yaml:"cooldown"`
+
+	// gRPC codes counted as failures (e.g. UNAVAILABLE); other errors are the
+	// caller's fault and do not affect the circuit
+	FailureCodes []string `json:"failure_codes" yaml:"failure_codes"`
+}
+
+// HedgingConfig contains request hedging settings. A call still running after
+// Delay is sent again and the first successful response wins; the others are
+// cancelled. Hedged attempts draw from a budget replenished by a percentage of
+// all hedgeable calls, so a slow upstream is never hit with a multiple of its
+// normal load. Only idempotent tools may be hedged, so they must be listed
+// explicitly; exclude every tool whose RPC has side effects.
+type HedgingConfig struct {
+	// Enable request hedging
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// How long an attempt may run before the next one is sent
+	Delay time.Duration `json:"delay" yaml:"delay"`
+
+	// Maximum attempts per call, including the original one
+	MaxAttempts int `json:"max_attempts" yaml:"max_attempts"`
+
+	// Hedged attempts allowed as a percentage of hedgeable calls (0-100)
+	BudgetPercent float64 `json:"budget_percent" yaml:"budget_percent"`
+
+	// Tools that may be hedged (names or path.Match globs); required, as only
+	// idempotent RPCs are safe to send twice
+	Tools []string `json:"tools" yaml:"tools"`
+
+	// Tools that are never hedged, e.g. mutating RPCs; takes precedence over Tools
+	ExcludeTools []string `json:"exclude_tools" yaml:"exclude_tools"`
+}
+
+// UpstreamSwitchConfig allows the primary upstream to be replaced at runtime
+// through POST /admin/upstream/switch
+type UpstreamSwitchConfig struct {
+	// Enable the upstream switch
+	Enabled bool `json:"enabled" yaml:"enabled"`
+}
+
+// UpstreamRegistrationConfig allows upstreams serving other services to be
+// added at runtime through POST /admin/upstreams. Their tools are served next
+// to those of the primary upstream, optionally under a name prefix.
+type UpstreamRegistrationConfig struct {
+	// Enable runtime upstream registration
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Maximum number of upstreams that can be registered
+	MaxUpstreams int `json:"max_upstreams" yaml:"max_upstreams"`
+
+	// How long a removed upstream may finish its in-flight calls before
+	// its connection is closed
+	DrainTimeout time.Duration `json:"drain_timeout" yaml:"drain_timeout"`
+}
+
+// AnomalyConfig contains the built-in detection of unusual tool-call
+// patterns. Alerts are logged and, when a webhook is set, posted as JSON.
+type AnomalyConfig struct {
+	// Enable anomaly detection
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Destructive tools (names or path.Match globs) whose call rate is watched
+	DestructiveTools []string `json:"destructive_tools" yaml:"destructive_tools"`
+
+	// Calls of one destructive tool within SpikeWindow that raise an alert
+	SpikeThreshold int           `json:"spike_threshold" yaml:"spike_threshold"`
+	SpikeWindow    time.Duration `json:"spike_window" yaml:"spike_window"`
+
+	// Consecutive failures of the same call (session, tool and arguments)
+	// within FailureWindow that raise an alert
+	RepeatedFailures int           `json:"repeated_failures" yaml:"repeated_failures"`
+	FailureWindow    time.Duration `json:"failure_window" yaml:"failure_window"`
+
+	// Minimum time between two alerts of the same kind for the same subject
+	Cooldown time.Duration `json:"cooldown" yaml:"cooldown"`
+
+	// URL alerts are posted to (empty logs them only)
+	WebhookURL string `json:"webhook_url" yaml:"webhook_url"`
+
+	// Timeout of a webhook delivery
+	WebhookTimeout time.Duration `json:"webhook_timeout" yaml:"webhook_timeout"`
+}
+
+// AuditConfig contains the append-only audit log of tool calls. Records can
+// be hash-chained and signed so the log can be proven untampered.
+type AuditConfig struct {
+	// Enable the audit log
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// File records are appended to, one JSON object per line
+	Path string `json:"path" yaml:"path"`
+
+	// Keep the call arguments in the record; otherwise only their SHA-256 is kept
+	IncludeArguments bool `json:"include_arguments" yaml:"include_arguments"`
+
+	// Hash-chain records so removed, reordered or edited records are detected
+	Chain bool `json:"chain" yaml:"chain"`
+
+	// PEM encoded PKCS #8 Ed25519 private key each record hash is signed with;
+	// implies Chain
+	SigningKeyFile string `json:"signing_key_file" yaml:"signing_key_file"`
+}
+
+// MirrorConfig contains shadow traffic mirroring settings. Mirrored calls are
+// fire-and-forget: their outcome never affects the response sent to the client.
+type MirrorConfig struct {
+	// Enable mirroring to the secondary upstream
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Secondary upstream address
+	Host string `json:"host" yaml:"host"`
+	Port int    `json:"port" yaml:"port"`
+
+	// Connection settings overriding the grpc section for the secondary
+	Connection ConnectionConfig `json:"connection" yaml:"connection"`
+
+	// Percentage of calls mirrored (0-100)
+	Percent float64 `json:"percent" yaml:"percent"`
+
+	// Timeout for each mirrored call
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+
+	// Maximum number of mirrored calls in flight; extra calls are dropped
+	MaxInFlight int `json:"max_in_flight" yaml:"max_in_flight"`
+
+	// Optional JSON lines file receiving response diffs
+	DiffLogPath string `json:"diff_log_path" yaml:"diff_log_path"`
+}
+
+// RoutingConfig contains settings for spreading calls over replicas of the
+// primary upstream that serve the same services
+type RoutingConfig struct {
+	// Pin each session to one replica using consistent hashing on the session ID
+	Sticky bool `json:"sticky" yaml:"sticky"`
+
+	// Additional replicas; the primary gRPC upstream is always part of the pool
+	Replicas []UpstreamConfig `json:"replicas" yaml:"replicas"`
+
+	// Temporarily remove replicas with elevated error rates from rotation
+	OutlierDetection OutlierDetectionConfig `json:"outlier_detection" yaml:"outlier_detection"`
+}
+
+// OutlierDetectionConfig contains settings for ejecting failing replicas.
+// Error rates are evaluated every Interval; a replica is ejected for
+// BaseEjectionTime times the number of consecutive ejections.
+type OutlierDetectionConfig struct {
+	// Enable outlier detection
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// How often error rates are evaluated
+	Interval time.Duration `json:"interval" yaml:"interval"`
+
+	// Minimum calls a replica must receive in an interval to be evaluated
+	MinRequests int `json:"min_requests" yaml:"min_requests"`
+
+	// Percentage of failed calls (0-100) that ejects a replica
+	FailurePercent float64 `json:"failure_percent" yaml:"failure_percent"`
+
+	// Base time a replica stays out of rotation
+	BaseEjectionTime time.Duration `json:"base_ejection_time" yaml:"base_ejection_time"`
+
+	// Maximum percentage of replicas (0-100) ejected at once
+	MaxEjectionPercent float64 `json:"max_ejection_percent" yaml:"max_ejection_percent"`
+
+	// gRPC codes counted as failures (e.g. UNAVAILABLE)
+	FailureCodes []string `json:"failure_codes" yaml:"failure_codes"`
+}
+
+// UpstreamConfig is the address of a gRPC upstream
+type UpstreamConfig struct {
+	Host string `json:"host" yaml:"host"`
+	Port int    `json:"port" yaml:"port"`
+
+	// Connection settings overriding the grpc section for this upstream
+	Connection ConnectionConfig `json:"connection" yaml:"connection"`
+}
+
+// Address returns the upstream address in host:port form
+func (u UpstreamConfig) Address() string {
+	return fmt.Sprintf("%s:%d", u.Host, u.Port)
+}
+
+// Default returns a configuration with sensible defaults
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:           50053,
+			Timeout:        30 * time.Second,
+			MaxRequestSize: 4 * 1024 * 1024, // 4MB
+			Security: SecurityConfig{
+				EnableHeaders: true,
+				CORS: CORSConfig{
+					AllowedOrigins: []string{"*"},
+					AllowedMethods: []string{"GET", "POST", "OPTIONS"},
+					AllowedHeaders: []string{"Content-Type", "Authorization", "Mcp-Session-Id", "Mcp-Allowed-Tools"},
+				},
+				RateLimit: RateLimitConfig{
+					RequestsPerMinute: 1000,
+					BurstSize:         100,
 					WindowSize:        time.Minute,
+					Backend:           RateLimitBackendMemory,
+					Redis: RedisConfig{
+						KeyPrefix:   "ggrmcp:ratelimit:",
+						DialTimeout: 2 * time.Second,
+						Timeout:     500 * time.Millisecond,
+						PoolSize:    16,
+					},
+					FailOpen: true,
+				},
+				Auth: AuthConfig{
+					RequireExp: true,
+					Lockout: LockoutConfig{
+						Enabled:     true,
+						MaxFailures: 5,
+						Window:      time.Minute,
+						Duration:    30 * time.Second,
+						MaxDuration: 15 * time.Minute,
+					},
 				},
 			},
+			Backpressure: BackpressureConfig{
+				MaxInFlight:  128,
+				MaxQueue:     256,
+				QueueTimeout: 5 * time.Second,
+			},
+			Affinity: AffinityConfig{
+				Cookie: "ggrmcp_replica",
+				Header: "Mcp-Replica-Id",
+			},
+			Batch: BatchConfig{
+				Enabled:        true,
+				MaxRequests:    32,
+				MaxConcurrency: 1,
+			},
 		},
 		GRPC: GRPCConfig{
 			Host:           "localhost",
@@ -242,7 +1918,8 @@ func Default() *Config {
 				Interval:    5 * time.Second,
 				MaxAttempts: 5,
 			},
-			MaxMessageSize: 4 * 1024 * 1024, // 4MB
+			MaxMessageSize:    4 * 1024 * 1024, // 4MB
+			CertExpiryWarning: 30 * 24 * time.Hour,
 			HeaderForwarding: HeaderForwardingConfig{
 				Enabled: true,
 				AllowedHeaders: []string{
@@ -261,21 +1938,35 @@ func Default() *Config {
 					"content-length",
 					"content-type",
 					"connection",
+					"keep-alive",
+					"te",
+					"trailer",
+					"transfer-encoding",
 					"upgrade",
+					"proxy-authorization",
+					"proxy-authenticate",
+					"proxy-connection",
 					"mcp-session-id",
 				},
 				ForwardAll:    false,
 				CaseSensitive: false,
 			},
+			WarmUp: WarmUpConfig{
+				Timeout: 5 * time.Second,
+			},
 			DescriptorSet: DescriptorSetConfig{
 				Enabled:              false, // Disabled by default
 				Path:                 "",
 				PreferOverReflection: false,
 				IncludeSourceInfo:    true,
 			},
+			Resolver: ResolverConfig{
+				RefreshInterval: 30 * time.Second,
+			},
 		},
 		MCP: MCPConfig{
 			ProtocolVersion: "2024-11-05",
+			ErrorDetail:     "standard",
 			Validation: ValidationConfig{
 				MaxFieldLength:    1024,
 				MaxToolNameLength: 128,
@@ -292,6 +1983,21 @@ func Default() *Config {
 				BurstSize:         20,
 				WindowSize:        time.Minute,
 			},
+			MaxConcurrentCalls: 4,
+			Metadata: SessionMetadataConfig{
+				Enabled:           false,
+				HeaderPrefix:      "Mcp-Meta-",
+				InjectMetadata:    true,
+				MetadataKeyPrefix: "x-session-",
+				InjectArguments:   true,
+				MaxEntries:        32,
+				MaxValueLength:    1024,
+			},
+			History: SessionHistoryConfig{
+				Enabled:        false,
+				MaxCalls:       50,
+				MaxResultBytes: 2048,
+			},
 		},
 		Tools: ToolsConfig{
 			Cache: CacheConfig{
@@ -302,12 +2008,110 @@ func Default() *Config {
 			MaxDepth:      10,
 			MaxFields:     100,
 			MaxEnumValues: 50,
+			Grouping: ToolGroupingConfig{
+				Enabled: true,
+			},
+			Docs: ToolDocsConfig{
+				MaxLength: 4000,
+			},
+			HealthGating: HealthGatingConfig{
+				Interval: 15 * time.Second,
+			},
+			Hints: ToolHintsConfig{
+				MinSamples:    10,
+				FastThreshold: 100 * time.Millisecond,
+				SlowThreshold: 2 * time.Second,
+			},
+			Usage: ToolUsageConfig{
+				Samples: 1000,
+			},
+			Summary: ToolSummaryConfig{
+				MaxBytes:    64 * 1024,
+				MaxItems:    20,
+				KeepResults: 100,
+			},
+			Pagination: ToolPaginationConfig{
+				MaxPages: 10,
+				MaxItems: 1000,
+			},
+			LongRunning: LongRunningConfig{
+				PollInterval:    500 * time.Millisecond,
+				MaxPollInterval: 5 * time.Second,
+				MaxWait:         25 * time.Second,
+			},
+			ServerStreaming: ServerStreamingConfig{
+				MaxMessages:    1000,
+				DeadlineMargin: time.Second,
+			},
+			BidiStreaming: BidiStreamingConfig{
+				MaxStreams:           100,
+				MaxStreamsPerSession: 4,
+				Buffer:               16,
+				IdleTimeout:          5 * time.Minute,
+			},
+			Comments: ToolCommentsConfig{
+				Normalize: true,
+			},
+			DescribeTool: ToolDescribeConfig{
+				Enabled: true,
+			},
+			Introspection: ToolIntrospectionConfig{
+				Enabled: true,
+			},
 		},
 		Logging: LoggingConfig{
 			Level:       "info",
 			Format:      "json",
 			Development: false,
 		},
+		Mirror: MirrorConfig{
+			Enabled:     false,
+			Percent:     100,
+			Timeout:     10 * time.Second,
+			MaxInFlight: 64,
+		},
+		Routing: RoutingConfig{
+			OutlierDetection: OutlierDetectionConfig{
+				Enabled:            false,
+				Interval:           10 * time.Second,
+				MinRequests:        5,
+				FailurePercent:     50,
+				BaseEjectionTime:   30 * time.Second,
+				MaxEjectionPercent: 50,
+				FailureCodes:       []string{"UNAVAILABLE", "DEADLINE_EXCEEDED", "INTERNAL", "UNKNOWN", "DATA_LOSS"},
+			},
+		},
+		CircuitBreaker: CircuitBreakerConfig{
+			Enabled:        false,
+			Window:         time.Minute,
+			MinRequests:    20,
+			FailurePercent: 50,
+			Cooldown:       30 * time.Second,
+			FailureCodes:   []string{"UNAVAILABLE", "DEADLINE_EXCEEDED", "INTERNAL", "UNKNOWN", "DATA_LOSS"},
+		},
+		Hedging: HedgingConfig{
+			Enabled:       false,
+			Delay:         100 * time.Millisecond,
+			MaxAttempts:   2,
+			BudgetPercent: 10,
+		},
+		UpstreamSwitch: UpstreamSwitchConfig{
+			Enabled: false,
+		},
+		UpstreamRegistration: UpstreamRegistrationConfig{
+			Enabled:      false,
+			MaxUpstreams: 16,
+			DrainTimeout: 30 * time.Second,
+		},
+		Anomaly: AnomalyConfig{
+			Enabled:          false,
+			SpikeThreshold:   20,
+			SpikeWindow:      time.Minute,
+			RepeatedFailures: 5,
+			FailureWindow:    5 * time.Minute,
+			Cooldown:         5 * time.Minute,
+			WebhookTimeout:   5 * time.Second,
+		},
 	}
 }
 
@@ -324,6 +2128,44 @@ func Development() *Config {
 	return config
 }
 
+// ApplySecureProfile hardens the configuration for production deployments:
+// server TLS and authentication are required, browser origins are validated,
+// errors and logs are redacted, header filtering is strict, session
+// metadata is sent upstream only under a key prefix and the built-in
+// introspection and describe tools, which reveal upstream details, are
+// turned off. The
+// certificate and auth keys cannot be defaulted; Validate reports them as
+// missing until they are configured.
+func (c *Config) ApplySecureProfile() {
+	security := &c.Server.Security
+	security.EnableHeaders = true
+	security.RequireTLS = true
+	security.ValidateOrigin = true
+	origins := security.CORS.AllowedOrigins[:0:0]
+	for _, origin := range security.CORS.AllowedOrigins {
+		if origin != "*" {
+			origins = append(origins, origin)
+		}
+	}
+	security.CORS.AllowedOrigins = origins
+	security.Auth.Enabled = true
+	security.Auth.Required = true
+	security.Auth.Lockout.Enabled = true
+
+	c.MCP.ErrorDetail = "strict"
+	c.Logging.Redact = true
+
+	c.GRPC.HeaderForwarding.ForwardAll = false
+	c.GRPC.HeaderForwarding.AllowUnsafeHeaders = false
+
+	if c.Session.Metadata.MetadataKeyPrefix == "" {
+		c.Session.Metadata.MetadataKeyPrefix = Default().Session.Metadata.MetadataKeyPrefix
+	}
+
+	c.Tools.Introspection.Enabled = false
+	c.Tools.DescribeTool.Enabled = false
+}
+
 // Validate validates the configuration
 func (c *Config) Validate() error {
 	if c.Server.Port <= 0 || c.Server.Port > 65535 {
@@ -338,14 +2180,105 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("server timeout must be positive")
 	}
 
+	if c.Server.Backpressure.MaxInFlight < 0 || c.Server.Backpressure.MaxQueue < 0 {
+		return fmt.Errorf("backpressure limits cannot be negative")
+	}
+	if c.Server.Backpressure.MaxInFlight > 0 && c.Server.Backpressure.MaxQueue > 0 && c.Server.Backpressure.QueueTimeout <= 0 {
+		return fmt.Errorf("backpressure queue timeout must be positive")
+	}
+
+	if c.Server.Batch.Enabled && (c.Server.Batch.MaxRequests <= 0 || c.Server.Batch.MaxConcurrency <= 0) {
+		return fmt.Errorf("batch max_requests and max_concurrency must be positive")
+	}
+
 	if c.GRPC.ConnectTimeout <= 0 {
 		return fmt.Errorf("gRPC connect timeout must be positive")
 	}
+	if c.GRPC.CertExpiryWarning < 0 {
+		return fmt.Errorf("gRPC cert expiry warning cannot be negative")
+	}
+
+	// Validate connection settings of every upstream
+	connections := map[string]ConnectionConfig{"grpc": {}, "mirror": c.Mirror.Connection}
+	for _, replica := range c.Routing.Replicas {
+		connections["replica "+replica.Address()] = replica.Connection
+	}
+	for name, override := range connections {
+		if override.ConnectTimeout < 0 || override.MaxRecvMessageSize < 0 || override.MaxSendMessageSize < 0 {
+			return fmt.Errorf("%s: connection settings cannot be negative", name)
+		}
+		if err := c.GRPC.Connection(override).validate(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	if err := c.Server.Security.Auth.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Server.Security.Network.validate(); err != nil {
+		return fmt.Errorf("network: %w", err)
+	}
+
+	if err := c.Server.Security.RateLimit.validate(); err != nil {
+		return fmt.Errorf("rate limit: %w", err)
+	}
+
+	if err := c.Server.Affinity.validate(); err != nil {
+		return fmt.Errorf("affinity: %w", err)
+	}
+
+	if (c.Server.TLS.CertFile == "") != (c.Server.TLS.KeyFile == "") {
+		return fmt.Errorf("server TLS requires both cert_file and key_file")
+	}
+	if c.Server.Security.RequireTLS && !c.Server.TLS.Enabled() {
+		return fmt.Errorf("require_tls is set but server.tls has no certificate")
+	}
+
+	if err := c.GRPC.HeaderForwarding.validate(); err != nil {
+		return fmt.Errorf("header forwarding: %w", err)
+	}
+
+	if err := c.GRPC.Baggage.validate(); err != nil {
+		return err
+	}
+
+	if c.GRPC.WarmUp.Enabled && c.GRPC.WarmUp.Timeout <= 0 {
+		return fmt.Errorf("warm_up timeout must be positive")
+	}
+
+	switch c.MCP.ErrorDetail {
+	case "verbose", "standard", "strict":
+	default:
+		return fmt.Errorf("invalid error detail: %s", c.MCP.ErrorDetail)
+	}
 
 	if c.Session.MaxSessions <= 0 {
 		return fmt.Errorf("max sessions must be positive")
 	}
 
+	if c.Session.MaxConcurrentCalls < 0 {
+		return fmt.Errorf("session max concurrent calls cannot be negative")
+	}
+
+	if c.Session.Metadata.Enabled {
+		if c.Session.Metadata.MaxEntries <= 0 {
+			return fmt.Errorf("session metadata max entries must be positive")
+		}
+		if c.Session.Metadata.MaxValueLength <= 0 {
+			return fmt.Errorf("session metadata max value length must be positive")
+		}
+	}
+
+	if c.Session.History.Enabled {
+		if c.Session.History.MaxCalls <= 0 {
+			return fmt.Errorf("session history max calls must be positive")
+		}
+		if c.Session.History.MaxResultBytes <= 0 {
+			return fmt.Errorf("session history max result bytes must be positive")
+		}
+	}
+
 	// Validate descriptor set configuration
 	if c.GRPC.DescriptorSet.Enabled {
 		if c.GRPC.DescriptorSet.Path == "" {
@@ -353,5 +2286,425 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate resolver configuration; custom resolver names are checked at startup
+	if c.GRPC.Resolver.Name == "static" && len(c.GRPC.Resolver.Addresses) == 0 {
+		return fmt.Errorf("static resolver requires at least one address")
+	}
+	if c.GRPC.Resolver.RefreshInterval < 0 {
+		return fmt.Errorf("resolver refresh_interval must not be negative")
+	}
+
+	// Validate record/replay configuration
+	switch c.Replay.Mode {
+	case ReplayModeOff:
+	case ReplayModeRecord, ReplayModeReplay:
+		if c.Replay.Path == "" {
+			return fmt.Errorf("replay path must be specified for mode %q", c.Replay.Mode)
+		}
+		if c.Replay.Mode == ReplayModeReplay && !c.GRPC.DescriptorSet.Enabled && c.GRPC.SnapshotPath == "" {
+			return fmt.Errorf("replay mode requires a descriptor set or snapshot to build tools without an upstream")
+		}
+	default:
+		return fmt.Errorf("invalid replay mode: %s", c.Replay.Mode)
+	}
+
+	// Validate fault injection configuration
+	if c.Chaos.Enabled {
+		for name, pct := range map[string]float64{
+			"delay_percent":    c.Chaos.DelayPercent,
+			"error_percent":    c.Chaos.ErrorPercent,
+			"truncate_percent": c.Chaos.TruncatePercent,
+		} {
+			if pct < 0 || pct > 100 {
+				return fmt.Errorf("chaos %s must be between 0 and 100", name)
+			}
+		}
+		if c.Chaos.DelayPercent > 0 && c.Chaos.Delay <= 0 {
+			return fmt.Errorf("chaos delay must be positive when delay_percent is set")
+		}
+		if c.Chaos.ErrorPercent > 0 && len(c.Chaos.ErrorCodes) == 0 {
+			return fmt.Errorf("chaos error_codes must be specified when error_percent is set")
+		}
+	}
+
+	// Validate circuit breaker configuration
+	if c.CircuitBreaker.Enabled {
+		if c.CircuitBreaker.Window <= 0 {
+			return fmt.Errorf("circuit breaker window must be positive")
+		}
+		if c.CircuitBreaker.Cooldown <= 0 {
+			return fmt.Errorf("circuit breaker cooldown must be positive")
+		}
+		if c.CircuitBreaker.MinRequests < 1 {
+			return fmt.Errorf("circuit breaker min_requests must be at least 1")
+		}
+		if c.CircuitBreaker.FailurePercent <= 0 || c.CircuitBreaker.FailurePercent > 100 {
+			return fmt.Errorf("circuit breaker failure_percent must be between 0 and 100")
+		}
+		if len(c.CircuitBreaker.FailureCodes) == 0 {
+			return fmt.Errorf("circuit breaker failure_codes must not be empty")
+		}
+	}
+
+	// Validate automatic pagination
+	if c.Tools.Pagination.Enabled {
+		if c.Tools.Pagination.MaxPages < 1 {
+			return fmt.Errorf("pagination max_pages must be at least 1")
+		}
+		if c.Tools.Pagination.MaxItems < 1 {
+			return fmt.Errorf("pagination max_items must be at least 1")
+		}
+		for _, pattern := range c.Tools.Pagination.Tools {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+			}
+		}
+	}
+
+	// Validate long-running operation polling
+	if lro := c.Tools.LongRunning; lro.Enabled {
+		if lro.PollInterval <= 0 {
+			return fmt.Errorf("long_running poll_interval must be positive")
+		}
+		if lro.MaxPollInterval < lro.PollInterval {
+			return fmt.Errorf("long_running max_poll_interval cannot be less than poll_interval")
+		}
+		if lro.MaxWait <= 0 {
+			return fmt.Errorf("long_running max_wait must be positive")
+		}
+		for _, pattern := range lro.Tools {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+			}
+		}
+	}
+
+	// Validate comment formatting
+	if c.Tools.Comments.MaxLength < 0 {
+		return fmt.Errorf("comments max_length cannot be negative")
+	}
+
+	// Validate server-streaming tools
+	if c.Tools.ServerStreaming.MaxMessages < 0 {
+		return fmt.Errorf("server_streaming max_messages cannot be negative")
+	}
+	if c.Tools.ServerStreaming.DeadlineMargin < 0 {
+		return fmt.Errorf("server_streaming deadline_margin cannot be negative")
+	}
+
+	// Validate bidirectional streams
+	if bidi := c.Tools.BidiStreaming; bidi.Enabled {
+		if bidi.MaxStreams <= 0 {
+			return fmt.Errorf("bidi_streaming max_streams must be positive")
+		}
+		if bidi.MaxStreamsPerSession <= 0 {
+			return fmt.Errorf("bidi_streaming max_streams_per_session must be positive")
+		}
+		if bidi.Buffer <= 0 {
+			return fmt.Errorf("bidi_streaming buffer must be positive")
+		}
+		if bidi.IdleTimeout <= 0 {
+			return fmt.Errorf("bidi_streaming idle_timeout must be positive")
+		}
+	}
+
+	// Validate hedging configuration
+	if c.Hedging.Enabled {
+		if c.Hedging.Delay <= 0 {
+			return fmt.Errorf("hedging delay must be positive")
+		}
+		if c.Hedging.MaxAttempts < 2 || c.Hedging.MaxAttempts > 5 {
+			return fmt.Errorf("hedging max_attempts must be between 2 and 5")
+		}
+		if c.Hedging.BudgetPercent <= 0 || c.Hedging.BudgetPercent > 100 {
+			return fmt.Errorf("hedging budget_percent must be between 0 and 100")
+		}
+		if len(c.Hedging.Tools) == 0 {
+			return fmt.Errorf("hedging requires tools: list the idempotent tools that may be hedged")
+		}
+		for _, pattern := range append(append([]string{}, c.Hedging.Tools...), c.Hedging.ExcludeTools...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+			}
+		}
+	}
+
+	if c.UpstreamRegistration.Enabled && c.UpstreamRegistration.MaxUpstreams < 1 {
+		return fmt.Errorf("upstream_registration max_upstreams must be at least 1")
+	}
+	if c.UpstreamRegistration.DrainTimeout < 0 {
+		return fmt.Errorf("upstream_registration drain_timeout must not be negative")
+	}
+
+	// Validate anomaly detection configuration
+	if c.Anomaly.Enabled {
+		if c.Anomaly.SpikeThreshold < 1 || c.Anomaly.SpikeWindow <= 0 {
+			return fmt.Errorf("anomaly spike_threshold must be at least 1 and spike_window positive")
+		}
+		if c.Anomaly.RepeatedFailures < 1 || c.Anomaly.FailureWindow <= 0 {
+			return fmt.Errorf("anomaly repeated_failures must be at least 1 and failure_window positive")
+		}
+		if c.Anomaly.Cooldown < 0 {
+			return fmt.Errorf("anomaly cooldown must not be negative")
+		}
+		if c.Anomaly.WebhookURL != "" {
+			u, err := url.Parse(c.Anomaly.WebhookURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("invalid anomaly webhook_url: %s", c.Anomaly.WebhookURL)
+			}
+			if c.Anomaly.WebhookTimeout <= 0 {
+				return fmt.Errorf("anomaly webhook_timeout must be positive")
+			}
+		}
+		for _, pattern := range c.Anomaly.DestructiveTools {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+			}
+		}
+	}
+
+	// Validate audit log configuration
+	if c.Audit.Enabled && c.Audit.Path == "" {
+		return fmt.Errorf("audit path must be specified when enabled")
+	}
+
+	// Validate mirroring configuration
+	if c.Mirror.Enabled {
+		if c.Mirror.Host == "" {
+			return fmt.Errorf("mirror host must be specified when enabled")
+		}
+		if c.Mirror.Port <= 0 || c.Mirror.Port > 65535 {
+			return fmt.Errorf("invalid mirror port: %d", c.Mirror.Port)
+		}
+		if c.Mirror.Percent < 0 || c.Mirror.Percent > 100 {
+			return fmt.Errorf("mirror percent must be between 0 and 100")
+		}
+		if c.Mirror.Timeout <= 0 {
+			return fmt.Errorf("mirror timeout must be positive")
+		}
+		if c.Mirror.MaxInFlight <= 0 {
+			return fmt.Errorf("mirror max_in_flight must be positive")
+		}
+	}
+
+	// Validate per-tool argument rules
+	for _, rule := range c.Tools.Arguments {
+		if rule.Tool == "" {
+			return fmt.Errorf("tool argument rule requires a tool name or pattern")
+		}
+		if _, err := path.Match(rule.Tool, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", rule.Tool, err)
+		}
+	}
+
+	// Validate per-tool response rules
+	for _, rule := range c.Tools.Responses {
+		if rule.Tool == "" {
+			return fmt.Errorf("tool response rule requires a tool name or pattern")
+		}
+		if _, err := path.Match(rule.Tool, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", rule.Tool, err)
+		}
+		for _, name := range rule.Prune {
+			if _, err := path.Match(name, ""); err != nil || name == "" || strings.Contains(name, ".") {
+				return fmt.Errorf("invalid prune pattern %q for %q", name, rule.Tool)
+			}
+		}
+		for _, mask := range rule.Mask {
+			if mask.Path == "" {
+				return fmt.Errorf("mask rule for %q requires a path", rule.Tool)
+			}
+			if mask.KeepLast < 0 {
+				return fmt.Errorf("mask rule for %q: keep_last cannot be negative", rule.Tool)
+			}
+		}
+	}
+
+	// Validate per-tool scripts (expressions are compiled at startup)
+	for _, script := range c.Tools.Scripts {
+		if script.Tool == "" {
+			return fmt.Errorf("tool script requires a tool name or pattern")
+		}
+		if _, err := path.Match(script.Tool, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", script.Tool, err)
+		}
+	}
+
+	// Validate composite tools
+	for _, composite := range c.Tools.Composites {
+		if composite.Name == "" {
+			return fmt.Errorf("composite tool requires a name")
+		}
+		if len(composite.Steps) == 0 {
+			return fmt.Errorf("composite tool %q requires at least one step", composite.Name)
+		}
+		for _, step := range composite.Steps {
+			if step.Tool == "" {
+				return fmt.Errorf("composite tool %q has a step without a tool", composite.Name)
+			}
+		}
+	}
+
+	// Validate tool aliases
+	aliases := make(map[string]string)
+	for _, alias := range c.Tools.Aliases {
+		if alias.Tool == "" {
+			return fmt.Errorf("tool alias requires a tool")
+		}
+		if alias.Alias == "" && alias.Title == "" {
+			return fmt.Errorf("tool alias for %q requires an alias or a title", alias.Tool)
+		}
+		if alias.Alias == "" {
+			continue
+		}
+		if !aliasPattern.MatchString(alias.Alias) {
+			return fmt.Errorf("invalid alias %q for tool %q", alias.Alias, alias.Tool)
+		}
+		if other, exists := aliases[alias.Alias]; exists {
+			return fmt.Errorf("alias %q is used by both %q and %q", alias.Alias, other, alias.Tool)
+		}
+		aliases[alias.Alias] = alias.Tool
+	}
+
+	if c.Tools.Docs.Dir != "" && c.Tools.Docs.MaxLength <= 0 {
+		return fmt.Errorf("tool docs max length must be positive")
+	}
+
+	switch c.Tools.Output.NonFinite {
+	case NonFiniteKeep, NonFiniteNull, NonFiniteAnnotate:
+	default:
+		return fmt.Errorf("invalid non-finite mode: %s", c.Tools.Output.NonFinite)
+	}
+	switch c.Tools.Output.Result {
+	case "", ResultText, ResultStructured, ResultBoth, ResultAuto:
+	default:
+		return fmt.Errorf("invalid tool result composition: %s", c.Tools.Output.Result)
+	}
+	if c.Tools.Output.FloatPrecision < 0 || c.Tools.Output.FloatPrecision > 17 {
+		return fmt.Errorf("float precision must be between 0 and 17")
+	}
+	for _, rule := range c.Tools.Output.TextFormats {
+		if _, err := path.Match(rule.Tool, ""); err != nil || rule.Tool == "" {
+			return fmt.Errorf("invalid text format tool pattern %q", rule.Tool)
+		}
+		switch rule.Format {
+		case TextFormatJSON, TextFormatPretty, TextFormatYAML, TextFormatCSV:
+		default:
+			return fmt.Errorf("invalid text format for %q: %s", rule.Tool, rule.Format)
+		}
+	}
+
+	switch c.Tools.HealthGating.Mode {
+	case HealthGatingOff, HealthGatingHide, HealthGatingMark:
+	default:
+		return fmt.Errorf("invalid health gating mode: %s", c.Tools.HealthGating.Mode)
+	}
+	if c.Tools.HealthGating.Mode != HealthGatingOff && c.Tools.HealthGating.Interval <= 0 {
+		return fmt.Errorf("health gating interval must be positive")
+	}
+
+	// Validate tool categories
+	for _, category := range c.Tools.Grouping.Categories {
+		if _, err := path.Match(category.Tool, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", category.Tool, err)
+		}
+		if category.Category == "" {
+			return fmt.Errorf("category for %q must not be empty", category.Tool)
+		}
+	}
+
+	// Validate flattened tools
+	for _, pattern := range c.Tools.Flattening.Tools {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+		}
+	}
+
+	// Validate per-tool ready modes
+	for _, rule := range c.Tools.ReadyModes {
+		if _, err := path.Match(rule.Tool, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", rule.Tool, err)
+		}
+		if rule.Mode == "" {
+			return fmt.Errorf("ready mode for %q must not be empty", rule.Tool)
+		}
+		if err := validateReadyMode(rule.Mode); err != nil {
+			return err
+		}
+	}
+
+	// Validate per-tool input limits
+	for _, limit := range c.Tools.InputLimits {
+		if _, err := path.Match(limit.Tool, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", limit.Tool, err)
+		}
+		if limit.MaxBytes <= 0 {
+			return fmt.Errorf("input limit max_bytes for %q must be positive", limit.Tool)
+		}
+	}
+
+	if err := c.Tools.Hints.validate(); err != nil {
+		return err
+	}
+	if c.Tools.Usage.Samples < 1 {
+		return fmt.Errorf("tool usage samples must be at least 1")
+	}
+	if summary := c.Tools.Summary; summary.Enabled {
+		if summary.MaxBytes <= 0 {
+			return fmt.Errorf("tool summary max_bytes must be positive")
+		}
+		if summary.MaxItems < 1 {
+			return fmt.Errorf("tool summary max_items must be at least 1")
+		}
+		if summary.KeepResults < 0 {
+			return fmt.Errorf("tool summary keep_results cannot be negative")
+		}
+	}
+
+	// Validate argument sanitizers
+	for _, sanitizer := range c.Tools.Sanitizers {
+		if _, err := path.Match(sanitizer.Tool, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", sanitizer.Tool, err)
+		}
+		switch sanitizer.Action {
+		case "", SanitizeReject, SanitizeStrip:
+		default:
+			return fmt.Errorf("invalid sanitizer action for %q: %s", sanitizer.Tool, sanitizer.Action)
+		}
+		if sanitizer.MaxLength < 0 {
+			return fmt.Errorf("sanitizer max_length for %q must not be negative", sanitizer.Tool)
+		}
+	}
+
+	// Validate routing configuration
+	if c.Routing.Sticky && len(c.Routing.Replicas) == 0 {
+		return fmt.Errorf("sticky routing requires at least one replica")
+	}
+	for _, replica := range c.Routing.Replicas {
+		if replica.Host == "" {
+			return fmt.Errorf("replica host must be specified")
+		}
+		if replica.Port <= 0 || replica.Port > 65535 {
+			return fmt.Errorf("invalid replica port: %d", replica.Port)
+		}
+	}
+	if od := c.Routing.OutlierDetection; od.Enabled {
+		if od.Interval <= 0 || od.BaseEjectionTime <= 0 {
+			return fmt.Errorf("outlier detection interval and base_ejection_time must be positive")
+		}
+		if od.MinRequests < 1 {
+			return fmt.Errorf("outlier detection min_requests must be at least 1")
+		}
+		if od.FailurePercent <= 0 || od.FailurePercent > 100 {
+			return fmt.Errorf("outlier detection failure_percent must be between 0 and 100")
+		}
+		if od.MaxEjectionPercent < 0 || od.MaxEjectionPercent > 100 {
+			return fmt.Errorf("outlier detection max_ejection_percent must be between 0 and 100")
+		}
+		if len(od.FailureCodes) == 0 {
+			return fmt.Errorf("outlier detection failure_codes must not be empty")
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_OverridesDefaults(t *testing.T) {
+	path := writeConfigFile(t, `
+grpc:
+  host: upstream.internal
+  port: 9090
+chaos:
+  enabled: true
+  delay_percent: 10
+  delay: 250ms
+  error_percent: 5
+  error_codes: [UNAVAILABLE]
+`)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "upstream.internal", cfg.GRPC.Host)
+	assert.Equal(t, 9090, cfg.GRPC.Port)
+	assert.True(t, cfg.Chaos.Enabled)
+	assert.Equal(t, 250*time.Millisecond, cfg.Chaos.Delay)
+	assert.Equal(t, []string{"UNAVAILABLE"}, cfg.Chaos.ErrorCodes)
+
+	// Untouched sections keep their defaults
+	assert.Equal(t, Default().Session.MaxSessions, cfg.Session.MaxSessions)
+	assert.Contains(t, cfg.GRPC.HeaderForwarding.BlockedHeaders, "cookie")
+}
+
+func TestLoad_InvalidChaos(t *testing.T) {
+	path := writeConfigFile(t, `
+chaos:
+  enabled: true
+  error_percent: 150
+  error_codes: [INTERNAL]
+`)
+
+	_, err := Load(path)
+	assert.Error(t, err)
+}
+
+func TestLoad_HedgingRequiresTools(t *testing.T) {
+	path := writeConfigFile(t, `
+hedging:
+  enabled: true
+`)
+
+	_, err := Load(path)
+	assert.ErrorContains(t, err, "hedging requires tools")
+}
+
+func TestLoad_PerUpstreamConnection(t *testing.T) {
+	path := writeConfigFile(t, `
+grpc:
+  max_recv_message_size: 16777216
+routing:
+  replicas:
+    - host: backend-2
+      port: 50051
+      connection:
+        max_send_message_size: 1048576
+        keep_alive:
+          time: 30s
+          timeout: 10s
+`)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	primary := cfg.GRPC.Connection(ConnectionConfig{})
+	assert.Equal(t, 16*1024*1024, primary.MaxRecvMessageSize)
+	assert.Equal(t, 4*1024*1024, primary.MaxSendMessageSize)
+	assert.Equal(t, 10*time.Second, primary.KeepAlive.Time)
+
+	replica := cfg.GRPC.Connection(cfg.Routing.Replicas[0].Connection)
+	assert.Equal(t, 16*1024*1024, replica.MaxRecvMessageSize)
+	assert.Equal(t, 1024*1024, replica.MaxSendMessageSize)
+	assert.Equal(t, KeepAliveConfig{Time: 30 * time.Second, Timeout: 10 * time.Second}, replica.KeepAlive)
+	assert.Equal(t, 5*time.Second, replica.ConnectTimeout)
+}
+
+func TestLoad_InvalidConnection(t *testing.T) {
+	path := writeConfigFile(t, `
+mirror:
+  connection:
+    max_recv_message_size: -1
+`)
+
+	_, err := Load(path)
+	assert.Error(t, err)
+}
+
+func TestLoad_UpstreamTLS(t *testing.T) {
+	path := writeConfigFile(t, `
+grpc:
+  tls:
+    enabled: true
+    ca_file: /etc/ggrmcp/ca.pem
+  cert_expiry_warning: 168h
+routing:
+  replicas:
+    - host: backend-2
+      port: 50051
+      connection:
+        tls:
+          enabled: true
+          server_name: backend.internal
+`)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, 7*24*time.Hour, cfg.GRPC.CertExpiryWarning)
+	assert.Equal(t, "/etc/ggrmcp/ca.pem", cfg.GRPC.Connection(ConnectionConfig{}).TLS.CAFile)
+	assert.Equal(t, UpstreamTLSConfig{Enabled: true, ServerName: "backend.internal"}, cfg.GRPC.Connection(cfg.Routing.Replicas[0].Connection).TLS)
+
+	// A client certificate needs its key
+	path = writeConfigFile(t, `
+grpc:
+  tls:
+    enabled: true
+    cert_file: /etc/ggrmcp/client.pem
+`)
+	_, err = Load(path)
+	assert.ErrorContains(t, err, "cert_file and key_file")
+}
+
+func TestLoad_UnsafeAllowedHeader(t *testing.T) {
+	path := writeConfigFile(t, `
+grpc:
+  header_forwarding:
+    allowed_headers: [authorization, Proxy-Authorization]
+`)
+
+	_, err := Load(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "allow_unsafe_headers")
+
+	path = writeConfigFile(t, `
+grpc:
+  header_forwarding:
+    allowed_headers: [authorization, Proxy-Authorization]
+    allow_unsafe_headers: true
+`)
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.True(t, cfg.GRPC.HeaderForwarding.AllowUnsafeHeaders)
+}
+
+func TestLoad_InvalidClaimHeaders(t *testing.T) {
+	path := writeConfigFile(t, `
+server:
+  security:
+    auth:
+      enabled: true
+      hmac_secret_env: JWT_SECRET
+      claim_headers:
+        - claim: sub
+          header: cookie
+`)
+
+	_, err := Load(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "reserved")
+}
+
+func TestReadFile_LeavesValidationToCaller(t *testing.T) {
+	path := writeConfigFile(t, `
+grpc:
+  port: 0
+`)
+	_, err := Load(path)
+	require.Error(t, err)
+
+	// A command line flag can still fix a value the file got wrong
+	cfg, err := ReadFile(path)
+	require.NoError(t, err)
+	cfg.GRPC.Port = 50051
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestApplySecureProfile(t *testing.T) {
+	cfg := Default()
+	cfg.GRPC.HeaderForwarding.ForwardAll = true
+	cfg.Session.Metadata.Enabled = true
+	cfg.Session.Metadata.MetadataKeyPrefix = ""
+	cfg.ApplySecureProfile()
+
+	assert.True(t, cfg.Server.Security.RequireTLS)
+	assert.True(t, cfg.Server.Security.ValidateOrigin)
+	assert.Empty(t, cfg.Server.Security.CORS.AllowedOrigins)
+	assert.True(t, cfg.Server.Security.Auth.Required)
+	assert.Equal(t, "strict", cfg.MCP.ErrorDetail)
+	assert.True(t, cfg.Logging.Redact)
+	assert.False(t, cfg.GRPC.HeaderForwarding.ForwardAll)
+	assert.Equal(t, "x-session-", cfg.Session.Metadata.MetadataKeyPrefix)
+	assert.False(t, cfg.Tools.Introspection.Enabled)
+	assert.False(t, cfg.Tools.DescribeTool.Enabled)
+
+	// Certificate and auth keys must be configured explicitly
+	require.Error(t, cfg.Validate())
+
+	path := writeConfigFile(t, `
+server:
+  tls:
+    cert_file: /etc/ggrmcp/tls.crt
+    key_file: /etc/ggrmcp/tls.key
+  security:
+    cors:
+      allowed_origins: ["*", "https://app.example.com"]
+    auth:
+      hmac_secret_env: JWT_SECRET
+`)
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	cfg.ApplySecureProfile()
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, []string{"https://app.example.com"}, cfg.Server.Security.CORS.AllowedOrigins)
+}
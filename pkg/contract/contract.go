@@ -0,0 +1,276 @@
+// Package contract runs declarative tool fixtures: a YAML file lists tool
+// calls with their arguments and the expected status and response fields.
+// It backs "grmcp test", which CI can run against a live upstream or a
+// replay recording to verify the MCP surface after backend changes.
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+// Expected statuses besides gRPC code names
+const (
+	StatusOK    = "OK"
+	StatusError = "ERROR"
+)
+
+// File is the YAML document holding fixtures
+type File struct {
+	Fixtures []Fixture `yaml:"fixtures"`
+}
+
+// Fixture is one tool call and its expected outcome
+type Fixture struct {
+	// Name shown in the results (defaults to the tool name)
+	Name string `yaml:"name"`
+
+	// Tool name as generated by the gateway
+	Tool string `yaml:"tool"`
+
+	// Arguments of the call
+	Arguments map[string]interface{} `yaml:"arguments"`
+
+	// Headers forwarded to the upstream as gRPC metadata
+	Headers map[string]string `yaml:"headers"`
+
+	// Expect describes the expected outcome
+	Expect Expectation `yaml:"expect"`
+}
+
+// Expectation is the expected outcome of a fixture
+type Expectation struct {
+	// Status is "OK" (the default), "ERROR" for any failure, or a gRPC code
+	// name such as NOT_FOUND or NotFound
+	Status string `yaml:"status"`
+
+	// Fields maps paths in the response ("order.id", "items[0].sku") to
+	// their expected values
+	Fields map[string]interface{} `yaml:"fields"`
+
+	// Present lists paths that must exist in the response
+	Present []string `yaml:"present"`
+
+	// Absent lists paths that must not exist in the response
+	Absent []string `yaml:"absent"`
+}
+
+// Load reads the fixtures in the YAML file at path
+func Load(path string) ([]Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures %s: %w", path, err)
+	}
+	for i, fixture := range file.Fixtures {
+		if fixture.Tool == "" {
+			return nil, fmt.Errorf("%s: fixture %d has no tool", path, i+1)
+		}
+	}
+	return file.Fixtures, nil
+}
+
+// Result is the outcome of one fixture
+type Result struct {
+	Name     string   `json:"name"`
+	Tool     string   `json:"tool"`
+	Failures []string `json:"failures,omitempty"`
+}
+
+// Passed reports whether the fixture met its expectation
+func (r Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// String returns the result formatted for command line output
+func (r Result) String() string {
+	if r.Passed() {
+		return "PASS " + r.Name
+	}
+	return "FAIL " + r.Name + ": " + strings.Join(r.Failures, "; ")
+}
+
+// Run calls the tool of every fixture through discoverer and checks the
+// outcomes, allowing each call callTimeout. Fixtures run in order, so later
+// ones can depend on earlier calls.
+func Run(ctx context.Context, discoverer grpc.ServiceDiscoverer, fixtures []Fixture, callTimeout time.Duration) []Result {
+	results := make([]Result, 0, len(fixtures))
+	for _, fixture := range fixtures {
+		callCtx, cancel := context.WithTimeout(ctx, callTimeout)
+		results = append(results, run(callCtx, discoverer, fixture))
+		cancel()
+	}
+	return results
+}
+
+// run executes one fixture
+func run(ctx context.Context, discoverer grpc.ServiceDiscoverer, fixture Fixture) Result {
+	result := Result{Name: fixture.Name, Tool: fixture.Tool}
+	if result.Name == "" {
+		result.Name = fixture.Tool
+	}
+	fail := func(format string, args ...interface{}) Result {
+		result.Failures = append(result.Failures, fmt.Sprintf(format, args...))
+		return result
+	}
+
+	if _, ok := discoverer.GetMethod(fixture.Tool); !ok {
+		return fail("tool %s not found", fixture.Tool)
+	}
+	arguments := fixture.Arguments
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+	input, err := json.Marshal(arguments)
+	if err != nil {
+		return fail("invalid arguments: %v", err)
+	}
+
+	response, callErr := discoverer.InvokeMethodByTool(ctx, fixture.Headers, fixture.Tool, string(input))
+	expected := fixture.Expect.Status
+	if expected == "" {
+		expected = StatusOK
+	}
+	actual := StatusOK
+	if callErr != nil {
+		actual = errorCode(callErr)
+	}
+	if !statusMatches(expected, actual) {
+		if callErr != nil {
+			return fail("expected status %s, got %s: %v", expected, actual, callErr)
+		}
+		return fail("expected status %s, got %s", expected, actual)
+	}
+	if callErr != nil {
+		return result
+	}
+
+	var body interface{}
+	if err := json.Unmarshal([]byte(response), &body); err != nil {
+		return fail("response is not JSON: %v", err)
+	}
+	for _, path := range sortedKeys(fixture.Expect.Fields) {
+		value, ok := lookup(body, path)
+		if !ok {
+			fail("%s: missing", path)
+			continue
+		}
+		want := normalize(fixture.Expect.Fields[path])
+		if !reflect.DeepEqual(want, value) {
+			fail("%s: expected %s, got %s", path, jsonString(want), jsonString(value))
+		}
+	}
+	for _, path := range fixture.Expect.Present {
+		if _, ok := lookup(body, path); !ok {
+			fail("%s: missing", path)
+		}
+	}
+	for _, path := range fixture.Expect.Absent {
+		if _, ok := lookup(body, path); ok {
+			fail("%s: present", path)
+		}
+	}
+	return result
+}
+
+// recordedCode extracts the code from a gRPC error message, as stored in
+// replay recordings
+var recordedCode = regexp.MustCompile(`code = (\w+) desc`)
+
+// errorCode returns the gRPC code name of a failed call
+func errorCode(err error) string {
+	if s, ok := status.FromError(err); ok && s.Code() != codes.Unknown {
+		return s.Code().String()
+	}
+	if match := recordedCode.FindStringSubmatch(err.Error()); match != nil {
+		return match[1]
+	}
+	return codes.Unknown.String()
+}
+
+// statusMatches compares status names ignoring case and underscores, so
+// NOT_FOUND matches NotFound
+func statusMatches(expected, actual string) bool {
+	canonical := func(s string) string {
+		return strings.ToUpper(strings.ReplaceAll(s, "_", ""))
+	}
+	if canonical(expected) == StatusError {
+		return actual != StatusOK
+	}
+	return canonical(expected) == canonical(actual)
+}
+
+// lookup resolves a path like "items[0].sku" in a decoded JSON value
+func lookup(value interface{}, path string) (interface{}, bool) {
+	for _, part := range strings.Split(strings.ReplaceAll(path, "[", ".["), ".") {
+		if part == "" {
+			continue
+		}
+		if strings.HasPrefix(part, "[") && strings.HasSuffix(part, "]") {
+			index, err := strconv.Atoi(part[1 : len(part)-1])
+			items, ok := value.([]interface{})
+			if err != nil || !ok || index < 0 || index >= len(items) {
+				return nil, false
+			}
+			value = items[index]
+			continue
+		}
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[part]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// normalize converts a YAML value to its decoded JSON form (numbers as
+// float64, maps keyed by string) so it compares equal to the response
+func normalize(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+// jsonString formats a value for failure messages
+func jsonString(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// sortedKeys returns the keys of m in order, for stable failure messages
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,118 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeDiscoverer answers calls from a table of responses keyed by tool and arguments
+type fakeDiscoverer struct {
+	grpc.ServiceDiscoverer
+	responses map[string]string
+	errors    map[string]error
+}
+
+func (f *fakeDiscoverer) GetMethod(toolName string) (types.MethodInfo, bool) {
+	return types.MethodInfo{ToolName: toolName}, toolName != "missing_tool"
+}
+
+func (f *fakeDiscoverer) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	key := toolName + " " + inputJSON
+	if err, ok := f.errors[key]; ok {
+		return "", err
+	}
+	return f.responses[key], nil
+}
+
+const fixturesYAML = `
+fixtures:
+  - name: get order
+    tool: orders_get
+    arguments:
+      order_id: "42"
+    expect:
+      fields:
+        order.id: "42"
+        order.total: 9.5
+        order.items[1].sku: B
+        order.tags: [new, paid]
+      present: [order.created_at]
+      absent: [order.deleted_at]
+  - tool: orders_get
+    arguments:
+      order_id: missing
+    expect:
+      status: NOT_FOUND
+  - name: recorded error
+    tool: orders_get
+    arguments:
+      order_id: recorded
+    expect:
+      status: ERROR
+  - name: wrong expectations
+    tool: orders_get
+    arguments:
+      order_id: "42"
+    expect:
+      fields:
+        order.id: "43"
+        order.items[5].sku: B
+      present: [order.shipped_at]
+  - name: unexpected failure
+    tool: orders_get
+    arguments:
+      order_id: missing
+  - name: unknown tool
+    tool: missing_tool
+`
+
+func TestRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(fixturesYAML), 0o644))
+	fixtures, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, fixtures, 6)
+
+	discoverer := &fakeDiscoverer{
+		responses: map[string]string{
+			`orders_get {"order_id":"42"}`: `{"order":{"id":"42","total":9.5,"tags":["new","paid"],"created_at":"2024-01-02T00:00:00Z","items":[{"sku":"A"},{"sku":"B"}]}}`,
+		},
+		errors: map[string]error{
+			`orders_get {"order_id":"missing"}`:  status.Error(codes.NotFound, "order not found"),
+			`orders_get {"order_id":"recorded"}`: errors.New("rpc error: code = Unavailable desc = connection refused"),
+		},
+	}
+	results := Run(context.Background(), discoverer, fixtures, time.Second)
+	require.Len(t, results, 6)
+
+	assert.True(t, results[0].Passed(), results[0].String())
+	assert.True(t, results[1].Passed(), results[1].String())
+	assert.Equal(t, "orders_get", results[1].Name)
+	assert.True(t, results[2].Passed(), results[2].String())
+
+	assert.Equal(t, []string{
+		`order.id: expected "43", got "42"`,
+		"order.items[5].sku: missing",
+		"order.shipped_at: missing",
+	}, results[3].Failures)
+	assert.Equal(t, []string{"expected status OK, got NotFound: rpc error: code = NotFound desc = order not found"}, results[4].Failures)
+	assert.Equal(t, "FAIL unknown tool: tool missing_tool not found", results[5].String())
+}
+
+func TestLoad_RequiresTool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("fixtures:\n  - name: no tool\n"), 0o644))
+	_, err := Load(path)
+	assert.ErrorContains(t, err, "fixture 1 has no tool")
+}
@@ -0,0 +1,136 @@
+package descriptors
+
+import (
+	"regexp"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// CommentFormat 控制从 proto 注释生成描述时的格式
+type CommentFormat struct {
+	// Normalize 规范化注释：去掉块注释的 '*' 前缀和 /** */ 标记，
+	// 把因换行而断开的句子合并为段落，压缩多余的空白
+	Normalize bool
+	// Detached 在前导注释之前包含与定义之间隔着空行的分离注释
+	Detached bool
+	// MaxLength 描述的最大字符数，超出时截断并以 "…" 结尾（0 表示不限制）
+	MaxLength int
+}
+
+// DefaultCommentFormat 规范化注释、不包含分离注释、不限制长度
+var DefaultCommentFormat = CommentFormat{Normalize: true}
+
+// listItem 匹配 markdown 列表项，规范化时这些行不与上一行合并
+var listItem = regexp.MustCompile(`^([-+*]|\d+[.)])\s`)
+
+// FormatComments 按 format 从描述符的源码信息中提取注释
+// 参数：
+//   - desc: protoreflect.Descriptor - 消息、字段、服务、方法等的描述符对象
+//   - format: CommentFormat - 注释格式
+//
+// 返回值：
+//   - string - 分离注释（可选）、前导注释与尾随注释按段落合并后的文本；没有源码信息时为空
+func FormatComments(desc protoreflect.Descriptor, format CommentFormat) string {
+	loc := desc.ParentFile().SourceLocations().ByDescriptor(desc)
+
+	var parts []string
+	if format.Detached {
+		parts = append(parts, loc.LeadingDetachedComments...)
+	}
+	parts = append(parts, loc.LeadingComments, loc.TrailingComments)
+
+	var paragraphs []string
+	for _, part := range parts {
+		if format.Normalize {
+			part = NormalizeComment(part)
+		}
+		if strings.TrimSpace(part) != "" {
+			paragraphs = append(paragraphs, part)
+		}
+	}
+
+	separator := "\n"
+	if format.Normalize {
+		separator = "\n\n"
+	}
+	return TruncateComment(strings.Join(paragraphs, separator), format.MaxLength)
+}
+
+// NormalizeComment 规范化一段 proto 注释
+//
+// 核心逻辑：
+// 1. 去掉 /** 与 */ 标记以及块注释行首的 '*'
+// （protoc 已去掉块注释后续行的 '*'，只剩 /** 开头那一行；其他工具生成的描述符
+// 若每行都以 '*' 开头，则全部去掉）
+// 2. 去掉每行首尾的空白
+// 3. 把连续的非空行合并为一个段落（markdown 列表项保持单独一行），段落之间以空行分隔
+// 4. 把连续的空白压缩为一个空格
+func NormalizeComment(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	starred := 0
+	content := 0
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		line = strings.TrimPrefix(line, "/*")
+		line = strings.TrimSuffix(line, "*/")
+		if strings.Trim(line, "*") == "" {
+			// 只有星号的行（例如 /***** 横幅）
+			line = ""
+		} else if content == 0 {
+			// 第一行内容：/** 留下的 '*'
+			line = strings.TrimLeft(line, "*")
+		} else if strings.HasPrefix(line, "*") {
+			starred++
+		}
+		if line != "" {
+			content++
+		}
+		lines[i] = line
+	}
+	stripAll := content > 1 && starred == content-1
+
+	var paragraphs []string
+	var current []string
+	flush := func() {
+		if len(current) > 0 {
+			paragraphs = append(paragraphs, strings.Join(current, "\n"))
+			current = nil
+		}
+	}
+	for _, line := range lines {
+		if stripAll {
+			line = strings.TrimLeft(line, "*")
+		}
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			flush()
+			continue
+		}
+		if len(current) > 0 && !listItem.MatchString(line) {
+			current[len(current)-1] += " " + line
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// TruncateComment 把文本截断到最多 maxLength 个字符，截断时以 "…" 结尾；maxLength 不大于 0 时原样返回
+func TruncateComment(text string, maxLength int) string {
+	if maxLength <= 0 {
+		return text
+	}
+	runes := []rune(text)
+	if len(runes) <= maxLength {
+		return text
+	}
+	cut := strings.TrimRight(string(runes[:maxLength-1]), " \n")
+	// 尽量在单词边界截断
+	if i := strings.LastIndexAny(cut, " \n"); i > len(cut)/2 {
+		cut = strings.TrimRight(cut[:i], " \n")
+	}
+	return cut + "…"
+}
@@ -0,0 +1,88 @@
+package descriptors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestNormalizeComment(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "line comment wrapped over several lines",
+			text: " Returns the book with the given\n identifier.\n",
+			want: "Returns the book with the given identifier.",
+		},
+		{
+			name: "javadoc block as stripped by protoc",
+			text: "*\n Creates a book.\n\n The   shelf must exist.\n",
+			want: "Creates a book.\n\nThe shelf must exist.",
+		},
+		{
+			name: "every line starred",
+			text: "/**\n * Deletes a book.\n * Fails when the book is on loan.\n */",
+			want: "Deletes a book. Fails when the book is on loan.",
+		},
+		{
+			name: "list items stay on their own lines",
+			text: " Supported filters:\n - author\n - year\n 1. first\n",
+			want: "Supported filters:\n- author\n- year\n1. first",
+		},
+		{
+			name: "banner lines",
+			text: "*****************\n Section\n*****************",
+			want: "Section",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeComment(tt.text))
+		})
+	}
+}
+
+func TestTruncateComment(t *testing.T) {
+	assert.Equal(t, "short", TruncateComment("short", 10))
+	assert.Equal(t, "unchanged text", TruncateComment("unchanged text", 0))
+	assert.Equal(t, "Returns the…", TruncateComment("Returns the book with the given id", 14))
+	// Lengths count characters, not bytes
+	assert.Equal(t, "书籍服…", TruncateComment("书籍服务接口", 4))
+}
+
+func TestFormatComments(t *testing.T) {
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:        proto.String("comments.proto"),
+		Package:     proto.String("comments"),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Book")}},
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+			Location: []*descriptorpb.SourceCodeInfo_Location{{
+				Path:                    []int32{4, 0},
+				Span:                    []int32{5, 0, 7, 1},
+				LeadingDetachedComments: []string{" Library types.\n"},
+				LeadingComments:         proto.String(" A book in the\n library.\n"),
+				TrailingComments:        proto.String(" Immutable.\n"),
+			}},
+		},
+	}
+	fd, err := protodesc.NewFile(fdp, &protoregistry.Files{})
+	require.NoError(t, err)
+	book := fd.Messages().ByName("Book")
+
+	assert.Equal(t, "A book in the library.\n\nImmutable.", FormatComments(book, DefaultCommentFormat))
+	assert.Equal(t, "Library types.\n\nA book in the library.\n\nImmutable.",
+		FormatComments(book, CommentFormat{Normalize: true, Detached: true}))
+	assert.Equal(t, "A book in the…", FormatComments(book, CommentFormat{Normalize: true, MaxLength: 16}))
+
+	// Without normalization the comments are kept as written
+	assert.Equal(t, " A book in the\n library.\n\n Immutable.\n", FormatComments(book, CommentFormat{}))
+}
@@ -104,7 +104,9 @@ func (l *Loader) LoadFromFile(path string) (*descriptorpb.FileDescriptorSet, err
 //  1. 创建新的空文件注册表
 //  2. 定义递归的 processFile 函数来处理文件及其依赖：
 //     a. 检查文件是否已处理（避免重复处理）
-//     b. 递归处理所有依赖文件（确保依赖顺序正确）
+//     b. 递归处理所有依赖文件（确保依赖顺序正确）；
+//     FileDescriptorSet 中缺失的 google/protobuf 和 google/api 依赖
+//     使用随二进制内置的描述符，无需在构建时指定 --include_imports
 //     c. 使用 protodesc.NewFile 创建文件描述符
 //     d. 如果创建失败，尝试使用全局注册表（用于已知的标准类型）
 //     e. 将文件描述符注册到文件注册表中
@@ -147,6 +149,11 @@ func (l *Loader) BuildRegistry(fdSet *descriptorpb.FileDescriptorSet) (*protoreg
 				if err := processFile(depFd); err != nil {
 					return err
 				}
+			} else if RegisterBundledFile(files, dep) {
+				// 使用随二进制内置的 google/protobuf 或 google/api 描述符
+				l.logger.Debug("Resolved dependency from bundled descriptors",
+					zap.String("file", fileName),
+					zap.String("dependency", dep))
 			} else {
 				l.logger.Warn("Dependency not found in FileDescriptorSet",
 					zap.String("file", fileName),
@@ -155,13 +162,11 @@ func (l *Loader) BuildRegistry(fdSet *descriptorpb.FileDescriptorSet) (*protoreg
 		}
 
 		// 创建文件描述符
+		// 只在本描述符集合的注册表中解析依赖，不回退到全局注册表：
+		// 全局注册表中可能有网关自身链接的同名但定义不同的文件
 		fd, err := protodesc.NewFile(fdProto, files)
 		if err != nil {
-			// 如果创建失败，尝试使用全局注册表作为解析器（用于已知的标准类型）
-			fd, err = protodesc.NewFile(fdProto, protoregistry.GlobalFiles)
-			if err != nil {
-				return fmt.Errorf("failed to create file descriptor for %s: %w", fileName, err)
-			}
+			return fmt.Errorf("failed to create file descriptor for %s: %w", fileName, err)
 		}
 
 		// 注册文件到文件注册表
@@ -215,7 +220,23 @@ func (l *Loader) BuildRegistry(fdSet *descriptorpb.FileDescriptorSet) (*protoreg
 // - 包含更丰富的元数据信息
 // - 不需要连接到运行中的 gRPC 服务器
 func (l *Loader) ExtractMethodInfo(files *protoregistry.Files) ([]types.MethodInfo, error) {
+	methods, failures := l.ExtractMethodInfoWithFailures(files)
+	for _, failure := range failures {
+		l.logger.Warn("Skipped method with unresolved descriptors",
+			zap.String("service", failure.Service),
+			zap.String("method", failure.Method),
+			zap.String("error", failure.Error))
+	}
+	return methods, nil
+}
+
+// ExtractMethodInfoWithFailures 与 ExtractMethodInfo 相同，但同时返回被跳过的方法
+//
+// 输入或输出消息无法解析（占位描述符）的方法不会出现在结果中，
+// 而是作为 DiscoveryFailure 返回，便于汇总到服务发现报告
+func (l *Loader) ExtractMethodInfoWithFailures(files *protoregistry.Files) ([]types.MethodInfo, []types.DiscoveryFailure) {
 	var methods []types.MethodInfo
+	var failures []types.DiscoveryFailure
 
 	// 遍历注册表中的所有文件
 	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
@@ -229,18 +250,28 @@ func (l *Loader) ExtractMethodInfo(files *protoregistry.Files) ([]types.MethodIn
 			// 例如："com.example.hello.HelloService" -> "hello.HelloService"
 			fullName := string(serviceDesc.FullName())
 			serviceName := extractServiceNameForCompatibility(fullName)
-			serviceDescription := extractComments(serviceDesc)
+			serviceDescription := ExtractComments(serviceDesc)
 
 			// 处理服务中的每个方法，并直接添加到扁平列表
 			for j := 0; j < serviceDesc.Methods().Len(); j++ {
 				methodDesc := serviceDesc.Methods().Get(j)
 
+				// 跳过消息类型无法解析的方法，并记录原因
+				if unresolved := unresolvedMessage(methodDesc); unresolved != "" {
+					failures = append(failures, types.DiscoveryFailure{
+						Service: fullName,
+						Method:  string(methodDesc.Name()),
+						Error:   fmt.Sprintf("unresolved message type %s", unresolved),
+					})
+					continue
+				}
+
 				methodInfo := types.MethodInfo{
 					Name:               string(methodDesc.Name()),
 					FullName:           string(methodDesc.FullName()),
 					ServiceName:        serviceName,
 					ServiceDescription: serviceDescription,
-					Description:        extractComments(methodDesc),
+					Description:        ExtractComments(methodDesc),
 					InputType:          string(methodDesc.Input().FullName()),
 					OutputType:         string(methodDesc.Output().FullName()),
 					InputDescriptor:    methodDesc.Input(),
@@ -248,7 +279,7 @@ func (l *Loader) ExtractMethodInfo(files *protoregistry.Files) ([]types.MethodIn
 					IsClientStreaming:  methodDesc.IsStreamingClient(),
 					IsServerStreaming:  methodDesc.IsStreamingServer(),
 					// 从文件描述符中提取的额外字段
-					Comments: []string{extractComments(methodDesc)},
+					Comments: []string{ExtractComments(methodDesc)},
 				}
 
 				// 生成工具名称（用于 MCP 工具调用）
@@ -266,12 +297,23 @@ func (l *Loader) ExtractMethodInfo(files *protoregistry.Files) ([]types.MethodIn
 	})
 
 	l.logger.Info("Extracted methods from FileDescriptorSet",
-		zap.Int("methodCount", len(methods)))
+		zap.Int("methodCount", len(methods)),
+		zap.Int("failureCount", len(failures)))
 
-	return methods, nil
+	return methods, failures
+}
+
+// unresolvedMessage 返回方法中第一个无法解析的消息类型名，全部可解析时返回空字符串
+func unresolvedMessage(method protoreflect.MethodDescriptor) string {
+	for _, msg := range []protoreflect.MessageDescriptor{method.Input(), method.Output()} {
+		if msg.IsPlaceholder() {
+			return string(msg.FullName())
+		}
+	}
+	return ""
 }
 
-// extractComments 从描述符中提取前导和尾随注释
+// ExtractComments 从描述符中提取前导和尾随注释
 // 参数：
 //   - desc: protoreflect.Descriptor - 消息、服务、方法等的描述符对象
 //
@@ -288,7 +330,9 @@ func (l *Loader) ExtractMethodInfo(files *protoregistry.Files) ([]types.MethodIn
 // 注释类型说明：
 // - 前导注释：定义前的多行或单行注释（通常用于文档）
 // - 尾随注释：定义后同行的注释（通常用于简短说明）
-func extractComments(desc protoreflect.Descriptor) string {
+//
+// Reflection 得到的描述符带有源码信息时也用它提取注释；没有源码信息时返回空字符串
+func ExtractComments(desc protoreflect.Descriptor) string {
 	// 从父文件获取源代码位置信息（如果可用）
 	loc := desc.ParentFile().SourceLocations().ByDescriptor(desc)
 
@@ -0,0 +1,93 @@
+package descriptors
+
+import (
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestExtractMethodInfoWithFailures_UnresolvedMessage(t *testing.T) {
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("partial.proto"),
+		Package:    proto.String("partial"),
+		Dependency: []string{"missing.proto"},
+		Syntax:     proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Request")},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("PartialService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: proto.String("Good"), InputType: proto.String(".partial.Request"), OutputType: proto.String(".partial.Request")},
+				{Name: proto.String("Bad"), InputType: proto.String(".partial.Request"), OutputType: proto.String(".missing.Response")},
+			},
+		}},
+	}
+	fd, err := protodesc.FileOptions{AllowUnresolvable: true}.New(fdp, &protoregistry.Files{})
+	require.NoError(t, err)
+	files := &protoregistry.Files{}
+	require.NoError(t, files.RegisterFile(fd))
+
+	methods, failures := NewLoader(zap.NewNop()).ExtractMethodInfoWithFailures(files)
+
+	require.Len(t, methods, 1)
+	assert.Equal(t, "Good", methods[0].Name)
+	assert.Equal(t, []types.DiscoveryFailure{{
+		Service: "partial.PartialService",
+		Method:  "Bad",
+		Error:   "unresolved message type missing.Response",
+	}}, failures)
+}
+
+func TestBuildRegistry_ResolvesBundledDependencies(t *testing.T) {
+	// events.proto imports a local file and well-known/google.api files that
+	// are not part of the set (built without --include_imports)
+	common := &descriptorpb.FileDescriptorProto{
+		Name:        proto.String("common.proto"),
+		Package:     proto.String("events"),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Empty")}},
+	}
+	events := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("events.proto"),
+		Package:    proto.String("events"),
+		Dependency: []string{"common.proto", "google/protobuf/timestamp.proto", "google/api/annotations.proto"},
+		Syntax:     proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Event"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:     proto.String("at"),
+				Number:   proto.Int32(1),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+				TypeName: proto.String(".google.protobuf.Timestamp"),
+				JsonName: proto.String("at"),
+			}},
+		}},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("EventService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: proto.String("Publish"), InputType: proto.String(".events.Event"), OutputType: proto.String(".events.Empty")},
+			},
+		}},
+	}
+
+	loader := NewLoader(zap.NewNop())
+	files, err := loader.BuildRegistry(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{events, common}})
+	require.NoError(t, err)
+
+	methods, err := loader.ExtractMethodInfo(files)
+	require.NoError(t, err)
+	require.Len(t, methods, 1)
+	field := methods[0].InputDescriptor.Fields().ByName("at")
+	require.NotNil(t, field)
+	assert.Equal(t, "google.protobuf.Timestamp", string(field.Message().FullName()))
+	assert.False(t, field.Message().IsPlaceholder())
+}
@@ -0,0 +1,200 @@
+package descriptors
+
+import (
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// 校验问题的严重级别
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue 是 FileDescriptorSet 校验发现的一个问题
+type Issue struct {
+	// Severity: 严重级别（error 会导致加载失败或工具缺失，warning 会降低工具质量）
+	Severity string `json:"severity"`
+	// File: 问题所在的 .proto 文件，整体问题为空
+	File string `json:"file,omitempty"`
+	// Message: 问题描述
+	Message string `json:"message"`
+	// Fix: 需要对 protoc 调用或 .proto 文件做的修改
+	Fix string `json:"fix"`
+}
+
+// String 返回适合命令行输出的描述，问题和修改建议各占一行
+func (i Issue) String() string {
+	location := ""
+	if i.File != "" {
+		location = i.File + ": "
+	}
+	return fmt.Sprintf("%s: %s%s\n  fix: %s", i.Severity, location, i.Message, i.Fix)
+}
+
+// ValidateDescriptorSet 检查 FileDescriptorSet 是否适合网关使用
+// 参数：
+//   - fdSet: *descriptorpb.FileDescriptorSet - 已加载的 FileDescriptorSet
+//
+// 返回值：
+//   - []Issue - 发现的问题列表，按严重级别（error 在前）和文件排序
+//
+// 检查项：
+// 1. 重复文件：同一路径出现多次（通常是合并了多个描述符集合）
+// 2. 缺失依赖：import 的文件既不在集合中，也不是内置的 google/protobuf 或 google/api 文件
+// 3. 无法构建：文件描述符无法解析（例如类型引用错误）
+// 4. 缺少源码信息：没有任何注释信息，工具描述将退化为通用文本
+// 5. 不支持的特性：流式方法不会暴露为工具；没有任何服务时没有可用工具
+func ValidateDescriptorSet(fdSet *descriptorpb.FileDescriptorSet) []Issue {
+	var issues []Issue
+
+	// 🔍 重复文件
+	seen := make(map[string]int)
+	for _, fdProto := range fdSet.File {
+		seen[fdProto.GetName()]++
+	}
+	for name, count := range seen {
+		if count > 1 {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				File:     name,
+				Message:  fmt.Sprintf("file appears %d times in the descriptor set", count),
+				Fix:      "generate a single descriptor set with one protoc invocation instead of concatenating several .binpb files",
+			})
+		}
+	}
+
+	// 🔍 缺失依赖
+	missing := false
+	for _, fdProto := range fdSet.File {
+		for _, dep := range fdProto.Dependency {
+			if seen[dep] > 0 {
+				continue
+			}
+			if _, ok := bundledFile(dep); ok {
+				continue
+			}
+			missing = true
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				File:     fdProto.GetName(),
+				Message:  fmt.Sprintf("imported file %q is not in the descriptor set", dep),
+				Fix:      "add --include_imports to the protoc invocation (and the --proto_path that contains " + dep + ")",
+			})
+		}
+	}
+
+	// 🔍 能否构建：依赖缺失时跳过，避免重复报告同一问题
+	if !missing {
+		if _, err := buildForValidation(fdSet); err != nil {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Message:  err.Error(),
+				Fix:      "make sure the .proto files compile with protoc and regenerate the descriptor set",
+			})
+		}
+	}
+
+	// 🔍 源码信息和服务特性
+	hasSourceInfo := false
+	services := 0
+	for _, fdProto := range fdSet.File {
+		if len(fdProto.GetSourceCodeInfo().GetLocation()) > 0 {
+			hasSourceInfo = true
+		}
+		for _, service := range fdProto.Service {
+			services++
+			for _, method := range service.Method {
+				if method.GetClientStreaming() || method.GetServerStreaming() {
+					issues = append(issues, Issue{
+						Severity: SeverityWarning,
+						File:     fdProto.GetName(),
+						Message:  fmt.Sprintf("streaming method %s.%s is not exposed as a tool", service.GetName(), method.GetName()),
+						Fix:      "add a unary method for agents to call, or ignore this warning",
+					})
+				}
+			}
+		}
+	}
+	if !hasSourceInfo && len(fdSet.File) > 0 {
+		issues = append(issues, Issue{
+			Severity: SeverityWarning,
+			Message:  "descriptor set has no source info, so tool and field descriptions will not include comments",
+			Fix:      "add --include_source_info to the protoc invocation",
+		})
+	}
+	if services == 0 {
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Message:  "descriptor set does not define any services",
+			Fix:      "pass the .proto files that declare your services to protoc",
+		})
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Severity != issues[j].Severity {
+			return issues[i].Severity == SeverityError
+		}
+		return issues[i].File < issues[j].File
+	})
+	return issues
+}
+
+// HasErrors 判断问题列表中是否包含 error 级别的问题
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// buildForValidation 按 BuildRegistry 的方式构建注册表，但不输出日志、不回退到全局注册表
+func buildForValidation(fdSet *descriptorpb.FileDescriptorSet) (*protoregistry.Files, error) {
+	files := &protoregistry.Files{}
+	byName := make(map[string]*descriptorpb.FileDescriptorProto, len(fdSet.File))
+	for _, fdProto := range fdSet.File {
+		byName[fdProto.GetName()] = fdProto
+	}
+
+	visiting := make(map[string]bool)
+	var build func(name string) error
+	build = func(name string) error {
+		if _, err := files.FindFileByPath(name); err == nil {
+			return nil
+		}
+		if visiting[name] {
+			return fmt.Errorf("%s: import cycle", name)
+		}
+		visiting[name] = true
+		fdProto, ok := byName[name]
+		if !ok {
+			if !RegisterBundledFile(files, name) {
+				return fmt.Errorf("%s: file not found", name)
+			}
+			return nil
+		}
+		for _, dep := range fdProto.Dependency {
+			if err := build(dep); err != nil {
+				return err
+			}
+		}
+		fd, err := protodesc.NewFile(fdProto, files)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return files.RegisterFile(fd)
+	}
+
+	for _, fdProto := range fdSet.File {
+		if err := build(fdProto.GetName()); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,66 @@
+package descriptors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func validationFile(name string, deps ...string) *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String(name),
+		Package:    proto.String("check"),
+		Dependency: deps,
+		Syntax:     proto.String("proto3"),
+	}
+}
+
+func TestValidateDescriptorSet(t *testing.T) {
+	service := validationFile("service.proto", "types.proto", "google/protobuf/empty.proto")
+	service.Service = []*descriptorpb.ServiceDescriptorProto{{
+		Name: proto.String("CheckService"),
+		Method: []*descriptorpb.MethodDescriptorProto{
+			{Name: proto.String("Get"), InputType: proto.String(".google.protobuf.Empty"), OutputType: proto.String(".google.protobuf.Empty")},
+			{Name: proto.String("Watch"), InputType: proto.String(".google.protobuf.Empty"), OutputType: proto.String(".google.protobuf.Empty"), ServerStreaming: proto.Bool(true)},
+		},
+	}}
+
+	t.Run("MissingImport", func(t *testing.T) {
+		issues := ValidateDescriptorSet(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{service}})
+
+		assert.True(t, HasErrors(issues))
+		assert.Equal(t, SeverityError, issues[0].Severity)
+		assert.Equal(t, "service.proto", issues[0].File)
+		assert.Contains(t, issues[0].Message, `"types.proto"`)
+		assert.Contains(t, issues[0].Fix, "--include_imports")
+	})
+
+	t.Run("DuplicateFile", func(t *testing.T) {
+		types := validationFile("types.proto")
+		issues := ValidateDescriptorSet(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{types, service, types}})
+
+		assert.True(t, HasErrors(issues))
+		assert.Equal(t, "types.proto", issues[0].File)
+		assert.Contains(t, issues[0].Message, "2 times")
+	})
+
+	t.Run("WarningsOnly", func(t *testing.T) {
+		issues := ValidateDescriptorSet(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{validationFile("types.proto"), service}})
+
+		assert.False(t, HasErrors(issues))
+		var fixes []string
+		for _, issue := range issues {
+			fixes = append(fixes, issue.Fix)
+		}
+		assert.Len(t, issues, 2)
+		assert.Contains(t, fixes, "add --include_source_info to the protoc invocation")
+		assert.Contains(t, issues[0].Message+issues[1].Message, "streaming method CheckService.Watch")
+	})
+
+	t.Run("NoServices", func(t *testing.T) {
+		issues := ValidateDescriptorSet(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{validationFile("types.proto")}})
+		assert.True(t, HasErrors(issues))
+	})
+}
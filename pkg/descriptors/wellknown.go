@@ -0,0 +1,84 @@
+package descriptors
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	// 内置的 google/api 描述符（HTTP 注解、字段行为、资源等）
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	_ "google.golang.org/genproto/googleapis/api/httpbody"
+
+	// 内置的 google/protobuf 描述符（Well-Known Types）
+	_ "google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/anypb"
+	_ "google.golang.org/protobuf/types/known/apipb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	_ "google.golang.org/protobuf/types/known/fieldmaskpb"
+	_ "google.golang.org/protobuf/types/known/sourcecontextpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/typepb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// bundledPrefixes 随二进制内置的描述符文件路径前缀
+var bundledPrefixes = []string{"google/protobuf/", "google/api/"}
+
+// bundledFile 返回随二进制内置的描述符文件
+// 参数：
+//   - path: string - 依赖文件路径（如 "google/protobuf/timestamp.proto"）
+//
+// 返回值：
+//   - protoreflect.FileDescriptor - 内置的文件描述符
+//   - bool - 该路径是否为内置的 google/protobuf 或 google/api 文件
+//
+// 核心逻辑：内置文件通过上方的空白导入注册到全局注册表，
+// 这里只在这两个前缀下查找，避免误用进程内其他已注册的文件
+func bundledFile(path string) (protoreflect.FileDescriptor, bool) {
+	bundled := false
+	for _, prefix := range bundledPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			bundled = true
+			break
+		}
+	}
+	if !bundled {
+		return nil, false
+	}
+
+	fd, err := protoregistry.GlobalFiles.FindFileByPath(path)
+	if err != nil {
+		return nil, false
+	}
+	return fd, true
+}
+
+// RegisterBundledFile 将内置的描述符文件及其传递依赖注册到 files
+//
+// 除 FileDescriptorSet 外，Reflection 客户端也用它为各上游独立的注册表补齐
+// 服务器未返回的标准依赖
+// 参数：
+//   - files: *protoregistry.Files - 正在构建的文件注册表
+//   - path: string - 依赖文件路径
+//
+// 返回值：
+//   - bool - 注册表中已有或成功注册了该文件时返回 true
+func RegisterBundledFile(files *protoregistry.Files, path string) bool {
+	if _, err := files.FindFileByPath(path); err == nil {
+		return true
+	}
+	fd, ok := bundledFile(path)
+	if !ok {
+		return false
+	}
+
+	// 先注册依赖，使引用依赖类型的文件可以被正确解析
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		RegisterBundledFile(files, imports.Get(i).Path())
+	}
+	return files.RegisterFile(fd) == nil
+}
@@ -0,0 +1,236 @@
+// Package examples generates example arguments for a tool from its input
+// schema. Examples read like a plausible call: enums skip their UNSPECIFIED
+// zero value, formats get realistic values, bounds and lengths are respected,
+// and only one member of each oneof is set. They back GET
+// /admin/tools/example and "grmcp example", for docs, fixtures and few-shot
+// prompts, the calls of "grmcp bench" and the inputs of the self-test's
+// protojson round trip.
+package examples
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"strings"
+)
+
+// maxDepth bounds the nesting of generated examples
+const maxDepth = 6
+
+// Options controls example generation
+type Options struct {
+	// RequiredOnly leaves out optional properties, giving the smallest valid
+	// arguments
+	RequiredOnly bool
+}
+
+// Arguments returns example arguments for an input schema. Properties that
+// cannot be described from the schema (maps, recursive messages and untyped
+// values) are left out.
+func Arguments(schema interface{}, opts Options) map[string]interface{} {
+	g := generator{opts: opts}
+	if object, ok := g.value(schema, "", 0).(map[string]interface{}); ok {
+		return object
+	}
+	return map[string]interface{}{}
+}
+
+// generator holds the options of one generation
+type generator struct {
+	opts Options
+}
+
+// value returns an example for schema, or nil when there is none; name is
+// the property holding the value, used for string examples
+func (g generator) value(schema interface{}, name string, depth int) interface{} {
+	s, ok := schema.(map[string]interface{})
+	if !ok || depth > maxDepth {
+		return nil
+	}
+	if _, ok := s["$ref"]; ok {
+		return nil
+	}
+	if examples, ok := s["examples"].([]interface{}); ok && len(examples) > 0 {
+		return examples[0]
+	}
+	if value, ok := s["default"]; ok {
+		return value
+	}
+	if enum, ok := s["enum"]; ok {
+		return enumValue(enum)
+	}
+
+	switch s["type"] {
+	case "object":
+		return g.object(s, depth)
+	case "array":
+		item := g.value(s["items"], name, depth+1)
+		if item == nil {
+			return []interface{}{}
+		}
+		items := []interface{}{item}
+		for len(items) < intValue(s["minItems"]) {
+			items = append(items, item)
+		}
+		return items
+	case "string":
+		return stringValue(s, name)
+	case "integer":
+		return math.Round(number(s, 1))
+	case "number":
+		return number(s, 1.5)
+	case "boolean":
+		return true
+	}
+	return nil
+}
+
+// object returns an example object: required properties always, optional
+// ones unless RequiredOnly, and the first member of each oneof group
+func (g generator) object(s map[string]interface{}, depth int) interface{} {
+	properties, _ := s["properties"].(map[string]interface{})
+	required := make(map[string]bool)
+	for _, name := range StringSlice(s["required"]) {
+		required[name] = true
+	}
+
+	// Oneof groups are described twice: by their member fields and by a
+	// synthetic property holding the alternatives. Keep the first member only,
+	// and set it even with RequiredOnly when the group itself is required.
+	skip := make(map[string]bool)
+	for name, property := range properties {
+		group, _ := property.(map[string]interface{})
+		options, ok := group["oneOf"].([]interface{})
+		if !ok {
+			continue
+		}
+		skip[name] = true
+		for i, option := range options {
+			alternative, _ := option.(map[string]interface{})
+			for _, member := range StringSlice(alternative["required"]) {
+				if i > 0 {
+					skip[member] = true
+				} else if required[name] {
+					required[member] = true
+				}
+			}
+		}
+	}
+
+	object := make(map[string]interface{}, len(properties))
+	for _, name := range slices.Sorted(maps.Keys(properties)) {
+		if skip[name] || (g.opts.RequiredOnly && !required[name]) {
+			continue
+		}
+		if value := g.value(properties[name], name, depth+1); value != nil {
+			object[name] = value
+		}
+	}
+	return object
+}
+
+// enumValue returns the first enum value that is not the UNSPECIFIED zero
+// value, which is rarely a meaningful argument
+func enumValue(enum interface{}) interface{} {
+	values := StringSlice(enum)
+	for _, value := range values {
+		if !strings.HasSuffix(value, "UNSPECIFIED") {
+			return value
+		}
+	}
+	if len(values) > 0 {
+		return values[0]
+	}
+	return nil
+}
+
+// stringValue returns an example string for its format, padded to minLength
+// and cut to maxLength
+func stringValue(s map[string]interface{}, name string) interface{} {
+	switch s["format"] {
+	case "byte":
+		return "ZXhhbXBsZQ=="
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "duration":
+		return "30s"
+	case "date":
+		return "2024-01-01"
+	case "email":
+		return "user@example.com"
+	case "uri":
+		return "https://example.com"
+	case "uuid":
+		return "123e4567-e89b-12d3-a456-426614174000"
+	}
+
+	value := "example"
+	if name != "" {
+		value = "example " + name
+	}
+	if missing := intValue(s["minLength"]) - len(value); missing > 0 {
+		value += strings.Repeat("x", missing)
+	}
+	if maximum, ok := s["maxLength"]; ok && len(value) > intValue(maximum) {
+		value = value[:intValue(maximum)]
+	}
+	return value
+}
+
+// number returns fallback moved inside the schema's minimum and maximum
+func number(s map[string]interface{}, fallback float64) float64 {
+	value := fallback
+	if minimum, ok := floatValue(s["minimum"]); ok && value < minimum {
+		value = minimum
+	}
+	if maximum, ok := floatValue(s["maximum"]); ok && value > maximum {
+		value = maximum
+	}
+	return value
+}
+
+// floatValue returns a numeric schema keyword as float64
+func floatValue(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// intValue returns a numeric schema keyword as int, 0 when missing
+func intValue(v interface{}) int {
+	n, _ := floatValue(v)
+	return int(n)
+}
+
+// StringSlice returns the strings of a schema keyword holding a list of
+// strings, such as required or enum, whether it is a []string or was decoded
+// from JSON as a []interface{}
+func StringSlice(v interface{}) []string {
+	switch values := v.(type) {
+	case []string:
+		return values
+	case []interface{}:
+		out := make([]string, 0, len(values))
+		for _, value := range values {
+			if s, ok := value.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
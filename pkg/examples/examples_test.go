@@ -0,0 +1,90 @@
+package examples
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func orderSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"customer": map[string]interface{}{"type": "string", "minLength": 12},
+			"status": map[string]interface{}{
+				"type": "string",
+				"enum": []interface{}{"STATUS_UNSPECIFIED", "STATUS_OPEN", "STATUS_CLOSED"},
+			},
+			"quantity": map[string]interface{}{"type": "integer", "format": "uint32", "minimum": 5},
+			"discount": map[string]interface{}{"type": "number", "maximum": 0.5},
+			"placedAt": map[string]interface{}{"type": "string", "format": "date-time"},
+			"note":     map[string]interface{}{"type": "string", "default": "none"},
+			"tags": map[string]interface{}{
+				"type":     "array",
+				"items":    map[string]interface{}{"type": "string"},
+				"minItems": 2,
+			},
+			"labels": map[string]interface{}{
+				"type":              "object",
+				"patternProperties": map[string]interface{}{".*": map[string]interface{}{"type": "string"}},
+			},
+			"parent": map[string]interface{}{"$ref": "#/definitions/Order"},
+			"email":  map[string]interface{}{"type": "string"},
+			"phone":  map[string]interface{}{"type": "string"},
+			"contact": map[string]interface{}{
+				"oneOf": []interface{}{
+					map[string]interface{}{"required": []interface{}{"email"}},
+					map[string]interface{}{"required": []interface{}{"phone"}},
+				},
+			},
+		},
+		"required": []interface{}{"customer", "status", "contact"},
+	}
+}
+
+func TestArguments(t *testing.T) {
+	args := Arguments(orderSchema(), Options{})
+
+	assert.Equal(t, map[string]interface{}{
+		"customer": "example customer",
+		"status":   "STATUS_OPEN",
+		"quantity": float64(5),
+		"discount": 0.5,
+		"placedAt": "2024-01-01T00:00:00Z",
+		"note":     "none",
+		"tags":     []interface{}{"example tags", "example tags"},
+		"labels":   map[string]interface{}{},
+		"email":    "example email",
+	}, args)
+}
+
+func TestArguments_RequiredOnly(t *testing.T) {
+	args := Arguments(orderSchema(), Options{RequiredOnly: true})
+
+	assert.Equal(t, map[string]interface{}{
+		"customer": "example customer",
+		"status":   "STATUS_OPEN",
+		"email":    "example email",
+	}, args)
+}
+
+func TestArguments_MinLengthAndExamples(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"code": map[string]interface{}{"type": "string", "minLength": 10, "maxLength": 10},
+			"sku":  map[string]interface{}{"type": "string", "examples": []interface{}{"SKU-1"}},
+			"only": map[string]interface{}{"type": "string", "enum": []interface{}{"KIND_UNSPECIFIED"}},
+		},
+	}
+
+	args := Arguments(schema, Options{})
+
+	assert.Equal(t, "example co", args["code"])
+	assert.Equal(t, "SKU-1", args["sku"])
+	assert.Equal(t, "KIND_UNSPECIFIED", args["only"])
+}
+
+func TestArguments_NotAnObject(t *testing.T) {
+	assert.Equal(t, map[string]interface{}{}, Arguments(nil, Options{}))
+}
@@ -0,0 +1,387 @@
+// Package federation serves the tools of upstreams registered at runtime next
+// to those of the primary upstream. Each registered upstream may prefix its
+// tool names; calls are routed to the upstream that owns the tool. A new
+// upstream is connected and discovered before its tools are added, and all
+// of them appear at once. A removed upstream disappears at once as well; its
+// connection is closed after the calls in flight complete.
+package federation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"go.uber.org/zap"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Connector connects to the upstream described by spec and discovers its
+// services
+type Connector func(ctx context.Context, spec grpc.UpstreamSpec) (grpc.ServiceDiscoverer, error)
+
+// upstream is a registered upstream
+type upstream struct {
+	name         string
+	prefix       string
+	registeredAt time.Time
+	discoverer   grpc.ServiceDiscoverer
+
+	// mu guards draining; calls counts the calls in flight
+	mu       sync.Mutex
+	draining bool
+	calls    sync.WaitGroup
+}
+
+// acquire counts a new call; it fails once the upstream is draining
+func (u *upstream) acquire() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.draining {
+		return false
+	}
+	u.calls.Add(1)
+	return true
+}
+
+// describe returns the upstream as reported by the admin API
+func (u *upstream) describe() grpc.RegisteredUpstream {
+	tools := make([]string, 0)
+	for _, method := range u.discoverer.GetMethods() {
+		tools = append(tools, u.exposed(method.ToolName))
+	}
+	return grpc.RegisteredUpstream{
+		Name:         u.name,
+		Prefix:       u.prefix,
+		Tools:        tools,
+		RegisteredAt: u.registeredAt,
+	}
+}
+
+// exposed returns the name a tool of the upstream is served under
+func (u *upstream) exposed(toolName string) string {
+	if u.prefix == "" {
+		return toolName
+	}
+	return u.prefix + "_" + toolName
+}
+
+// original returns the upstream's own name of an exposed tool
+func (u *upstream) original(toolName string) (string, bool) {
+	if u.prefix == "" {
+		return toolName, true
+	}
+	return strings.CutPrefix(toolName, u.prefix+"_")
+}
+
+// Federation wraps the primary discoverer and serves the tools of the
+// registered upstreams next to its own. The primary wins when a later
+// rediscovery makes a tool name ambiguous, then the earliest registration.
+type Federation struct {
+	grpc.ServiceDiscoverer
+
+	connect      Connector
+	maxUpstreams int
+	drainTimeout time.Duration
+	logger       *zap.Logger
+
+	// mu serializes registrations; readers use the upstreams snapshot
+	mu        sync.Mutex
+	upstreams atomic.Pointer[[]*upstream]
+}
+
+// New creates a federation over the primary discoverer; connect is used to
+// reach upstreams registered later. Removed upstreams get drainTimeout to
+// finish their calls in flight.
+func New(primary grpc.ServiceDiscoverer, connect Connector, maxUpstreams int, drainTimeout time.Duration, logger *zap.Logger) *Federation {
+	f := &Federation{
+		ServiceDiscoverer: primary,
+		connect:           connect,
+		maxUpstreams:      maxUpstreams,
+		drainTimeout:      drainTimeout,
+		logger:            logger.Named("federation"),
+	}
+	f.upstreams.Store(&[]*upstream{})
+	return f
+}
+
+// registered returns the current upstreams
+func (f *Federation) registered() []*upstream {
+	return *f.upstreams.Load()
+}
+
+// RegisterUpstream connects to and discovers the upstream, then adds all of
+// its tools at once. The upstream is closed again when it is already
+// registered, one of its tool names is taken or it serves no tools.
+func (f *Federation) RegisterUpstream(ctx context.Context, spec grpc.UpstreamSpec) (*grpc.RegisteredUpstream, error) {
+	name := fmt.Sprintf("%s:%d", spec.Host, spec.Port)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	current := f.registered()
+	if len(current) >= f.maxUpstreams {
+		return nil, fmt.Errorf("%w: the limit of %d upstreams is reached", grpc.ErrUpstreamConflict, f.maxUpstreams)
+	}
+	for _, u := range current {
+		if u.name == name {
+			return nil, fmt.Errorf("%w: %s is already registered", grpc.ErrUpstreamConflict, name)
+		}
+	}
+
+	discoverer, err := f.connect(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	added := &upstream{name: name, prefix: spec.Prefix, registeredAt: time.Now(), discoverer: discoverer}
+
+	taken := make(map[string]bool)
+	for _, method := range f.GetMethods() {
+		taken[method.ToolName] = true
+	}
+	var tools, conflicts []string
+	for _, method := range discoverer.GetMethods() {
+		tool := added.exposed(method.ToolName)
+		if taken[tool] {
+			conflicts = append(conflicts, tool)
+		}
+		tools = append(tools, tool)
+	}
+	if len(conflicts) > 0 || len(tools) == 0 {
+		_ = discoverer.Close()
+		if len(tools) == 0 {
+			return nil, fmt.Errorf("upstream %s serves no tools", name)
+		}
+		return nil, fmt.Errorf("%w: tools %v already exist, register the upstream with a prefix", grpc.ErrUpstreamConflict, conflicts)
+	}
+
+	next := make([]*upstream, 0, len(current)+1)
+	next = append(next, current...)
+	next = append(next, added)
+	f.upstreams.Store(&next)
+
+	f.logger.Info("Registered upstream",
+		zap.String("upstream", name),
+		zap.String("prefix", spec.Prefix),
+		zap.Int("tools", len(tools)))
+	return &grpc.RegisteredUpstream{Name: name, Prefix: spec.Prefix, Tools: tools, RegisteredAt: added.registeredAt}, nil
+}
+
+// UnregisterUpstream removes the upstream's tools at once and drains it in
+// the background: new calls no longer reach it, and its connection is closed
+// when the calls in flight complete or the drain timeout passes
+func (f *Federation) UnregisterUpstream(name string) (*grpc.RegisteredUpstream, error) {
+	f.mu.Lock()
+	current := f.registered()
+	var removed *upstream
+	next := make([]*upstream, 0, len(current))
+	for _, u := range current {
+		if u.name == name {
+			removed = u
+			continue
+		}
+		next = append(next, u)
+	}
+	if removed == nil {
+		f.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", grpc.ErrUpstreamNotFound, name)
+	}
+	f.upstreams.Store(&next)
+	f.mu.Unlock()
+
+	described := removed.describe()
+	f.logger.Info("Unregistered upstream",
+		zap.String("upstream", name),
+		zap.Int("tools", len(described.Tools)))
+	go f.drain(removed)
+	return &described, nil
+}
+
+// drain waits for the removed upstream's calls in flight, then closes it
+func (f *Federation) drain(u *upstream) {
+	u.mu.Lock()
+	u.draining = true
+	u.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		u.calls.Wait()
+		close(drained)
+	}()
+	timer := time.NewTimer(f.drainTimeout)
+	defer timer.Stop()
+	select {
+	case <-drained:
+	case <-timer.C:
+		f.logger.Warn("Closing removed upstream with calls still in flight",
+			zap.String("upstream", u.name),
+			zap.Duration("drainTimeout", f.drainTimeout))
+	}
+
+	if err := u.discoverer.Close(); err != nil {
+		f.logger.Warn("Failed to close removed upstream", zap.String("upstream", u.name), zap.Error(err))
+		return
+	}
+	f.logger.Info("Closed removed upstream", zap.String("upstream", u.name))
+}
+
+// Upstreams returns the registered upstreams in registration order
+func (f *Federation) Upstreams() []grpc.RegisteredUpstream {
+	registered := f.registered()
+	upstreams := make([]grpc.RegisteredUpstream, 0, len(registered))
+	for _, u := range registered {
+		upstreams = append(upstreams, u.describe())
+	}
+	return upstreams
+}
+
+// owner returns the registered upstream serving the tool and the upstream's
+// own name for it; nil means the primary
+func (f *Federation) owner(toolName string) (*upstream, types.MethodInfo, bool) {
+	if method, ok := f.ServiceDiscoverer.GetMethod(toolName); ok {
+		return nil, method, true
+	}
+	for _, u := range f.registered() {
+		original, ok := u.original(toolName)
+		if !ok {
+			continue
+		}
+		if method, ok := u.discoverer.GetMethod(original); ok {
+			return u, method, true
+		}
+	}
+	return nil, types.MethodInfo{}, false
+}
+
+// GetMethods returns the primary's methods followed by those of every
+// registered upstream, named as they are served
+func (f *Federation) GetMethods() []types.MethodInfo {
+	methods := f.ServiceDiscoverer.GetMethods()
+	registered := f.registered()
+	if len(registered) == 0 {
+		return methods
+	}
+
+	taken := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		taken[method.ToolName] = true
+	}
+	for _, u := range registered {
+		for _, method := range u.discoverer.GetMethods() {
+			method.ToolName = u.exposed(method.ToolName)
+			if taken[method.ToolName] {
+				continue
+			}
+			taken[method.ToolName] = true
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// GetMethod returns the method served under the tool name
+func (f *Federation) GetMethod(toolName string) (types.MethodInfo, bool) {
+	u, method, ok := f.owner(toolName)
+	if ok && u != nil {
+		method.ToolName = toolName
+	}
+	return method, ok
+}
+
+// GetMethodCount returns the number of methods of all upstreams
+func (f *Federation) GetMethodCount() int {
+	if len(f.registered()) == 0 {
+		return f.ServiceDiscoverer.GetMethodCount()
+	}
+	return len(f.GetMethods())
+}
+
+// InvokeMethodByTool forwards the call to the upstream serving the tool
+func (f *Federation) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	u, method, ok := f.owner(toolName)
+	if !ok || u == nil {
+		return f.ServiceDiscoverer.InvokeMethodByTool(ctx, headers, toolName, inputJSON)
+	}
+	// The upstream was removed after the lookup
+	if !u.acquire() {
+		return "", fmt.Errorf("tool %s not found: its upstream was removed", toolName)
+	}
+	defer u.calls.Done()
+	return u.discoverer.InvokeMethodByTool(ctx, headers, method.ToolName, inputJSON)
+}
+
+// CheckServiceHealth asks the upstream serving the service for its health
+func (f *Federation) CheckServiceHealth(ctx context.Context, service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+	target := f.ServiceDiscoverer
+	for _, u := range f.registered() {
+		if servesService(u.discoverer, service) {
+			target = u.discoverer
+			break
+		}
+	}
+	checker, ok := grpc.Find[grpc.ServiceHealthChecker](target)
+	if !ok {
+		return healthpb.HealthCheckResponse_UNKNOWN, fmt.Errorf("upstream does not support service health checks")
+	}
+	return checker.CheckServiceHealth(ctx, service)
+}
+
+// servesService reports whether the discoverer has methods of the service
+func servesService(sd grpc.ServiceDiscoverer, service string) bool {
+	for _, method := range sd.GetMethods() {
+		if method.ServiceName == service {
+			return true
+		}
+	}
+	return false
+}
+
+// DiscoverServices rediscovers the primary and every registered upstream;
+// failures of registered upstreams are logged and keep their previous tools
+func (f *Federation) DiscoverServices(ctx context.Context) error {
+	if err := f.ServiceDiscoverer.DiscoverServices(ctx); err != nil {
+		return err
+	}
+	for _, u := range f.registered() {
+		if err := u.discoverer.DiscoverServices(ctx); err != nil {
+			f.logger.Warn("Failed to rediscover registered upstream", zap.String("upstream", u.name), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// GetServiceStats adds the registered upstreams to the primary stats
+func (f *Federation) GetServiceStats() map[string]interface{} {
+	stats := f.ServiceDiscoverer.GetServiceStats()
+	if stats == nil {
+		stats = make(map[string]interface{})
+	}
+	if registered := f.Upstreams(); len(registered) > 0 {
+		stats["registeredUpstreams"] = registered
+	}
+	return stats
+}
+
+// Unwrap returns the primary discoverer
+func (f *Federation) Unwrap() grpc.ServiceDiscoverer {
+	return f.ServiceDiscoverer
+}
+
+// Close closes the registered upstreams and the primary
+func (f *Federation) Close() error {
+	f.mu.Lock()
+	registered := f.registered()
+	f.upstreams.Store(&[]*upstream{})
+	f.mu.Unlock()
+
+	for _, u := range registered {
+		if err := u.discoverer.Close(); err != nil {
+			f.logger.Warn("Failed to close registered upstream", zap.String("upstream", u.name), zap.Error(err))
+		}
+	}
+	return f.ServiceDiscoverer.Close()
+}
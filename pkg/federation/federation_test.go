@@ -0,0 +1,197 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type stubDiscoverer struct {
+	name   string
+	tools  []string
+	closed atomic.Bool
+
+	// calls signal started and block until release is closed when it is set
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *stubDiscoverer) Connect(ctx context.Context) error          { return nil }
+func (s *stubDiscoverer) DiscoverServices(ctx context.Context) error { return nil }
+func (s *stubDiscoverer) HealthCheck(ctx context.Context) error      { return nil }
+func (s *stubDiscoverer) GetMethodCount() int                        { return len(s.tools) }
+func (s *stubDiscoverer) Close() error                               { s.closed.Store(true); return nil }
+func (s *stubDiscoverer) GetServiceStats() map[string]interface{}    { return map[string]interface{}{} }
+
+func (s *stubDiscoverer) GetMethods() []types.MethodInfo {
+	methods := make([]types.MethodInfo, 0, len(s.tools))
+	for _, tool := range s.tools {
+		methods = append(methods, types.MethodInfo{ToolName: tool, ServiceName: s.name})
+	}
+	return methods
+}
+
+func (s *stubDiscoverer) GetMethod(toolName string) (types.MethodInfo, bool) {
+	for _, tool := range s.tools {
+		if tool == toolName {
+			return types.MethodInfo{ToolName: tool, ServiceName: s.name}, true
+		}
+	}
+	return types.MethodInfo{}, false
+}
+
+func (s *stubDiscoverer) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	if _, ok := s.GetMethod(toolName); !ok {
+		return "", errors.New("tool not found")
+	}
+	if s.release != nil {
+		s.started <- struct{}{}
+		<-s.release
+	}
+	return s.name + ":" + toolName, nil
+}
+
+// newTestFederation returns a federation over a primary serving
+// orders_get; upstreams connect to the stubs in available by host
+func newTestFederation(available map[string]*stubDiscoverer) *Federation {
+	primary := &stubDiscoverer{name: "primary", tools: []string{"orders_get"}}
+	connect := func(ctx context.Context, spec grpc.UpstreamSpec) (grpc.ServiceDiscoverer, error) {
+		stub, ok := available[spec.Host]
+		if !ok {
+			return nil, errors.New("connection refused")
+		}
+		return stub, nil
+	}
+	return New(primary, connect, 2, time.Minute, zap.NewNop())
+}
+
+func toolNames(methods []types.MethodInfo) []string {
+	names := make([]string, 0, len(methods))
+	for _, method := range methods {
+		names = append(names, method.ToolName)
+	}
+	return names
+}
+
+func TestFederation_RegisterUpstream(t *testing.T) {
+	billing := &stubDiscoverer{name: "billing", tools: []string{"invoices_get", "orders_get"}}
+	f := newTestFederation(map[string]*stubDiscoverer{"billing": billing})
+	ctx := context.Background()
+
+	registered, err := f.RegisterUpstream(ctx, grpc.UpstreamSpec{Host: "billing", Port: 50051, Prefix: "billing"})
+	require.NoError(t, err)
+	assert.Equal(t, "billing:50051", registered.Name)
+	assert.Equal(t, []string{"billing_invoices_get", "billing_orders_get"}, registered.Tools)
+
+	// The tools are served under their prefixed names and routed to the upstream
+	assert.Equal(t, []string{"orders_get", "billing_invoices_get", "billing_orders_get"}, toolNames(f.GetMethods()))
+	assert.Equal(t, 3, f.GetMethodCount())
+	method, ok := f.GetMethod("billing_orders_get")
+	require.True(t, ok)
+	assert.Equal(t, "billing_orders_get", method.ToolName)
+	result, err := f.InvokeMethodByTool(ctx, nil, "billing_orders_get", "{}")
+	require.NoError(t, err)
+	assert.Equal(t, "billing:orders_get", result)
+	result, err = f.InvokeMethodByTool(ctx, nil, "orders_get", "{}")
+	require.NoError(t, err)
+	assert.Equal(t, "primary:orders_get", result)
+	_, ok = f.GetMethod("invoices_get")
+	assert.False(t, ok)
+
+	assert.Len(t, f.Upstreams(), 1)
+	assert.Contains(t, f.GetServiceStats(), "registeredUpstreams")
+
+	require.NoError(t, f.Close())
+	assert.True(t, billing.closed.Load())
+}
+
+func TestFederation_RegisterUpstreamRejected(t *testing.T) {
+	clash := &stubDiscoverer{name: "clash", tools: []string{"orders_get", "orders_list"}}
+	empty := &stubDiscoverer{name: "empty"}
+	f := newTestFederation(map[string]*stubDiscoverer{
+		"clash": clash,
+		"empty": empty,
+		"a":     {name: "a", tools: []string{"a_get"}},
+		"b":     {name: "b", tools: []string{"b_get"}},
+		"c":     {name: "c", tools: []string{"c_get"}},
+	})
+	ctx := context.Background()
+
+	// Tool names already served are not replaced, and nothing is added
+	_, err := f.RegisterUpstream(ctx, grpc.UpstreamSpec{Host: "clash", Port: 1})
+	assert.ErrorIs(t, err, grpc.ErrUpstreamConflict)
+	assert.ErrorContains(t, err, "orders_get")
+	assert.True(t, clash.closed.Load())
+	assert.Equal(t, []string{"orders_get"}, toolNames(f.GetMethods()))
+
+	_, err = f.RegisterUpstream(ctx, grpc.UpstreamSpec{Host: "empty", Port: 1})
+	assert.ErrorContains(t, err, "serves no tools")
+	assert.True(t, empty.closed.Load())
+
+	_, err = f.RegisterUpstream(ctx, grpc.UpstreamSpec{Host: "unreachable", Port: 1})
+	assert.ErrorContains(t, err, "connection refused")
+	assert.NotErrorIs(t, err, grpc.ErrUpstreamConflict)
+
+	// Duplicates and registrations beyond the limit conflict
+	_, err = f.RegisterUpstream(ctx, grpc.UpstreamSpec{Host: "a", Port: 1})
+	require.NoError(t, err)
+	_, err = f.RegisterUpstream(ctx, grpc.UpstreamSpec{Host: "a", Port: 1})
+	assert.ErrorIs(t, err, grpc.ErrUpstreamConflict)
+	_, err = f.RegisterUpstream(ctx, grpc.UpstreamSpec{Host: "b", Port: 1})
+	require.NoError(t, err)
+	_, err = f.RegisterUpstream(ctx, grpc.UpstreamSpec{Host: "c", Port: 1})
+	assert.ErrorIs(t, err, grpc.ErrUpstreamConflict)
+	assert.Equal(t, []string{"orders_get", "a_get", "b_get"}, toolNames(f.GetMethods()))
+}
+
+func TestFederation_UnregisterUpstream(t *testing.T) {
+	billing := &stubDiscoverer{name: "billing", tools: []string{"invoices_get"}, started: make(chan struct{}, 1), release: make(chan struct{})}
+	stuck := &stubDiscoverer{name: "stuck", tools: []string{"stuck_get"}, started: make(chan struct{}, 1), release: make(chan struct{})}
+	f := newTestFederation(map[string]*stubDiscoverer{"billing": billing, "stuck": stuck})
+	ctx := context.Background()
+
+	_, err := f.RegisterUpstream(ctx, grpc.UpstreamSpec{Host: "billing", Port: 1, Prefix: "billing"})
+	require.NoError(t, err)
+	_, err = f.UnregisterUpstream("billing:2")
+	assert.ErrorIs(t, err, grpc.ErrUpstreamNotFound)
+
+	// A call in flight completes after the upstream is removed
+	result := make(chan string)
+	go func() {
+		out, _ := f.InvokeMethodByTool(ctx, nil, "billing_invoices_get", "{}")
+		result <- out
+	}()
+	<-billing.started
+
+	removed, err := f.UnregisterUpstream("billing:1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"billing_invoices_get"}, removed.Tools)
+	assert.Equal(t, []string{"orders_get"}, toolNames(f.GetMethods()))
+	assert.Empty(t, f.Upstreams())
+	_, err = f.InvokeMethodByTool(ctx, nil, "billing_invoices_get", "{}")
+	assert.ErrorContains(t, err, "not found")
+
+	assert.False(t, billing.closed.Load())
+	close(billing.release)
+	assert.Equal(t, "billing:invoices_get", <-result)
+	assert.Eventually(t, billing.closed.Load, time.Second, time.Millisecond)
+
+	// Calls that outlast the drain timeout do not keep the upstream open
+	f.drainTimeout = 10 * time.Millisecond
+	_, err = f.RegisterUpstream(ctx, grpc.UpstreamSpec{Host: "stuck", Port: 1})
+	require.NoError(t, err)
+	go func() { _, _ = f.InvokeMethodByTool(ctx, nil, "stuck_get", "{}") }()
+	<-stuck.started
+	_, err = f.UnregisterUpstream("stuck:1")
+	require.NoError(t, err)
+	assert.Eventually(t, stuck.closed.Load, time.Second, time.Millisecond)
+	close(stuck.release)
+}
@@ -0,0 +1,136 @@
+package grpc
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// methodTypes 返回解析方法消息中 Any 字段所需的类型解析器
+//
+// 通过反射得到的消息类型没有注册到全局注册表，protojson 遇到 Any
+// （例如 google.longrunning.Operation 的 response 与 metadata）时无法解析其中的类型。
+// 这里用方法输入/输出消息所在文件及其传递依赖中声明的类型构建解析器；
+// 找不到的类型只回退到随二进制内置的 Well-Known Types。
+func methodTypes(method MethodInfo) *typeResolver {
+	files := new(protoregistry.Files)
+	seen := make(map[string]bool)
+	var addFile func(fd protoreflect.FileDescriptor)
+	addFile = func(fd protoreflect.FileDescriptor) {
+		if fd == nil || fd.IsPlaceholder() || seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			addFile(imports.Get(i).FileDescriptor)
+		}
+		// 重复声明的文件忽略即可，解析时以先注册的为准
+		_ = files.RegisterFile(fd)
+	}
+	if method.InputDescriptor != nil {
+		addFile(method.InputDescriptor.ParentFile())
+	}
+	if method.OutputDescriptor != nil {
+		addFile(method.OutputDescriptor.ParentFile())
+	}
+	return &typeResolver{local: dynamicpb.NewTypes(files)}
+}
+
+// cachedTypes 是缓存的解析器及构建它时方法的输入/输出描述符
+type cachedTypes struct {
+	input, output protoreflect.MessageDescriptor
+	resolver      *typeResolver
+}
+
+// methodTypes 返回方法的类型解析器，优先使用缓存
+//
+// 重新发现服务后描述符会被替换，此时缓存失效并重新构建。
+func (r *reflectionClient) methodTypes(method MethodInfo) *typeResolver {
+	r.typesMu.RLock()
+	cached, ok := r.typesCache[method.FullName]
+	r.typesMu.RUnlock()
+	if ok && cached.input == method.InputDescriptor && cached.output == method.OutputDescriptor {
+		return cached.resolver
+	}
+
+	resolver := methodTypes(method)
+	resolver.upstream = r
+	r.typesMu.Lock()
+	if r.typesCache == nil {
+		r.typesCache = make(map[string]cachedTypes)
+	}
+	r.typesCache[method.FullName] = cachedTypes{input: method.InputDescriptor, output: method.OutputDescriptor, resolver: resolver}
+	r.typesMu.Unlock()
+	return resolver
+}
+
+// typeResolver 依次在方法相关的动态类型、所属上游的注册表和内置的
+// Well-Known Types 中查找，从不使用其他上游或网关自身链接的业务类型
+type typeResolver struct {
+	local *dynamicpb.Types
+	// upstream: 方法所属上游的反射客户端，为 nil 时跳过
+	upstream *reflectionClient
+}
+
+// wellKnown 报告类型是否为随二进制内置、所有上游共用的 google.protobuf 类型
+func wellKnown(name protoreflect.FullName) bool {
+	return strings.HasPrefix(string(name), "google.protobuf.")
+}
+
+// upstreamRegistry 返回上游最近发布的注册表，尚未发布时返回 nil
+func (r *typeResolver) upstreamRegistry() *fileRegistry {
+	if r.upstream == nil {
+		return nil
+	}
+	return r.upstream.registry.Load()
+}
+
+func (r *typeResolver) FindMessageByName(name protoreflect.FullName) (protoreflect.MessageType, error) {
+	if mt, err := r.local.FindMessageByName(name); err == nil {
+		return mt, nil
+	}
+	if registry := r.upstreamRegistry(); registry != nil {
+		if mt, err := registry.types.FindMessageByName(name); err == nil {
+			return mt, nil
+		}
+	}
+	if wellKnown(name) {
+		return protoregistry.GlobalTypes.FindMessageByName(name)
+	}
+	return nil, protoregistry.NotFound
+}
+
+func (r *typeResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	name := url
+	if i := strings.LastIndexByte(url, '/'); i >= 0 {
+		name = url[i+1:]
+	}
+	return r.FindMessageByName(protoreflect.FullName(name))
+}
+
+func (r *typeResolver) FindExtensionByName(name protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	if xt, err := r.local.FindExtensionByName(name); err == nil {
+		return xt, nil
+	}
+	if registry := r.upstreamRegistry(); registry != nil {
+		if xt, err := registry.FindExtensionByName(name); err == nil {
+			return xt, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+func (r *typeResolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	if xt, err := r.local.FindExtensionByNumber(message, field); err == nil {
+		return xt, nil
+	}
+	if registry := r.upstreamRegistry(); registry != nil {
+		if xt, err := registry.FindExtensionByNumber(message, field); err == nil {
+			return xt, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
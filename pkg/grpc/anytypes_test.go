@@ -0,0 +1,57 @@
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestMethodTypes_ResolvesAny(t *testing.T) {
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("anytypes_test.proto"),
+		Package:    proto.String("anytest"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/any.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Book"), Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("title"), JsonName: proto.String("title"), Number: proto.Int32(1), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+			}},
+			{Name: proto.String("Envelope"), Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("payload"), JsonName: proto.String("payload"), Number: proto.Int32(1), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".google.protobuf.Any")},
+			}},
+		},
+	}, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+
+	book := dynamicpb.NewMessage(fd.Messages().ByName("Book"))
+	book.Set(fd.Messages().ByName("Book").Fields().ByName("title"), protoreflect.ValueOfString("Dune"))
+	payload, err := anypb.New(book)
+	require.NoError(t, err)
+
+	envelope := dynamicpb.NewMessage(fd.Messages().ByName("Envelope"))
+	envelope.Set(fd.Messages().ByName("Envelope").Fields().ByName("payload"), protoreflect.ValueOfMessage(payload.ProtoReflect()))
+
+	// The global registry does not know types discovered through reflection
+	_, err = protojson.Marshal(envelope)
+	assert.Error(t, err)
+
+	method := MethodInfo{InputDescriptor: fd.Messages().ByName("Book"), OutputDescriptor: fd.Messages().ByName("Envelope")}
+	resolver := methodTypes(method)
+	data, err := protojson.MarshalOptions{Resolver: resolver}.Marshal(envelope)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"payload":{"@type":"type.googleapis.com/anytest.Book","title":"Dune"}}`, string(data))
+
+	parsed := dynamicpb.NewMessage(fd.Messages().ByName("Envelope"))
+	require.NoError(t, protojson.UnmarshalOptions{Resolver: resolver}.Unmarshal(data, parsed))
+	assert.True(t, proto.Equal(envelope, parsed))
+}
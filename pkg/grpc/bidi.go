@@ -0,0 +1,268 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"go.uber.org/zap"
+	grpcLib "google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ErrStreamClosed 表示流已结束或客户端已关闭发送方向，不能再发送消息
+var ErrStreamClosed = errors.New("stream is closed")
+
+// defaultStreamBuffer 未配置 buffer 时每个方向缓冲的消息数
+const defaultStreamBuffer = 16
+
+// StreamOpener 由支持客户端流和双向流方法的发现器实现
+type StreamOpener interface {
+	// OpenStream 为工具对应的客户端流或双向流方法打开一个流
+	//
+	// 流的生命周期由 ctx 决定，不受打开它的请求限制；调用方负责 Close。
+	OpenStream(ctx context.Context, headers map[string]string, toolName string) (*StreamSession, error)
+}
+
+// BidiStreamingReflectionClient 由支持客户端流和双向流调用的反射客户端实现
+type BidiStreamingReflectionClient interface {
+	// OpenStream 打开方法的流，每个方向最多缓冲 buffer 条消息
+	OpenStream(ctx context.Context, headers map[string]string, method MethodInfo, buffer int) (*StreamSession, error)
+}
+
+// WithBidiStreaming 启用客户端流和双向流方法的流式桥接
+func WithBidiStreaming(cfg config.BidiStreamingConfig) DiscovererOption {
+	return func(c *ConnectionManagerConfig) {
+		c.BidiStreaming = cfg
+	}
+}
+
+// StreamSession 是一个打开的客户端流或双向流调用
+//
+// 发送和接收各由一个 goroutine 完成，与调用方之间通过有界通道传递消息：
+// 调用方不读取收到的消息时接收 goroutine 停止从上游读取，gRPC 的流量控制随之
+// 让上游放慢发送；上游不接收时发送通道写满，Send 阻塞直到 ctx 结束。
+type StreamSession struct {
+	method   MethodInfo
+	resolver *typeResolver
+	stream   grpcLib.ClientStream
+	cancel   context.CancelFunc
+
+	outgoing chan protoreflect.ProtoMessage
+	messages chan json.RawMessage
+	done     chan struct{}
+
+	closeSendOnce sync.Once
+	sendClosed    chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// Method 返回流对应的方法
+func (s *StreamSession) Method() MethodInfo {
+	return s.method
+}
+
+// Send 把一条 JSON 消息发送到流中
+//
+// JSON 无法解析时立即返回错误；发送缓冲区已满时阻塞，直到消息进入缓冲区、
+// ctx 结束或流结束。
+func (s *StreamSession) Send(ctx context.Context, inputJSON string) error {
+	msg := dynamicpb.NewMessage(s.method.InputDescriptor)
+	if inputJSON != "" && inputJSON != "{}" {
+		if err := (protojson.UnmarshalOptions{Resolver: s.resolver}).Unmarshal([]byte(inputJSON), msg); err != nil {
+			return fmt.Errorf("failed to parse input JSON: %w", err)
+		}
+	}
+	select {
+	case <-s.sendClosed:
+		return ErrStreamClosed
+	case <-s.done:
+		return ErrStreamClosed
+	default:
+	}
+	select {
+	case s.outgoing <- msg:
+		return nil
+	case <-s.sendClosed:
+		return ErrStreamClosed
+	case <-s.done:
+		return ErrStreamClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CloseSend 关闭发送方向：已缓冲的消息发送完后通知上游客户端不再发送消息
+func (s *StreamSession) CloseSend() {
+	s.closeSendOnce.Do(func() {
+		close(s.sendClosed)
+	})
+}
+
+// Messages 返回收到的消息，流结束后通道关闭，随后 Err 返回结束原因
+func (s *StreamSession) Messages() <-chan json.RawMessage {
+	return s.messages
+}
+
+// Done 在流结束时关闭
+func (s *StreamSession) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err 返回流失败的原因；流正常结束或仍在进行时返回 nil
+func (s *StreamSession) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close 取消流，未发送和未读取的消息被丢弃
+func (s *StreamSession) Close() {
+	s.cancel()
+}
+
+// fail 记录流失败的原因（只保留第一个）并取消流
+func (s *StreamSession) fail(err error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mu.Unlock()
+	s.cancel()
+}
+
+// sendLoop 按顺序把缓冲的消息发送到上游，关闭发送方向后调用 CloseSend
+func (s *StreamSession) sendLoop() {
+	for {
+		var msg protoreflect.ProtoMessage
+		select {
+		case <-s.done:
+			return
+		case msg = <-s.outgoing:
+		case <-s.sendClosed:
+			// 先发送关闭前已进入缓冲区的消息
+			select {
+			case msg = <-s.outgoing:
+			default:
+				if err := s.stream.CloseSend(); err != nil {
+					s.fail(fmt.Errorf("gRPC call failed: %w", err))
+				}
+				return
+			}
+		}
+		if err := s.stream.SendMsg(msg); err != nil {
+			// io.EOF 表示上游已结束流，真正的状态由接收方向返回
+			if !errors.Is(err, io.EOF) {
+				s.fail(fmt.Errorf("gRPC call failed: %w", err))
+			}
+			return
+		}
+	}
+}
+
+// recvLoop 把上游发来的消息转换为 JSON 放入缓冲区，流结束时关闭 messages 和 done
+func (s *StreamSession) recvLoop(ctx context.Context) {
+	defer close(s.done)
+	defer close(s.messages)
+	defer s.cancel()
+	for {
+		outputMsg := dynamicpb.NewMessage(s.method.OutputDescriptor)
+		err := s.stream.RecvMsg(outputMsg)
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			s.fail(fmt.Errorf("gRPC call failed: %w", err))
+			return
+		}
+		outputJSON, err := (protojson.MarshalOptions{Resolver: s.resolver}).Marshal(outputMsg)
+		if err != nil {
+			s.fail(fmt.Errorf("failed to marshal output to JSON: %w", err))
+			return
+		}
+		select {
+		case s.messages <- outputJSON:
+		case <-ctx.Done():
+			s.fail(ctx.Err())
+			return
+		}
+	}
+}
+
+// OpenStream 为工具对应的客户端流或双向流方法打开一个流
+func (d *serviceDiscoverer) OpenStream(ctx context.Context, headers map[string]string, toolName string) (*StreamSession, error) {
+	d.upstreamMu.RLock()
+	cfg := d.connConfig.BidiStreaming
+	d.upstreamMu.RUnlock()
+	if !cfg.Enabled {
+		return nil, fmt.Errorf("streaming methods are not supported")
+	}
+
+	method, exists := d.getMethodByTool(toolName)
+	if !exists {
+		return nil, fmt.Errorf("tool %s not found", toolName)
+	}
+	if !method.IsClientStreaming {
+		return nil, fmt.Errorf("tool %s is not a client-streaming or bidirectional method", toolName)
+	}
+
+	_, reflectionClient := d.upstream()
+	if reflectionClient == nil {
+		return nil, fmt.Errorf("not connected to gRPC server")
+	}
+	streamer, ok := reflectionClient.(BidiStreamingReflectionClient)
+	if !ok {
+		return nil, fmt.Errorf("streaming methods are not supported")
+	}
+
+	d.logger.Debug("Opening stream",
+		zap.String("toolName", toolName),
+		zap.String("service", method.FullName),
+		zap.Int("headerCount", len(headers)))
+
+	ctx = withCallOptions(ctx, d.toolCallOptions(toolName)...)
+	return streamer.OpenStream(ctx, headers, method, cfg.Buffer)
+}
+
+// OpenStream 打开客户端流或双向流方法的流，并启动发送和接收 goroutine
+func (r *reflectionClient) OpenStream(ctx context.Context, headers map[string]string, method MethodInfo, buffer int) (*StreamSession, error) {
+	for key, value := range headers {
+		ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+	}
+	if buffer <= 0 {
+		buffer = defaultStreamBuffer
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	grpcMethodName := fmt.Sprintf("/%s/%s", method.FullName[:strings.LastIndex(method.FullName, ".")], method.Name)
+	desc := &grpcLib.StreamDesc{ClientStreams: true, ServerStreams: method.IsServerStreaming}
+	stream, err := r.conn.NewStream(streamCtx, desc, grpcMethodName, callOptionsFromContext(ctx)...)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("gRPC call failed: %w", err)
+	}
+
+	s := &StreamSession{
+		method:     method,
+		resolver:   r.methodTypes(method),
+		stream:     stream,
+		cancel:     cancel,
+		outgoing:   make(chan protoreflect.ProtoMessage, buffer),
+		messages:   make(chan json.RawMessage, buffer),
+		done:       make(chan struct{}),
+		sendClosed: make(chan struct{}),
+	}
+	go s.sendLoop()
+	go s.recvLoop(streamCtx)
+	return s, nil
+}
@@ -0,0 +1,198 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	grpcLib "google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// startEchoUpstream serves streamtest.Echo/Chat, a bidirectional method
+// answering every message with "echo: <message>", and streamtest.Echo/Count,
+// a client-streaming method returning the number of messages it received
+func startEchoUpstream(t *testing.T) int {
+	t.Helper()
+
+	registerTestFilesMu.Lock()
+	if _, err := protoregistry.GlobalFiles.FindDescriptorByName("streamtest.Echo"); err != nil {
+		fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+			Name:       proto.String("test/echotest.proto"),
+			Package:    proto.String("streamtest"),
+			Dependency: []string{"google/protobuf/wrappers.proto"},
+			Syntax:     proto.String("proto3"),
+			Service: []*descriptorpb.ServiceDescriptorProto{{
+				Name: proto.String("Echo"),
+				Method: []*descriptorpb.MethodDescriptorProto{{
+					Name:            proto.String("Chat"),
+					InputType:       proto.String(".google.protobuf.StringValue"),
+					OutputType:      proto.String(".google.protobuf.StringValue"),
+					ClientStreaming: proto.Bool(true),
+					ServerStreaming: proto.Bool(true),
+				}, {
+					Name:            proto.String("Count"),
+					InputType:       proto.String(".google.protobuf.StringValue"),
+					OutputType:      proto.String(".google.protobuf.StringValue"),
+					ClientStreaming: proto.Bool(true),
+				}},
+			}},
+		}, protoregistry.GlobalFiles)
+		require.NoError(t, err)
+		require.NoError(t, protoregistry.GlobalFiles.RegisterFile(fd))
+	}
+	registerTestFilesMu.Unlock()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpcLib.NewServer()
+	srv.RegisterService(&grpcLib.ServiceDesc{
+		ServiceName: "streamtest.Echo",
+		HandlerType: (*interface{})(nil),
+		Streams: []grpcLib.StreamDesc{{
+			StreamName:    "Chat",
+			ClientStreams: true,
+			ServerStreams: true,
+			Handler: func(_ interface{}, stream grpcLib.ServerStream) error {
+				for {
+					in := &wrapperspb.StringValue{}
+					if err := stream.RecvMsg(in); errors.Is(err, io.EOF) {
+						return nil
+					} else if err != nil {
+						return err
+					}
+					if err := stream.SendMsg(wrapperspb.String("echo: " + in.GetValue())); err != nil {
+						return err
+					}
+				}
+			},
+		}, {
+			StreamName:    "Count",
+			ClientStreams: true,
+			Handler: func(_ interface{}, stream grpcLib.ServerStream) error {
+				n := 0
+				for {
+					if err := stream.RecvMsg(&wrapperspb.StringValue{}); errors.Is(err, io.EOF) {
+						return stream.SendMsg(wrapperspb.String(strconv.Itoa(n)))
+					} else if err != nil {
+						return err
+					}
+					n++
+				}
+			},
+		}},
+	}, struct{}{})
+	reflection.Register(srv)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	return lis.Addr().(*net.TCPAddr).Port
+}
+
+func TestOpenStream(t *testing.T) {
+	port := startEchoUpstream(t)
+
+	newDiscoverer := func(opts ...DiscovererOption) ServiceDiscoverer {
+		sd, err := NewServiceDiscoverer("127.0.0.1", port, zap.NewNop(), config.DescriptorSetConfig{}, opts...)
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		require.NoError(t, sd.Connect(ctx))
+		require.NoError(t, sd.DiscoverServices(ctx))
+		t.Cleanup(func() { _ = sd.Close() })
+		return sd
+	}
+	receive := func(t *testing.T, s *StreamSession) []string {
+		var messages []string
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case message, ok := <-s.Messages():
+				if !ok {
+					return messages
+				}
+				var value string
+				require.NoError(t, json.Unmarshal(message, &value))
+				messages = append(messages, value)
+			case <-timeout:
+				t.Fatal("stream did not end")
+			}
+		}
+	}
+
+	// Disabled by default
+	opener, ok := Find[StreamOpener](newDiscoverer())
+	require.True(t, ok)
+	_, err := opener.OpenStream(context.Background(), nil, "streamtest_echo_chat")
+	assert.ErrorContains(t, err, "streaming methods are not supported")
+
+	opener, _ = Find[StreamOpener](newDiscoverer(WithBidiStreaming(config.BidiStreamingConfig{Enabled: true, Buffer: 2})))
+
+	t.Run("bidirectional", func(t *testing.T) {
+		s, err := opener.OpenStream(context.Background(), nil, "streamtest_echo_chat")
+		require.NoError(t, err)
+		defer s.Close()
+
+		ctx := context.Background()
+		require.NoError(t, s.Send(ctx, `"a"`))
+		select {
+		case message := <-s.Messages():
+			assert.JSONEq(t, `"echo: a"`, string(message))
+		case <-time.After(5 * time.Second):
+			t.Fatal("no reply")
+		}
+		require.NoError(t, s.Send(ctx, `"b"`))
+		s.CloseSend()
+		assert.ErrorIs(t, s.Send(ctx, `"c"`), ErrStreamClosed)
+
+		assert.Equal(t, []string{"echo: b"}, receive(t, s))
+		assert.NoError(t, s.Err())
+	})
+
+	t.Run("client streaming", func(t *testing.T) {
+		s, err := opener.OpenStream(context.Background(), nil, "streamtest_echo_count")
+		require.NoError(t, err)
+		defer s.Close()
+
+		for i := 0; i < 5; i++ {
+			require.NoError(t, s.Send(context.Background(), `"x"`))
+		}
+		s.CloseSend()
+		assert.Equal(t, []string{"5"}, receive(t, s))
+	})
+
+	t.Run("invalid message", func(t *testing.T) {
+		s, err := opener.OpenStream(context.Background(), nil, "streamtest_echo_chat")
+		require.NoError(t, err)
+		defer s.Close()
+		assert.ErrorContains(t, s.Send(context.Background(), `{"bad":`), "failed to parse input JSON")
+	})
+
+	t.Run("close cancels the stream", func(t *testing.T) {
+		s, err := opener.OpenStream(context.Background(), nil, "streamtest_echo_chat")
+		require.NoError(t, err)
+		s.Close()
+		receive(t, s)
+		assert.Error(t, s.Err())
+		assert.ErrorIs(t, s.Send(context.Background(), `"a"`), ErrStreamClosed)
+	})
+
+	t.Run("unknown tool", func(t *testing.T) {
+		_, err := opener.OpenStream(context.Background(), nil, "missing_tool")
+		assert.ErrorContains(t, err, "not found")
+	})
+}
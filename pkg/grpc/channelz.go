@@ -0,0 +1,224 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	grpcLib "google.golang.org/grpc"
+	channelzpb "google.golang.org/grpc/channelz/grpc_channelz_v1"
+	channelzservice "google.golang.org/grpc/channelz/service"
+)
+
+// ChannelzSummary 汇总网关进程内所有上游客户端连接的 channelz 数据
+type ChannelzSummary struct {
+	CollectedAt time.Time        `json:"collectedAt"`
+	Channels    []ChannelSummary `json:"channels"`
+}
+
+// CallStats 调用计数
+type CallStats struct {
+	Started           int64      `json:"started"`
+	Succeeded         int64      `json:"succeeded"`
+	Failed            int64      `json:"failed"`
+	LastCallStartedAt *time.Time `json:"lastCallStartedAt,omitempty"`
+}
+
+// ChannelSummary 顶层 channel（即一个 grpc.ClientConn）的摘要
+type ChannelSummary struct {
+	ID          int64               `json:"id"`
+	Target      string              `json:"target"`
+	State       string              `json:"state"`
+	Calls       CallStats           `json:"calls"`
+	LastEvent   string              `json:"lastEvent,omitempty"`
+	LastError   string              `json:"lastError,omitempty"`
+	Subchannels []SubchannelSummary `json:"subchannels,omitempty"`
+}
+
+// SubchannelSummary 子连接摘要，包含其底层 socket
+type SubchannelSummary struct {
+	ID        int64           `json:"id"`
+	Target    string          `json:"target"`
+	State     string          `json:"state"`
+	Calls     CallStats       `json:"calls"`
+	LastEvent string          `json:"lastEvent,omitempty"`
+	LastError string          `json:"lastError,omitempty"`
+	Sockets   []SocketSummary `json:"sockets,omitempty"`
+}
+
+// SocketSummary 单个传输层 socket 的摘要
+type SocketSummary struct {
+	ID               int64  `json:"id"`
+	Local            string `json:"local,omitempty"`
+	Remote           string `json:"remote,omitempty"`
+	StreamsStarted   int64  `json:"streamsStarted"`
+	StreamsSucceeded int64  `json:"streamsSucceeded"`
+	StreamsFailed    int64  `json:"streamsFailed"`
+	MessagesSent     int64  `json:"messagesSent"`
+	MessagesReceived int64  `json:"messagesReceived"`
+	KeepAlivesSent   int64  `json:"keepAlivesSent"`
+}
+
+var (
+	channelzOnce   sync.Once
+	channelzServer channelzpb.ChannelzServer
+)
+
+// channelzCapture 是一个只用于捕获 channelz 服务实现的 ServiceRegistrar
+// 这样无需启动额外的 gRPC 服务器即可在进程内查询 channelz
+type channelzCapture struct {
+	impl any
+}
+
+func (c *channelzCapture) RegisterService(_ *grpcLib.ServiceDesc, impl any) {
+	c.impl = impl
+}
+
+func getChannelzServer() channelzpb.ChannelzServer {
+	channelzOnce.Do(func() {
+		capture := &channelzCapture{}
+		channelzservice.RegisterChannelzServiceToServer(capture)
+		if impl, ok := capture.impl.(channelzpb.ChannelzServer); ok {
+			channelzServer = impl
+		}
+	})
+	return channelzServer
+}
+
+// CollectChannelz 收集当前进程中所有客户端 channel、子 channel 及 socket 的摘要
+func CollectChannelz(ctx context.Context) (*ChannelzSummary, error) {
+	cz := getChannelzServer()
+	if cz == nil {
+		return nil, fmt.Errorf("channelz service is not available")
+	}
+
+	summary := &ChannelzSummary{
+		CollectedAt: time.Now().UTC(),
+		Channels:    []ChannelSummary{},
+	}
+
+	var startID int64
+	for {
+		resp, err := cz.GetTopChannels(ctx, &channelzpb.GetTopChannelsRequest{StartChannelId: startID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get top channels: %w", err)
+		}
+
+		for _, ch := range resp.GetChannel() {
+			channelSummary := summarizeChannel(ch)
+			for _, ref := range ch.GetSubchannelRef() {
+				subResp, err := cz.GetSubchannel(ctx, &channelzpb.GetSubchannelRequest{SubchannelId: ref.GetSubchannelId()})
+				if err != nil {
+					// 子 channel 可能在查询期间已被关闭
+					continue
+				}
+				channelSummary.Subchannels = append(channelSummary.Subchannels, summarizeSubchannel(ctx, cz, subResp.GetSubchannel()))
+			}
+			summary.Channels = append(summary.Channels, channelSummary)
+			startID = ch.GetRef().GetChannelId() + 1
+		}
+
+		if resp.GetEnd() || len(resp.GetChannel()) == 0 {
+			break
+		}
+	}
+
+	return summary, nil
+}
+
+func summarizeChannel(ch *channelzpb.Channel) ChannelSummary {
+	data := ch.GetData()
+	lastEvent, lastError := summarizeTrace(data.GetTrace())
+	return ChannelSummary{
+		ID:        ch.GetRef().GetChannelId(),
+		Target:    data.GetTarget(),
+		State:     data.GetState().GetState().String(),
+		Calls:     summarizeCalls(data),
+		LastEvent: lastEvent,
+		LastError: lastError,
+	}
+}
+
+func summarizeSubchannel(ctx context.Context, cz channelzpb.ChannelzServer, sc *channelzpb.Subchannel) SubchannelSummary {
+	data := sc.GetData()
+	lastEvent, lastError := summarizeTrace(data.GetTrace())
+	summary := SubchannelSummary{
+		ID:        sc.GetRef().GetSubchannelId(),
+		Target:    data.GetTarget(),
+		State:     data.GetState().GetState().String(),
+		Calls:     summarizeCalls(data),
+		LastEvent: lastEvent,
+		LastError: lastError,
+	}
+
+	for _, ref := range sc.GetSocketRef() {
+		resp, err := cz.GetSocket(ctx, &channelzpb.GetSocketRequest{SocketId: ref.GetSocketId()})
+		if err != nil {
+			continue
+		}
+		summary.Sockets = append(summary.Sockets, summarizeSocket(resp.GetSocket()))
+	}
+
+	return summary
+}
+
+func summarizeCalls(data *channelzpb.ChannelData) CallStats {
+	stats := CallStats{
+		Started:   data.GetCallsStarted(),
+		Succeeded: data.GetCallsSucceeded(),
+		Failed:    data.GetCallsFailed(),
+	}
+	if ts := data.GetLastCallStartedTimestamp(); ts != nil && ts.GetSeconds() > 0 {
+		t := ts.AsTime()
+		stats.LastCallStartedAt = &t
+	}
+	return stats
+}
+
+// summarizeTrace 返回最近一条 trace 事件以及最近一条错误/警告事件的描述
+func summarizeTrace(trace *channelzpb.ChannelTrace) (lastEvent, lastError string) {
+	events := trace.GetEvents()
+	if len(events) == 0 {
+		return "", ""
+	}
+	lastEvent = events[len(events)-1].GetDescription()
+	for i := len(events) - 1; i >= 0; i-- {
+		switch events[i].GetSeverity() {
+		case channelzpb.ChannelTraceEvent_CT_ERROR, channelzpb.ChannelTraceEvent_CT_WARNING:
+			return lastEvent, events[i].GetDescription()
+		}
+	}
+	return lastEvent, ""
+}
+
+func summarizeSocket(s *channelzpb.Socket) SocketSummary {
+	data := s.GetData()
+	return SocketSummary{
+		ID:               s.GetRef().GetSocketId(),
+		Local:            formatChannelzAddress(s.GetLocal()),
+		Remote:           formatChannelzAddress(s.GetRemote()),
+		StreamsStarted:   data.GetStreamsStarted(),
+		StreamsSucceeded: data.GetStreamsSucceeded(),
+		StreamsFailed:    data.GetStreamsFailed(),
+		MessagesSent:     data.GetMessagesSent(),
+		MessagesReceived: data.GetMessagesReceived(),
+		KeepAlivesSent:   data.GetKeepAlivesSent(),
+	}
+}
+
+func formatChannelzAddress(addr *channelzpb.Address) string {
+	switch {
+	case addr == nil:
+		return ""
+	case addr.GetTcpipAddress() != nil:
+		tcp := addr.GetTcpipAddress()
+		return net.JoinHostPort(net.IP(tcp.GetIpAddress()).String(), fmt.Sprintf("%d", tcp.GetPort()))
+	case addr.GetUdsAddress() != nil:
+		return "unix:" + addr.GetUdsAddress().GetFilename()
+	case addr.GetOtherAddress() != nil:
+		return addr.GetOtherAddress().GetName()
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	grpcLib "google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestCollectChannelz_UpstreamConnection(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := grpcLib.NewServer()
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	port := lis.Addr().(*net.TCPAddr).Port
+	cm := NewConnectionManager(ConnectionManagerConfig{
+		Host:           "127.0.0.1",
+		Port:           port,
+		ConnectTimeout: 5 * time.Second,
+		KeepAlive: KeepAliveConfig{
+			Time:    30 * time.Second,
+			Timeout: 5 * time.Second,
+		},
+		MaxMessageSize: 4 * 1024 * 1024,
+	}, zap.NewNop())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, cm.Connect(ctx))
+	defer func() { _ = cm.Close() }()
+
+	// 调用一个不存在的方法，产生一次失败的调用
+	err = cm.GetConnection().Invoke(ctx, "/unknown.Service/Missing", &emptypb.Empty{}, &emptypb.Empty{})
+	require.Error(t, err)
+
+	summary, err := CollectChannelz(ctx)
+	require.NoError(t, err)
+
+	var found *ChannelSummary
+	for i := range summary.Channels {
+		if strings.HasSuffix(summary.Channels[i].Target, lis.Addr().String()) {
+			found = &summary.Channels[i]
+		}
+	}
+	require.NotNil(t, found, "expected a channel for the upstream target")
+
+	assert.Equal(t, "READY", found.State)
+	assert.GreaterOrEqual(t, found.Calls.Started, int64(1))
+	assert.GreaterOrEqual(t, found.Calls.Failed, int64(1))
+	require.NotEmpty(t, found.Subchannels)
+
+	var remotes []string
+	for _, sub := range found.Subchannels {
+		for _, sock := range sub.Sockets {
+			remotes = append(remotes, sock.Remote)
+		}
+	}
+	assert.Contains(t, remotes, lis.Addr().String())
+}
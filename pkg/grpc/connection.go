@@ -6,10 +6,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aalobaidi/ggRMCP/pkg/config"
 	"go.uber.org/zap"
 	grpcLib "google.golang.org/grpc"
 	"google.golang.org/grpc/connectivity"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/keepalive"
 )
 
@@ -55,7 +55,7 @@ func NewConnectionManager(config ConnectionManagerConfig, logger *zap.Logger) Co
 // 2. 关闭任何已存在的连接，避免连接泄漏
 // 3. 构建连接目标地址 (host:port)
 // 4. 配置连接参数：
-//   - 使用不安全的凭证（insecure）用于开发/测试环境
+//   - 使用配置的传输凭证（TLS，未配置时为明文）
 //   - 配置心跳（keepalive）参数保持连接活跃
 //   - 设置最大消息大小限制
 //
@@ -72,24 +72,55 @@ func (cm *connectionManager) Connect(ctx context.Context) error {
 		_ = cm.conn.Close()
 	}
 
-	target := fmt.Sprintf("%s:%d", cm.config.Host, cm.config.Port)
-	cm.logger.Info("Connecting to gRPC server", zap.String("target", target))
+	target := cm.config.target()
+	cm.logger.Info("Connecting to gRPC server", zap.String("target", target), zap.Bool("tls", cm.config.TLS.Enabled))
+
+	// 传输凭证：未配置 TLS 时使用明文（用于开发/测试环境，生产环境应使用 TLS）
+	creds, err := cm.config.transportCredentials()
+	if err != nil {
+		return err
+	}
 
 	// 配置 gRPC 连接选项
 	opts := []grpcLib.DialOption{
-		// 使用不安全的传输凭证（用于开发/测试环境，生产环境应使用 TLS）
-		grpcLib.WithTransportCredentials(insecure.NewCredentials()),
+		grpcLib.WithTransportCredentials(creds),
 		// 配置心跳参数以保持连接活跃，检测连接异常
 		grpcLib.WithKeepaliveParams(keepalive.ClientParameters{
 			Time:                cm.config.KeepAlive.Time,                // 心跳检测间隔
 			Timeout:             cm.config.KeepAlive.Timeout,             // 心跳响应超时
 			PermitWithoutStream: cm.config.KeepAlive.PermitWithoutStream, // 无流时是否发送心跳
 		}),
-		// 配置默认调用选项，限制消息大小防止内存溢出
-		grpcLib.WithDefaultCallOptions(
-			grpcLib.MaxCallRecvMsgSize(cm.config.MaxMessageSize),
-			grpcLib.MaxCallSendMsgSize(cm.config.MaxMessageSize),
-		),
+		// 配置默认调用选项，分别限制收发消息大小防止内存溢出
+		grpcLib.WithDefaultCallOptions(cm.config.callOptions()...),
+	}
+
+	// 配置了解析器时通过自定义 scheme 拨号，由解析器提供（并持续更新）地址列表
+	dialTarget := target
+	if cm.config.Resolver != nil {
+		opts = append(opts,
+			grpcLib.WithResolvers(&resolverBuilder{
+				resolver: cm.config.Resolver,
+				target:   Target{Host: cm.config.Host, Port: cm.config.Port},
+				interval: cm.config.ResolveInterval,
+				logger:   cm.logger,
+			}),
+			grpcLib.WithDefaultServiceConfig(roundRobinServiceConfig),
+		)
+		dialTarget = resolverScheme + ":///" + target
+	}
+
+	// 配置 authority 覆盖、代理或自定义拨号函数（服务网格、堡垒机等场景）
+	if cm.config.Authority != "" {
+		opts = append(opts, grpcLib.WithAuthority(cm.config.Authority))
+	}
+	dialer, err := cm.config.dialer()
+	if err != nil {
+		return err
+	}
+	if dialer != nil {
+		opts = append(opts, grpcLib.WithContextDialer(dialer))
+	} else if cm.config.Proxy == config.ProxyNone {
+		opts = append(opts, grpcLib.WithNoProxy())
 	}
 
 	// 创建带超时的连接上下文
@@ -97,7 +128,7 @@ func (cm *connectionManager) Connect(ctx context.Context) error {
 	defer cancel()
 
 	// 执行实际的 gRPC 连接操作
-	conn, err := grpcLib.DialContext(connectCtx, target, opts...)
+	conn, err := grpcLib.DialContext(connectCtx, dialTarget, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to connect to gRPC server: %w", err)
 	}
@@ -214,17 +245,22 @@ func (cm *connectionManager) healthCheckLocked(ctx context.Context) error {
 		return fmt.Errorf("connection is in unhealthy state: %v", state)
 	}
 
-	// 如果连接不是就绪状态，则等待状态变化
+	// 如果连接不是就绪状态，则等待状态变化（经过解析器时会先经历 Idle → Connecting）
 	if state != connectivity.Ready {
 		healthCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 		defer cancel()
 
-		if !cm.conn.WaitForStateChange(healthCtx, state) {
-			return fmt.Errorf("connection state did not change within timeout")
-		}
-
-		if cm.conn.GetState() != connectivity.Ready {
-			return fmt.Errorf("connection failed to become ready")
+		for state != connectivity.Ready {
+			if state == connectivity.Idle {
+				cm.conn.Connect()
+			}
+			if !cm.conn.WaitForStateChange(healthCtx, state) {
+				return fmt.Errorf("connection state did not change within timeout")
+			}
+			state = cm.conn.GetState()
+			if state == connectivity.TransientFailure || state == connectivity.Shutdown {
+				return fmt.Errorf("connection failed to become ready")
+			}
 		}
 	}
 
@@ -252,6 +288,9 @@ func (cm *connectionManager) Close() error {
 		// 关闭连接
 		err := cm.conn.Close()
 		cm.conn = nil
+		if cm.config.CertificateMonitor != nil {
+			cm.config.CertificateMonitor.forget(cm.config.target())
+		}
 		if err != nil {
 			cm.logger.Error("Failed to close gRPC connection", zap.Error(err))
 			return err
@@ -261,3 +300,34 @@ func (cm *connectionManager) Close() error {
 
 	return nil
 }
+
+// callOptions 返回连接的默认调用选项：收发消息大小上限，以及配置的请求压缩方式和 ready 模式
+func (c ConnectionManagerConfig) callOptions() []grpcLib.CallOption {
+	opts := []grpcLib.CallOption{
+		grpcLib.MaxCallRecvMsgSize(c.recvMessageSize()),
+		grpcLib.MaxCallSendMsgSize(c.sendMessageSize()),
+	}
+	if c.Compressor != "" {
+		opts = append(opts, grpcLib.UseCompressor(c.Compressor))
+	}
+	if c.WaitForReady {
+		opts = append(opts, grpcLib.WaitForReady(true))
+	}
+	return opts
+}
+
+// recvMessageSize 返回接收消息大小上限，未单独配置时使用 MaxMessageSize
+func (c ConnectionManagerConfig) recvMessageSize() int {
+	if c.MaxRecvMessageSize > 0 {
+		return c.MaxRecvMessageSize
+	}
+	return c.MaxMessageSize
+}
+
+// sendMessageSize 返回发送消息大小上限，未单独配置时使用 MaxMessageSize
+func (c ConnectionManagerConfig) sendMessageSize() int {
+	if c.MaxSendMessageSize > 0 {
+		return c.MaxSendMessageSize
+	}
+	return c.MaxMessageSize
+}
@@ -0,0 +1,75 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWithConnection_MessageSizeLimits(t *testing.T) {
+	upstream := startTestUpstream(t, "conntest.v1.EchoService")
+	large := `"` + strings.Repeat("x", 4096) + `"`
+
+	connect := func(conn config.ConnectionConfig) ServiceDiscoverer {
+		sd, err := NewServiceDiscoverer("127.0.0.1", upstream.addr.Port, zap.NewNop(), config.DescriptorSetConfig{}, WithConnection(conn))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = sd.Close() })
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		require.NoError(t, sd.Connect(ctx))
+		require.NoError(t, sd.DiscoverServices(ctx))
+		return sd
+	}
+	ctx := context.Background()
+
+	// Responses larger than the receive limit are rejected
+	small := connect(config.ConnectionConfig{MaxRecvMessageSize: 1024})
+	_, err := small.InvokeMethodByTool(ctx, nil, "conntest_v1_echoservice_echo", large)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// The send limit is independent and stops the request before it is sent
+	require.Equal(t, 1, upstream.callCount())
+	sendLimited := connect(config.ConnectionConfig{MaxSendMessageSize: 1024})
+	_, err = sendLimited.InvokeMethodByTool(ctx, nil, "conntest_v1_echoservice_echo", large)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, 1, upstream.callCount())
+
+	out, err := connect(config.ConnectionConfig{MaxRecvMessageSize: 8192}).InvokeMethodByTool(ctx, nil, "conntest_v1_echoservice_echo", large)
+	require.NoError(t, err)
+	assert.Equal(t, large, out)
+}
+
+func TestWithConnection_GzipCompression(t *testing.T) {
+	upstream := startTestUpstream(t, "conntest.v1.GzipService")
+	large := `"` + strings.Repeat("x", 4096) + `"`
+
+	connect := func(conn config.ConnectionConfig) ServiceDiscoverer {
+		sd, err := NewServiceDiscoverer("127.0.0.1", upstream.addr.Port, zap.NewNop(), config.DescriptorSetConfig{}, WithConnection(conn))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = sd.Close() })
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		require.NoError(t, sd.Connect(ctx))
+		require.NoError(t, sd.DiscoverServices(ctx))
+		return sd
+	}
+	ctx := context.Background()
+
+	// The send limit applies to the payload on the wire, so a highly
+	// compressible request only fits when it is gzipped
+	_, err := connect(config.ConnectionConfig{MaxSendMessageSize: 1024}).InvokeMethodByTool(ctx, nil, "conntest_v1_gzipservice_echo", large)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	gzipped := connect(config.ConnectionConfig{MaxSendMessageSize: 1024, Compression: config.CompressionGzip})
+	out, err := gzipped.InvokeMethodByTool(ctx, nil, "conntest_v1_gzipservice_echo", large)
+	require.NoError(t, err)
+	assert.Equal(t, large, out)
+}
@@ -0,0 +1,126 @@
+package grpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"golang.org/x/net/proxy"
+)
+
+// Dialer 建立到上游地址（host:port）的原始连接，例如经由堡垒机或网格 sidecar
+type Dialer func(ctx context.Context, addr string) (net.Conn, error)
+
+// WithDialer 使用自定义拨号函数连接上游，优先于配置的代理
+func WithDialer(d Dialer) DiscovererOption {
+	return func(c *ConnectionManagerConfig) {
+		c.Dialer = d
+	}
+}
+
+// dialer 返回连接上游使用的拨号函数，nil 表示使用 gRPC 默认拨号
+//
+// 自定义拨号函数优先；否则按 Proxy 配置经 HTTP CONNECT 或 SOCKS5 代理拨号。
+// Proxy 为空时 gRPC 默认会读取 HTTPS_PROXY 环境变量，为 "none" 时直连。
+func (c ConnectionManagerConfig) dialer() (Dialer, error) {
+	if c.Dialer != nil {
+		return c.Dialer, nil
+	}
+	if c.Proxy == "" || c.Proxy == config.ProxyNone {
+		return nil, nil
+	}
+
+	proxyURL, err := url.Parse(c.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch proxyURL.Scheme {
+	case "http":
+		return httpConnectDialer(proxyURL), nil
+	case "socks5", "socks5h":
+		d, err := proxy.FromURL(proxyURL, proxy.Direct)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SOCKS5 proxy: %w", err)
+		}
+		contextDialer, ok := d.(proxy.ContextDialer)
+		if !ok {
+			return nil, fmt.Errorf("SOCKS5 dialer does not support contexts")
+		}
+		return func(ctx context.Context, addr string) (net.Conn, error) {
+			return contextDialer.DialContext(ctx, "tcp", addr)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+}
+
+// httpConnectDialer 通过 HTTP CONNECT 隧道连接上游，URL 中的用户信息作为 Basic 认证发送
+func httpConnectDialer(proxyURL *url.URL) Dialer {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", proxyURL.Host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dial proxy %s: %w", proxyURL.Host, err)
+		}
+		// 握手期间遵守上下文的截止时间和取消
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		}
+		stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+		defer stop()
+
+		req := &http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Opaque: addr},
+			Host:   addr,
+			Header: make(http.Header),
+		}
+		if user := proxyURL.User; user != nil {
+			password, _ := user.Password()
+			credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+			req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+		}
+		if err := req.Write(conn); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("failed to send CONNECT to proxy: %w", err)
+		}
+
+		reader := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(reader, req)
+		if err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("failed to read CONNECT response: %w", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			_ = conn.Close()
+			return nil, fmt.Errorf("proxy refused CONNECT to %s: %s", addr, resp.Status)
+		}
+		if !stop() {
+			return nil, ctx.Err()
+		}
+		_ = conn.SetDeadline(time.Time{})
+
+		// 代理可能在响应之后紧接着发送了隧道数据
+		if reader.Buffered() > 0 {
+			return &bufferedConn{Conn: conn, reader: reader}, nil
+		}
+		return conn, nil
+	}
+}
+
+// bufferedConn 先返回读缓冲中剩余的数据，再从底层连接读取
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
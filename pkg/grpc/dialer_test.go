@@ -0,0 +1,147 @@
+package grpc
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// connectProxy is a minimal HTTP CONNECT proxy recording the tunnels it opened
+type connectProxy struct {
+	addr string
+
+	mu      sync.Mutex
+	targets []string
+	auth    string
+}
+
+func startConnectProxy(t *testing.T) *connectProxy {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lis.Close() })
+
+	p := &connectProxy{addr: lis.Addr().String()}
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn)
+		}
+	}()
+	return p
+}
+
+func (p *connectProxy) serve(conn net.Conn) {
+	defer conn.Close()
+	req, err := http.ReadRequest(bufio.NewReader(conn))
+	if err != nil || req.Method != http.MethodConnect {
+		return
+	}
+	p.mu.Lock()
+	p.targets = append(p.targets, req.Host)
+	p.auth = req.Header.Get("Proxy-Authorization")
+	p.mu.Unlock()
+
+	upstream, err := net.Dial("tcp", req.Host)
+	if err != nil {
+		_, _ = io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+		return
+	}
+	defer upstream.Close()
+	_, _ = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+
+	go func() { _, _ = io.Copy(upstream, conn) }()
+	_, _ = io.Copy(conn, upstream)
+}
+
+func (p *connectProxy) tunnels() ([]string, string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.targets...), p.auth
+}
+
+func connectThrough(t *testing.T, upstream *testUpstream, opts ...DiscovererOption) ServiceDiscoverer {
+	t.Helper()
+	sd, err := NewServiceDiscoverer("127.0.0.1", upstream.addr.Port, zap.NewNop(), config.DescriptorSetConfig{}, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sd.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, sd.Connect(ctx))
+	require.NoError(t, sd.DiscoverServices(ctx))
+	return sd
+}
+
+func TestWithConnection_HTTPConnectProxyAndAuthority(t *testing.T) {
+	upstream := startTestUpstream(t, "dialtest.v1.EchoService")
+	proxy := startConnectProxy(t)
+
+	sd := connectThrough(t, upstream, WithConnection(config.ConnectionConfig{
+		Proxy:     "http://user:secret@" + proxy.addr,
+		Authority: "orders.mesh.internal",
+	}))
+	out, err := sd.InvokeMethodByTool(context.Background(), nil, "dialtest_v1_echoservice_echo", `"hi"`)
+	require.NoError(t, err)
+	assert.Equal(t, `"hi"`, out)
+
+	targets, auth := proxy.tunnels()
+	require.NotEmpty(t, targets)
+	assert.Equal(t, upstream.addr.String(), targets[0])
+	assert.Equal(t, "Basic dXNlcjpzZWNyZXQ=", auth)
+	assert.Equal(t, []string{"orders.mesh.internal"}, upstream.lastMetadata().Get(":authority"))
+}
+
+func TestWithDialer_OverridesProxy(t *testing.T) {
+	upstream := startTestUpstream(t, "dialtest.v1.EchoService")
+
+	var mu sync.Mutex
+	var dialed []string
+	dialer := func(ctx context.Context, addr string) (net.Conn, error) {
+		mu.Lock()
+		dialed = append(dialed, addr)
+		mu.Unlock()
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", addr)
+	}
+
+	// The proxy is unreachable; the custom dialer must be used instead
+	sd := connectThrough(t, upstream,
+		WithConnection(config.ConnectionConfig{Proxy: "socks5://127.0.0.1:1"}),
+		WithDialer(dialer))
+	_, err := sd.InvokeMethodByTool(context.Background(), nil, "dialtest_v1_echoservice_echo", `"hi"`)
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, dialed, upstream.addr.String())
+}
+
+func TestConnectionManagerConfig_Dialer(t *testing.T) {
+	d, err := ConnectionManagerConfig{}.dialer()
+	require.NoError(t, err)
+	assert.Nil(t, d)
+
+	d, err = ConnectionManagerConfig{Proxy: config.ProxyNone}.dialer()
+	require.NoError(t, err)
+	assert.Nil(t, d)
+
+	d, err = ConnectionManagerConfig{Proxy: "socks5://127.0.0.1:1080"}.dialer()
+	require.NoError(t, err)
+	assert.NotNil(t, d)
+
+	_, err = ConnectionManagerConfig{Proxy: "ftp://127.0.0.1:21"}.dialer()
+	assert.Error(t, err)
+}
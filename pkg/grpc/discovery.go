@@ -3,6 +3,8 @@ package grpc
 import (
 	"context"
 	"fmt"
+	"runtime/debug"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 	"github.com/aalobaidi/ggRMCP/pkg/descriptors"
 	"github.com/aalobaidi/ggRMCP/pkg/types"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/encoding/gzip"
 )
 
 // serviceDiscoverer 实现 ServiceDiscoverer 接口
@@ -30,8 +33,11 @@ import (
 // - connManager: 连接管理器，负责 gRPC 连接的创建、健康检查和重连
 // - reflectionClient: gRPC Reflection 客户端，用于从运行中的服务获取元数据
 // - tools: 原子指针，存储所有已发现的 gRPC 方法，键为工具名称，值为方法信息（线程安全）
+// - report: 原子指针，存储最近一次服务发现的报告（包含被跳过的服务和方法）
 // - descriptorLoader: 文件描述符加载器，用于从 .binpb 文件加载 Protobuf 元数据
 // - descriptorConfig: 文件描述符配置，指定是否启用及文件路径
+// - connConfig: 当前上游的连接配置，切换上游时以此为模板
+// - upstreamMu: 保护 connManager 和 reflectionClient，使上游切换对调用方原子可见
 // - reconnectInterval: 重连间隔，两次重连尝试之间的等待时间
 // - maxReconnectAttempts: 最大重连次数，超过此次数后放弃重连
 type serviceDiscoverer struct {
@@ -39,6 +45,11 @@ type serviceDiscoverer struct {
 	connManager      ConnectionManager
 	reflectionClient ReflectionClient
 	tools            atomic.Pointer[map[string]types.MethodInfo]
+	report           atomic.Pointer[DiscoveryReport]
+
+	// Upstream switching
+	connConfig ConnectionManagerConfig
+	upstreamMu sync.RWMutex
 
 	// Method extraction components
 	descriptorLoader *descriptors.Loader
@@ -47,6 +58,63 @@ type serviceDiscoverer struct {
 	// Configuration
 	reconnectInterval    time.Duration
 	maxReconnectAttempts int
+
+	// Invocations that panicked (e.g. on a malformed descriptor or message)
+	panics atomic.Int64
+}
+
+// DiscovererOption 调整服务发现器的连接配置
+type DiscovererOption func(*ConnectionManagerConfig)
+
+// WithResolver 通过解析器解析上游目标，并每隔 interval 重新解析（0 表示只在 gRPC 请求时解析）
+func WithResolver(r Resolver, interval time.Duration) DiscovererOption {
+	return func(c *ConnectionManagerConfig) {
+		c.Resolver = r
+		c.ResolveInterval = interval
+	}
+}
+
+// WithConnection 使用配置中该上游的连接参数（超时、心跳、收发消息大小、压缩、ready 模式、authority、代理、TLS），覆盖内置默认值
+func WithConnection(cfg config.ConnectionConfig) DiscovererOption {
+	return func(c *ConnectionManagerConfig) {
+		if cfg.ConnectTimeout > 0 {
+			c.ConnectTimeout = cfg.ConnectTimeout
+		}
+		if cfg.KeepAlive.Time > 0 {
+			c.KeepAlive = KeepAliveConfig{
+				Time:                cfg.KeepAlive.Time,
+				Timeout:             cfg.KeepAlive.Timeout,
+				PermitWithoutStream: cfg.KeepAlive.PermitWithoutStream,
+			}
+		}
+		if cfg.MaxRecvMessageSize > 0 {
+			c.MaxRecvMessageSize = cfg.MaxRecvMessageSize
+		}
+		if cfg.MaxSendMessageSize > 0 {
+			c.MaxSendMessageSize = cfg.MaxSendMessageSize
+		}
+		switch cfg.Compression {
+		case config.CompressionGzip:
+			c.Compressor = gzip.Name
+		case config.CompressionNone:
+			c.Compressor = ""
+		}
+		switch cfg.ReadyMode {
+		case config.ReadyModeWaitForReady:
+			c.WaitForReady = true
+		case config.ReadyModeFailFast:
+			c.WaitForReady = false
+		}
+		if cfg.Authority != "" {
+			c.Authority = cfg.Authority
+		}
+		if cfg.Proxy != "" {
+			c.Proxy = cfg.Proxy
+		}
+		if cfg.TLS.Enabled {
+			c.TLS = cfg.TLS
+		}
+	}
 }
 
 // NewServiceDiscoverer 创建一个新的服务发现器实例
@@ -62,12 +130,13 @@ type serviceDiscoverer struct {
 //   - port: gRPC 服务器端口（例如：50051）
 //   - logger: 日志记录器，用于输出各类日志
 //   - descriptorConfig: 文件描述符配置，指定是否使用 .binpb 文件
+//   - opts: 可选的连接配置（例如 WithResolver）
 //
 // 返回值：
 //   - ServiceDiscoverer: 已初始化的服务发现器接口
 //   - error: 初始化过程中的错误
 //
-// ConnectionManager 配置说明：
+// ConnectionManager 默认配置（可通过 WithConnection 按上游覆盖）：
 //   - ConnectTimeout: 连接超时时间（5秒）
 //   - KeepAlive: 心跳配置，定期检查连接状态
 //   - MaxMessageSize: 单条消息的最大大小（4MB），避免大消息溢出
@@ -78,7 +147,7 @@ type serviceDiscoverer struct {
 //	if err != nil {
 //	    log.Fatal("Failed to create discoverer:", err)
 //	}
-func NewServiceDiscoverer(host string, port int, logger *zap.Logger, descriptorConfig config.DescriptorSetConfig) (ServiceDiscoverer, error) {
+func NewServiceDiscoverer(host string, port int, logger *zap.Logger, descriptorConfig config.DescriptorSetConfig, opts ...DiscovererOption) (ServiceDiscoverer, error) {
 	// 🔧 第一步：创建 ConnectionManager 配置
 	// 这些配置决定了与 gRPC 服务器的连接特性
 	baseConfig := ConnectionManagerConfig{
@@ -92,6 +161,9 @@ func NewServiceDiscoverer(host string, port int, logger *zap.Logger, descriptorC
 		},
 		MaxMessageSize: 4 * 1024 * 1024, // 最大消息大小：4MB
 	}
+	for _, opt := range opts {
+		opt(&baseConfig)
+	}
 
 	// 🔌 第二步：创建连接管理器
 	// 连接管理器会在后续 Connect() 调用时建立实际连接
@@ -101,6 +173,7 @@ func NewServiceDiscoverer(host string, port int, logger *zap.Logger, descriptorC
 	d := &serviceDiscoverer{
 		logger:               logger.Named("discovery"), // 为日志添加 "discovery" 标签便于追踪
 		connManager:          connManager,
+		connConfig:           baseConfig,
 		descriptorLoader:     descriptors.NewLoader(logger), // 创建文件描述符加载器
 		descriptorConfig:     descriptorConfig,
 		reconnectInterval:    5 * time.Second, // 重连间隔：5秒
@@ -156,11 +229,14 @@ func (d *serviceDiscoverer) Connect(ctx context.Context) error {
 	// 🔍 第三步：创建 Reflection 客户端
 	// Reflection 客户端会通过 gRPC Reflection API 与服务器通信
 	// 用于获取服务、方法和消息定义的元数据
-	d.reflectionClient = NewReflectionClient(conn, d.logger)
+	reflectionClient := NewReflectionClient(conn, d.logger)
+	d.upstreamMu.Lock()
+	d.reflectionClient = reflectionClient
+	d.upstreamMu.Unlock()
 
 	// ✅ 第四步：执行健康检查
 	// 验证连接是否真正可用，服务是否可以访问
-	if err := d.reflectionClient.HealthCheck(ctx); err != nil {
+	if err := reflectionClient.HealthCheck(ctx); err != nil {
 		return fmt.Errorf("health check failed: %w", err)
 	}
 
@@ -211,55 +287,153 @@ func (d *serviceDiscoverer) Connect(ctx context.Context) error {
 //	methods := discoverer.GetMethods()
 //	log.Printf("Discovered %d methods\n", len(methods))
 func (d *serviceDiscoverer) DiscoverServices(ctx context.Context) error {
+	d.upstreamMu.RLock()
+	reflectionClient := d.reflectionClient
+	d.upstreamMu.RUnlock()
+
+	// 发现过程中逐批将工具合并进缓存，慢速或大型上游的工具可以尽早可用
+	methods, report, err := d.collectMethods(ctx, reflectionClient, d.publishTools)
+	if err != nil {
+		return err
+	}
+
+	// 📦 将发现的方法存入缓存
+	// 构建方法映射：key 为工具名称，value 为方法信息
+	tools := make(map[string]types.MethodInfo)
+	for _, method := range methods {
+		// 工具名称通常为：service_name_method_name（例：user_service_get_user）
+		tools[method.ToolName] = method
+	}
+	// 使用原子操作存储，确保线程安全
+	// 完整结果会替换增量合并的结果，移除上游已不再提供的工具
+	d.tools.Store(&tools)
+	d.storeReport(report)
+
+	return nil
+}
+
+// DiscoveryReport 返回最近一次服务发现的报告，尚未完成发现时返回 nil
+func (d *serviceDiscoverer) DiscoveryReport() *DiscoveryReport {
+	return d.report.Load()
+}
+
+// storeReport 保存服务发现报告并输出汇总日志
+//
+// 被跳过的服务和方法会以警告级别逐条记录，避免单个错误的描述符
+// 悄无声息地从工具目录中消失
+func (d *serviceDiscoverer) storeReport(report *DiscoveryReport) {
+	d.report.Store(report)
+
+	d.logger.Info("Discovery summary",
+		zap.String("source", report.Source),
+		zap.Int("serviceCount", report.ServiceCount),
+		zap.Int("methodCount", report.MethodCount),
+		zap.Int("failureCount", len(report.Failures)),
+		zap.String("duration", report.Duration))
+	if report.FallbackReason != "" {
+		d.logger.Warn("Discovery fell back to reflection", zap.String("reason", report.FallbackReason))
+	}
+	for _, failure := range report.Failures {
+		d.logger.Warn("Service left out of the catalog",
+			zap.String("service", failure.Service),
+			zap.String("method", failure.Method),
+			zap.String("error", failure.Error))
+	}
+}
+
+// newDiscoveryReport 根据发现结果生成报告
+func newDiscoveryReport(source string, methods []types.MethodInfo, failures []types.DiscoveryFailure, start time.Time) *DiscoveryReport {
+	services := make(map[string]bool)
+	for _, method := range methods {
+		services[method.ServiceName] = true
+	}
+	return &DiscoveryReport{
+		Source:       source,
+		CompletedAt:  time.Now().UTC(),
+		Duration:     time.Since(start).String(),
+		ServiceCount: len(services),
+		MethodCount:  len(methods),
+		Failures:     failures,
+	}
+}
+
+// publishTools 将一批新发现的方法合并进工具缓存
+//
+// 采用写时复制 + CompareAndSwap，读取方始终看到完整一致的映射，
+// 并发合并也不会丢失更新
+func (d *serviceDiscoverer) publishTools(methods []types.MethodInfo) {
+	for {
+		current := d.tools.Load()
+		tools := make(map[string]types.MethodInfo, len(*current)+len(methods))
+		for name, method := range *current {
+			tools[name] = method
+		}
+		for _, method := range methods {
+			tools[method.ToolName] = method
+		}
+		if d.tools.CompareAndSwap(current, &tools) {
+			d.logger.Debug("Published discovered tools",
+				zap.Int("batchSize", len(methods)),
+				zap.Int("toolCount", len(tools)))
+			return
+		}
+	}
+}
+
+// collectMethods 使用给定的反射客户端发现方法（不更新缓存）
+//
+// 优先使用 FileDescriptorSet，失败时回退到 Reflection；
+// 配置了 FileDescriptorSet 时 reflectionClient 可以为 nil（例如回放模式）。
+// publish 非 nil 时，Reflection 发现过程中每得到一批方法就回调一次。
+// 返回的报告记录了实际使用的发现方式以及被跳过的服务和方法
+func (d *serviceDiscoverer) collectMethods(ctx context.Context, reflectionClient ReflectionClient, publish func([]types.MethodInfo)) ([]types.MethodInfo, *DiscoveryReport, error) {
 	// ✅ 前置条件检查：必须先建立连接
-	if d.reflectionClient == nil {
-		return fmt.Errorf("not connected to gRPC server")
+	// 配置了 FileDescriptorSet 时允许在未连接的情况下发现服务（例如回放模式）
+	descriptorEnabled := d.descriptorConfig.Enabled && d.descriptorConfig.Path != ""
+	if reflectionClient == nil && !descriptorEnabled {
+		return nil, nil, fmt.Errorf("not connected to gRPC server")
 	}
 
 	d.logger.Info("Starting service discovery")
+	start := time.Now()
 
 	var methods []types.MethodInfo
+	var failures []types.DiscoveryFailure
+	var fallbackReason string
 	var err error
 
 	// 🔀 第一步：尝试从 FileDescriptorSet 发现服务
 	// FileDescriptorSet 是预编译的文件，包含所有 Protobuf 定义和注释
 	// 优点：包含完整的文档和注释，生成更好的 AI 工具描述
-	if d.descriptorConfig.Enabled && d.descriptorConfig.Path != "" {
+	if descriptorEnabled {
 		// 尝试从文件加载
-		methods, err = d.discoverFromFileDescriptor()
+		methods, failures, err = d.discoverFromFileDescriptor()
 		if err == nil {
 			// 成功从 FileDescriptorSet 加载
 			d.logger.Info("Successfully discovered services from FileDescriptorSet")
-		} else {
-			// 加载失败，记录警告并继续尝试 Reflection
-			d.logger.Warn("Failed to discover from FileDescriptorSet, falling back to reflection",
-				zap.Error(err))
-			methods = nil // 清空失败的结果，准备使用 Reflection
+			return methods, newDiscoveryReport("descriptor_set", methods, failures, start), nil
 		}
+		// 加载失败，记录警告并继续尝试 Reflection
+		d.logger.Warn("Failed to discover from FileDescriptorSet, falling back to reflection",
+			zap.Error(err))
+		fallbackReason = err.Error()
 	}
 
 	// 🔁 第二步：如果 FileDescriptorSet 不可用或失败，使用 gRPC Reflection
 	// Reflection 动态发现运行中的服务，但不包含注释信息
 	// 优点：无需预编译文件，实时发现，适应服务变化
-	if methods == nil {
-		methods, err = d.discoverFromReflection(ctx)
-		if err != nil {
-			// 两种方式都失败，返回错误
-			return err
-		}
+	if reflectionClient == nil {
+		return nil, nil, fmt.Errorf("not connected to gRPC server and descriptor set unavailable: %w", err)
 	}
-
-	// 📦 第三步：将发现的方法存入缓存
-	// 构建方法映射：key 为工具名称，value 为方法信息
-	tools := make(map[string]types.MethodInfo)
-	for _, method := range methods {
-		// 工具名称通常为：service_name_method_name（例：user_service_get_user）
-		tools[method.ToolName] = method
+	methods, failures, err = d.discoverFromReflection(ctx, reflectionClient, publish)
+	if err != nil {
+		// 两种方式都失败，返回错误
+		return nil, nil, err
 	}
-	// 使用原子操作存储，确保线程安全
-	d.tools.Store(&tools)
 
-	return nil
+	report := newDiscoveryReport("reflection", methods, failures, start)
+	report.FallbackReason = fallbackReason
+	return methods, report, nil
 }
 
 // discoverFromFileDescriptor 从 FileDescriptorSet 文件加载服务定义
@@ -277,22 +451,32 @@ func (d *serviceDiscoverer) DiscoverServices(ctx context.Context) error {
 //
 // 返回值：
 // - []types.MethodInfo: 提取的所有方法信息列表
+// - []types.DiscoveryFailure: 因消息类型无法解析而被跳过的方法
 // - error: 加载或解析过程中的错误
 //
 // 示例使用：
 //
-//	methods, err := discoverer.discoverFromFileDescriptor()
+//	methods, failures, err := discoverer.discoverFromFileDescriptor()
 //	if err != nil {
 //	    log.Printf("Failed to load descriptor file: %v\n", err)
 //	}
-func (d *serviceDiscoverer) discoverFromFileDescriptor() ([]types.MethodInfo, error) {
+func (d *serviceDiscoverer) discoverFromFileDescriptor() ([]types.MethodInfo, []types.DiscoveryFailure, error) {
 	// 📋 第一步：从文件系统加载 FileDescriptorSet
 	d.logger.Info("Discovering services from FileDescriptorSet", zap.String("path", d.descriptorConfig.Path))
 
 	// 使用 DescriptorLoader 从 .binpb 文件加载二进制描述符
 	fdSet, err := d.descriptorLoader.LoadFromFile(d.descriptorConfig.Path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load descriptor set: %w", err)
+		return nil, nil, fmt.Errorf("failed to load descriptor set: %w", err)
+	}
+
+	// 校验描述符集合，提示需要调整的 protoc 参数（与 grmcp validate-descriptor 相同）
+	for _, issue := range descriptors.ValidateDescriptorSet(fdSet) {
+		d.logger.Warn("Descriptor set issue",
+			zap.String("severity", issue.Severity),
+			zap.String("file", issue.File),
+			zap.String("issue", issue.Message),
+			zap.String("fix", issue.Fix))
 	}
 
 	// 🔨 第二步：构建文件描述符注册表
@@ -300,7 +484,7 @@ func (d *serviceDiscoverer) discoverFromFileDescriptor() ([]types.MethodInfo, er
 	// 用于快速查找和遍历所有定义的类型
 	files, err := d.descriptorLoader.BuildRegistry(fdSet)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build file registry: %w", err)
+		return nil, nil, fmt.Errorf("failed to build file registry: %w", err)
 	}
 
 	// 📝 第三步：从文件描述符中提取方法信息
@@ -309,13 +493,10 @@ func (d *serviceDiscoverer) discoverFromFileDescriptor() ([]types.MethodInfo, er
 	// - 输入/输出类型
 	// - 是否为流式方法
 	// - 方法注释和说明
-	methods, err := d.descriptorLoader.ExtractMethodInfo(files)
-	if err != nil {
-		return nil, fmt.Errorf("failed to extract method info: %w", err)
-	}
+	methods, failures := d.descriptorLoader.ExtractMethodInfoWithFailures(files)
 
 	d.logger.Info("FileDescriptorSet discovery completed", zap.Int("methodCount", len(methods)))
-	return methods, nil
+	return methods, failures, nil
 }
 
 // discoverFromReflection 通过 gRPC Reflection API 动态发现服务
@@ -333,6 +514,7 @@ func (d *serviceDiscoverer) discoverFromFileDescriptor() ([]types.MethodInfo, er
 //
 // 返回值：
 // - []types.MethodInfo: 发现的所有方法信息列表
+// - []types.DiscoveryFailure: 被跳过的服务和方法（客户端支持时）
 // - error: 发现过程中的错误
 //
 // 注意：
@@ -341,11 +523,11 @@ func (d *serviceDiscoverer) discoverFromFileDescriptor() ([]types.MethodInfo, er
 //
 // 示例使用：
 //
-//	methods, err := discoverer.discoverFromReflection(ctx)
+//	methods, failures, err := discoverer.discoverFromReflection(ctx, discoverer.reflectionClient, nil)
 //	if err != nil {
 //	    log.Printf("Failed to discover via reflection: %v\n", err)
 //	}
-func (d *serviceDiscoverer) discoverFromReflection(ctx context.Context) ([]types.MethodInfo, error) {
+func (d *serviceDiscoverer) discoverFromReflection(ctx context.Context, reflectionClient ReflectionClient, publish func([]types.MethodInfo)) ([]types.MethodInfo, []types.DiscoveryFailure, error) {
 	// 🔍 使用 ReflectionClient 查询运行中的服务
 	d.logger.Info("Discovering services from reflection")
 
@@ -353,13 +535,24 @@ func (d *serviceDiscoverer) discoverFromReflection(ctx context.Context) ([]types
 	// - 服务列表 (ListServices)
 	// - 每个服务的方法定义 (GetServiceDescriptor)
 	// - 方法的输入输出类型 (GetMessageDescriptor)
-	methods, err := d.reflectionClient.DiscoverMethods(ctx)
+	// 支持增量发现的客户端会在每个文件描述符处理完后回调 publish，并报告被跳过的服务
+	var methods []types.MethodInfo
+	var failures []types.DiscoveryFailure
+	var err error
+	if progressive, ok := reflectionClient.(ProgressiveReflectionClient); ok {
+		if publish == nil {
+			publish = func([]types.MethodInfo) {}
+		}
+		methods, failures, err = progressive.DiscoverMethodsProgressively(ctx, publish)
+	} else {
+		methods, err = reflectionClient.DiscoverMethods(ctx)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to discover services via reflection: %w", err)
+		return nil, nil, fmt.Errorf("failed to discover services via reflection: %w", err)
 	}
 
 	d.logger.Info("Reflection discovery completed", zap.Int("methodCount", len(methods)))
-	return methods, nil
+	return methods, failures, nil
 }
 
 // GetMethods 返回所有已发现的 gRPC 方法
@@ -490,7 +683,9 @@ func (d *serviceDiscoverer) Reconnect(ctx context.Context) error {
 			lastErr = fmt.Errorf("connection manager returned nil connection after reconnect")
 			continue
 		}
+		d.upstreamMu.Lock()
 		d.reflectionClient = NewReflectionClient(conn, d.logger)
+		d.upstreamMu.Unlock()
 
 		// 🔍 第三步：重新发现服务
 		// 在重连后，需要重新获取服务元数据
@@ -520,7 +715,22 @@ func (d *serviceDiscoverer) Reconnect(ctx context.Context) error {
 //
 // 返回值：true = 已连接，false = 未连接
 func (d *serviceDiscoverer) isConnected() bool {
-	return d.connManager.IsConnected() && d.reflectionClient != nil
+	connManager, reflectionClient := d.upstream()
+	return connManager.IsConnected() && reflectionClient != nil
+}
+
+// upstreamTarget 返回当前上游地址（host:port）
+func (d *serviceDiscoverer) upstreamTarget() string {
+	d.upstreamMu.RLock()
+	defer d.upstreamMu.RUnlock()
+	return fmt.Sprintf("%s:%d", d.connConfig.Host, d.connConfig.Port)
+}
+
+// upstream 返回当前上游的连接管理器和反射客户端（在读锁保护下读取）
+func (d *serviceDiscoverer) upstream() (ConnectionManager, ReflectionClient) {
+	d.upstreamMu.RLock()
+	defer d.upstreamMu.RUnlock()
+	return d.connManager, d.reflectionClient
 }
 
 // HealthCheck 执行健康检查，验证与 gRPC 服务器的连接状态
@@ -556,18 +766,19 @@ func (d *serviceDiscoverer) isConnected() bool {
 func (d *serviceDiscoverer) HealthCheck(ctx context.Context) error {
 	// 🔌 第一步：检查连接管理器的健康状态
 	// 这会验证底层 TCP 连接和心跳状态
-	if err := d.connManager.HealthCheck(ctx); err != nil {
+	connManager, reflectionClient := d.upstream()
+	if err := connManager.HealthCheck(ctx); err != nil {
 		return fmt.Errorf("connection manager health check failed: %w", err)
 	}
 
 	// 🔍 第二步：检查 Reflection 客户端是否初始化
-	if d.reflectionClient == nil {
+	if reflectionClient == nil {
 		return fmt.Errorf("reflection client not initialized")
 	}
 
 	// ✅ 第三步：执行 Reflection 客户端的健康检查
 	// 这会通过 gRPC 调用与服务器通信，验证服务可达性
-	return d.reflectionClient.HealthCheck(ctx)
+	return reflectionClient.HealthCheck(ctx)
 }
 
 // Close 关闭服务发现器，释放所有相关资源
@@ -604,6 +815,9 @@ func (d *serviceDiscoverer) HealthCheck(ctx context.Context) error {
 //	    log.Printf("Warning: close returned error: %v\n", err)
 //	}
 func (d *serviceDiscoverer) Close() error {
+	d.upstreamMu.Lock()
+	defer d.upstreamMu.Unlock()
+
 	// 🔍 第一步：关闭 ReflectionClient
 	// 这会清理与 gRPC 服务器的反射相关连接
 	if d.reflectionClient != nil {
@@ -710,6 +924,7 @@ func (d *serviceDiscoverer) GetServiceStats() map[string]interface{} {
 			"methodCount":  0,
 			"isConnected":  d.isConnected(),
 			"services":     []string{},
+			"invokePanics": d.panics.Load(),
 		}
 		return stats
 	}
@@ -732,11 +947,18 @@ func (d *serviceDiscoverer) GetServiceStats() map[string]interface{} {
 		"methodCount":  len(*tools),
 		"isConnected":  d.isConnected(),
 		"services":     serviceList,
+		"upstream":     d.upstreamTarget(),
+		"invokePanics": d.panics.Load(),
 	}
 
 	return stats
 }
 
+// GetMethod 根据工具名称获取方法信息
+func (d *serviceDiscoverer) GetMethod(toolName string) (types.MethodInfo, bool) {
+	return d.getMethodByTool(toolName)
+}
+
 // getMethodByTool 根据工具名称获取方法信息（私有辅助函数）
 //
 // 参数：
@@ -797,7 +1019,20 @@ func (d *serviceDiscoverer) getMethodByTool(toolName string) (types.MethodInfo,
 //	    log.Fatal("Invocation failed:", err)
 //	}
 //	log.Println("Result:", result)
-func (d *serviceDiscoverer) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+func (d *serviceDiscoverer) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (result string, err error) {
+	// 🛟 调用中的 panic 转换为错误，避免一个异常的描述符或消息拖垮整个网关
+	// （对冲、镜像等装饰器在独立 goroutine 中调用，HTTP 层的恢复无法覆盖）
+	defer func() {
+		if p := recover(); p != nil {
+			d.panics.Add(1)
+			d.logger.Error("Panic while invoking method",
+				zap.String("toolName", toolName),
+				zap.Any("panic", p),
+				zap.ByteString("stack", debug.Stack()))
+			result, err = "", fmt.Errorf("internal error while invoking tool %s", toolName)
+		}
+	}()
+
 	// 🔍 第一步：根据工具名称查找方法定义
 	method, exists := d.getMethodByTool(toolName)
 	if !exists {
@@ -805,13 +1040,15 @@ func (d *serviceDiscoverer) InvokeMethodByTool(ctx context.Context, headers map[
 	}
 
 	// ⚠️ 第二步：检查方法是否为流式方法
-	// 当前实现不支持流式 RPC（客户端流、服务器流、双向流）
-	if method.IsClientStreaming || method.IsServerStreaming {
+	// 不支持客户端流和双向流；服务端流需在配置中启用，收到的消息聚合为一个结果
+	streaming := d.serverStreaming()
+	if method.IsClientStreaming || (method.IsServerStreaming && !streaming.Enabled) {
 		return "", fmt.Errorf("streaming methods are not supported")
 	}
 
 	// 🔌 第三步：验证反射客户端已初始化
-	if d.reflectionClient == nil {
+	_, reflectionClient := d.upstream()
+	if reflectionClient == nil {
 		return "", fmt.Errorf("not connected to gRPC server")
 	}
 
@@ -829,7 +1066,11 @@ func (d *serviceDiscoverer) InvokeMethodByTool(ctx context.Context, headers map[
 	// 3. 将 HTTP headers 转换为 gRPC metadata
 	// 4. 发送 gRPC 调用
 	// 5. 将 Protobuf 响应转换为 JSON
-	result, err := d.reflectionClient.InvokeMethod(ctx, headers, method, inputJSON)
+	ctx = withCallOptions(ctx, d.toolCallOptions(toolName)...)
+	if method.IsServerStreaming {
+		return d.invokeServerStream(ctx, reflectionClient, headers, method, inputJSON, streaming)
+	}
+	result, err = reflectionClient.InvokeMethod(ctx, headers, method, inputJSON)
 	if err != nil {
 		return "", fmt.Errorf("failed to invoke method: %w", err)
 	}
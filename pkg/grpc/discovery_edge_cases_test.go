@@ -31,7 +31,7 @@ func TestDiscoverMethods_MultipleServicesInOneFile(t *testing.T) {
 	unifiedDiscoverer := discoverer.(*serviceDiscoverer)
 
 	// Test direct FileDescriptorSet discovery
-	methods, err := unifiedDiscoverer.discoverFromFileDescriptor()
+	methods, _, err := unifiedDiscoverer.discoverFromFileDescriptor()
 	if err != nil {
 		t.Skip("Complex service descriptor file not found - run 'make descriptor' in examples/hello-service")
 		return
@@ -297,7 +297,7 @@ func TestMethodInvocationByToolName(t *testing.T) {
 
 	unifiedDiscoverer := discoverer.(*serviceDiscoverer)
 
-	methods, err := unifiedDiscoverer.discoverFromFileDescriptor()
+	methods, _, err := unifiedDiscoverer.discoverFromFileDescriptor()
 	if err != nil {
 		t.Skip("Descriptor set file not found - run 'make descriptor' in examples/hello-service")
 		return
@@ -350,7 +350,7 @@ func TestComplexServiceCoverage(t *testing.T) {
 
 	unifiedDiscoverer := discoverer.(*serviceDiscoverer)
 
-	methods, err := unifiedDiscoverer.discoverFromFileDescriptor()
+	methods, _, err := unifiedDiscoverer.discoverFromFileDescriptor()
 	if err != nil {
 		t.Skip("Complex service descriptor file not found - run 'make descriptor' in examples/hello-service")
 		return
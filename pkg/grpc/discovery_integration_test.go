@@ -30,7 +30,7 @@ func TestUnifiedServiceDiscovery_FileDescriptorSetIntegration(t *testing.T) {
 		unifiedDiscoverer := discoverer.(*serviceDiscoverer)
 
 		// Test direct FileDescriptorSet discovery (without needing gRPC connection)
-		methods, err := unifiedDiscoverer.discoverFromFileDescriptor()
+		methods, _, err := unifiedDiscoverer.discoverFromFileDescriptor()
 
 		if err != nil {
 			if err.Error() == "failed to load descriptor set: failed to open descriptor file ../../examples/hello-service/build/hello.binpb: open ../../examples/hello-service/build/hello.binpb: no such file or directory" {
@@ -98,7 +98,7 @@ func TestUnifiedServiceDiscovery_FileDescriptorSetIntegration(t *testing.T) {
 		unifiedDiscoverer := discoverer.(*serviceDiscoverer)
 
 		// This should fail gracefully
-		_, err = unifiedDiscoverer.discoverFromFileDescriptor()
+		_, _, err = unifiedDiscoverer.discoverFromFileDescriptor()
 		assert.Error(t, err, "Should fail when file doesn't exist")
 		assert.Contains(t, err.Error(), "failed to load descriptor set")
 
@@ -123,7 +123,7 @@ func TestMethodInfoPropagation_EndToEnd(t *testing.T) {
 	unifiedDiscoverer := discoverer.(*serviceDiscoverer)
 
 	// Discover from FileDescriptorSet
-	methods, err := unifiedDiscoverer.discoverFromFileDescriptor()
+	methods, _, err := unifiedDiscoverer.discoverFromFileDescriptor()
 	if err != nil {
 		t.Skip("Descriptor set file not found - run 'make descriptor' in examples/hello-service")
 		return
@@ -202,7 +202,7 @@ func TestServiceDiscoveryConfiguration_EndToEnd(t *testing.T) {
 
 			if tc.config.Enabled {
 				// Should attempt FileDescriptorSet loading
-				methods, err := unifiedDiscoverer.discoverFromFileDescriptor()
+				methods, _, err := unifiedDiscoverer.discoverFromFileDescriptor()
 				if err != nil && err.Error() == "failed to load descriptor set: failed to open descriptor file ../../examples/hello-service/build/hello.binpb: open ../../examples/hello-service/build/hello.binpb: no such file or directory" {
 					t.Skip("Descriptor set file not found")
 					return
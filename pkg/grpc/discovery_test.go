@@ -7,6 +7,7 @@ import (
 	"github.com/aalobaidi/ggRMCP/pkg/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	grpcLib "google.golang.org/grpc"
 )
@@ -140,3 +141,92 @@ func TestServiceDiscoverer_InvokeMethodByTool(t *testing.T) {
 	// Verify all expectations were met
 	mockReflClient.AssertExpectations(t)
 }
+
+func TestServiceDiscoverer_InvokeMethodByToolRecoversPanic(t *testing.T) {
+	mockConnMgr := &mockConnectionManager{}
+	mockConnMgr.On("IsConnected").Return(true)
+	discoverer := newServiceDiscovererWithConnManager(mockConnMgr, zap.NewNop())
+
+	toolName := "test_service_panics"
+	methodInfo := types.MethodInfo{Name: "Panics", ServiceName: "test.Service", ToolName: toolName}
+	tools := map[string]types.MethodInfo{toolName: methodInfo}
+	discoverer.tools.Store(&tools)
+
+	mockReflClient := &mockReflectionClient{}
+	mockReflClient.On("InvokeMethod", mock.Anything, mock.Anything, methodInfo, "{}").
+		Run(func(mock.Arguments) { panic("boom") }).
+		Return("", nil)
+	discoverer.reflectionClient = mockReflClient
+
+	result, err := discoverer.InvokeMethodByTool(context.Background(), nil, toolName, "{}")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "internal error")
+	assert.NotContains(t, err.Error(), "boom")
+	assert.Empty(t, result)
+	assert.Equal(t, int64(1), discoverer.GetServiceStats()["invokePanics"])
+}
+
+type progressiveReflectionClient struct {
+	mockReflectionClient
+	batches  [][]types.MethodInfo
+	failures []types.DiscoveryFailure
+	observed []int
+	onBatch  func() int
+}
+
+func (p *progressiveReflectionClient) DiscoverMethodsProgressively(ctx context.Context, publish func([]types.MethodInfo)) ([]types.MethodInfo, []types.DiscoveryFailure, error) {
+	var methods []types.MethodInfo
+	for _, batch := range p.batches {
+		publish(batch)
+		p.observed = append(p.observed, p.onBatch())
+		methods = append(methods, batch...)
+	}
+	return methods, p.failures, nil
+}
+
+func TestServiceDiscoverer_DiscoverServicesPublishesProgressively(t *testing.T) {
+	discoverer := newServiceDiscovererWithConnManager(&mockConnectionManager{}, zap.NewNop())
+
+	// A tool from a previous discovery pass stays visible until the pass completes
+	stale := map[string]types.MethodInfo{"old_tool": {ToolName: "old_tool"}}
+	discoverer.tools.Store(&stale)
+
+	client := &progressiveReflectionClient{
+		batches: [][]types.MethodInfo{
+			{{ToolName: "a_one"}, {ToolName: "a_two"}},
+			{{ToolName: "b_one"}},
+		},
+		onBatch: discoverer.GetMethodCount,
+	}
+	discoverer.reflectionClient = client
+
+	require.NoError(t, discoverer.DiscoverServices(context.Background()))
+
+	assert.Equal(t, []int{3, 4}, client.observed)
+	assert.Equal(t, 3, discoverer.GetMethodCount())
+	_, exists := discoverer.GetMethod("old_tool")
+	assert.False(t, exists)
+}
+
+func TestServiceDiscoverer_DiscoveryReportListsFailures(t *testing.T) {
+	discoverer := newServiceDiscovererWithConnManager(&mockConnectionManager{}, zap.NewNop())
+	assert.Nil(t, discoverer.DiscoveryReport())
+
+	failure := types.DiscoveryFailure{Service: "broken.v1.BrokenService", Error: "file descriptor not found"}
+	discoverer.reflectionClient = &progressiveReflectionClient{
+		batches: [][]types.MethodInfo{
+			{{ToolName: "a_one", ServiceName: "a.Service"}, {ToolName: "a_two", ServiceName: "a.Service"}},
+		},
+		failures: []types.DiscoveryFailure{failure},
+		onBatch:  discoverer.GetMethodCount,
+	}
+
+	require.NoError(t, discoverer.DiscoverServices(context.Background()))
+
+	report := discoverer.DiscoveryReport()
+	require.NotNil(t, report)
+	assert.Equal(t, "reflection", report.Source)
+	assert.Equal(t, 1, report.ServiceCount)
+	assert.Equal(t, 2, report.MethodCount)
+	assert.Equal(t, []types.DiscoveryFailure{failure}, report.Failures)
+}
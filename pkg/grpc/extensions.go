@@ -0,0 +1,198 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// optionTypes 是自定义选项可以扩展的 descriptor.proto 选项消息
+var optionTypes = []string{
+	"google.protobuf.FileOptions",
+	"google.protobuf.MessageOptions",
+	"google.protobuf.FieldOptions",
+	"google.protobuf.OneofOptions",
+	"google.protobuf.EnumOptions",
+	"google.protobuf.EnumValueOptions",
+	"google.protobuf.ServiceOptions",
+	"google.protobuf.MethodOptions",
+}
+
+// fetchOptionExtensions 通过 Reflection 的扩展请求获取上游声明的选项扩展
+//
+// 对每个选项消息发送 all_extension_numbers_of_type 请求，再对注册表中尚不认识的编号
+// 发送 file_containing_extension 请求；返回的文件缓存到 fdCache 并注册到 registry。
+// 这样即使扩展所在的文件没有被服务文件直接导入，自定义选项（例如 validate 规则）
+// 也能像从 FileDescriptorSet 加载时一样被解析。
+// 服务器不支持扩展请求或某个编号查询失败时只记录日志，不影响服务发现。
+func (r *reflectionClient) fetchOptionExtensions(ctx context.Context, registry *fileRegistry) {
+	stream, err := r.client.ServerReflectionInfo(ctx)
+	if err != nil {
+		r.logger.Debug("Failed to create reflection stream for extensions", zap.Error(err))
+		return
+	}
+	defer func() {
+		if closeErr := stream.CloseSend(); closeErr != nil {
+			r.logger.Warn("Failed to close reflection stream", zap.Error(closeErr))
+		}
+	}()
+
+	// 同一个流上依次发送请求；服务器在同一个流上不会重复发送已发送过的文件。
+	// 流出错后不能继续使用，返回错误；协议级错误（ErrorResponse）只影响当前请求
+	send := func(req *grpc_reflection_v1alpha.ServerReflectionRequest) (*grpc_reflection_v1alpha.ServerReflectionResponse, error) {
+		if err := stream.Send(req); err != nil {
+			return nil, err
+		}
+		return stream.Recv()
+	}
+
+	fetched := 0
+	for _, optionType := range optionTypes {
+		resp, err := send(&grpc_reflection_v1alpha.ServerReflectionRequest{
+			MessageRequest: &grpc_reflection_v1alpha.ServerReflectionRequest_AllExtensionNumbersOfType{
+				AllExtensionNumbersOfType: optionType,
+			},
+		})
+		if err != nil {
+			r.logger.Debug("Failed to list option extensions", zap.Error(err))
+			return
+		}
+		if errResp := resp.GetErrorResponse(); errResp != nil {
+			r.logger.Debug("Upstream does not list option extensions",
+				zap.String("type", optionType),
+				zap.String("error", errResp.GetErrorMessage()))
+			continue
+		}
+
+		for _, number := range resp.GetAllExtensionNumbersResponse().GetExtensionNumber() {
+			if registry.hasExtension(protoreflect.FullName(optionType), protoreflect.FieldNumber(number)) {
+				continue
+			}
+			resp, err := send(&grpc_reflection_v1alpha.ServerReflectionRequest{
+				MessageRequest: &grpc_reflection_v1alpha.ServerReflectionRequest_FileContainingExtension{
+					FileContainingExtension: &grpc_reflection_v1alpha.ExtensionRequest{
+						ContainingType:  optionType,
+						ExtensionNumber: number,
+					},
+				},
+			})
+			if err != nil {
+				r.logger.Debug("Failed to fetch option extension", zap.Error(err))
+				return
+			}
+			if errResp := resp.GetErrorResponse(); errResp != nil {
+				r.logger.Debug("Upstream does not serve option extension",
+					zap.String("type", optionType),
+					zap.Int32("number", number),
+					zap.String("error", errResp.GetErrorMessage()))
+				continue
+			}
+
+			fileDescriptors, err := r.cacheFileDescriptors(resp.GetFileDescriptorResponse().GetFileDescriptorProto())
+			if err != nil || len(fileDescriptors) == 0 {
+				continue
+			}
+			if err := registry.register(fileDescriptors[0]); err != nil {
+				r.logger.Warn("Failed to register option extension file",
+					zap.String("file", fileDescriptors[0].GetName()),
+					zap.Error(err))
+				continue
+			}
+			fetched++
+		}
+	}
+
+	if fetched > 0 {
+		r.logger.Info("Fetched option extensions via reflection", zap.Int("files", fetched))
+	}
+}
+
+// register 将文件描述符及其依赖注册到注册表
+func (g *fileRegistry) register(fdProto *descriptorpb.FileDescriptorProto) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, err := g.registerFile(fdProto, make(map[string]bool))
+	return err
+}
+
+// hasExtension 报告注册表中是否已有给定消息上的扩展编号
+func (g *fileRegistry) hasExtension(message protoreflect.FullName, number protoreflect.FieldNumber) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, err := g.FindExtensionByNumber(message, number)
+	return err == nil
+}
+
+// resolveOptions 用注册表中的扩展重新解析文件中的选项，调用方需持有 mu
+//
+// 反序列化 Reflection 响应时只认识网关自身链接的扩展，上游的自定义选项都作为未知字段保留；
+// 依赖注册后这里重新解析，使自定义选项可以通过扩展类型读取
+func (g *fileRegistry) resolveOptions(fdProto *descriptorpb.FileDescriptorProto) *descriptorpb.FileDescriptorProto {
+	data, err := proto.Marshal(fdProto)
+	if err != nil {
+		return fdProto
+	}
+	resolved := new(descriptorpb.FileDescriptorProto)
+	if err := (proto.UnmarshalOptions{Resolver: optionResolver{g}}).Unmarshal(data, resolved); err != nil {
+		return fdProto
+	}
+	return resolved
+}
+
+// optionResolver 解析选项扩展：内置的 google/protobuf 与 google/api 扩展使用生成的类型
+// （便于 proto.GetExtension(opts, annotations.E_FieldBehavior) 这样的调用），其余只在注册表中查找
+type optionResolver struct {
+	registry *fileRegistry
+}
+
+// bundledExtension 报告扩展是否随二进制内置
+func bundledExtension(xt protoreflect.ExtensionType) bool {
+	name := string(xt.TypeDescriptor().FullName())
+	return strings.HasPrefix(name, "google.protobuf.") || strings.HasPrefix(name, "google.api.")
+}
+
+func (o optionResolver) FindExtensionByName(name protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	if xt, err := protoregistry.GlobalTypes.FindExtensionByName(name); err == nil && bundledExtension(xt) {
+		return xt, nil
+	}
+	return o.registry.FindExtensionByName(name)
+}
+
+func (o optionResolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	if xt, err := protoregistry.GlobalTypes.FindExtensionByNumber(message, field); err == nil && bundledExtension(xt) {
+		return xt, nil
+	}
+	return o.registry.FindExtensionByNumber(message, field)
+}
+
+// FindExtensionByName 在注册表中查找扩展类型
+//
+// 同一扩展总是返回同一实例：proto.HasExtension 与 proto.GetExtension 只认
+// 解析选项时使用的那个扩展类型实例，dynamicpb.Types 每次查找却会创建新实例
+func (g *fileRegistry) FindExtensionByName(name protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	xt, err := g.types.FindExtensionByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return g.extensionType(xt), nil
+}
+
+// FindExtensionByNumber 按扩展的消息与编号在注册表中查找扩展类型，实例与 FindExtensionByName 相同
+func (g *fileRegistry) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	xt, err := g.types.FindExtensionByNumber(message, field)
+	if err != nil {
+		return nil, err
+	}
+	return g.extensionType(xt), nil
+}
+
+func (g *fileRegistry) extensionType(xt protoreflect.ExtensionType) protoreflect.ExtensionType {
+	actual, _ := g.extensions.LoadOrStore(xt.TypeDescriptor().FullName(), xt)
+	return actual.(protoreflect.ExtensionType)
+}
@@ -0,0 +1,102 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// registerExtensionTestFiles registers, like a backend binary would:
+// exttest/options.proto declaring the field option exttest.label,
+// exttest/audit.proto declaring the method option exttest.audited (imported
+// by no service) and exttest/service.proto using exttest.label
+func registerExtensionTestFiles(t *testing.T) {
+	t.Helper()
+	registerTestFilesMu.Lock()
+	defer registerTestFilesMu.Unlock()
+	if _, err := protoregistry.GlobalFiles.FindFileByPath("exttest/service.proto"); err == nil {
+		return
+	}
+
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	register := func(fdp *descriptorpb.FileDescriptorProto) protoreflect.FileDescriptor {
+		fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+		require.NoError(t, err)
+		require.NoError(t, protoregistry.GlobalFiles.RegisterFile(fd))
+		for i := 0; i < fd.Extensions().Len(); i++ {
+			require.NoError(t, protoregistry.GlobalTypes.RegisterExtension(dynamicpb.NewExtensionType(fd.Extensions().Get(i))))
+		}
+		return fd
+	}
+	option := func(file, name, extendee string, number int32, kind descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FileDescriptorProto {
+		return &descriptorpb.FileDescriptorProto{
+			Name:       proto.String(file),
+			Package:    proto.String("exttest"),
+			Dependency: []string{"google/protobuf/descriptor.proto"},
+			Extension: []*descriptorpb.FieldDescriptorProto{{
+				Name: proto.String(name), JsonName: proto.String(name), Number: proto.Int32(number), Label: optional,
+				Type: kind.Enum(), Extendee: proto.String(extendee),
+			}},
+		}
+	}
+
+	options := register(option("exttest/options.proto", "label", ".google.protobuf.FieldOptions", 50001, descriptorpb.FieldDescriptorProto_TYPE_STRING))
+	register(option("exttest/audit.proto", "audited", ".google.protobuf.MethodOptions", 50002, descriptorpb.FieldDescriptorProto_TYPE_BOOL))
+
+	fieldOptions := &descriptorpb.FieldOptions{}
+	proto.SetExtension(fieldOptions, dynamicpb.NewExtensionType(options.Extensions().ByName("label")), "Display name")
+	register(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("exttest/service.proto"),
+		Package:    proto.String("exttest"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"exttest/options.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Item"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name: proto.String("name"), JsonName: proto.String("name"), Number: proto.Int32(1), Label: optional,
+				Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Options: fieldOptions,
+			}},
+		}},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name:   proto.String("Items"),
+			Method: []*descriptorpb.MethodDescriptorProto{{Name: proto.String("Get"), InputType: proto.String(".exttest.Item"), OutputType: proto.String(".exttest.Item")}},
+		}},
+	})
+}
+
+func TestDiscoverMethods_FetchesOptionExtensions(t *testing.T) {
+	registerExtensionTestFiles(t)
+
+	client := newReflectionOnlyClient(t, "exttest.Items")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	methods, err := client.DiscoverMethods(ctx)
+	require.NoError(t, err)
+	require.Len(t, methods, 1)
+	registry := client.registry.Load()
+	require.NotNil(t, registry)
+
+	// The custom option set on the field is readable from the reflected descriptor
+	label, err := registry.FindExtensionByName("exttest.label")
+	require.NoError(t, err)
+	field := methods[0].InputDescriptor.Fields().ByName("name")
+	fieldOptions := field.Options().(*descriptorpb.FieldOptions)
+	require.True(t, proto.HasExtension(fieldOptions, label))
+	assert.Equal(t, "Display name", proto.GetExtension(fieldOptions, label))
+
+	// Extensions no service file imports are fetched through extension requests
+	_, err = registry.FindExtensionByName("exttest.audited")
+	assert.NoError(t, err)
+	_, err = registry.files.FindFileByPath("exttest/audit.proto")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,25 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// CheckServiceHealth 通过标准的 grpc.health.v1 协议查询单个服务的状态
+//
+// 上游未实现健康检查服务时返回错误，由调用方决定如何处理（通常视为状态未知）。
+func (d *serviceDiscoverer) CheckServiceHealth(ctx context.Context, service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+	connManager, _ := d.upstream()
+	conn := connManager.GetConnection()
+	if conn == nil {
+		return healthpb.HealthCheckResponse_UNKNOWN, fmt.Errorf("not connected")
+	}
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		return healthpb.HealthCheckResponse_UNKNOWN, err
+	}
+	return resp.GetStatus(), nil
+}
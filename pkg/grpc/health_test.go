@@ -0,0 +1,37 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestCheckServiceHealth(t *testing.T) {
+	upstream := startTestUpstream(t, "healthtest.v1.EchoService")
+	sd := newTestDiscoverer(t, upstream)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	upstream.health.SetServingStatus("healthtest.v1.EchoService", healthpb.HealthCheckResponse_NOT_SERVING)
+	status, err := sd.CheckServiceHealth(ctx, "healthtest.v1.EchoService")
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status)
+
+	upstream.health.SetServingStatus("healthtest.v1.EchoService", healthpb.HealthCheckResponse_SERVING)
+	status, err = sd.CheckServiceHealth(ctx, "healthtest.v1.EchoService")
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status)
+
+	// Services the health server doesn't know about are reported as errors
+	_, err = sd.CheckServiceHealth(ctx, "healthtest.v1.Unknown")
+	assert.Error(t, err)
+
+	checker, ok := Find[ServiceHealthChecker](sd)
+	require.True(t, ok)
+	assert.Equal(t, sd, checker)
+}
@@ -85,7 +85,7 @@ func TestResolveMessageDescriptor_CrossFileDependencies(t *testing.T) {
 
 	t.Run("ResolveLocalMessage", func(t *testing.T) {
 		// Test resolving a message from the same file
-		desc, err := client.resolveMessageDescriptor("com.example.service.ServiceRequest", serviceFileDescriptor)
+		desc, err := client.newRegistry().resolveMessageDescriptor("com.example.service.ServiceRequest", serviceFileDescriptor)
 		if err != nil {
 			// This might fail with current implementation for cross-file deps
 			// but we want to document the behavior
@@ -99,7 +99,7 @@ func TestResolveMessageDescriptor_CrossFileDependencies(t *testing.T) {
 
 	t.Run("ResolveCrossFileMessage", func(t *testing.T) {
 		// Test resolving a message from a different file (cross-file dependency)
-		desc, err := client.resolveMessageDescriptor("com.example.base.BaseMetadata", serviceFileDescriptor)
+		desc, err := client.newRegistry().resolveMessageDescriptor("com.example.base.BaseMetadata", serviceFileDescriptor)
 		if err != nil {
 			// This documents current limitation - cross-file deps may not work
 			// without proper dependency graph or global registry
@@ -118,7 +118,7 @@ func TestResolveMessageDescriptor_CrossFileDependencies(t *testing.T) {
 	t.Run("GlobalRegistryFallback", func(t *testing.T) {
 		// Test that the global registry fallback works for well-known types
 		// Using google.protobuf.Timestamp as an example
-		desc, err := client.resolveMessageDescriptor("google.protobuf.Timestamp", serviceFileDescriptor)
+		desc, err := client.newRegistry().resolveMessageDescriptor("google.protobuf.Timestamp", serviceFileDescriptor)
 
 		if err != nil {
 			t.Logf("Global registry fallback test - this might fail in test environment: %v", err)
@@ -165,7 +165,7 @@ func TestResolveMessageDescriptor_RealWorldScenario(t *testing.T) {
 
 	t.Run("ResolveLocalMessageWithExternalDep", func(t *testing.T) {
 		// Test resolving local message that has external dependencies
-		desc, err := client.resolveMessageDescriptor("com.example.realtest.UserProfile", testFileDescriptor)
+		desc, err := client.newRegistry().resolveMessageDescriptor("com.example.realtest.UserProfile", testFileDescriptor)
 
 		if err != nil {
 			// Document what happens when external deps are missing
@@ -180,7 +180,7 @@ func TestResolveMessageDescriptor_RealWorldScenario(t *testing.T) {
 
 	t.Run("ResolveWellKnownType", func(t *testing.T) {
 		// Test resolving well-known types directly
-		desc, err := client.resolveMessageDescriptor("google.protobuf.Timestamp", testFileDescriptor)
+		desc, err := client.newRegistry().resolveMessageDescriptor("google.protobuf.Timestamp", testFileDescriptor)
 
 		if err != nil {
 			t.Logf("Well-known type resolution failed in test env: %v", err)
@@ -226,7 +226,7 @@ func TestResolveMessageDescriptor_DocumentCurrentBehavior(t *testing.T) {
 			},
 		}
 
-		desc, err := client.resolveMessageDescriptor("com.example.self.SimpleMessage", selfContainedFile)
+		desc, err := client.newRegistry().resolveMessageDescriptor("com.example.self.SimpleMessage", selfContainedFile)
 		assert.NoError(t, err, "Self-contained messages should resolve successfully")
 		assert.Equal(t, "SimpleMessage", string(desc.Name()))
 		assert.Equal(t, "com.example.self.SimpleMessage", string(desc.FullName()))
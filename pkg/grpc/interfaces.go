@@ -2,10 +2,13 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/aalobaidi/ggRMCP/pkg/config"
 	"github.com/aalobaidi/ggRMCP/pkg/types"
 	grpcLib "google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 // ConnectionManager manages gRPC connections with health checking and reconnection
@@ -40,6 +43,9 @@ type ServiceDiscoverer interface {
 	// GetMethods returns all discovered methods in a flat list
 	GetMethods() []types.MethodInfo
 
+	// GetMethod returns the method exposed under the given tool name
+	GetMethod(toolName string) (types.MethodInfo, bool)
+
 	// InvokeMethodByTool invokes a gRPC method by tool name with optional headers
 	InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error)
 
@@ -56,6 +62,116 @@ type ServiceDiscoverer interface {
 	GetServiceStats() map[string]interface{}
 }
 
+// Unwrapper is implemented by ServiceDiscoverer decorators (record/replay,
+// fault injection, mirroring, ...) to expose the discoverer they wrap
+type Unwrapper interface {
+	// Unwrap returns the wrapped discoverer
+	Unwrap() ServiceDiscoverer
+}
+
+// WarmUpper is implemented by discoverers that can prepare their upstream
+// ahead of the first tool call
+type WarmUpper interface {
+	// WarmUp pre-resolves the message types of the discovered methods and
+	// sends a health RPC over the upstream connection
+	WarmUp(ctx context.Context) error
+}
+
+// Find walks the decorator chain starting at sd and returns the first
+// discoverer implementing T
+func Find[T any](sd ServiceDiscoverer) (T, bool) {
+	for sd != nil {
+		if target, ok := sd.(T); ok {
+			return target, true
+		}
+		unwrapper, ok := sd.(Unwrapper)
+		if !ok {
+			break
+		}
+		sd = unwrapper.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+// UpstreamSwitcher is implemented by discoverers that can atomically move to a
+// new upstream target
+type UpstreamSwitcher interface {
+	// SwitchUpstream connects to and discovers the new target, validates that
+	// it serves every current tool (unless force is set) and then swaps it in
+	SwitchUpstream(ctx context.Context, host string, port int, force bool) (*SwitchResult, error)
+}
+
+// SwitchResult describes the outcome of an upstream switch
+type SwitchResult struct {
+	Previous     string   `json:"previous"`
+	Current      string   `json:"current"`
+	Switched     bool     `json:"switched"`
+	ToolCount    int      `json:"toolCount"`
+	AddedTools   []string `json:"addedTools,omitempty"`
+	RemovedTools []string `json:"removedTools,omitempty"`
+}
+
+// UpstreamRegistrar is implemented by discoverers that serve upstreams
+// registered at runtime next to the primary upstream
+type UpstreamRegistrar interface {
+	// RegisterUpstream connects to and discovers the upstream, then adds all
+	// of its tools at once. Nothing changes when a tool name is already taken.
+	RegisterUpstream(ctx context.Context, spec UpstreamSpec) (*RegisteredUpstream, error)
+
+	// UnregisterUpstream removes the upstream's tools at once. Its connection
+	// is closed after the calls in flight complete.
+	UnregisterUpstream(name string) (*RegisteredUpstream, error)
+
+	// Upstreams returns the registered upstreams in registration order
+	Upstreams() []RegisteredUpstream
+}
+
+// UpstreamSpec describes an upstream to register
+type UpstreamSpec struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+
+	// Prefix is prepended to the upstream's tool names as "<prefix>_"
+	Prefix string `json:"prefix,omitempty"`
+
+	// TLS replaces the TLS settings of the grpc section when set
+	TLS *config.UpstreamTLSConfig `json:"tls,omitempty"`
+}
+
+// RegisteredUpstream describes an upstream registered at runtime
+type RegisteredUpstream struct {
+	Name         string    `json:"name"`
+	Prefix       string    `json:"prefix,omitempty"`
+	Tools        []string  `json:"tools"`
+	RegisteredAt time.Time `json:"registeredAt"`
+}
+
+// ErrUpstreamConflict is returned when an upstream cannot be registered next
+// to the current ones: it is already registered, one of its tool names is
+// taken, or the limit of registered upstreams is reached
+var ErrUpstreamConflict = errors.New("upstream conflicts with the registered upstreams")
+
+// ErrUpstreamNotFound is returned when no upstream with the name is registered
+var ErrUpstreamNotFound = errors.New("upstream is not registered")
+
+// Snapshotter is implemented by discoverers that can export their discovered
+// methods as a snapshot and boot from one instead of discovering services
+type Snapshotter interface {
+	// Snapshot returns a snapshot of the currently discovered methods
+	Snapshot() (*Snapshot, error)
+
+	// LoadSnapshot replaces the discovered methods with those in snapshot
+	LoadSnapshot(snapshot *Snapshot) error
+}
+
+// ServiceHealthChecker is implemented by discoverers that can query the
+// standard grpc.health.v1 status of individual upstream services
+type ServiceHealthChecker interface {
+	// CheckServiceHealth returns the serving status of a fully qualified service
+	CheckServiceHealth(ctx context.Context, service string) (healthpb.HealthCheckResponse_ServingStatus, error)
+}
+
 // ReflectionClient handles gRPC reflection API
 type ReflectionClient interface {
 	// DiscoverMethods discovers all methods using reflection
@@ -71,6 +187,35 @@ type ReflectionClient interface {
 	Close() error
 }
 
+// ProgressiveReflectionClient is implemented by reflection clients that can
+// hand out methods as each file descriptor is resolved
+type ProgressiveReflectionClient interface {
+	// DiscoverMethodsProgressively discovers all methods like DiscoverMethods,
+	// passing each batch to publish as soon as it is available. Services and
+	// methods that could not be discovered are returned as failures
+	DiscoverMethodsProgressively(ctx context.Context, publish func([]types.MethodInfo)) ([]types.MethodInfo, []types.DiscoveryFailure, error)
+}
+
+// DiscoveryReporter is implemented by discoverers that keep a report of their
+// last discovery pass
+type DiscoveryReporter interface {
+	// DiscoveryReport returns the report of the last discovery pass, or nil
+	// before the first pass completed
+	DiscoveryReport() *DiscoveryReport
+}
+
+// DiscoveryReport summarizes a discovery pass, including every service or
+// method that was skipped
+type DiscoveryReport struct {
+	Source         string                   `json:"source"`
+	FallbackReason string                   `json:"fallbackReason,omitempty"`
+	CompletedAt    time.Time                `json:"completedAt"`
+	Duration       string                   `json:"duration"`
+	ServiceCount   int                      `json:"serviceCount"`
+	MethodCount    int                      `json:"methodCount"`
+	Failures       []types.DiscoveryFailure `json:"failures,omitempty"`
+}
+
 // ConnectionManagerConfig contains configuration for connection management
 type ConnectionManagerConfig struct {
 	Host           string          `json:"host"`
@@ -78,6 +223,47 @@ type ConnectionManagerConfig struct {
 	ConnectTimeout time.Duration   `json:"connect_timeout"`
 	KeepAlive      KeepAliveConfig `json:"keep_alive"`
 	MaxMessageSize int             `json:"max_message_size"`
+
+	// Separate receive/send limits; zero falls back to MaxMessageSize
+	MaxRecvMessageSize int `json:"max_recv_message_size"`
+	MaxSendMessageSize int `json:"max_send_message_size"`
+
+	// Compressor used for requests (e.g. "gzip"); empty sends uncompressed.
+	// Compressed responses are decompressed regardless of this setting.
+	Compressor string `json:"compressor,omitempty"`
+
+	// WaitForReady queues calls while the connection is not ready instead
+	// of failing them immediately
+	WaitForReady bool `json:"wait_for_ready"`
+
+	// Per-tool ready modes overriding WaitForReady
+	ToolReadyModes []config.ToolReadyModeConfig `json:"tool_ready_modes,omitempty"`
+
+	// Invocation of server-streaming methods (disabled by default)
+	ServerStreaming config.ServerStreamingConfig `json:"server_streaming"`
+
+	// Bridging of client-streaming and bidirectional methods (disabled by default)
+	BidiStreaming config.BidiStreamingConfig `json:"bidi_streaming"`
+
+	// Authority overrides the :authority of calls (default host:port)
+	Authority string `json:"authority,omitempty"`
+
+	// Proxy URL (http or socks5) or "none"; empty uses HTTPS_PROXY
+	Proxy string `json:"proxy,omitempty"`
+
+	// TLS to the upstream; disabled connects in plaintext
+	TLS config.UpstreamTLSConfig `json:"tls"`
+
+	// CertificateMonitor records the certificates presented in TLS handshakes
+	CertificateMonitor *CertificateMonitor `json:"-"`
+
+	// Dialer replaces the proxy and the default dialer when set
+	Dialer Dialer `json:"-"`
+
+	// Resolver resolves Host:Port to the addresses to connect to; nil dials
+	// Host:Port directly
+	Resolver        Resolver      `json:"-"`
+	ResolveInterval time.Duration `json:"resolve_interval"`
 }
 
 // KeepAliveConfig contains keep-alive settings for gRPC connections
@@ -0,0 +1,65 @@
+package grpc
+
+import (
+	"context"
+	"path"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	grpcLib "google.golang.org/grpc"
+)
+
+// WithToolReadyModes 按工具覆盖上游的 ready 模式（fail_fast / wait_for_ready）
+//
+// 规则按顺序匹配，后面的规则覆盖前面的规则；没有规则匹配的工具使用上游的模式。
+func WithToolReadyModes(rules []config.ToolReadyModeConfig) DiscovererOption {
+	return func(c *ConnectionManagerConfig) {
+		c.ToolReadyModes = rules
+	}
+}
+
+// toolCallOptions 返回调用某个工具时额外使用的调用选项
+func (c ConnectionManagerConfig) toolCallOptions(toolName string) []grpcLib.CallOption {
+	mode := ""
+	for _, rule := range c.ToolReadyModes {
+		if rule.Tool == toolName {
+			mode = rule.Mode
+			continue
+		}
+		if matched, err := path.Match(rule.Tool, toolName); err == nil && matched {
+			mode = rule.Mode
+		}
+	}
+
+	switch mode {
+	case config.ReadyModeWaitForReady:
+		return []grpcLib.CallOption{grpcLib.WaitForReady(true)}
+	case config.ReadyModeFailFast:
+		return []grpcLib.CallOption{grpcLib.WaitForReady(false)}
+	default:
+		return nil
+	}
+}
+
+// toolCallOptions 在读锁保护下返回当前上游调用某个工具的额外调用选项
+func (d *serviceDiscoverer) toolCallOptions(toolName string) []grpcLib.CallOption {
+	d.upstreamMu.RLock()
+	defer d.upstreamMu.RUnlock()
+	return d.connConfig.toolCallOptions(toolName)
+}
+
+// callOptionsKey 是上下文中单次调用选项的键
+type callOptionsKey struct{}
+
+// withCallOptions 将调用选项附加到上下文，由 ReflectionClient.InvokeMethod 传给 gRPC
+func withCallOptions(ctx context.Context, opts ...grpcLib.CallOption) context.Context {
+	if len(opts) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, callOptionsKey{}, opts)
+}
+
+// callOptionsFromContext 返回上下文中附加的调用选项
+func callOptionsFromContext(ctx context.Context) []grpcLib.CallOption {
+	opts, _ := ctx.Value(callOptionsKey{}).([]grpcLib.CallOption)
+	return opts
+}
@@ -0,0 +1,61 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+)
+
+func TestReadyModes_UpstreamUnavailable(t *testing.T) {
+	upstream := startTestUpstream(t, "readytest.v1.EchoService")
+
+	connect := func(opts ...DiscovererOption) ServiceDiscoverer {
+		sd, err := NewServiceDiscoverer("127.0.0.1", upstream.addr.Port, zap.NewNop(), config.DescriptorSetConfig{}, opts...)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = sd.Close() })
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		require.NoError(t, sd.Connect(ctx))
+		require.NoError(t, sd.DiscoverServices(ctx))
+		return sd
+	}
+	failFast := connect()
+	waiting := connect(WithConnection(config.ConnectionConfig{ReadyMode: config.ReadyModeWaitForReady}))
+	perTool := connect(WithToolReadyModes([]config.ToolReadyModeConfig{
+		{Tool: "readytest_v1_*", Mode: config.ReadyModeWaitForReady},
+		{Tool: "readytest_v1_echoservice_fail", Mode: config.ReadyModeFailFast},
+	}))
+	upstream.stop()
+
+	// Calls sent before a client notices the shutdown fail with UNAVAILABLE
+	// regardless of the ready mode; wait until every connection has left READY
+	for _, sd := range []ServiceDiscoverer{failFast, waiting, perTool} {
+		connManager, _ := sd.(*serviceDiscoverer).upstream()
+		conn := connManager.GetConnection()
+		require.Eventually(t, func() bool { return conn.GetState() != connectivity.Ready }, 5*time.Second, 10*time.Millisecond)
+	}
+
+	call := func(sd ServiceDiscoverer, tool string) codes.Code {
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		_, err := sd.InvokeMethodByTool(ctx, nil, tool, `"hi"`)
+		require.Error(t, err)
+		return status.Code(err)
+	}
+
+	// Fail-fast calls error immediately; wait-for-ready calls queue until the deadline
+	assert.Equal(t, codes.Unavailable, call(failFast, "readytest_v1_echoservice_echo"))
+	assert.Equal(t, codes.DeadlineExceeded, call(waiting, "readytest_v1_echoservice_echo"))
+
+	// Later tool rules override earlier ones
+	assert.Equal(t, codes.DeadlineExceeded, call(perTool, "readytest_v1_echoservice_echo"))
+	assert.Equal(t, codes.Unavailable, call(perTool, "readytest_v1_echoservice_fail"))
+}
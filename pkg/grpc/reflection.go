@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/aalobaidi/ggRMCP/pkg/descriptors"
 	"github.com/aalobaidi/ggRMCP/pkg/types"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
@@ -14,9 +16,6 @@ import (
 	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
-	"google.golang.org/protobuf/reflect/protodesc"
-	"google.golang.org/protobuf/reflect/protoreflect"
-	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/dynamicpb"
 )
@@ -36,6 +35,17 @@ type reflectionClient struct {
 	fdCache map[string]*descriptorpb.FileDescriptorProto
 	// mu: 保护 fdCache 的读写锁，确保并发安全
 	mu sync.RWMutex
+
+	// typesCache: 方法全名到 Any 类型解析器的缓存，避免每次调用重新构建
+	typesCache map[string]cachedTypes
+	// typesMu: 保护 typesCache 的读写锁
+	typesMu sync.RWMutex
+
+	// registry: 最近一次发现发布的本上游文件描述符注册表，消息类型只在其中解析，
+	// 不同上游对同名消息的不同定义因此互不影响
+	registry atomic.Pointer[fileRegistry]
+	// versions: 已创建的注册表版本计数
+	versions atomic.Uint64
 }
 
 // NewReflectionClient 创建一个新的反射客户端实例
@@ -49,10 +59,11 @@ type reflectionClient struct {
 // 核心逻辑：初始化反射客户端，包含 ServerReflectionClient 和空的文件描述符缓存
 func NewReflectionClient(conn *grpc.ClientConn, logger *zap.Logger) ReflectionClient {
 	return &reflectionClient{
-		conn:    conn,
-		client:  grpc_reflection_v1alpha.NewServerReflectionClient(conn),
-		logger:  logger,
-		fdCache: make(map[string]*descriptorpb.FileDescriptorProto),
+		conn:       conn,
+		client:     grpc_reflection_v1alpha.NewServerReflectionClient(conn),
+		logger:     logger,
+		fdCache:    make(map[string]*descriptorpb.FileDescriptorProto),
+		typesCache: make(map[string]cachedTypes),
 	}
 }
 
@@ -80,12 +91,22 @@ type SourceLocation = types.SourceLocation
 //
 // 5. 返回扁平化的方法列表给调用者使用
 func (r *reflectionClient) DiscoverMethods(ctx context.Context) ([]types.MethodInfo, error) {
+	methods, _, err := r.DiscoverMethodsProgressively(ctx, nil)
+	return methods, err
+}
+
+// DiscoverMethodsProgressively 与 DiscoverMethods 相同，但每处理完一个文件描述符
+// 就通过 publish 回调交出该文件中的方法，使调用方可以在整个发现过程结束前暴露工具
+//
+// publish 为 nil 时等同于 DiscoverMethods；返回值始终是完整的方法列表，
+// 以及因描述符获取或解析失败而被跳过的服务和方法
+func (r *reflectionClient) DiscoverMethodsProgressively(ctx context.Context, publish func([]types.MethodInfo)) ([]types.MethodInfo, []types.DiscoveryFailure, error) {
 	r.logger.Info("Starting method discovery via gRPC reflection")
 
 	// 通过 Server Reflection 获取服务列表
 	serviceNames, err := r.listServices(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list services: %w", err)
+		return nil, nil, fmt.Errorf("failed to list services: %w", err)
 	}
 
 	r.logger.Info("Found services", zap.Strings("services", serviceNames))
@@ -96,17 +117,24 @@ func (r *reflectionClient) DiscoverMethods(ctx context.Context) ([]types.MethodI
 		zap.Strings("originalServices", serviceNames),
 		zap.Strings("filteredServices", filteredServices))
 
-	// 按文件描述符分组，避免重复查询
-	fileDescriptorMap := make(map[string]*descriptorpb.FileDescriptorProto)
-	serviceToFileMap := make(map[string]string)
+	// 每次发现都在新版本的注册表中解析类型，完成后原子替换上一版本；
+	// 已发布的注册表不再修改，重复发现不会重复注册同一文件
+	registry := r.newRegistry()
+	r.fetchOptionExtensions(ctx, registry)
+
+	// 按文件描述符分组，避免重复处理同一文件
+	processedFiles := make(map[string]bool)
+	var methods []types.MethodInfo
+	var failures []types.DiscoveryFailure
 
-	// 为每个服务获取其文件描述符
+	// 为每个服务获取其文件描述符，首次遇见的文件立即提取方法
 	for _, serviceName := range filteredServices {
 		fileDescriptor, err := r.getFileDescriptorBySymbol(ctx, serviceName)
 		if err != nil {
 			r.logger.Error("Failed to get file descriptor for service",
 				zap.String("service", serviceName),
 				zap.Error(err))
+			failures = append(failures, types.DiscoveryFailure{Service: serviceName, Error: err.Error()})
 			continue
 		}
 
@@ -114,27 +142,29 @@ func (r *reflectionClient) DiscoverMethods(ctx context.Context) ([]types.MethodI
 		if fileName == "" {
 			fileName = serviceName // fallback to service name if no file name
 		}
-
-		// 仅当首次遇见此文件时才添加到映射
-		if _, exists := fileDescriptorMap[fileName]; !exists {
-			fileDescriptorMap[fileName] = fileDescriptor
+		if processedFiles[fileName] {
+			continue
 		}
-		serviceToFileMap[serviceName] = fileName
-	}
+		processedFiles[fileName] = true
 
-	// 从每个文件描述符中提取所有方法
-	var methods []types.MethodInfo
-
-	for fileName, fileDescriptor := range fileDescriptorMap {
 		r.logger.Info("Processing file descriptor", zap.String("file", fileName))
 
-		// 从文件描述符中提取所有方法
-		fileMethods := r.extractMethodsFromFileDescriptor(ctx, fileDescriptor, filteredServices)
+		// 从文件描述符中提取所有方法（同一文件中的其他目标服务一并提取）
+		fileMethods, fileFailures := r.extractMethodsWithFailures(ctx, registry, fileDescriptor, filteredServices)
 		methods = append(methods, fileMethods...)
+		failures = append(failures, fileFailures...)
+		if publish != nil && len(fileMethods) > 0 {
+			publish(fileMethods)
+		}
 	}
 
-	r.logger.Info("Successfully discovered methods", zap.Int("count", len(methods)))
-	return methods, nil
+	r.publishRegistry(registry)
+
+	r.logger.Info("Successfully discovered methods",
+		zap.Int("count", len(methods)),
+		zap.Int("failureCount", len(failures)),
+		zap.Uint64("registryVersion", registry.version))
+	return methods, failures, nil
 }
 
 // listServices 获取 gRPC 服务器上所有可用的服务列表
@@ -210,7 +240,15 @@ func (r *reflectionClient) listServices(ctx context.Context) ([]string, error) {
 // 5. 从每个服务中提取所有方法，并创建方法元数据对象
 // 6. 返回提取的所有方法列表
 func (r *reflectionClient) extractMethodsFromFileDescriptor(ctx context.Context, fileDescriptor *descriptorpb.FileDescriptorProto, targetServices []string) []types.MethodInfo {
+	methods, _ := r.extractMethodsWithFailures(ctx, r.newRegistry(), fileDescriptor, targetServices)
+	return methods
+}
+
+// extractMethodsWithFailures 与 extractMethodsFromFileDescriptor 相同，
+// 但在给定的注册表中解析类型，并同时返回无法创建方法信息而被跳过的方法
+func (r *reflectionClient) extractMethodsWithFailures(ctx context.Context, registry *fileRegistry, fileDescriptor *descriptorpb.FileDescriptorProto, targetServices []string) ([]types.MethodInfo, []types.DiscoveryFailure) {
 	var methods []types.MethodInfo
+	var failures []types.DiscoveryFailure
 
 	// 创建目标服务名称映射，用于快速查询（O(1) 时间复杂度）
 	targetServiceMap := make(map[string]bool)
@@ -240,19 +278,24 @@ func (r *reflectionClient) extractMethodsFromFileDescriptor(ctx context.Context,
 
 		// 从服务中提取所有方法信息
 		for _, method := range service.Method {
-			methodInfo, err := r.createMethodInfoWithServiceContext(ctx, fullServiceName, service, method, fileDescriptor)
+			methodInfo, err := r.createMethodInfoWithServiceContext(ctx, registry, fullServiceName, service, method, fileDescriptor)
 			if err != nil {
 				r.logger.Error("Failed to create method info",
 					zap.String("service", fullServiceName),
 					zap.String("method", method.GetName()),
 					zap.Error(err))
+				failures = append(failures, types.DiscoveryFailure{
+					Service: fullServiceName,
+					Method:  method.GetName(),
+					Error:   err.Error(),
+				})
 				continue
 			}
 			methods = append(methods, methodInfo)
 		}
 	}
 
-	return methods
+	return methods, failures
 }
 
 // getFileDescriptorBySymbol 通过符号名称获取文件描述符
@@ -320,26 +363,50 @@ func (r *reflectionClient) getFileDescriptorBySymbol(ctx context.Context, symbol
 		return nil, fmt.Errorf("no file descriptor found for symbol %s", symbol)
 	}
 
-	// 反序列化文件描述符（从字节数组转换为结构体）
-	var fileDescriptor descriptorpb.FileDescriptorProto
-	if err := proto.Unmarshal(fileDescResp.FileDescriptorProto[0], &fileDescriptor); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal file descriptor: %w", err)
+	// 反序列化并缓存文件描述符（第一个是包含该符号的文件，其余是服务器一并返回的传递依赖）
+	fileDescriptors, err := r.cacheFileDescriptors(fileDescResp.FileDescriptorProto)
+	if err != nil {
+		return nil, err
 	}
+	fileDescriptor := fileDescriptors[0]
 
-	// 将获取的文件描述符缓存，避免后续重复查询
 	r.mu.Lock()
-	r.fdCache[symbol] = &fileDescriptor
-	if fileName := fileDescriptor.GetName(); fileName != "" {
-		r.fdCache[fileName] = &fileDescriptor
-	}
+	r.fdCache[symbol] = fileDescriptor
 	r.mu.Unlock()
 
-	return &fileDescriptor, nil
+	return fileDescriptor, nil
+}
+
+// cacheFileDescriptors 反序列化服务器返回的文件描述符并按文件名缓存
+//
+// 第一个文件总是覆盖缓存中的同名文件，其余依赖只在缓存中没有时写入；
+// 缓存的依赖供注册表解析导入时使用
+func (r *reflectionClient) cacheFileDescriptors(raw [][]byte) ([]*descriptorpb.FileDescriptorProto, error) {
+	fileDescriptors := make([]*descriptorpb.FileDescriptorProto, 0, len(raw))
+	for _, data := range raw {
+		var fd descriptorpb.FileDescriptorProto
+		if err := proto.Unmarshal(data, &fd); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal file descriptor: %w", err)
+		}
+		fileDescriptors = append(fileDescriptors, &fd)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, fd := range fileDescriptors {
+		if fileName := fd.GetName(); fileName != "" {
+			if _, exists := r.fdCache[fileName]; !exists || i == 0 {
+				r.fdCache[fileName] = fd
+			}
+		}
+	}
+	return fileDescriptors, nil
 }
 
 // createMethodInfoWithServiceContext 创建包含服务上下文的方法信息
 // 参数：
 //   - ctx: context.Context - 上下文对象
+//   - registry: *fileRegistry - 本次发现使用的注册表
 //   - serviceName: string - 完整的服务名称
 //   - service: *descriptorpb.ServiceDescriptorProto - 服务描述符
 //   - method: *descriptorpb.MethodDescriptorProto - 方法描述符
@@ -359,8 +426,9 @@ func (r *reflectionClient) getFileDescriptorBySymbol(ctx context.Context, symbol
 // 3. 提取服务级别的选项和描述（可扩展）
 // 4. 解析输入消息描述符，从文件描述符中查询并解析输入类型
 // 5. 解析输出消息描述符，从文件描述符中查询并解析输出类型
-// 6. 返回完整的方法信息对象
-func (r *reflectionClient) createMethodInfoWithServiceContext(ctx context.Context, serviceName string, service *descriptorpb.ServiceDescriptorProto, method *descriptorpb.MethodDescriptorProto, fileDescriptor *descriptorpb.FileDescriptorProto) (types.MethodInfo, error) {
+// 6. 文件带有源码信息时提取服务与方法的注释
+// 7. 返回完整的方法信息对象
+func (r *reflectionClient) createMethodInfoWithServiceContext(ctx context.Context, registry *fileRegistry, serviceName string, service *descriptorpb.ServiceDescriptorProto, method *descriptorpb.MethodDescriptorProto, fileDescriptor *descriptorpb.FileDescriptorProto) (types.MethodInfo, error) {
 	// 创建基础方法信息
 	methodInfo := types.MethodInfo{
 		Name:              method.GetName(),
@@ -382,77 +450,28 @@ func (r *reflectionClient) createMethodInfoWithServiceContext(ctx context.Contex
 	}
 
 	// 解析输入消息描述符
-	inputDescriptor, err := r.resolveMessageDescriptor(method.GetInputType(), fileDescriptor)
+	inputDescriptor, err := registry.resolveMessageDescriptor(method.GetInputType(), fileDescriptor)
 	if err != nil {
 		return types.MethodInfo{}, fmt.Errorf("failed to resolve input descriptor for %s: %w", method.GetInputType(), err)
 	}
 	methodInfo.InputDescriptor = inputDescriptor
 
 	// 解析输出消息描述符
-	outputDescriptor, err := r.resolveMessageDescriptor(method.GetOutputType(), fileDescriptor)
+	outputDescriptor, err := registry.resolveMessageDescriptor(method.GetOutputType(), fileDescriptor)
 	if err != nil {
 		return types.MethodInfo{}, fmt.Errorf("failed to resolve output descriptor for %s: %w", method.GetOutputType(), err)
 	}
 	methodInfo.OutputDescriptor = outputDescriptor
 
-	return methodInfo, nil
-}
-
-// resolveMessageDescriptor 通过类型名和文件描述符解析消息描述符
-// 参数：
-//   - typeName: string - 消息类型名（例如：.package.MessageName）
-//   - fileDescriptor: *descriptorpb.FileDescriptorProto - 包含该消息的文件描述符
-//
-// 返回值：
-//   - protoreflect.MessageDescriptor - 解析后的消息描述符
-//   - error - 解析成功返回 nil，失败返回错误信息
-//
-// 核心逻辑：
-// 1. 移除类型名前面的点前缀（如果有）
-// 2. 使用 protodesc.NewFile 创建 protoreflect 文件描述符：
-//   - 将 FileDescriptorProto 转换为 protoreflect.FileDescriptor
-//   - 使用全局注册表作为依赖解析器
-//
-// 3. 创建临时的文件注册表，用于查询消息描述符
-// 4. 在临时注册表中查询指定类型名的描述符
-// 5. 如果临时注册表查询失败，则回退到全局注册表
-// 6. 验证查询到的描述符确实是消息类型
-// 7. 返回消息描述符
-func (r *reflectionClient) resolveMessageDescriptor(typeName string, fileDescriptor *descriptorpb.FileDescriptorProto) (protoreflect.MessageDescriptor, error) {
-	// 移除类型名前面的点前缀（如果存在）
-	typeName = strings.TrimPrefix(typeName, ".")
-
-	// 使用 protodesc.NewFile 创建 protoreflect 文件描述符
-	// 依赖解析使用全局注册表
-	fileDesc, err := protodesc.NewFile(fileDescriptor, protoregistry.GlobalFiles)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create file descriptor: %w", err)
-	}
-
-	// 创建临时注册表用于查询消息描述符
-	files := &protoregistry.Files{}
-	if regErr := files.RegisterFile(fileDesc); regErr != nil {
-		// 如果注册失败，则使用全局注册表作为备选
-		r.logger.Warn("Failed to register file descriptor, using global registry", zap.Error(regErr))
-	}
-
-	// 在注册表中查询指定类型名的描述符
-	messageDesc, err := files.FindDescriptorByName(protoreflect.FullName(typeName))
-	if err != nil {
-		// 回退到全局注册表进行查询
-		messageDesc, err = protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(typeName))
-		if err != nil {
-			return nil, fmt.Errorf("failed to find message descriptor for %s: %w", typeName, err)
-		}
+	// 服务器注册的描述符带有源码信息（SourceCodeInfo）时，
+	// 像 FileDescriptorSet 一样提取服务与方法的注释
+	if methodDesc := registry.findMethod(methodInfo.FullName); methodDesc != nil {
+		methodInfo.ServiceDescription = descriptors.ExtractComments(methodDesc.Parent())
+		methodInfo.Description = descriptors.ExtractComments(methodDesc)
+		methodInfo.Comments = []string{methodInfo.Description}
 	}
 
-	// 验证查询到的描述符是否为消息类型
-	msgDesc, ok := messageDesc.(protoreflect.MessageDescriptor)
-	if !ok {
-		return nil, fmt.Errorf("descriptor for %s is not a message descriptor", typeName)
-	}
-
-	return msgDesc, nil
+	return methodInfo, nil
 }
 
 // InvokeMethod 动态调用 gRPC 方法（带可选的请求头）
@@ -494,10 +513,11 @@ func (r *reflectionClient) InvokeMethod(ctx context.Context, headers map[string]
 
 	// 1. 创建动态输入消息对象（根据方法的输入描述符）
 	inputMsg := dynamicpb.NewMessage(method.InputDescriptor)
+	resolver := r.methodTypes(method)
 
 	// 2. 将 JSON 输入反序列化到动态消息对象中
 	if inputJSON != "" && inputJSON != "{}" {
-		if err := protojson.Unmarshal([]byte(inputJSON), inputMsg); err != nil {
+		if err := (protojson.UnmarshalOptions{Resolver: resolver}).Unmarshal([]byte(inputJSON), inputMsg); err != nil {
 			return "", fmt.Errorf("failed to parse input JSON: %w", err)
 		}
 	}
@@ -515,16 +535,16 @@ func (r *reflectionClient) InvokeMethod(ctx context.Context, headers map[string]
 		zap.String("grpcMethodName", grpcMethodName),
 		zap.String("originalFullName", method.FullName))
 
-	// 执行实际的 gRPC 调用
-	err := r.conn.Invoke(ctx, grpcMethodName, inputMsg, outputMsg)
+	// 执行实际的 gRPC 调用（附加上下文中按工具配置的调用选项）
+	err := r.conn.Invoke(ctx, grpcMethodName, inputMsg, outputMsg, callOptionsFromContext(ctx)...)
 	if err != nil {
 		return "", fmt.Errorf("gRPC call failed: %w", err)
 	}
 
 	r.logger.Debug("Received output message", zap.String("message", outputMsg.String()))
 
-	// 5. 将输出消息转换为 JSON 格式
-	outputJSON, err := protojson.Marshal(outputMsg)
+	// 5. 将输出消息转换为 JSON 格式（Any 字段按方法相关的类型展开）
+	outputJSON, err := (protojson.MarshalOptions{Resolver: resolver}).Marshal(outputMsg)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal output to JSON: %w", err)
 	}
@@ -1,10 +1,18 @@
 package grpc
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/aalobaidi/ggRMCP/pkg/descriptors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
@@ -54,3 +62,177 @@ func TestGetSimpleServiceName(t *testing.T) {
 		assert.Equal(t, test.expected, result, "Input: %s", test.input)
 	}
 }
+
+// upstreamFiles returns a service file importing shared/book.proto, and a
+// shared/book.proto whose Book message has the given field
+func upstreamFiles(field string, kind descriptorpb.FieldDescriptorProto_Type) (*descriptorpb.FileDescriptorProto, *descriptorpb.FileDescriptorProto) {
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	book := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("shared/book.proto"),
+		Package: proto.String("shared"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name:  proto.String("Book"),
+			Field: []*descriptorpb.FieldDescriptorProto{{Name: proto.String(field), JsonName: proto.String(field), Number: proto.Int32(1), Label: optional, Type: kind.Enum()}},
+		}},
+	}
+	service := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("library/service.proto"),
+		Package:    proto.String("library"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"shared/book.proto", "google/protobuf/timestamp.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("GetBookRequest"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("book"), JsonName: proto.String("book"), Number: proto.Int32(1), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".shared.Book")},
+				{Name: proto.String("as_of"), JsonName: proto.String("asOf"), Number: proto.Int32(2), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".google.protobuf.Timestamp")},
+			},
+		}},
+	}
+	return service, book
+}
+
+func TestResolveMessageDescriptor_UpstreamIsolation(t *testing.T) {
+	// Two upstreams declare shared.Book differently
+	newClient := func(field string, kind descriptorpb.FieldDescriptorProto_Type) (*reflectionClient, *descriptorpb.FileDescriptorProto) {
+		service, book := upstreamFiles(field, kind)
+		client := &reflectionClient{
+			logger: zap.NewNop(),
+			// Dependencies returned alongside the service file by the server
+			fdCache: map[string]*descriptorpb.FileDescriptorProto{"shared/book.proto": book},
+		}
+		return client, service
+	}
+	a, serviceA := newClient("title", descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	b, serviceB := newClient("pages", descriptorpb.FieldDescriptorProto_TYPE_INT32)
+
+	registryA, registryB := a.newRegistry(), b.newRegistry()
+	requestA, err := registryA.resolveMessageDescriptor(".library.GetBookRequest", serviceA)
+	require.NoError(t, err)
+	requestB, err := registryB.resolveMessageDescriptor(".library.GetBookRequest", serviceB)
+	require.NoError(t, err)
+
+	bookA := requestA.Fields().ByName("book").Message()
+	bookB := requestB.Fields().ByName("book").Message()
+	assert.NotNil(t, bookA.Fields().ByName("title"))
+	assert.Nil(t, bookA.Fields().ByName("pages"))
+	assert.NotNil(t, bookB.Fields().ByName("pages"))
+	assert.Nil(t, bookB.Fields().ByName("title"))
+
+	// Well-known imports missing from the response come from the bundled descriptors
+	assert.Equal(t, protoreflect.FullName("google.protobuf.Timestamp"), requestA.Fields().ByName("as_of").Message().FullName())
+
+	// A type only upstream A declares, outside the method's own files
+	_, other := upstreamFiles("title", descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	other.Name = proto.String("only/a.proto")
+	other.Package = proto.String("only.a")
+	_, err = registryA.resolveMessageDescriptor("only.a.Book", other)
+	require.NoError(t, err)
+	_, err = registryB.resolveMessageDescriptor("only.a.Book", serviceB)
+	assert.Error(t, err)
+	a.publishRegistry(registryA)
+	b.publishRegistry(registryB)
+
+	// Any payloads resolve against the owning upstream only
+	resolverA := a.methodTypes(MethodInfo{FullName: "library.Library.GetBook", InputDescriptor: requestA, OutputDescriptor: requestA})
+	resolverB := b.methodTypes(MethodInfo{FullName: "library.Library.GetBook", InputDescriptor: requestB, OutputDescriptor: requestB})
+	bookType, err := resolverA.FindMessageByURL("type.googleapis.com/shared.Book")
+	require.NoError(t, err)
+	assert.Same(t, bookA.ParentFile(), bookType.Descriptor().ParentFile())
+	_, err = resolverA.FindMessageByName("only.a.Book")
+	assert.NoError(t, err)
+	_, err = resolverB.FindMessageByName("only.a.Book")
+	assert.Error(t, err)
+	_, err = resolverA.FindMessageByName("grpc.health.v1.HealthCheckRequest")
+	assert.Error(t, err, "types linked into the gateway are not visible to upstreams")
+	_, err = resolverA.FindMessageByName("google.protobuf.Duration")
+	assert.NoError(t, err)
+}
+
+func TestPublishRegistry_KeepsNewestVersion(t *testing.T) {
+	client := &reflectionClient{logger: zap.NewNop(), fdCache: make(map[string]*descriptorpb.FileDescriptorProto)}
+	older, newer := client.newRegistry(), client.newRegistry()
+
+	client.publishRegistry(newer)
+	// A discovery that started earlier but finished later does not win
+	client.publishRegistry(older)
+	assert.Same(t, newer, client.registry.Load())
+}
+
+func TestDiscoverMethods_RediscoveryBuildsNewRegistry(t *testing.T) {
+	upstream := startTestUpstream(t, "registrytest.Books")
+	sd := newTestDiscoverer(t, upstream)
+	client := sd.reflectionClient.(*reflectionClient)
+	first := client.registry.Load()
+	require.NotNil(t, first)
+	before := sd.GetMethods()
+	firstFiles := first.files.NumFiles()
+
+	// The file is already registered in the published registry; discovering
+	// again builds the next version instead of registering it a second time
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	methods, failures, err := client.DiscoverMethodsProgressively(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+	assert.Len(t, methods, len(before))
+
+	second := client.registry.Load()
+	assert.Greater(t, second.version, first.version)
+	assert.Equal(t, firstFiles, first.files.NumFiles(), "a published version is never modified")
+	assert.Equal(t, firstFiles, second.files.NumFiles())
+	for _, method := range methods {
+		fd, err := second.files.FindFileByPath(method.InputDescriptor.ParentFile().Path())
+		require.NoError(t, err)
+		assert.Same(t, fd, method.InputDescriptor.ParentFile())
+	}
+}
+
+func TestDiscoverMethods_ExtractsSourceComments(t *testing.T) {
+	registerTestFilesMu.Lock()
+	if _, err := protoregistry.GlobalFiles.FindFileByPath("commenttest/notes.proto"); err != nil {
+		optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+		location := func(comment string, path ...int32) *descriptorpb.SourceCodeInfo_Location {
+			return &descriptorpb.SourceCodeInfo_Location{Path: path, Span: []int32{0, 0, 1}, LeadingComments: proto.String(comment)}
+		}
+		fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+			Name:    proto.String("commenttest/notes.proto"),
+			Package: proto.String("commenttest"),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{{
+				Name:  proto.String("Note"),
+				Field: []*descriptorpb.FieldDescriptorProto{{Name: proto.String("text"), JsonName: proto.String("text"), Number: proto.Int32(1), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()}},
+			}},
+			Service: []*descriptorpb.ServiceDescriptorProto{{
+				Name:   proto.String("Notes"),
+				Method: []*descriptorpb.MethodDescriptorProto{{Name: proto.String("Save"), InputType: proto.String(".commenttest.Note"), OutputType: proto.String(".commenttest.Note")}},
+			}},
+			// Paths: message_type = 4, field = 2, service = 6, method = 2
+			SourceCodeInfo: &descriptorpb.SourceCodeInfo{Location: []*descriptorpb.SourceCodeInfo_Location{
+				location(" A note.\n", 4, 0),
+				location(" The note's text.\n", 4, 0, 2, 0),
+				location(" Stores notes.\n", 6, 0),
+				location(" Saves a note.\n", 6, 0, 2, 0),
+			}},
+		}, protoregistry.GlobalFiles)
+		require.NoError(t, err)
+		require.NoError(t, protoregistry.GlobalFiles.RegisterFile(fd))
+	}
+	registerTestFilesMu.Unlock()
+
+	client := newReflectionOnlyClient(t, "commenttest.Notes")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	methods, err := client.DiscoverMethods(ctx)
+	require.NoError(t, err)
+	require.Len(t, methods, 1)
+
+	method := methods[0]
+	assert.Equal(t, " Saves a note.\n", method.Description)
+	assert.Equal(t, " Stores notes.\n", method.ServiceDescription)
+	assert.Equal(t, []string{" Saves a note.\n"}, method.Comments)
+
+	// Message and field comments reach the schema builder through the descriptors
+	assert.Equal(t, " A note.\n", descriptors.ExtractComments(method.InputDescriptor))
+	assert.Equal(t, " The note's text.\n", descriptors.ExtractComments(method.InputDescriptor.Fields().ByName("text")))
+}
@@ -0,0 +1,178 @@
+package grpc
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aalobaidi/ggRMCP/pkg/descriptors"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// fileRegistry 是单个上游在一次发现中构建的文件描述符注册表
+//
+// 每次发现都构建新版本的注册表，发现完成后原子地替换上一版本（见 publishRegistry），
+// 而不是向共享的注册表追加文件：重复发现不会因同一文件已注册而失败（AlreadyExists），
+// 上游修改了定义时新版本也不会与旧定义冲突。
+// 构建期间的注册与查询由 mu 保护；发布后注册表不再修改，可以无锁并发查询。
+type fileRegistry struct {
+	// version: 注册表版本，同一上游内单调递增
+	version uint64
+	// files: 已注册的文件描述符
+	files *protoregistry.Files
+	// types: files 中声明的类型，用于解析 Any
+	types *dynamicpb.Types
+	// extensions: 扩展全名到扩展类型实例的缓存（见 FindExtensionByName）
+	extensions sync.Map
+	// lookup: 按文件名查找服务器通过 Reflection 返回的文件描述符
+	lookup func(path string) (*descriptorpb.FileDescriptorProto, bool)
+	// mu: 构建期间保护 files 的互斥锁（protoregistry.Files 本身不支持并发注册与查询）
+	mu sync.Mutex
+}
+
+// newRegistry 创建本上游的新版本注册表，依赖从 fdCache 中查找
+func (r *reflectionClient) newRegistry() *fileRegistry {
+	files := new(protoregistry.Files)
+	return &fileRegistry{
+		version: r.versions.Add(1),
+		files:   files,
+		types:   dynamicpb.NewTypes(files),
+		lookup:  r.cachedFile,
+	}
+}
+
+// publishRegistry 发布一次发现构建的注册表，替换上一版本
+//
+// 并发的发现按完成顺序发布，较旧的版本不会覆盖较新的版本
+func (r *reflectionClient) publishRegistry(registry *fileRegistry) {
+	for {
+		current := r.registry.Load()
+		if current != nil && current.version > registry.version {
+			return
+		}
+		if r.registry.CompareAndSwap(current, registry) {
+			if current != nil {
+				r.logger.Debug("Replaced upstream type registry",
+					zap.Uint64("previousVersion", current.version),
+					zap.Uint64("version", registry.version))
+			}
+			return
+		}
+	}
+}
+
+// cachedFile 按文件名返回缓存的文件描述符
+func (r *reflectionClient) cachedFile(path string) (*descriptorpb.FileDescriptorProto, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fd, ok := r.fdCache[path]
+	return fd, ok
+}
+
+// resolveMessageDescriptor 通过类型名和文件描述符解析消息描述符
+// 参数：
+//   - typeName: string - 消息类型名（例如：.package.MessageName）
+//   - fileDescriptor: *descriptorpb.FileDescriptorProto - 包含该消息的文件描述符
+//
+// 返回值：
+//   - protoreflect.MessageDescriptor - 解析后的消息描述符
+//   - error - 解析成功返回 nil，失败返回错误信息
+//
+// 核心逻辑：
+// 1. 移除类型名前面的点前缀（如果有）
+// 2. 将文件及其依赖注册到注册表（见 registerFile）
+// 3. 只在本注册表中查询指定类型名的描述符，不回退到全局注册表，
+// 避免解析到其他上游或网关自身链接的同名类型
+// 4. 验证查询到的描述符确实是消息类型
+// 5. 返回消息描述符
+func (g *fileRegistry) resolveMessageDescriptor(typeName string, fileDescriptor *descriptorpb.FileDescriptorProto) (protoreflect.MessageDescriptor, error) {
+	// 移除类型名前面的点前缀（如果存在）
+	typeName = strings.TrimPrefix(typeName, ".")
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, err := g.registerFile(fileDescriptor, make(map[string]bool)); err != nil {
+		return nil, fmt.Errorf("failed to create file descriptor: %w", err)
+	}
+
+	// 在注册表中查询指定类型名的描述符
+	messageDesc, err := g.files.FindDescriptorByName(protoreflect.FullName(typeName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find message descriptor for %s: %w", typeName, err)
+	}
+
+	// 验证查询到的描述符是否为消息类型
+	msgDesc, ok := messageDesc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("descriptor for %s is not a message descriptor", typeName)
+	}
+
+	return msgDesc, nil
+}
+
+// findMethod 返回注册表中的方法描述符，不存在时返回 nil
+func (g *fileRegistry) findMethod(fullName string) protoreflect.MethodDescriptor {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	desc, err := g.files.FindDescriptorByName(protoreflect.FullName(fullName))
+	if err != nil {
+		return nil
+	}
+	methodDesc, _ := desc.(protoreflect.MethodDescriptor)
+	return methodDesc
+}
+
+// registerFile 将文件描述符及其传递依赖注册到注册表，调用方需持有 mu
+//
+// 依赖按以下顺序查找：
+// 1. 注册表中已有的文件
+// 2. 内置的 google/protobuf 文件：其消息就是生成的类型（如 descriptorpb.FieldOptions），
+// 选项扩展和 Well-Known Types 因此可以直接用于生成代码
+// 3. 服务器通过 Reflection 返回的文件（lookup）
+// 4. 随二进制内置的 google/api 文件
+//
+// 同一路径的文件只注册一次；visiting 用于检测循环导入
+func (g *fileRegistry) registerFile(fdProto *descriptorpb.FileDescriptorProto, visiting map[string]bool) (protoreflect.FileDescriptor, error) {
+	fileName := fdProto.GetName()
+	if fd, err := g.files.FindFileByPath(fileName); err == nil {
+		return fd, nil
+	}
+	if visiting[fileName] {
+		return nil, fmt.Errorf("import cycle through %s", fileName)
+	}
+	visiting[fileName] = true
+
+	for _, dep := range fdProto.GetDependency() {
+		if _, err := g.files.FindFileByPath(dep); err == nil {
+			continue
+		}
+		if strings.HasPrefix(dep, "google/protobuf/") && descriptors.RegisterBundledFile(g.files, dep) {
+			continue
+		}
+		if g.lookup != nil {
+			if depProto, ok := g.lookup(dep); ok {
+				if _, err := g.registerFile(depProto, visiting); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+		// 缺少的依赖留给 protodesc.NewFile 报告（could not resolve import）
+		descriptors.RegisterBundledFile(g.files, dep)
+	}
+
+	fd, err := protodesc.NewFile(g.resolveOptions(fdProto), g.files)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.files.RegisterFile(fd); err != nil {
+		return nil, fmt.Errorf("failed to register %s: %w", fileName, err)
+	}
+	return fd, nil
+}
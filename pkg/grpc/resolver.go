@@ -0,0 +1,280 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/resolver"
+)
+
+// resolverScheme 使用自定义解析器时拨号目标的 scheme
+const resolverScheme = "ggrmcp"
+
+// resolveTimeout 单次解析的超时时间
+const resolveTimeout = 10 * time.Second
+
+// minResolveGap 两次解析之间的最短间隔，避免连接反复失败时频繁查询
+const minResolveGap = time.Second
+
+// roundRobinServiceConfig 解析出多个地址时在它们之间轮询
+const roundRobinServiceConfig = `{"loadBalancingConfig":[{"round_robin":{}}]}`
+
+// Target 是配置中的上游目标（grpc.host / grpc.port、副本、镜像等）
+type Target struct {
+	Host string
+	Port int
+}
+
+// String 返回 host:port 形式的目标
+func (t Target) String() string {
+	return net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+}
+
+// Resolver 将上游目标解析为实际提供服务的地址（host:port）
+//
+// 组织可以实现该接口接入内部的服务发现系统，并通过 RegisterResolver 注册，
+// 无需修改连接管理代码。连接建立后解析器会被定期调用，地址变化会自动生效。
+type Resolver interface {
+	// Resolve 返回当前提供服务的地址列表
+	Resolve(ctx context.Context, target Target) ([]string, error)
+}
+
+// ResolverFunc 将普通函数适配为 Resolver
+type ResolverFunc func(ctx context.Context, target Target) ([]string, error)
+
+// Resolve 调用函数本身
+func (f ResolverFunc) Resolve(ctx context.Context, target Target) ([]string, error) {
+	return f(ctx, target)
+}
+
+// StaticResolver 返回固定的地址列表；列表为空时返回目标本身
+type StaticResolver []string
+
+// Resolve 返回配置的地址
+func (s StaticResolver) Resolve(_ context.Context, target Target) ([]string, error) {
+	if len(s) == 0 {
+		return []string{target.String()}, nil
+	}
+	return append([]string(nil), s...), nil
+}
+
+// DNSResolver 通过 DNS 查询目标主机的所有地址，端口沿用目标端口
+type DNSResolver struct {
+	// Resolver 为 nil 时使用 net.DefaultResolver
+	Resolver *net.Resolver
+}
+
+// Resolve 查询主机的 A/AAAA 记录
+func (d DNSResolver) Resolve(ctx context.Context, target Target) ([]string, error) {
+	r := d.Resolver
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	hosts, err := r.LookupHost(ctx, target.Host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(target.Port)))
+	}
+	sort.Strings(addrs)
+	return addrs, nil
+}
+
+// KubernetesResolver 解析 Kubernetes headless Service 的 Pod 地址
+//
+// 目标主机写作 "service.namespace"（省略命名空间时为 default），通过集群 DNS
+// 查询 service.namespace.svc.<ClusterDomain>，因此无需访问 Kubernetes API。
+type KubernetesResolver struct {
+	// ClusterDomain 集群域名，为空时使用 cluster.local
+	ClusterDomain string
+	// DNS 用于查询完整域名的解析器，为 nil 时使用 DNSResolver
+	DNS Resolver
+}
+
+// Resolve 将目标展开为集群内的完整域名后进行 DNS 查询
+func (k KubernetesResolver) Resolve(ctx context.Context, target Target) ([]string, error) {
+	domain := k.ClusterDomain
+	if domain == "" {
+		domain = "cluster.local"
+	}
+	host := target.Host
+	if !strings.Contains(host, ".svc.") {
+		if !strings.Contains(host, ".") {
+			host += ".default"
+		}
+		host = fmt.Sprintf("%s.svc.%s", host, domain)
+	}
+	dns := k.DNS
+	if dns == nil {
+		dns = DNSResolver{}
+	}
+	return dns.Resolve(ctx, Target{Host: host, Port: target.Port})
+}
+
+var (
+	resolversMu sync.RWMutex
+	resolvers   = map[string]Resolver{
+		"dns": DNSResolver{},
+		"k8s": KubernetesResolver{},
+	}
+)
+
+// RegisterResolver 以名称注册解析器，之后可在 grpc.resolver.name 中引用
+//
+// 应在启动时（例如 init 函数中）调用；同名注册会覆盖已有的解析器。
+func RegisterResolver(name string, r Resolver) {
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+	resolvers[name] = r
+}
+
+// LookupResolver 返回已注册的解析器
+func LookupResolver(name string) (Resolver, bool) {
+	resolversMu.RLock()
+	defer resolversMu.RUnlock()
+	r, ok := resolvers[name]
+	return r, ok
+}
+
+// ResolverFromConfig 根据配置返回解析器；未配置时返回 nil（直接拨号 host:port）
+func ResolverFromConfig(cfg config.ResolverConfig) (Resolver, error) {
+	switch cfg.Name {
+	case "", "passthrough":
+		return nil, nil
+	case "static":
+		return StaticResolver(cfg.Addresses), nil
+	}
+	r, ok := LookupResolver(cfg.Name)
+	if !ok {
+		return nil, fmt.Errorf("unknown resolver %q", cfg.Name)
+	}
+	return r, nil
+}
+
+// resolverBuilder 将 Resolver 适配为 gRPC 的 resolver.Builder
+type resolverBuilder struct {
+	resolver Resolver
+	target   Target
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+// Scheme 返回自定义 scheme
+func (b *resolverBuilder) Scheme() string {
+	return resolverScheme
+}
+
+// Build 启动后台解析循环
+func (b *resolverBuilder) Build(_ resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &resolverWatcher{
+		builder: b,
+		cc:      cc,
+		cancel:  cancel,
+		now:     make(chan struct{}, 1),
+	}
+	go w.run(ctx)
+	return w, nil
+}
+
+// resolverWatcher 定期（以及 gRPC 请求时）重新解析目标并更新连接的地址列表
+type resolverWatcher struct {
+	builder *resolverBuilder
+	cc      resolver.ClientConn
+	cancel  context.CancelFunc
+	now     chan struct{}
+}
+
+func (w *resolverWatcher) run(ctx context.Context) {
+	var last []string
+	for {
+		addrs, err := w.resolve(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			w.builder.logger.Warn("Failed to resolve upstream",
+				zap.String("target", w.builder.target.String()),
+				zap.Error(err))
+			w.cc.ReportError(err)
+		default:
+			if !slices.Equal(addrs, last) {
+				w.builder.logger.Info("Resolved upstream",
+					zap.String("target", w.builder.target.String()),
+					zap.Strings("addresses", addrs))
+				last = addrs
+			}
+			state := resolver.State{Addresses: make([]resolver.Address, 0, len(addrs))}
+			for _, addr := range addrs {
+				state.Addresses = append(state.Addresses, resolver.Address{Addr: addr})
+			}
+			_ = w.cc.UpdateState(state)
+		}
+
+		if !w.wait(ctx) {
+			return
+		}
+	}
+}
+
+// wait 等待下一次解析的时机，上下文结束时返回 false
+func (w *resolverWatcher) wait(ctx context.Context) bool {
+	var tick <-chan time.Time
+	if w.builder.interval > 0 {
+		timer := time.NewTimer(w.builder.interval)
+		defer timer.Stop()
+		tick = timer.C
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-w.now:
+	case <-tick:
+	}
+
+	gap := time.NewTimer(minResolveGap)
+	defer gap.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-gap.C:
+	}
+	return true
+}
+
+func (w *resolverWatcher) resolve(ctx context.Context) ([]string, error) {
+	resolveCtx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+	addrs, err := w.builder.resolver.Resolve(resolveCtx, w.builder.target)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("resolver returned no addresses for %s", w.builder.target)
+	}
+	return addrs, nil
+}
+
+// ResolveNow 请求立即重新解析（例如连接失败后）
+func (w *resolverWatcher) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case w.now <- struct{}{}:
+	default:
+	}
+}
+
+// Close 停止解析循环
+func (w *resolverWatcher) Close() {
+	w.cancel()
+}
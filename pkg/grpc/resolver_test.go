@@ -0,0 +1,102 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestResolver_StaticAddresses(t *testing.T) {
+	upstream := startTestUpstream(t, "resolvertest.v1.EchoService")
+
+	// The configured host does not exist; the resolver supplies the address
+	r, err := ResolverFromConfig(config.ResolverConfig{Name: "static", Addresses: []string{upstream.addr.String()}})
+	require.NoError(t, err)
+	sd, err := NewServiceDiscoverer("echo.internal", 50051, zap.NewNop(), config.DescriptorSetConfig{}, WithResolver(r, 0))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sd.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, sd.Connect(ctx))
+	require.NoError(t, sd.DiscoverServices(ctx))
+
+	out, err := sd.InvokeMethodByTool(ctx, nil, "resolvertest_v1_echoservice_echo", `"hi"`)
+	require.NoError(t, err)
+	assert.Equal(t, `"hi"`, out)
+}
+
+func TestResolver_CustomResolverSpreadsCalls(t *testing.T) {
+	first := startTestUpstream(t, "resolvertest.v1.EchoService")
+	second := startTestUpstream(t, "resolvertest.v1.EchoService")
+
+	var resolved atomic.Value
+	RegisterResolver("resolver-test", ResolverFunc(func(ctx context.Context, target Target) ([]string, error) {
+		resolved.Store(target)
+		return []string{first.addr.String(), second.addr.String()}, nil
+	}))
+	r, err := ResolverFromConfig(config.ResolverConfig{Name: "resolver-test"})
+	require.NoError(t, err)
+
+	sd, err := NewServiceDiscoverer("echo.internal", 7000, zap.NewNop(), config.DescriptorSetConfig{}, WithResolver(r, 0))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sd.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, sd.Connect(ctx))
+	require.NoError(t, sd.DiscoverServices(ctx))
+	assert.Equal(t, Target{Host: "echo.internal", Port: 7000}, resolved.Load())
+
+	// Calls are balanced over every resolved address
+	require.Eventually(t, func() bool {
+		_, err := sd.InvokeMethodByTool(ctx, nil, "resolvertest_v1_echoservice_echo", `"hi"`)
+		require.NoError(t, err)
+		return first.callCount() > 0 && second.callCount() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestResolverFromConfig(t *testing.T) {
+	r, err := ResolverFromConfig(config.ResolverConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, r)
+
+	r, err = ResolverFromConfig(config.ResolverConfig{Name: "dns"})
+	require.NoError(t, err)
+	assert.Equal(t, DNSResolver{}, r)
+
+	_, err = ResolverFromConfig(config.ResolverConfig{Name: "consul"})
+	assert.Error(t, err)
+}
+
+func TestKubernetesResolver(t *testing.T) {
+	var hosts []string
+	k := KubernetesResolver{DNS: ResolverFunc(func(ctx context.Context, target Target) ([]string, error) {
+		hosts = append(hosts, target.Host)
+		return []string{fmt.Sprintf("10.0.0.1:%d", target.Port)}, nil
+	})}
+
+	for _, host := range []string{"echo", "echo.team-a", "echo.team-a.svc.cluster.local"} {
+		addrs, err := k.Resolve(context.Background(), Target{Host: host, Port: 9000})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.1:9000"}, addrs)
+	}
+	assert.Equal(t, []string{
+		"echo.default.svc.cluster.local",
+		"echo.team-a.svc.cluster.local",
+		"echo.team-a.svc.cluster.local",
+	}, hosts)
+}
+
+func TestStaticResolver_Empty(t *testing.T) {
+	addrs, err := StaticResolver(nil).Resolve(context.Background(), Target{Host: "localhost", Port: 50051})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"localhost:50051"}, addrs)
+}
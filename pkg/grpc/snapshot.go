@@ -0,0 +1,227 @@
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// snapshotVersion 快照格式版本，格式不兼容变更时递增
+const snapshotVersion = 1
+
+// Snapshot 是服务发现结果的可移植快照
+//
+// 快照包含所有已发现方法的元数据，以及解析输入/输出消息所需的
+// 文件描述符（序列化的 FileDescriptorSet，包含传递依赖），
+// 因此可以在无法访问 Reflection 的环境（例如隔离网络）中启动网关，
+// 或跳过冷启动时耗时的服务发现。
+type Snapshot struct {
+	Version   int              `json:"version"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Upstream  string           `json:"upstream,omitempty"`
+	Methods   []SnapshotMethod `json:"methods"`
+
+	// Files 序列化的 FileDescriptorSet（JSON 中为 base64）
+	Files []byte `json:"files"`
+}
+
+// SnapshotMethod 是快照中单个方法的元数据
+type SnapshotMethod struct {
+	Name               string   `json:"name"`
+	FullName           string   `json:"fullName"`
+	ToolName           string   `json:"toolName"`
+	ServiceName        string   `json:"serviceName"`
+	ServiceDescription string   `json:"serviceDescription,omitempty"`
+	Description        string   `json:"description,omitempty"`
+	InputType          string   `json:"inputType"`
+	OutputType         string   `json:"outputType"`
+	InputMessage       string   `json:"inputMessage"`
+	OutputMessage      string   `json:"outputMessage"`
+	IsClientStreaming  bool     `json:"isClientStreaming,omitempty"`
+	IsServerStreaming  bool     `json:"isServerStreaming,omitempty"`
+	Comments           []string `json:"comments,omitempty"`
+}
+
+// NewSnapshot 从已发现的方法构建快照
+//
+// 输入/输出消息所在的文件及其传递依赖都会写入快照，
+// 方法按工具名排序，使相同的发现结果生成相同的快照内容。
+func NewSnapshot(methods []types.MethodInfo, upstream string) (*Snapshot, error) {
+	snapshot := &Snapshot{
+		Version:   snapshotVersion,
+		CreatedAt: time.Now().UTC(),
+		Upstream:  upstream,
+		Methods:   make([]SnapshotMethod, 0, len(methods)),
+	}
+
+	fdSet := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+	var addFile func(fd protoreflect.FileDescriptor)
+	addFile = func(fd protoreflect.FileDescriptor) {
+		if fd.IsPlaceholder() || seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		// 依赖先于引用它的文件写入，便于按顺序构建注册表
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			addFile(imports.Get(i).FileDescriptor)
+		}
+		fdSet.File = append(fdSet.File, protodesc.ToFileDescriptorProto(fd))
+	}
+
+	for _, method := range methods {
+		if method.InputDescriptor == nil || method.OutputDescriptor == nil {
+			return nil, fmt.Errorf("method %s has no resolved message descriptors", method.FullName)
+		}
+		addFile(method.InputDescriptor.ParentFile())
+		addFile(method.OutputDescriptor.ParentFile())
+
+		snapshot.Methods = append(snapshot.Methods, SnapshotMethod{
+			Name:               method.Name,
+			FullName:           method.FullName,
+			ToolName:           method.ToolName,
+			ServiceName:        method.ServiceName,
+			ServiceDescription: method.ServiceDescription,
+			Description:        method.Description,
+			InputType:          method.InputType,
+			OutputType:         method.OutputType,
+			InputMessage:       string(method.InputDescriptor.FullName()),
+			OutputMessage:      string(method.OutputDescriptor.FullName()),
+			IsClientStreaming:  method.IsClientStreaming,
+			IsServerStreaming:  method.IsServerStreaming,
+			Comments:           method.Comments,
+		})
+	}
+	sort.Slice(snapshot.Methods, func(i, j int) bool {
+		return snapshot.Methods[i].ToolName < snapshot.Methods[j].ToolName
+	})
+
+	files, err := proto.Marshal(fdSet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal descriptor set: %w", err)
+	}
+	snapshot.Files = files
+
+	return snapshot, nil
+}
+
+// MethodInfos 还原快照中的方法信息，并重新解析输入/输出消息描述符
+func (s *Snapshot) MethodInfos() ([]types.MethodInfo, error) {
+	if s.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+
+	fdSet := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(s.Files, fdSet); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal descriptor set: %w", err)
+	}
+	files, err := protodesc.NewFiles(fdSet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build descriptor registry: %w", err)
+	}
+
+	methods := make([]types.MethodInfo, 0, len(s.Methods))
+	for _, m := range s.Methods {
+		input, err := findMessage(files, m.InputMessage)
+		if err != nil {
+			return nil, fmt.Errorf("method %s: %w", m.FullName, err)
+		}
+		output, err := findMessage(files, m.OutputMessage)
+		if err != nil {
+			return nil, fmt.Errorf("method %s: %w", m.FullName, err)
+		}
+
+		methods = append(methods, types.MethodInfo{
+			Name:               m.Name,
+			FullName:           m.FullName,
+			ToolName:           m.ToolName,
+			ServiceName:        m.ServiceName,
+			ServiceDescription: m.ServiceDescription,
+			Description:        m.Description,
+			InputType:          m.InputType,
+			OutputType:         m.OutputType,
+			InputDescriptor:    input,
+			OutputDescriptor:   output,
+			IsClientStreaming:  m.IsClientStreaming,
+			IsServerStreaming:  m.IsServerStreaming,
+			Comments:           m.Comments,
+		})
+	}
+
+	return methods, nil
+}
+
+// findMessage 在注册表中查找消息描述符
+func findMessage(files *protoregistry.Files, name string) (protoreflect.MessageDescriptor, error) {
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find message %s: %w", name, err)
+	}
+	msg, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("descriptor for %s is not a message descriptor", name)
+	}
+	return msg, nil
+}
+
+// WriteSnapshot 将快照以 JSON 格式写入文件
+func WriteSnapshot(path string, snapshot *Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// ReadSnapshot 从文件读取快照
+func ReadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+// Snapshot 导出当前已发现方法的快照
+func (d *serviceDiscoverer) Snapshot() (*Snapshot, error) {
+	return NewSnapshot(d.GetMethods(), d.upstreamTarget())
+}
+
+// LoadSnapshot 用快照中的方法替换工具缓存，代替 DiscoverServices
+func (d *serviceDiscoverer) LoadSnapshot(snapshot *Snapshot) error {
+	methods, err := snapshot.MethodInfos()
+	if err != nil {
+		return err
+	}
+
+	tools := make(map[string]types.MethodInfo, len(methods))
+	for _, method := range methods {
+		tools[method.ToolName] = method
+	}
+	d.tools.Store(&tools)
+	d.report.Store(newDiscoveryReport("snapshot", methods, nil, time.Now()))
+
+	d.logger.Info("Loaded services from snapshot",
+		zap.Int("methodCount", len(methods)),
+		zap.String("upstream", snapshot.Upstream),
+		zap.Time("createdAt", snapshot.CreatedAt))
+	return nil
+}
@@ -0,0 +1,55 @@
+package grpc
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	upstream := startTestUpstream(t, "snapshottest.v1.EchoService", "snapshottest.v2.OtherService")
+	source := newTestDiscoverer(t, upstream)
+
+	snapshot, err := source.Snapshot()
+	require.NoError(t, err)
+	assert.Equal(t, source.upstreamTarget(), snapshot.Upstream)
+	require.Len(t, snapshot.Methods, 4)
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	require.NoError(t, WriteSnapshot(path, snapshot))
+	loaded, err := ReadSnapshot(path)
+	require.NoError(t, err)
+
+	// A discoverer booted from the snapshot serves the same tools without discovery
+	sd, err := NewServiceDiscoverer("127.0.0.1", upstream.addr.Port, zap.NewNop(), config.DescriptorSetConfig{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sd.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, sd.Connect(ctx))
+	require.NoError(t, sd.(Snapshotter).LoadSnapshot(loaded))
+
+	assert.Equal(t, source.GetMethodCount(), sd.GetMethodCount())
+	method, ok := sd.GetMethod("snapshottest_v2_otherservice_echo")
+	require.True(t, ok)
+	assert.Equal(t, "google.protobuf.StringValue", string(method.InputDescriptor.FullName()))
+
+	out, err := sd.InvokeMethodByTool(ctx, nil, "snapshottest_v1_echoservice_echo", `"hi"`)
+	require.NoError(t, err)
+	assert.Equal(t, `"hi"`, out)
+}
+
+func TestSnapshot_UnsupportedVersion(t *testing.T) {
+	snapshot, err := NewSnapshot(nil, "")
+	require.NoError(t, err)
+	snapshot.Version = snapshotVersion + 1
+
+	_, err = snapshot.MethodInfos()
+	assert.Error(t, err)
+}
@@ -0,0 +1,182 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"go.uber.org/zap"
+	grpcLib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// 流被提前截断的原因
+const (
+	TruncatedDeadline    = "deadline_exceeded"
+	TruncatedMaxMessages = "max_messages"
+)
+
+// StreamResult 是服务端流式方法的聚合结果
+//
+// Truncated 表示流没有正常结束：到达调用期限或消息数上限时，
+// 已收到的消息仍然返回，而不是只返回 DEADLINE_EXCEEDED 错误。
+type StreamResult struct {
+	Messages         []json.RawMessage `json:"messages"`
+	Truncated        bool              `json:"truncated,omitempty"`
+	TruncationReason string            `json:"truncationReason,omitempty"`
+}
+
+// ServerStreamingReflectionClient 由支持服务端流式调用的反射客户端实现
+type ServerStreamingReflectionClient interface {
+	// InvokeServerStream 调用服务端流式方法并聚合收到的消息
+	InvokeServerStream(ctx context.Context, headers map[string]string, method MethodInfo, inputJSON string, cfg config.ServerStreamingConfig) (*StreamResult, error)
+}
+
+// WithServerStreaming 启用服务端流式方法的调用，收到的消息聚合为一个结果
+func WithServerStreaming(cfg config.ServerStreamingConfig) DiscovererOption {
+	return func(c *ConnectionManagerConfig) {
+		c.ServerStreaming = cfg
+	}
+}
+
+// streamObserverKey 是上下文中流消息观察者的键
+type streamObserverKey struct{}
+
+// WithStreamObserver 返回附加了流消息观察者的上下文
+//
+// 服务端流式调用每收到一条消息就以其 JSON 调用 observe，调用方可以在流结束前
+// 把消息逐条转发给客户端；调用结果仍是聚合后的全部消息。
+func WithStreamObserver(ctx context.Context, observe func(message json.RawMessage)) context.Context {
+	return context.WithValue(ctx, streamObserverKey{}, observe)
+}
+
+// StreamObserverFromContext 返回上下文中的流消息观察者，没有时返回 nil
+//
+// 自行实现流式调用的 ServiceDiscoverer 包装器（例如录制回放）可以据此转发消息。
+func StreamObserverFromContext(ctx context.Context) func(json.RawMessage) {
+	observe, _ := ctx.Value(streamObserverKey{}).(func(json.RawMessage))
+	return observe
+}
+
+// serverStreaming 在读锁保护下返回当前上游的服务端流式调用配置
+func (d *serviceDiscoverer) serverStreaming() config.ServerStreamingConfig {
+	d.upstreamMu.RLock()
+	defer d.upstreamMu.RUnlock()
+	return d.connConfig.ServerStreaming
+}
+
+// invokeServerStream 通过支持流式调用的反射客户端调用服务端流式方法，返回聚合结果的 JSON
+func (d *serviceDiscoverer) invokeServerStream(ctx context.Context, client ReflectionClient, headers map[string]string, method MethodInfo, inputJSON string, cfg config.ServerStreamingConfig) (string, error) {
+	streamer, ok := client.(ServerStreamingReflectionClient)
+	if !ok {
+		return "", fmt.Errorf("streaming methods are not supported")
+	}
+	result, err := streamer.InvokeServerStream(ctx, headers, method, inputJSON, cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to invoke method: %w", err)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal stream result: %w", err)
+	}
+	return string(data), nil
+}
+
+// streamDeadline 返回流的截止时间：在调用期限前预留 margin，
+// 使截断后的部分结果仍能在调用期限内返回；剩余时间不足 margin 时预留一半
+func streamDeadline(ctx context.Context, margin time.Duration) (time.Time, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return time.Time{}, false
+	}
+	remaining := time.Until(deadline)
+	if remaining <= margin {
+		margin = remaining / 2
+	}
+	return deadline.Add(-margin), true
+}
+
+// InvokeServerStream 调用服务端流式方法，按顺序收集每条消息的 JSON
+//
+// 流到达截止时间（调用期限减去 deadline_margin）或消息数达到 max_messages 时
+// 停止接收，返回已收到的消息并标记截断原因；其他错误按普通调用失败处理。
+// 上下文中附加了流消息观察者（WithStreamObserver）时，每条消息收到后立即通知观察者。
+func (r *reflectionClient) InvokeServerStream(ctx context.Context, headers map[string]string, method MethodInfo, inputJSON string, cfg config.ServerStreamingConfig) (*StreamResult, error) {
+	for key, value := range headers {
+		ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+	}
+
+	resolver := r.methodTypes(method)
+	inputMsg := dynamicpb.NewMessage(method.InputDescriptor)
+	if inputJSON != "" && inputJSON != "{}" {
+		if err := (protojson.UnmarshalOptions{Resolver: resolver}).Unmarshal([]byte(inputJSON), inputMsg); err != nil {
+			return nil, fmt.Errorf("failed to parse input JSON: %w", err)
+		}
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	if deadline, ok := streamDeadline(ctx, cfg.DeadlineMargin); ok {
+		cancel()
+		streamCtx, cancel = context.WithDeadline(ctx, deadline)
+	}
+	defer cancel()
+
+	grpcMethodName := fmt.Sprintf("/%s/%s", method.FullName[:strings.LastIndex(method.FullName, ".")], method.Name)
+	stream, err := r.conn.NewStream(streamCtx, &grpcLib.StreamDesc{ServerStreams: true}, grpcMethodName, callOptionsFromContext(ctx)...)
+	if err != nil {
+		return nil, fmt.Errorf("gRPC call failed: %w", err)
+	}
+	if err := stream.SendMsg(inputMsg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("gRPC call failed: %w", err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, fmt.Errorf("gRPC call failed: %w", err)
+	}
+
+	observe := StreamObserverFromContext(ctx)
+	result := &StreamResult{Messages: []json.RawMessage{}}
+	for {
+		outputMsg := dynamicpb.NewMessage(method.OutputDescriptor)
+		err := stream.RecvMsg(outputMsg)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// 只有流自己的截止时间到达（调用本身未超时或取消）才返回部分结果
+			if status.Code(err) == codes.DeadlineExceeded && ctx.Err() == nil {
+				result.Truncated = true
+				result.TruncationReason = TruncatedDeadline
+				r.logger.Info("Stream reached its deadline, returning partial results",
+					zap.String("method", method.FullName),
+					zap.Int("messages", len(result.Messages)))
+				break
+			}
+			return nil, fmt.Errorf("gRPC call failed: %w", err)
+		}
+
+		outputJSON, err := (protojson.MarshalOptions{Resolver: resolver}).Marshal(outputMsg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal output to JSON: %w", err)
+		}
+		result.Messages = append(result.Messages, outputJSON)
+		if observe != nil {
+			observe(outputJSON)
+		}
+
+		if cfg.MaxMessages > 0 && len(result.Messages) >= cfg.MaxMessages {
+			result.Truncated = true
+			result.TruncationReason = TruncatedMaxMessages
+			break
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,173 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	grpcLib "google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// startTickerUpstream serves streamtest.Ticker/Count, which streams the
+// numbers 1..n, one every 20ms, for a request "n"
+func startTickerUpstream(t *testing.T) int {
+	t.Helper()
+
+	registerTestFilesMu.Lock()
+	if _, err := protoregistry.GlobalFiles.FindDescriptorByName("streamtest.Ticker"); err != nil {
+		fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+			Name:       proto.String("test/streamtest.proto"),
+			Package:    proto.String("streamtest"),
+			Dependency: []string{"google/protobuf/wrappers.proto"},
+			Syntax:     proto.String("proto3"),
+			Service: []*descriptorpb.ServiceDescriptorProto{{
+				Name: proto.String("Ticker"),
+				Method: []*descriptorpb.MethodDescriptorProto{{
+					Name:            proto.String("Count"),
+					InputType:       proto.String(".google.protobuf.StringValue"),
+					OutputType:      proto.String(".google.protobuf.StringValue"),
+					ServerStreaming: proto.Bool(true),
+				}},
+			}},
+		}, protoregistry.GlobalFiles)
+		require.NoError(t, err)
+		require.NoError(t, protoregistry.GlobalFiles.RegisterFile(fd))
+	}
+	registerTestFilesMu.Unlock()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpcLib.NewServer()
+	srv.RegisterService(&grpcLib.ServiceDesc{
+		ServiceName: "streamtest.Ticker",
+		HandlerType: (*interface{})(nil),
+		Streams: []grpcLib.StreamDesc{{
+			StreamName:    "Count",
+			ServerStreams: true,
+			Handler: func(_ interface{}, stream grpcLib.ServerStream) error {
+				in := &wrapperspb.StringValue{}
+				if err := stream.RecvMsg(in); err != nil {
+					return err
+				}
+				n, _ := strconv.Atoi(in.GetValue())
+				for i := 1; i <= n; i++ {
+					if err := stream.SendMsg(wrapperspb.String(strconv.Itoa(i))); err != nil {
+						return err
+					}
+					select {
+					case <-stream.Context().Done():
+						return stream.Context().Err()
+					case <-time.After(20 * time.Millisecond):
+					}
+				}
+				return nil
+			},
+		}},
+	}, struct{}{})
+	reflection.Register(srv)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	return lis.Addr().(*net.TCPAddr).Port
+}
+
+func TestServerStreaming(t *testing.T) {
+	port := startTickerUpstream(t)
+	const tool = "streamtest_ticker_count"
+
+	newDiscoverer := func(opts ...DiscovererOption) ServiceDiscoverer {
+		sd, err := NewServiceDiscoverer("127.0.0.1", port, zap.NewNop(), config.DescriptorSetConfig{}, opts...)
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		require.NoError(t, sd.Connect(ctx))
+		require.NoError(t, sd.DiscoverServices(ctx))
+		t.Cleanup(func() { _ = sd.Close() })
+		return sd
+	}
+	invoke := func(sd ServiceDiscoverer, timeout time.Duration, input string) StreamResult {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		out, err := sd.InvokeMethodByTool(ctx, nil, tool, input)
+		require.NoError(t, err)
+		var result StreamResult
+		require.NoError(t, json.Unmarshal([]byte(out), &result))
+		return result
+	}
+
+	// Disabled by default
+	_, err := newDiscoverer().InvokeMethodByTool(context.Background(), nil, tool, `"3"`)
+	assert.ErrorContains(t, err, "streaming methods are not supported")
+
+	sd := newDiscoverer(WithServerStreaming(config.ServerStreamingConfig{
+		Enabled:        true,
+		MaxMessages:    5,
+		DeadlineMargin: 100 * time.Millisecond,
+	}))
+
+	t.Run("complete stream", func(t *testing.T) {
+		result := invoke(sd, 5*time.Second, `"3"`)
+		assert.False(t, result.Truncated)
+		require.Len(t, result.Messages, 3)
+		assert.JSONEq(t, `"3"`, string(result.Messages[2]))
+	})
+
+	t.Run("max messages", func(t *testing.T) {
+		result := invoke(sd, 5*time.Second, `"10"`)
+		assert.True(t, result.Truncated)
+		assert.Equal(t, TruncatedMaxMessages, result.TruncationReason)
+		assert.Len(t, result.Messages, 5)
+	})
+
+	t.Run("observer sees each message as it arrives", func(t *testing.T) {
+		var observed []string
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		ctx = WithStreamObserver(ctx, func(message json.RawMessage) {
+			observed = append(observed, string(message))
+		})
+		_, err := sd.InvokeMethodByTool(ctx, nil, tool, `"3"`)
+		require.NoError(t, err)
+		assert.Equal(t, []string{`"1"`, `"2"`, `"3"`}, observed)
+	})
+
+	t.Run("deadline returns partial results", func(t *testing.T) {
+		// The stream stops 100ms before the 130ms call deadline, while the
+		// upstream needs 60ms to send all four messages
+		result := invoke(sd, 130*time.Millisecond, `"4"`)
+		assert.True(t, result.Truncated)
+		assert.Equal(t, TruncatedDeadline, result.TruncationReason)
+		assert.NotEmpty(t, result.Messages)
+		assert.Less(t, len(result.Messages), 4)
+	})
+}
+
+func TestStreamDeadline(t *testing.T) {
+	_, ok := streamDeadline(context.Background(), time.Second)
+	assert.False(t, ok)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	deadline, ok := streamDeadline(ctx, time.Second)
+	require.True(t, ok)
+	assert.InDelta(t, 9*time.Second, time.Until(deadline), float64(100*time.Millisecond))
+
+	// With less time left than the margin, half of it is reserved
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	deadline, _ = streamDeadline(ctx, 5*time.Second)
+	assert.InDelta(t, 500*time.Millisecond, time.Until(deadline), float64(100*time.Millisecond))
+}
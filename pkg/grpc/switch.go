@@ -0,0 +1,142 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"go.uber.org/zap"
+)
+
+// ErrIncompatibleUpstream 表示新上游缺少当前已暴露的工具
+var ErrIncompatibleUpstream = errors.New("upstream is not compatible with the current tool set")
+
+// switchDrainTimeout 切换后旧连接的保留时间，让进行中的调用完成
+const switchDrainTimeout = 30 * time.Second
+
+// SwitchUpstream 原子地将服务发现器切换到新的上游（蓝绿切换）
+//
+// 切换流程：
+// 1. 使用当前连接配置为新目标创建连接管理器并连接
+// 2. 在新连接上发现服务
+// 3. 校验兼容性：当前所有工具名必须在新上游中存在（force 时跳过）
+// 4. 在写锁下同时替换连接管理器、反射客户端和 tools 指针
+// 5. 旧连接在 switchDrainTimeout 后关闭，保证进行中的调用不被打断
+//
+// 任何一步失败都会关闭新连接，当前上游保持不变。
+func (d *serviceDiscoverer) SwitchUpstream(ctx context.Context, host string, port int, force bool) (*SwitchResult, error) {
+	d.upstreamMu.RLock()
+	newConfig := d.connConfig
+	d.upstreamMu.RUnlock()
+	newConfig.Host = host
+	newConfig.Port = port
+
+	result := &SwitchResult{
+		Previous: d.upstreamTarget(),
+		Current:  fmt.Sprintf("%s:%d", host, port),
+	}
+
+	d.logger.Info("Switching upstream",
+		zap.String("from", result.Previous),
+		zap.String("to", result.Current),
+		zap.Bool("force", force))
+
+	// 🔌 连接新上游
+	connManager := NewConnectionManager(newConfig, d.logger)
+	if err := connManager.Connect(ctx); err != nil {
+		return result, fmt.Errorf("failed to connect to new upstream: %w", err)
+	}
+	conn := connManager.GetConnection()
+	if conn == nil {
+		_ = connManager.Close()
+		return result, fmt.Errorf("connection manager returned nil connection")
+	}
+	reflectionClient := NewReflectionClient(conn, d.logger)
+
+	abort := func(err error) (*SwitchResult, error) {
+		_ = reflectionClient.Close()
+		_ = connManager.Close()
+		return result, err
+	}
+
+	// 🔍 通过反射校验新上游实际提供的方法
+	served, err := reflectionClient.DiscoverMethods(ctx)
+	if err != nil && !force {
+		return abort(fmt.Errorf("failed to discover services on new upstream: %w", err))
+	}
+
+	// 📋 构建新的工具集合（优先 FileDescriptorSet 以保留注释）
+	methods, report, err := d.collectMethods(ctx, reflectionClient, nil)
+	if err != nil {
+		return abort(fmt.Errorf("failed to discover services on new upstream: %w", err))
+	}
+	tools := make(map[string]types.MethodInfo, len(methods))
+	for _, method := range methods {
+		tools[method.ToolName] = method
+	}
+
+	servedNames := make(map[string]bool, len(served))
+	for _, method := range served {
+		servedNames[method.ToolName] = true
+	}
+
+	// ✅ 兼容性校验
+	current := d.tools.Load()
+	if current != nil {
+		for name := range *current {
+			_, inNew := tools[name]
+			if !inNew || (served != nil && !servedNames[name]) {
+				result.RemovedTools = append(result.RemovedTools, name)
+			}
+		}
+	}
+	for name := range tools {
+		if current == nil {
+			result.AddedTools = append(result.AddedTools, name)
+			continue
+		}
+		if _, ok := (*current)[name]; !ok {
+			result.AddedTools = append(result.AddedTools, name)
+		}
+	}
+	sort.Strings(result.AddedTools)
+	sort.Strings(result.RemovedTools)
+	result.ToolCount = len(tools)
+
+	if len(result.RemovedTools) > 0 && !force {
+		return abort(fmt.Errorf("%w: missing tools %v", ErrIncompatibleUpstream, result.RemovedTools))
+	}
+
+	// 🔀 原子切换
+	d.upstreamMu.Lock()
+	oldConnManager, oldReflectionClient := d.connManager, d.reflectionClient
+	d.connManager = connManager
+	d.reflectionClient = reflectionClient
+	d.connConfig = newConfig
+	d.tools.Store(&tools)
+	d.upstreamMu.Unlock()
+
+	result.Switched = true
+	d.logger.Info("Upstream switched",
+		zap.String("from", result.Previous),
+		zap.String("to", result.Current),
+		zap.Int("toolCount", result.ToolCount),
+		zap.Strings("added", result.AddedTools),
+		zap.Strings("removed", result.RemovedTools))
+	d.storeReport(report)
+
+	// 🧹 延迟关闭旧连接
+	time.AfterFunc(switchDrainTimeout, func() {
+		if oldReflectionClient != nil {
+			_ = oldReflectionClient.Close()
+		}
+		if oldConnManager != nil {
+			_ = oldConnManager.Close()
+		}
+	})
+
+	return result, nil
+}
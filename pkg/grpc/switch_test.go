@@ -0,0 +1,87 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSwitchUpstream(t *testing.T) {
+	blue := startTestUpstream(t, "switchtest.v1.EchoService")
+	green := startTestUpstream(t, "switchtest.v1.EchoService", "switchtest.v1.ExtraService")
+
+	sd := newTestDiscoverer(t, blue)
+	require.Equal(t, 2, sd.GetMethodCount())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := sd.SwitchUpstream(ctx, "127.0.0.1", green.addr.Port, false)
+	require.NoError(t, err)
+	assert.True(t, result.Switched)
+	assert.Equal(t, 4, result.ToolCount)
+	assert.Equal(t, []string{"switchtest_v1_extraservice_echo", "switchtest_v1_extraservice_fail"}, result.AddedTools)
+	assert.Empty(t, result.RemovedTools)
+
+	out, err := sd.InvokeMethodByTool(ctx, nil, "switchtest_v1_echoservice_echo", `"hi"`)
+	require.NoError(t, err)
+	assert.Equal(t, `"hi"`, out)
+	assert.Equal(t, 0, blue.callCount())
+	assert.Equal(t, 1, green.callCount())
+}
+
+func TestSwitchUpstream_Incompatible(t *testing.T) {
+	blue := startTestUpstream(t, "switchtest.v1.EchoService", "switchtest.v1.ExtraService")
+	green := startTestUpstream(t, "switchtest.v1.EchoService")
+
+	sd := newTestDiscoverer(t, blue)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := sd.SwitchUpstream(ctx, "127.0.0.1", green.addr.Port, false)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrIncompatibleUpstream))
+	assert.False(t, result.Switched)
+	assert.Len(t, result.RemovedTools, 2)
+
+	// The active upstream is untouched
+	_, err = sd.InvokeMethodByTool(ctx, nil, "switchtest_v1_extraservice_echo", `"hi"`)
+	require.NoError(t, err)
+	assert.Equal(t, 1, blue.callCount())
+
+	// Forcing the switch drops the missing tools
+	result, err = sd.SwitchUpstream(ctx, "127.0.0.1", green.addr.Port, true)
+	require.NoError(t, err)
+	assert.True(t, result.Switched)
+	assert.Equal(t, 2, sd.GetMethodCount())
+}
+
+// wrappingDiscoverer mimics a decorator such as the mirror or fault injector
+type wrappingDiscoverer struct {
+	ServiceDiscoverer
+	unwrap bool
+}
+
+func (w wrappingDiscoverer) Unwrap() ServiceDiscoverer {
+	if !w.unwrap {
+		return nil
+	}
+	return w.ServiceDiscoverer
+}
+
+func TestFind(t *testing.T) {
+	sd := newServiceDiscovererWithConnManager(&mockConnectionManager{}, zap.NewNop())
+
+	switcher, ok := Find[UpstreamSwitcher](wrappingDiscoverer{ServiceDiscoverer: sd, unwrap: true})
+	assert.True(t, ok)
+	assert.Same(t, sd, switcher)
+
+	_, ok = Find[UpstreamSwitcher](wrappingDiscoverer{ServiceDiscoverer: sd})
+	assert.False(t, ok)
+}
@@ -0,0 +1,189 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	grpcLib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// testUpstream is a real gRPC server with reflection enabled that serves
+// dynamically registered services, each with an Echo and a Fail method
+type testUpstream struct {
+	addr   *net.TCPAddr
+	health *health.Server
+	server *grpcLib.Server
+
+	mu       sync.Mutex
+	calls    int
+	metadata metadata.MD
+}
+
+var registerTestFilesMu sync.Mutex
+
+// registerTestService registers a file descriptor for service in the global
+// registry so that the reflection service can serve it
+func registerTestService(t *testing.T, service string) {
+	t.Helper()
+	registerTestFilesMu.Lock()
+	defer registerTestFilesMu.Unlock()
+
+	if _, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service)); err == nil {
+		return
+	}
+
+	pkg, name := splitServiceName(service)
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/" + service + ".proto"),
+		Package:    proto.String(pkg),
+		Dependency: []string{"google/protobuf/wrappers.proto"},
+		Syntax:     proto.String("proto3"),
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String(name),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: proto.String("Echo"), InputType: proto.String(".google.protobuf.StringValue"), OutputType: proto.String(".google.protobuf.StringValue")},
+				{Name: proto.String("Fail"), InputType: proto.String(".google.protobuf.StringValue"), OutputType: proto.String(".google.protobuf.StringValue")},
+			},
+		}},
+	}
+
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	require.NoError(t, protoregistry.GlobalFiles.RegisterFile(fd))
+}
+
+// startTestUpstream starts a server exposing the given fully qualified services
+func startTestUpstream(t *testing.T, services ...string) *testUpstream {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	upstream := &testUpstream{addr: lis.Addr().(*net.TCPAddr)}
+	srv := grpcLib.NewServer()
+	for _, service := range services {
+		registerTestService(t, service)
+		srv.RegisterService(upstream.serviceDesc(service), upstream)
+	}
+	reflection.Register(srv)
+	upstream.server = srv
+	upstream.health = health.NewServer()
+	healthpb.RegisterHealthServer(srv, upstream.health)
+
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	return upstream
+}
+
+// newReflectionOnlyClient serves only reflection for the given services,
+// whose files must already be registered globally, and returns a reflection
+// client connected to it
+func newReflectionOnlyClient(t *testing.T, services ...string) *reflectionClient {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpcLib.NewServer()
+	for _, service := range services {
+		srv.RegisterService(&grpcLib.ServiceDesc{ServiceName: service, HandlerType: (*interface{})(nil)}, struct{}{})
+	}
+	reflection.Register(srv)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpcLib.NewClient(lis.Addr().String(), grpcLib.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return NewReflectionClient(conn, zap.NewNop()).(*reflectionClient)
+}
+
+// newTestDiscoverer returns a discoverer connected to upstream with services discovered
+func newTestDiscoverer(t *testing.T, upstream *testUpstream) *serviceDiscoverer {
+	t.Helper()
+
+	sd, err := NewServiceDiscoverer("127.0.0.1", upstream.addr.Port, zap.NewNop(), config.DescriptorSetConfig{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, sd.Connect(ctx))
+	require.NoError(t, sd.DiscoverServices(ctx))
+	t.Cleanup(func() { _ = sd.Close() })
+
+	return sd.(*serviceDiscoverer)
+}
+
+func (u *testUpstream) serviceDesc(service string) *grpcLib.ServiceDesc {
+	handler := func(fail bool) grpcLib.MethodHandler {
+		return func(_ interface{}, ctx context.Context, dec func(interface{}) error, _ grpcLib.UnaryServerInterceptor) (interface{}, error) {
+			in := &wrapperspb.StringValue{}
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			md, _ := metadata.FromIncomingContext(ctx)
+			u.mu.Lock()
+			u.calls++
+			u.metadata = md
+			u.mu.Unlock()
+			if fail {
+				return nil, status.Error(codes.Unavailable, in.GetValue())
+			}
+			return wrapperspb.String(in.GetValue()), nil
+		}
+	}
+
+	return &grpcLib.ServiceDesc{
+		ServiceName: service,
+		HandlerType: (*interface{})(nil),
+		Methods: []grpcLib.MethodDesc{
+			{MethodName: "Echo", Handler: handler(false)},
+			{MethodName: "Fail", Handler: handler(true)},
+		},
+	}
+}
+
+func (u *testUpstream) callCount() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.calls
+}
+
+// stop shuts the server down, leaving connected clients without an upstream
+func (u *testUpstream) stop() {
+	u.server.Stop()
+}
+
+func (u *testUpstream) lastMetadata() metadata.MD {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.metadata
+}
+
+func splitServiceName(service string) (pkg, name string) {
+	for i := len(service) - 1; i >= 0; i-- {
+		if service[i] == '.' {
+			return service[:i], service[i+1:]
+		}
+	}
+	return "", service
+}
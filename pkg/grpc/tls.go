@@ -0,0 +1,207 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// PeerCertificate 一次 TLS 握手中上游出示的证书
+type PeerCertificate struct {
+	// Target 上游目标（host:port）
+	Target string `json:"target"`
+	// Address 握手的对端地址
+	Address string `json:"address"`
+	// Subject 叶子证书的主题
+	Subject string `json:"subject"`
+	// NotAfter 证书链中最早的过期时间
+	NotAfter time.Time `json:"notAfter"`
+	// ExpiresIn 距离过期的秒数，已过期时为负数
+	ExpiresIn int64 `json:"expiresInSeconds"`
+	// Expiring 是否在告警窗口内过期（或已过期）
+	Expiring bool `json:"expiring"`
+}
+
+// CertificateMonitor 记录各上游连接 TLS 握手时出示的证书，用于在证书过期前告警
+//
+// 证书在握手时读取，连接保持期间上游更换的证书要到下次重连才能看到。
+// 所有上游（主上游、副本、镜像、切换目标）可以共享同一个监控器。
+type CertificateMonitor struct {
+	warning time.Duration
+	logger  *zap.Logger
+	now     func() time.Time
+
+	mu    sync.Mutex
+	certs map[string]map[string]PeerCertificate // target -> address -> 证书
+}
+
+// NewCertificateMonitor 创建证书监控器，证书在 warning 内过期时视为即将过期
+func NewCertificateMonitor(warning time.Duration, logger *zap.Logger) *CertificateMonitor {
+	return &CertificateMonitor{
+		warning: warning,
+		logger:  logger.Named("tls"),
+		now:     time.Now,
+		certs:   make(map[string]map[string]PeerCertificate),
+	}
+}
+
+// WithCertificateMonitor 在 TLS 握手时把上游证书记录到监控器（未启用 TLS 时无作用）
+func WithCertificateMonitor(m *CertificateMonitor) DiscovererOption {
+	return func(c *ConnectionManagerConfig) {
+		c.CertificateMonitor = m
+	}
+}
+
+// record 记录一次握手中出示的证书链
+func (m *CertificateMonitor) record(target, address string, chain []*x509.Certificate) {
+	if len(chain) == 0 {
+		return
+	}
+	notAfter := chain[0].NotAfter
+	for _, cert := range chain[1:] {
+		if cert.NotAfter.Before(notAfter) {
+			notAfter = cert.NotAfter
+		}
+	}
+
+	m.mu.Lock()
+	if m.certs[target] == nil {
+		m.certs[target] = make(map[string]PeerCertificate)
+	}
+	m.certs[target][address] = PeerCertificate{
+		Target:   target,
+		Address:  address,
+		Subject:  chain[0].Subject.String(),
+		NotAfter: notAfter,
+	}
+	m.mu.Unlock()
+
+	if remaining := notAfter.Sub(m.now()); remaining < m.warning {
+		m.logger.Warn("Upstream TLS certificate expires soon",
+			zap.String("target", target),
+			zap.String("address", address),
+			zap.String("subject", chain[0].Subject.String()),
+			zap.Time("notAfter", notAfter),
+			zap.Duration("remaining", remaining))
+	}
+}
+
+// forget 删除上游的证书记录（连接关闭后）
+func (m *CertificateMonitor) forget(target string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.certs, target)
+}
+
+// Certificates 返回当前连接的上游证书，按过期时间升序
+func (m *CertificateMonitor) Certificates() []PeerCertificate {
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var certs []PeerCertificate
+	for _, byAddress := range m.certs {
+		for _, cert := range byAddress {
+			remaining := cert.NotAfter.Sub(now)
+			cert.ExpiresIn = int64(remaining / time.Second)
+			cert.Expiring = remaining < m.warning
+			certs = append(certs, cert)
+		}
+	}
+	sort.Slice(certs, func(i, j int) bool {
+		if !certs[i].NotAfter.Equal(certs[j].NotAfter) {
+			return certs[i].NotAfter.Before(certs[j].NotAfter)
+		}
+		return certs[i].Address < certs[j].Address
+	})
+	return certs
+}
+
+// Expiring 返回在告警窗口内过期（或已过期）的上游证书
+func (m *CertificateMonitor) Expiring() []PeerCertificate {
+	var expiring []PeerCertificate
+	for _, cert := range m.Certificates() {
+		if cert.Expiring {
+			expiring = append(expiring, cert)
+		}
+	}
+	return expiring
+}
+
+// monitoredCredentials 在客户端握手成功后把对端证书链记录到监控器
+type monitoredCredentials struct {
+	credentials.TransportCredentials
+	monitor *CertificateMonitor
+	target  string
+}
+
+func (c *monitoredCredentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	conn, authInfo, err := c.TransportCredentials.ClientHandshake(ctx, authority, rawConn)
+	if err != nil {
+		return conn, authInfo, err
+	}
+	if info, ok := authInfo.(credentials.TLSInfo); ok {
+		c.monitor.record(c.target, rawConn.RemoteAddr().String(), info.State.PeerCertificates)
+	}
+	return conn, authInfo, nil
+}
+
+func (c *monitoredCredentials) Clone() credentials.TransportCredentials {
+	return &monitoredCredentials{
+		TransportCredentials: c.TransportCredentials.Clone(),
+		monitor:              c.monitor,
+		target:               c.target,
+	}
+}
+
+// target 返回上游目标（host:port）
+func (c ConnectionManagerConfig) target() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+// transportCredentials 返回连接上游使用的传输凭证：未启用 TLS 时为明文
+//
+// 配置了 CA 文件时只信任其中的证书，否则使用系统根证书；配置了客户端证书时启用双向 TLS。
+func (c ConnectionManagerConfig) transportCredentials() (credentials.TransportCredentials, error) {
+	if !c.TLS.Enabled {
+		return insecure.NewCredentials(), nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: c.TLS.ServerName,
+	}
+	if c.TLS.CAFile != "" {
+		pem, err := os.ReadFile(c.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read upstream CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in upstream CA file %s", c.TLS.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if c.TLS.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLS.CertFile, c.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load upstream client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	creds := credentials.NewTLS(tlsConfig)
+	if c.CertificateMonitor != nil {
+		creds = &monitoredCredentials{TransportCredentials: creds, monitor: c.CertificateMonitor, target: c.target()}
+	}
+	return creds, nil
+}
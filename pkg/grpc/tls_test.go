@@ -0,0 +1,122 @@
+package grpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	grpcLib "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// startTLSUpstream serves an empty gRPC server over TLS with a certificate
+// for 127.0.0.1 expiring after validFor, and returns its port and the path
+// of the CA certificate that signed it
+func startTLSUpstream(t *testing.T, validFor time.Duration) (int, string) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "upstream"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(validFor),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}, caCert, &key.PublicKey, caKey)
+	require.NoError(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0o600))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpcLib.NewServer(grpcLib.Creds(credentials.NewServerTLSFromCert(&tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	})))
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return lis.Addr().(*net.TCPAddr).Port, caFile
+}
+
+func TestCertificateMonitor(t *testing.T) {
+	port, caFile := startTLSUpstream(t, 10*24*time.Hour)
+	monitor := NewCertificateMonitor(30*24*time.Hour, zap.NewNop())
+
+	cfg := ConnectionManagerConfig{Host: "127.0.0.1", Port: port, ConnectTimeout: 5 * time.Second}
+	WithConnection(config.ConnectionConfig{TLS: config.UpstreamTLSConfig{Enabled: true, CAFile: caFile}})(&cfg)
+	WithCertificateMonitor(monitor)(&cfg)
+	cm := NewConnectionManager(cfg, zap.NewNop())
+	require.NoError(t, cm.Connect(context.Background()))
+
+	certs := monitor.Certificates()
+	require.Len(t, certs, 1)
+	assert.Equal(t, cfg.target(), certs[0].Target)
+	assert.Equal(t, "CN=upstream", certs[0].Subject)
+	assert.True(t, certs[0].Expiring)
+	assert.InDelta(t, (10 * 24 * time.Hour).Seconds(), float64(certs[0].ExpiresIn), 60)
+	assert.Equal(t, certs, monitor.Expiring())
+
+	// Outside the warning window the certificate is reported but not expiring
+	monitor.warning = 24 * time.Hour
+	assert.Len(t, monitor.Certificates(), 1)
+	assert.Empty(t, monitor.Expiring())
+
+	// Closing the connection forgets its certificates
+	require.NoError(t, cm.Close())
+	assert.Empty(t, monitor.Certificates())
+}
+
+func TestTransportCredentials(t *testing.T) {
+	_, caFile := startTLSUpstream(t, time.Hour)
+
+	creds, err := ConnectionManagerConfig{}.transportCredentials()
+	require.NoError(t, err)
+	assert.Equal(t, "insecure", creds.Info().SecurityProtocol)
+
+	creds, err = ConnectionManagerConfig{TLS: config.UpstreamTLSConfig{Enabled: true, CAFile: caFile}}.transportCredentials()
+	require.NoError(t, err)
+	assert.Equal(t, "tls", creds.Info().SecurityProtocol)
+
+	_, err = ConnectionManagerConfig{TLS: config.UpstreamTLSConfig{Enabled: true, CAFile: filepath.Join(t.TempDir(), "missing.pem")}}.transportCredentials()
+	assert.ErrorContains(t, err, "failed to read upstream CA file")
+
+	// A CA file without certificates is rejected
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0o600))
+	_, err = ConnectionManagerConfig{TLS: config.UpstreamTLSConfig{Enabled: true, CAFile: notPEM}}.transportCredentials()
+	assert.ErrorContains(t, err, "no certificates found")
+}
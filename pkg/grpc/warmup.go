@@ -0,0 +1,28 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WarmUp 在服务发现后预热上游，使第一次工具调用不必承担冷启动延迟
+//
+// 为每个已发现的方法预先构建类型解析器，并通过 grpc.health.v1 发送一次
+// 健康检查 RPC 以建立连接（包括 TLS 握手）。上游未实现健康检查服务时
+// 连接同样已经建立，不视为错误。
+func (d *serviceDiscoverer) WarmUp(ctx context.Context) error {
+	_, client := d.upstream()
+	if r, ok := client.(*reflectionClient); ok {
+		for _, method := range d.GetMethods() {
+			r.methodTypes(method)
+		}
+	}
+
+	_, err := d.CheckServiceHealth(ctx, "")
+	if status.Code(err) == codes.Unimplemented {
+		return nil
+	}
+	return err
+}
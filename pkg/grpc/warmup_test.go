@@ -0,0 +1,45 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWarmUp(t *testing.T) {
+	// The ticker upstream does not implement grpc.health.v1
+	port := startTickerUpstream(t)
+	discoverer, err := NewServiceDiscoverer("127.0.0.1", port, zap.NewNop(), config.DescriptorSetConfig{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = discoverer.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, discoverer.Connect(ctx))
+	require.NoError(t, discoverer.DiscoverServices(ctx))
+
+	warmUpper, ok := Find[WarmUpper](discoverer)
+	require.True(t, ok)
+	require.NoError(t, warmUpper.WarmUp(ctx))
+
+	sd := discoverer.(*serviceDiscoverer)
+	_, client := sd.upstream()
+	r := client.(*reflectionClient)
+	method, ok := sd.GetMethod("streamtest_ticker_count")
+	require.True(t, ok)
+	r.typesMu.RLock()
+	cached, ok := r.typesCache[method.FullName]
+	r.typesMu.RUnlock()
+	require.True(t, ok, "warm-up pre-resolves message types")
+	assert.Same(t, cached.resolver, r.methodTypes(method))
+
+	// New descriptors (e.g. after rediscovery) replace the cached resolver
+	rediscovered := method
+	rediscovered.OutputDescriptor = method.InputDescriptor.ParentFile().Messages().Get(0)
+	assert.NotSame(t, cached.resolver, r.methodTypes(rediscovered))
+}
@@ -1,75 +1,155 @@
 package headers
 
 import (
+	"fmt"
 	"strings"
+	"sync/atomic"
 
 	"github.com/aalobaidi/ggRMCP/pkg/config"
 )
 
-// Filter handles header filtering based on configuration
+// Filter handles header filtering based on configuration. Its rules can be
+// replaced at runtime with Update; every call sees either the old or the new
+// rule set, never a mix.
 type Filter struct {
-	config config.HeaderForwardingConfig
+	config atomic.Pointer[config.HeaderForwardingConfig]
 }
 
 // NewFilter creates a new header filter with the given configuration
 func NewFilter(config config.HeaderForwardingConfig) *Filter {
-	return &Filter{
-		config: config,
-	}
+	f := &Filter{}
+	f.Update(config)
+	return f
+}
+
+// Update atomically replaces the filter rules
+func (f *Filter) Update(config config.HeaderForwardingConfig) {
+	f.config.Store(&config)
+}
+
+// rules returns the current rule set
+func (f *Filter) rules() *config.HeaderForwardingConfig {
+	return f.config.Load()
+}
+
+// Filter decisions
+const (
+	ActionForward = "forward"
+	ActionDrop    = "drop"
+)
+
+// Decision explains what the filter does with one header and why
+type Decision struct {
+	// Header is the header name as given
+	Header string `json:"header"`
+
+	// Action is ActionForward or ActionDrop
+	Action string `json:"action"`
+
+	// Rule is the configuration entry that decided, e.g. `blocked_headers: "cookie"`;
+	// empty when no entry matched
+	Rule string `json:"rule,omitempty"`
+
+	// Reason describes the decision in words
+	Reason string `json:"reason"`
+
+	// MetadataKey is the gRPC metadata key the header is sent as; gRPC
+	// metadata keys are lowercase, so differently cased names are rewritten
+	MetadataKey string `json:"metadataKey,omitempty"`
 }
 
 // ShouldForward determines if a header should be forwarded based on configuration
 func (f *Filter) ShouldForward(headerName string) bool {
-	if !f.config.Enabled {
-		return false
+	return explain(f.rules(), headerName).Action == ActionForward
+}
+
+// Explain returns the filter decision for a header together with the rule
+// that produced it. Blocked headers take precedence over everything else,
+// followed by unsafe headers, which only AllowUnsafeHeaders lets through.
+func (f *Filter) Explain(headerName string) Decision {
+	return explain(f.rules(), headerName)
+}
+
+func explain(rules *config.HeaderForwardingConfig, headerName string) Decision {
+	decision := Decision{Header: headerName, Action: ActionDrop}
+	if !rules.Enabled {
+		decision.Reason = "header forwarding is disabled"
+		return decision
 	}
 
 	// Normalize header name for comparison if not case sensitive
 	name := headerName
-	if !f.config.CaseSensitive {
+	if !rules.CaseSensitive {
 		name = strings.ToLower(headerName)
 	}
 
 	// Check blocked headers first (takes precedence)
-	for _, blocked := range f.config.BlockedHeaders {
+	for _, blocked := range rules.BlockedHeaders {
 		blockedName := blocked
-		if !f.config.CaseSensitive {
+		if !rules.CaseSensitive {
 			blockedName = strings.ToLower(blocked)
 		}
 		if name == blockedName {
-			return false
+			decision.Rule = fmt.Sprintf("blocked_headers: %q", blocked)
+			decision.Reason = "header is blocked"
+			return decision
 		}
 	}
 
+	// Unsafe headers are dropped even when allowed, unless explicitly permitted
+	if config.IsUnsafeHeader(headerName) && !rules.AllowUnsafeHeaders {
+		decision.Rule = "allow_unsafe_headers: false"
+		decision.Reason = "header is unsafe to forward (hop-by-hop, proxy, cookie or session header)"
+		return decision
+	}
+
+	forward := func(rule, reason string) Decision {
+		decision.Action = ActionForward
+		decision.Rule = rule
+		decision.Reason = reason
+		decision.MetadataKey = strings.ToLower(headerName)
+		return decision
+	}
+
 	// If ForwardAll is enabled, forward unless blocked
-	if f.config.ForwardAll {
-		return true
+	if rules.ForwardAll {
+		return forward("forward_all: true", "all headers that are not blocked are forwarded")
 	}
 
 	// Check allowed headers
-	for _, allowed := range f.config.AllowedHeaders {
+	for _, allowed := range rules.AllowedHeaders {
 		allowedName := allowed
-		if !f.config.CaseSensitive {
+		if !rules.CaseSensitive {
 			allowedName = strings.ToLower(allowed)
 		}
 		if name == allowedName {
-			return true
+			return forward(fmt.Sprintf("allowed_headers: %q", allowed), "header is allowed")
 		}
 	}
 
 	// Not in allowed list and ForwardAll is false
-	return false
+	decision.Reason = "header is not in allowed_headers and forward_all is false"
+	if rules.CaseSensitive {
+		for _, allowed := range rules.AllowedHeaders {
+			if strings.EqualFold(headerName, allowed) {
+				decision.Reason += fmt.Sprintf(" (allowed_headers has %q but matching is case sensitive)", allowed)
+				break
+			}
+		}
+	}
+	return decision
 }
 
 // FilterHeaders filters a map of headers, returning only those that should be forwarded
 func (f *Filter) FilterHeaders(headers map[string]string) map[string]string {
-	if !f.config.Enabled {
+	rules := f.rules()
+	if !rules.Enabled {
 		return make(map[string]string)
 	}
 
 	filtered := make(map[string]string)
 	for name, value := range headers {
-		if f.ShouldForward(name) {
+		if explain(rules, name).Action == ActionForward {
 			filtered[name] = value
 		}
 	}
@@ -79,15 +159,27 @@ func (f *Filter) FilterHeaders(headers map[string]string) map[string]string {
 
 // GetAllowedHeaders returns the list of allowed headers
 func (f *Filter) GetAllowedHeaders() []string {
-	return f.config.AllowedHeaders
+	return f.rules().AllowedHeaders
 }
 
 // GetBlockedHeaders returns the list of blocked headers
 func (f *Filter) GetBlockedHeaders() []string {
-	return f.config.BlockedHeaders
+	return f.rules().BlockedHeaders
+}
+
+// IsDropped reports whether a header is dropped regardless of the allowlist,
+// because it is blocked or unsafe
+func (f *Filter) IsDropped(headerName string) bool {
+	rules := f.rules()
+	for _, blocked := range rules.BlockedHeaders {
+		if strings.EqualFold(headerName, blocked) {
+			return true
+		}
+	}
+	return config.IsUnsafeHeader(headerName) && !rules.AllowUnsafeHeaders
 }
 
 // IsEnabled returns whether header forwarding is enabled
 func (f *Filter) IsEnabled() bool {
-	return f.config.Enabled
+	return f.rules().Enabled
 }
@@ -245,3 +245,124 @@ func TestDefaultConfiguration(t *testing.T) {
 	assert.Contains(t, hf.BlockedHeaders, "host")
 	assert.Contains(t, hf.BlockedHeaders, "content-length")
 }
+
+func TestHeaderFilter_Explain(t *testing.T) {
+	filter := NewFilter(config.HeaderForwardingConfig{
+		Enabled:        true,
+		AllowedHeaders: []string{"Authorization", "cookie"},
+		BlockedHeaders: []string{"cookie"},
+	})
+
+	tests := []struct {
+		header      string
+		action      string
+		rule        string
+		metadataKey string
+	}{
+		{"Authorization", ActionForward, `allowed_headers: "Authorization"`, "authorization"},
+		{"Cookie", ActionDrop, `blocked_headers: "cookie"`, ""},
+		{"X-Unknown", ActionDrop, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			decision := filter.Explain(tt.header)
+			assert.Equal(t, tt.header, decision.Header)
+			assert.Equal(t, tt.action, decision.Action)
+			assert.Equal(t, tt.rule, decision.Rule)
+			assert.Equal(t, tt.metadataKey, decision.MetadataKey)
+			assert.NotEmpty(t, decision.Reason)
+			assert.Equal(t, tt.action == ActionForward, filter.ShouldForward(tt.header))
+		})
+	}
+
+	caseSensitive := NewFilter(config.HeaderForwardingConfig{
+		Enabled:        true,
+		AllowedHeaders: []string{"X-Trace-Id"},
+		CaseSensitive:  true,
+	})
+	decision := caseSensitive.Explain("x-trace-id")
+	assert.Equal(t, ActionDrop, decision.Action)
+	assert.Contains(t, decision.Reason, "case sensitive")
+
+	disabled := NewFilter(config.HeaderForwardingConfig{Enabled: false})
+	assert.Equal(t, "header forwarding is disabled", disabled.Explain("authorization").Reason)
+}
+
+func TestHeaderFilter_UnsafeHeaders(t *testing.T) {
+	unsafe := []string{"Cookie", "Set-Cookie", "Proxy-Authorization", "Proxy-Foo", "Transfer-Encoding", "Mcp-Session-Id", "Connection", "TE"}
+
+	// Unsafe headers are dropped even with forward_all and no blocklist
+	filter := NewFilter(config.HeaderForwardingConfig{
+		Enabled:        true,
+		AllowedHeaders: unsafe,
+		ForwardAll:     true,
+	})
+	for _, name := range unsafe {
+		decision := filter.Explain(name)
+		assert.Equal(t, ActionDrop, decision.Action, name)
+		assert.Equal(t, "allow_unsafe_headers: false", decision.Rule, name)
+		assert.True(t, filter.IsDropped(name), name)
+	}
+	assert.True(t, filter.ShouldForward("X-Trace-Id"))
+	assert.False(t, filter.IsDropped("X-Trace-Id"))
+
+	// The explicit flag lets allowlisted unsafe headers through
+	permissive := NewFilter(config.HeaderForwardingConfig{
+		Enabled:            true,
+		AllowedHeaders:     []string{"proxy-authorization"},
+		AllowUnsafeHeaders: true,
+	})
+	assert.True(t, permissive.ShouldForward("Proxy-Authorization"))
+	assert.False(t, permissive.ShouldForward("Cookie"))
+
+	// The default configuration blocks them explicitly as well
+	defaults := NewFilter(config.Default().GRPC.HeaderForwarding)
+	for _, name := range unsafe {
+		assert.False(t, defaults.ShouldForward(name), name)
+	}
+}
+
+func TestHeaderFilter_Update(t *testing.T) {
+	filter := NewFilter(config.HeaderForwardingConfig{
+		Enabled:        true,
+		AllowedHeaders: []string{"authorization", "x-tenant-id"},
+	})
+	require.True(t, filter.ShouldForward("x-tenant-id"))
+
+	// Tightening a rule takes effect for the next call
+	filter.Update(config.HeaderForwardingConfig{
+		Enabled:        true,
+		AllowedHeaders: []string{"authorization", "x-tenant-id"},
+		BlockedHeaders: []string{"x-tenant-id"},
+	})
+	assert.False(t, filter.ShouldForward("x-tenant-id"))
+	assert.Equal(t, map[string]string{"authorization": "Bearer t"},
+		filter.FilterHeaders(map[string]string{"authorization": "Bearer t", "x-tenant-id": "acme"}))
+	assert.True(t, filter.IsDropped("x-tenant-id"))
+}
+
+func TestHeaderFilter_UpdateConcurrent(t *testing.T) {
+	open := config.HeaderForwardingConfig{Enabled: true, ForwardAll: true}
+	closed := config.HeaderForwardingConfig{Enabled: false}
+	filter := NewFilter(open)
+	headers := map[string]string{"a": "1", "b": "2", "c": "3"}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			if i%2 == 0 {
+				filter.Update(closed)
+			} else {
+				filter.Update(open)
+			}
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		// Each call sees one whole rule set: all headers or none
+		n := len(filter.FilterHeaders(headers))
+		require.True(t, n == 0 || n == len(headers), "mixed rule sets: %d headers", n)
+	}
+	<-done
+}
@@ -0,0 +1,201 @@
+// Package health gates tools on the per-service grpc.health.v1 status of the
+// upstream, so agents are not left repeatedly invoking services that are
+// known to be down.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"go.uber.org/zap"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// checkTimeout bounds a single service health check
+const checkTimeout = 5 * time.Second
+
+// Gate wraps a ServiceDiscoverer and tracks which upstream services report
+// NOT_SERVING. In hide mode their tools are removed from the method list and
+// calls are rejected; in mark mode the gate only reports them.
+type Gate struct {
+	grpc.ServiceDiscoverer
+
+	checker  grpc.ServiceHealthChecker
+	mode     string
+	interval time.Duration
+	logger   *zap.Logger
+
+	mu         sync.RWMutex
+	notServing map[string]bool
+	checkedAt  time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewGate wraps inner; the chain must contain a discoverer that can check
+// service health. Call Start to begin polling.
+func NewGate(inner grpc.ServiceDiscoverer, cfg config.HealthGatingConfig, logger *zap.Logger) (*Gate, error) {
+	checker, ok := grpc.Find[grpc.ServiceHealthChecker](inner)
+	if !ok {
+		return nil, fmt.Errorf("upstream does not support per-service health checks")
+	}
+	return &Gate{
+		ServiceDiscoverer: inner,
+		checker:           checker,
+		mode:              cfg.Mode,
+		interval:          cfg.Interval,
+		logger:            logger.Named("health"),
+		notServing:        make(map[string]bool),
+	}, nil
+}
+
+// Start checks every service once and then keeps polling in the background
+func (g *Gate) Start(ctx context.Context) {
+	g.Refresh(ctx)
+
+	g.stop = make(chan struct{})
+	g.done = make(chan struct{})
+	go func() {
+		defer close(g.done)
+		ticker := time.NewTicker(g.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-g.stop:
+				return
+			case <-ticker.C:
+				g.Refresh(context.Background())
+			}
+		}
+	}()
+}
+
+// Refresh checks the health of every discovered service. Services whose
+// check fails (e.g. no health server upstream) are treated as serving.
+func (g *Gate) Refresh(ctx context.Context) {
+	services := make(map[string]bool)
+	for _, method := range g.ServiceDiscoverer.GetMethods() {
+		services[method.ServiceName] = true
+	}
+
+	notServing := make(map[string]bool)
+	for service := range services {
+		checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		status, err := g.checker.CheckServiceHealth(checkCtx, service)
+		cancel()
+		if err != nil {
+			g.logger.Debug("Service health unavailable", zap.String("service", service), zap.Error(err))
+			continue
+		}
+		if status == healthpb.HealthCheckResponse_NOT_SERVING {
+			notServing[service] = true
+		}
+	}
+
+	g.mu.Lock()
+	previous := g.notServing
+	g.notServing = notServing
+	g.checkedAt = time.Now()
+	g.mu.Unlock()
+
+	for service := range notServing {
+		if !previous[service] {
+			g.logger.Warn("Service is not serving", zap.String("service", service))
+		}
+	}
+	for service := range previous {
+		if !notServing[service] {
+			g.logger.Info("Service is serving again", zap.String("service", service))
+		}
+	}
+}
+
+// IsServing reports whether the service is not known to be NOT_SERVING
+func (g *Gate) IsServing(service string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return !g.notServing[service]
+}
+
+// IsToolServing reports whether the service behind the tool is not known to
+// be NOT_SERVING
+func (g *Gate) IsToolServing(toolName string) bool {
+	method, ok := g.ServiceDiscoverer.GetMethod(toolName)
+	return !ok || g.IsServing(method.ServiceName)
+}
+
+// NotServing returns the services currently reported as NOT_SERVING
+func (g *Gate) NotServing() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	services := make([]string, 0, len(g.notServing))
+	for service := range g.notServing {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services
+}
+
+// GetMethods hides the methods of NOT_SERVING services in hide mode
+func (g *Gate) GetMethods() []types.MethodInfo {
+	methods := g.ServiceDiscoverer.GetMethods()
+	if g.mode != config.HealthGatingHide {
+		return methods
+	}
+	visible := make([]types.MethodInfo, 0, len(methods))
+	for _, method := range methods {
+		if g.IsServing(method.ServiceName) {
+			visible = append(visible, method)
+		}
+	}
+	return visible
+}
+
+// InvokeMethodByTool rejects calls to NOT_SERVING services in hide mode
+func (g *Gate) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	if g.mode == config.HealthGatingHide {
+		if method, ok := g.ServiceDiscoverer.GetMethod(toolName); ok && !g.IsServing(method.ServiceName) {
+			return "", fmt.Errorf("service %s is not serving, retry later", method.ServiceName)
+		}
+	}
+	return g.ServiceDiscoverer.InvokeMethodByTool(ctx, headers, toolName, inputJSON)
+}
+
+// GetServiceStats adds the gating state to the wrapped discoverer's stats
+func (g *Gate) GetServiceStats() map[string]interface{} {
+	stats := g.ServiceDiscoverer.GetServiceStats()
+	if stats == nil {
+		stats = make(map[string]interface{})
+	}
+	g.mu.RLock()
+	checkedAt := g.checkedAt
+	g.mu.RUnlock()
+	stats["health"] = map[string]interface{}{
+		"mode":       g.mode,
+		"notServing": g.NotServing(),
+		"checkedAt":  checkedAt,
+	}
+	return stats
+}
+
+// Unwrap returns the wrapped discoverer
+func (g *Gate) Unwrap() grpc.ServiceDiscoverer {
+	return g.ServiceDiscoverer
+}
+
+// Close stops polling and closes the wrapped discoverer
+func (g *Gate) Close() error {
+	if g.stop != nil {
+		close(g.stop)
+		<-g.done
+		g.stop = nil
+	}
+	return g.ServiceDiscoverer.Close()
+}
@@ -0,0 +1,158 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type stubDiscoverer struct {
+	methods []types.MethodInfo
+
+	mu       sync.Mutex
+	statuses map[string]healthpb.HealthCheckResponse_ServingStatus
+	calls    int
+}
+
+func (s *stubDiscoverer) Connect(ctx context.Context) error          { return nil }
+func (s *stubDiscoverer) DiscoverServices(ctx context.Context) error { return nil }
+func (s *stubDiscoverer) GetMethods() []types.MethodInfo             { return s.methods }
+func (s *stubDiscoverer) HealthCheck(ctx context.Context) error      { return nil }
+func (s *stubDiscoverer) GetMethodCount() int                        { return len(s.methods) }
+func (s *stubDiscoverer) Close() error                               { return nil }
+func (s *stubDiscoverer) GetServiceStats() map[string]interface{}    { return map[string]interface{}{} }
+
+func (s *stubDiscoverer) GetMethod(toolName string) (types.MethodInfo, bool) {
+	for _, method := range s.methods {
+		if method.ToolName == toolName {
+			return method, true
+		}
+	}
+	return types.MethodInfo{}, false
+}
+
+func (s *stubDiscoverer) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	return "ok", nil
+}
+
+func (s *stubDiscoverer) CheckServiceHealth(ctx context.Context, service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status, ok := s.statuses[service]
+	if !ok {
+		return healthpb.HealthCheckResponse_UNKNOWN, errors.New("unknown service")
+	}
+	return status, nil
+}
+
+func (s *stubDiscoverer) setStatus(service string, status healthpb.HealthCheckResponse_ServingStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[service] = status
+}
+
+func newStub() *stubDiscoverer {
+	return &stubDiscoverer{
+		methods: []types.MethodInfo{
+			{ServiceName: "orders.OrderService", ToolName: "orders_orderservice_get"},
+			{ServiceName: "users.UserService", ToolName: "users_userservice_get"},
+			{ServiceName: "legacy.LegacyService", ToolName: "legacy_legacyservice_get"},
+		},
+		statuses: map[string]healthpb.HealthCheckResponse_ServingStatus{
+			"orders.OrderService": healthpb.HealthCheckResponse_NOT_SERVING,
+			"users.UserService":   healthpb.HealthCheckResponse_SERVING,
+		},
+	}
+}
+
+func TestGate_Hide(t *testing.T) {
+	stub := newStub()
+	gate, err := NewGate(stub, config.HealthGatingConfig{Mode: config.HealthGatingHide, Interval: time.Hour}, zap.NewNop())
+	require.NoError(t, err)
+	gate.Refresh(context.Background())
+
+	var tools []string
+	for _, method := range gate.GetMethods() {
+		tools = append(tools, method.ToolName)
+	}
+	// Services without health reporting stay visible
+	assert.Equal(t, []string{"users_userservice_get", "legacy_legacyservice_get"}, tools)
+
+	_, err = gate.InvokeMethodByTool(context.Background(), nil, "orders_orderservice_get", "{}")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "orders.OrderService is not serving")
+	assert.Equal(t, 0, stub.calls)
+
+	// Recovery is picked up on the next check
+	stub.setStatus("orders.OrderService", healthpb.HealthCheckResponse_SERVING)
+	gate.Refresh(context.Background())
+	assert.Len(t, gate.GetMethods(), 3)
+	_, err = gate.InvokeMethodByTool(context.Background(), nil, "orders_orderservice_get", "{}")
+	require.NoError(t, err)
+}
+
+func TestGate_Mark(t *testing.T) {
+	stub := newStub()
+	gate, err := NewGate(stub, config.HealthGatingConfig{Mode: config.HealthGatingMark, Interval: time.Hour}, zap.NewNop())
+	require.NoError(t, err)
+	gate.Refresh(context.Background())
+
+	assert.Len(t, gate.GetMethods(), 3)
+	assert.False(t, gate.IsToolServing("orders_orderservice_get"))
+	assert.True(t, gate.IsToolServing("users_userservice_get"))
+	assert.Equal(t, []string{"orders.OrderService"}, gate.NotServing())
+
+	_, err = gate.InvokeMethodByTool(context.Background(), nil, "orders_orderservice_get", "{}")
+	require.NoError(t, err)
+
+	stats := gate.GetServiceStats()["health"].(map[string]interface{})
+	assert.Equal(t, []string{"orders.OrderService"}, stats["notServing"])
+}
+
+func TestGate_StartAndClose(t *testing.T) {
+	stub := newStub()
+	gate, err := NewGate(stub, config.HealthGatingConfig{Mode: config.HealthGatingHide, Interval: 10 * time.Millisecond}, zap.NewNop())
+	require.NoError(t, err)
+	gate.Start(context.Background())
+	assert.False(t, gate.IsServing("orders.OrderService"))
+
+	stub.setStatus("orders.OrderService", healthpb.HealthCheckResponse_SERVING)
+	assert.Eventually(t, func() bool { return gate.IsServing("orders.OrderService") }, time.Second, 10*time.Millisecond)
+	require.NoError(t, gate.Close())
+}
+
+func TestNewGate_RequiresHealthChecker(t *testing.T) {
+	_, err := NewGate(&plainDiscoverer{stubDiscoverer: newStub()}, config.HealthGatingConfig{Mode: config.HealthGatingHide}, zap.NewNop())
+	assert.Error(t, err)
+}
+
+// plainDiscoverer hides the stub's CheckServiceHealth method
+type plainDiscoverer struct {
+	stubDiscoverer *stubDiscoverer
+}
+
+func (p *plainDiscoverer) Connect(ctx context.Context) error          { return nil }
+func (p *plainDiscoverer) DiscoverServices(ctx context.Context) error { return nil }
+func (p *plainDiscoverer) GetMethods() []types.MethodInfo             { return p.stubDiscoverer.methods }
+func (p *plainDiscoverer) HealthCheck(ctx context.Context) error      { return nil }
+func (p *plainDiscoverer) GetMethodCount() int                        { return 0 }
+func (p *plainDiscoverer) Close() error                               { return nil }
+func (p *plainDiscoverer) GetServiceStats() map[string]interface{}    { return nil }
+func (p *plainDiscoverer) GetMethod(toolName string) (types.MethodInfo, bool) {
+	return p.stubDiscoverer.GetMethod(toolName)
+}
+func (p *plainDiscoverer) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	return "", nil
+}
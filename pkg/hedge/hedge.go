@@ -0,0 +1,207 @@
+// Package hedge implements request hedging on the tool invoke path: a call
+// that is still running after a delay is sent again, and the first successful
+// response wins. Hedged attempts are limited by a budget and restricted to
+// tools that are safe to send twice, so tail latency improves without
+// duplicating side effects or overloading a slow upstream.
+package hedge
+
+import (
+	"context"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"go.uber.org/zap"
+)
+
+// maxBudgetTokens bounds the hedges that can be sent in a burst, e.g. right
+// after startup or after a quiet period
+const maxBudgetTokens = 10
+
+// Stats reports hedging counters. Comparing Wins with Hedges shows whether
+// hedging helps: a hedge that rarely wins only adds load.
+type Stats struct {
+	// Calls eligible for hedging
+	Calls int64 `json:"calls"`
+	// Calls of excluded tools, which are never hedged
+	Excluded int64 `json:"excluded"`
+	// Hedged attempts sent
+	Hedges int64 `json:"hedges"`
+	// Calls answered by a hedged attempt rather than the original one
+	Wins int64 `json:"wins"`
+	// Hedges skipped because the budget was exhausted
+	BudgetExhausted int64 `json:"budgetExhausted"`
+	// Hedge tokens currently available
+	Budget float64 `json:"budget"`
+}
+
+// Hedger wraps a discoverer and hedges calls of eligible tools
+type Hedger struct {
+	grpc.ServiceDiscoverer
+
+	config config.HedgingConfig
+	logger *zap.Logger
+
+	calls           atomic.Int64
+	excluded        atomic.Int64
+	hedges          atomic.Int64
+	wins            atomic.Int64
+	budgetExhausted atomic.Int64
+
+	mu     sync.Mutex
+	tokens float64
+}
+
+// New creates a hedger in front of inner
+func New(inner grpc.ServiceDiscoverer, cfg config.HedgingConfig, logger *zap.Logger) *Hedger {
+	return &Hedger{
+		ServiceDiscoverer: inner,
+		config:            cfg,
+		logger:            logger.Named("hedge"),
+		tokens:            maxBudgetTokens,
+	}
+}
+
+// attempt is the outcome of one attempt of a call
+type attempt struct {
+	result string
+	err    error
+	hedged bool
+}
+
+// InvokeMethodByTool sends the call and, while it is still running after the
+// configured delay, hedged attempts. The first success is returned and the
+// remaining attempts are cancelled; if every attempt fails, the last error is
+// returned.
+func (h *Hedger) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	if !h.hedgeable(toolName) {
+		h.excluded.Add(1)
+		return h.ServiceDiscoverer.InvokeMethodByTool(ctx, headers, toolName, inputJSON)
+	}
+	h.calls.Add(1)
+	h.deposit()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan attempt, h.config.MaxAttempts)
+	launch := func(hedged bool) {
+		go func() {
+			result, err := h.ServiceDiscoverer.InvokeMethodByTool(ctx, headers, toolName, inputJSON)
+			results <- attempt{result: result, err: err, hedged: hedged}
+		}()
+	}
+	launch(false)
+	launched, running := 1, 1
+
+	timer := time.NewTimer(h.config.Delay)
+	defer timer.Stop()
+
+	var last attempt
+	for {
+		select {
+		case a := <-results:
+			running--
+			if a.err == nil {
+				if a.hedged {
+					h.wins.Add(1)
+				}
+				return a.result, nil
+			}
+			last = a
+			if running == 0 {
+				return last.result, last.err
+			}
+		case <-timer.C:
+			if launched >= h.config.MaxAttempts {
+				continue
+			}
+			if !h.withdraw() {
+				h.budgetExhausted.Add(1)
+				h.logger.Debug("Hedge budget exhausted", zap.String("toolName", toolName))
+				continue
+			}
+			h.hedges.Add(1)
+			launch(true)
+			launched++
+			running++
+			timer.Reset(h.config.Delay)
+		}
+	}
+}
+
+// hedgeable reports whether calls of the tool may be hedged; only tools
+// listed in Tools are, since a hedge sends the request twice
+func (h *Hedger) hedgeable(toolName string) bool {
+	if matchAny(h.config.ExcludeTools, toolName) {
+		return false
+	}
+	return matchAny(h.config.Tools, toolName)
+}
+
+func matchAny(patterns []string, toolName string) bool {
+	for _, pattern := range patterns {
+		if pattern == toolName {
+			return true
+		}
+		if matched, err := path.Match(pattern, toolName); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// deposit adds the budget share of one hedgeable call
+func (h *Hedger) deposit() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tokens += h.config.BudgetPercent / 100
+	if h.tokens > maxBudgetTokens {
+		h.tokens = maxBudgetTokens
+	}
+}
+
+// withdraw takes one hedge from the budget if available
+func (h *Hedger) withdraw() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.tokens < 1 {
+		return false
+	}
+	h.tokens--
+	return true
+}
+
+// Stats returns the hedging counters
+func (h *Hedger) Stats() Stats {
+	h.mu.Lock()
+	budget := h.tokens
+	h.mu.Unlock()
+
+	return Stats{
+		Calls:           h.calls.Load(),
+		Excluded:        h.excluded.Load(),
+		Hedges:          h.hedges.Load(),
+		Wins:            h.wins.Load(),
+		BudgetExhausted: h.budgetExhausted.Load(),
+		Budget:          budget,
+	}
+}
+
+// GetServiceStats adds hedging stats to the inner stats
+func (h *Hedger) GetServiceStats() map[string]interface{} {
+	stats := h.ServiceDiscoverer.GetServiceStats()
+	if stats == nil {
+		stats = make(map[string]interface{})
+	}
+	stats["hedging"] = h.Stats()
+	return stats
+}
+
+// Unwrap returns the wrapped discoverer
+func (h *Hedger) Unwrap() grpc.ServiceDiscoverer {
+	return h.ServiceDiscoverer
+}
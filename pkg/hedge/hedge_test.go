@@ -0,0 +1,154 @@
+package hedge
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// stubDiscoverer answers the n-th call after delays[n] (the last delay repeats)
+type stubDiscoverer struct {
+	mu        sync.Mutex
+	delays    []time.Duration
+	calls     int
+	cancelled int
+}
+
+func (s *stubDiscoverer) Connect(ctx context.Context) error          { return nil }
+func (s *stubDiscoverer) DiscoverServices(ctx context.Context) error { return nil }
+func (s *stubDiscoverer) GetMethods() []types.MethodInfo             { return nil }
+func (s *stubDiscoverer) HealthCheck(ctx context.Context) error      { return nil }
+func (s *stubDiscoverer) GetMethod(string) (types.MethodInfo, bool)  { return types.MethodInfo{}, false }
+func (s *stubDiscoverer) Close() error                               { return nil }
+func (s *stubDiscoverer) GetMethodCount() int                        { return 0 }
+func (s *stubDiscoverer) GetServiceStats() map[string]interface{} {
+	return map[string]interface{}{"serviceCount": 1}
+}
+
+func (s *stubDiscoverer) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	s.mu.Lock()
+	n := s.calls
+	s.calls++
+	delay := s.delays[len(s.delays)-1]
+	if n < len(s.delays) {
+		delay = s.delays[n]
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-time.After(delay):
+		return fmt.Sprintf(`{"attempt":%d}`, n), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		s.cancelled++
+		s.mu.Unlock()
+		return "", ctx.Err()
+	}
+}
+
+func (s *stubDiscoverer) counts() (calls, cancelled int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls, s.cancelled
+}
+
+func testConfig() config.HedgingConfig {
+	return config.HedgingConfig{
+		Enabled:       true,
+		Delay:         20 * time.Millisecond,
+		MaxAttempts:   2,
+		BudgetPercent: 10,
+		Tools:         []string{"svc_*"},
+	}
+}
+
+func TestHedger_HedgeWinsAndCancelsOriginal(t *testing.T) {
+	inner := &stubDiscoverer{delays: []time.Duration{time.Second, 0}}
+	h := New(inner, testConfig(), zap.NewNop())
+
+	result, err := h.InvokeMethodByTool(context.Background(), nil, "svc_get", `{}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"attempt":1}`, result)
+
+	assert.Eventually(t, func() bool {
+		_, cancelled := inner.counts()
+		return cancelled == 1
+	}, time.Second, 5*time.Millisecond)
+
+	stats := h.Stats()
+	assert.Equal(t, int64(1), stats.Calls)
+	assert.Equal(t, int64(1), stats.Hedges)
+	assert.Equal(t, int64(1), stats.Wins)
+}
+
+func TestHedger_FastCallsAreNotHedged(t *testing.T) {
+	inner := &stubDiscoverer{delays: []time.Duration{0}}
+	h := New(inner, testConfig(), zap.NewNop())
+
+	_, err := h.InvokeMethodByTool(context.Background(), nil, "svc_get", `{}`)
+	require.NoError(t, err)
+
+	calls, _ := inner.counts()
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, int64(0), h.Stats().Hedges)
+}
+
+func TestHedger_ExcludedToolsAreNeverHedged(t *testing.T) {
+	cfg := testConfig()
+	cfg.ExcludeTools = []string{"svc_create*"}
+	inner := &stubDiscoverer{delays: []time.Duration{50 * time.Millisecond}}
+	h := New(inner, cfg, zap.NewNop())
+
+	for _, tool := range []string{"svc_createorder", "other_get"} {
+		result, err := h.InvokeMethodByTool(context.Background(), nil, tool, `{}`)
+		require.NoError(t, err)
+		assert.NotEmpty(t, result)
+	}
+
+	calls, _ := inner.counts()
+	assert.Equal(t, 2, calls)
+	stats := h.Stats()
+	assert.Equal(t, int64(2), stats.Excluded)
+	assert.Equal(t, int64(0), stats.Hedges)
+}
+
+func TestHedger_UnlistedToolsAreNeverHedged(t *testing.T) {
+	cfg := testConfig()
+	cfg.Tools = nil
+	inner := &stubDiscoverer{delays: []time.Duration{50 * time.Millisecond}}
+	h := New(inner, cfg, zap.NewNop())
+
+	_, err := h.InvokeMethodByTool(context.Background(), nil, "svc_get", `{}`)
+	require.NoError(t, err)
+
+	calls, _ := inner.counts()
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, int64(0), h.Stats().Hedges)
+}
+
+func TestHedger_BudgetLimitsHedges(t *testing.T) {
+	inner := &stubDiscoverer{delays: []time.Duration{30 * time.Millisecond}}
+	cfg := testConfig()
+	cfg.Delay = time.Millisecond
+	h := New(inner, cfg, zap.NewNop())
+
+	// The initial burst allows maxBudgetTokens hedges; later calls only
+	// replenish BudgetPercent of a hedge each
+	for i := 0; i < maxBudgetTokens+5; i++ {
+		_, err := h.InvokeMethodByTool(context.Background(), nil, "svc_get", `{}`)
+		require.NoError(t, err)
+	}
+
+	stats := h.Stats()
+	assert.Equal(t, int64(maxBudgetTokens+1), stats.Hedges)
+	assert.Equal(t, int64(4), stats.BudgetExhausted)
+	assert.Contains(t, h.GetServiceStats(), "hedging")
+}
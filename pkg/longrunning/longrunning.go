@@ -0,0 +1,190 @@
+// Package longrunning waits for google.longrunning operations on the tool
+// invoke path. Methods returning an Operation only start the work; instead
+// of handing the operation envelope to the agent, the gateway polls
+// Operations.GetOperation until the operation is done and returns its
+// unpacked response, or its error as a gRPC status.
+package longrunning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// operationMessage is the message returned by long-running methods
+	operationMessage = "google.longrunning.Operation"
+	// getOperationMethod polls an operation by name
+	getOperationMethod = "google.longrunning.Operations.GetOperation"
+)
+
+// operation is the JSON form of google.longrunning.Operation
+type operation struct {
+	Name     string          `json:"name"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+	Done     bool            `json:"done"`
+	Error    *struct {
+		Code    int32  `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// Poller wraps a discoverer and waits for the operations returned by
+// long-running methods
+type Poller struct {
+	grpc.ServiceDiscoverer
+
+	config config.LongRunningConfig
+	logger *zap.Logger
+}
+
+// New creates a poller in front of inner
+func New(inner grpc.ServiceDiscoverer, cfg config.LongRunningConfig, logger *zap.Logger) *Poller {
+	return &Poller{
+		ServiceDiscoverer: inner,
+		config:            cfg,
+		logger:            logger.Named("longrunning"),
+	}
+}
+
+// InvokeMethodByTool invokes the tool and, when it returns an operation that
+// is not done yet, polls it until it is. The poll interval doubles after each
+// poll. An operation still running after max_wait, or shortly before the
+// call's deadline, is returned as is; so is every operation when the
+// upstream does not expose the Operations service.
+func (p *Poller) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	result, err := p.ServiceDiscoverer.InvokeMethodByTool(ctx, headers, toolName, inputJSON)
+	if err != nil || !p.longRunning(toolName) {
+		return result, err
+	}
+
+	var op operation
+	if err := json.Unmarshal([]byte(result), &op); err != nil {
+		return result, nil
+	}
+
+	if !op.Done {
+		getOperation, ok := p.getOperationTool()
+		if !ok {
+			p.logger.Warn("Upstream does not expose google.longrunning.Operations; returning the pending operation",
+				zap.String("toolName", toolName),
+				zap.String("operation", op.Name))
+			return result, nil
+		}
+
+		deadline := time.Now().Add(p.config.MaxWait)
+		if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+			deadline = ctxDeadline
+		}
+		request, err := json.Marshal(map[string]string{"name": op.Name})
+		if err != nil {
+			return "", err
+		}
+
+		interval := p.config.PollInterval
+		for !op.Done {
+			// Leave room for one more poll before giving up
+			if time.Now().Add(interval).After(deadline) {
+				p.logger.Info("Operation still running, returning it pending",
+					zap.String("toolName", toolName),
+					zap.String("operation", op.Name))
+				return result, nil
+			}
+
+			timer := time.NewTimer(interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return "", ctx.Err()
+			case <-timer.C:
+			}
+
+			result, err = p.ServiceDiscoverer.InvokeMethodByTool(ctx, headers, getOperation, string(request))
+			if err != nil {
+				return "", fmt.Errorf("failed to poll operation %s: %w", op.Name, err)
+			}
+			op = operation{}
+			if err := json.Unmarshal([]byte(result), &op); err != nil {
+				return "", fmt.Errorf("invalid operation returned by GetOperation: %w", err)
+			}
+			p.logger.Debug("Polled operation",
+				zap.String("toolName", toolName),
+				zap.String("operation", op.Name),
+				zap.Bool("done", op.Done),
+				zap.ByteString("metadata", op.Metadata))
+
+			interval = min(interval*2, p.config.MaxPollInterval)
+		}
+	}
+
+	return unpack(op)
+}
+
+// longRunning reports whether the tool returns an operation and is selected
+func (p *Poller) longRunning(toolName string) bool {
+	if len(p.config.Tools) > 0 && !matchAny(p.config.Tools, toolName) {
+		return false
+	}
+	method, ok := p.GetMethod(toolName)
+	return ok && method.OutputDescriptor != nil && method.OutputDescriptor.FullName() == operationMessage
+}
+
+// getOperationTool returns the tool name of Operations.GetOperation
+func (p *Poller) getOperationTool() (string, bool) {
+	for _, method := range p.GetMethods() {
+		if method.FullName == getOperationMethod {
+			return method.ToolName, true
+		}
+	}
+	return "", false
+}
+
+// unpack returns the response of a done operation without its "@type", or
+// its error as a gRPC status error
+func unpack(op operation) (string, error) {
+	if op.Error != nil {
+		return "", status.Errorf(codes.Code(op.Error.Code), "operation %s failed: %s", op.Name, op.Error.Message)
+	}
+	if len(op.Response) == 0 {
+		return "{}", nil
+	}
+
+	var response map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(op.Response))
+	decoder.UseNumber()
+	if err := decoder.Decode(&response); err != nil {
+		return "", fmt.Errorf("invalid operation response: %w", err)
+	}
+	delete(response, "@type")
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(response); err != nil {
+		return "", fmt.Errorf("failed to encode operation response: %w", err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+func matchAny(patterns []string, toolName string) bool {
+	for _, pattern := range patterns {
+		if pattern == toolName {
+			return true
+		}
+		if matched, err := path.Match(pattern, toolName); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
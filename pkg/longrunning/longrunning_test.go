@@ -0,0 +1,173 @@
+package longrunning
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// stubDiscoverer returns the start response for the long-running tool and
+// the polls in order (the last one repeats) for GetOperation
+type stubDiscoverer struct {
+	methods []types.MethodInfo
+	start   string
+	polls   []string
+	polled  []string
+}
+
+func (s *stubDiscoverer) Connect(ctx context.Context) error          { return nil }
+func (s *stubDiscoverer) DiscoverServices(ctx context.Context) error { return nil }
+func (s *stubDiscoverer) GetMethods() []types.MethodInfo             { return s.methods }
+func (s *stubDiscoverer) HealthCheck(ctx context.Context) error      { return nil }
+func (s *stubDiscoverer) Close() error                               { return nil }
+func (s *stubDiscoverer) GetMethodCount() int                        { return len(s.methods) }
+func (s *stubDiscoverer) GetServiceStats() map[string]interface{}    { return nil }
+
+func (s *stubDiscoverer) GetMethod(toolName string) (types.MethodInfo, bool) {
+	for _, method := range s.methods {
+		if method.ToolName == toolName {
+			return method, true
+		}
+	}
+	return types.MethodInfo{}, false
+}
+
+func (s *stubDiscoverer) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	if toolName != "operations_get" {
+		return s.start, nil
+	}
+	s.polled = append(s.polled, inputJSON)
+	return s.polls[min(len(s.polled), len(s.polls))-1], nil
+}
+
+func newStub(t *testing.T, withOperations bool) *stubDiscoverer {
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test/longrunning/operations.proto"),
+		Package: proto.String("google.longrunning"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Operation"), Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("name"), JsonName: proto.String("name"), Number: proto.Int32(1), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+				{Name: proto.String("done"), JsonName: proto.String("done"), Number: proto.Int32(3), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_BOOL.Enum()},
+			}},
+			{Name: proto.String("GetOperationRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("name"), JsonName: proto.String("name"), Number: proto.Int32(1), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+			}},
+		},
+	}, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	messages := fd.Messages()
+
+	stub := &stubDiscoverer{methods: []types.MethodInfo{
+		{
+			ToolName:         "library_createbook",
+			FullName:         "library.Library.CreateBook",
+			InputDescriptor:  messages.ByName("GetOperationRequest"),
+			OutputDescriptor: messages.ByName("Operation"),
+		},
+		{
+			ToolName:         "library_getbook",
+			FullName:         "library.Library.GetBook",
+			InputDescriptor:  messages.ByName("GetOperationRequest"),
+			OutputDescriptor: messages.ByName("GetOperationRequest"),
+		},
+	}}
+	if withOperations {
+		stub.methods = append(stub.methods, types.MethodInfo{
+			ToolName:         "operations_get",
+			FullName:         getOperationMethod,
+			InputDescriptor:  messages.ByName("GetOperationRequest"),
+			OutputDescriptor: messages.ByName("Operation"),
+		})
+	}
+	return stub
+}
+
+func testConfig() config.LongRunningConfig {
+	return config.LongRunningConfig{
+		Enabled:         true,
+		PollInterval:    time.Millisecond,
+		MaxPollInterval: 2 * time.Millisecond,
+		MaxWait:         time.Second,
+	}
+}
+
+func TestPoller_WaitsForResponse(t *testing.T) {
+	stub := newStub(t, true)
+	stub.start = `{"name":"operations/1"}`
+	stub.polls = []string{
+		`{"name":"operations/1","metadata":{"@type":"type.googleapis.com/library.Progress","percent":50}}`,
+		`{"name":"operations/1","done":true,"response":{"@type":"type.googleapis.com/library.Book","title":"Dune","pages":"412"}}`,
+	}
+	p := New(stub, testConfig(), zap.NewNop())
+
+	result, err := p.InvokeMethodByTool(context.Background(), nil, "library_createbook", `{}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"title":"Dune","pages":"412"}`, result)
+	assert.Equal(t, []string{`{"name":"operations/1"}`, `{"name":"operations/1"}`}, stub.polled)
+}
+
+func TestPoller_OperationError(t *testing.T) {
+	stub := newStub(t, true)
+	stub.start = `{"name":"operations/2","done":true,"error":{"code":6,"message":"book exists"}}`
+	p := New(stub, testConfig(), zap.NewNop())
+
+	_, err := p.InvokeMethodByTool(context.Background(), nil, "library_createbook", `{}`)
+	require.Error(t, err)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	assert.Contains(t, err.Error(), "book exists")
+	assert.Empty(t, stub.polled)
+}
+
+func TestPoller_ReturnsPendingOperation(t *testing.T) {
+	pending := `{"name":"operations/3"}`
+
+	// The wait is bounded by max_wait
+	stub := newStub(t, true)
+	stub.start = pending
+	stub.polls = []string{pending}
+	cfg := testConfig()
+	cfg.MaxWait = 20 * time.Millisecond
+	result, err := New(stub, cfg, zap.NewNop()).InvokeMethodByTool(context.Background(), nil, "library_createbook", `{}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, pending, result)
+	assert.NotEmpty(t, stub.polled)
+
+	// Without the Operations service there is nothing to poll
+	stub = newStub(t, false)
+	stub.start = pending
+	result, err = New(stub, testConfig(), zap.NewNop()).InvokeMethodByTool(context.Background(), nil, "library_createbook", `{}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, pending, result)
+}
+
+func TestPoller_PassThrough(t *testing.T) {
+	stub := newStub(t, true)
+	stub.start = `{"name":"operations/4"}`
+
+	// Methods not returning an operation
+	result, err := New(stub, testConfig(), zap.NewNop()).InvokeMethodByTool(context.Background(), nil, "library_getbook", `{}`)
+	require.NoError(t, err)
+	assert.Equal(t, stub.start, result)
+
+	// Tools not selected
+	cfg := testConfig()
+	cfg.Tools = []string{"other_*"}
+	result, err = New(stub, cfg, zap.NewNop()).InvokeMethodByTool(context.Background(), nil, "library_createbook", `{}`)
+	require.NoError(t, err)
+	assert.Equal(t, stub.start, result)
+	assert.Empty(t, stub.polled)
+}
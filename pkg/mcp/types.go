@@ -45,6 +45,22 @@ type JSONRPCRequest struct {
 	ID      RequestID              `json:"id"`
 }
 
+// JSONRPCNotification represents a JSON-RPC 2.0 notification, a message
+// without an ID that expects no response
+type JSONRPCNotification struct {
+	JSONRPC string                 `json:"jsonrpc"`
+	Method  string                 `json:"method"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
+// ProgressNotificationMethod is the method of the notifications reporting
+// progress on a request that carried a progress token
+const ProgressNotificationMethod = "notifications/progress"
+
+// ToolsListChangedNotificationMethod is the method of the notification sent
+// when the list of tools offered by the server changed
+const ToolsListChangedNotificationMethod = "notifications/tools/list_changed"
+
 // JSONRPCResponse represents a JSON-RPC 2.0 response
 type JSONRPCResponse struct {
 	JSONRPC string      `json:"jsonrpc"`
@@ -72,8 +88,19 @@ const (
 	ErrorCodeMethodNotFound = -32601
 	ErrorCodeInvalidParams  = -32602
 	ErrorCodeInternalError  = -32603
+
+	// ErrorCodeRateLimited is returned when the gateway throttles a request
+	ErrorCodeRateLimited = -32029
 )
 
+// RetryInfo is the error data of throttled requests, telling clients how
+// long to back off
+type RetryInfo struct {
+	RetryAfterSeconds int     `json:"retryAfterSeconds"`
+	Scope             string  `json:"scope"`
+	Limit             float64 `json:"limit,omitempty"`
+}
+
 // ServerInfo represents the server information
 type ServerInfo struct {
 	Name    string `json:"name"`
@@ -122,6 +149,8 @@ const (
 	ContentTypeText  ContentType = "text"
 	ContentTypeImage ContentType = "image"
 	ContentTypeAudio ContentType = "audio"
+	// ContentTypeResourceLink links to a resource the client can read
+	ContentTypeResourceLink ContentType = "resource_link"
 )
 
 // ContentBlock represents a content block
@@ -130,6 +159,8 @@ type ContentBlock struct {
 	Text     string      `json:"text,omitempty"`
 	Data     string      `json:"data,omitempty"`
 	MimeType string      `json:"mimeType,omitempty"`
+	URI      string      `json:"uri,omitempty"`
+	Name     string      `json:"name,omitempty"`
 }
 
 // TextContent creates a text content block
@@ -149,6 +180,16 @@ func ImageContent(data, mimeType string) ContentBlock {
 	}
 }
 
+// ResourceLinkContent creates a resource link content block
+func ResourceLinkContent(uri, name, mimeType string) ContentBlock {
+	return ContentBlock{
+		Type:     ContentTypeResourceLink,
+		URI:      uri,
+		Name:     name,
+		MimeType: mimeType,
+	}
+}
+
 // AudioContent creates an audio content block
 func AudioContent(data, mimeType string) ContentBlock {
 	return ContentBlock{
@@ -161,15 +202,21 @@ func AudioContent(data, mimeType string) ContentBlock {
 // ToolCallResult represents the result of a tool call
 type ToolCallResult struct {
 	Content []ContentBlock `json:"content"`
-	IsError bool           `json:"isError,omitempty"`
+	// StructuredContent is the result as a JSON object, for clients that
+	// consume it instead of (or besides) the text content
+	StructuredContent interface{}            `json:"structuredContent,omitempty"`
+	IsError           bool                   `json:"isError,omitempty"`
+	Meta              map[string]interface{} `json:"_meta,omitempty"`
 }
 
 // Tool represents an MCP tool
 type Tool struct {
-	Name         string      `json:"name"`
-	Description  string      `json:"description"`
-	InputSchema  interface{} `json:"inputSchema"`
-	OutputSchema interface{} `json:"outputSchema,omitempty"`
+	Name         string                 `json:"name"`
+	Title        string                 `json:"title,omitempty"`
+	Description  string                 `json:"description"`
+	InputSchema  interface{}            `json:"inputSchema"`
+	OutputSchema interface{}            `json:"outputSchema,omitempty"`
+	Meta         map[string]interface{} `json:"_meta,omitempty"`
 }
 
 // ToolsListResult represents the result of listing tools
@@ -177,6 +224,57 @@ type ToolsListResult struct {
 	Tools []Tool `json:"tools"`
 }
 
+// Prompt represents an MCP prompt
+type Prompt struct {
+	Name        string           `json:"name"`
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+// PromptArgument represents an argument of a prompt
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// PromptsListResult represents the result of listing prompts
+type PromptsListResult struct {
+	Prompts []Prompt `json:"prompts"`
+}
+
+// PromptMessage represents a message of a prompt
+type PromptMessage struct {
+	Role    Role         `json:"role"`
+	Content ContentBlock `json:"content"`
+}
+
+// GetPromptResult represents the result of getting a prompt
+type GetPromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// Resource represents an MCP resource
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourcesListResult represents the result of listing resources
+type ResourcesListResult struct {
+	Resources []Resource `json:"resources"`
+}
+
+// ReadResourceResult represents the result of reading a resource
+type ReadResourceResult struct {
+	Contents []ResourceContents `json:"contents"`
+}
+
 // Role represents different roles in MCP
 type Role string
 
@@ -4,6 +4,21 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDetail controls how much of an error is returned to clients
+type ErrorDetail string
+
+const (
+	// ErrorDetailVerbose returns the full error, for internal deployments
+	ErrorDetailVerbose ErrorDetail = "verbose"
+	// ErrorDetailStandard returns the error with sensitive-looking words redacted
+	ErrorDetailStandard ErrorDetail = "standard"
+	// ErrorDetailStrict returns only a generic message derived from the gRPC status code
+	ErrorDetailStrict ErrorDetail = "strict"
 )
 
 // Validator provides validation functionality
@@ -269,3 +284,28 @@ func SanitizeError(err error) string {
 
 	return SanitizeString(msg)
 }
+
+// SanitizeErrorWithDetail sanitizes an error message at the given level of
+// detail. Unknown levels are treated as ErrorDetailStandard.
+func SanitizeErrorWithDetail(err error, detail ErrorDetail) string {
+	if err == nil {
+		return ""
+	}
+
+	switch detail {
+	case ErrorDetailVerbose:
+		return SanitizeString(err.Error())
+	case ErrorDetailStrict:
+		return genericErrorMessage(err)
+	}
+	return SanitizeError(err)
+}
+
+// genericErrorMessage describes an error by its gRPC status code only
+func genericErrorMessage(err error) string {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() == codes.Unknown {
+		return "the request failed"
+	}
+	return fmt.Sprintf("the upstream service returned %s", st.Code())
+}
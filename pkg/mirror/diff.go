@@ -0,0 +1,72 @@
+package mirror
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// maxDiffPaths bounds the number of paths reported for a single diff
+const maxDiffPaths = 20
+
+// DiffJSON compares two JSON documents and returns the paths that differ.
+// Documents that are not valid JSON are compared as plain strings.
+func DiffJSON(a, b string) []string {
+	var va, vb interface{}
+	errA := json.Unmarshal([]byte(a), &va)
+	errB := json.Unmarshal([]byte(b), &vb)
+	if errA != nil || errB != nil {
+		if a == b {
+			return nil
+		}
+		return []string{"$"}
+	}
+
+	var paths []string
+	diffValues("$", va, vb, &paths)
+	return paths
+}
+
+func diffValues(path string, a, b interface{}, paths *[]string) {
+	if len(*paths) >= maxDiffPaths {
+		return
+	}
+
+	switch ta := a.(type) {
+	case map[string]interface{}:
+		tb, ok := b.(map[string]interface{})
+		if !ok {
+			*paths = append(*paths, path)
+			return
+		}
+		keys := make(map[string]struct{}, len(ta)+len(tb))
+		for k := range ta {
+			keys[k] = struct{}{}
+		}
+		for k := range tb {
+			keys[k] = struct{}{}
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			diffValues(path+"."+k, ta[k], tb[k], paths)
+		}
+	case []interface{}:
+		tb, ok := b.([]interface{})
+		if !ok || len(ta) != len(tb) {
+			*paths = append(*paths, path)
+			return
+		}
+		for i := range ta {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), ta[i], tb[i], paths)
+		}
+	default:
+		if !reflect.DeepEqual(a, b) {
+			*paths = append(*paths, path)
+		}
+	}
+}
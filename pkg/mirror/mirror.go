@@ -0,0 +1,224 @@
+// Package mirror implements shadow traffic mirroring: tool calls served by
+// the primary upstream are replayed against a secondary upstream in the
+// background and the responses are compared, which supports canary
+// validation of backend rewrites behind the same MCP surface.
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"go.uber.org/zap"
+)
+
+// maxRecentDiffs bounds the diffs kept in memory for the stats endpoint
+const maxRecentDiffs = 20
+
+// Diff describes a mismatch between the primary and the secondary response
+type Diff struct {
+	Tool           string    `json:"tool"`
+	Arguments      string    `json:"arguments"`
+	Paths          []string  `json:"paths,omitempty"`
+	PrimaryError   string    `json:"primaryError,omitempty"`
+	SecondaryError string    `json:"secondaryError,omitempty"`
+	ObservedAt     time.Time `json:"observedAt"`
+}
+
+// Mirror wraps the primary discoverer and mirrors calls to a secondary one
+type Mirror struct {
+	grpc.ServiceDiscoverer
+
+	secondary grpc.ServiceDiscoverer
+	logger    *zap.Logger
+	config    config.MirrorConfig
+	slots     chan struct{}
+	wg        sync.WaitGroup
+
+	mirrored   atomic.Int64
+	matched    atomic.Int64
+	mismatched atomic.Int64
+	dropped    atomic.Int64
+
+	mu      sync.Mutex
+	recent  []Diff
+	diffLog *os.File
+	rand    func() float64
+}
+
+// New creates a mirror in front of primary. The secondary discoverer must
+// already be connected and have discovered its services.
+func New(primary, secondary grpc.ServiceDiscoverer, cfg config.MirrorConfig, logger *zap.Logger) (*Mirror, error) {
+	m := &Mirror{
+		ServiceDiscoverer: primary,
+		secondary:         secondary,
+		logger:            logger.Named("mirror"),
+		config:            cfg,
+		slots:             make(chan struct{}, cfg.MaxInFlight),
+		rand:              rand.New(rand.NewSource(time.Now().UnixNano())).Float64,
+	}
+
+	if cfg.DiffLogPath != "" {
+		file, err := os.OpenFile(cfg.DiffLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open mirror diff log: %w", err)
+		}
+		m.diffLog = file
+	}
+
+	return m, nil
+}
+
+// InvokeMethodByTool serves the call from the primary and mirrors it in the background
+func (m *Mirror) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	result, err := m.ServiceDiscoverer.InvokeMethodByTool(ctx, headers, toolName, inputJSON)
+
+	if m.shouldMirror() {
+		select {
+		case m.slots <- struct{}{}:
+			m.wg.Add(1)
+			go m.mirror(ctx, copyHeaders(headers), toolName, inputJSON, result, err)
+		default:
+			m.dropped.Add(1)
+		}
+	}
+
+	return result, err
+}
+
+func (m *Mirror) shouldMirror() bool {
+	if m.config.Percent >= 100 {
+		return true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rand()*100 < m.config.Percent
+}
+
+// mirror replays the call against the secondary and records any difference.
+// It detaches from the request's cancellation so that it outlives the client
+// request, but keeps its values (e.g. injected outgoing metadata).
+func (m *Mirror) mirror(reqCtx context.Context, headers map[string]string, toolName, inputJSON, primaryResult string, primaryErr error) {
+	defer m.wg.Done()
+	defer func() { <-m.slots }()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(reqCtx), m.config.Timeout)
+	defer cancel()
+
+	m.mirrored.Add(1)
+	secondaryResult, secondaryErr := m.secondary.InvokeMethodByTool(ctx, headers, toolName, inputJSON)
+
+	diff := Diff{Tool: toolName, Arguments: inputJSON, ObservedAt: time.Now().UTC()}
+	switch {
+	case primaryErr != nil || secondaryErr != nil:
+		if primaryErr != nil {
+			diff.PrimaryError = primaryErr.Error()
+		}
+		if secondaryErr != nil {
+			diff.SecondaryError = secondaryErr.Error()
+		}
+		if primaryErr != nil && secondaryErr != nil {
+			// Both failed: treat as a match
+			m.matched.Add(1)
+			return
+		}
+	default:
+		diff.Paths = DiffJSON(primaryResult, secondaryResult)
+		if len(diff.Paths) == 0 {
+			m.matched.Add(1)
+			return
+		}
+	}
+
+	m.mismatched.Add(1)
+	m.recordDiff(diff)
+}
+
+func (m *Mirror) recordDiff(diff Diff) {
+	m.logger.Warn("Mirrored response differs from primary",
+		zap.String("tool", diff.Tool),
+		zap.Strings("paths", diff.Paths),
+		zap.String("primaryError", diff.PrimaryError),
+		zap.String("secondaryError", diff.SecondaryError))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.recent = append(m.recent, diff)
+	if len(m.recent) > maxRecentDiffs {
+		m.recent = m.recent[len(m.recent)-maxRecentDiffs:]
+	}
+
+	if m.diffLog != nil {
+		if err := json.NewEncoder(m.diffLog).Encode(diff); err != nil {
+			m.logger.Warn("Failed to write mirror diff", zap.Error(err))
+		}
+	}
+}
+
+// Stats returns mirroring counters and the most recent diffs
+func (m *Mirror) Stats() map[string]interface{} {
+	m.mu.Lock()
+	recent := make([]Diff, len(m.recent))
+	copy(recent, m.recent)
+	m.mu.Unlock()
+
+	return map[string]interface{}{
+		"target":      fmt.Sprintf("%s:%d", m.config.Host, m.config.Port),
+		"mirrored":    m.mirrored.Load(),
+		"matched":     m.matched.Load(),
+		"mismatched":  m.mismatched.Load(),
+		"dropped":     m.dropped.Load(),
+		"recentDiffs": recent,
+	}
+}
+
+// GetServiceStats adds mirroring stats to the primary stats
+func (m *Mirror) GetServiceStats() map[string]interface{} {
+	stats := m.ServiceDiscoverer.GetServiceStats()
+	if stats == nil {
+		stats = make(map[string]interface{})
+	}
+	stats["mirror"] = m.Stats()
+	return stats
+}
+
+// Unwrap returns the primary discoverer
+func (m *Mirror) Unwrap() grpc.ServiceDiscoverer {
+	return m.ServiceDiscoverer
+}
+
+// Close waits for in-flight mirrored calls and closes both upstreams
+func (m *Mirror) Close() error {
+	m.wg.Wait()
+
+	m.mu.Lock()
+	if m.diffLog != nil {
+		_ = m.diffLog.Close()
+		m.diffLog = nil
+	}
+	m.mu.Unlock()
+
+	if err := m.secondary.Close(); err != nil {
+		m.logger.Warn("Failed to close mirror upstream", zap.Error(err))
+	}
+	return m.ServiceDiscoverer.Close()
+}
+
+func copyHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(headers))
+	for k, v := range headers {
+		copied[k] = v
+	}
+	return copied
+}
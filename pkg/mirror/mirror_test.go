@@ -0,0 +1,98 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type stubDiscoverer struct {
+	mu       sync.Mutex
+	response string
+	err      error
+	headers  []map[string]string
+}
+
+func (s *stubDiscoverer) Connect(ctx context.Context) error          { return nil }
+func (s *stubDiscoverer) DiscoverServices(ctx context.Context) error { return nil }
+func (s *stubDiscoverer) GetMethods() []types.MethodInfo             { return nil }
+func (s *stubDiscoverer) HealthCheck(ctx context.Context) error      { return nil }
+func (s *stubDiscoverer) GetMethod(string) (types.MethodInfo, bool)  { return types.MethodInfo{}, false }
+func (s *stubDiscoverer) Close() error                               { return nil }
+func (s *stubDiscoverer) GetMethodCount() int                        { return 0 }
+func (s *stubDiscoverer) GetServiceStats() map[string]interface{} {
+	return map[string]interface{}{"serviceCount": 1}
+}
+
+func (s *stubDiscoverer) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.headers = append(s.headers, headers)
+	return s.response, s.err
+}
+
+func testConfig() config.MirrorConfig {
+	return config.MirrorConfig{
+		Enabled:     true,
+		Host:        "shadow",
+		Port:        9090,
+		Percent:     100,
+		Timeout:     time.Second,
+		MaxInFlight: 4,
+	}
+}
+
+func TestMirror_RecordsDiffs(t *testing.T) {
+	primary := &stubDiscoverer{response: `{"name":"a","items":[1,2]}`}
+	secondary := &stubDiscoverer{response: `{"name":"b","items":[1,2]}`}
+
+	m, err := New(primary, secondary, testConfig(), zap.NewNop())
+	require.NoError(t, err)
+
+	result, err := m.InvokeMethodByTool(context.Background(), map[string]string{"x-trace-id": "t1"}, "svc_method", `{}`)
+	require.NoError(t, err)
+	assert.Equal(t, primary.response, result)
+
+	require.NoError(t, m.Close())
+
+	stats := m.Stats()
+	assert.Equal(t, int64(1), stats["mirrored"])
+	assert.Equal(t, int64(1), stats["mismatched"])
+	diffs := stats["recentDiffs"].([]Diff)
+	require.Len(t, diffs, 1)
+	assert.Equal(t, []string{"$.name"}, diffs[0].Paths)
+	assert.Equal(t, "t1", secondary.headers[0]["x-trace-id"])
+}
+
+func TestMirror_SecondaryFailureDoesNotAffectClient(t *testing.T) {
+	primary := &stubDiscoverer{response: `{"ok":true}`}
+	secondary := &stubDiscoverer{err: errors.New("boom")}
+
+	m, err := New(primary, secondary, testConfig(), zap.NewNop())
+	require.NoError(t, err)
+
+	result, err := m.InvokeMethodByTool(context.Background(), nil, "svc_method", `{}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"ok":true}`, result)
+
+	require.NoError(t, m.Close())
+	stats := m.GetServiceStats()
+	assert.Equal(t, 1, stats["serviceCount"])
+	mirrorStats := stats["mirror"].(map[string]interface{})
+	assert.Equal(t, int64(1), mirrorStats["mismatched"])
+}
+
+func TestDiffJSON(t *testing.T) {
+	assert.Empty(t, DiffJSON(`{"a":1,"b":[1,2]}`, `{"b":[1,2],"a":1}`))
+	assert.Equal(t, []string{"$.a", "$.c"}, DiffJSON(`{"a":1,"c":true}`, `{"a":2}`))
+	assert.Equal(t, []string{"$.b"}, DiffJSON(`{"b":[1]}`, `{"b":[1,2]}`))
+	assert.Equal(t, []string{"$"}, DiffJSON(`not json`, `{}`))
+}
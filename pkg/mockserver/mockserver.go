@@ -0,0 +1,171 @@
+// Package mockserver serves fake implementations of the services in a
+// descriptor set. Every method answers with a sample response generated from
+// its output message, echoing request fields the response shares, so MCP
+// clients can be developed against the gateway without a real backend. The
+// server also offers reflection and the standard health service, so the
+// gateway discovers it like any other upstream. It backs "grmcp mock".
+package mockserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// streamResponses is the number of responses sent by server-streaming methods
+const streamResponses = 3
+
+// Server serves the mock services
+type Server struct {
+	logger   *zap.Logger
+	server   *grpc.Server
+	services []string
+}
+
+// New creates a server for the services in files. Services of the gRPC
+// infrastructure (grpc.*) are skipped.
+func New(files *protoregistry.Files, logger *zap.Logger) (*Server, error) {
+	s := &Server{
+		logger: logger.Named("mock"),
+		server: grpc.NewServer(),
+	}
+
+	var descs []protoreflect.ServiceDescriptor
+	files.RangeFiles(func(file protoreflect.FileDescriptor) bool {
+		services := file.Services()
+		for i := 0; i < services.Len(); i++ {
+			if !strings.HasPrefix(string(services.Get(i).FullName()), "grpc.") {
+				descs = append(descs, services.Get(i))
+			}
+		}
+		return true
+	})
+	if len(descs) == 0 {
+		return nil, errors.New("descriptor set has no services")
+	}
+	sort.Slice(descs, func(i, j int) bool { return descs[i].FullName() < descs[j].FullName() })
+	for _, desc := range descs {
+		s.server.RegisterService(s.serviceDesc(desc), struct{}{})
+		s.services = append(s.services, string(desc.FullName()))
+	}
+
+	opts := reflection.ServerOptions{
+		Services:           s.server,
+		DescriptorResolver: files,
+		ExtensionResolver:  new(protoregistry.Types),
+	}
+	grpc_reflection_v1.RegisterServerReflectionServer(s.server, reflection.NewServerV1(opts))
+	grpc_reflection_v1alpha.RegisterServerReflectionServer(s.server, reflection.NewServer(opts))
+	healthpb.RegisterHealthServer(s.server, health.NewServer())
+	return s, nil
+}
+
+// Services returns the full names of the mock services
+func (s *Server) Services() []string {
+	return s.services
+}
+
+// Serve accepts connections on lis until Stop is called
+func (s *Server) Serve(lis net.Listener) error {
+	return s.server.Serve(lis)
+}
+
+// Stop stops the server, letting pending calls finish
+func (s *Server) Stop() {
+	s.server.GracefulStop()
+}
+
+// serviceDesc describes a mock service to the gRPC server
+func (s *Server) serviceDesc(service protoreflect.ServiceDescriptor) *grpc.ServiceDesc {
+	desc := &grpc.ServiceDesc{
+		ServiceName: string(service.FullName()),
+		HandlerType: (*interface{})(nil),
+		Metadata:    service.ParentFile().Path(),
+	}
+	methods := service.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		method := methods.Get(i)
+		if !method.IsStreamingClient() && !method.IsStreamingServer() {
+			desc.Methods = append(desc.Methods, grpc.MethodDesc{
+				MethodName: string(method.Name()),
+				Handler:    s.unaryHandler(method),
+			})
+			continue
+		}
+		desc.Streams = append(desc.Streams, grpc.StreamDesc{
+			StreamName:    string(method.Name()),
+			Handler:       s.streamHandler(method),
+			ServerStreams: method.IsStreamingServer(),
+			ClientStreams: method.IsStreamingClient(),
+		})
+	}
+	return desc
+}
+
+// unaryHandler answers a unary call with one sample response
+func (s *Server) unaryHandler(method protoreflect.MethodDescriptor) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(_ interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+		request := dynamicpb.NewMessage(method.Input())
+		if err := dec(request); err != nil {
+			return nil, err
+		}
+		s.logger.Debug("Mock call", zap.String("method", string(method.FullName())))
+		return Response(method, request), nil
+	}
+}
+
+// streamHandler answers streaming calls: server streams send several sample
+// responses, client streams answer the last request once the client is done,
+// and bidirectional streams answer every request
+func (s *Server) streamHandler(method protoreflect.MethodDescriptor) grpc.StreamHandler {
+	return func(_ interface{}, stream grpc.ServerStream) error {
+		s.logger.Debug("Mock stream", zap.String("method", string(method.FullName())))
+		request := dynamicpb.NewMessage(method.Input())
+		if !method.IsStreamingClient() {
+			if err := stream.RecvMsg(request); err != nil {
+				return err
+			}
+			for i := 0; i < streamResponses; i++ {
+				if err := stream.SendMsg(Response(method, request)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		for {
+			next := dynamicpb.NewMessage(method.Input())
+			err := stream.RecvMsg(next)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			request = next
+			if method.IsStreamingServer() {
+				if err := stream.SendMsg(Response(method, request)); err != nil {
+					return err
+				}
+			}
+		}
+		if method.IsStreamingServer() {
+			return nil
+		}
+		return stream.SendMsg(Response(method, request))
+	}
+}
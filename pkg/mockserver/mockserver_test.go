@@ -0,0 +1,132 @@
+package mockserver
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// testFiles builds a registry with a small library service
+func testFiles(t *testing.T) *protoregistry.Files {
+	t.Helper()
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	field := func(name string, number int32, label *descriptorpb.FieldDescriptorProto_Label, kind descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(number), Label: label, Type: kind.Enum()}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("library/books.proto"),
+		Package: proto.String("library"),
+		Syntax:  proto.String("proto3"),
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Genre"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("GENRE_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("GENRE_FICTION"), Number: proto.Int32(1)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Book"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("id", 1, optional, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				field("pages", 2, optional, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+				field("genre", 3, optional, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".library.Genre"),
+				field("tags", 4, repeated, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				field("sequel", 5, optional, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".library.Book"),
+			}},
+			{Name: proto.String("GetBookRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("id", 1, optional, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				field("pages", 2, optional, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+			}},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Books"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("GetBook"),
+				InputType:  proto.String(".library.GetBookRequest"),
+				OutputType: proto.String(".library.Book"),
+			}},
+		}},
+	}, new(protoregistry.Files))
+	require.NoError(t, err)
+
+	files := new(protoregistry.Files)
+	require.NoError(t, files.RegisterFile(fd))
+	return files
+}
+
+func TestResponse(t *testing.T) {
+	files := testFiles(t)
+	desc, err := files.FindDescriptorByName("library.Books")
+	require.NoError(t, err)
+	method := desc.(protoreflect.ServiceDescriptor).Methods().ByName("GetBook")
+
+	request := dynamicpb.NewMessage(method.Input())
+	request.Set(method.Input().Fields().ByName("id"), protoreflect.ValueOfString("b-42"))
+	// A field with the same name but another type is not echoed
+	request.Set(method.Input().Fields().ByName("pages"), protoreflect.ValueOfString("many"))
+
+	data, err := protojson.Marshal(Response(method, request))
+	require.NoError(t, err)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &response))
+	assert.Equal(t, "b-42", response["id"])
+	assert.Equal(t, float64(1), response["pages"])
+	assert.Equal(t, "GENRE_FICTION", response["genre"])
+	assert.Equal(t, []interface{}{"sample tags"}, response["tags"])
+
+	// Recursive messages stop at maxSampleDepth
+	depth := 0
+	for book := response; book != nil; depth++ {
+		book, _ = book["sequel"].(map[string]interface{})
+	}
+	assert.Equal(t, maxSampleDepth+1, depth)
+}
+
+func TestServer_ServesGatewayDiscovery(t *testing.T) {
+	mock, err := New(testFiles(t), zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"library.Books"}, mock.Services())
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = mock.Serve(lis) }()
+	t.Cleanup(mock.Stop)
+
+	// The gateway discovers the mock through reflection like a real upstream
+	port := lis.Addr().(*net.TCPAddr).Port
+	discoverer, err := grpc.NewServiceDiscoverer("127.0.0.1", port, zap.NewNop(), config.DescriptorSetConfig{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = discoverer.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, discoverer.Connect(ctx))
+	require.NoError(t, discoverer.DiscoverServices(ctx))
+	methods := discoverer.GetMethods()
+	require.Len(t, methods, 1)
+
+	result, err := discoverer.InvokeMethodByTool(ctx, nil, methods[0].ToolName, `{"id":"b-7"}`)
+	require.NoError(t, err)
+	var book map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(result), &book))
+	assert.Equal(t, "b-7", book["id"])
+	assert.Equal(t, "GENRE_FICTION", book["genre"])
+}
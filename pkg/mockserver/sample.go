@@ -0,0 +1,147 @@
+package mockserver
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// maxSampleDepth bounds the nesting of sample messages, so recursive
+// messages end
+const maxSampleDepth = 3
+
+// Response returns the sample response of method to request: every field of
+// the output message gets a sample value, then fields the request shares
+// with the response (same name and type) are copied from the request
+func Response(method protoreflect.MethodDescriptor, request proto.Message) *dynamicpb.Message {
+	response := dynamicpb.NewMessage(method.Output())
+	fillSample(response, 0)
+	if request != nil {
+		echo(request.ProtoReflect(), response)
+	}
+	return response
+}
+
+// fillSample sets every field of msg to a sample value; only the first field
+// of each oneof is set
+func fillSample(msg protoreflect.Message, depth int) {
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if oneof := field.ContainingOneof(); oneof != nil && oneof.Fields().Get(0) != field {
+			continue
+		}
+		if field.Message() != nil && !sampleable(field.Message(), depth) {
+			continue
+		}
+		switch {
+		case field.IsMap():
+			if value := field.MapValue(); value.Message() != nil && !sampleable(value.Message(), depth) {
+				continue
+			}
+			entries := msg.Mutable(field).Map()
+			entries.Set(sampleScalar(field.MapKey()).MapKey(), sampleElement(entries.NewValue(), field.MapValue(), depth))
+		case field.IsList():
+			items := msg.Mutable(field).List()
+			items.Append(sampleElement(items.NewElement(), field, depth))
+		case field.Message() != nil:
+			fillSample(msg.Mutable(field).Message(), depth+1)
+		default:
+			msg.Set(field, sampleScalar(field))
+		}
+	}
+}
+
+// sampleElement returns a sample list element or map value; empty is a new
+// element, used for messages
+func sampleElement(empty protoreflect.Value, field protoreflect.FieldDescriptor, depth int) protoreflect.Value {
+	if field.Message() == nil {
+		return sampleScalar(field)
+	}
+	fillSample(empty.Message(), depth+1)
+	return empty
+}
+
+// sampleable reports whether a message field gets a sample value: messages
+// nest up to maxSampleDepth, and google.protobuf.Any is left unset since its
+// type URL must name a known message
+func sampleable(desc protoreflect.MessageDescriptor, depth int) bool {
+	return depth < maxSampleDepth && desc.FullName() != "google.protobuf.Any"
+}
+
+// sampleScalar returns a sample value of a scalar or enum field
+func sampleScalar(field protoreflect.FieldDescriptor) protoreflect.Value {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(true)
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString("sample " + string(field.Name()))
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte("sample"))
+	case protoreflect.EnumKind:
+		// The first value after the zero value, which is usually UNSPECIFIED
+		values := field.Enum().Values()
+		if values.Len() > 1 {
+			return protoreflect.ValueOfEnum(values.Get(1).Number())
+		}
+		return protoreflect.ValueOfEnum(values.Get(0).Number())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(1)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(1)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(1)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(1)
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(1.5)
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(1.5)
+	}
+	return field.Default()
+}
+
+// echo copies the populated fields of request to the response fields with
+// the same name and type
+func echo(request, response protoreflect.Message) {
+	request.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		target := response.Descriptor().Fields().ByName(field.Name())
+		if target == nil || !sameType(field, target) {
+			return true
+		}
+		response.Clear(target)
+		switch {
+		case field.IsMap():
+			entries := response.Mutable(target).Map()
+			value.Map().Range(func(key protoreflect.MapKey, v protoreflect.Value) bool {
+				entries.Set(key, v)
+				return true
+			})
+		case field.IsList():
+			items := response.Mutable(target).List()
+			for i := 0; i < value.List().Len(); i++ {
+				items.Append(value.List().Get(i))
+			}
+		default:
+			response.Set(target, value)
+		}
+		return true
+	})
+}
+
+// sameType reports whether two fields hold the same type of value
+func sameType(a, b protoreflect.FieldDescriptor) bool {
+	if a.Kind() != b.Kind() || a.Cardinality() != b.Cardinality() || a.IsMap() != b.IsMap() {
+		return false
+	}
+	if a.IsMap() {
+		return sameType(a.MapKey(), b.MapKey()) && sameType(a.MapValue(), b.MapValue())
+	}
+	switch a.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return a.Message().FullName() == b.Message().FullName()
+	case protoreflect.EnumKind:
+		return a.Enum().FullName() == b.Enum().FullName()
+	}
+	return true
+}
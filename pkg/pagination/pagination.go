@@ -0,0 +1,208 @@
+// Package pagination follows the standard page_token / next_page_token
+// convention of list RPCs on the tool invoke path. A call of a paginated tool
+// fetches pages until the last one or a configured cap and returns the items
+// of all pages as one response, so agents do not have to run the pagination
+// loop themselves.
+package pagination
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Field names of the pagination convention
+const (
+	pageTokenField     = "page_token"
+	nextPageTokenField = "next_page_token"
+)
+
+// fields names the request and response fields of a paginated method
+type fields struct {
+	pageToken     protoreflect.FieldDescriptor
+	nextPageToken protoreflect.FieldDescriptor
+	items         protoreflect.FieldDescriptor
+}
+
+// detect returns the pagination fields of a method, or false when the method
+// does not follow the convention: a string page_token request field, a string
+// next_page_token response field and exactly one repeated response field
+func detect(method types.MethodInfo) (fields, bool) {
+	if method.IsClientStreaming || method.IsServerStreaming || method.InputDescriptor == nil || method.OutputDescriptor == nil {
+		return fields{}, false
+	}
+
+	var f fields
+	f.pageToken = method.InputDescriptor.Fields().ByName(pageTokenField)
+	f.nextPageToken = method.OutputDescriptor.Fields().ByName(nextPageTokenField)
+	if f.pageToken == nil || f.nextPageToken == nil ||
+		f.pageToken.Kind() != protoreflect.StringKind || f.nextPageToken.Kind() != protoreflect.StringKind {
+		return fields{}, false
+	}
+
+	outputFields := method.OutputDescriptor.Fields()
+	for i := 0; i < outputFields.Len(); i++ {
+		field := outputFields.Get(i)
+		if !field.IsList() {
+			continue
+		}
+		if f.items != nil {
+			// Several lists: which one is paginated is ambiguous
+			return fields{}, false
+		}
+		f.items = field
+	}
+	return f, f.items != nil
+}
+
+// Paginator wraps a discoverer and iterates the pages of paginated tools
+type Paginator struct {
+	grpc.ServiceDiscoverer
+
+	config config.ToolPaginationConfig
+	logger *zap.Logger
+}
+
+// New creates a paginator in front of inner
+func New(inner grpc.ServiceDiscoverer, cfg config.ToolPaginationConfig, logger *zap.Logger) *Paginator {
+	return &Paginator{
+		ServiceDiscoverer: inner,
+		config:            cfg,
+		logger:            logger.Named("pagination"),
+	}
+}
+
+// InvokeMethodByTool invokes the tool and, for paginated tools, fetches the
+// following pages. Iteration starts at the page_token of the arguments, if
+// any, and stops at the last page or after the page reaching max_items or
+// max_pages; the combined response then carries the token of the next page.
+// A failing page fails the whole call.
+func (p *Paginator) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	f, ok := p.paginated(toolName)
+	if !ok {
+		return p.ServiceDiscoverer.InvokeMethodByTool(ctx, headers, toolName, inputJSON)
+	}
+
+	input := map[string]interface{}{}
+	if strings.TrimSpace(inputJSON) != "" {
+		if err := decodeJSON(inputJSON, &input); err != nil {
+			// Leave the error to the invoker, which reports it properly
+			return p.ServiceDiscoverer.InvokeMethodByTool(ctx, headers, toolName, inputJSON)
+		}
+	}
+
+	var (
+		combined map[string]interface{}
+		items    []interface{}
+		pages    int
+	)
+	for {
+		result, err := p.ServiceDiscoverer.InvokeMethodByTool(ctx, headers, toolName, inputJSON)
+		if err != nil {
+			return result, err
+		}
+		pages++
+
+		var page map[string]interface{}
+		if err := decodeJSON(result, &page); err != nil {
+			return "", fmt.Errorf("failed to decode page %d: %w", pages, err)
+		}
+		if page == nil {
+			page = map[string]interface{}{}
+		}
+		pageItems, _ := take(page, f.items).([]interface{})
+		items = append(items, pageItems...)
+		token, _ := take(page, f.nextPageToken).(string)
+		if combined == nil {
+			combined = page
+		}
+
+		if token == "" || pages >= p.config.MaxPages || len(items) >= p.config.MaxItems {
+			if token != "" {
+				combined[f.nextPageToken.JSONName()] = token
+			}
+			break
+		}
+
+		// Request the next page
+		delete(input, string(f.pageToken.Name()))
+		input[f.pageToken.JSONName()] = token
+		if inputJSON, err = encodeJSON(input); err != nil {
+			return "", err
+		}
+	}
+
+	if pages > 1 {
+		p.logger.Debug("Combined paginated results",
+			zap.String("toolName", toolName),
+			zap.Int("pages", pages),
+			zap.Int("items", len(items)))
+	}
+	if len(items) > 0 {
+		combined[f.items.JSONName()] = items
+	}
+	return encodeJSON(combined)
+}
+
+// paginated returns the pagination fields of a tool selected for pagination
+func (p *Paginator) paginated(toolName string) (fields, bool) {
+	if len(p.config.Tools) > 0 && !matchAny(p.config.Tools, toolName) {
+		return fields{}, false
+	}
+	method, ok := p.GetMethod(toolName)
+	if !ok {
+		return fields{}, false
+	}
+	return detect(method)
+}
+
+// take removes a field from a decoded response and returns its value. The
+// response normally uses JSON names; proto names are accepted as well.
+func take(page map[string]interface{}, field protoreflect.FieldDescriptor) interface{} {
+	for _, name := range []string{field.JSONName(), string(field.Name())} {
+		if value, ok := page[name]; ok {
+			delete(page, name)
+			return value
+		}
+	}
+	return nil
+}
+
+func matchAny(patterns []string, toolName string) bool {
+	for _, pattern := range patterns {
+		if pattern == toolName {
+			return true
+		}
+		if matched, err := path.Match(pattern, toolName); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeJSON decodes keeping numbers as json.Number, so that 64-bit values
+// pass through without losing precision
+func decodeJSON(data string, v interface{}) error {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+func encodeJSON(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return "", fmt.Errorf("failed to encode paginated result: %w", err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
@@ -0,0 +1,197 @@
+package pagination
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// testMessages builds ListOrdersRequest, ListOrdersResponse (paginated) and
+// SearchResponse (two lists, not paginated)
+func testMessages(t *testing.T) protoreflect.FileDescriptor {
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	str := descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+	field := func(name, jsonName string, number int32, label *descriptorpb.FieldDescriptorProto_Label) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{Name: proto.String(name), JsonName: proto.String(jsonName), Number: proto.Int32(number), Label: label, Type: str}
+	}
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("orders_pagination.proto"),
+		Package: proto.String("paging"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("ListOrdersRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("page_size", "pageSize", 1, optional),
+					field("page_token", "pageToken", 2, optional),
+				},
+			},
+			{
+				Name: proto.String("ListOrdersResponse"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("orders", "orders", 1, repeated),
+					field("next_page_token", "nextPageToken", 2, optional),
+					field("total", "total", 3, optional),
+				},
+			},
+			{
+				Name: proto.String("SearchResponse"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("orders", "orders", 1, repeated),
+					field("customers", "customers", 2, repeated),
+					field("next_page_token", "nextPageToken", 3, optional),
+				},
+			},
+		},
+	}, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	return fd
+}
+
+// stubDiscoverer serves pages of orders: the page_token is the page index
+type stubDiscoverer struct {
+	methods map[string]types.MethodInfo
+	pages   [][]string
+	inputs  []string
+	failAt  int
+}
+
+func (s *stubDiscoverer) Connect(ctx context.Context) error          { return nil }
+func (s *stubDiscoverer) DiscoverServices(ctx context.Context) error { return nil }
+func (s *stubDiscoverer) GetMethods() []types.MethodInfo             { return nil }
+func (s *stubDiscoverer) HealthCheck(ctx context.Context) error      { return nil }
+func (s *stubDiscoverer) Close() error                               { return nil }
+func (s *stubDiscoverer) GetMethodCount() int                        { return 0 }
+func (s *stubDiscoverer) GetServiceStats() map[string]interface{}    { return nil }
+
+func (s *stubDiscoverer) GetMethod(toolName string) (types.MethodInfo, bool) {
+	method, ok := s.methods[toolName]
+	return method, ok
+}
+
+func (s *stubDiscoverer) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	s.inputs = append(s.inputs, inputJSON)
+	if s.failAt > 0 && len(s.inputs) == s.failAt {
+		return "", errors.New("upstream unavailable")
+	}
+
+	// Like protojson, accept both the JSON and the proto name
+	var input struct {
+		PageToken      string `json:"pageToken"`
+		ProtoPageToken string `json:"page_token"`
+	}
+	_ = json.Unmarshal([]byte(inputJSON), &input)
+	index := 0
+	if token := input.PageToken + input.ProtoPageToken; token != "" {
+		_, _ = fmt.Sscan(token, &index)
+	}
+
+	page := map[string]interface{}{"orders": s.pages[index], "total": len(s.pages)}
+	if index+1 < len(s.pages) {
+		page["nextPageToken"] = fmt.Sprint(index + 1)
+	}
+	data, _ := json.Marshal(page)
+	return string(data), nil
+}
+
+func newStub(t *testing.T, pages ...[]string) *stubDiscoverer {
+	fd := testMessages(t)
+	messages := fd.Messages()
+	return &stubDiscoverer{
+		pages: pages,
+		methods: map[string]types.MethodInfo{
+			"orders_list": {
+				ToolName:         "orders_list",
+				InputDescriptor:  messages.ByName("ListOrdersRequest"),
+				OutputDescriptor: messages.ByName("ListOrdersResponse"),
+			},
+			"orders_search": {
+				ToolName:         "orders_search",
+				InputDescriptor:  messages.ByName("ListOrdersRequest"),
+				OutputDescriptor: messages.ByName("SearchResponse"),
+			},
+			"orders_get": {
+				ToolName:         "orders_get",
+				InputDescriptor:  messages.ByName("ListOrdersResponse"),
+				OutputDescriptor: messages.ByName("ListOrdersResponse"),
+			},
+		},
+	}
+}
+
+func TestPaginator_CombinesPages(t *testing.T) {
+	stub := newStub(t, []string{"a", "b"}, []string{"c"}, []string{"d", "e"})
+	p := New(stub, config.ToolPaginationConfig{Enabled: true, MaxPages: 10, MaxItems: 100}, zap.NewNop())
+
+	result, err := p.InvokeMethodByTool(context.Background(), nil, "orders_list", `{"page_size":"2"}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"orders":["a","b","c","d","e"],"total":3}`, result)
+	assert.Equal(t, []string{
+		`{"page_size":"2"}`,
+		`{"pageToken":"1","page_size":"2"}`,
+		`{"pageToken":"2","page_size":"2"}`,
+	}, stub.inputs)
+}
+
+func TestPaginator_Caps(t *testing.T) {
+	stub := newStub(t, []string{"a", "b"}, []string{"c", "d"}, []string{"e"})
+	p := New(stub, config.ToolPaginationConfig{Enabled: true, MaxPages: 10, MaxItems: 3}, zap.NewNop())
+
+	// The page reaching max_items is kept whole and the next token returned
+	result, err := p.InvokeMethodByTool(context.Background(), nil, "orders_list", `{}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"orders":["a","b","c","d"],"total":3,"nextPageToken":"2"}`, result)
+
+	// Resuming from the returned token
+	stub.inputs = nil
+	p.config.MaxPages = 1
+	result, err = p.InvokeMethodByTool(context.Background(), nil, "orders_list", `{"page_token":"1"}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"orders":["c","d"],"total":3,"nextPageToken":"2"}`, result)
+	assert.Len(t, stub.inputs, 1)
+}
+
+func TestPaginator_PassThrough(t *testing.T) {
+	stub := newStub(t, []string{"a"}, []string{"b"})
+
+	// Methods not following the convention are invoked once
+	p := New(stub, config.ToolPaginationConfig{Enabled: true, MaxPages: 10, MaxItems: 100}, zap.NewNop())
+	for _, tool := range []string{"orders_search", "orders_get", "unknown"} {
+		stub.inputs = nil
+		_, err := p.InvokeMethodByTool(context.Background(), nil, tool, `{}`)
+		require.NoError(t, err)
+		assert.Len(t, stub.inputs, 1, tool)
+	}
+
+	// Tools not selected are invoked once
+	p = New(stub, config.ToolPaginationConfig{Enabled: true, Tools: []string{"users_*"}, MaxPages: 10, MaxItems: 100}, zap.NewNop())
+	stub.inputs = nil
+	result, err := p.InvokeMethodByTool(context.Background(), nil, "orders_list", `{}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"orders":["a"],"total":2,"nextPageToken":"1"}`, result)
+	assert.Len(t, stub.inputs, 1)
+}
+
+func TestPaginator_FailingPage(t *testing.T) {
+	stub := newStub(t, []string{"a"}, []string{"b"})
+	stub.failAt = 2
+	p := New(stub, config.ToolPaginationConfig{Enabled: true, MaxPages: 10, MaxItems: 100}, zap.NewNop())
+
+	_, err := p.InvokeMethodByTool(context.Background(), nil, "orders_list", `{}`)
+	assert.Error(t, err)
+}
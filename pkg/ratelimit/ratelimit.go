@@ -0,0 +1,107 @@
+// Package ratelimit provides keyed token-bucket rate limiters. The in-memory
+// limiter keeps buckets per replica; the Redis limiter keeps them in Redis so
+// that every replica of a horizontally scaled gateway enforces the same limit.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"golang.org/x/time/rate"
+)
+
+// maxMemoryKeys bounds the buckets kept before full (idle) ones are pruned
+const maxMemoryKeys = 10000
+
+// Result is the outcome of a limiter check
+type Result struct {
+	Allowed bool
+	// RetryAfter is how long until a request would be allowed (when denied)
+	RetryAfter time.Duration
+}
+
+// Limiter admits requests per key, e.g. a session ID, at a steady rate with
+// bursts. An error means the backend could not decide; the caller chooses
+// whether to fail open or closed.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// New creates the limiter of the configured backend
+func New(cfg config.RateLimitConfig) (Limiter, error) {
+	perSecond := float64(cfg.RequestsPerMinute) / 60
+	switch cfg.Backend {
+	case config.RateLimitBackendMemory, "":
+		return NewMemory(perSecond, cfg.BurstSize), nil
+	case config.RateLimitBackendRedis:
+		redisConfig := cfg.Redis
+		if redisConfig.PasswordEnv != "" {
+			password := os.Getenv(redisConfig.PasswordEnv)
+			if password == "" {
+				return nil, fmt.Errorf("environment variable %s is empty", redisConfig.PasswordEnv)
+			}
+			redisConfig.Password = password
+		}
+		return NewRedis(redisConfig, perSecond, cfg.BurstSize), nil
+	}
+	return nil, fmt.Errorf("unknown rate limit backend: %s", cfg.Backend)
+}
+
+// Memory is a Limiter keeping a token bucket per key in process memory
+type Memory struct {
+	limit rate.Limit
+	burst int
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+// NewMemory creates an in-memory limiter allowing perSecond requests per key
+// with bursts of burst
+func NewMemory(perSecond float64, burst int) *Memory {
+	return &Memory{
+		limit:    rate.Limit(perSecond),
+		burst:    burst,
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+// Allow implements Limiter
+func (m *Memory) Allow(_ context.Context, key string) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	limiter, ok := m.limiters[key]
+	if !ok {
+		if len(m.limiters) >= maxMemoryKeys {
+			m.prune(now)
+		}
+		limiter = rate.NewLimiter(m.limit, m.burst)
+		m.limiters[key] = limiter
+	}
+
+	reservation := limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return Result{RetryAfter: time.Second}, nil
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return Result{RetryAfter: delay}, nil
+	}
+	return Result{Allowed: true}, nil
+}
+
+// prune drops the buckets that have refilled completely; recreating them
+// later is indistinguishable from keeping them
+func (m *Memory) prune(now time.Time) {
+	for key, limiter := range m.limiters {
+		if limiter.TokensAt(now) >= float64(m.burst) {
+			delete(m.limiters, key)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestMemory_PerKeyBuckets(t *testing.T) {
+	limiter := NewMemory(1, 2)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		result, err := limiter.Allow(ctx, "a")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+	result, err := limiter.Allow(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.InDelta(t, time.Second, result.RetryAfter, float64(100*time.Millisecond))
+
+	// Other keys have their own bucket
+	result, err = limiter.Allow(ctx, "b")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestMemory_PrunesFullBuckets(t *testing.T) {
+	limiter := NewMemory(1, 1)
+	ctx := context.Background()
+	for i := 0; i < maxMemoryKeys; i++ {
+		limiter.limiters[strconv.Itoa(i)] = rate.NewLimiter(limiter.limit, limiter.burst)
+	}
+	_, err := limiter.Allow(ctx, "used")
+	require.NoError(t, err)
+
+	// Full buckets are dropped, the one just used is kept
+	_, err = limiter.Allow(ctx, "new")
+	require.NoError(t, err)
+	assert.Len(t, limiter.limiters, 2)
+	result, err := limiter.Allow(ctx, "used")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
+
+func TestNew_Backends(t *testing.T) {
+	cfg := config.Default().Server.Security.RateLimit
+	limiter, err := New(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &Memory{}, limiter)
+
+	cfg.Backend = config.RateLimitBackendRedis
+	limiter, err = New(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &Redis{}, limiter)
+
+	// The password is read from the environment
+	cfg.Redis.PasswordEnv = "TEST_REDIS_PASSWORD"
+	_, err = New(cfg)
+	assert.ErrorContains(t, err, "TEST_REDIS_PASSWORD")
+	t.Setenv("TEST_REDIS_PASSWORD", "secret")
+	limiter, err = New(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", limiter.(*Redis).config.Password)
+
+	cfg.Backend = "etcd"
+	_, err = New(cfg)
+	assert.Error(t, err)
+}
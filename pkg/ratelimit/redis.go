@@ -0,0 +1,270 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+)
+
+// tokenBucketScript refills and takes a token from the bucket in KEYS[1]
+// atomically. ARGV[1] is the refill rate per second and ARGV[2] the burst.
+// It uses the Redis clock so that replicas with skewed clocks agree, and
+// returns {allowed, milliseconds until a token is available}.
+const tokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+  tokens = burst
+  ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate * 1000)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, wait}
+`
+
+// tokenBucketSHA is the SHA1 digest EVALSHA refers to the script by
+var tokenBucketSHA = func() string {
+	sum := sha1.Sum([]byte(tokenBucketScript))
+	return hex.EncodeToString(sum[:])
+}()
+
+// redisError is an error reply from Redis
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// Redis is a Limiter keeping token buckets in Redis, shared by all replicas
+// pointed at the same Redis and key prefix
+type Redis struct {
+	config    config.RedisConfig
+	perSecond string
+	burst     string
+
+	idle chan *redisConn
+}
+
+// NewRedis creates a Redis backed limiter allowing perSecond requests per key
+// with bursts of burst. Connections are opened on demand.
+func NewRedis(cfg config.RedisConfig, perSecond float64, burst int) *Redis {
+	return &Redis{
+		config:    cfg,
+		perSecond: strconv.FormatFloat(perSecond, 'f', -1, 64),
+		burst:     strconv.Itoa(burst),
+		idle:      make(chan *redisConn, max(cfg.PoolSize, 1)),
+	}
+}
+
+// Allow implements Limiter
+func (r *Redis) Allow(ctx context.Context, key string) (Result, error) {
+	key = r.config.KeyPrefix + key
+	reply, err := r.do(ctx, "EVALSHA", tokenBucketSHA, "1", key, r.perSecond, r.burst)
+	var replyErr redisError
+	if errors.As(err, &replyErr) && strings.HasPrefix(string(replyErr), "NOSCRIPT") {
+		// First use on this Redis (or after SCRIPT FLUSH): send the script itself
+		reply, err = r.do(ctx, "EVAL", tokenBucketScript, "1", key, r.perSecond, r.burst)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return Result{}, fmt.Errorf("redis: unexpected rate limit reply %v", reply)
+	}
+	allowed, _ := values[0].(int64)
+	waitMs, _ := values[1].(int64)
+	if allowed == 1 {
+		return Result{Allowed: true}, nil
+	}
+	return Result{RetryAfter: time.Duration(waitMs) * time.Millisecond}, nil
+}
+
+// Close closes the idle connections
+func (r *Redis) Close() error {
+	for {
+		select {
+		case conn := <-r.idle:
+			_ = conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do runs one command on a pooled connection. Connections that fail are
+// discarded; error replies leave them usable.
+func (r *Redis) do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.do(ctx, r.config.Timeout, args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		_ = conn.Close()
+		return nil, err
+	}
+	select {
+	case r.idle <- conn:
+	default:
+		_ = conn.Close()
+	}
+	return reply, err
+}
+
+// conn returns an idle connection or dials a new one
+func (r *Redis) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-r.idle:
+		return conn, nil
+	default:
+	}
+
+	dialer := &net.Dialer{Timeout: r.config.DialTimeout}
+	var (
+		netConn net.Conn
+		err     error
+	)
+	if r.config.TLS {
+		host, _, _ := net.SplitHostPort(r.config.Address)
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}}
+		netConn, err = tlsDialer.DialContext(ctx, "tcp", r.config.Address)
+	} else {
+		netConn, err = dialer.DialContext(ctx, "tcp", r.config.Address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+
+	conn := &redisConn{Conn: netConn, reader: bufio.NewReader(netConn)}
+	if r.config.Password != "" {
+		args := []string{"AUTH", r.config.Password}
+		if r.config.Username != "" {
+			args = []string{"AUTH", r.config.Username, r.config.Password}
+		}
+		if _, err := conn.do(ctx, r.config.Timeout, args...); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	if r.config.DB != 0 {
+		if _, err := conn.do(ctx, r.config.Timeout, "SELECT", strconv.Itoa(r.config.DB)); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// redisConn is a connection speaking the Redis serialization protocol (RESP2)
+type redisConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// do sends a command and reads its reply
+func (c *redisConn) do(ctx context.Context, timeout time.Duration, args ...string) (interface{}, error) {
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := c.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.Conn, b.String()); err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	return c.readReply()
+}
+
+// readReply reads one reply: a string, redisError, int64, []byte (nil for a
+// null bulk string) or []interface{}
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, redisError(payload)
+	case ':':
+		n, err := strconv.ParseInt(payload, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed integer %q", payload)
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", payload)
+		}
+		if n < 0 {
+			return []byte(nil), nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, fmt.Errorf("redis: %w", err)
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed array length %q", payload)
+		}
+		if n < 0 {
+			return []interface{}(nil), nil
+		}
+		values := make([]interface{}, n)
+		for i := range values {
+			values[i], err = c.readReply()
+			var replyErr redisError
+			if errors.As(err, &replyErr) {
+				values[i] = replyErr
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
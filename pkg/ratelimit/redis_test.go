@@ -0,0 +1,155 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis answers RESP commands with canned replies and records them
+type fakeRedis struct {
+	listener net.Listener
+	reply    func(args []string) string
+
+	mu       sync.Mutex
+	commands [][]string
+	conns    int
+}
+
+func newFakeRedis(t *testing.T, reply func(args []string) string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	f := &fakeRedis{listener: listener, reply: reply}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.conns++
+			f.mu.Unlock()
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, n)
+		for i := range args {
+			line, _ = reader.ReadString('\n')
+			size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, data); err != nil {
+				return
+			}
+			args[i] = string(data[:size])
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, args)
+		f.mu.Unlock()
+		_, _ = io.WriteString(conn, f.reply(args))
+	}
+}
+
+func (f *fakeRedis) names() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var names []string
+	for _, args := range f.commands {
+		names = append(names, args[0])
+	}
+	return names
+}
+
+func testRedisConfig(address string) config.RedisConfig {
+	cfg := config.Default().Server.Security.RateLimit.Redis
+	cfg.Address = address
+	return cfg
+}
+
+func TestRedis_Allow(t *testing.T) {
+	var denied atomic.Bool
+	f := newFakeRedis(t, func(args []string) string {
+		switch args[0] {
+		case "AUTH", "SELECT":
+			return "+OK\r\n"
+		case "EVALSHA":
+			if len(args) > 1 && args[1] == tokenBucketSHA && denied.Load() {
+				return "*2\r\n:0\r\n:1500\r\n"
+			}
+			return "-NOSCRIPT No matching script. Please use EVAL.\r\n"
+		case "EVAL":
+			return "*2\r\n:1\r\n:0\r\n"
+		}
+		return "-ERR unknown command\r\n"
+	})
+
+	cfg := testRedisConfig(f.listener.Addr().String())
+	cfg.Password = "secret"
+	cfg.DB = 2
+	limiter := NewRedis(cfg, 0.5, 3)
+	defer func() { _ = limiter.Close() }()
+
+	// The script is loaded with EVAL when Redis does not know it yet
+	result, err := limiter.Allow(context.Background(), "session:a")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, []string{"AUTH", "SELECT", "EVALSHA", "EVAL"}, f.names())
+
+	f.mu.Lock()
+	eval := f.commands[3]
+	f.mu.Unlock()
+	assert.Equal(t, []string{"1", "ggrmcp:ratelimit:session:a", "0.5", "3"}, eval[2:])
+
+	// The pooled connection is reused
+	denied.Store(true)
+	result, err = limiter.Allow(context.Background(), "session:a")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 1500*time.Millisecond, result.RetryAfter)
+	f.mu.Lock()
+	assert.Equal(t, 1, f.conns)
+	f.mu.Unlock()
+}
+
+func TestRedis_ErrorReply(t *testing.T) {
+	f := newFakeRedis(t, func(args []string) string {
+		return "-WRONGPASS invalid username-password pair\r\n"
+	})
+	cfg := testRedisConfig(f.listener.Addr().String())
+	cfg.Password = "wrong"
+	_, err := NewRedis(cfg, 1, 1).Allow(context.Background(), "k")
+	assert.ErrorContains(t, err, "WRONGPASS")
+}
+
+func TestRedis_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	_, err = NewRedis(testRedisConfig(address), 1, 1).Allow(context.Background(), "k")
+	assert.Error(t, err)
+}
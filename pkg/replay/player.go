@@ -0,0 +1,96 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Player serves recorded responses instead of calling the upstream.
+//
+// The wrapped discoverer is only used for tool metadata, so it can be a
+// descriptor-only discoverer that was never connected. When the same call was
+// recorded several times the responses are served in order, and the last one
+// is repeated once they are exhausted.
+type Player struct {
+	grpc.ServiceDiscoverer
+
+	logger  *zap.Logger
+	mu      sync.Mutex
+	entries map[string][]Entry
+	served  map[string]int
+}
+
+// NewPlayer creates a player from the entries in the recording file at path
+func NewPlayer(inner grpc.ServiceDiscoverer, path string, logger *zap.Logger) (*Player, error) {
+	entries, err := LoadEntries(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewPlayerFromEntries(inner, entries, logger), nil
+}
+
+// NewPlayerFromEntries creates a player from already loaded entries
+func NewPlayerFromEntries(inner grpc.ServiceDiscoverer, entries []Entry, logger *zap.Logger) *Player {
+	p := &Player{
+		ServiceDiscoverer: inner,
+		logger:            logger.Named("replay"),
+		entries:           make(map[string][]Entry),
+		served:            make(map[string]int),
+	}
+	for _, entry := range entries {
+		key := replayKey(entry.Tool, string(entry.Arguments))
+		p.entries[key] = append(p.entries[key], entry)
+	}
+	return p
+}
+
+// InvokeMethodByTool returns the recorded response for the call
+func (p *Player) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	key := replayKey(toolName, inputJSON)
+
+	p.mu.Lock()
+	recorded := p.entries[key]
+	if len(recorded) == 0 {
+		p.mu.Unlock()
+		return "", fmt.Errorf("no recorded response for tool %s with the given arguments", toolName)
+	}
+	idx := p.served[key]
+	if idx >= len(recorded) {
+		idx = len(recorded) - 1
+	} else {
+		p.served[key] = idx + 1
+	}
+	entry := recorded[idx]
+	p.mu.Unlock()
+
+	p.logger.Debug("Replaying recorded tool call", zap.String("tool", toolName), zap.Int("index", idx))
+
+	if entry.Code != codes.OK {
+		return "", status.Error(entry.Code, entry.Error)
+	}
+	if entry.Error != "" {
+		return "", errors.New(entry.Error)
+	}
+	return entry.Response, nil
+}
+
+// Unwrap returns the wrapped discoverer
+func (p *Player) Unwrap() grpc.ServiceDiscoverer {
+	return p.ServiceDiscoverer
+}
+
+// HealthCheck always succeeds since no upstream is involved
+func (p *Player) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+func replayKey(toolName, inputJSON string) string {
+	return toolName + "\x00" + string(canonicalArguments(inputJSON))
+}
@@ -0,0 +1,150 @@
+// Package replay implements record-and-replay of MCP tool calls.
+//
+// A Recorder wraps a ServiceDiscoverer and appends every tool invocation to a
+// JSON lines file. A Player serves those recorded responses without
+// contacting the upstream, which makes MCP client tests and demos
+// deterministic.
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Entry is a single recorded tool call
+type Entry struct {
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments"`
+	Response  string          `json:"response,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	// Code is the gRPC status code of a failed call, so that replayed errors
+	// carry the same code as live ones (zero when the error had no status)
+	Code       codes.Code `json:"code,omitempty"`
+	RecordedAt time.Time  `json:"recordedAt"`
+}
+
+// Recorder forwards tool calls to the wrapped discoverer and records them
+type Recorder struct {
+	grpc.ServiceDiscoverer
+
+	logger *zap.Logger
+	mu     sync.Mutex
+	file   *os.File
+	enc    *json.Encoder
+}
+
+// NewRecorder creates a recorder that appends entries to the file at path
+func NewRecorder(inner grpc.ServiceDiscoverer, path string, logger *zap.Logger) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording file: %w", err)
+	}
+
+	return &Recorder{
+		ServiceDiscoverer: inner,
+		logger:            logger.Named("recorder"),
+		file:              file,
+		enc:               json.NewEncoder(file),
+	}, nil
+}
+
+// InvokeMethodByTool invokes the upstream and records the outcome
+func (r *Recorder) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	result, err := r.ServiceDiscoverer.InvokeMethodByTool(ctx, headers, toolName, inputJSON)
+
+	entry := Entry{
+		Tool:       toolName,
+		Arguments:  canonicalArguments(inputJSON),
+		Response:   result,
+		RecordedAt: time.Now().UTC(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		// Keep the status of the upstream error, even when it is wrapped
+		var grpcErr interface{ GRPCStatus() *status.Status }
+		if errors.As(err, &grpcErr) {
+			entry.Code = grpcErr.GRPCStatus().Code()
+			entry.Error = grpcErr.GRPCStatus().Message()
+		}
+	}
+
+	r.mu.Lock()
+	if encErr := r.enc.Encode(entry); encErr != nil {
+		r.logger.Warn("Failed to record tool call", zap.String("tool", toolName), zap.Error(encErr))
+	}
+	r.mu.Unlock()
+
+	return result, err
+}
+
+// Unwrap returns the wrapped discoverer
+func (r *Recorder) Unwrap() grpc.ServiceDiscoverer {
+	return r.ServiceDiscoverer
+}
+
+// Close closes the recording file and the wrapped discoverer
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	fileErr := r.file.Close()
+	r.mu.Unlock()
+
+	if err := r.ServiceDiscoverer.Close(); err != nil {
+		return err
+	}
+	return fileErr
+}
+
+// LoadEntries reads all entries from a recording file
+func LoadEntries(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid entry at line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording file: %w", err)
+	}
+
+	return entries, nil
+}
+
+// canonicalArguments re-encodes JSON so that equal arguments compare equal
+// regardless of key order or whitespace
+func canonicalArguments(inputJSON string) json.RawMessage {
+	var v interface{}
+	if err := json.Unmarshal([]byte(inputJSON), &v); err != nil {
+		return json.RawMessage(fmt.Sprintf("%q", inputJSON))
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return json.RawMessage(fmt.Sprintf("%q", inputJSON))
+	}
+	return data
+}
@@ -0,0 +1,105 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeDiscoverer answers every call with a fixed response and counts invocations
+type fakeDiscoverer struct {
+	calls    int
+	response string
+	err      error
+}
+
+var _ grpc.ServiceDiscoverer = (*fakeDiscoverer)(nil)
+
+func (f *fakeDiscoverer) Connect(ctx context.Context) error          { return nil }
+func (f *fakeDiscoverer) DiscoverServices(ctx context.Context) error { return nil }
+func (f *fakeDiscoverer) GetMethods() []types.MethodInfo             { return nil }
+func (f *fakeDiscoverer) HealthCheck(ctx context.Context) error      { return errors.New("down") }
+func (f *fakeDiscoverer) GetMethod(string) (types.MethodInfo, bool)  { return types.MethodInfo{}, false }
+func (f *fakeDiscoverer) Close() error                               { return nil }
+func (f *fakeDiscoverer) GetMethodCount() int                        { return 0 }
+func (f *fakeDiscoverer) GetServiceStats() map[string]interface{}    { return nil }
+
+func (f *fakeDiscoverer) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	f.calls++
+	return f.response, f.err
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calls.jsonl")
+	logger := zap.NewNop()
+
+	upstream := &fakeDiscoverer{response: `{"message":"hi"}`}
+	recorder, err := NewRecorder(upstream, path, logger)
+	require.NoError(t, err)
+
+	result, err := recorder.InvokeMethodByTool(context.Background(), nil, "hello_helloservice_sayhello", `{"name": "a", "count": 1}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"message":"hi"}`, result)
+
+	upstream.response, upstream.err = "", errors.New("upstream failed")
+	_, err = recorder.InvokeMethodByTool(context.Background(), nil, "hello_helloservice_sayhello", `{"name":"b"}`)
+	require.Error(t, err)
+	upstream.err = fmt.Errorf("invoke failed: %w", status.Error(codes.Unavailable, "upstream down"))
+	_, err = recorder.InvokeMethodByTool(context.Background(), nil, "hello_helloservice_sayhello", `{"name":"d"}`)
+	require.Error(t, err)
+	require.NoError(t, recorder.Close())
+
+	entries, err := LoadEntries(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.JSONEq(t, `{"count":1,"name":"a"}`, string(entries[0].Arguments))
+
+	offline := &fakeDiscoverer{}
+	player, err := NewPlayer(offline, path, logger)
+	require.NoError(t, err)
+
+	// Key order and whitespace do not matter when matching
+	result, err = player.InvokeMethodByTool(context.Background(), nil, "hello_helloservice_sayhello", `{"count":1,"name":"a"}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"message":"hi"}`, result)
+
+	_, err = player.InvokeMethodByTool(context.Background(), nil, "hello_helloservice_sayhello", `{"name":"b"}`)
+	assert.EqualError(t, err, "upstream failed")
+
+	_, err = player.InvokeMethodByTool(context.Background(), nil, "hello_helloservice_sayhello", `{"name":"c"}`)
+	assert.Error(t, err)
+
+	// gRPC status errors are replayed with their status code
+	_, err = player.InvokeMethodByTool(context.Background(), nil, "hello_helloservice_sayhello", `{"name":"d"}`)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, "rpc error: code = Unavailable desc = upstream down", err.Error())
+
+	assert.NoError(t, player.HealthCheck(context.Background()))
+	assert.Equal(t, 0, offline.calls, "replay must not contact the upstream")
+}
+
+func TestPlayer_ServesRepeatedCallsInOrder(t *testing.T) {
+	entries := []Entry{
+		{Tool: "svc_method", Arguments: []byte(`{}`), Response: "first"},
+		{Tool: "svc_method", Arguments: []byte(`{}`), Response: "second"},
+	}
+	player := NewPlayerFromEntries(&fakeDiscoverer{}, entries, zap.NewNop())
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		result, err := player.InvokeMethodByTool(context.Background(), nil, "svc_method", `{}`)
+		require.NoError(t, err)
+		got = append(got, result)
+	}
+	assert.Equal(t, []string{"first", "second", "second"}, got)
+}
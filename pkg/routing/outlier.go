@@ -0,0 +1,154 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/chaos"
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// outlierDetector ejects replicas whose error rate over the last interval is
+// too high. Ejected replicas are skipped by pick until their ejection expires.
+type outlierDetector struct {
+	config   config.OutlierDetectionConfig
+	failures map[codes.Code]bool
+	now      func() time.Time
+
+	mu      sync.Mutex
+	lastRun time.Time
+}
+
+// EnableOutlierDetection starts tracking replica error rates and ejecting
+// replicas that exceed the configured failure percentage
+func (p *Pool) EnableOutlierDetection(cfg config.OutlierDetectionConfig) error {
+	parsed, err := chaos.ParseCodes(cfg.FailureCodes)
+	if err != nil {
+		return err
+	}
+	failures := make(map[codes.Code]bool, len(parsed))
+	for _, code := range parsed {
+		failures[code] = true
+	}
+	p.outliers = &outlierDetector{config: cfg, failures: failures, now: time.Now}
+	return nil
+}
+
+// record counts the outcome of a call to backend. Calls abandoned by the
+// client are ignored.
+func (p *Pool) record(ctx context.Context, backend *Backend, err error) {
+	if p.outliers == nil {
+		return
+	}
+	switch {
+	case err == nil:
+		backend.successes.Add(1)
+	case ctx.Err() != nil && errors.Is(ctx.Err(), context.Canceled):
+	case p.outliers.failures[status.Code(err)]:
+		backend.failures.Add(1)
+	default:
+		backend.successes.Add(1)
+	}
+	p.evaluate()
+}
+
+// evaluate ejects failing replicas once per interval
+func (p *Pool) evaluate() {
+	o := p.outliers
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := o.now()
+	if o.lastRun.IsZero() {
+		o.lastRun = now
+		return
+	}
+	if now.Sub(o.lastRun) < o.config.Interval {
+		return
+	}
+	o.lastRun = now
+
+	maxEjected := int(float64(len(p.backends)) * o.config.MaxEjectionPercent / 100)
+	ejected := 0
+	for _, backend := range p.backends {
+		if backend.ejected(now) {
+			ejected++
+		} else if backend.ejectedUntil.Load() != 0 {
+			backend.ejectedUntil.Store(0)
+			p.logger.Info("Replica returned to rotation", zap.String("replica", backend.Name))
+		}
+	}
+
+	for _, backend := range p.backends {
+		successes, failures := backend.successes.Swap(0), backend.failures.Swap(0)
+		if backend.ejected(now) {
+			continue
+		}
+		requests := successes + failures
+		if requests < int64(o.config.MinRequests) {
+			continue
+		}
+		if float64(failures)*100 < o.config.FailurePercent*float64(requests) {
+			// A healthy interval shortens the next ejection
+			if backend.multiplier > 0 {
+				backend.multiplier--
+			}
+			continue
+		}
+		if ejected >= maxEjected {
+			p.logger.Warn("Replica is failing but the ejection limit is reached",
+				zap.String("replica", backend.Name),
+				zap.Int64("requests", requests),
+				zap.Int64("failures", failures))
+			continue
+		}
+
+		backend.multiplier++
+		duration := o.config.BaseEjectionTime * time.Duration(backend.multiplier)
+		backend.ejectedUntil.Store(now.Add(duration).UnixNano())
+		backend.ejections.Add(1)
+		ejected++
+		p.logger.Warn("Ejecting replica from rotation",
+			zap.String("replica", backend.Name),
+			zap.Int64("requests", requests),
+			zap.Int64("failures", failures),
+			zap.Duration("duration", duration))
+	}
+}
+
+// available returns the replicas in rotation; when every replica is ejected
+// all of them are used, since refusing every call helps nobody
+func (p *Pool) available() []*Backend {
+	if p.outliers == nil {
+		return p.backends
+	}
+	now := p.now()
+	backends := make([]*Backend, 0, len(p.backends))
+	for _, backend := range p.backends {
+		if !backend.ejected(now) {
+			backends = append(backends, backend)
+		}
+	}
+	if len(backends) == 0 {
+		return p.backends
+	}
+	return backends
+}
+
+func (b *Backend) ejected(now time.Time) bool {
+	until := b.ejectedUntil.Load()
+	return until != 0 && now.UnixNano() < until
+}
+
+// now returns the current time, as seen by the outlier detector when enabled
+func (p *Pool) now() time.Time {
+	if p.outliers != nil {
+		return p.outliers.now()
+	}
+	return time.Now()
+}
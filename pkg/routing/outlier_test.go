@@ -0,0 +1,136 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newOutlierPool(t *testing.T, sticky bool, names ...string) (*Pool, []*stubDiscoverer, *time.Time) {
+	t.Helper()
+	pool, stubs := newTestPool(t, sticky, names...)
+	require.NoError(t, pool.EnableOutlierDetection(config.Default().Routing.OutlierDetection))
+	now := time.Unix(1700000000, 0)
+	pool.outliers.now = func() time.Time { return now }
+	return pool, stubs, &now
+}
+
+func invokeN(t *testing.T, pool *Pool, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		_, _ = pool.InvokeMethodByTool(context.Background(), nil, "svc_method", "{}")
+	}
+}
+
+func TestOutlier_EjectsFailingReplica(t *testing.T) {
+	pool, stubs, now := newOutlierPool(t, false, "a:1", "b:1", "c:1")
+	stubs[1].err = status.Error(codes.Unavailable, "down")
+
+	invokeN(t, pool, 30)
+	*now = now.Add(10 * time.Second)
+	invokeN(t, pool, 1) // evaluates the interval
+
+	stats := pool.Stats()
+	assert.True(t, stats[1].Ejected)
+	assert.Equal(t, int64(1), stats[1].Ejections)
+	assert.False(t, stats[0].Ejected)
+
+	// The ejected replica receives no calls
+	stubs[1].calls.Store(0)
+	invokeN(t, pool, 10)
+	assert.Zero(t, stubs[1].calls.Load())
+
+	// It returns to rotation once the ejection expires
+	*now = now.Add(30 * time.Second)
+	invokeN(t, pool, 10)
+	assert.Greater(t, stubs[1].calls.Load(), int64(0))
+}
+
+func TestOutlier_ConsecutiveEjectionsGrow(t *testing.T) {
+	pool, stubs, now := newOutlierPool(t, false, "a:1", "b:1")
+	stubs[1].err = status.Error(codes.Unavailable, "down")
+	invokeN(t, pool, 1)
+
+	for i := 1; i <= 2; i++ {
+		invokeN(t, pool, 20)
+		*now = now.Add(10 * time.Second)
+		invokeN(t, pool, 1)
+		require.True(t, pool.Stats()[1].Ejected, "round %d", i)
+
+		// Ejected for BaseEjectionTime times the consecutive ejections
+		*now = now.Add(time.Duration(i)*30*time.Second - time.Second)
+		assert.True(t, pool.Stats()[1].Ejected)
+		*now = now.Add(time.Second)
+		assert.False(t, pool.Stats()[1].Ejected)
+	}
+}
+
+func TestOutlier_MaxEjectionPercent(t *testing.T) {
+	pool, stubs, now := newOutlierPool(t, false, "a:1", "b:1")
+	for _, stub := range stubs {
+		stub.err = status.Error(codes.Unavailable, "down")
+	}
+
+	invokeN(t, pool, 20)
+	*now = now.Add(10 * time.Second)
+	invokeN(t, pool, 1)
+
+	// Only half of the pool may be ejected
+	stats := pool.Stats()
+	assert.Equal(t, 1, boolCount(stats[0].Ejected, stats[1].Ejected))
+}
+
+func TestOutlier_IgnoresClientErrors(t *testing.T) {
+	pool, stubs, now := newOutlierPool(t, false, "a:1", "b:1")
+	stubs[1].err = status.Error(codes.InvalidArgument, "bad input")
+
+	invokeN(t, pool, 20)
+	*now = now.Add(10 * time.Second)
+	invokeN(t, pool, 1)
+	assert.False(t, pool.Stats()[1].Ejected)
+}
+
+func TestOutlier_StickySessionsOnHealthyReplicasStay(t *testing.T) {
+	pool, stubs, now := newOutlierPool(t, true, "a:1", "b:1", "c:1")
+
+	routes := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprintf("session-%d", i)
+		routes[id] = pool.Route(id)
+	}
+
+	stubs[2].err = status.Error(codes.Unavailable, "down")
+	for id := range routes {
+		ctx := session.NewContextWithID(context.Background(), id)
+		_, _ = pool.InvokeMethodByTool(ctx, nil, "svc_method", "{}")
+	}
+	*now = now.Add(10 * time.Second)
+	invokeN(t, pool, 1)
+	require.True(t, pool.Stats()[2].Ejected)
+
+	for id, before := range routes {
+		after := pool.Route(id)
+		assert.NotEqual(t, "c:1", after)
+		if before != "c:1" {
+			assert.Equal(t, before, after)
+		}
+	}
+}
+
+func boolCount(values ...bool) int {
+	n := 0
+	for _, v := range values {
+		if v {
+			n++
+		}
+	}
+	return n
+}
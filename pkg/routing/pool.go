@@ -0,0 +1,183 @@
+// Package routing spreads tool calls over several replicas of the same
+// upstream. With sticky routing every call of a session lands on the same
+// replica (rendezvous hashing on the session ID), so stateful upstream flows
+// such as paginated cursors or server-side caches behave correctly.
+package routing
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync/atomic"
+
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"go.uber.org/zap"
+)
+
+// Backend is one replica in the pool
+type Backend struct {
+	// Name identifies the replica (usually host:port) and seeds its hash
+	Name       string
+	Discoverer grpc.ServiceDiscoverer
+
+	calls atomic.Int64
+
+	// Outlier detection state
+	successes    atomic.Int64
+	failures     atomic.Int64
+	ejectedUntil atomic.Int64 // unix nanoseconds, 0 while in rotation
+	ejections    atomic.Int64
+	multiplier   int // consecutive ejections, guarded by outlierDetector.mu
+}
+
+// BackendStats reports per-replica routing counters
+type BackendStats struct {
+	Name      string `json:"name"`
+	Calls     int64  `json:"calls"`
+	Ejected   bool   `json:"ejected,omitempty"`
+	Ejections int64  `json:"ejections,omitempty"`
+}
+
+// Pool wraps the primary discoverer and routes calls over all replicas.
+// Discovery, tool listing and health checks are served by the primary.
+type Pool struct {
+	grpc.ServiceDiscoverer
+
+	backends []*Backend
+	sticky   bool
+	next     atomic.Uint64
+	outliers *outlierDetector
+	logger   *zap.Logger
+}
+
+// NewPool creates a pool over backends; the first backend is the primary.
+// Replicas must already be connected and have discovered their services.
+func NewPool(backends []*Backend, sticky bool, logger *zap.Logger) (*Pool, error) {
+	if len(backends) == 0 {
+		return nil, errors.New("routing pool requires at least one backend")
+	}
+	return &Pool{
+		ServiceDiscoverer: backends[0].Discoverer,
+		backends:          backends,
+		sticky:            sticky,
+		logger:            logger.Named("routing"),
+	}, nil
+}
+
+// InvokeMethodByTool forwards the call to the replica chosen for the session
+func (p *Pool) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	backend := p.pick(session.IDFromContext(ctx))
+
+	// A replica that does not (yet) expose the tool falls back to the primary
+	if backend != p.backends[0] {
+		if _, ok := backend.Discoverer.GetMethod(toolName); !ok {
+			p.logger.Debug("Replica does not expose tool, using primary",
+				zap.String("replica", backend.Name),
+				zap.String("toolName", toolName))
+			backend = p.backends[0]
+		}
+	}
+
+	backend.calls.Add(1)
+	result, err := backend.Discoverer.InvokeMethodByTool(ctx, headers, toolName, inputJSON)
+	p.record(ctx, backend, err)
+	return result, err
+}
+
+// Route returns the name of the replica serving the given session
+func (p *Pool) Route(sessionID string) string {
+	return p.pick(sessionID).Name
+}
+
+// pick selects a replica in rotation: sticky sessions use rendezvous hashing,
+// anything else (including calls without a session) is spread round-robin.
+// Ejecting a replica only moves the sessions pinned to it.
+func (p *Pool) pick(sessionID string) *Backend {
+	backends := p.available()
+	if len(backends) == 1 {
+		return backends[0]
+	}
+	if p.sticky && sessionID != "" {
+		return rendezvous(backends, sessionID)
+	}
+	return backends[(p.next.Add(1)-1)%uint64(len(backends))]
+}
+
+// rendezvous returns the backend with the highest hash for the key. Adding or
+// removing a backend only moves the sessions that hash to that backend.
+func rendezvous(backends []*Backend, key string) *Backend {
+	var best *Backend
+	var bestScore uint64
+	for _, backend := range backends {
+		if s := score(backend.Name, key); best == nil || s > bestScore {
+			best, bestScore = backend, s
+		}
+	}
+	return best
+}
+
+func score(name, key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(key))
+	return mix(h.Sum64())
+}
+
+// mix is the splitmix64 finalizer; it spreads FNV's weak low bits
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Stats returns per-replica routing counters
+func (p *Pool) Stats() []BackendStats {
+	stats := make([]BackendStats, 0, len(p.backends))
+	now := p.now()
+	for _, backend := range p.backends {
+		stats = append(stats, BackendStats{
+			Name:      backend.Name,
+			Calls:     backend.calls.Load(),
+			Ejected:   backend.ejected(now),
+			Ejections: backend.ejections.Load(),
+		})
+	}
+	return stats
+}
+
+// GetServiceStats adds routing stats to the primary stats
+func (p *Pool) GetServiceStats() map[string]interface{} {
+	stats := p.ServiceDiscoverer.GetServiceStats()
+	if stats == nil {
+		stats = make(map[string]interface{})
+	}
+	strategy := "round_robin"
+	if p.sticky {
+		strategy = "sticky"
+	}
+	stats["routing"] = map[string]interface{}{
+		"strategy": strategy,
+		"backends": p.Stats(),
+	}
+	return stats
+}
+
+// Unwrap returns the primary discoverer
+func (p *Pool) Unwrap() grpc.ServiceDiscoverer {
+	return p.ServiceDiscoverer
+}
+
+// Close closes all replicas and the primary
+func (p *Pool) Close() error {
+	for _, backend := range p.backends[1:] {
+		if err := backend.Discoverer.Close(); err != nil {
+			p.logger.Warn("Failed to close replica", zap.String("replica", backend.Name), zap.Error(err))
+		}
+	}
+	return p.ServiceDiscoverer.Close()
+}
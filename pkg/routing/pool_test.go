@@ -0,0 +1,128 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type stubDiscoverer struct {
+	name   string
+	tools  map[string]bool
+	calls  atomic.Int64
+	closed atomic.Bool
+	err    error
+}
+
+func (s *stubDiscoverer) Connect(ctx context.Context) error          { return nil }
+func (s *stubDiscoverer) DiscoverServices(ctx context.Context) error { return nil }
+func (s *stubDiscoverer) GetMethods() []types.MethodInfo             { return nil }
+func (s *stubDiscoverer) HealthCheck(ctx context.Context) error      { return nil }
+func (s *stubDiscoverer) GetMethodCount() int                        { return len(s.tools) }
+func (s *stubDiscoverer) Close() error                               { s.closed.Store(true); return nil }
+func (s *stubDiscoverer) GetServiceStats() map[string]interface{}    { return map[string]interface{}{} }
+
+func (s *stubDiscoverer) GetMethod(toolName string) (types.MethodInfo, bool) {
+	return types.MethodInfo{ToolName: toolName}, s.tools == nil || s.tools[toolName]
+}
+
+func (s *stubDiscoverer) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	s.calls.Add(1)
+	if s.err != nil {
+		return "", s.err
+	}
+	return s.name, nil
+}
+
+func newTestPool(t *testing.T, sticky bool, names ...string) (*Pool, []*stubDiscoverer) {
+	t.Helper()
+	var backends []*Backend
+	var stubs []*stubDiscoverer
+	for _, name := range names {
+		stub := &stubDiscoverer{name: name}
+		stubs = append(stubs, stub)
+		backends = append(backends, &Backend{Name: name, Discoverer: stub})
+	}
+	pool, err := NewPool(backends, sticky, zap.NewNop())
+	require.NoError(t, err)
+	return pool, stubs
+}
+
+func TestPool_StickySessions(t *testing.T) {
+	pool, _ := newTestPool(t, true, "a:1", "b:1", "c:1")
+
+	seen := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		sessionID := fmt.Sprintf("session-%d", i)
+		ctx := session.NewContextWithID(context.Background(), sessionID)
+
+		first, err := pool.InvokeMethodByTool(ctx, nil, "svc_method", "{}")
+		require.NoError(t, err)
+		for j := 0; j < 3; j++ {
+			again, err := pool.InvokeMethodByTool(ctx, nil, "svc_method", "{}")
+			require.NoError(t, err)
+			assert.Equal(t, first, again, "session %s moved between replicas", sessionID)
+		}
+		assert.Equal(t, first, pool.Route(sessionID))
+		seen[first]++
+	}
+
+	// Sessions are spread over every replica
+	assert.Len(t, seen, 3)
+	for name, count := range seen {
+		assert.Greater(t, count, 50, "replica %s is underused", name)
+	}
+}
+
+func TestPool_AddingReplicaOnlyMovesSessionsToIt(t *testing.T) {
+	before, _ := newTestPool(t, true, "a:1", "b:1")
+	after, _ := newTestPool(t, true, "a:1", "b:1", "c:1")
+
+	moved := 0
+	for i := 0; i < 500; i++ {
+		sessionID := fmt.Sprintf("session-%d", i)
+		if before.Route(sessionID) != after.Route(sessionID) {
+			moved++
+			assert.Equal(t, "c:1", after.Route(sessionID))
+		}
+	}
+	assert.Greater(t, moved, 0)
+}
+
+func TestPool_NoSessionRoundRobin(t *testing.T) {
+	pool, stubs := newTestPool(t, true, "a:1", "b:1")
+
+	for i := 0; i < 4; i++ {
+		_, err := pool.InvokeMethodByTool(context.Background(), nil, "svc_method", "{}")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int64(2), stubs[0].calls.Load())
+	assert.Equal(t, int64(2), stubs[1].calls.Load())
+}
+
+func TestPool_MissingToolFallsBackToPrimary(t *testing.T) {
+	pool, stubs := newTestPool(t, false, "primary:1", "replica:1")
+	stubs[1].tools = map[string]bool{"svc_other": true}
+
+	for i := 0; i < 4; i++ {
+		result, err := pool.InvokeMethodByTool(context.Background(), nil, "svc_method", "{}")
+		require.NoError(t, err)
+		assert.Equal(t, "primary:1", result)
+	}
+	assert.Zero(t, stubs[1].calls.Load())
+
+	stats := pool.GetServiceStats()["routing"].(map[string]interface{})
+	assert.Equal(t, "round_robin", stats["strategy"])
+	assert.Equal(t, []BackendStats{{Name: "primary:1", Calls: 4}, {Name: "replica:1", Calls: 0}}, stats["backends"])
+
+	require.NoError(t, pool.Close())
+	assert.True(t, stubs[0].closed.Load())
+	assert.True(t, stubs[1].closed.Load())
+}
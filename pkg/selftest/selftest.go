@@ -0,0 +1,253 @@
+// Package selftest checks that every discovered method yields a usable MCP
+// tool: the tool builds, its schemas are well formed and, optionally, a
+// sample input generated from the input schema survives a protojson round
+// trip through the method's input message. It backs "grmcp selftest", which
+// CI can run against a backend to catch proto changes the gateway cannot
+// serve before they are deployed.
+package selftest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aalobaidi/ggRMCP/pkg/examples"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/tools"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Severity of an issue; only errors fail the self-test
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is a problem found with one tool
+type Issue struct {
+	Severity string `json:"severity"`
+	Tool     string `json:"tool"`
+	Message  string `json:"message"`
+}
+
+// String returns the issue formatted for command line output
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Severity, i.Tool, i.Message)
+}
+
+// Options selects the optional checks
+type Options struct {
+	// RoundTrip dry-runs a protojson round trip of a sample input per tool
+	RoundTrip bool
+
+	// ServerStreaming matches tools.server_streaming.enabled: when set,
+	// server-streaming methods are expected to become tools
+	ServerStreaming bool
+}
+
+// Report is the outcome of a self-test
+type Report struct {
+	Methods int     `json:"methods"`
+	Tools   int     `json:"tools"`
+	Issues  []Issue `json:"issues"`
+}
+
+// HasErrors reports whether any issue fails the self-test
+func (r *Report) HasErrors() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Run builds and checks the tool of every method. Issues are sorted by
+// severity (errors first) and tool name.
+func Run(methods []types.MethodInfo, builder *tools.MCPToolBuilder, opts Options) *Report {
+	report := &Report{Methods: len(methods)}
+	add := func(severity, tool, format string, args ...interface{}) {
+		report.Issues = append(report.Issues, Issue{Severity: severity, Tool: tool, Message: fmt.Sprintf(format, args...)})
+	}
+
+	validator := mcp.NewValidator()
+	seen := make(map[string]string)
+	for _, method := range methods {
+		toolName := method.GenerateToolName()
+		if method.IsClientStreaming || (method.IsServerStreaming && !opts.ServerStreaming) {
+			add(SeverityWarning, toolName, "%s is a streaming method and is not exposed as a tool", method.FullName)
+			continue
+		}
+
+		tool, err := builder.BuildTool(method)
+		if err != nil {
+			add(SeverityError, toolName, "failed to build tool: %v", err)
+			continue
+		}
+		report.Tools++
+
+		if other, ok := seen[tool.Name]; ok {
+			add(SeverityError, tool.Name, "tool name is also generated for %s", other)
+		}
+		seen[tool.Name] = method.FullName
+		if err := validator.ValidateTool(&tool); err != nil {
+			add(SeverityError, tool.Name, "invalid tool: %v", err)
+		}
+
+		for _, check := range []struct {
+			name   string
+			schema interface{}
+		}{{"inputSchema", tool.InputSchema}, {"outputSchema", tool.OutputSchema}} {
+			if check.schema == nil {
+				continue
+			}
+			for _, issue := range CheckSchema(check.schema) {
+				issue.Tool = tool.Name
+				issue.Message = check.name + issue.Message
+				report.Issues = append(report.Issues, issue)
+			}
+		}
+
+		if opts.RoundTrip {
+			if err := RoundTrip(method, tool.InputSchema); err != nil {
+				add(SeverityError, tool.Name, "%v", err)
+			}
+		}
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.Severity != b.Severity {
+			return a.Severity == SeverityError
+		}
+		return a.Tool < b.Tool
+	})
+	return report
+}
+
+// validTypes are the JSON Schema type names
+var validTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "integer": true,
+	"number": true, "boolean": true, "null": true,
+}
+
+// CheckSchema reports structural problems of a generated JSON schema. Issue
+// messages start with the JSON path of the problem (e.g.
+// ".properties.user: unknown type "obj""); Tool is left empty.
+func CheckSchema(schema interface{}) []Issue {
+	root, _ := schema.(map[string]interface{})
+	var issues []Issue
+	checkSchema("", schema, root, &issues)
+	return issues
+}
+
+func checkSchema(path string, schema interface{}, root map[string]interface{}, issues *[]Issue) {
+	add := func(severity, format string, args ...interface{}) {
+		*issues = append(*issues, Issue{Severity: severity, Message: path + ": " + fmt.Sprintf(format, args...)})
+	}
+
+	s, ok := schema.(map[string]interface{})
+	if !ok {
+		add(SeverityError, "schema is a %T, not an object", schema)
+		return
+	}
+	if _, err := json.Marshal(s); err != nil {
+		add(SeverityError, "schema cannot be encoded as JSON: %v", err)
+		return
+	}
+
+	if t, ok := s["type"]; ok {
+		if name, _ := t.(string); !validTypes[name] {
+			add(SeverityError, "unknown type %v", t)
+		}
+	}
+
+	if ref, ok := s["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/definitions/")
+		definitions, _ := root["definitions"].(map[string]interface{})
+		if _, ok := definitions[name]; !ok || name == ref {
+			add(SeverityWarning, "unresolved $ref %q; the recursive field is not described to clients", ref)
+		}
+	}
+
+	if enum, ok := s["enum"]; ok && sliceLen(enum) == 0 {
+		add(SeverityError, "enum has no values")
+	}
+
+	properties, _ := s["properties"].(map[string]interface{})
+	if p, ok := s["properties"]; ok && properties == nil {
+		add(SeverityError, "properties is a %T, not an object", p)
+	}
+	for _, name := range sortedKeys(properties) {
+		checkSchema(path+".properties."+name, properties[name], root, issues)
+	}
+	for _, name := range examples.StringSlice(s["required"]) {
+		if _, ok := properties[name]; !ok {
+			add(SeverityError, "required property %q is not defined", name)
+		}
+	}
+
+	if items, ok := s["items"]; ok {
+		checkSchema(path+".items", items, root, issues)
+	}
+	if patterns, ok := s["patternProperties"].(map[string]interface{}); ok {
+		for _, pattern := range sortedKeys(patterns) {
+			checkSchema(path+".patternProperties."+pattern, patterns[pattern], root, issues)
+		}
+	}
+	if options, ok := s["oneOf"].([]interface{}); ok {
+		for i, option := range options {
+			checkSchema(fmt.Sprintf("%s.oneOf[%d]", path, i), option, root, issues)
+		}
+	}
+}
+
+// RoundTrip generates example arguments from the tool's input schema, parses
+// them into the method's input message and checks that marshalling the
+// message and parsing it again yields the same message
+func RoundTrip(method types.MethodInfo, inputSchema interface{}) error {
+	sample, err := json.Marshal(examples.Arguments(inputSchema, examples.Options{}))
+	if err != nil {
+		return fmt.Errorf("failed to encode sample input: %w", err)
+	}
+
+	msg := dynamicpb.NewMessage(method.InputDescriptor)
+	if err := protojson.Unmarshal(sample, msg); err != nil {
+		return fmt.Errorf("sample input %s is rejected by %s: %w", sample, method.InputDescriptor.FullName(), err)
+	}
+	encoded, err := protojson.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", method.InputDescriptor.FullName(), err)
+	}
+	parsed := dynamicpb.NewMessage(method.InputDescriptor)
+	if err := protojson.Unmarshal(encoded, parsed); err != nil {
+		return fmt.Errorf("marshalled %s cannot be parsed again: %w", method.InputDescriptor.FullName(), err)
+	}
+	if !proto.Equal(msg, parsed) {
+		return fmt.Errorf("%s changed in a protojson round trip: %s", method.InputDescriptor.FullName(), encoded)
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sliceLen(v interface{}) int {
+	switch values := v.(type) {
+	case []string:
+		return len(values)
+	case []interface{}:
+		return len(values)
+	}
+	return 0
+}
@@ -0,0 +1,180 @@
+package selftest
+
+import (
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/tools"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// testFile declares selftest.Catalog with a Search method whose request uses
+// every kind of field the schema generator handles
+func testFile(t *testing.T) protoreflect.FileDescriptor {
+	t.Helper()
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	field := func(name string, number int32, kind descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{Name: proto.String(name), JsonName: proto.String(name), Number: proto.Int32(number), Label: optional, Type: kind.Enum()}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	list := func(f *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		f.Label = repeated
+		return f
+	}
+	inOneof := func(f *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		f.OneofIndex = proto.Int32(0)
+		return f
+	}
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("selftest/catalog.proto"),
+		Package:    proto.String("selftest"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto", "google/protobuf/struct.proto"},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name:  proto.String("Order"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{{Name: proto.String("ORDER_UNSPECIFIED"), Number: proto.Int32(0)}, {Name: proto.String("ORDER_NEWEST"), Number: proto.Int32(1)}},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Category"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				list(field("children", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".selftest.Category")),
+			}},
+			{
+				Name: proto.String("SearchRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("query", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("limit", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64, ""),
+					field("min_score", 3, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, ""),
+					field("exact", 4, descriptorpb.FieldDescriptorProto_TYPE_BOOL, ""),
+					field("cursor", 5, descriptorpb.FieldDescriptorProto_TYPE_BYTES, ""),
+					field("order", 6, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".selftest.Order"),
+					list(field("tags", 7, descriptorpb.FieldDescriptorProto_TYPE_STRING, "")),
+					field("since", 8, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Timestamp"),
+					field("filters", 9, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Struct"),
+					field("category", 10, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".selftest.Category"),
+					list(field("labels", 11, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".selftest.SearchRequest.LabelsEntry")),
+					inOneof(field("author", 12, descriptorpb.FieldDescriptorProto_TYPE_STRING, "")),
+					inOneof(field("publisher", 13, descriptorpb.FieldDescriptorProto_TYPE_STRING, "")),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{{
+					Name:    proto.String("LabelsEntry"),
+					Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("key", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+						field("value", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					},
+				}},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("by")}},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Catalog"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: proto.String("Search"), InputType: proto.String(".selftest.SearchRequest"), OutputType: proto.String(".selftest.Category")},
+				{Name: proto.String("Watch"), InputType: proto.String(".selftest.SearchRequest"), OutputType: proto.String(".selftest.Category"), ServerStreaming: proto.Bool(true)},
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	return fd
+}
+
+func testMethods(fd protoreflect.FileDescriptor) []types.MethodInfo {
+	var methods []types.MethodInfo
+	service := fd.Services().Get(0)
+	for i := 0; i < service.Methods().Len(); i++ {
+		m := service.Methods().Get(i)
+		method := types.MethodInfo{
+			Name:              string(m.Name()),
+			FullName:          string(m.FullName()),
+			ServiceName:       string(service.FullName()),
+			InputDescriptor:   m.Input(),
+			OutputDescriptor:  m.Output(),
+			IsServerStreaming: m.IsStreamingServer(),
+		}
+		method.ToolName = method.GenerateToolName()
+		methods = append(methods, method)
+	}
+	return methods
+}
+
+func TestRun(t *testing.T) {
+	methods := testMethods(testFile(t))
+
+	report := Run(methods, tools.NewMCPToolBuilder(zap.NewNop()), Options{RoundTrip: true})
+	assert.False(t, report.HasErrors(), "%v", report.Issues)
+	assert.Equal(t, 2, report.Methods)
+	assert.Equal(t, 1, report.Tools)
+
+	var messages []string
+	for _, issue := range report.Issues {
+		assert.Equal(t, SeverityWarning, issue.Severity)
+		messages = append(messages, issue.Message)
+	}
+	assert.Contains(t, messages, "selftest.Catalog.Watch is a streaming method and is not exposed as a tool")
+	assert.Contains(t, messages, `outputSchema.properties.children.items: unresolved $ref "#/definitions/selftest.Category"; the recursive field is not described to clients`)
+
+	// With server streaming enabled the streaming method becomes a tool too
+	report = Run(methods, tools.NewMCPToolBuilder(zap.NewNop()), Options{RoundTrip: true, ServerStreaming: true})
+	assert.False(t, report.HasErrors(), "%v", report.Issues)
+	assert.Equal(t, 2, report.Tools)
+}
+
+func TestRoundTrip_ExampleArguments(t *testing.T) {
+	method := testMethods(testFile(t))[0]
+	schema, err := tools.NewMCPToolBuilder(zap.NewNop()).ExtractMessageSchema(method.InputDescriptor)
+	require.NoError(t, err)
+
+	// The example sets a single oneof member, enums, timestamps and lists
+	assert.NoError(t, RoundTrip(method, schema))
+}
+
+func TestCheckSchema(t *testing.T) {
+	issues := CheckSchema(map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":     map[string]interface{}{"type": "int"},
+			"status": map[string]interface{}{"type": "string", "enum": []interface{}{}},
+			"items":  map[string]interface{}{"type": "array", "items": "string"},
+		},
+		"required": []string{"id", "name"},
+	})
+
+	var messages []string
+	for _, issue := range issues {
+		assert.Equal(t, SeverityError, issue.Severity)
+		messages = append(messages, issue.Message)
+	}
+	assert.ElementsMatch(t, []string{
+		`.properties.id: unknown type int`,
+		`.properties.items.items: schema is a string, not an object`,
+		`.properties.status: enum has no values`,
+		`: required property "name" is not defined`,
+	}, messages)
+}
+
+func TestRoundTrip_RejectedSample(t *testing.T) {
+	method := testMethods(testFile(t))[0]
+
+	// A schema property the input message does not declare
+	err := RoundTrip(method, map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"page_size": map[string]interface{}{"type": "integer"}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is rejected by selftest.SearchRequest")
+}
@@ -0,0 +1,318 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"github.com/aalobaidi/ggRMCP/pkg/headers"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"go.uber.org/zap"
+)
+
+// ChannelzHandler 处理上游连接自省请求（GET /admin/channelz）
+//
+// 返回网关进程内所有 gRPC 客户端 channel 的 channelz 摘要，
+// 包括连接状态、调用开始/成功/失败计数、最近的错误事件以及底层 socket 统计，
+// 用于直接在网关上诊断连接层面的问题。
+func (h *Handler) ChannelzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	summary, err := grpc.CollectChannelz(ctx)
+	if err != nil {
+		h.logger.Error("Failed to collect channelz data", zap.Error(err))
+		http.Error(w, "Failed to collect channelz data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(summary); err != nil {
+		h.logger.Error("Failed to encode channelz summary", zap.Error(err))
+	}
+}
+
+// WithUpstreamSwitch 设置是否允许通过 POST /admin/upstream/switch 切换上游（默认关闭）
+func WithUpstreamSwitch(cfg config.UpstreamSwitchConfig) HandlerOption {
+	return func(h *Handler) {
+		h.upstreamSwitch = cfg.Enabled
+	}
+}
+
+// switchUpstreamRequest 上游切换请求体
+type switchUpstreamRequest struct {
+	Host  string `json:"host"`
+	Port  int    `json:"port"`
+	Force bool   `json:"force"`
+}
+
+// SwitchUpstreamHandler 处理蓝绿上游切换请求（POST /admin/upstream/switch）
+//
+// 请求体：{"host": "green.internal", "port": 50051, "force": false}
+//
+// 网关会连接新上游并发现服务，校验当前所有工具在新上游中都存在后，
+// 原子地切换工具表和连接。校验失败返回 409，连接或发现失败返回 502，
+// 两种情况下当前上游都保持不变。未启用 upstream_switch 时返回 501。
+func (h *Handler) SwitchUpstreamHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.upstreamSwitch {
+		http.Error(w, "Upstream switching is not enabled", http.StatusNotImplemented)
+		return
+	}
+
+	var req switchUpstreamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Host == "" || req.Port <= 0 || req.Port > 65535 {
+		http.Error(w, "host and a valid port are required", http.StatusBadRequest)
+		return
+	}
+
+	switcher, ok := grpc.Find[grpc.UpstreamSwitcher](h.serviceDiscoverer)
+	if !ok {
+		http.Error(w, "Upstream switching is not supported", http.StatusNotImplemented)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	result, err := switcher.SwitchUpstream(ctx, req.Host, req.Port, req.Force)
+	status := http.StatusOK
+	response := map[string]interface{}{"result": result}
+	if err != nil {
+		h.logger.Warn("Upstream switch rejected", zap.Error(err))
+		status = http.StatusBadGateway
+		if errors.Is(err, grpc.ErrIncompatibleUpstream) {
+			status = http.StatusConflict
+		}
+		response["error"] = err.Error()
+	}
+	// 📣 切换后工具列表有变化时通知打开了事件通道的会话
+	if err == nil && result != nil && result.Switched && (len(result.AddedTools) > 0 || len(result.RemovedTools) > 0) {
+		h.notify(mcp.ToolsListChangedNotificationMethod, nil)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode switch result", zap.Error(err))
+	}
+}
+
+// upstreamPrefixPattern 注册上游的工具名前缀只能包含字母、数字、下划线和连字符
+var upstreamPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// UpstreamsHandler 处理运行时上游注册（GET/POST /admin/upstreams）
+//
+// GET 返回已注册的上游及其工具。POST 注册新上游，请求体：
+//
+//	{"host": "billing.internal", "port": 50051, "prefix": "billing", "tls": {"enabled": true}}
+//
+// 网关连接新上游并发现服务后，一次性加入它的全部工具（名称加上 "<prefix>_" 前缀），
+// 并通知打开了事件通道的会话。工具名冲突、重复注册或达到上限时返回 409，
+// 连接或发现失败返回 502，两种情况下工具列表都保持不变。
+func (h *Handler) UpstreamsHandler(w http.ResponseWriter, r *http.Request) {
+	registrar, ok := grpc.Find[grpc.UpstreamRegistrar](h.serviceDiscoverer)
+	if !ok {
+		http.Error(w, "Upstream registration is not enabled", http.StatusNotImplemented)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"upstreams": registrar.Upstreams()}); err != nil {
+			h.logger.Error("Failed to encode registered upstreams", zap.Error(err))
+		}
+		return
+	}
+
+	var spec grpc.UpstreamSpec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if spec.Host == "" || spec.Port <= 0 || spec.Port > 65535 {
+		http.Error(w, "host and a valid port are required", http.StatusBadRequest)
+		return
+	}
+	if spec.Prefix != "" && !upstreamPrefixPattern.MatchString(spec.Prefix) {
+		http.Error(w, "prefix may only contain letters, digits, '_' and '-'", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	registered, err := registrar.RegisterUpstream(ctx, spec)
+	if err != nil {
+		h.logger.Warn("Upstream registration rejected", zap.String("host", spec.Host), zap.Int("port", spec.Port), zap.Error(err))
+		status := http.StatusBadGateway
+		if errors.Is(err, grpc.ErrUpstreamConflict) {
+			status = http.StatusConflict
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+		return
+	}
+	// 📣 新上游的工具已加入，通知打开了事件通道的会话
+	h.notify(mcp.ToolsListChangedNotificationMethod, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(registered); err != nil {
+		h.logger.Error("Failed to encode registered upstream", zap.Error(err))
+	}
+}
+
+// RemoveUpstreamHandler 移除运行时注册的上游（DELETE /admin/upstreams/{name}，name 为 host:port）
+//
+// 上游的全部工具立即从工具列表中移除，并通知打开了事件通道的会话；
+// 进行中的调用继续完成，之后（最迟 drain_timeout 后）关闭上游连接。未注册的上游返回 404。
+func (h *Handler) RemoveUpstreamHandler(w http.ResponseWriter, r *http.Request) {
+	registrar, ok := grpc.Find[grpc.UpstreamRegistrar](h.serviceDiscoverer)
+	if !ok {
+		http.Error(w, "Upstream registration is not enabled", http.StatusNotImplemented)
+		return
+	}
+
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/upstreams"), "/")
+	if name == "" {
+		http.Error(w, "upstream name is required", http.StatusBadRequest)
+		return
+	}
+
+	removed, err := registrar.UnregisterUpstream(name)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, grpc.ErrUpstreamNotFound) {
+			status = http.StatusNotFound
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+		return
+	}
+	// 📣 上游的工具已移除，通知打开了事件通道的会话
+	h.notify(mcp.ToolsListChangedNotificationMethod, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(removed); err != nil {
+		h.logger.Error("Failed to encode removed upstream", zap.Error(err))
+	}
+}
+
+// SnapshotHandler 导出服务发现快照（GET /admin/snapshot）
+//
+// 返回当前已发现方法及其描述符的 JSON 快照，保存后可通过
+// grpc.snapshot_path（或 --snapshot）让网关跳过服务发现直接启动。
+func (h *Handler) SnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	snapshotter, ok := grpc.Find[grpc.Snapshotter](h.serviceDiscoverer)
+	if !ok {
+		http.Error(w, "Discovery snapshots are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	snapshot, err := snapshotter.Snapshot()
+	if err != nil {
+		h.logger.Error("Failed to build discovery snapshot", zap.Error(err))
+		http.Error(w, "Failed to build discovery snapshot", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="snapshot.json"`)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(snapshot); err != nil {
+		h.logger.Error("Failed to encode discovery snapshot", zap.Error(err))
+	}
+}
+
+// DiscoveryReportHandler 返回最近一次服务发现的报告（GET /admin/discovery）
+//
+// 报告包含使用的发现方式、服务和方法数量，以及因描述符错误被跳过的
+// 服务和方法；尚未完成服务发现时返回 503。
+func (h *Handler) DiscoveryReportHandler(w http.ResponseWriter, r *http.Request) {
+	reporter, ok := grpc.Find[grpc.DiscoveryReporter](h.serviceDiscoverer)
+	if !ok {
+		http.Error(w, "Discovery reports are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	report := reporter.DiscoveryReport()
+	if report == nil {
+		http.Error(w, "Service discovery has not completed", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		h.logger.Error("Failed to encode discovery report", zap.Error(err))
+	}
+}
+
+// headerDryRunRequest 请求头过滤预演请求体
+type headerDryRunRequest struct {
+	Headers map[string]string `json:"headers"`
+}
+
+// headerDryRunResponse 请求头过滤预演结果
+type headerDryRunResponse struct {
+	Enabled   bool               `json:"enabled"`
+	Decisions []headers.Decision `json:"decisions"`
+	Forwarded []string           `json:"forwarded"`
+}
+
+// HeaderFilterDryRunHandler 预演请求头过滤（POST /admin/headers/dry-run）
+//
+// 请求体：{"headers": {"Authorization": "Bearer ...", "Cookie": "a=b"}}
+//
+// 对每个请求头返回转发或丢弃的决定、起决定作用的配置项，以及转发时
+// 使用的 gRPC metadata 键，无需阅读 debug 日志即可排查 allow/deny 配置。
+// 请求头的值只用于与真实请求保持一致，不会出现在响应中。
+func (h *Handler) HeaderFilterDryRunHandler(w http.ResponseWriter, r *http.Request) {
+	var req headerDryRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	names := make([]string, 0, len(req.Headers))
+	for name := range req.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	response := headerDryRunResponse{
+		Enabled:   h.headerFilter.IsEnabled(),
+		Decisions: make([]headers.Decision, 0, len(names)),
+		Forwarded: make([]string, 0, len(names)),
+	}
+	for _, name := range names {
+		decision := h.headerFilter.Explain(name)
+		response.Decisions = append(response.Decisions, decision)
+		if decision.Action == headers.ActionForward {
+			response.Forwarded = append(response.Forwarded, decision.MetadataKey)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode header dry-run result", zap.Error(err))
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"github.com/aalobaidi/ggRMCP/pkg/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// registrarDiscoverer accepts upstreams unless their host is "taken"
+type registrarDiscoverer struct {
+	*mockServiceDiscoverer
+	registered []grpc.RegisteredUpstream
+}
+
+func (d *registrarDiscoverer) RegisterUpstream(ctx context.Context, spec grpc.UpstreamSpec) (*grpc.RegisteredUpstream, error) {
+	if spec.Host == "taken" {
+		return nil, fmt.Errorf("%w: tools [orders_get] already exist", grpc.ErrUpstreamConflict)
+	}
+	registered := grpc.RegisteredUpstream{Name: fmt.Sprintf("%s:%d", spec.Host, spec.Port), Prefix: spec.Prefix, Tools: []string{spec.Prefix + "_orders_get"}}
+	d.registered = append(d.registered, registered)
+	return &registered, nil
+}
+
+func (d *registrarDiscoverer) UnregisterUpstream(name string) (*grpc.RegisteredUpstream, error) {
+	for i, registered := range d.registered {
+		if registered.Name == name {
+			d.registered = append(d.registered[:i], d.registered[i+1:]...)
+			return &registered, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", grpc.ErrUpstreamNotFound, name)
+}
+
+func (d *registrarDiscoverer) Upstreams() []grpc.RegisteredUpstream {
+	return d.registered
+}
+
+func TestUpstreamsHandler(t *testing.T) {
+	discoverer := &registrarDiscoverer{mockServiceDiscoverer: &mockServiceDiscoverer{}}
+	logger := zap.NewNop()
+	sessionManager := session.NewManager(logger)
+	t.Cleanup(func() { _ = sessionManager.Close() })
+	handler := NewHandler(logger, discoverer, sessionManager, tools.NewMCPToolBuilder(logger), config.Default().GRPC.HeaderForwarding)
+	request := func(method, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.UpstreamsHandler(w, httptest.NewRequest(method, "/admin/upstreams", strings.NewReader(body)))
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, `{"host":"billing"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, `{"host":"billing","port":50051,"prefix":"bill ing"}`).Code)
+	assert.Equal(t, http.StatusConflict, request(http.MethodPost, `{"host":"taken","port":50051}`).Code)
+
+	w := request(http.MethodPost, `{"host":"billing","port":50051,"prefix":"billing"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var registered grpc.RegisteredUpstream
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&registered))
+	assert.Equal(t, []string{"billing_orders_get"}, registered.Tools)
+
+	w = request(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"billing:50051"`)
+
+	// Removing an upstream returns its tools; unknown upstreams are not found
+	remove := func(name string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.RemoveUpstreamHandler(w, httptest.NewRequest(http.MethodDelete, "/admin/upstreams/"+name, nil))
+		return w
+	}
+	w = remove("billing:50051")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"billing_orders_get"`)
+	assert.Empty(t, discoverer.registered)
+	assert.Equal(t, http.StatusNotFound, remove("billing:50051").Code)
+
+	// Without registration support the endpoint is not implemented
+	w = httptest.NewRecorder()
+	plain, _ := newTestHandler(t, &mockServiceDiscoverer{})
+	plain.UpstreamsHandler(w, httptest.NewRequest(http.MethodGet, "/admin/upstreams", nil))
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
+func TestSwitchUpstreamHandler_DisabledByDefault(t *testing.T) {
+	handler, _ := newTestHandler(t, &mockServiceDiscoverer{})
+	w := httptest.NewRecorder()
+	handler.SwitchUpstreamHandler(w, httptest.NewRequest(http.MethodPost, "/admin/upstream/switch", strings.NewReader(`{"host":"green","port":50051}`)))
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+	assert.Contains(t, w.Body.String(), "not enabled")
+
+	// Once enabled, a discoverer that cannot switch is still not implemented
+	handler, _ = newTestHandler(t, &mockServiceDiscoverer{}, WithUpstreamSwitch(config.UpstreamSwitchConfig{Enabled: true}))
+	w = httptest.NewRecorder()
+	handler.SwitchUpstreamHandler(w, httptest.NewRequest(http.MethodPost, "/admin/upstream/switch", strings.NewReader(`{"host":"green","port":50051}`)))
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+	assert.Contains(t, w.Body.String(), "not supported")
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"go.uber.org/zap"
+)
+
+// AdminAuthMiddleware protects the /admin endpoints: requests must carry the
+// admin token read from cfg.TokenEnv as a bearer token or get HTTP 401.
+// Without a configured token the endpoints are disabled and answer HTTP 403.
+func AdminAuthMiddleware(cfg config.AdminConfig, logger *zap.Logger) (Middleware, error) {
+	var token []byte
+	if cfg.TokenEnv != "" {
+		value := os.Getenv(cfg.TokenEnv)
+		if value == "" {
+			return nil, fmt.Errorf("environment variable %s is empty", cfg.TokenEnv)
+		}
+		token = []byte(value)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == nil {
+				http.Error(w, "Admin endpoints are disabled; set server.admin.token_env", http.StatusForbidden)
+				return
+			}
+
+			// 🔒 常量时间比较，避免通过响应时间逐字节猜出令牌
+			presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(presented)), token) != 1 {
+				logger.Warn("Rejected admin request",
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+					zap.String("remote_addr", r.RemoteAddr))
+				w.Header().Set("WWW-Authenticate", `Bearer realm="ggrmcp-admin"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAdminAuthMiddleware(t *testing.T) {
+	t.Setenv("TEST_ADMIN_TOKEN", "s3cret")
+	middleware, err := AdminAuthMiddleware(config.AdminConfig{TokenEnv: "TEST_ADMIN_TOKEN"}, zap.NewNop())
+	require.NoError(t, err)
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+	}{
+		{"valid token", "Bearer s3cret", http.StatusOK},
+		{"wrong token", "Bearer guess", http.StatusUnauthorized},
+		{"missing token", "", http.StatusUnauthorized},
+		{"wrong scheme", "Basic s3cret", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/upstream/switch", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestAdminAuthMiddleware_DisabledWithoutToken(t *testing.T) {
+	middleware, err := AdminAuthMiddleware(config.AdminConfig{}, zap.NewNop())
+	require.NoError(t, err)
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/channelz", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	_, err = AdminAuthMiddleware(config.AdminConfig{TokenEnv: "TEST_ADMIN_TOKEN_UNSET"}, zap.NewNop())
+	assert.Error(t, err)
+}
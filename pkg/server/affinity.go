@@ -0,0 +1,105 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"go.uber.org/zap"
+)
+
+// affinityForwardedHeader 标记已由其他副本转发的请求，防止转发循环
+const affinityForwardedHeader = "X-Ggrmcp-Forwarded-By"
+
+// affinityTarget 返回请求固定到的副本 ID：Header 优先于 Cookie
+func affinityTarget(r *http.Request, cfg config.AffinityConfig) string {
+	if cfg.Header != "" {
+		if target := r.Header.Get(cfg.Header); target != "" {
+			return target
+		}
+	}
+	if cfg.Cookie != "" {
+		if cookie, err := r.Cookie(cfg.Cookie); err == nil {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+// setAffinity 在响应中写入副本 ID；maxAge 为负时删除 Cookie
+func setAffinity(w http.ResponseWriter, r *http.Request, cfg config.AffinityConfig, replicaID string, maxAge int) {
+	if cfg.Header != "" && maxAge >= 0 {
+		w.Header().Set(cfg.Header, replicaID)
+	}
+	if cfg.Cookie != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     cfg.Cookie,
+			Value:    replicaID,
+			Path:     "/",
+			MaxAge:   maxAge,
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+}
+
+// AffinityMiddleware keeps clients on the replica holding their session.
+// Responses served here name this replica in the affinity cookie and header.
+// Requests pinned to a configured peer are forwarded to it; requests pinned
+// to an unknown replica are served here, which re-pins them. When a peer
+// cannot be reached the cookie is cleared and the client is asked to retry,
+// landing on any replica.
+func AffinityMiddleware(cfg config.AffinityConfig, logger *zap.Logger) (Middleware, error) {
+	if cfg.ReplicaID == "" {
+		return nil, fmt.Errorf("affinity requires a replica ID")
+	}
+
+	proxies := make(map[string]*httputil.ReverseProxy, len(cfg.Peers))
+	for id, peer := range cfg.Peers {
+		if id == cfg.ReplicaID {
+			continue
+		}
+		target, err := url.Parse(peer)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer URL for %s: %w", id, err)
+		}
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			logger.Warn("Failed to forward request to session replica",
+				zap.String("replica", id),
+				zap.String("sessionId", r.Header.Get("Mcp-Session-Id")),
+				zap.Error(err))
+			setAffinity(w, r, cfg, "", -1)
+			w.Header().Set("Retry-After", "1")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(&mcp.JSONRPCResponse{
+				JSONRPC: "2.0",
+				Error: &mcp.RPCError{
+					Code:    mcp.ErrorCodeInternalError,
+					Message: "Session replica unavailable",
+				},
+			})
+		}
+		proxies[id] = proxy
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			target := affinityTarget(r, cfg)
+			if proxy, ok := proxies[target]; ok && r.Header.Get(affinityForwardedHeader) == "" {
+				r.Header.Set(affinityForwardedHeader, cfg.ReplicaID)
+				proxy.ServeHTTP(w, r)
+				return
+			}
+
+			setAffinity(w, r, cfg, cfg.ReplicaID, 0)
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// affinityReplica serves a replica that answers with its ID
+func affinityReplica(t *testing.T, cfg config.AffinityConfig) http.Handler {
+	t.Helper()
+	middleware, err := AffinityMiddleware(cfg, zap.NewNop())
+	require.NoError(t, err)
+	return middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, cfg.ReplicaID+":"+string(body))
+	}))
+}
+
+func TestAffinityMiddleware_EmitsReplica(t *testing.T) {
+	cfg := config.Default().Server.Affinity
+	cfg.Enabled = true
+	cfg.ReplicaID = "a"
+	handler := affinityReplica(t, cfg)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}")))
+	assert.Equal(t, "a", w.Header().Get("Mcp-Replica-Id"))
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "ggrmcp_replica", cookies[0].Name)
+	assert.Equal(t, "a", cookies[0].Value)
+	assert.True(t, cookies[0].HttpOnly)
+
+	// Requests pinned to an unknown replica are served here and re-pinned
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	req.AddCookie(&http.Cookie{Name: "ggrmcp_replica", Value: "gone"})
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, "a:{}", w.Body.String())
+	assert.Equal(t, "a", w.Header().Get("Mcp-Replica-Id"))
+}
+
+func TestAffinityMiddleware_ForwardsToPeer(t *testing.T) {
+	cfg := config.Default().Server.Affinity
+	cfg.Enabled = true
+
+	peerCfg := cfg
+	peerCfg.ReplicaID = "b"
+	var forwardedBy string
+	peerHandler := affinityReplica(t, peerCfg)
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwardedBy = r.Header.Get(affinityForwardedHeader)
+		peerHandler.ServeHTTP(w, r)
+	}))
+	defer peer.Close()
+
+	cfg.ReplicaID = "a"
+	cfg.Peers = map[string]string{"a": "http://a.invalid", "b": peer.URL}
+	handler := affinityReplica(t, cfg)
+
+	for _, pin := range []func(*http.Request){
+		func(r *http.Request) { r.Header.Set("Mcp-Replica-Id", "b") },
+		func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "ggrmcp_replica", Value: "b"}) },
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"id":1}`))
+		pin(req)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, `b:{"id":1}`, w.Body.String())
+		assert.Equal(t, "b", w.Header().Get("Mcp-Replica-Id"))
+		assert.Equal(t, "a", forwardedBy)
+	}
+
+	// Requests pinned to this replica are not forwarded
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Mcp-Replica-Id", "a")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, "a:", w.Body.String())
+}
+
+func TestAffinityMiddleware_UnreachablePeer(t *testing.T) {
+	peer := httptest.NewServer(http.NotFoundHandler())
+	peerURL := peer.URL
+	peer.Close()
+
+	cfg := config.Default().Server.Affinity
+	cfg.Enabled = true
+	cfg.ReplicaID = "a"
+	cfg.Peers = map[string]string{"b": peerURL}
+	handler := affinityReplica(t, cfg)
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Mcp-Replica-Id", "b")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, -1, cookies[0].MaxAge)
+	assert.Contains(t, w.Body.String(), "Session replica unavailable")
+}
+
+func TestAffinityMiddleware_RequiresReplicaID(t *testing.T) {
+	_, err := AffinityMiddleware(config.AffinityConfig{Enabled: true, Header: "Mcp-Replica-Id"}, zap.NewNop())
+	assert.Error(t, err)
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+)
+
+// toolAliases 保存工具的短名称和显示标题
+//
+// tools/list 中工具以别名列出并带上标题；调用时别名和生成的原始名称都有效。
+// 别名在进入调用流程前就解析为原始名称，因此会话作用域、参数规则和响应规则
+// 都只需要按原始名称配置。
+type toolAliases struct {
+	byAlias map[string]string
+	byTool  map[string]config.ToolAliasConfig
+}
+
+// WithToolAliases 设置工具别名与显示标题
+func WithToolAliases(configs []config.ToolAliasConfig) HandlerOption {
+	return func(h *Handler) {
+		aliases := &toolAliases{
+			byAlias: make(map[string]string, len(configs)),
+			byTool:  make(map[string]config.ToolAliasConfig, len(configs)),
+		}
+		for _, cfg := range configs {
+			aliases.byTool[cfg.Tool] = cfg
+			if cfg.Alias != "" {
+				aliases.byAlias[cfg.Alias] = cfg.Tool
+			}
+		}
+		h.aliases = aliases
+	}
+}
+
+// apply 为工具列表设置别名和标题
+func (a *toolAliases) apply(toolList []mcp.Tool) []mcp.Tool {
+	if a == nil {
+		return toolList
+	}
+	for i := range toolList {
+		cfg, ok := a.byTool[toolList[i].Name]
+		if !ok {
+			continue
+		}
+		if cfg.Alias != "" {
+			toolList[i].Name = cfg.Alias
+		}
+		if cfg.Title != "" {
+			toolList[i].Title = cfg.Title
+		}
+	}
+	return toolList
+}
+
+// displayName 返回工具对外展示的名称：有别名时使用别名
+func (a *toolAliases) displayName(toolName string) string {
+	if a == nil {
+		return toolName
+	}
+	if cfg, ok := a.byTool[toolName]; ok && cfg.Alias != "" {
+		return cfg.Alias
+	}
+	return toolName
+}
+
+// resolveToolName 将别名解析为原始工具名称
+//
+// 与已发现工具或组合工具同名的别名不生效，真实工具优先。
+func (h *Handler) resolveToolName(name string) string {
+	if h.aliases == nil {
+		return name
+	}
+	canonical, ok := h.aliases.byAlias[name]
+	if !ok {
+		return name
+	}
+	if _, exists := h.serviceDiscoverer.GetMethod(name); exists {
+		return name
+	}
+	if _, exists := h.composites.Lookup(name); exists {
+		return name
+	}
+	return canonical
+}
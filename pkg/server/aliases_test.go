@@ -0,0 +1,57 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolAliases(t *testing.T) {
+	method := testMethod("echo.service", "Say")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{method})
+	discoverer.On("GetMethod", "say").Return(types.MethodInfo{}, false)
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, `{"value":"hi"}`).Return(`"ok"`, nil).Twice()
+	handler, _ := newTestHandler(t, discoverer, WithToolAliases([]config.ToolAliasConfig{
+		{Tool: method.ToolName, Alias: "say", Title: "Say Something"},
+	}))
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+
+	// The tool is listed under its alias with a title
+	resp, _ := postRPC(t, handler, "/", sessionID, "tools/list", nil, nil)
+	require.Nil(t, resp.Error)
+	tool := resp.Result.(map[string]interface{})["tools"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "say", tool["name"])
+	assert.Equal(t, "Say Something", tool["title"])
+
+	// Both the alias and the generated name can be called
+	for _, name := range []string{"say", method.ToolName} {
+		resp, _ = postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{
+			"name":      name,
+			"arguments": map[string]interface{}{"value": "hi"},
+		}, nil)
+		assert.Equal(t, `"ok"`, callText(t, resp.Result), name)
+	}
+	discoverer.AssertExpectations(t)
+}
+
+func TestToolAliases_DiscoveredToolWins(t *testing.T) {
+	method := testMethod("echo.service", "Say")
+	other := testMethod("other.service", "Run")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethod", other.ToolName).Return(other, true)
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, other.ToolName, "").Return(`"other"`, nil)
+	handler, _ := newTestHandler(t, discoverer, WithToolAliases([]config.ToolAliasConfig{
+		{Tool: method.ToolName, Alias: other.ToolName},
+	}))
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": other.ToolName}, nil)
+	assert.Equal(t, `"other"`, callText(t, resp.Result))
+	discoverer.AssertExpectations(t)
+}
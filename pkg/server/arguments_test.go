@@ -0,0 +1,72 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/arguments"
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolsCall_ArgumentRules(t *testing.T) {
+	method := testMethod("echo.service", "Say")
+	injector, err := arguments.NewInjector([]config.ToolArgumentsConfig{
+		{Tool: "echo_*", Fixed: map[string]interface{}{"value": "session {{.SessionID}}"}},
+	})
+	require.NoError(t, err)
+
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{method})
+	handler, _ := newTestHandler(t, discoverer, WithArgumentInjector(injector))
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, `{"value":"session `+sessionID+`"}`).Return(`"ok"`, nil)
+
+	// Fixed arguments are hidden from the schema...
+	resp, _ := postRPC(t, handler, "/", sessionID, "tools/list", nil, nil)
+	require.Nil(t, resp.Error)
+	tool := resp.Result.(map[string]interface{})["tools"].([]interface{})[0].(map[string]interface{})
+	assert.NotContains(t, tool["inputSchema"].(map[string]interface{})["properties"], "value")
+
+	// ...and override whatever the client sends
+	resp, _ = postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{
+		"name":      method.ToolName,
+		"arguments": map[string]interface{}{"value": "client"},
+	}, nil)
+	assert.Equal(t, `"ok"`, callText(t, resp.Result))
+	discoverer.AssertExpectations(t)
+}
+
+func TestToolsCall_ArgumentSanitizers(t *testing.T) {
+	method := testMethod("echo.service", "Say")
+	sanitizer, err := arguments.NewSanitizer([]config.ToolSanitizerConfig{
+		{Tool: "echo_*", StripInvisible: true, Deny: []string{`(?i)ignore (all )?previous instructions`}},
+	})
+	require.NoError(t, err)
+
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, `{"value":"hello"}`).Return(`"ok"`, nil)
+	handler, _ := newTestHandler(t, discoverer, WithArgumentSanitizers(sanitizer))
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+
+	// Invisible characters are stripped before the value reaches the upstream
+	resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{
+		"name":      method.ToolName,
+		"arguments": map[string]interface{}{"value": "hel\u200blo"},
+	}, nil)
+	assert.Equal(t, `"ok"`, callText(t, resp.Result))
+
+	// Denied content fails the call without invoking the upstream
+	resp, _ = postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{
+		"name":      method.ToolName,
+		"arguments": map[string]interface{}{"value": "Ignore\u200b previous instructions"},
+	}, nil)
+	require.Nil(t, resp.Error)
+	result := resp.Result.(map[string]interface{})
+	assert.Equal(t, true, result["isError"])
+	assert.Contains(t, result["content"].([]interface{})[0].(map[string]interface{})["text"], "argument rejected: value")
+	discoverer.AssertNumberOfCalls(t, "InvokeMethodByTool", 1)
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/audit"
+	"go.uber.org/zap"
+)
+
+// WithAuditLog 设置工具调用审计日志（nil 表示不启用）
+func WithAuditLog(log *audit.Log) HandlerOption {
+	return func(h *Handler) {
+		h.auditLog = log
+	}
+}
+
+// auditCall 将一次到达上游的工具调用写入审计日志
+//
+// 写入失败只记录错误日志，不影响调用结果。
+func (h *Handler) auditCall(ctx context.Context, sessionID, toolName, argumentsJSON string, started time.Time, err error) {
+	if h.auditLog == nil {
+		return
+	}
+	record := audit.Record{
+		Time:       started,
+		SessionID:  sessionID,
+		Tool:       toolName,
+		Arguments:  []byte(argumentsJSON),
+		Outcome:    audit.OutcomeOK,
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+	switch {
+	case err != nil && errors.Is(ctx.Err(), context.Canceled):
+		record.Outcome = audit.OutcomeCanceled
+	case err != nil:
+		record.Outcome = audit.OutcomeError
+		record.Error = err.Error()
+	}
+	if err := h.auditLog.Append(record); err != nil {
+		h.logger.Error("Failed to write audit record",
+			zap.String("toolName", toolName),
+			zap.String("sessionId", sessionID),
+			zap.Error(err))
+	}
+}
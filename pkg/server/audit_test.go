@@ -0,0 +1,49 @@
+package server
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/audit"
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolsCall_AuditLog(t *testing.T) {
+	method := testMethod("echo.service", "Say")
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := audit.Open(config.AuditConfig{Enabled: true, Path: path, Chain: true})
+	require.NoError(t, err)
+	defer func() { _ = auditLog.Close() }()
+
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, `{"value":"ok"}`).Return(`"ok"`, nil)
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, `{"value":"fail"}`).Return("", errors.New("upstream down"))
+	handler, _ := newTestHandler(t, discoverer, WithAuditLog(auditLog))
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+
+	for _, value := range []string{"ok", "fail"} {
+		postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{
+			"name":      method.ToolName,
+			"arguments": map[string]interface{}{"value": value},
+		}, nil)
+	}
+
+	count, err := audit.Verify(path, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"outcome":"ok"`)
+	assert.Contains(t, lines[0], `"sessionId":"`+sessionID+`"`)
+	assert.Contains(t, lines[1], `"outcome":"error"`)
+	assert.Contains(t, lines[1], `"error":"upstream down"`)
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+)
+
+// overloadedError 表示网关过载、调用被直接拒绝
+//
+// handlePost 识别该错误并返回 HTTP 503 和重试信息，而不是普通的 JSON-RPC 错误。
+type overloadedError struct {
+	reason     string
+	retryAfter time.Duration
+}
+
+func (e *overloadedError) Error() string {
+	return fmt.Sprintf("gateway overloaded: %s", e.reason)
+}
+
+// loadShedder 限制网关同时执行和排队的工具调用数量
+//
+// 执行槽位用满后，调用最多排队 queueTimeout；排队数量超过 maxQueue 或等待超时的
+// 调用立即失败，避免过载时所有请求一起排队直到 30 秒超时。
+type loadShedder struct {
+	slots        chan struct{}
+	maxQueue     int64
+	queueTimeout time.Duration
+
+	waiting atomic.Int64
+	shed    atomic.Int64
+}
+
+// newLoadShedder 根据配置创建 loadShedder；MaxInFlight 为 0 时返回 nil（不限制）
+func newLoadShedder(cfg config.BackpressureConfig) *loadShedder {
+	if cfg.MaxInFlight <= 0 {
+		return nil
+	}
+	return &loadShedder{
+		slots:        make(chan struct{}, cfg.MaxInFlight),
+		maxQueue:     int64(cfg.MaxQueue),
+		queueTimeout: cfg.QueueTimeout,
+	}
+}
+
+// WithBackpressure 设置网关级别的工具调用负载保护（默认使用 config.Default() 中的配置）
+func WithBackpressure(cfg config.BackpressureConfig) HandlerOption {
+	return func(h *Handler) {
+		h.loadShedder = newLoadShedder(cfg)
+	}
+}
+
+// acquire 获取执行槽位，成功后调用方必须调用 release
+func (l *loadShedder) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if l.waiting.Add(1) > l.maxQueue {
+		l.waiting.Add(-1)
+		l.shed.Add(1)
+		return &overloadedError{reason: "too many queued tool calls", retryAfter: minRetryAfter}
+	}
+	defer l.waiting.Add(-1)
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		l.shed.Add(1)
+		return &overloadedError{reason: "timed out waiting for a free slot", retryAfter: l.queueTimeout}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release 释放执行槽位
+func (l *loadShedder) release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+}
+
+// stats 返回当前执行数、排队数和累计拒绝数
+func (l *loadShedder) stats() map[string]interface{} {
+	if l == nil {
+		return map[string]interface{}{"enabled": false}
+	}
+	return map[string]interface{}{
+		"enabled":     true,
+		"inFlight":    len(l.slots),
+		"maxInFlight": cap(l.slots),
+		"queued":      l.waiting.Load(),
+		"maxQueue":    l.maxQueue,
+		"shed":        l.shed.Load(),
+	}
+}
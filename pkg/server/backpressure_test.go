@@ -0,0 +1,113 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolsCall_ShedUnderOverload(t *testing.T) {
+	method := testMethod("slow.service", "Wait")
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).
+		Run(func(mock.Arguments) {
+			once.Do(func() {
+				close(started)
+				<-release
+			})
+		}).
+		Return(`"done"`, nil)
+	handler, _ := newTestHandler(t, discoverer, WithBackpressure(config.BackpressureConfig{MaxInFlight: 1}))
+	call := map[string]interface{}{"name": method.ToolName, "arguments": map[string]interface{}{}}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		resp, _ := postRPC(t, handler, "/", "", "tools/call", call, nil)
+		assert.Equal(t, `"done"`, callText(t, resp.Result))
+	}()
+	<-started
+
+	// No free slot and no queue: the call fails fast with 503
+	body, err := json.Marshal(mcp.JSONRPCRequest{JSONRPC: "2.0", ID: mcp.RequestID{Value: "shed"}, Method: "tools/call", Params: call})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	var resp struct {
+		ID    string `json:"id"`
+		Error struct {
+			Code    int           `json:"code"`
+			Message string        `json:"message"`
+			Data    mcp.RetryInfo `json:"data"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "shed", resp.ID)
+	assert.Equal(t, mcp.ErrorCodeRateLimited, resp.Error.Code)
+	assert.Contains(t, resp.Error.Message, "gateway overloaded")
+	assert.Equal(t, "gateway", resp.Error.Data.Scope)
+
+	close(release)
+	wg.Wait()
+	resp2, _ := postRPC(t, handler, "/", "", "tools/call", call, nil)
+	assert.Equal(t, `"done"`, callText(t, resp2.Result))
+}
+
+func TestLoadShedder_Queue(t *testing.T) {
+	shedder := newLoadShedder(config.BackpressureConfig{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: time.Second})
+	ctx := context.Background()
+	require.NoError(t, shedder.acquire(ctx))
+
+	// The queued call gets the slot once it is released
+	acquired := make(chan error, 1)
+	go func() { acquired <- shedder.acquire(ctx) }()
+	require.Eventually(t, func() bool { return shedder.waiting.Load() == 1 }, time.Second, time.Millisecond)
+
+	// The queue is full
+	var overloaded *overloadedError
+	require.ErrorAs(t, shedder.acquire(ctx), &overloaded)
+
+	shedder.release()
+	require.NoError(t, <-acquired)
+	assert.Equal(t, int64(1), shedder.stats()["shed"])
+	shedder.release()
+}
+
+func TestLoadShedder_QueueTimeout(t *testing.T) {
+	shedder := newLoadShedder(config.BackpressureConfig{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: 10 * time.Millisecond})
+	require.NoError(t, shedder.acquire(context.Background()))
+	defer shedder.release()
+
+	var overloaded *overloadedError
+	require.ErrorAs(t, shedder.acquire(context.Background()), &overloaded)
+	assert.Equal(t, 10*time.Millisecond, overloaded.retryAfter)
+}
+
+func TestLoadShedder_Disabled(t *testing.T) {
+	shedder := newLoadShedder(config.BackpressureConfig{})
+	assert.Nil(t, shedder)
+	assert.NoError(t, shedder.acquire(context.Background()))
+	shedder.release()
+	assert.Equal(t, false, shedder.stats()["enabled"])
+}
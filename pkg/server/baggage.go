@@ -0,0 +1,97 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+)
+
+// baggageKey 是 W3C baggage 使用的 metadata 键
+const baggageKey = "baggage"
+
+// sessionIDHashLength 是 session_id_hash 保留的十六进制字符数
+const sessionIDHashLength = 16
+
+// WithBaggage 设置每次上游调用附带的 W3C baggage 条目
+func WithBaggage(cfg config.BaggageConfig) HandlerOption {
+	return func(h *Handler) {
+		if cfg.Enabled {
+			h.baggage = &cfg
+		}
+	}
+}
+
+// applyBaggage 将配置的 baggage 条目合并进转发的 headers
+//
+// 客户端转发的 baggage 中与配置同名的条目被移除，其余条目保留；
+// 配置了 MetadataPrefix 时每个条目另外作为单独的 metadata 键发送。
+func (h *Handler) applyBaggage(headers map[string]string, toolName string, sessionCtx *session.Context) map[string]string {
+	if h.baggage == nil {
+		return headers
+	}
+
+	var members []string
+	keys := make(map[string]bool, len(h.baggage.Entries))
+	for _, entry := range h.baggage.Entries {
+		// 配置的键归网关所有：即使本次没有值，客户端同名条目也不会被转发
+		keys[entry.Key] = true
+		value, ok := baggageValue(h.baggage, entry.Source, toolName, sessionCtx)
+		if !ok {
+			continue
+		}
+		members = append(members, entry.Key+"="+url.PathEscape(value))
+		if h.baggage.MetadataPrefix != "" {
+			headers[strings.ToLower(h.baggage.MetadataPrefix+entry.Key)] = value
+		}
+	}
+
+	// 保留客户端 baggage 中未被覆盖的条目
+	for name, value := range headers {
+		if !strings.EqualFold(name, baggageKey) {
+			continue
+		}
+		delete(headers, name)
+		var kept []string
+		for _, member := range strings.Split(value, ",") {
+			member = strings.TrimSpace(member)
+			key, _, _ := strings.Cut(member, "=")
+			if member != "" && !keys[strings.TrimSpace(key)] {
+				kept = append(kept, member)
+			}
+		}
+		members = append(kept, members...)
+	}
+	if len(members) > 0 {
+		headers[baggageKey] = strings.Join(members, ",")
+	}
+	return headers
+}
+
+// baggageValue 返回某个来源在本次调用中的值；没有值时返回 false
+func baggageValue(cfg *config.BaggageConfig, source, toolName string, sessionCtx *session.Context) (string, bool) {
+	switch {
+	case source == config.BaggageSourceSessionIDHash:
+		sum := sha256.Sum256([]byte(cfg.HashSalt + sessionCtx.ID))
+		return hex.EncodeToString(sum[:])[:sessionIDHashLength], true
+	case source == config.BaggageSourceTool:
+		return toolName, true
+	case strings.HasPrefix(source, config.BaggageSourceHeader):
+		name := strings.TrimPrefix(source, config.BaggageSourceHeader)
+		for header, value := range sessionCtx.GetHeaders() {
+			if strings.EqualFold(header, name) && value != "" {
+				return value, true
+			}
+		}
+	case strings.HasPrefix(source, config.BaggageSourceMetadata):
+		value, ok := sessionCtx.GetMetadata()[strings.TrimPrefix(source, config.BaggageSourceMetadata)]
+		return value, ok && value != ""
+	case strings.HasPrefix(source, config.BaggageSourceStatic):
+		value := strings.TrimPrefix(source, config.BaggageSourceStatic)
+		return value, value != ""
+	}
+	return "", false
+}
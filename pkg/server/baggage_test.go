@@ -0,0 +1,67 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func testBaggageConfig() config.BaggageConfig {
+	return config.BaggageConfig{
+		Enabled: true,
+		Entries: []config.BaggageEntryConfig{
+			{Key: "mcp.session", Source: config.BaggageSourceSessionIDHash},
+			{Key: "mcp.tool", Source: config.BaggageSourceTool},
+			{Key: "tenant", Source: "header:X-Tenant-Id"},
+			{Key: "env", Source: "static:prod eu"},
+		},
+		HashSalt: "salt",
+	}
+}
+
+func TestToolsCall_Baggage(t *testing.T) {
+	method := testMethod("echo.service", "Say")
+	var forwarded map[string]string
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).
+		Run(func(args mock.Arguments) { forwarded = args.Get(1).(map[string]string) }).
+		Return(`"ok"`, nil)
+
+	cfg := testBaggageConfig()
+	cfg.MetadataPrefix = "X-MCP-"
+	handler, _ := newTestHandler(t, discoverer, WithBaggage(cfg))
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, map[string]string{"X-Tenant-Id": "acme"})
+	postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": method.ToolName}, nil)
+
+	sum := sha256.Sum256([]byte("salt" + sessionID))
+	hash := hex.EncodeToString(sum[:])[:16]
+	require.NotNil(t, forwarded)
+	assert.Equal(t, "mcp.session="+hash+",mcp.tool="+method.ToolName+",tenant=acme,env=prod%20eu", forwarded["baggage"])
+	assert.Equal(t, "acme", forwarded["x-mcp-tenant"])
+	assert.Equal(t, hash, forwarded["x-mcp-mcp.session"])
+	assert.NotContains(t, forwarded["baggage"], sessionID, "the session ID itself is never sent")
+}
+
+func TestApplyBaggage_MergesClientBaggage(t *testing.T) {
+	h := &Handler{}
+	WithBaggage(testBaggageConfig())(h)
+	sessionCtx := &session.Context{ID: "s1"}
+
+	headers := h.applyBaggage(map[string]string{"Baggage": "tenant=spoofed, userId=42"}, "orders_get", sessionCtx)
+	assert.NotContains(t, headers, "Baggage")
+	// Configured keys belong to the gateway: the client's tenant is dropped
+	// even though this session has no X-Tenant-Id header
+	assert.Regexp(t, `^userId=42,mcp\.session=[0-9a-f]{16},mcp\.tool=orders_get,env=prod%20eu$`, headers["baggage"])
+
+	// Disabled: headers pass through untouched
+	h = &Handler{}
+	WithBaggage(config.BaggageConfig{})(h)
+	headers = h.applyBaggage(map[string]string{"a": "b"}, "orders_get", sessionCtx)
+	assert.Equal(t, map[string]string{"a": "b"}, headers)
+}
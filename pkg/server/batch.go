@@ -0,0 +1,188 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"sync"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"github.com/aalobaidi/ggRMCP/pkg/telemetry"
+	"go.uber.org/zap"
+)
+
+// WithBatch 设置 JSON-RPC 批量请求的处理（默认使用 config.Default() 中的配置）
+func WithBatch(cfg config.BatchConfig) HandlerOption {
+	return func(h *Handler) {
+		h.batch = cfg
+	}
+}
+
+// isBatch 判断请求体是否为 JSON 数组（跳过前导空白，不消耗第一个有效字符）
+func isBatch(body *bufio.Reader) bool {
+	for {
+		b, err := body.ReadByte()
+		if err != nil {
+			return false
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		_ = body.UnreadByte()
+		return b == '['
+	}
+}
+
+// handleBatch 处理 JSON-RPC 2.0 批量请求：数组中的每个请求经 handleRequest 处理，
+// 响应按请求顺序以数组返回
+//
+// 批量中的请求共用一个会话（Mcp-Session-Id 与单个请求相同），initialize 不能放在批量中。
+// 整个请求体无法解析、数组为空或超过 max_requests 时返回单个错误响应；
+// 只包含通知时返回 202。max_concurrency 大于 1 时批量中的请求并发处理。
+func (h *Handler) handleBatch(w http.ResponseWriter, r *http.Request, body io.Reader) {
+	if !h.batch.Enabled {
+		h.writeErrorResponse(w, mcp.RequestID{}, mcp.ErrorCodeInvalidRequest, "Batch requests are not supported")
+		return
+	}
+
+	var batch []json.RawMessage
+	if err := json.NewDecoder(body).Decode(&batch); err != nil {
+		h.logger.Error("Failed to decode JSON-RPC batch", zap.Error(err))
+		h.writeErrorResponse(w, mcp.RequestID{}, mcp.ErrorCodeParseError, "Parse error")
+		return
+	}
+	_, _ = io.Copy(io.Discard, body)
+	if len(batch) == 0 {
+		h.writeErrorResponse(w, mcp.RequestID{}, mcp.ErrorCodeInvalidRequest, "Empty batch")
+		return
+	}
+	if len(batch) > h.batch.MaxRequests {
+		h.writeErrorResponse(w, mcp.RequestID{}, mcp.ErrorCodeInvalidRequest,
+			fmt.Sprintf("Batch of %d requests exceeds the limit of %d", len(batch), h.batch.MaxRequests))
+		return
+	}
+
+	// 📋 会话处理与单个请求相同：未知的会话 ID 返回 404
+	sessionID := r.Header.Get("Mcp-Session-Id")
+	if sessionID != "" {
+		if _, ok := h.sessionManager.GetSession(sessionID); !ok {
+			writeSessionNotFound(w, mcp.RequestID{})
+			return
+		}
+	}
+	sessionCtx := h.sessionManager.GetOrCreateSession(sessionID, extractHeaders(r))
+	w.Header().Set("Mcp-Session-Id", sessionCtx.ID)
+	h.applyToolScope(r, sessionCtx)
+	h.applyMetadataHeaders(r, sessionCtx)
+
+	h.logger.Info("Processing MCP batch",
+		zap.Int("requests", len(batch)),
+		zap.String("sessionId", sessionCtx.ID))
+
+	responses := make([]*mcp.JSONRPCResponse, len(batch))
+	slots := make(chan struct{}, h.batch.MaxConcurrency)
+	var wg sync.WaitGroup
+	for i, raw := range batch {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int, raw json.RawMessage) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			responses[i] = h.batchResponse(r.Context(), raw, sessionCtx, i > 0)
+		}(i, raw)
+	}
+	wg.Wait()
+
+	answered := make([]*mcp.JSONRPCResponse, 0, len(responses))
+	for _, response := range responses {
+		if response != nil {
+			answered = append(answered, response)
+		}
+	}
+	if len(answered) == 0 {
+		// 📭 批量中只有通知
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	h.writeJSONResponse(w, answered)
+}
+
+// batchResponse 处理批量中的一个请求，通知返回 nil
+//
+// charge 为 true 时请求先从限流器扣除自己的令牌（HTTP 请求本身只扣除了一个），
+// 被限流的请求得到限流错误，批量中的其他请求不受影响。
+func (h *Handler) batchResponse(ctx context.Context, raw json.RawMessage, sessionCtx *session.Context, charge bool) (response *mcp.JSONRPCResponse) {
+	var req mcp.JSONRPCRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return batchError(mcp.RequestID{}, &mcp.RPCError{Code: mcp.ErrorCodeInvalidRequest, Message: "Invalid Request"})
+	}
+
+	// 🛟 单个请求的 panic 只影响它自己的响应
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		panicCount.Add(1)
+		h.metrics.Count(telemetry.MetricPanics, 1, nil)
+		h.logger.Error("Panic while handling batched request",
+			zap.Any("panic", p),
+			zap.String("method", req.Method),
+			zap.String("sessionId", sessionCtx.ID),
+			zap.ByteString("stack", debug.Stack()))
+		response = batchError(req.ID, &mcp.RPCError{Code: mcp.ErrorCodeInternalError, Message: "Internal error"})
+	}()
+
+	if req.ID.Value == nil {
+		h.processNotification(&req, sessionCtx.ID)
+		return nil
+	}
+	if err := h.validator.ValidateRequest(&req); err != nil {
+		return batchError(req.ID, &mcp.RPCError{Code: mcp.ErrorCodeInvalidRequest, Message: mcp.SanitizeError(err)})
+	}
+	if req.Method == "initialize" {
+		return batchError(req.ID, &mcp.RPCError{Code: mcp.ErrorCodeInvalidRequest, Message: "initialize cannot be part of a batch"})
+	}
+	if charge {
+		if info, ok := chargeRateLimits(ctx); !ok {
+			return batchError(req.ID, &mcp.RPCError{Code: mcp.ErrorCodeRateLimited, Message: "Rate limit exceeded", Data: info})
+		}
+	}
+
+	ctx, done, ok := h.pending.track(ctx, sessionCtx.ID, req.ID)
+	if !ok {
+		return batchError(req.ID, &mcp.RPCError{Code: mcp.ErrorCodeInvalidRequest, Message: duplicateRequestIDMessage})
+	}
+	defer done()
+	result, err := h.handleRequest(ctx, &req, sessionCtx)
+	var overloaded *overloadedError
+	switch {
+	case errors.As(err, &overloaded):
+		return batchError(req.ID, &mcp.RPCError{
+			Code:    mcp.ErrorCodeRateLimited,
+			Message: overloaded.Error(),
+			Data: mcp.RetryInfo{
+				RetryAfterSeconds: retryAfterSeconds(overloaded.retryAfter),
+				Scope:             "gateway",
+			},
+		})
+	case err != nil:
+		return batchError(req.ID, h.requestError(req.Method, err))
+	}
+	return &mcp.JSONRPCResponse{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+// batchError 返回批量中一个请求的错误响应
+func batchError(id mcp.RequestID, rpcErr *mcp.RPCError) *mcp.JSONRPCResponse {
+	return &mcp.JSONRPCResponse{JSONRPC: "2.0", ID: id, Error: rpcErr}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchRequests(t *testing.T) {
+	method := testMethod("acme.users.v1.UserService", "Get")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethod", method.ToolName).Return(method, true)
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).Return(`{"name":"Ada"}`, nil)
+	handler, _ := newTestHandler(t, discoverer, WithBatch(config.BatchConfig{Enabled: true, MaxRequests: 8, MaxConcurrency: 4}))
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+
+	call := `{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":"` + method.ToolName + `"}}`
+	w := sendRaw(handler, http.MethodPost, sessionID, ` [`+
+		fmt.Sprintf(call, 1)+`,`+
+		`{"jsonrpc":"2.0","method":"notifications/initialized"},`+
+		`1,`+
+		`{"jsonrpc":"2.0","id":3,"method":"initialize"},`+
+		`{"jsonrpc":"2.0","id":4,"method":"unknown/method"},`+
+		fmt.Sprintf(call, 5)+`]`, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, sessionID, w.Header().Get("Mcp-Session-Id"))
+
+	// Every request but the notification is answered, in request order
+	var responses []map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &responses))
+	require.Len(t, responses, 5)
+	errorCode := func(response map[string]interface{}) interface{} {
+		rpcErr, _ := response["error"].(map[string]interface{})
+		return rpcErr["code"]
+	}
+	assert.Equal(t, float64(1), responses[0]["id"])
+	assert.NotNil(t, responses[0]["result"])
+	assert.Nil(t, responses[1]["id"])
+	assert.Equal(t, float64(mcp.ErrorCodeInvalidRequest), errorCode(responses[1]))
+	assert.Equal(t, float64(3), responses[2]["id"])
+	assert.Equal(t, float64(mcp.ErrorCodeInvalidRequest), errorCode(responses[2]))
+	assert.Equal(t, float64(4), responses[3]["id"])
+	assert.Equal(t, float64(mcp.ErrorCodeMethodNotFound), errorCode(responses[3]))
+	assert.Equal(t, float64(5), responses[4]["id"])
+	assert.NotNil(t, responses[4]["result"])
+
+	// A batch of notifications has no response
+	w = sendRaw(handler, http.MethodPost, sessionID, `[{"jsonrpc":"2.0","method":"notifications/initialized"}]`, "")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	// Malformed, empty and oversized batches get a single error
+	single := func(body string) *mcp.RPCError {
+		w := sendRaw(handler, http.MethodPost, sessionID, body, "")
+		var response struct {
+			Error *mcp.RPCError `json:"error"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.NotNil(t, response.Error)
+		return response.Error
+	}
+	assert.Equal(t, mcp.ErrorCodeParseError, single(`[{"jsonrpc":"2.0",`).Code)
+	assert.Equal(t, mcp.ErrorCodeInvalidRequest, single(`[]`).Code)
+	assert.Contains(t, single(`[1,2,3,4,5,6,7,8,9]`).Message, "exceeds the limit of 8")
+
+	// Unknown sessions are rejected like single requests
+	w = sendRaw(handler, http.MethodPost, "expired", `[`+fmt.Sprintf(call, 1)+`]`, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestBatchRequests_ChargeRateLimitPerRequest(t *testing.T) {
+	method := testMethod("acme.users.v1.UserService", "Get")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethod", method.ToolName).Return(method, true)
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).Return(`{"name":"Ada"}`, nil)
+	handler, _ := newTestHandler(t, discoverer, WithBatch(config.BatchConfig{Enabled: true, MaxRequests: 8, MaxConcurrency: 1}))
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	limited := RateLimitMiddleware(1, 3)(handler)
+
+	call := `{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":"` + method.ToolName + `"}}`
+	w := sendRaw(limited, http.MethodPost, sessionID, `[`+
+		fmt.Sprintf(call, 1)+`,`+fmt.Sprintf(call, 2)+`,`+fmt.Sprintf(call, 3)+`,`+fmt.Sprintf(call, 4)+`]`, "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// The burst of 3 covers the first three requests; the fourth is throttled
+	var responses []mcp.JSONRPCResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &responses))
+	require.Len(t, responses, 4)
+	for _, response := range responses[:3] {
+		assert.Nil(t, response.Error)
+	}
+	require.NotNil(t, responses[3].Error)
+	assert.Equal(t, mcp.ErrorCodeRateLimited, responses[3].Error.Code)
+
+	// The bucket is empty, so the next HTTP request is rejected outright
+	w = sendRaw(limited, http.MethodPost, sessionID, `[`+fmt.Sprintf(call, 5)+`]`, "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestBatchRequestsDisabled(t *testing.T) {
+	handler, _ := newTestHandler(t, &mockServiceDiscoverer{}, WithBatch(config.BatchConfig{}))
+	w := sendRaw(handler, http.MethodPost, "", `[{"jsonrpc":"2.0","id":1,"method":"tools/list"}]`, "")
+	var response struct {
+		Error *mcp.RPCError `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "Batch requests are not supported", response.Error.Message)
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+)
+
+// builtinTool 网关内置的工具，由 Handler 直接处理，不调用上游
+//
+// 内置工具不受会话作用域限制，也不参与文档、提示、分组和别名；
+// 它们返回的内容只包含会话可见的工具
+type builtinTool struct {
+	definition mcp.Tool
+	call       func(ctx context.Context, args map[string]interface{}, sessionCtx *session.Context) (*mcp.ToolCallResult, error)
+}
+
+// builtinTools 返回已启用的内置工具
+func (h *Handler) builtinTools() []builtinTool {
+	var builtins []builtinTool
+	if h.describeTool {
+		builtins = append(builtins, builtinTool{definition: describeToolDefinition(), call: h.describeToolCall})
+	}
+	if h.introspectionTools {
+		builtins = append(builtins,
+			builtinTool{definition: listServicesToolDefinition(), call: h.listServicesCall},
+			builtinTool{definition: gatewayStatusToolDefinition(), call: h.gatewayStatusCall})
+	}
+	return builtins
+}
+
+// lookupBuiltinTool 按名称查找已启用的内置工具
+func (h *Handler) lookupBuiltinTool(name string) (builtinTool, bool) {
+	for _, builtin := range h.builtinTools() {
+		if builtin.definition.Name == name {
+			return builtin, true
+		}
+	}
+	return builtinTool{}, false
+}
+
+// builtinResult 将内置工具的结果编码为 JSON，按会话的组合方式返回
+func (h *Handler) builtinResult(value interface{}, sessionCtx *session.Context) (*mcp.ToolCallResult, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode tool result: %w", err)
+	}
+	return h.composeResult("", string(data), nil, sessionCtx), nil
+}
+
+// builtinError 返回内置工具的错误结果
+func builtinError(format string, args ...interface{}) *mcp.ToolCallResult {
+	return &mcp.ToolCallResult{
+		Content: []mcp.ContentBlock{mcp.TextContent("Error invoking method: " + fmt.Sprintf(format, args...))},
+		IsError: true,
+	}
+}
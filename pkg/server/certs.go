@@ -0,0 +1,37 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+)
+
+// WithCertificateMonitor 设置上游 TLS 证书监控器，即将过期的证书在 /health 中告警（nil 表示不启用）
+func WithCertificateMonitor(m *grpc.CertificateMonitor) HandlerOption {
+	return func(h *Handler) {
+		h.certMonitor = m
+	}
+}
+
+// certificateWarnings 为即将过期或已过期的上游证书生成告警消息
+func (h *Handler) certificateWarnings() []string {
+	if h.certMonitor == nil {
+		return nil
+	}
+	var warnings []string
+	for _, cert := range h.certMonitor.Expiring() {
+		if cert.ExpiresIn < 0 {
+			warnings = append(warnings, fmt.Sprintf("TLS certificate of upstream %s (%s, %s) expired at %s",
+				cert.Target, cert.Address, cert.Subject, cert.NotAfter.UTC().Format(time.RFC3339)))
+			continue
+		}
+		remaining := (time.Duration(cert.ExpiresIn) * time.Second).Round(time.Minute).String()
+		if days := cert.ExpiresIn / 86400; days > 0 {
+			remaining = fmt.Sprintf("%d days", days)
+		}
+		warnings = append(warnings, fmt.Sprintf("TLS certificate of upstream %s (%s, %s) expires at %s, in %s",
+			cert.Target, cert.Address, cert.Subject, cert.NotAfter.UTC().Format(time.RFC3339), remaining))
+	}
+	return warnings
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/aalobaidi/ggRMCP/pkg/arguments"
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"go.uber.org/zap"
+)
+
+// coercionsMetaKey 工具调用结果 _meta 中记录参数修正的键
+const coercionsMetaKey = "ggrmcp/coercions"
+
+// WithArgumentCoercion 设置反序列化前对参数类型错误和时间格式的修正（默认不修正）
+func WithArgumentCoercion(cfg config.ToolCoercionConfig) HandlerOption {
+	return func(h *Handler) {
+		h.coercion.Types = cfg.Enabled
+		h.coercion.Times = cfg.TimeFormats
+		h.coercion.Bytes = cfg.ByteInputs
+	}
+}
+
+// WithEnumNormalization 设置枚举名称的宽松匹配（默认只接受声明的名称）
+func WithEnumNormalization(cfg config.ToolEnumsConfig) HandlerOption {
+	return func(h *Handler) {
+		h.coercion.Enums = arguments.EnumOptions{
+			CaseInsensitive: cfg.CaseInsensitive,
+			StripPrefix:     cfg.StripPrefix,
+		}
+	}
+}
+
+// coerceArguments 按工具的请求消息修正参数（见 arguments.Coerce）
+//
+// 组合工具等没有请求消息描述符的工具保持不变；bytes 字段引用的资源按会话读取
+func (h *Handler) coerceArguments(toolName string, args map[string]interface{}, sessionCtx *session.Context) (map[string]interface{}, []arguments.Coercion) {
+	if !h.coercion.Enabled() || args == nil {
+		return args, nil
+	}
+	method, ok := h.serviceDiscoverer.GetMethod(toolName)
+	if !ok || method.InputDescriptor == nil {
+		return args, nil
+	}
+	opts := h.coercion
+	if opts.Bytes {
+		opts.ReadResource = func(uri string) ([]byte, error) {
+			return h.readResource(uri, sessionCtx)
+		}
+	}
+	coerced, coercions := arguments.Coerce(method.InputDescriptor, args, opts)
+	if len(coercions) > 0 {
+		h.logger.Debug("Coerced tool arguments",
+			zap.String("toolName", toolName),
+			zap.Any("coercions", coercions))
+	}
+	return coerced, coercions
+}
+
+// readResource 以会话的身份读取网关提供的资源内容，供 bytes 字段引用
+func (h *Handler) readResource(uri string, sessionCtx *session.Context) ([]byte, error) {
+	result, err := h.handleResourcesRead(map[string]interface{}{"uri": uri}, sessionCtx)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Contents) == 0 {
+		return nil, fmt.Errorf("resource %s has no contents", uri)
+	}
+	contents := result.Contents[0]
+	if contents.Blob != "" {
+		return base64.StdEncoding.DecodeString(contents.Blob)
+	}
+	return []byte(contents.Text), nil
+}
+
+// coercionMeta 返回需要合并到结果 _meta 中的参数修正记录（没有修正时为 nil）
+func coercionMeta(coercions []arguments.Coercion) map[string]interface{} {
+	if len(coercions) == 0 {
+		return nil
+	}
+	return map[string]interface{}{coercionsMetaKey: coercions}
+}
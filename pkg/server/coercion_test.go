@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArgumentCoercion(t *testing.T) {
+	create := wrapperMethod(t)
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{create})
+	discoverer.On("GetMethod", create.ToolName).Return(create, true)
+	invoke := discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, create.ToolName, `{"book":{"pages":412,"title":"Dune"}}`).Return(`{"title":"Dune"}`, nil)
+	handler, _ := newTestHandler(t, discoverer, WithArgumentCoercion(config.ToolCoercionConfig{Enabled: true}))
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	call := func() mcp.ToolCallResult {
+		resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{
+			"name":      create.ToolName,
+			"arguments": map[string]interface{}{"book": map[string]interface{}{"title": "Dune", "pages": "412"}},
+		}, nil)
+		require.Nil(t, resp.Error)
+		data, err := json.Marshal(resp.Result)
+		require.NoError(t, err)
+		var result mcp.ToolCallResult
+		require.NoError(t, json.Unmarshal(data, &result))
+		return result
+	}
+	expected := []interface{}{map[string]interface{}{"path": "book.pages", "from": "string", "to": "number"}}
+
+	result := call()
+	assert.False(t, result.IsError)
+	assert.Equal(t, expected, result.Meta[coercionsMetaKey])
+
+	// Coercions are also reported when the call fails
+	invoke.Unset()
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, create.ToolName, mock.Anything).Return("", errors.New("unavailable"))
+	result = call()
+	assert.True(t, result.IsError)
+	assert.Equal(t, expected, result.Meta[coercionsMetaKey])
+}
+
+func TestArgumentCoercion_Disabled(t *testing.T) {
+	create := wrapperMethod(t)
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethod", create.ToolName).Return(create, true)
+	handler, _ := newTestHandler(t, discoverer)
+
+	args := map[string]interface{}{"book": map[string]interface{}{"pages": "412"}}
+	coerced, coercions := handler.coerceArguments(create.ToolName, args, nil)
+	assert.Equal(t, args, coerced)
+	assert.Empty(t, coercions)
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/composite"
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolsCall_CompositeTool(t *testing.T) {
+	method := testMethod("echo.service", "Say")
+	registry, err := composite.NewRegistry([]config.CompositeToolConfig{{
+		Name:        "say_twice",
+		Description: "Says the value twice",
+		Steps: []config.CompositeStepConfig{
+			{ID: "first", Tool: method.ToolName, Arguments: map[string]interface{}{"value": "$args.value"}},
+			{ID: "second", Tool: method.ToolName, Arguments: map[string]interface{}{"value": "$steps.first.value"}},
+		},
+	}})
+	require.NoError(t, err)
+
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{method})
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, `{"value":"hi"}`).Return(`{"value":"hi hi"}`, nil).Once()
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, `{"value":"hi hi"}`).Return(`{"value":"hi hi hi hi"}`, nil).Once()
+	handler, _ := newTestHandler(t, discoverer, WithComposites(registry))
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+
+	resp, _ := postRPC(t, handler, "/", sessionID, "tools/list", nil, nil)
+	require.Nil(t, resp.Error)
+	var names []string
+	for _, tool := range resp.Result.(map[string]interface{})["tools"].([]interface{}) {
+		names = append(names, tool.(map[string]interface{})["name"].(string))
+	}
+	assert.Contains(t, names, "say_twice")
+
+	resp, _ = postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{
+		"name":      "say_twice",
+		"arguments": map[string]interface{}{"value": "hi"},
+	}, nil)
+	assert.JSONEq(t, `{"value":"hi hi hi hi"}`, callText(t, resp.Result))
+	discoverer.AssertExpectations(t)
+}
+
+func TestToolsCall_CompositeToolCompensation(t *testing.T) {
+	reserve := testMethod("inventory.service", "Reserve")
+	release := testMethod("inventory.service", "Release")
+	charge := testMethod("payments.service", "Charge")
+	registry, err := composite.NewRegistry([]config.CompositeToolConfig{{
+		Name: "place_order",
+		Steps: []config.CompositeStepConfig{
+			{ID: "reserve", Tool: reserve.ToolName, Compensate: &config.CompensationConfig{
+				Tool: release.ToolName, Arguments: map[string]interface{}{"id": "$steps.reserve.id"},
+			}},
+			{ID: "charge", Tool: charge.ToolName},
+		},
+	}})
+	require.NoError(t, err)
+
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{reserve, release, charge}).Maybe()
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, reserve.ToolName, `{}`).Return(`{"id":"r-1"}`, nil).Once()
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, charge.ToolName, `{}`).Return("", errors.New("card declined")).Once()
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, release.ToolName, `{"id":"r-1"}`).Return(`{}`, nil).Once()
+	handler, _ := newTestHandler(t, discoverer, WithComposites(registry))
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": "place_order"}, nil)
+	require.Nil(t, resp.Error)
+	result := resp.Result.(map[string]interface{})
+	assert.Equal(t, true, result["isError"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"step": "reserve", "tool": release.ToolName},
+	}, result["_meta"].(map[string]interface{})[compensationsMetaKey])
+	discoverer.AssertExpectations(t)
+}
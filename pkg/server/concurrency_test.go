@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolsCall_SessionConcurrencyLimit(t *testing.T) {
+	method := testMethod("slow.service", "Wait")
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var blocking atomic.Bool
+	blocking.Store(true)
+
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).
+		Run(func(mock.Arguments) {
+			// Only the first call blocks until released
+			if blocking.CompareAndSwap(true, false) {
+				close(started)
+				<-release
+			}
+		}).
+		Return(`"done"`, nil)
+	handler, _ := newTestHandler(t, discoverer, WithMaxConcurrentCalls(1))
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	call := map[string]interface{}{"name": method.ToolName, "arguments": map[string]interface{}{}}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", call, nil)
+		assert.Equal(t, `"done"`, callText(t, resp.Result))
+	}()
+	<-started
+
+	// A second call in the same session is rejected while the first is running
+	resp, _ := postRPCWithID(t, handler, "/", sessionID, 2, "tools/call", call, nil)
+	require.Nil(t, resp.Error)
+	result := resp.Result.(map[string]interface{})
+	assert.Equal(t, true, result["isError"])
+	assert.Contains(t, result["content"].([]interface{})[0].(map[string]interface{})["text"], "too many concurrent tool calls")
+	assert.Equal(t, float64(1), result["_meta"].(map[string]interface{})[retryAfterMetaKey])
+
+	// Other sessions are not affected
+	resp, _ = postRPC(t, handler, "/", "", "tools/call", call, nil)
+	assert.Equal(t, `"done"`, callText(t, resp.Result))
+
+	// The slot is released when the call completes
+	close(release)
+	wg.Wait()
+	resp, _ = postRPC(t, handler, "/", sessionID, "tools/call", call, nil)
+	assert.Equal(t, `"done"`, callText(t, resp.Result))
+}
+
+func TestToolsCall_ClientDisconnectCancelsUpstream(t *testing.T) {
+	method := testMethod("slow.service", "Wait")
+	started := make(chan struct{})
+	canceled := make(chan error, 1)
+
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).
+		Run(func(args mock.Arguments) {
+			ctx := args.Get(0).(context.Context)
+			close(started)
+			select {
+			case <-ctx.Done():
+				canceled <- ctx.Err()
+			case <-time.After(5 * time.Second):
+				canceled <- nil
+			}
+		}).
+		Return("", context.Canceled)
+	handler, _ := newTestHandler(t, discoverer)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// A body of unknown length is sent chunked, as streaming clients do
+	body := io.NopCloser(strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + method.ToolName + `"}}`))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	go func() {
+		<-started
+		cancel()
+	}()
+	_, err = server.Client().Do(req)
+	require.ErrorIs(t, err, context.Canceled)
+
+	assert.ErrorIs(t, <-canceled, context.Canceled)
+	assert.Eventually(t, func() bool {
+		return handler.clientDisconnects.Load() == 1
+	}, time.Second, 10*time.Millisecond)
+}
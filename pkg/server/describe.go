@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+)
+
+// describeToolName 内置的工具说明工具的名称
+const describeToolName = "ggrmcp_describe_tool"
+
+// WithDescribeTool 设置是否注册内置的 ggrmcp_describe_tool 工具，
+// 网关按 tools.describe_tool.enabled 设置（默认启用）
+func WithDescribeTool(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.describeTool = enabled
+	}
+}
+
+// describeToolDefinition 返回工具说明工具的定义
+func describeToolDefinition() mcp.Tool {
+	return mcp.Tool{
+		Name:  describeToolName,
+		Title: "Describe tool",
+		Description: "Returns the full documentation of another tool: its description, " +
+			"input and output schemas, the gRPC method it calls and example arguments. " +
+			"Use it before calling a tool whose arguments are unclear.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"name": map[string]interface{}{
+					"type":        "string",
+					"description": "Name of the tool to describe, as listed by tools/list",
+				},
+			},
+			"required": []string{"name"},
+		},
+	}
+}
+
+// toolDescription 工具说明工具的返回结果
+type toolDescription struct {
+	Name               string                 `json:"name"`
+	Title              string                 `json:"title,omitempty"`
+	Description        string                 `json:"description,omitempty"`
+	Service            string                 `json:"service,omitempty"`
+	ServiceDescription string                 `json:"serviceDescription,omitempty"`
+	Method             string                 `json:"method,omitempty"`
+	Streaming          bool                   `json:"serverStreaming,omitempty"`
+	InputSchema        interface{}            `json:"inputSchema"`
+	OutputSchema       interface{}            `json:"outputSchema,omitempty"`
+	ExampleArguments   map[string]interface{} `json:"exampleArguments,omitempty"`
+	Meta               map[string]interface{} `json:"_meta,omitempty"`
+}
+
+// describeToolCall 处理对工具说明工具的调用
+//
+// 说明基于会话的 tools/list 结果（包括文档、参数规则、展开和别名），
+// 因此只能查询会话可见的工具，描述与客户端实际看到的 schema 一致；
+// 另外补充服务注释、对应的 gRPC 方法和示例参数。
+func (h *Handler) describeToolCall(ctx context.Context, args map[string]interface{}, sessionCtx *session.Context) (*mcp.ToolCallResult, error) {
+	name, _ := args["name"].(string)
+	if name == "" {
+		return builtinError("argument \"name\" is required"), nil
+	}
+
+	list, err := h.handleToolsList(ctx, sessionCtx)
+	if err != nil {
+		return nil, err
+	}
+	var tool *mcp.Tool
+	for i := range list.Tools {
+		if list.Tools[i].Name == name {
+			tool = &list.Tools[i]
+			break
+		}
+	}
+	if tool == nil {
+		return builtinError("tool %s not found", name), nil
+	}
+
+	description := toolDescription{
+		Name:         tool.Name,
+		Title:        tool.Title,
+		Description:  tool.Description,
+		InputSchema:  tool.InputSchema,
+		OutputSchema: tool.OutputSchema,
+		Meta:         tool.Meta,
+	}
+	toolName := h.resolveToolName(name)
+	if method, ok := h.serviceDiscoverer.GetMethod(toolName); ok {
+		description.Service = method.ServiceName
+		description.ServiceDescription = method.ServiceDescription
+		description.Method = method.FullName
+		description.Streaming = method.IsServerStreaming
+		if method.InputDescriptor != nil {
+			description.ExampleArguments = h.exampleArguments(toolName, exampleMessage(method.InputDescriptor, 0), tool.InputSchema)
+		}
+	}
+
+	return h.builtinResult(description, sessionCtx)
+}
+
+// exampleArguments 使示例参数与客户端看到的 input schema 一致：
+// 展开过的工具使用包装字段的示例，schema 中没有的参数（例如固定参数）被去掉
+func (h *Handler) exampleArguments(toolName string, example map[string]interface{}, inputSchema interface{}) map[string]interface{} {
+	if field := h.flattenedField(toolName); field != "" {
+		if nested, ok := example[field].(map[string]interface{}); ok {
+			example = nested
+		}
+	}
+	schema, _ := inputSchema.(map[string]interface{})
+	properties, ok := schema["properties"].(map[string]interface{})
+	if !ok {
+		return example
+	}
+	for name := range example {
+		if _, ok := properties[name]; !ok {
+			delete(example, name)
+		}
+	}
+	return example
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribeTool(t *testing.T) {
+	create := wrapperMethod(t)
+	create.Description = "Creates a book"
+	create.ServiceDescription = "Manages the library catalog"
+	echo := testMethod("echo.service", "Echo")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{create, echo})
+	discoverer.On("GetMethod", create.ToolName).Return(create, true)
+	discoverer.On("GetMethod", echo.ToolName).Return(echo, true)
+	handler, _ := newTestHandler(t, discoverer,
+		WithDescribeTool(true),
+		WithToolFlattening(config.ToolFlatteningConfig{Enabled: true}))
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+
+	resp, _ := postRPC(t, handler, "/", sessionID, "tools/list", nil, nil)
+	require.Nil(t, resp.Error)
+	assert.Contains(t, toolNames(t, resp.Result), describeToolName)
+
+	describe := func(name string) mcp.ToolCallResult {
+		resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{
+			"name":      describeToolName,
+			"arguments": map[string]interface{}{"name": name},
+		}, nil)
+		require.Nil(t, resp.Error)
+		data, err := json.Marshal(resp.Result)
+		require.NoError(t, err)
+		var result mcp.ToolCallResult
+		require.NoError(t, json.Unmarshal(data, &result))
+		return result
+	}
+
+	result := describe(create.ToolName)
+	require.False(t, result.IsError)
+	var description map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].Text), &description))
+	assert.Equal(t, create.ToolName, description["name"])
+	assert.Equal(t, "Creates a book", description["description"])
+	assert.Equal(t, "Manages the library catalog", description["serviceDescription"])
+	assert.Equal(t, "library.Books.CreateBook", description["method"])
+	assert.Contains(t, description["inputSchema"].(map[string]interface{})["properties"], "title")
+	// Examples follow the flattened schema
+	assert.Equal(t, map[string]interface{}{"title": "string", "pages": float64(0)}, description["exampleArguments"])
+
+	result = describe("library_books_missing")
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Content[0].Text, "tool library_books_missing not found")
+
+	discoverer.AssertNotCalled(t, "InvokeMethodByTool")
+}
+
+func TestDescribeTool_Disabled(t *testing.T) {
+	echo := testMethod("echo.service", "Echo")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{echo})
+	handler, _ := newTestHandler(t, discoverer)
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	resp, _ := postRPC(t, handler, "/", sessionID, "tools/list", nil, nil)
+	require.Nil(t, resp.Error)
+	assert.Equal(t, []string{echo.ToolName}, toolNames(t, resp.Result))
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+)
+
+// toolDocsExt 工具文档文件的扩展名
+const toolDocsExt = ".md"
+
+// ToolDocs 按原始工具名称索引的 markdown 文档
+type ToolDocs map[string]string
+
+// LoadToolDocs 从目录加载工具文档
+//
+// 目录中每个 "<工具名>.md" 文件对应一个工具，内容会追加到该工具的描述之后。
+// 超过 maxLength 个字符的文档会被截断，避免单个工具占满客户端的上下文。
+// 目录为空字符串时返回 nil。
+func LoadToolDocs(dir string, maxLength int) (ToolDocs, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tool docs directory: %w", err)
+	}
+
+	docs := make(ToolDocs)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != toolDocsExt {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tool docs %s: %w", entry.Name(), err)
+		}
+		content := strings.TrimSpace(string(data))
+		if content == "" {
+			continue
+		}
+		docs[strings.TrimSuffix(entry.Name(), toolDocsExt)] = truncateDocs(content, maxLength)
+	}
+	return docs, nil
+}
+
+// truncateDocs 将文档截断到 maxLength 个字符（按 rune 计算）
+func truncateDocs(content string, maxLength int) string {
+	runes := []rune(content)
+	if maxLength <= 0 || len(runes) <= maxLength {
+		return content
+	}
+	return strings.TrimSpace(string(runes[:maxLength])) + "…"
+}
+
+// WithToolDocs 设置追加到工具描述的 markdown 文档
+func WithToolDocs(docs ToolDocs) HandlerOption {
+	return func(h *Handler) {
+		h.toolDocs = docs
+	}
+}
+
+// appendToolDocs 将文档追加到对应工具的描述之后，需在别名生效前调用
+func (h *Handler) appendToolDocs(toolList []mcp.Tool) {
+	for i := range toolList {
+		doc, ok := h.toolDocs[toolList[i].Name]
+		if !ok {
+			continue
+		}
+		if toolList[i].Description == "" {
+			toolList[i].Description = doc
+		} else {
+			toolList[i].Description += "\n\n" + doc
+		}
+	}
+}
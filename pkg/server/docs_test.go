@@ -0,0 +1,45 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadToolDocs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "orders_get.md"), []byte("\n## Example\n\nCall with an order ID.\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "orders_list.md"), []byte(strings.Repeat("é", 20)), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "empty.md"), []byte("  \n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0o644))
+
+	docs, err := LoadToolDocs(dir, 10)
+	require.NoError(t, err)
+	assert.Equal(t, ToolDocs{
+		"orders_get":  "## Example…",
+		"orders_list": strings.Repeat("é", 10) + "…",
+	}, docs)
+
+	docs, err = LoadToolDocs("", 10)
+	require.NoError(t, err)
+	assert.Nil(t, docs)
+
+	_, err = LoadToolDocs(filepath.Join(dir, "missing"), 10)
+	assert.Error(t, err)
+}
+
+func TestToolsList_AppendsDocs(t *testing.T) {
+	method := testMethod("echo.service", "Say")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{method})
+	handler, _ := newTestHandler(t, discoverer, WithToolDocs(ToolDocs{method.ToolName: "Only say nice things."}))
+
+	tool := listTools(t, handler)[method.ToolName]
+	require.NotNil(t, tool)
+	assert.True(t, strings.HasSuffix(tool["description"].(string), "\n\nOnly say nice things."), tool["description"])
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// errorIDMetaKey 工具调用错误结果中错误 ID 在 _meta 里的键
+const errorIDMetaKey = "ggrmcp/errorId"
+
+// circuitMetaKey 被熔断器拒绝的工具调用结果中熔断范围在 _meta 里的键
+const circuitMetaKey = "ggrmcp/circuit"
+
+// compensationsMetaKey 组合工具步骤失败时已执行的补偿在 _meta 里的键
+const compensationsMetaKey = "ggrmcp/compensations"
+
+// newErrorID 生成错误引用 ID
+//
+// 返回给客户端的错误经过脱敏，完整错误只记录在网关日志中；
+// 同一个 ID 同时出现在两处，用户报告问题时运维人员可以据此查到完整错误。
+func newErrorID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// withErrorID 在错误消息后附加错误 ID
+func withErrorID(message, errorID string) string {
+	return fmt.Sprintf("%s (error ID: %s)", message, errorID)
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/breaker"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// callError returns the text of a failed tool call without its error ID
+// suffix, checking that the ID in the text matches the one in _meta
+func callError(t *testing.T, result interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	var call mcp.ToolCallResult
+	require.NoError(t, json.Unmarshal(data, &call))
+	require.True(t, call.IsError)
+	require.Len(t, call.Content, 1)
+
+	errorID, _ := call.Meta[errorIDMetaKey].(string)
+	require.NotEmpty(t, errorID)
+	suffix := " (error ID: " + errorID + ")"
+	require.True(t, strings.HasSuffix(call.Content[0].Text, suffix), call.Content[0].Text)
+	return strings.TrimSuffix(call.Content[0].Text, suffix)
+}
+
+func TestToolsCall_ErrorDetail(t *testing.T) {
+	upstreamErr := fmt.Errorf("failed to invoke method: %w", status.Error(codes.NotFound, "user 42 missing for token=abc"))
+
+	tests := []struct {
+		detail mcp.ErrorDetail
+		want   string
+	}{
+		{mcp.ErrorDetailVerbose, "Error invoking method: failed to invoke method: rpc error: code = NotFound desc = user 42 missing for token=abc"},
+		{mcp.ErrorDetailStandard, "Error invoking method: failed to invoke method: rpc error: code = NotFound desc = user 42 missing for [REDACTED]"},
+		{mcp.ErrorDetailStrict, "Error invoking method: the upstream service returned NotFound"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.detail), func(t *testing.T) {
+			method := testMethod("user.service", "Get")
+			discoverer := &mockServiceDiscoverer{}
+			discoverer.On("GetMethods").Return([]types.MethodInfo{method})
+			discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).Return("", upstreamErr)
+			handler, _ := newTestHandler(t, discoverer, WithErrorDetail(tt.detail))
+
+			_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+			resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": method.ToolName}, nil)
+			assert.Equal(t, tt.want, callError(t, resp.Result))
+		})
+	}
+}
+
+func TestSanitizeErrorWithDetail_StrictWithoutStatus(t *testing.T) {
+	assert.Equal(t, "the request failed", mcp.SanitizeErrorWithDetail(fmt.Errorf("dial tcp 10.0.0.3:50051: refused"), mcp.ErrorDetailStrict))
+}
+
+func TestRequestErrors_CarryErrorID(t *testing.T) {
+	discoverer := &mockServiceDiscoverer{}
+	handler, _ := newTestHandler(t, discoverer)
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	resp, _ := postRPC(t, handler, "/", sessionID, "prompts/get", map[string]interface{}{}, nil)
+	require.NotNil(t, resp.Error)
+
+	errorID := resp.Error.Data.(map[string]interface{})["errorId"].(string)
+	assert.Len(t, errorID, 16)
+	assert.True(t, strings.HasSuffix(resp.Error.Message, "(error ID: "+errorID+")"), resp.Error.Message)
+}
+
+func TestToolsCall_CircuitOpenMeta(t *testing.T) {
+	method := testMethod("user.service", "Get")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).
+		Return("", &breaker.OpenError{Scope: breaker.ScopeTool, Name: method.ToolName, RetryAfter: 12 * time.Second})
+	handler, _ := newTestHandler(t, discoverer)
+
+	resp, _ := postRPC(t, handler, "/", "", "tools/call", map[string]interface{}{"name": method.ToolName}, nil)
+	assert.Contains(t, callError(t, resp.Result), "circuit open for tool")
+
+	meta := resp.Result.(map[string]interface{})["_meta"].(map[string]interface{})
+	assert.Equal(t, float64(12), meta[retryAfterMetaKey])
+	assert.Equal(t, map[string]interface{}{"scope": "tool", "name": method.ToolName}, meta[circuitMetaKey])
+}
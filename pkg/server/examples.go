@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aalobaidi/ggRMCP/pkg/examples"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"go.uber.org/zap"
+)
+
+// toolExample 示例参数端点的返回结果
+type toolExample struct {
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+// ToolExampleHandler 按工具的 input schema 生成示例参数（GET /admin/tools/example）
+//
+// 查询参数：tool 为工具名（可重复，省略时返回所有工具），required_only=true
+// 时只生成必填参数。schema 取自不限作用域的 tools/list 结果，因此示例与客户端
+// 看到的参数一致（包括展开、参数规则和别名）。未知工具返回 404。
+func (h *Handler) ToolExampleHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := h.handleToolsList(r.Context(), &session.Context{})
+	if err != nil {
+		h.logger.Error("Failed to build tools for examples", zap.Error(err))
+		http.Error(w, "Failed to build tools", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	opts := examples.Options{RequiredOnly: query.Get("required_only") == "true"}
+	wanted := make(map[string]bool)
+	for _, name := range query["tool"] {
+		wanted[name] = true
+	}
+	found := make(map[string]bool)
+
+	result := make([]toolExample, 0, len(list.Tools))
+	for _, tool := range list.Tools {
+		if len(wanted) > 0 && !wanted[tool.Name] {
+			continue
+		}
+		found[tool.Name] = true
+		result = append(result, toolExample{
+			Tool:      tool.Name,
+			Arguments: examples.Arguments(tool.InputSchema, opts),
+		})
+	}
+	for _, name := range query["tool"] {
+		if !found[name] {
+			http.Error(w, "Tool not found: "+name, http.StatusNotFound)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		h.logger.Error("Failed to encode tool examples", zap.Error(err))
+	}
+}
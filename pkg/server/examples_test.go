@@ -0,0 +1,51 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolExampleHandler(t *testing.T) {
+	create := wrapperMethod(t)
+	echo := testMethod("echo.service", "Echo")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{create, echo})
+	discoverer.On("GetMethod", create.ToolName).Return(create, true)
+	discoverer.On("GetMethod", echo.ToolName).Return(echo, true)
+	handler, _ := newTestHandler(t, discoverer, WithToolFlattening(config.ToolFlatteningConfig{Enabled: true}))
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ToolExampleHandler(w, httptest.NewRequest(http.MethodGet, "/admin/tools/example"+query, nil))
+		return w
+	}
+
+	// Examples follow the schema clients see, so the wrapper is flattened
+	w := get("?tool=" + create.ToolName)
+	require.Equal(t, http.StatusOK, w.Code)
+	var result []toolExample
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	require.Len(t, result, 1)
+	assert.Equal(t, create.ToolName, result[0].Tool)
+	assert.Equal(t, map[string]interface{}{"title": "example title", "pages": float64(1)}, result[0].Arguments)
+
+	// Without a tool every tool gets an example
+	w = get("")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	names := make([]string, 0, len(result))
+	for _, example := range result {
+		names = append(names, example.Tool)
+	}
+	assert.Contains(t, names, create.ToolName)
+	assert.Contains(t, names, echo.ToolName)
+
+	assert.Equal(t, http.StatusNotFound, get("?tool=missing_tool").Code)
+}
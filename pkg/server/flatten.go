@@ -0,0 +1,117 @@
+package server
+
+import (
+	"path"
+	"strings"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// WithToolFlattening 设置请求包装消息的展开（默认不展开）
+func WithToolFlattening(cfg config.ToolFlatteningConfig) HandlerOption {
+	return func(h *Handler) {
+		h.flattening = cfg
+	}
+}
+
+// flattenedField 返回工具展开的请求字段名，不展开时返回空字符串
+//
+// 按原始工具名称匹配配置；只有请求消息是包装消息时才展开（见 wrapperField）
+func (h *Handler) flattenedField(toolName string) string {
+	if !h.flattening.Enabled {
+		return ""
+	}
+	if len(h.flattening.Tools) > 0 {
+		matched := false
+		for _, pattern := range h.flattening.Tools {
+			if ok, err := path.Match(pattern, toolName); err == nil && ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return ""
+		}
+	}
+	method, ok := h.serviceDiscoverer.GetMethod(toolName)
+	if !ok || method.InputDescriptor == nil {
+		return ""
+	}
+	return wrapperField(method.InputDescriptor)
+}
+
+// wrapperField 返回包装消息唯一的字段名，消息不是包装消息时返回空字符串
+//
+// 包装消息只有一个字段，且该字段是单个（非 repeated、非 map）的自定义消息：
+// Well-Known Types 有特殊的 JSON 表示，没有可以展开的字段
+func wrapperField(msg protoreflect.MessageDescriptor) string {
+	if msg.Fields().Len() != 1 {
+		return ""
+	}
+	field := msg.Fields().Get(0)
+	if field.Kind() != protoreflect.MessageKind || field.IsList() || field.IsMap() {
+		return ""
+	}
+	nested := field.Message().FullName()
+	if strings.HasPrefix(string(nested), "google.protobuf.") || nested == msg.FullName() {
+		return ""
+	}
+	return string(field.Name())
+}
+
+// flattenInputSchemas 把展开的工具的 input schema 替换为包装字段的消息 schema
+//
+// 包装消息没有注释时使用嵌套消息（或字段）的描述
+func (h *Handler) flattenInputSchemas(toolList []mcp.Tool) {
+	if !h.flattening.Enabled {
+		return
+	}
+	for i, tool := range toolList {
+		field := h.flattenedField(tool.Name)
+		if field == "" {
+			continue
+		}
+		schema, ok := tool.InputSchema.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		nested, ok := properties[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, ok := nested["properties"].(map[string]interface{}); !ok {
+			continue
+		}
+
+		flattened := make(map[string]interface{}, len(nested))
+		for k, v := range nested {
+			flattened[k] = v
+		}
+		if desc, ok := schema["description"]; ok {
+			flattened["description"] = desc
+		}
+		toolList[i].InputSchema = flattened
+	}
+}
+
+// nestArguments 把展开的工具的参数放回包装字段中
+//
+// 客户端仍按原始结构传入（只有包装字段且其值是对象）时保持不变
+func (h *Handler) nestArguments(toolName string, args map[string]interface{}) map[string]interface{} {
+	field := h.flattenedField(toolName)
+	if field == "" {
+		return args
+	}
+	if len(args) == 1 {
+		if _, nested := args[field].(map[string]interface{}); nested {
+			return args
+		}
+	}
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	return map[string]interface{}{field: args}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// wrapperMethod returns library.Books.CreateBook taking CreateBookRequest,
+// a wrapper around a single Book message
+func wrapperMethod(t *testing.T) types.MethodInfo {
+	t.Helper()
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("library/books.proto"),
+		Package: proto.String("library"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Book"), Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("title"), JsonName: proto.String("title"), Number: proto.Int32(1), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+				{Name: proto.String("pages"), JsonName: proto.String("pages"), Number: proto.Int32(2), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum()},
+			}},
+			{Name: proto.String("CreateBookRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("book"), JsonName: proto.String("book"), Number: proto.Int32(1), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".library.Book")},
+			}},
+		},
+	}, new(protoregistry.Files))
+	require.NoError(t, err)
+
+	input := fd.Messages().ByName("CreateBookRequest")
+	output := fd.Messages().ByName("Book")
+	method := types.MethodInfo{
+		Name:             "CreateBook",
+		FullName:         "library.Books.CreateBook",
+		ServiceName:      "library.Books",
+		InputType:        string(input.FullName()),
+		OutputType:       string(output.FullName()),
+		InputDescriptor:  input,
+		OutputDescriptor: output,
+	}
+	method.ToolName = method.GenerateToolName()
+	return method
+}
+
+func TestToolFlattening(t *testing.T) {
+	create := wrapperMethod(t)
+	echo := testMethod("echo.service", "Echo")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{create, echo})
+	discoverer.On("GetMethod", create.ToolName).Return(create, true)
+	discoverer.On("GetMethod", echo.ToolName).Return(echo, true)
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(`{}`, nil)
+	handler, _ := newTestHandler(t, discoverer, WithToolFlattening(config.ToolFlatteningConfig{Enabled: true}))
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+
+	resp, _ := postRPC(t, handler, "/", sessionID, "tools/list", nil, nil)
+	require.Nil(t, resp.Error)
+	data, err := json.Marshal(resp.Result)
+	require.NoError(t, err)
+	var list mcp.ToolsListResult
+	require.NoError(t, json.Unmarshal(data, &list))
+	schemas := make(map[string]map[string]interface{})
+	for _, tool := range list.Tools {
+		schemas[tool.Name] = tool.InputSchema.(map[string]interface{})
+	}
+
+	// The wrapper's fields are listed at the top level
+	properties := schemas[create.ToolName]["properties"].(map[string]interface{})
+	assert.Contains(t, properties, "title")
+	assert.Contains(t, properties, "pages")
+	assert.NotContains(t, properties, "book")
+	// Messages that are not wrappers keep their schema
+	assert.Contains(t, schemas[echo.ToolName]["properties"], "value")
+
+	call := func(toolName string, args map[string]interface{}) {
+		resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{
+			"name":      toolName,
+			"arguments": args,
+		}, nil)
+		require.Nil(t, resp.Error)
+	}
+
+	// Flattened arguments are re-nested before the call
+	call(create.ToolName, map[string]interface{}{"title": "Dune", "pages": 412})
+	discoverer.AssertCalled(t, "InvokeMethodByTool", mock.Anything, mock.Anything, create.ToolName, `{"book":{"pages":412,"title":"Dune"}}`)
+
+	// Arguments already in the wrapped form are passed through
+	call(create.ToolName, map[string]interface{}{"book": map[string]interface{}{"title": "Emma"}})
+	discoverer.AssertCalled(t, "InvokeMethodByTool", mock.Anything, mock.Anything, create.ToolName, `{"book":{"title":"Emma"}}`)
+
+	call(echo.ToolName, map[string]interface{}{"value": "hi"})
+	discoverer.AssertCalled(t, "InvokeMethodByTool", mock.Anything, mock.Anything, echo.ToolName, `{"value":"hi"}`)
+}
+
+func TestToolFlattening_ToolPatterns(t *testing.T) {
+	create := wrapperMethod(t)
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethod", create.ToolName).Return(create, true)
+	handler, _ := newTestHandler(t, discoverer, WithToolFlattening(config.ToolFlatteningConfig{
+		Enabled: true,
+		Tools:   []string{"billing_*"},
+	}))
+
+	assert.Empty(t, handler.flattenedField(create.ToolName))
+	args := map[string]interface{}{"title": "Dune"}
+	assert.Equal(t, args, handler.nestArguments(create.ToolName, args))
+
+	handler.flattening.Tools = []string{"library_*"}
+	assert.Equal(t, "book", handler.flattenedField(create.ToolName))
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"path"
+	"strings"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+)
+
+// toolGroupMetaKey tools/list 中分组提示在 _meta 里的键
+const toolGroupMetaKey = "ggrmcp/group"
+
+// toolGroup 单个工具的分组提示
+type toolGroup struct {
+	Service  string `json:"service,omitempty"`
+	Package  string `json:"package,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+// label 标题前缀使用的分组名：优先使用配置的分类，其次是服务短名
+func (g toolGroup) label() string {
+	if g.Category != "" {
+		return g.Category
+	}
+	if i := strings.LastIndex(g.Service, "."); i >= 0 {
+		return g.Service[i+1:]
+	}
+	return g.Service
+}
+
+// WithToolGrouping 设置 tools/list 中的分组提示（默认使用 config.Default() 中的配置）
+func WithToolGrouping(cfg config.ToolGroupingConfig) HandlerOption {
+	return func(h *Handler) {
+		h.grouping = cfg
+	}
+}
+
+// toolGroups 按原始工具名称计算每个工具的分组，结果与 toolList 一一对应
+//
+// 必须在别名生效之前调用，分类规则和服务信息都按原始名称匹配。
+func (h *Handler) toolGroups(toolList []mcp.Tool, methods []types.MethodInfo) []toolGroup {
+	if !h.grouping.Enabled {
+		return nil
+	}
+	byTool := make(map[string]types.MethodInfo, len(methods))
+	for _, method := range methods {
+		byTool[method.ToolName] = method
+	}
+
+	groups := make([]toolGroup, len(toolList))
+	for i, tool := range toolList {
+		if method, ok := byTool[tool.Name]; ok {
+			groups[i].Service = method.ServiceName
+			if j := strings.LastIndex(method.ServiceName, "."); j >= 0 {
+				groups[i].Package = method.ServiceName[:j]
+			}
+		}
+		for _, category := range h.grouping.Categories {
+			if matched, err := path.Match(category.Tool, tool.Name); err == nil && matched {
+				groups[i].Category = category.Category
+				break
+			}
+		}
+	}
+	return groups
+}
+
+// applyToolGroups 将分组写入工具的 _meta，并按配置为标题加上分组前缀
+func (h *Handler) applyToolGroups(toolList []mcp.Tool, groups []toolGroup) {
+	for i, group := range groups {
+		if group == (toolGroup{}) {
+			continue
+		}
+		meta := make(map[string]interface{}, len(toolList[i].Meta)+1)
+		for k, v := range toolList[i].Meta {
+			meta[k] = v
+		}
+		meta[toolGroupMetaKey] = group
+		toolList[i].Meta = meta
+
+		if label := group.label(); h.grouping.TitlePrefix && label != "" {
+			title := toolList[i].Title
+			if title == "" {
+				title = toolList[i].Name
+			}
+			toolList[i].Title = label + ": " + title
+		}
+	}
+}
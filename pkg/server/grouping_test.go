@@ -0,0 +1,74 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listTools(t *testing.T, handler *Handler) map[string]map[string]interface{} {
+	t.Helper()
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	resp, _ := postRPC(t, handler, "/", sessionID, "tools/list", nil, nil)
+	require.Nil(t, resp.Error)
+	tools := make(map[string]map[string]interface{})
+	for _, tool := range resp.Result.(map[string]interface{})["tools"].([]interface{}) {
+		tool := tool.(map[string]interface{})
+		tools[tool["name"].(string)] = tool
+	}
+	return tools
+}
+
+func TestToolsList_GroupingMeta(t *testing.T) {
+	billing := testMethod("acme.billing.v1.InvoiceService", "Get")
+	users := testMethod("acme.users.v1.UserService", "Get")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{billing, users})
+	handler, _ := newTestHandler(t, discoverer)
+
+	tools := listTools(t, handler)
+	group := tools[billing.ToolName]["_meta"].(map[string]interface{})[toolGroupMetaKey]
+	assert.Equal(t, map[string]interface{}{
+		"service": "acme.billing.v1.InvoiceService",
+		"package": "acme.billing.v1",
+	}, group)
+	assert.NotContains(t, tools[users.ToolName], "title")
+}
+
+func TestToolsList_GroupingCategoriesAndTitles(t *testing.T) {
+	billing := testMethod("acme.billing.v1.InvoiceService", "Get")
+	users := testMethod("acme.users.v1.UserService", "Get")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{billing, users})
+	handler, _ := newTestHandler(t, discoverer,
+		WithToolAliases([]config.ToolAliasConfig{{Tool: billing.ToolName, Alias: "get_invoice", Title: "Get Invoice"}}),
+		WithToolGrouping(config.ToolGroupingConfig{
+			Enabled:     true,
+			TitlePrefix: true,
+			Categories:  []config.ToolCategoryConfig{{Tool: "acme_billing_*", Category: "Billing"}},
+		}))
+
+	tools := listTools(t, handler)
+
+	// Categories match the generated name even when the tool is aliased
+	invoice := tools["get_invoice"]
+	require.NotNil(t, invoice)
+	assert.Equal(t, "Billing: Get Invoice", invoice["title"])
+	assert.Equal(t, "Billing", invoice["_meta"].(map[string]interface{})[toolGroupMetaKey].(map[string]interface{})["category"])
+
+	// Without a category the service name labels the group
+	assert.Equal(t, "UserService: "+users.ToolName, tools[users.ToolName]["title"])
+}
+
+func TestToolsList_GroupingDisabled(t *testing.T) {
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{testMethod("acme.users.v1.UserService", "Get")})
+	handler, _ := newTestHandler(t, discoverer, WithToolGrouping(config.ToolGroupingConfig{}))
+
+	for _, tool := range listTools(t, handler) {
+		assert.NotContains(t, tool, "_meta")
+	}
+}
@@ -1,19 +1,33 @@
 package server
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"runtime/debug"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/aalobaidi/ggRMCP/pkg/anomaly"
+	"github.com/aalobaidi/ggRMCP/pkg/arguments"
+	"github.com/aalobaidi/ggRMCP/pkg/audit"
+	"github.com/aalobaidi/ggRMCP/pkg/breaker"
+	"github.com/aalobaidi/ggRMCP/pkg/composite"
 	"github.com/aalobaidi/ggRMCP/pkg/config"
 	"github.com/aalobaidi/ggRMCP/pkg/grpc"
 	"github.com/aalobaidi/ggRMCP/pkg/headers"
 	"github.com/aalobaidi/ggRMCP/pkg/mcp"
 	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"github.com/aalobaidi/ggRMCP/pkg/summary"
+	"github.com/aalobaidi/ggRMCP/pkg/telemetry"
 	"github.com/aalobaidi/ggRMCP/pkg/tools"
+	"github.com/aalobaidi/ggRMCP/pkg/transform"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
 	"go.uber.org/zap"
 )
 
@@ -41,7 +55,8 @@ import (
 //	   ├─ initialize → handleInitialize
 //	   ├─ tools/list → handleToolsList
 //	   ├─ tools/call → handleToolsCall
-//	   └─ prompts/list, resources/list (占位)
+//	   ├─ prompts/list, prompts/get → 服务使用提示
+//	   └─ resources/list (占位)
 //	   ↓
 //	响应序列化和返回
 //
@@ -52,13 +67,162 @@ import (
 // - sessionManager: 会话管理器，维护客户端状态和限流
 // - toolBuilder: MCP 工具构建器，将 gRPC 方法转换为 MCP 工具
 // - headerFilter: HTTP Header 过滤器，安全地转发 headers 到 gRPC
+// - sessionMetadata: 会话元数据配置，控制元数据如何注入到工具调用
+// - maxConcurrentCalls: 每个会话同时执行的工具调用上限（0 表示不限制）
+// - argumentInjector: 按工具配置的默认参数与固定参数（nil 表示不注入）
+// - responseRules: 响应转换规则，用于从 output schema 中裁剪字段（nil 表示不裁剪）
+// - composites: 配置定义的组合工具，按顺序调用多个已发现的工具（nil 表示没有）
+// - aliases: 工具别名与显示标题（nil 表示没有）
+// - grouping: tools/list 中的分组提示配置
+// - toolDocs: 追加到工具描述的 markdown 文档（nil 表示没有）
+// - errorDetail: 工具调用失败时返回给客户端的错误详细程度
+// - loadShedder: 网关级别的工具调用负载保护（nil 表示不限制）
+// - output: 工具调用结果的序列化方式（例如规范化 JSON）
+// - identity: 从已验证 token 的 claim 得到的身份 metadata（nil 表示不转发）
+// - redactLogs: 日志中不记录请求参数、工具参数和 header 值
+// - baggage: 每次上游调用附带的 W3C baggage 条目（nil 表示不附加）
+// - flattening: 展开只包含一个嵌套消息字段的请求包装消息
+// - describeTool: 是否注册内置的 ggrmcp_describe_tool 工具
+// - introspectionTools: 是否注册内置的服务列表与网关状态工具
+// - coercion: 反序列化前对参数做的修正（类型错误、枚举名称）
+// - summary / summarizer / results: 超大结果的摘要方式，以及被摘要的完整结果
+// - version: 网关版本，用于 initialize 的 serverInfo 和网关状态工具
 type Handler struct {
-	logger            *zap.Logger
-	validator         *mcp.Validator
-	serviceDiscoverer grpc.ServiceDiscoverer
-	sessionManager    *session.Manager
-	toolBuilder       *tools.MCPToolBuilder
-	headerFilter      *headers.Filter
+	logger             *zap.Logger
+	validator          *mcp.Validator
+	serviceDiscoverer  grpc.ServiceDiscoverer
+	sessionManager     *session.Manager
+	toolBuilder        *tools.MCPToolBuilder
+	headerFilter       *headers.Filter
+	sessionMetadata    config.SessionMetadataConfig
+	maxConcurrentCalls int
+	argumentInjector   *arguments.Injector
+	responseRules      *transform.Rules
+	composites         *composite.Registry
+	aliases            *toolAliases
+	grouping           config.ToolGroupingConfig
+	toolDocs           ToolDocs
+	errorDetail        mcp.ErrorDetail
+	loadShedder        *loadShedder
+	output             config.ToolOutputConfig
+	identity           *identity
+	redactLogs         bool
+	inputLimits        []config.ToolInputLimitConfig
+	hints              *toolHints
+	usage              *toolUsage
+	anomalies          *anomaly.Detector
+	sanitizers         []arguments.Sanitizer
+	auditLog           *audit.Log
+	baggage            *config.BaggageConfig
+	flattening         config.ToolFlatteningConfig
+	describeTool       bool
+	introspectionTools bool
+	coercion           arguments.Options
+	summary            config.ToolSummaryConfig
+	summarizer         summary.Summarizer
+	results            *resultStore
+	streams            *streamRegistry
+	channels           *sessionChannels
+	history            config.SessionHistoryConfig
+	batch              config.BatchConfig
+	upstreamSwitch     bool
+	legacy             *legacySessions
+	pending            *pendingRequests
+	metrics            telemetry.Metrics
+	tracer             telemetry.Tracer
+	certMonitor        *grpc.CertificateMonitor
+	version            string
+
+	// clientDisconnects 统计客户端在工具调用完成前断开连接的次数
+	clientDisconnects atomic.Int64
+	// oversizedResponses 统计响应超过接收消息大小上限的工具调用次数
+	oversizedResponses atomic.Int64
+}
+
+// HandlerOption 用于定制 Handler 的可选配置
+type HandlerOption func(*Handler)
+
+// defaultVersion 未通过 WithVersion 设置时使用的网关版本
+const defaultVersion = "1.0.0"
+
+// WithVersion 设置网关版本（构建时通过 -ldflags 注入）
+func WithVersion(version string) HandlerOption {
+	return func(h *Handler) {
+		if version != "" {
+			h.version = version
+		}
+	}
+}
+
+// WithMaxConcurrentCalls 设置每个会话并发工具调用的上限（0 表示不限制）
+func WithMaxConcurrentCalls(limit int) HandlerOption {
+	return func(h *Handler) {
+		h.maxConcurrentCalls = limit
+	}
+}
+
+// WithArgumentInjector 设置按工具配置的默认参数与固定参数
+func WithArgumentInjector(injector *arguments.Injector) HandlerOption {
+	return func(h *Handler) {
+		h.argumentInjector = injector
+	}
+}
+
+// WithResponseRules 设置响应转换规则，tools/list 中的 output schema 会去掉被裁剪的字段
+func WithResponseRules(rules *transform.Rules) HandlerOption {
+	return func(h *Handler) {
+		h.responseRules = rules
+	}
+}
+
+// WithArgumentSanitizers 添加参数校验/清理钩子，按顺序作用于客户端提供的参数
+func WithArgumentSanitizers(sanitizers ...arguments.Sanitizer) HandlerOption {
+	return func(h *Handler) {
+		h.sanitizers = append(h.sanitizers, sanitizers...)
+	}
+}
+
+// WithErrorDetail 设置工具调用错误返回给客户端的详细程度
+func WithErrorDetail(detail mcp.ErrorDetail) HandlerOption {
+	return func(h *Handler) {
+		h.errorDetail = detail
+	}
+}
+
+// WithComposites 设置配置定义的组合工具
+func WithComposites(registry *composite.Registry) HandlerOption {
+	return func(h *Handler) {
+		h.composites = registry
+	}
+}
+
+// WithSessionMetadata 设置会话元数据的注入配置（默认使用 config.Default() 中的配置）
+func WithSessionMetadata(cfg config.SessionMetadataConfig) HandlerOption {
+	return func(h *Handler) {
+		h.sessionMetadata = cfg
+	}
+}
+
+// WithAnomalyDetector 设置异常调用模式检测（nil 表示不启用）
+func WithAnomalyDetector(detector *anomaly.Detector) HandlerOption {
+	return func(h *Handler) {
+		h.anomalies = detector
+	}
+}
+
+// WithLogRedaction 设置是否在日志中隐去请求参数、工具参数和 header 值
+func WithLogRedaction(redact bool) HandlerOption {
+	return func(h *Handler) {
+		h.redactLogs = redact
+	}
+}
+
+// logPayload 返回记录请求内容的日志字段，开启脱敏时只记录占位符
+func (h *Handler) logPayload(key string, value interface{}) zap.Field {
+	if h.redactLogs {
+		return zap.String(key, "[REDACTED]")
+	}
+	return zap.Any(key, value)
 }
 
 // NewHandler 创建一个新的 HTTP 请求处理器
@@ -75,6 +239,7 @@ type Handler struct {
 //   - sessionManager: 会话管理器，用于维护客户端会话
 //   - toolBuilder: MCP 工具构建器，用于生成工具 schema
 //   - headerConfig: Header 转发配置，指定哪些 headers 可以转发
+//   - opts: 可选配置，例如 WithSessionMetadata
 //
 // 返回值：
 //   - *Handler: 完整初始化的处理器实例
@@ -93,15 +258,33 @@ func NewHandler(
 	sessionManager *session.Manager,
 	toolBuilder *tools.MCPToolBuilder,
 	headerConfig config.HeaderForwardingConfig,
+	opts ...HandlerOption,
 ) *Handler {
-	return &Handler{
-		logger:            logger,
-		validator:         mcp.NewValidator(), // 创建新的 MCP 验证器
-		serviceDiscoverer: serviceDiscoverer,
-		sessionManager:    sessionManager,
-		toolBuilder:       toolBuilder,
-		headerFilter:      headers.NewFilter(headerConfig), // 创建 header 过滤器
+	h := &Handler{
+		logger:             logger,
+		validator:          mcp.NewValidator(), // 创建新的 MCP 验证器
+		serviceDiscoverer:  serviceDiscoverer,
+		sessionManager:     sessionManager,
+		toolBuilder:        toolBuilder,
+		headerFilter:       headers.NewFilter(headerConfig), // 创建 header 过滤器
+		sessionMetadata:    config.Default().Session.Metadata,
+		maxConcurrentCalls: config.Default().Session.MaxConcurrentCalls,
+		grouping:           config.Default().Tools.Grouping,
+		errorDetail:        mcp.ErrorDetail(config.Default().MCP.ErrorDetail),
+		loadShedder:        newLoadShedder(config.Default().Server.Backpressure),
+		batch:              config.Default().Server.Batch,
+		usage:              newToolUsage(config.Default().Tools.Usage),
+		channels:           newSessionChannels(),
+		legacy:             newLegacySessions(),
+		pending:            newPendingRequests(),
+		metrics:            telemetry.Nop,
+		tracer:             telemetry.Nop,
+		version:            defaultVersion,
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	return h
 }
 
 // ServeHTTP 实现 http.Handler 接口，处理所有 HTTP 请求
@@ -123,6 +306,11 @@ func NewHandler(
 //   - w: HTTP 响应写入器
 //   - r: HTTP 请求对象
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// 🔐 认证失败过多的客户端在锁定期内直接拒绝；要求 token 时没有有效 token 的请求返回 401
+	if (r.Method == http.MethodGet || r.Method == http.MethodPost || r.Method == http.MethodDelete) && !h.authenticate(w, r) {
+		return
+	}
+
 	// 🔀 根据 HTTP 方法分发请求到相应的处理器
 	switch r.Method {
 	case http.MethodGet:
@@ -131,6 +319,9 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	case http.MethodPost:
 		// POST 请求：处理 JSON-RPC 请求（工具调用）
 		h.handlePost(w, r)
+	case http.MethodDelete:
+		// DELETE 请求：终止会话
+		h.handleDelete(w, r)
 	default:
 		// 不支持的 HTTP 方法
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -163,6 +354,12 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 // - 如果不存在，自动创建新会话
 // - 将会话 ID 写入响应 Header，便于客户端后续使用
 func (h *Handler) handleGet(w http.ResponseWriter, r *http.Request) {
+	// 📡 接受 SSE 的 GET 打开会话的事件通道（Streamable HTTP 传输）
+	if acceptsEventStream(r) {
+		h.handleEventChannel(w, r)
+		return
+	}
+
 	// 📋 第一步：提取会话 ID
 	// 从 HTTP Header 读取 Mcp-Session-Id，如果不存在则为空字符串
 	// sessionManager 会自动创建新会话
@@ -177,9 +374,15 @@ func (h *Handler) handleGet(w http.ResponseWriter, r *http.Request) {
 	// 客户端可以通过此 Header 获得会话 ID，用于后续请求
 	w.Header().Set("Mcp-Session-Id", sessionCtx.ID)
 
+	// 🔐 应用客户端请求的工具作用域（查询参数、Header 或 token claim）
+	h.applyToolScope(r, sessionCtx)
+
+	// 🏷️ 合并 Mcp-Meta-* Header 中的会话元数据
+	h.applyMetadataHeaders(r, sessionCtx)
+
 	// 🎯 第四步：生成初始化结果
 	// handleInitialize 会返回服务器的能力信息
-	initResult := h.handleInitialize()
+	initResult := h.handleInitialize(nil)
 
 	// 📦 第五步：构建 JSON-RPC 响应
 	response := &mcp.JSONRPCResponse{
@@ -231,9 +434,16 @@ func (h *Handler) handleGet(w http.ResponseWriter, r *http.Request) {
 //   - w: HTTP 响应写入器
 //   - r: HTTP 请求对象（包含 JSON-RPC 请求体）
 func (h *Handler) handlePost(w http.ResponseWriter, r *http.Request) {
+	// 📚 请求体是 JSON 数组时按批量请求处理
+	body := bufio.NewReader(r.Body)
+	if isBatch(body) {
+		h.handleBatch(w, r, body)
+		return
+	}
+
 	// 🔍 第一步：解析 JSON-RPC 请求体
 	var req mcp.JSONRPCRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
 		// 解析失败：JSON 格式错误
 		h.logger.Error("Failed to decode JSON-RPC request", zap.Error(err))
 		// 返回 Parse Error 错误码 (-32700)
@@ -241,6 +451,21 @@ func (h *Handler) handlePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 🛟 处理过程中的 panic 转换为带请求 ID 的 Internal error
+	defer h.recoverRequest(w, r, &req)
+
+	// 🔌 读完请求体剩余内容（如尾随换行、chunked 结束块）
+	// net/http 只有在请求体读到 EOF 后才会监听连接关闭，
+	// 之后客户端断开会立即取消 r.Context()，进而取消上游 gRPC 调用
+	_, _ = io.Copy(io.Discard, body)
+
+	// 📭 没有 id 的消息是通知（例如 notifications/initialized、notifications/cancelled）：
+	// 处理后返回 202 Accepted，不返回 JSON-RPC 响应
+	if req.ID.Value == nil {
+		h.handleNotification(w, r, &req)
+		return
+	}
+
 	// ✅ 第二步：验证 JSON-RPC 请求格式
 	// 验证内容：必需字段、类型检查、版本检查等
 	if err := h.validator.ValidateRequest(&req); err != nil {
@@ -252,39 +477,69 @@ func (h *Handler) handlePost(w http.ResponseWriter, r *http.Request) {
 
 	// 📋 第三步：提取或创建会话
 	// 会话用于维护客户端状态、实现限流、追踪请求
+	// 未知的会话 ID（已过期或已终止）返回 404，客户端需要重新 initialize；
+	// 没有会话 ID 的请求仍会创建新会话，兼容不维护会话的客户端
 	sessionID := r.Header.Get("Mcp-Session-Id")
+	if sessionID != "" && req.Method != "initialize" {
+		if _, ok := h.sessionManager.GetSession(sessionID); !ok {
+			writeSessionNotFound(w, req.ID)
+			return
+		}
+	}
 	sessionCtx := h.sessionManager.GetOrCreateSession(sessionID, extractHeaders(r))
 
 	// 📤 第四步：将会话 ID 设置到响应 Header
 	w.Header().Set("Mcp-Session-Id", sessionCtx.ID)
 
+	// 🔐 应用客户端请求的工具作用域（仅在会话尚未设置作用域时生效）
+	h.applyToolScope(r, sessionCtx)
+
+	// 🏷️ 合并 Mcp-Meta-* Header 中的会话元数据
+	h.applyMetadataHeaders(r, sessionCtx)
+
 	// 📝 第五步：记录请求日志
 	h.logger.Info("Processing MCP request",
 		zap.String("method", req.Method),
 		zap.String("sessionId", sessionCtx.ID),
-		zap.Any("params", req.Params))
+		h.logPayload("params", req.Params))
 
-	// 🎯 第六步：路由到具体的处理方法
-	// handleRequest 会根据 method 字段分发请求
-	result, err := h.handleRequest(r.Context(), &req, sessionCtx)
-	if err != nil {
-		// 处理出错：记录日志并返回错误
-		h.logger.Error("Request handling failed",
-			zap.String("method", req.Method),
-			zap.Error(err))
-
-		// 🔍 第七步：确定合适的错误码
-		var errorCode int
-		if strings.Contains(err.Error(), "not found") {
-			errorCode = mcp.ErrorCodeMethodNotFound // -32601
-		} else if strings.Contains(err.Error(), "invalid") {
-			errorCode = mcp.ErrorCodeInvalidParams // -32602
-		} else {
-			errorCode = mcp.ErrorCodeInternalError // -32603
+	// 📡 客户端请求了进度通知时，服务端流式工具收到的消息以 SSE 逐条发送；
+	// 只接受 SSE 的客户端以 SSE 事件接收响应
+	ctx := r.Context()
+	if stream := newProgressStream(w, r, &req); stream != nil {
+		w = stream
+		if stream.token != nil {
+			ctx = grpc.WithStreamObserver(ctx, stream.send)
 		}
+	}
 
-		// 返回错误响应
-		h.writeErrorResponse(w, req.ID, errorCode, mcp.SanitizeError(err))
+	// 🎯 第六步：路由到具体的处理方法
+	// handleRequest 会根据 method 字段分发请求；处理期间客户端可以用 notifications/cancelled 取消请求
+	// 会话中已有相同 ID 的请求在处理时拒绝，否则取消通知无法区分两者
+	ctx, done, ok := h.pending.track(ctx, sessionCtx.ID, req.ID)
+	if !ok {
+		h.writeErrorResponse(w, req.ID, mcp.ErrorCodeInvalidRequest, duplicateRequestIDMessage)
+		return
+	}
+	defer done()
+	result, err := h.handleRequest(ctx, &req, sessionCtx)
+	// 🛑 网关过载：返回 503 和重试信息
+	var overloaded *overloadedError
+	if errors.As(err, &overloaded) {
+		writeThrottled(w, req.ID, http.StatusServiceUnavailable, overloaded.Error(), mcp.RetryInfo{
+			RetryAfterSeconds: retryAfterSeconds(overloaded.retryAfter),
+			Scope:             "gateway",
+		})
+		return
+	}
+	// 🔌 客户端已断开：没有人接收响应
+	if err != nil && r.Context().Err() != nil {
+		return
+	}
+	if err != nil {
+		// 🔍 第七步：处理出错，记录日志并返回对应错误码的错误，错误 ID 同时出现在日志和响应中
+		rpcErr := h.requestError(req.Method, err)
+		h.writeErrorResponseWithData(w, req.ID, rpcErr.Code, rpcErr.Message, rpcErr.Data)
 		return
 	}
 
@@ -299,14 +554,43 @@ func (h *Handler) handlePost(w http.ResponseWriter, r *http.Request) {
 	h.writeJSONResponse(w, response)
 }
 
+// requestError 把 handleRequest 返回的错误转换为 JSON-RPC 错误并记录日志，
+// 错误 ID 同时出现在日志和响应中
+func (h *Handler) requestError(method string, err error) *mcp.RPCError {
+	errorID := newErrorID()
+	h.logger.Error("Request handling failed",
+		zap.String("method", method),
+		zap.String("errorId", errorID),
+		zap.Error(err))
+
+	// 🔍 确定合适的错误码
+	var errorCode int
+	if strings.Contains(err.Error(), "not found") {
+		errorCode = mcp.ErrorCodeMethodNotFound // -32601
+	} else if strings.Contains(err.Error(), "invalid") {
+		errorCode = mcp.ErrorCodeInvalidParams // -32602
+	} else {
+		errorCode = mcp.ErrorCodeInternalError // -32603
+	}
+	return &mcp.RPCError{
+		Code:    errorCode,
+		Message: withErrorID(mcp.SanitizeError(err), errorID),
+		Data:    map[string]interface{}{"errorId": errorID},
+	}
+}
+
 // handleRequest 路由 JSON-RPC 请求到相应的处理方法
 //
 // 支持的方法：
 // - initialize: 获取服务器初始化信息
 // - tools/list: 列出所有可用的工具（gRPC 方法）
 // - tools/call: 调用指定的工具（执行 gRPC 方法）
-// - prompts/list: 列出可用的提示（占位实现）
-// - resources/list: 列出可用的资源（占位实现）
+// - prompts/list: 列出每个服务自动生成的使用提示
+// - prompts/get: 获取服务的使用提示
+// - resources/list: 列出可用的资源（工具使用统计，需开启）
+// - resources/read: 读取资源内容
+// - session/setMetadata: 设置会话元数据（注入到后续的工具调用）
+// - session/getMetadata: 查询当前会话元数据
 //
 // 参数：
 //   - ctx: 上下文，用于超时控制和取消
@@ -316,26 +600,51 @@ func (h *Handler) handlePost(w http.ResponseWriter, r *http.Request) {
 // 返回值：
 //   - interface{}: 处理结果（具体类型取决于方法）
 //   - error: 处理过程中的错误
-func (h *Handler) handleRequest(ctx context.Context, req *mcp.JSONRPCRequest, sessionCtx *session.Context) (interface{}, error) {
+func (h *Handler) handleRequest(ctx context.Context, req *mcp.JSONRPCRequest, sessionCtx *session.Context) (result interface{}, err error) {
+	// 📡 每个请求一个 span，并按方法和结果计数；不支持的方法统一记为 unknown，避免标签无限增长
+	method := req.Method
+	ctx, span := h.tracer.Start(ctx, telemetry.SpanRequest, telemetry.Tags{"method": method, "session": sessionCtx.ID})
+	defer func() {
+		outcome := callOutcome(ctx, err)
+		h.metrics.Count(telemetry.MetricRequests, 1, telemetry.Tags{"method": method, "outcome": outcome})
+		span.SetTag("outcome", outcome)
+		span.End(err)
+	}()
+
 	// 🔀 根据 method 字段路由到不同的处理函数
 	switch req.Method {
 	case "initialize":
-		// 服务器初始化：返回能力信息
-		return h.handleInitialize(), nil
+		// 服务器初始化：保存客户端提供的默认 gRPC metadata，返回能力信息
+		h.applyInitializeMetadata(req.Params, sessionCtx)
+		h.applyResultFormat(req.Params, sessionCtx)
+		return h.handleInitialize(req.Params), nil
 	case "tools/list":
 		// 列出所有可用的工具
-		return h.handleToolsList(ctx)
+		return h.handleToolsList(ctx, sessionCtx)
 	case "tools/call":
 		// 调用指定的工具（实际的 gRPC 方法调用）
 		return h.handleToolsCall(ctx, req.Params, sessionCtx)
 	case "prompts/list":
-		// 列出可用的提示
-		return h.handlePromptsList(ctx)
+		// 列出每个服务自动生成的使用提示
+		return h.handlePromptsList(sessionCtx)
+	case promptsGetMethod:
+		// 获取服务的使用提示
+		return h.handlePromptsGet(req.Params, sessionCtx)
 	case "resources/list":
 		// 列出可用的资源
 		return h.handleResourcesList(ctx)
+	case resourcesReadMethod:
+		// 读取资源内容
+		return h.handleResourcesRead(req.Params, sessionCtx)
+	case sessionSetMetadataMethod:
+		// 设置会话元数据
+		return h.handleSetMetadata(req.Params, sessionCtx)
+	case sessionGetMetadataMethod:
+		// 查询会话元数据
+		return h.handleGetMetadata(sessionCtx)
 	default:
 		// 不支持的方法
+		method = "unknown"
 		return nil, fmt.Errorf("method not found: %s", req.Method)
 	}
 }
@@ -355,7 +664,7 @@ func (h *Handler) handleRequest(ctx context.Context, req *mcp.JSONRPCRequest, se
 //	{
 //	    "protocolVersion": "2024-11-05",
 //	    "capabilities": {
-//	        "tools": {"listChanged": false},
+//	        "tools": {"listChanged": true},
 //	        "prompts": {"listChanged": false},
 //	        "resources": {"listChanged": false}
 //	    },
@@ -364,14 +673,14 @@ func (h *Handler) handleRequest(ctx context.Context, req *mcp.JSONRPCRequest, se
 //	        "version": "1.0.0"
 //	    }
 //	}
-func (h *Handler) handleInitialize() *mcp.InitializationResult {
+func (h *Handler) handleInitialize(params map[string]interface{}) *mcp.InitializationResult {
 	// 🏗️ 构建初始化结果
 	return &mcp.InitializationResult{
-		ProtocolVersion: "2024-11-05", // MCP 协议版本
+		ProtocolVersion: negotiateProtocolVersion(params), // 按客户端请求协商的 MCP 协议版本
 		Capabilities: mcp.ServerCapabilities{
-			// 工具支持：ListChanged=false 表示工具列表不会动态变化
+			// 工具支持：切换上游导致工具列表变化时，通过 GET 事件通道发送 list_changed 通知
 			Tools: &mcp.ToolsCapability{
-				ListChanged: false,
+				ListChanged: true,
 			},
 			// 提示支持：ListChanged=false 表示提示列表不会动态变化
 			Prompts: &mcp.PromptsCapability{
@@ -383,8 +692,8 @@ func (h *Handler) handleInitialize() *mcp.InitializationResult {
 			},
 		},
 		ServerInfo: mcp.ServerInfo{
-			Name:    "ggRMCP", // 服务器名称
-			Version: "1.0.0",  // 版本号
+			Name:    "ggRMCP",  // 服务器名称
+			Version: h.version, // 版本号
 		},
 	}
 }
@@ -403,6 +712,7 @@ func (h *Handler) handleInitialize() *mcp.InitializationResult {
 //
 // 参数：
 //   - ctx: 上下文，用于超时控制
+//   - sessionCtx: 会话上下文，用于按会话作用域过滤工具
 //
 // 返回值：
 //   - *mcp.ToolsListResult: 包含所有工具的列表结果
@@ -425,9 +735,15 @@ func (h *Handler) handleInitialize() *mcp.InitializationResult {
 //	        }
 //	    ]
 //	}
-func (h *Handler) handleToolsList(ctx context.Context) (*mcp.ToolsListResult, error) {
+func (h *Handler) handleToolsList(ctx context.Context, sessionCtx *session.Context) (*mcp.ToolsListResult, error) {
 	// 📡 第一步：从 ServiceDiscoverer 获取所有已发现的 gRPC 方法
-	methods := h.serviceDiscoverer.GetMethods()
+	// 只保留会话作用域允许的工具
+	var methods []types.MethodInfo
+	for _, method := range h.serviceDiscoverer.GetMethods() {
+		if sessionCtx.IsToolAllowed(method.ToolName) {
+			methods = append(methods, method)
+		}
+	}
 
 	h.logger.Info("Processing methods for tools list",
 		zap.Int("methodCount", len(methods)))
@@ -455,6 +771,53 @@ func (h *Handler) handleToolsList(ctx context.Context) (*mcp.ToolsListResult, er
 		return nil, fmt.Errorf("failed to build tools: %w", err)
 	}
 
+	// 🔗 追加配置定义的组合工具
+	for _, tool := range h.composites.Tools() {
+		if sessionCtx.IsToolAllowed(tool.Name()) {
+			toolList = append(toolList, tool.MCPTool())
+		}
+	}
+
+	// 📤 展开请求包装消息，参数规则按展开后的字段名作用
+	h.flattenInputSchemas(toolList)
+
+	// 🧩 根据参数规则调整 input schema：隐藏固定参数，默认参数变为可选
+	if h.argumentInjector != nil {
+		for i := range toolList {
+			toolList[i].InputSchema = h.argumentInjector.AdjustSchema(toolList[i].Name, toolList[i].InputSchema)
+		}
+	}
+
+	// ✂️ 从 output schema 中裁剪响应规则会删除的字段，使 schema 与实际响应一致
+	if h.responseRules != nil {
+		for i := range toolList {
+			toolList[i].OutputSchema = h.responseRules.PruneSchema(toolList[i].Name, toolList[i].OutputSchema)
+		}
+	}
+
+	// 📖 追加 markdown 文档
+	h.appendToolDocs(toolList)
+
+	// 🩺 标记上游服务报告 NOT_SERVING 的工具
+	h.markNotServingTools(toolList)
+
+	// 🧭 附加延迟、成本和副作用提示，供 agent 规划时优先选择低成本工具
+	h.applyToolHints(toolList)
+
+	// 🏷️ 以别名列出工具并设置显示标题，再附加分组提示
+	// 文档、提示和分组都需在别名生效前按原始名称处理
+	groups := h.toolGroups(toolList, methods)
+	toolList = h.aliases.apply(toolList)
+	h.applyToolGroups(toolList, groups)
+
+	// 📚 内置工具（工具说明、服务列表、网关状态），不参与文档、提示、分组和别名；
+	// 与其他工具一样受会话作用域限制
+	for _, builtin := range h.builtinTools() {
+		if sessionCtx.IsToolAllowed(builtin.definition.Name) {
+			toolList = append(toolList, builtin.definition)
+		}
+	}
+
 	h.logger.Info("Generated tools list", zap.Int("toolCount", len(toolList)))
 
 	// 📦 第四步：返回工具列表
@@ -491,22 +854,46 @@ func (h *Handler) handleToolsCall(ctx context.Context, params map[string]interfa
 	}
 
 	// 📌 第二步：提取工具名称
-	toolName := params["name"].(string)
-
-	// 📋 第三步：提取和序列化参数
-	var argumentsJSON string
-	if args, exists := params["arguments"]; exists && args != nil {
-		// 将参数对象转换为 JSON 字符串，用于 gRPC 调用
-		argBytes, err := json.Marshal(args)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal arguments: %w", err)
-		}
-		argumentsJSON = string(argBytes)
+	// 别名解析为原始名称，后续的作用域检查和参数规则都使用原始名称
+	toolName := h.resolveToolName(params["name"].(string))
+
+	// 🔐 会话作用域之外的工具（包括内置工具）按不存在处理，不暴露其存在性
+	if rejection := h.checkToolScope(toolName, sessionCtx); rejection != nil {
+		return rejection.result(), nil
 	}
 
+	// 📚 内置工具由网关直接处理：只返回会话可见的工具
+	if builtin, ok := h.lookupBuiltinTool(toolName); ok {
+		args, _ := params["arguments"].(map[string]interface{})
+		return builtin.call(ctx, args, sessionCtx)
+	}
+
+	// 🚦 准入检查：会话并发上限、token 与转发的 headers、网关过载保护（与 /streams 共用）
+	filteredHeaders, release, rejection, err := h.admitToolCall(ctx, toolName, sessionCtx)
+	if err != nil {
+		return nil, err
+	}
+	if rejection != nil {
+		return rejection.result(), nil
+	}
+	defer release()
+
+	// 📋 第三步：处理参数（与 /streams 共用）
+	// 会话 ID 同时写入 ctx，供上游路由等下层组件使用
+	ctx = session.NewContextWithID(ctx, sessionCtx.ID)
+	historyArgs := h.historyArguments(params)
+	ctx, prepared, rejection, err := h.prepareArguments(ctx, toolName, params["arguments"], filteredHeaders, sessionCtx)
+	if err != nil {
+		return nil, err
+	}
+	if rejection != nil {
+		return rejection.result(), nil
+	}
+	argumentsJSON, coercions := prepared.json, prepared.coercions
+
 	h.logger.Debug("Invoking tool",
 		zap.String("toolName", toolName),
-		zap.String("arguments", argumentsJSON),
+		h.logPayload("arguments", argumentsJSON),
 		zap.String("sessionId", sessionCtx.ID))
 
 	// ⏱️ 第四步：为 gRPC 调用设置超时
@@ -514,16 +901,15 @@ func (h *Handler) handleToolsCall(ctx context.Context, params map[string]interfa
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	// 🔒 第五步：过滤 HTTP headers
+	// 🔒 第五步：过滤 HTTP headers（包括 initialize 中提供的默认 metadata）
 	// HeaderFilter 会验证和过滤 headers，防止安全问题
 	// 黑名单过滤：Cookie, Host, Content-Length 等不转发
 	// 白名单过滤：Authorization, X-Trace-Id 等允许转发
-	filteredHeaders := h.headerFilter.FilterHeaders(sessionCtx.Headers)
-
+	// （已在调用开始时与身份 metadata 一起计算，见 forwardedHeaders）
 	h.logger.Debug("Filtered headers for forwarding",
 		zap.String("toolName", toolName),
-		zap.Any("originalHeaders", sessionCtx.Headers),
-		zap.Any("filteredHeaders", filteredHeaders))
+		h.logPayload("originalHeaders", sessionCtx.GetHeaders()),
+		h.logPayload("filteredHeaders", filteredHeaders))
 
 	// 📞 第六步：调用 gRPC 服务
 	// ServiceDiscoverer.InvokeMethodByTool 会：
@@ -532,53 +918,112 @@ func (h *Handler) handleToolsCall(ctx context.Context, params map[string]interfa
 	// 3. 将 headers 转换为 gRPC metadata
 	// 4. 执行 gRPC 调用
 	// 5. 将响应转换回 JSON
-	result, err := h.serviceDiscoverer.InvokeMethodByTool(ctx, filteredHeaders, toolName, argumentsJSON)
+	var result string
+	ctx, span := h.tracer.Start(ctx, telemetry.SpanToolCall, telemetry.Tags{"tool": toolName, "session": sessionCtx.ID})
+	started := time.Now()
+	if compositeTool, ok := h.composites.Lookup(toolName); ok {
+		// 🔗 组合工具：按顺序调用各步骤，每一步（包括补偿调用）同样受会话作用域限制
+		result, err = compositeTool.Execute(ctx, argumentsJSON, func(ctx context.Context, stepTool string, input string) (string, error) {
+			if !sessionCtx.IsToolAllowed(stepTool) {
+				return "", fmt.Errorf("tool %s not found", stepTool)
+			}
+			return h.serviceDiscoverer.InvokeMethodByTool(ctx, filteredHeaders, stepTool, input)
+		})
+	} else {
+		result, err = h.serviceDiscoverer.InvokeMethodByTool(ctx, filteredHeaders, toolName, argumentsJSON)
+	}
+	// 📡 结束 span，发送调用次数和耗时（包括客户端断开的调用）
+	h.emitToolCall(ctx, span, toolName, started, err)
+	// 🧾 写入审计日志（包括客户端断开的调用）
+	h.auditCall(ctx, sessionCtx.ID, toolName, argumentsJSON, started, err)
+	// 🕘 记录到会话历史，客户端恢复会话后可以读取
+	h.recordHistory(ctx, sessionCtx, toolName, historyArgs, result, started, err)
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		// 🔌 客户端已断开：上游调用随请求 context 一起取消，结果无人接收，不计为失败
+		h.clientDisconnects.Add(1)
+		h.logger.Info("Client disconnected during tool call",
+			zap.String("toolName", toolName),
+			zap.String("sessionId", sessionCtx.ID))
+		return nil, fmt.Errorf("tool call %s canceled: %w", toolName, ctx.Err())
+	}
+	// 📈 记录工具使用统计，并检查异常调用模式（客户端断开的调用不计入）
+	h.usage.record(toolName, time.Since(started), err != nil)
+	h.anomalies.Observe(anomaly.Call{
+		Tool:      toolName,
+		SessionID: sessionCtx.ID,
+		Arguments: argumentsJSON,
+		Failed:    err != nil,
+	})
 	if err != nil {
-		// gRPC 调用失败：返回错误结果
+		// gRPC 调用失败：完整错误只记录在日志中，返回给客户端的内容按配置的详细程度处理
+		// 错误 ID 同时出现在日志和结果中，便于根据用户报告查找完整错误
+		errorID := newErrorID()
+		h.logger.Warn("Tool call failed",
+			zap.String("toolName", toolName),
+			zap.String("sessionId", sessionCtx.ID),
+			zap.String("errorId", errorID),
+			zap.Error(err))
+		message := fmt.Sprintf("Error invoking method: %s", mcp.SanitizeErrorWithDetail(err, h.errorDetail))
+		meta := map[string]interface{}{errorIDMetaKey: errorID}
+		// 📏 响应超过接收大小上限：返回可操作的错误，建议如何减少请求的数据
+		if oversize, ok := detectResponseTooLarge(err); ok {
+			h.oversizedResponses.Add(1)
+			message = h.oversizeResult(toolName, oversize)
+			meta[oversizeMetaKey] = oversize
+		}
+		for key, value := range coercionMeta(coercions) {
+			meta[key] = value
+		}
+
+		// 🔁 组合工具步骤失败：列出撤销已完成步骤的补偿调用及其结果
+		var compensated *composite.CompensatedError
+		if errors.As(err, &compensated) {
+			meta[compensationsMetaKey] = compensated.Compensations
+			h.logger.Warn("Composite tool compensated",
+				zap.String("toolName", toolName),
+				zap.String("sessionId", sessionCtx.ID),
+				zap.Any("compensations", compensated.Compensations))
+		}
+
+		// 熔断器打开：告诉客户端等待多久再重试
+		var open *breaker.OpenError
+		if errors.As(err, &open) {
+			meta[retryAfterMetaKey] = retryAfterSeconds(open.RetryAfter)
+			meta[circuitMetaKey] = map[string]interface{}{"scope": open.Scope, "name": open.Name}
+		}
 		return &mcp.ToolCallResult{
 			Content: []mcp.ContentBlock{
-				mcp.TextContent(fmt.Sprintf("Error invoking method: %s", mcp.SanitizeError(err))),
+				mcp.TextContent(withErrorID(message, errorID)),
 			},
 			IsError: true, // 标记为错误
+			Meta:    meta,
 		}, nil
 	}
 
 	// 📊 第七步：更新会话统计信息
-	// 记录此会话的调用次数和最后访问时间（用于限流和监控）
+	// 记录此会话的调用次数和最后访问时间（用于限流和监控），以及工具的调用耗时
+	h.hints.record(toolName, time.Since(started))
 	sessionCtx.IncrementCallCount()
 	sessionCtx.UpdateLastAccessed()
 
 	// 📦 第八步：返回成功结果
-	return &mcp.ToolCallResult{
-		Content: []mcp.ContentBlock{
-			mcp.TextContent(result), // gRPC 响应的 JSON 字符串
-		},
-		IsError: false, // 标记为成功
-	}, nil
-}
-
-// handlePromptsList 处理 prompts/list 请求
-//
-// MCP 协议支持三种资源类型：
-// 1. Tools（已实现）：gRPC 方法
-// 2. Prompts（占位实现）：预定义提示模板
-// 3. Resources（占位实现）：静态或动态资源
-//
-// 当前实现：
-// - 返回空列表，因为该 MCP 网关专注于工具功能
-// - 为了完整的 MCP 兼容性而保留
-// - 可在后续扩展中实现 Prompt 功能
-//
-// 参数：
-//   - ctx: 上下文
-//
-// 返回值：
-//   - 空提示列表
-func (h *Handler) handlePromptsList(ctx context.Context) (interface{}, error) {
-	// 返回空的提示列表（占位实现）
-	return map[string]interface{}{
-		"prompts": []interface{}{},
-	}, nil
+	// gRPC 响应的 JSON 按配置以文本、structuredContent 或两者返回
+	output, outputMeta := h.formatResult(toolName, result)
+	// 📉 超过字节预算的结果以摘要返回，完整结果作为资源链接
+	output, summaryMeta, fullResult := h.summarizeResult(toolName, output, sessionCtx)
+	for _, extra := range []map[string]interface{}{summaryMeta, coercionMeta(coercions)} {
+		for key, value := range extra {
+			if outputMeta == nil {
+				outputMeta = make(map[string]interface{})
+			}
+			outputMeta[key] = value
+		}
+	}
+	callResult := h.composeResult(toolName, output, outputMeta, sessionCtx)
+	if fullResult != "" {
+		callResult.Content = append(callResult.Content, mcp.ResourceLinkContent(fullResult, "full_result", "application/json"))
+	}
+	return callResult, nil
 }
 
 // handleResourcesList 处理 resources/list 请求
@@ -588,20 +1033,36 @@ func (h *Handler) handlePromptsList(ctx context.Context) (interface{}, error) {
 // - 动态资源：数据库记录、API 端点等
 //
 // 当前实现：
-// - 返回空列表，因为该 MCP 网关专注于工具功能
-// - 为了完整的 MCP 兼容性而保留
-// - 可在后续扩展中实现 Resource 功能
+// - 该 MCP 网关专注于工具功能，默认返回空列表
+// - 开启 tools.usage.resource 时提供工具使用统计（ggrmcp://usage/tools）
+// - 开启 session.history 时提供会话的工具调用历史（ggrmcp://session/history）
 //
 // 参数：
 //   - ctx: 上下文
 //
 // 返回值：
-//   - 空资源列表
-func (h *Handler) handleResourcesList(ctx context.Context) (interface{}, error) {
-	// 返回空的资源列表（占位实现）
-	return map[string]interface{}{
-		"resources": []interface{}{},
-	}, nil
+//   - 资源列表
+func (h *Handler) handleResourcesList(ctx context.Context) (*mcp.ResourcesListResult, error) {
+	resources := []mcp.Resource{}
+	if h.usage.resource {
+		resources = append(resources, mcp.Resource{
+			URI:         usageResourceURI,
+			Name:        "tool_usage",
+			Title:       "Tool usage statistics",
+			Description: "Calls, error rate, p50/p95 latency and last call time of each tool",
+			MimeType:    "application/json",
+		})
+	}
+	if h.history.Enabled {
+		resources = append(resources, mcp.Resource{
+			URI:         historyResourceURI,
+			Name:        "session_history",
+			Title:       "Session tool-call history",
+			Description: "The latest tool calls of this session with their arguments and results",
+			MimeType:    "application/json",
+		})
+	}
+	return &mcp.ResourcesListResult{Resources: resources}, nil
 }
 
 // writeJSONResponse 将对象序列化为 JSON 并写入 HTTP 响应
@@ -654,6 +1115,35 @@ func (h *Handler) writeJSONResponse(w http.ResponseWriter, response interface{})
 //   - code: JSON-RPC 错误码
 //   - message: 错误消息
 func (h *Handler) writeErrorResponse(w http.ResponseWriter, id mcp.RequestID, code int, message string) {
+	h.writeErrorResponseWithData(w, id, code, message, nil)
+}
+
+// recoverRequest 恢复处理 JSON-RPC 请求时的 panic：记录请求上下文和调用栈，
+// 计入 panic 指标，并返回 Internal error，使单个异常请求不会导致网关崩溃
+func (h *Handler) recoverRequest(w http.ResponseWriter, r *http.Request, req *mcp.JSONRPCRequest) {
+	p := recover()
+	if p == nil {
+		return
+	}
+	if p == http.ErrAbortHandler {
+		panic(p)
+	}
+	panicCount.Add(1)
+	h.metrics.Count(telemetry.MetricPanics, 1, nil)
+
+	toolName, _ := req.Params["name"].(string)
+	h.logger.Error("Panic while handling request",
+		zap.Any("panic", p),
+		zap.String("method", req.Method),
+		zap.String("toolName", toolName),
+		zap.String("sessionId", r.Header.Get("Mcp-Session-Id")),
+		zap.String("remoteAddr", r.RemoteAddr),
+		zap.ByteString("stack", debug.Stack()))
+	h.writeErrorResponse(w, req.ID, mcp.ErrorCodeInternalError, "Internal error")
+}
+
+// writeErrorResponseWithData 与 writeErrorResponse 相同，额外在 error.data 中携带结构化信息
+func (h *Handler) writeErrorResponseWithData(w http.ResponseWriter, id mcp.RequestID, code int, message string, data interface{}) {
 	// 🚨 构建 JSON-RPC 错误响应
 	response := &mcp.JSONRPCResponse{
 		JSONRPC: "2.0", // JSON-RPC 版本
@@ -661,6 +1151,7 @@ func (h *Handler) writeErrorResponse(w http.ResponseWriter, id mcp.RequestID, co
 		Error: &mcp.RPCError{
 			Code:    code,    // 错误码
 			Message: message, // 错误消息
+			Data:    data,    // 附加数据（例如错误 ID）
 		},
 	}
 
@@ -760,6 +1251,10 @@ func (h *Handler) HealthHandler(w http.ResponseWriter, r *http.Request) {
 		"serviceCount": stats["serviceCount"],
 		"methodCount":  h.serviceDiscoverer.GetMethodCount(),
 	}
+	// ⚠️ 上游 TLS 证书即将过期时附加告警（不影响健康状态）
+	if warnings := h.certificateWarnings(); len(warnings) > 0 {
+		healthInfo["warnings"] = warnings
+	}
 
 	// 💬 返回健康信息
 	if err := json.NewEncoder(w).Encode(healthInfo); err != nil {
@@ -789,8 +1284,25 @@ func (h *Handler) HealthHandler(w http.ResponseWriter, r *http.Request) {
 //   - w: HTTP 响应写入器
 //   - r: HTTP 请求对象
 func (h *Handler) MetricsHandler(w http.ResponseWriter, r *http.Request) {
-	// 📊 获取服务统计信息
+	// 📊 获取服务统计信息，并附加负载保护状态
 	stats := h.serviceDiscoverer.GetServiceStats()
+	if stats == nil {
+		stats = make(map[string]interface{})
+	}
+	stats["backpressure"] = h.loadShedder.stats()
+	stats["panics"] = PanicCount()
+	stats["clientDisconnects"] = h.clientDisconnects.Load()
+	stats["oversizedResponses"] = h.oversizedResponses.Load()
+	stats["legacySSEStreams"] = h.legacy.count()
+	if lockout := h.lockoutStats(); lockout != nil {
+		stats["authLockout"] = lockout
+	}
+	if h.anomalies != nil {
+		stats["anomaly"] = h.anomalies.Stats()
+	}
+	if h.certMonitor != nil {
+		stats["upstreamCertificates"] = h.certMonitor.Certificates()
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -823,3 +1335,10 @@ func (h *Handler) HandleToolsCall(ctx context.Context, params map[string]interfa
 func (h *Handler) GetServiceDiscoverer() grpc.ServiceDiscoverer {
 	return h.serviceDiscoverer
 }
+
+// UpdateHeaderFilter 原子替换 header 转发规则，无需重启即可生效
+//
+// 正在进行的调用继续使用旧规则，之后的调用使用新规则。
+func (h *Handler) UpdateHeaderFilter(cfg config.HeaderForwardingConfig) {
+	h.headerFilter.Update(cfg)
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/aalobaidi/ggRMCP/pkg/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
@@ -42,6 +43,11 @@ func (m *mockServiceDiscoverer) GetMethods() []types.MethodInfo {
 	return args.Get(0).([]types.MethodInfo)
 }
 
+func (m *mockServiceDiscoverer) GetMethod(toolName string) (types.MethodInfo, bool) {
+	args := m.Called(toolName)
+	return args.Get(0).(types.MethodInfo), args.Bool(1)
+}
+
 func (m *mockServiceDiscoverer) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
 	args := m.Called(ctx, headers, toolName, inputJSON)
 	return args.String(0), args.Error(1)
@@ -152,7 +158,7 @@ func TestHandler_HeaderFilteringAndForwarding(t *testing.T) {
 	req.Header.Set("User-Agent", "test-client")        // Should be forwarded
 	req.Header.Set("Cookie", "session=abc123")         // Should be blocked
 	req.Header.Set("X-Random-Header", "random-value")  // Should be blocked (not in allowed list)
-	req.Header.Set("Mcp-Session-Id", "test-session-123")
+	req.Header.Set("Mcp-Session-Id", sessionManager.CreateSession(extractHeaders(req)).ID)
 
 	// Create response recorder
 	w := httptest.NewRecorder()
@@ -232,7 +238,7 @@ func TestHandler_HeaderFilteringDisabled(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer token123")
 	req.Header.Set("X-Trace-ID", "trace-456")
-	req.Header.Set("Mcp-Session-Id", "test-session-123")
+	req.Header.Set("Mcp-Session-Id", sessionManager.CreateSession(extractHeaders(req)).ID)
 
 	// Create response recorder
 	w := httptest.NewRecorder()
@@ -323,8 +329,8 @@ func TestHandler_HeaderFilteringForwardAll(t *testing.T) {
 	req.Header.Set("X-Trace-ID", "trace-456")
 	req.Header.Set("User-Agent", "test-client")
 	req.Header.Set("X-Custom-Header", "custom-value")
-	req.Header.Set("Cookie", "session=abc123")           // Should be blocked
-	req.Header.Set("Mcp-Session-Id", "test-session-123") // Should be filtered out by extractHeaders
+	req.Header.Set("Cookie", "session=abc123")                                             // Should be blocked
+	req.Header.Set("Mcp-Session-Id", sessionManager.CreateSession(extractHeaders(req)).ID) // Should be filtered out by extractHeaders
 
 	// Create response recorder
 	w := httptest.NewRecorder()
@@ -411,7 +417,7 @@ func TestHandler_HeaderFilteringCaseSensitive(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer token123") // Canonicalized header - should be forwarded
 	req.Header.Set("X-Trace-ID", "trace-456")          // Canonicalized to X-Trace-Id - should be forwarded
 	req.Header.Set("X-Custom-Header", "custom-value")  // Not in allowed list - should not be forwarded
-	req.Header.Set("Mcp-Session-Id", "test-session-123")
+	req.Header.Set("Mcp-Session-Id", sessionManager.CreateSession(extractHeaders(req)).ID)
 
 	// Create response recorder
 	w := httptest.NewRecorder()
@@ -425,3 +431,63 @@ func TestHandler_HeaderFilteringCaseSensitive(t *testing.T) {
 	// Verify mock expectations
 	mockDiscoverer.AssertExpectations(t)
 }
+
+func TestHandler_HeaderFilterDryRun(t *testing.T) {
+	handler, _ := newTestHandler(t, &mockServiceDiscoverer{})
+
+	body := `{"headers": {"Authorization": "Bearer secret", "Cookie": "a=b", "X-Custom": "1"}}`
+	req := httptest.NewRequest(http.MethodPost, "/admin/headers/dry-run", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	handler.HeaderFilterDryRunHandler(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "secret", "header values are not echoed")
+
+	var resp headerDryRunResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Enabled)
+	assert.Equal(t, []string{"authorization"}, resp.Forwarded)
+	if assert.Len(t, resp.Decisions, 3) {
+		assert.Equal(t, "Authorization", resp.Decisions[0].Header)
+		assert.Equal(t, `allowed_headers: "authorization"`, resp.Decisions[0].Rule)
+		assert.Equal(t, "Cookie", resp.Decisions[1].Header)
+		assert.Equal(t, `blocked_headers: "cookie"`, resp.Decisions[1].Rule)
+		assert.Equal(t, "X-Custom", resp.Decisions[2].Header)
+		assert.Empty(t, resp.Decisions[2].Rule)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/headers/dry-run", bytes.NewBufferString("not json"))
+	w = httptest.NewRecorder()
+	handler.HeaderFilterDryRunHandler(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandler_UpdateHeaderFilter(t *testing.T) {
+	method := testMethod("echo.service", "Say")
+	var forwarded []map[string]string
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).
+		Run(func(args mock.Arguments) { forwarded = append(forwarded, args.Get(1).(map[string]string)) }).
+		Return(`"ok"`, nil)
+	handler, _ := newTestHandler(t, discoverer)
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, map[string]string{
+		"Authorization": "Bearer t",
+		"X-Trace-Id":    "trace-1",
+	})
+
+	call := func() {
+		postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": method.ToolName}, nil)
+	}
+	call()
+
+	// Tightening the rules applies to the next call without a restart
+	rules := config.Default().GRPC.HeaderForwarding
+	rules.BlockedHeaders = append(rules.BlockedHeaders, "authorization")
+	handler.UpdateHeaderFilter(rules)
+	call()
+
+	require.Len(t, forwarded, 2)
+	assert.Equal(t, "Bearer t", forwarded[0]["Authorization"])
+	assert.NotContains(t, forwarded[1], "Authorization")
+	assert.Equal(t, "trace-1", forwarded[1]["X-Trace-Id"])
+}
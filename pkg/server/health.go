@@ -0,0 +1,37 @@
+package server
+
+import (
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"github.com/aalobaidi/ggRMCP/pkg/health"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+)
+
+const (
+	// toolHealthMetaKey tools/list 中健康状态在 _meta 里的键
+	toolHealthMetaKey = "ggrmcp/health"
+	// notServingPrefix 标记不可用工具的描述前缀
+	notServingPrefix = "[NOT SERVING] "
+)
+
+// markNotServingTools 标记上游服务报告 NOT_SERVING 的工具（健康门控为 mark 模式时）
+//
+// hide 模式下这些工具已被 health.Gate 从方法列表中移除，不会出现在这里。
+// 需在别名生效前按原始名称调用。
+func (h *Handler) markNotServingTools(toolList []mcp.Tool) {
+	gate, ok := grpc.Find[*health.Gate](h.serviceDiscoverer)
+	if !ok {
+		return
+	}
+	for i := range toolList {
+		if gate.IsToolServing(toolList[i].Name) {
+			continue
+		}
+		meta := make(map[string]interface{}, len(toolList[i].Meta)+1)
+		for k, v := range toolList[i].Meta {
+			meta[k] = v
+		}
+		meta[toolHealthMetaKey] = "NOT_SERVING"
+		toolList[i].Meta = meta
+		toolList[i].Description = notServingPrefix + toolList[i].Description
+	}
+}
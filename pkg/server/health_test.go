@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/health"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"github.com/aalobaidi/ggRMCP/pkg/tools"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthReportingDiscoverer adds per-service health to the mock discoverer
+type healthReportingDiscoverer struct {
+	*mockServiceDiscoverer
+	statuses map[string]healthpb.HealthCheckResponse_ServingStatus
+}
+
+func (d *healthReportingDiscoverer) CheckServiceHealth(ctx context.Context, service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+	return d.statuses[service], nil
+}
+
+func TestToolsList_MarksNotServingTools(t *testing.T) {
+	down := testMethod("orders.OrderService", "Get")
+	up := testMethod("users.UserService", "Get")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{down, up})
+	discoverer.On("GetMethod", down.ToolName).Return(down, true)
+	discoverer.On("GetMethod", up.ToolName).Return(up, true)
+
+	gate, err := health.NewGate(&healthReportingDiscoverer{
+		mockServiceDiscoverer: discoverer,
+		statuses: map[string]healthpb.HealthCheckResponse_ServingStatus{
+			"orders.OrderService": healthpb.HealthCheckResponse_NOT_SERVING,
+			"users.UserService":   healthpb.HealthCheckResponse_SERVING,
+		},
+	}, config.HealthGatingConfig{Mode: config.HealthGatingMark, Interval: time.Hour}, zap.NewNop())
+	require.NoError(t, err)
+	gate.Refresh(context.Background())
+
+	logger := zap.NewNop()
+	sessionManager := session.NewManager(logger)
+	t.Cleanup(func() { _ = sessionManager.Close() })
+	handler := NewHandler(logger, gate, sessionManager, tools.NewMCPToolBuilder(logger), config.Default().GRPC.HeaderForwarding)
+
+	listed := listTools(t, handler)
+	require.Len(t, listed, 2)
+	assert.True(t, strings.HasPrefix(listed[down.ToolName]["description"].(string), notServingPrefix))
+	assert.Equal(t, "NOT_SERVING", listed[down.ToolName]["_meta"].(map[string]interface{})[toolHealthMetaKey])
+	assert.NotContains(t, listed[up.ToolName]["_meta"].(map[string]interface{}), toolHealthMetaKey)
+}
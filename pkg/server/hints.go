@@ -0,0 +1,137 @@
+package server
+
+import (
+	"path"
+	"sync"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+)
+
+// toolHintsMetaKey tools/list 中规划提示在 _meta 里的键
+const toolHintsMetaKey = "ggrmcp/hints"
+
+// latencyWeight 观测延迟指数移动平均中新样本的权重
+const latencyWeight = 0.2
+
+// toolHint 单个工具的规划提示
+type toolHint struct {
+	Latency     string `json:"latency,omitempty"`
+	Cost        string `json:"cost,omitempty"`
+	SideEffects string `json:"sideEffects,omitempty"`
+	// ObservedLatencyMs 成功调用耗时的移动平均（样本不足时为空）
+	ObservedLatencyMs *int64 `json:"observedLatencyMs,omitempty"`
+}
+
+// observedLatency 单个工具的调用耗时统计
+type observedLatency struct {
+	samples int
+	average time.Duration
+}
+
+// toolHints 根据配置规则和观测到的调用耗时生成工具的规划提示
+type toolHints struct {
+	config config.ToolHintsConfig
+
+	mu       sync.Mutex
+	observed map[string]*observedLatency
+}
+
+// WithToolHints 设置 tools/list 中的成本、延迟和副作用提示
+func WithToolHints(cfg config.ToolHintsConfig) HandlerOption {
+	return func(h *Handler) {
+		if !cfg.Enabled {
+			h.hints = nil
+			return
+		}
+		h.hints = &toolHints{
+			config:   cfg,
+			observed: make(map[string]*observedLatency),
+		}
+	}
+}
+
+// record 记录一次成功调用的耗时（未开启观测时忽略）
+func (t *toolHints) record(toolName string, elapsed time.Duration) {
+	if t == nil || !t.config.Observed {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats, ok := t.observed[toolName]
+	if !ok {
+		t.observed[toolName] = &observedLatency{samples: 1, average: elapsed}
+		return
+	}
+	stats.samples++
+	stats.average += time.Duration(latencyWeight * float64(elapsed-stats.average))
+}
+
+// hint 按原始工具名称计算提示：每项取第一条设置了该项的规则，
+// 未配置延迟等级时按观测到的平均耗时推断
+func (t *toolHints) hint(toolName string) toolHint {
+	var hint toolHint
+	for _, rule := range t.config.Rules {
+		if matched, err := path.Match(rule.Tool, toolName); err != nil || !matched {
+			continue
+		}
+		if hint.Latency == "" {
+			hint.Latency = rule.Latency
+		}
+		if hint.Cost == "" {
+			hint.Cost = rule.Cost
+		}
+		if hint.SideEffects == "" {
+			hint.SideEffects = rule.SideEffects
+		}
+	}
+
+	t.mu.Lock()
+	stats, ok := t.observed[toolName]
+	ok = ok && stats.samples >= t.config.MinSamples
+	var average time.Duration
+	if ok {
+		average = stats.average
+	}
+	t.mu.Unlock()
+	if !ok {
+		return hint
+	}
+
+	ms := average.Milliseconds()
+	hint.ObservedLatencyMs = &ms
+	if hint.Latency == "" {
+		switch {
+		case average < t.config.FastThreshold:
+			hint.Latency = config.LatencyFast
+		case average > t.config.SlowThreshold:
+			hint.Latency = config.LatencySlow
+		default:
+			hint.Latency = config.LatencyModerate
+		}
+	}
+	return hint
+}
+
+// applyToolHints 将规划提示写入工具的 _meta
+//
+// 必须在别名生效之前调用，规则和观测数据都按原始名称匹配。
+func (h *Handler) applyToolHints(toolList []mcp.Tool) {
+	if h.hints == nil {
+		return
+	}
+	for i := range toolList {
+		hint := h.hints.hint(toolList[i].Name)
+		if hint == (toolHint{}) {
+			continue
+		}
+		meta := make(map[string]interface{}, len(toolList[i].Meta)+1)
+		for k, v := range toolList[i].Meta {
+			meta[k] = v
+		}
+		meta[toolHintsMetaKey] = hint
+		toolList[i].Meta = meta
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolsList_HintsFromRules(t *testing.T) {
+	report := testMethod("acme.reports.v1.ReportService", "Generate")
+	lookup := testMethod("acme.users.v1.UserService", "Get")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{report, lookup})
+	handler, _ := newTestHandler(t, discoverer, WithToolHints(config.ToolHintsConfig{
+		Enabled: true,
+		Rules: []config.ToolHintConfig{
+			{Tool: report.ToolName, Latency: config.LatencySlow, Cost: config.CostHigh},
+			{Tool: "*", Cost: config.CostLow, SideEffects: config.SideEffectsNone},
+		},
+	}))
+
+	tools := listTools(t, handler)
+	assert.Equal(t, map[string]interface{}{
+		"latency":     "slow",
+		"cost":        "high",
+		"sideEffects": "none",
+	}, tools[report.ToolName]["_meta"].(map[string]interface{})[toolHintsMetaKey])
+	assert.Equal(t, map[string]interface{}{
+		"cost":        "low",
+		"sideEffects": "none",
+	}, tools[lookup.ToolName]["_meta"].(map[string]interface{})[toolHintsMetaKey])
+}
+
+func TestToolsList_ObservedLatency(t *testing.T) {
+	method := testMethod("acme.users.v1.UserService", "Get")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{method})
+	discoverer.On("GetMethod", method.ToolName).Return(method, true)
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).Return(`"ok"`, nil)
+	handler, _ := newTestHandler(t, discoverer, WithToolHints(config.ToolHintsConfig{
+		Enabled:       true,
+		Observed:      true,
+		MinSamples:    3,
+		FastThreshold: time.Second,
+		SlowThreshold: time.Minute,
+	}))
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	call := map[string]interface{}{"name": method.ToolName}
+	for i := 0; i < 2; i++ {
+		postRPC(t, handler, "/", sessionID, "tools/call", call, nil)
+	}
+	// Too few samples to derive a class
+	assert.NotContains(t, listTools(t, handler)[method.ToolName]["_meta"], toolHintsMetaKey)
+
+	postRPC(t, handler, "/", sessionID, "tools/call", call, nil)
+	meta, ok := listTools(t, handler)[method.ToolName]["_meta"].(map[string]interface{})
+	require.True(t, ok)
+	hint := meta[toolHintsMetaKey].(map[string]interface{})
+	assert.Equal(t, "fast", hint["latency"])
+	assert.Contains(t, hint, "observedLatencyMs")
+}
+
+func TestToolHints_LatencyClasses(t *testing.T) {
+	hints := &toolHints{
+		config: config.ToolHintsConfig{
+			Observed:      true,
+			MinSamples:    1,
+			FastThreshold: 100 * time.Millisecond,
+			SlowThreshold: time.Second,
+			Rules:         []config.ToolHintConfig{{Tool: "pinned", Latency: config.LatencyFast}},
+		},
+		observed: make(map[string]*observedLatency),
+	}
+	hints.record("moderate", 500*time.Millisecond)
+	hints.record("slow", 3*time.Second)
+	hints.record("pinned", 3*time.Second)
+
+	assert.Equal(t, config.LatencyModerate, hints.hint("moderate").Latency)
+	assert.Equal(t, config.LatencySlow, hints.hint("slow").Latency)
+	// A configured class wins over the observed one
+	assert.Equal(t, config.LatencyFast, hints.hint("pinned").Latency)
+	assert.Equal(t, int64(3000), *hints.hint("pinned").ObservedLatencyMs)
+}
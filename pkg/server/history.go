@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+)
+
+// historyResourceURI 会话工具调用历史的资源 URI（每个会话读到自己的历史）
+const historyResourceURI = "ggrmcp://session/history"
+
+// SessionHistory 会话最近的工具调用，按调用顺序排列
+type SessionHistory struct {
+	SessionID string                 `json:"sessionId"`
+	Calls     []session.HistoryEntry `json:"calls"`
+}
+
+// WithSessionHistory 设置会话工具调用历史（默认不记录）
+func WithSessionHistory(cfg config.SessionHistoryConfig) HandlerOption {
+	return func(h *Handler) {
+		h.history = cfg
+	}
+}
+
+// historyArguments 返回客户端提供的参数，用于记录到历史
+//
+// 在注入会话元数据和配置参数之前调用：注入的值可能来自 Header 等敏感来源，不写入历史。
+func (h *Handler) historyArguments(params map[string]interface{}) json.RawMessage {
+	if !h.history.Enabled || params["arguments"] == nil {
+		return nil
+	}
+	data, err := json.Marshal(params["arguments"])
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// recordHistory 把一次到达上游的工具调用记录到会话历史
+//
+// 失败的调用记录返回给客户端的错误信息；结果超过 max_result_bytes 时截断。
+func (h *Handler) recordHistory(ctx context.Context, sessionCtx *session.Context, toolName string, args json.RawMessage, result string, started time.Time, err error) {
+	if !h.history.Enabled {
+		return
+	}
+	entry := session.HistoryEntry{
+		Tool:       h.aliases.displayName(toolName),
+		Arguments:  args,
+		Time:       started,
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+	switch {
+	case err != nil && errors.Is(ctx.Err(), context.Canceled):
+		entry.Error = "canceled"
+	case err != nil:
+		entry.Error = mcp.SanitizeErrorWithDetail(err, h.errorDetail)
+	default:
+		entry.Result, entry.Truncated = truncateBytes(result, h.history.MaxResultBytes)
+	}
+	sessionCtx.RecordCall(entry, h.history.MaxCalls)
+}
+
+// truncateBytes 把 s 截断到最多 max 字节，不拆开 UTF-8 字符
+func truncateBytes(s string, max int) (string, bool) {
+	if len(s) <= max {
+		return s, false
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut], true
+}
+
+// readHistory 处理对会话历史资源的 resources/read 请求
+func (h *Handler) readHistory(sessionCtx *session.Context) (*mcp.ReadResourceResult, error) {
+	data, err := json.MarshalIndent(SessionHistory{SessionID: sessionCtx.ID, Calls: sessionCtx.History()}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode session history: %w", err)
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []mcp.ResourceContents{{
+			URI:      historyResourceURI,
+			MimeType: "application/json",
+			Text:     string(data),
+		}},
+	}, nil
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// readSessionHistory reads the history resource of the session
+func readSessionHistory(t *testing.T, handler *Handler, sessionID string) SessionHistory {
+	t.Helper()
+	resp, _ := postRPC(t, handler, "/", sessionID, resourcesReadMethod, map[string]interface{}{"uri": historyResourceURI}, nil)
+	require.Nil(t, resp.Error)
+	data, err := json.Marshal(resp.Result)
+	require.NoError(t, err)
+	var result mcp.ReadResourceResult
+	require.NoError(t, json.Unmarshal(data, &result))
+	require.Len(t, result.Contents, 1)
+	var history SessionHistory
+	require.NoError(t, json.Unmarshal([]byte(result.Contents[0].Text), &history))
+	return history
+}
+
+func TestSessionHistory(t *testing.T) {
+	method := testMethod("acme.users.v1.UserService", "Get")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethod", method.ToolName).Return(method, true)
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, `{"id":"1"}`).Return(`{"name":"Ada Lovelace"}`, nil)
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, `{"id":"2"}`).Return("", errors.New("user 2 not found"))
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, `{"id":"3"}`).Return(`{"name":"Grace Hopper"}`, nil)
+	handler, _ := newTestHandler(t, discoverer,
+		WithSessionHistory(config.SessionHistoryConfig{Enabled: true, MaxCalls: 2, MaxResultBytes: 16}))
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	_, otherSession := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	for _, id := range []string{"1", "2", "3"} {
+		postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": method.ToolName, "arguments": map[string]interface{}{"id": id}}, nil)
+	}
+
+	resp, _ := postRPC(t, handler, "/", sessionID, "resources/list", nil, nil)
+	require.Nil(t, resp.Error)
+	resources := resp.Result.(map[string]interface{})["resources"].([]interface{})
+	require.Len(t, resources, 1)
+	assert.Equal(t, historyResourceURI, resources[0].(map[string]interface{})["uri"])
+
+	// Only the latest calls are kept, with errors and cut results
+	history := readSessionHistory(t, handler, sessionID)
+	assert.Equal(t, sessionID, history.SessionID)
+	require.Len(t, history.Calls, 2)
+	assert.Equal(t, method.ToolName, history.Calls[0].Tool)
+	assert.JSONEq(t, `{"id":"2"}`, string(history.Calls[0].Arguments))
+	assert.Contains(t, history.Calls[0].Error, "user 2 not found")
+	assert.Equal(t, `{"name":"Grace H`, history.Calls[1].Result)
+	assert.True(t, history.Calls[1].Truncated)
+
+	// Each session reads its own history
+	assert.Empty(t, readSessionHistory(t, handler, otherSession).Calls)
+}
+
+func TestTruncateBytes(t *testing.T) {
+	cut, truncated := truncateBytes("short", 16)
+	assert.Equal(t, "short", cut)
+	assert.False(t, truncated)
+
+	// Multi-byte characters are not split
+	cut, truncated = truncateBytes("日本語", 4)
+	assert.Equal(t, "日", cut)
+	assert.True(t, truncated)
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/aalobaidi/ggRMCP/pkg/auth"
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"go.uber.org/zap"
+)
+
+// identity 将已验证 token 中的 claim 映射为转发给上游的 gRPC metadata
+//
+// 映射的 header 只能由网关设置：客户端提供的同名 Header、initialize 默认 metadata
+// 和会话元数据都不会被转发，上游因此可以信任这些值。
+type identity struct {
+	verifier *auth.Verifier
+	claims   []config.ClaimHeaderConfig
+	required bool
+	reserved map[string]bool
+
+	// lockout 暴力破解保护（nil 表示不启用）
+	lockout *auth.Lockout
+}
+
+// WithIdentity 设置 token 校验器和 claim 到 metadata 的映射（见 config.AuthConfig）
+func WithIdentity(verifier *auth.Verifier, cfg config.AuthConfig) HandlerOption {
+	return func(h *Handler) {
+		id := &identity{
+			verifier: verifier,
+			claims:   cfg.ClaimHeaders,
+			required: cfg.Required,
+			reserved: make(map[string]bool, len(cfg.ClaimHeaders)),
+		}
+		for _, mapping := range cfg.ClaimHeaders {
+			id.reserved[strings.ToLower(mapping.Header)] = true
+		}
+		if cfg.Lockout.Enabled {
+			id.lockout = auth.NewLockout(cfg.Lockout)
+		}
+		h.identity = id
+	}
+}
+
+// reservesMetadataKey 报告 metadata 键是否由 claim 映射占用
+func (h *Handler) reservesMetadataKey(key string) bool {
+	return h.identity != nil && h.identity.reserved[strings.ToLower(key)]
+}
+
+// forwardedHeaders 返回工具调用转发给上游的 headers
+//
+// 先按 header 过滤规则过滤会话 headers，去掉由 claim 映射占用的 header，
+// 再加入从会话 token 的 claim 得到的值。token 缺失或无效时不转发身份；
+// 配置了 required 时返回错误。
+func (h *Handler) forwardedHeaders(sessionCtx *session.Context) (map[string]string, error) {
+	sessionHeaders := sessionCtx.GetHeaders()
+	forwarded := h.headerFilter.FilterHeaders(sessionHeaders)
+	if h.identity == nil {
+		return forwarded, nil
+	}
+
+	var authorization string
+	for name, value := range sessionHeaders {
+		if strings.EqualFold(name, "authorization") {
+			authorization = value
+			break
+		}
+	}
+	for name := range forwarded {
+		if h.reservesMetadataKey(name) {
+			delete(forwarded, name)
+		}
+	}
+
+	claims, err := h.identity.verifier.VerifyAuthorization(authorization)
+	if err != nil {
+		if h.identity.required {
+			return nil, err
+		}
+		if authorization != "" {
+			h.logger.Debug("Not forwarding identity",
+				zap.String("sessionId", sessionCtx.ID),
+				zap.Error(err))
+		}
+		return forwarded, nil
+	}
+	for name, value := range auth.ClaimHeaders(claims, h.identity.claims) {
+		forwarded[name] = value
+	}
+	return forwarded, nil
+}
+
+// authenticate 在处理任何 MCP 方法之前校验请求，返回 false 表示已写入响应
+//
+// 先检查暴力破解锁定；配置了 required 时请求必须携带有效的 bearer token，
+// 否则返回 HTTP 401。initialize、列表、资源、内置工具和批量请求都经过这里。
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) bool {
+	if !h.checkLockout(w, r) {
+		return false
+	}
+	if h.identity == nil || !h.identity.required {
+		return true
+	}
+	if _, err := h.identity.verifier.VerifyAuthorization(r.Header.Get("Authorization")); err != nil {
+		h.logger.Warn("Rejected request without valid token",
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.Error(err))
+		writeUnauthorized(w)
+		return false
+	}
+	return true
+}
+
+// writeUnauthorized 返回 HTTP 401 和 JSON-RPC 错误，不说明 token 被拒绝的原因
+func writeUnauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="ggrmcp"`)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	_ = json.NewEncoder(w).Encode(&mcp.JSONRPCResponse{
+		JSONRPC: "2.0",
+		Error:   &mcp.RPCError{Code: mcp.ErrorCodeInvalidRequest, Message: "Unauthorized"},
+	})
+}
+
+// checkLockout 校验请求携带的 bearer token，并对认证失败的客户端 IP 和凭据计数
+//
+// 被锁定的 IP 或凭据在锁定期内收到 HTTP 429，不再校验 token；
+// 返回 false 表示已写入响应。没有 Authorization Header 的请求不计数，
+// 是否必须携带 token 由 authenticate 决定。
+func (h *Handler) checkLockout(w http.ResponseWriter, r *http.Request) bool {
+	if h.identity == nil || h.identity.lockout == nil {
+		return true
+	}
+	authorization := r.Header.Get("Authorization")
+	if authorization == "" {
+		return true
+	}
+
+	lockout := h.identity.lockout
+	ipKey := "ip:" + remoteIP(r)
+	sum := sha256.Sum256([]byte(authorization))
+	credentialKey := "credential:" + hex.EncodeToString(sum[:8])
+
+	if wait, locked := lockout.Locked(ipKey, credentialKey); locked {
+		h.logger.Warn("Request rejected by auth lockout",
+			zap.String("client", ipKey),
+			zap.Duration("retryAfter", wait))
+		writeThrottled(w, mcp.RequestID{Value: nil}, http.StatusTooManyRequests, "Too many failed authentication attempts", mcp.RetryInfo{
+			RetryAfterSeconds: int(math.Ceil(wait.Seconds())),
+			Scope:             "auth",
+		})
+		return false
+	}
+
+	if _, err := h.identity.verifier.VerifyAuthorization(authorization); err != nil {
+		if !errors.Is(err, auth.ErrInvalidToken) {
+			return true
+		}
+		if duration := lockout.Failure(ipKey, credentialKey); duration > 0 {
+			h.logger.Warn("Auth lockout started",
+				zap.String("client", ipKey),
+				zap.Duration("duration", duration))
+		}
+		return true
+	}
+	lockout.Success(credentialKey)
+	return true
+}
+
+// lockoutStats 返回暴力破解保护的计数（未启用时为 nil）
+func (h *Handler) lockoutStats() *auth.LockoutStats {
+	if h.identity == nil || h.identity.lockout == nil {
+		return nil
+	}
+	stats := h.identity.lockout.Stats()
+	return &stats
+}
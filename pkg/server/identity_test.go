@@ -0,0 +1,182 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/auth"
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func testToken(t *testing.T, secret string, claims map[string]interface{}) string {
+	t.Helper()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	input := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func identityOption(t *testing.T, required bool, lockout ...config.LockoutConfig) HandlerOption {
+	t.Helper()
+	t.Setenv("TEST_JWT_SECRET", "s3cret")
+	cfg := config.AuthConfig{
+		Enabled:       true,
+		HMACSecretEnv: "TEST_JWT_SECRET",
+		Required:      required,
+		ClaimHeaders: []config.ClaimHeaderConfig{
+			{Claim: "sub", Header: "x-user-id"},
+			{Claim: "org", Header: "x-tenant-id"},
+		},
+	}
+	if len(lockout) > 0 {
+		cfg.Lockout = lockout[0]
+	}
+	verifier, err := auth.NewVerifier(cfg)
+	require.NoError(t, err)
+	return WithIdentity(verifier, cfg)
+}
+
+func TestIdentity_ClaimsReplaceClientHeaders(t *testing.T) {
+	method := testMethod("identity.service", "Echo")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethod", method.ToolName).Return(method, true)
+	var forwarded []map[string]string
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.MatchedBy(func(headers map[string]string) bool {
+		forwarded = append(forwarded, headers)
+		return true
+	}), method.ToolName, mock.Anything).Return(`"ok"`, nil)
+	handler, _ := newTestHandler(t, discoverer, identityOption(t, false))
+
+	token := testToken(t, "s3cret", map[string]interface{}{"sub": "user-1", "org": "acme"})
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, map[string]string{
+		"Authorization": "Bearer " + token,
+		"X-User-Id":     "spoofed",
+	})
+	resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": method.ToolName}, nil)
+	require.Nil(t, resp.Error)
+	assert.Equal(t, `"ok"`, callText(t, resp.Result))
+
+	// Without a valid token the client-supplied identity is still dropped
+	_, sessionID = postRPC(t, handler, "/", "", "initialize", nil, map[string]string{
+		"Authorization": "Bearer " + testToken(t, "wrong", map[string]interface{}{"sub": "user-2"}),
+		"X-User-Id":     "spoofed",
+	})
+	resp, _ = postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": method.ToolName}, nil)
+	require.Nil(t, resp.Error)
+
+	require.Len(t, forwarded, 2)
+	assert.Equal(t, "user-1", forwarded[0]["x-user-id"])
+	assert.Equal(t, "acme", forwarded[0]["x-tenant-id"])
+	assert.NotContains(t, forwarded[0], "X-User-Id")
+	assert.NotContains(t, forwarded[1], "x-user-id")
+	assert.NotContains(t, forwarded[1], "X-User-Id")
+
+	// Session metadata cannot set an identity key either
+	resp, _ = postRPC(t, handler, "/", sessionID, sessionSetMetadataMethod, map[string]interface{}{
+		"metadata": map[string]interface{}{"x-tenant-id": "other"},
+	}, nil)
+	require.NotNil(t, resp.Error)
+}
+
+func TestIdentity_RequiredToken(t *testing.T) {
+	method := testMethod("identity.service", "Echo")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethod", method.ToolName).Return(method, true)
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).Return(`"ok"`, nil)
+	handler, _ := newTestHandler(t, discoverer, identityOption(t, true), WithIntrospectionTools(true),
+		WithBatch(config.BatchConfig{Enabled: true, MaxRequests: 8, MaxConcurrency: 1}))
+
+	send := func(target string, body string, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// Every method, including builtins and batches, needs a valid token
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize"}`,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + gatewayStatusToolName + `"}}`,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + method.ToolName + `"}}`,
+		`[{"jsonrpc":"2.0","id":1,"method":"tools/list"}]`,
+	} {
+		for _, authorization := range []string{"", "Bearer " + testToken(t, "wrong", map[string]interface{}{"sub": "user-1"})} {
+			w := send("/", body, authorization)
+			assert.Equal(t, http.StatusUnauthorized, w.Code, body)
+			assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+			assert.NotContains(t, w.Body.String(), "upstream", body)
+		}
+	}
+	w := httptest.NewRecorder()
+	handler.LegacySSEHandler(w, httptest.NewRequest(http.MethodGet, "/sse", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	discoverer.AssertNotCalled(t, "InvokeMethodByTool", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	// With a valid token the request is handled
+	w = send("/", `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"`+method.ToolName+`"}}`,
+		"Bearer "+testToken(t, "s3cret", map[string]interface{}{"sub": "user-1"}))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp mcp.JSONRPCResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Nil(t, resp.Error)
+	assert.Equal(t, `"ok"`, callText(t, resp.Result))
+}
+
+func TestIdentity_LockoutAfterFailures(t *testing.T) {
+	handler, _ := newTestHandler(t, &mockServiceDiscoverer{}, identityOption(t, false, config.LockoutConfig{
+		Enabled:     true,
+		MaxFailures: 3,
+		Window:      time.Minute,
+		Duration:    time.Minute,
+		MaxDuration: time.Hour,
+	}))
+
+	bad := map[string]string{"Authorization": "Bearer " + testToken(t, "guess", map[string]interface{}{"sub": "admin"})}
+	for i := 0; i < 3; i++ {
+		resp, _ := postRPC(t, handler, "/", "", "initialize", nil, bad)
+		require.Nil(t, resp.Error)
+	}
+
+	// The client IP is locked out, even with a valid token
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`))
+	req.Header.Set("Authorization", "Bearer "+testToken(t, "s3cret", map[string]interface{}{"sub": "user-1"}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	var body struct {
+		Error struct {
+			Code int           `json:"code"`
+			Data mcp.RetryInfo `json:"data"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, mcp.ErrorCodeRateLimited, body.Error.Code)
+	assert.Equal(t, "auth", body.Error.Data.Scope)
+
+	stats := handler.lockoutStats()
+	require.NotNil(t, stats)
+	assert.Equal(t, int64(3), stats.Failures)
+	// Both the client IP and the guessed credential are locked out
+	assert.Equal(t, int64(2), stats.Lockouts)
+	assert.Equal(t, int64(1), stats.Rejected)
+}
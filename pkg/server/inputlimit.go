@@ -0,0 +1,26 @@
+package server
+
+import (
+	"path"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+)
+
+// WithToolInputLimits 设置按工具限制的调用参数大小（按顺序匹配，第一条生效）
+func WithToolInputLimits(limits []config.ToolInputLimitConfig) HandlerOption {
+	return func(h *Handler) {
+		h.inputLimits = limits
+	}
+}
+
+// inputLimit 返回工具调用参数的字节上限（0 表示不限制）
+//
+// 按客户端调用的名称（已解析别名）匹配，组合工具按组合工具自身的名称匹配。
+func (h *Handler) inputLimit(toolName string) int {
+	for _, limit := range h.inputLimits {
+		if matched, err := path.Match(limit.Tool, toolName); err == nil && matched {
+			return limit.MaxBytes
+		}
+	}
+	return 0
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolsCall_InputLimit(t *testing.T) {
+	upload := testMethod("files.service", "Upload")
+	echo := testMethod("files.service", "Echo")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethod", upload.ToolName).Return(upload, true)
+	discoverer.On("GetMethod", echo.ToolName).Return(echo, true)
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(`"ok"`, nil)
+	handler, _ := newTestHandler(t, discoverer, WithToolInputLimits([]config.ToolInputLimitConfig{
+		{Tool: upload.ToolName, MaxBytes: 64},
+		{Tool: "files_*", MaxBytes: 1024},
+	}))
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	call := func(toolName string, size int) mcp.ToolCallResult {
+		resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{
+			"name":      toolName,
+			"arguments": map[string]interface{}{"value": strings.Repeat("x", size)},
+		}, nil)
+		require.Nil(t, resp.Error)
+		data, err := json.Marshal(resp.Result)
+		require.NoError(t, err)
+		var result mcp.ToolCallResult
+		require.NoError(t, json.Unmarshal(data, &result))
+		return result
+	}
+
+	assert.False(t, call(upload.ToolName, 10).IsError)
+
+	// The first matching rule wins, so Upload is capped at 64 bytes
+	result := call(upload.ToolName, 100)
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Content[0].Text, "exceeds the 64 byte limit")
+	assert.False(t, call(echo.ToolName, 100).IsError)
+
+	discoverer.AssertNumberOfCalls(t, "InvokeMethodByTool", 2)
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"github.com/aalobaidi/ggRMCP/pkg/health"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+)
+
+const (
+	// listServicesToolName 内置的服务列表工具的名称
+	listServicesToolName = "ggrmcp_list_services"
+	// gatewayStatusToolName 内置的网关状态工具的名称
+	gatewayStatusToolName = "ggrmcp_gateway_status"
+	// statusHealthTimeout 网关状态工具检查上游连接的超时时间
+	statusHealthTimeout = 5 * time.Second
+)
+
+// WithIntrospectionTools 设置是否注册内置的 ggrmcp_list_services 与
+// ggrmcp_gateway_status 工具，网关按 tools.introspection.enabled 设置（默认启用）
+func WithIntrospectionTools(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.introspectionTools = enabled
+	}
+}
+
+// emptyInputSchema 没有参数的工具的 input schema
+func emptyInputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+// listServicesToolDefinition 返回服务列表工具的定义
+func listServicesToolDefinition() mcp.Tool {
+	return mcp.Tool{
+		Name:  listServicesToolName,
+		Title: "List services",
+		Description: "Lists the gRPC services behind the gateway with their tools, " +
+			"services reported as not serving and the services or methods the last discovery " +
+			"left out of the catalog. Use it to find out why a tool is missing.",
+		InputSchema: emptyInputSchema(),
+	}
+}
+
+// gatewayStatusToolDefinition 返回网关状态工具的定义
+func gatewayStatusToolDefinition() mcp.Tool {
+	return mcp.Tool{
+		Name:  gatewayStatusToolName,
+		Title: "Gateway status",
+		Description: "Reports the gateway version, the upstream it is connected to, " +
+			"the upstream connection health and a summary of the last service discovery.",
+		InputSchema: emptyInputSchema(),
+	}
+}
+
+// serviceListing 服务列表中的单个服务
+type serviceListing struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Status      string   `json:"status,omitempty"`
+	Tools       []string `json:"tools"`
+}
+
+// servicesResult 服务列表工具的返回结果
+type servicesResult struct {
+	Services []serviceListing `json:"services"`
+	// ToolsOutsideScope: 会话作用域之外的工具数量
+	ToolsOutsideScope int `json:"toolsOutsideScope,omitempty"`
+	// NotServing: 报告 NOT_SERVING 的服务（健康门控为 hide 模式时其工具不在列表中）
+	NotServing []string `json:"notServing,omitempty"`
+	// Failures: 最近一次发现中未能加入工具目录的服务和方法
+	Failures []types.DiscoveryFailure `json:"discoveryFailures,omitempty"`
+}
+
+// listServicesCall 处理对服务列表工具的调用
+//
+// 按服务列出会话可见的工具（使用别名），并说明工具可能缺失的原因：
+// 会话作用域、上游健康状态和发现失败
+func (h *Handler) listServicesCall(ctx context.Context, args map[string]interface{}, sessionCtx *session.Context) (*mcp.ToolCallResult, error) {
+	var result servicesResult
+	gate, hasGate := grpc.Find[*health.Gate](h.serviceDiscoverer)
+
+	byService := make(map[string]*serviceListing)
+	for _, method := range h.serviceDiscoverer.GetMethods() {
+		if !sessionCtx.IsToolAllowed(method.ToolName) {
+			result.ToolsOutsideScope++
+			continue
+		}
+		listing, ok := byService[method.ServiceName]
+		if !ok {
+			listing = &serviceListing{Name: method.ServiceName, Description: method.ServiceDescription}
+			if hasGate && !gate.IsServing(method.ServiceName) {
+				listing.Status = "NOT_SERVING"
+			}
+			byService[method.ServiceName] = listing
+		}
+		listing.Tools = append(listing.Tools, h.aliases.displayName(method.ToolName))
+	}
+
+	result.Services = make([]serviceListing, 0, len(byService))
+	for _, listing := range byService {
+		sort.Strings(listing.Tools)
+		result.Services = append(result.Services, *listing)
+	}
+	sort.Slice(result.Services, func(i, j int) bool { return result.Services[i].Name < result.Services[j].Name })
+
+	if hasGate {
+		result.NotServing = gate.NotServing()
+	}
+	if reporter, ok := grpc.Find[grpc.DiscoveryReporter](h.serviceDiscoverer); ok {
+		if report := reporter.DiscoveryReport(); report != nil {
+			result.Failures = report.Failures
+		}
+	}
+	return h.builtinResult(result, sessionCtx)
+}
+
+// gatewayStatus 网关状态工具的返回结果
+type gatewayStatus struct {
+	Version      string      `json:"version"`
+	Status       string      `json:"status"`
+	HealthError  string      `json:"healthError,omitempty"`
+	Upstream     interface{} `json:"upstream,omitempty"`
+	Connected    interface{} `json:"connected,omitempty"`
+	ServiceCount interface{} `json:"serviceCount"`
+	MethodCount  int         `json:"methodCount"`
+	// Discovery: 最近一次服务发现的摘要（发现方式、完成时间、失败数量）
+	Discovery map[string]interface{} `json:"discovery,omitempty"`
+}
+
+// gatewayStatusCall 处理对网关状态工具的调用
+//
+// 与 /health 使用相同的检查：上游连接健康且已发现方法时为 healthy
+func (h *Handler) gatewayStatusCall(ctx context.Context, args map[string]interface{}, sessionCtx *session.Context) (*mcp.ToolCallResult, error) {
+	stats := h.serviceDiscoverer.GetServiceStats()
+	status := gatewayStatus{
+		Version:      h.version,
+		Status:       "healthy",
+		Upstream:     stats["upstream"],
+		Connected:    stats["isConnected"],
+		ServiceCount: stats["serviceCount"],
+		MethodCount:  h.serviceDiscoverer.GetMethodCount(),
+	}
+
+	healthCtx, cancel := context.WithTimeout(ctx, statusHealthTimeout)
+	defer cancel()
+	if err := h.serviceDiscoverer.HealthCheck(healthCtx); err != nil {
+		status.Status = "unhealthy"
+		status.HealthError = mcp.SanitizeErrorWithDetail(err, h.errorDetail)
+	} else if status.MethodCount == 0 {
+		status.Status = "unhealthy"
+		status.HealthError = "no services available"
+	}
+
+	if reporter, ok := grpc.Find[grpc.DiscoveryReporter](h.serviceDiscoverer); ok {
+		if report := reporter.DiscoveryReport(); report != nil {
+			status.Discovery = map[string]interface{}{
+				"source":       report.Source,
+				"completedAt":  report.CompletedAt,
+				"duration":     report.Duration,
+				"failureCount": len(report.Failures),
+			}
+			if report.FallbackReason != "" {
+				status.Discovery["fallbackReason"] = report.FallbackReason
+			}
+		}
+	}
+	return h.builtinResult(status, sessionCtx)
+}
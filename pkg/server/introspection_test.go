@@ -0,0 +1,123 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"github.com/aalobaidi/ggRMCP/pkg/tools"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// reportingDiscoverer adds a discovery report to the mock discoverer
+type reportingDiscoverer struct {
+	*mockServiceDiscoverer
+	report *grpc.DiscoveryReport
+}
+
+func (d *reportingDiscoverer) DiscoveryReport() *grpc.DiscoveryReport {
+	return d.report
+}
+
+// callBuiltin calls a built-in tool and decodes its JSON result
+func callBuiltin(t *testing.T, handler *Handler, sessionID, name string) map[string]interface{} {
+	t.Helper()
+	resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": name}, nil)
+	require.Nil(t, resp.Error)
+	data, err := json.Marshal(resp.Result)
+	require.NoError(t, err)
+	var result mcp.ToolCallResult
+	require.NoError(t, json.Unmarshal(data, &result))
+	require.False(t, result.IsError)
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].Text), &decoded))
+	return decoded
+}
+
+func TestIntrospectionTools(t *testing.T) {
+	getOrder := testMethod("orders.OrderService", "Get")
+	listOrders := testMethod("orders.OrderService", "List")
+	getUser := testMethod("users.UserService", "Get")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{listOrders, getUser, getOrder})
+	discoverer.On("GetMethodCount").Return(3)
+	discoverer.On("GetServiceStats").Return(map[string]interface{}{
+		"serviceCount": 2,
+		"isConnected":  true,
+		"upstream":     "orders:50051",
+	})
+	health := discoverer.On("HealthCheck", mock.Anything).Return(nil)
+	reporter := &reportingDiscoverer{
+		mockServiceDiscoverer: discoverer,
+		report: &grpc.DiscoveryReport{
+			Source:       "reflection",
+			ServiceCount: 2,
+			MethodCount:  3,
+			Failures:     []types.DiscoveryFailure{{Service: "billing.BillingService", Error: "unresolved message type billing.Invoice"}},
+		},
+	}
+	logger := zap.NewNop()
+	sessionManager := session.NewManager(logger)
+	t.Cleanup(func() { _ = sessionManager.Close() })
+	handler := NewHandler(logger, reporter, sessionManager, tools.NewMCPToolBuilder(logger), config.Default().GRPC.HeaderForwarding,
+		WithIntrospectionTools(true), WithVersion("2.3.0"))
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	resp, _ := postRPC(t, handler, "/", sessionID, "tools/list", nil, nil)
+	require.Nil(t, resp.Error)
+	names := toolNames(t, resp.Result)
+	assert.Contains(t, names, listServicesToolName)
+	assert.Contains(t, names, gatewayStatusToolName)
+
+	services := callBuiltin(t, handler, sessionID, listServicesToolName)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"name":  "orders.OrderService",
+			"tools": []interface{}{getOrder.ToolName, listOrders.ToolName},
+		},
+		map[string]interface{}{
+			"name":  "users.UserService",
+			"tools": []interface{}{getUser.ToolName},
+		},
+	}, services["services"])
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"service": "billing.BillingService",
+		"error":   "unresolved message type billing.Invoice",
+	}}, services["discoveryFailures"])
+
+	status := callBuiltin(t, handler, sessionID, gatewayStatusToolName)
+	assert.Equal(t, "2.3.0", status["version"])
+	assert.Equal(t, "healthy", status["status"])
+	assert.Equal(t, "orders:50051", status["upstream"])
+	assert.Equal(t, float64(3), status["methodCount"])
+	assert.Equal(t, "reflection", status["discovery"].(map[string]interface{})["source"])
+	assert.Equal(t, float64(1), status["discovery"].(map[string]interface{})["failureCount"])
+
+	health.Unset()
+	discoverer.On("HealthCheck", mock.Anything).Return(errors.New("connection refused"))
+	status = callBuiltin(t, handler, sessionID, gatewayStatusToolName)
+	assert.Equal(t, "unhealthy", status["status"])
+	assert.NotEmpty(t, status["healthError"])
+
+	discoverer.AssertNotCalled(t, "InvokeMethodByTool", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	// Builtins are subject to the session's tool scope like other tools
+	_, scopedID := postRPC(t, handler, "/?tools=orders_*", "", "initialize", nil, nil)
+	resp, _ = postRPC(t, handler, "/", scopedID, "tools/list", nil, nil)
+	require.Nil(t, resp.Error)
+	assert.NotContains(t, toolNames(t, resp.Result), gatewayStatusToolName)
+	resp, _ = postRPC(t, handler, "/", scopedID, "tools/call", map[string]interface{}{"name": gatewayStatusToolName}, nil)
+	require.Nil(t, resp.Error)
+	result := resp.Result.(map[string]interface{})
+	assert.Equal(t, true, result["isError"])
+	assert.NotContains(t, result["content"].([]interface{})[0].(map[string]interface{})["text"], "orders:50051")
+}
@@ -0,0 +1,263 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 2024-11-05 HTTP+SSE 传输的端点：客户端 GET /sse 打开事件流，
+// 按 endpoint 事件给出的地址 POST /messages 发送请求，响应通过事件流返回
+const (
+	legacySSEPath      = "/sse"
+	legacyMessagesPath = "/messages"
+)
+
+// legacyQueueLimit 每个 legacy SSE 会话排队等待发送的消息上限
+const legacyQueueLimit = 64
+
+var (
+	// errEventQueueFull 客户端读取事件流过慢，排队的消息达到 legacyQueueLimit
+	errEventQueueFull = errors.New("event queue is full")
+	// errEventQueueClosed 事件流已关闭
+	errEventQueueClosed = errors.New("event stream is closed")
+)
+
+// eventQueue 一个 legacy SSE 会话待发送的消息队列
+//
+// 与 GET 事件通道不同，响应不能丢弃：队列满时 push 返回错误，由 POST 请求告知客户端。
+type eventQueue struct {
+	mu       sync.Mutex
+	messages [][]byte
+	closed   bool
+	// ready 有新消息时收到信号（容量为 1，多次 push 合并为一次）
+	ready chan struct{}
+}
+
+// newEventQueue 创建空队列
+func newEventQueue() *eventQueue {
+	return &eventQueue{ready: make(chan struct{}, 1)}
+}
+
+// push 追加一条消息
+func (q *eventQueue) push(data []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return errEventQueueClosed
+	}
+	if len(q.messages) >= legacyQueueLimit {
+		return errEventQueueFull
+	}
+	q.messages = append(q.messages, data)
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// drain 取出所有待发送的消息
+func (q *eventQueue) drain() [][]byte {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	messages := q.messages
+	q.messages = nil
+	return messages
+}
+
+// close 关闭队列，之后的 push 返回 errEventQueueClosed
+func (q *eventQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.messages = nil
+}
+
+// legacySessions 记录每个 legacy SSE 会话的消息队列
+type legacySessions struct {
+	mu     sync.Mutex
+	queues map[string]*eventQueue
+}
+
+// newLegacySessions 创建空的会话表
+func newLegacySessions() *legacySessions {
+	return &legacySessions{queues: make(map[string]*eventQueue)}
+}
+
+// open 为会话创建消息队列，返回的函数用于关闭它
+func (s *legacySessions) open(sessionID string) (*eventQueue, func()) {
+	queue := newEventQueue()
+	s.mu.Lock()
+	s.queues[sessionID] = queue
+	s.mu.Unlock()
+	return queue, func() {
+		s.mu.Lock()
+		delete(s.queues, sessionID)
+		s.mu.Unlock()
+		queue.close()
+	}
+}
+
+// get 返回会话的消息队列，会话没有打开事件流时返回 nil
+func (s *legacySessions) get(sessionID string) *eventQueue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queues[sessionID]
+}
+
+// count 返回打开的 legacy 事件流数量
+func (s *legacySessions) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queues)
+}
+
+// queuedResponse 缓冲 handlePost 写出的响应，之后作为 SSE 事件发送
+type queuedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *queuedResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *queuedResponse) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(data)
+}
+
+func (r *queuedResponse) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+// LegacySSEHandler 处理 GET /sse：为 2024-11-05 HTTP+SSE 传输的客户端创建会话并打开事件流
+//
+// 第一条事件是 endpoint，给出发送请求的地址（/messages?sessionId=...）；
+// 之后的 message 事件依次是请求的响应和服务端主动发起的通知。
+// 会话随事件流存在：客户端断开后会话被删除。
+func (h *Handler) LegacySSEHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.authenticate(w, r) {
+		return
+	}
+
+	sessionCtx := h.sessionManager.CreateSession(extractHeaders(r))
+	h.applyToolScope(r, sessionCtx)
+	h.applyMetadataHeaders(r, sessionCtx)
+
+	queue, closeQueue := h.legacy.open(sessionCtx.ID)
+	notifications, unsubscribe := h.channels.subscribe(sessionCtx.ID)
+	defer func() {
+		unsubscribe()
+		closeQueue()
+		if h.streams != nil {
+			h.streams.closeSession(sessionCtx.ID)
+		}
+		if _, ok := h.sessionManager.GetSession(sessionCtx.ID); ok {
+			h.sessionManager.DeleteSession(sessionCtx.ID)
+		}
+	}()
+
+	controller := http.NewResponseController(w)
+	// 事件流长时间保持打开，不受服务器写超时限制
+	_ = controller.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", eventStreamType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	endpoint := legacyMessagesPath + "?sessionId=" + url.QueryEscape(sessionCtx.ID)
+	if _, err := fmt.Fprintf(w, "event: endpoint\ndata: %s\n\n", endpoint); err != nil {
+		return
+	}
+	if err := controller.Flush(); err != nil {
+		return
+	}
+	h.logger.Info("Legacy SSE stream opened", zap.String("sessionId", sessionCtx.ID))
+
+	ping := time.NewTicker(eventChannelPing)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case data, ok := <-notifications:
+			if !ok {
+				// 会话已终止
+				return
+			}
+			_, err = fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+		case <-queue.ready:
+			for _, data := range queue.drain() {
+				if _, err = fmt.Fprintf(w, "event: message\ndata: %s\n\n", data); err != nil {
+					break
+				}
+			}
+		case <-ping.C:
+			// 事件流打开期间会话不过期
+			h.sessionManager.UpdateSession(sessionCtx.ID, sessionCtx)
+			_, err = fmt.Fprint(w, ": ping\n\n")
+		}
+		if err == nil {
+			err = controller.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// LegacyMessagesHandler 处理 POST /messages?sessionId=...：按 Streamable HTTP 的 POST 处理请求，
+// 响应放入会话的事件流并返回 202 Accepted
+//
+// 会话没有打开的事件流时返回 404；事件流读取过慢、排队的响应过多时返回 503。
+func (h *Handler) LegacyMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.authenticate(w, r) {
+		return
+	}
+
+	sessionID := r.URL.Query().Get("sessionId")
+	if sessionID == "" {
+		http.Error(w, "sessionId query parameter is required", http.StatusBadRequest)
+		return
+	}
+	queue := h.legacy.get(sessionID)
+	if queue == nil {
+		http.Error(w, "Session not found, open a new event stream", http.StatusNotFound)
+		return
+	}
+
+	req := r.Clone(r.Context())
+	req.Header.Set("Mcp-Session-Id", sessionID)
+	req.Header.Set("Accept", "application/json")
+	response := &queuedResponse{header: make(http.Header)}
+	h.handlePost(response, req)
+
+	// 通知没有响应
+	if data := bytes.TrimSpace(response.body.Bytes()); len(data) > 0 {
+		if err := queue.push(data); err != nil {
+			h.logger.Warn("Failed to queue legacy SSE response",
+				zap.String("sessionId", sessionID),
+				zap.Error(err))
+			if errors.Is(err, errEventQueueClosed) {
+				http.Error(w, "Session not found, open a new event stream", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Event stream is not being read", http.StatusServiceUnavailable)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
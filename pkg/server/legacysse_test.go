@@ -0,0 +1,103 @@
+package server
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readEvent returns the next event name and data from an SSE stream
+func readEvent(t *testing.T, scanner *bufio.Scanner) (string, string) {
+	t.Helper()
+	var event, data string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "" && data != "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	require.NoError(t, scanner.Err())
+	t.Fatal("event stream closed")
+	return "", ""
+}
+
+func TestLegacySSETransport(t *testing.T) {
+	handler, sessionManager := newTestHandler(t, &mockServiceDiscoverer{})
+	mux := http.NewServeMux()
+	mux.HandleFunc(legacySSEPath, handler.LegacySSEHandler)
+	mux.HandleFunc(legacyMessagesPath, handler.LegacyMessagesHandler)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	post := func(endpoint, body string) *http.Response {
+		resp, err := http.Post(server.URL+endpoint, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp
+	}
+	assert.Equal(t, http.StatusBadRequest, post(legacyMessagesPath, `{}`).StatusCode)
+	assert.Equal(t, http.StatusNotFound, post(legacyMessagesPath+"?sessionId=expired", `{}`).StatusCode)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+legacySSEPath, nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "text/event-stream")
+	stream, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, stream.StatusCode)
+	assert.Equal(t, "text/event-stream", stream.Header.Get("Content-Type"))
+	scanner := bufio.NewScanner(stream.Body)
+
+	// The first event tells the client where to post its requests
+	event, endpoint := readEvent(t, scanner)
+	require.Equal(t, "endpoint", event)
+	sessionID := strings.TrimPrefix(endpoint, legacyMessagesPath+"?sessionId=")
+	_, ok := sessionManager.GetSession(sessionID)
+	require.True(t, ok)
+
+	// Responses arrive on the stream, notifications have none
+	resp := post(endpoint, `{"jsonrpc":"2.0","id":7,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, http.StatusAccepted, post(endpoint, `{"jsonrpc":"2.0","method":"notifications/initialized"}`).StatusCode)
+	event, data := readEvent(t, scanner)
+	assert.Equal(t, "message", event)
+	assert.Contains(t, data, `"id":7`)
+	assert.Contains(t, data, `"protocolVersion":"2024-11-05"`)
+
+	// Server-initiated notifications share the stream
+	handler.notify(mcp.ToolsListChangedNotificationMethod, nil)
+	_, data = readEvent(t, scanner)
+	assert.Contains(t, data, mcp.ToolsListChangedNotificationMethod)
+
+	// Closing the stream ends the session
+	_ = stream.Body.Close()
+	assert.Eventually(t, func() bool {
+		_, ok := sessionManager.GetSession(sessionID)
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, http.StatusNotFound, post(endpoint, `{"jsonrpc":"2.0","id":8,"method":"initialize"}`).StatusCode)
+}
+
+func TestEventQueue(t *testing.T) {
+	queue := newEventQueue()
+	for i := 0; i < legacyQueueLimit; i++ {
+		require.NoError(t, queue.push([]byte("{}")))
+	}
+	assert.ErrorIs(t, queue.push([]byte("{}")), errEventQueueFull)
+	assert.Len(t, queue.drain(), legacyQueueLimit)
+	assert.NoError(t, queue.push([]byte("{}")))
+
+	queue.close()
+	assert.ErrorIs(t, queue.push([]byte("{}")), errEventQueueClosed)
+}
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// sessionSetMetadataMethod 设置会话元数据的 JSON-RPC 方法
+	sessionSetMetadataMethod = "session/setMetadata"
+	// sessionGetMetadataMethod 查询会话元数据的 JSON-RPC 方法
+	sessionGetMetadataMethod = "session/getMetadata"
+)
+
+// initializeMetadataKey 是 initialize 请求 _meta 中默认 gRPC metadata 的键
+const initializeMetadataKey = "ggrmcp/metadata"
+
+// SessionMetadataResult session/setMetadata 与 session/getMetadata 的返回结果
+type SessionMetadataResult struct {
+	Metadata map[string]string `json:"metadata"`
+}
+
+// normalizeMetadataKey 将元数据键规范化为合法的 gRPC metadata 键
+//
+// 键统一转为小写，只允许字母、数字、'-'、'_' 和 '.'；
+// 以 "grpc-" 开头的保留键、被 header 黑名单拦截的键，以及注入 gRPC metadata 时
+// 加上前缀后不被 header 过滤规则转发的键会被拒绝。
+func (h *Handler) normalizeMetadataKey(key string) (string, error) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if key == "" {
+		return "", fmt.Errorf("invalid metadata key: empty")
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return "", fmt.Errorf("invalid metadata key %q", key)
+		}
+	}
+	if strings.HasPrefix(key, "grpc-") {
+		return "", fmt.Errorf("invalid metadata key %q: reserved prefix", key)
+	}
+	if h.headerFilter.IsDropped(key) {
+		return "", fmt.Errorf("invalid metadata key %q: blocked", key)
+	}
+	// 作为 gRPC metadata 发送的键（含前缀）与请求 Header 一样必须通过 header 过滤规则
+	if h.sessionMetadata.InjectMetadata && !h.headerFilter.ShouldForward(h.sessionMetadata.MetadataKeyPrefix+key) {
+		return "", fmt.Errorf("invalid metadata key %q: not forwarded by the header filter", key)
+	}
+	if h.reservesMetadataKey(h.sessionMetadata.MetadataKeyPrefix + key) {
+		return "", fmt.Errorf("invalid metadata key %q: reserved for identity", key)
+	}
+	return key, nil
+}
+
+// mergeSessionMetadata 校验并合并元数据到会话中，空值表示删除对应键
+func (h *Handler) mergeSessionMetadata(sessionCtx *session.Context, entries map[string]string) error {
+	normalized := make(map[string]string, len(entries))
+	for key, value := range entries {
+		key, err := h.normalizeMetadataKey(key)
+		if err != nil {
+			return err
+		}
+		if len(value) > h.sessionMetadata.MaxValueLength {
+			return fmt.Errorf("invalid metadata value for %q: exceeds %d bytes", key, h.sessionMetadata.MaxValueLength)
+		}
+		normalized[key] = value
+	}
+
+	// 合并后的条目数不能超过上限（在会话锁内检查，并发的合并不会绕过上限）
+	if !sessionCtx.MergeMetadata(normalized, h.sessionMetadata.MaxEntries) {
+		return fmt.Errorf("invalid metadata: more than %d entries", h.sessionMetadata.MaxEntries)
+	}
+	return nil
+}
+
+// applyMetadataHeaders 将带有指定前缀的请求 Header（如 Mcp-Meta-Tenant-Id）合并到会话元数据
+func (h *Handler) applyMetadataHeaders(r *http.Request, sessionCtx *session.Context) {
+	if !h.sessionMetadata.Enabled || h.sessionMetadata.HeaderPrefix == "" {
+		return
+	}
+
+	prefix := strings.ToLower(h.sessionMetadata.HeaderPrefix)
+	entries := make(map[string]string)
+	for name, values := range r.Header {
+		lower := strings.ToLower(name)
+		if !strings.HasPrefix(lower, prefix) || len(values) == 0 {
+			continue
+		}
+		entries[strings.TrimPrefix(lower, prefix)] = values[0]
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	if err := h.mergeSessionMetadata(sessionCtx, entries); err != nil {
+		h.logger.Warn("Ignoring session metadata headers",
+			zap.String("sessionId", sessionCtx.ID),
+			zap.Error(err))
+	}
+}
+
+// applyInitializeMetadata 保存 initialize 请求中客户端提供的默认 gRPC metadata
+//
+// 请求参数示例：
+//
+//	{"protocolVersion": "2024-11-05", "_meta": {"ggrmcp/metadata": {"x-workspace-id": "ws-42"}}}
+//
+// 默认 metadata 与请求 Header 一样经过 header 过滤规则，只有允许转发的键会被保存；
+// 每次工具调用时合并到转发的 Header 中，同名的请求 Header 优先。
+func (h *Handler) applyInitializeMetadata(params map[string]interface{}, sessionCtx *session.Context) {
+	meta, _ := params["_meta"].(map[string]interface{})
+	raw, ok := meta[initializeMetadataKey].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	defaults := make(map[string]string, len(raw))
+	var dropped []string
+	for key, value := range raw {
+		v, ok := value.(string)
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !ok || key == "" || len(v) > h.sessionMetadata.MaxValueLength || !h.headerFilter.ShouldForward(key) {
+			dropped = append(dropped, key)
+			continue
+		}
+		defaults[key] = v
+	}
+	if len(defaults) > h.sessionMetadata.MaxEntries {
+		h.logger.Warn("Ignoring initialize metadata: too many entries",
+			zap.String("sessionId", sessionCtx.ID),
+			zap.Int("entries", len(defaults)),
+			zap.Int("maxEntries", h.sessionMetadata.MaxEntries))
+		return
+	}
+	if len(dropped) > 0 {
+		sort.Strings(dropped)
+		h.logger.Warn("Dropping initialize metadata that is invalid or not forwarded by the header filter",
+			zap.String("sessionId", sessionCtx.ID),
+			zap.Strings("keys", dropped))
+	}
+
+	sessionCtx.SetDefaultHeaders(defaults)
+	h.logger.Info("Session default metadata set",
+		zap.String("sessionId", sessionCtx.ID),
+		zap.Strings("keys", sortedKeys(defaults)))
+}
+
+// handleSetMetadata 处理 session/setMetadata 请求
+//
+// 请求参数示例：
+//
+//	{"metadata": {"tenant-id": "acme", "locale": "de-DE"}}
+//
+// 返回合并后的完整会话元数据
+func (h *Handler) handleSetMetadata(params map[string]interface{}, sessionCtx *session.Context) (*SessionMetadataResult, error) {
+	if !h.sessionMetadata.Enabled {
+		return nil, fmt.Errorf("method not found: %s", sessionSetMetadataMethod)
+	}
+
+	raw, ok := params["metadata"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid parameters: metadata must be an object")
+	}
+
+	entries := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case string:
+			entries[key] = v
+		case nil:
+			entries[key] = ""
+		default:
+			return nil, fmt.Errorf("invalid parameters: metadata value for %q must be a string", key)
+		}
+	}
+
+	if err := h.mergeSessionMetadata(sessionCtx, entries); err != nil {
+		return nil, err
+	}
+
+	h.logger.Info("Session metadata updated",
+		zap.String("sessionId", sessionCtx.ID),
+		zap.Strings("keys", sortedKeys(entries)))
+
+	return &SessionMetadataResult{Metadata: sessionCtx.GetMetadata()}, nil
+}
+
+// handleGetMetadata 处理 session/getMetadata 请求
+func (h *Handler) handleGetMetadata(sessionCtx *session.Context) (*SessionMetadataResult, error) {
+	if !h.sessionMetadata.Enabled {
+		return nil, fmt.Errorf("method not found: %s", sessionGetMetadataMethod)
+	}
+	return &SessionMetadataResult{Metadata: sessionCtx.GetMetadata()}, nil
+}
+
+// injectSessionMetadata 将会话元数据注入到本次工具调用
+//
+// - 作为 gRPC metadata：附加到 ctx 的 outgoing metadata（可配置键前缀）
+// - 作为默认参数：填充输入消息中同名且未提供的顶层 string 字段（键中的 '-' 视为 '_'）
+func (h *Handler) injectSessionMetadata(ctx context.Context, toolName string, args map[string]interface{}, sessionCtx *session.Context) (context.Context, map[string]interface{}) {
+	if !h.sessionMetadata.Enabled {
+		return ctx, args
+	}
+	entries := sessionCtx.GetMetadata()
+	if len(entries) == 0 {
+		return ctx, args
+	}
+
+	if h.sessionMetadata.InjectMetadata {
+		kv := make([]string, 0, len(entries)*2)
+		for _, key := range sortedKeys(entries) {
+			kv = append(kv, h.sessionMetadata.MetadataKeyPrefix+key, entries[key])
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, kv...)
+	}
+
+	if h.sessionMetadata.InjectArguments {
+		if method, ok := h.serviceDiscoverer.GetMethod(toolName); ok && method.InputDescriptor != nil {
+			args = applyArgumentDefaults(method.InputDescriptor, args, entries)
+		}
+	}
+
+	return ctx, args
+}
+
+// applyArgumentDefaults 用元数据填充未提供的顶层 string 字段，不修改原参数
+func applyArgumentDefaults(desc protoreflect.MessageDescriptor, args map[string]interface{}, entries map[string]string) map[string]interface{} {
+	var result map[string]interface{}
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if field.Kind() != protoreflect.StringKind || field.IsList() || field.IsMap() || field.ContainingOneof() != nil {
+			continue
+		}
+
+		name := string(field.Name())
+		value, ok := entries[strings.ReplaceAll(name, "_", "-")]
+		if !ok {
+			value, ok = entries[name]
+		}
+		if !ok {
+			continue
+		}
+		if _, exists := args[name]; exists {
+			continue
+		}
+		if _, exists := args[field.JSONName()]; exists {
+			continue
+		}
+
+		if result == nil {
+			result = make(map[string]interface{}, len(args)+1)
+			for k, v := range args {
+				result[k] = v
+			}
+		}
+		result[name] = value
+	}
+
+	if result == nil {
+		return args
+	}
+	return result
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
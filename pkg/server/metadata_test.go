@@ -0,0 +1,193 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"github.com/aalobaidi/ggRMCP/pkg/tools"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+func outgoingValue(ctx context.Context, key string) string {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func callText(t *testing.T, result interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	var call mcp.ToolCallResult
+	require.NoError(t, json.Unmarshal(data, &call))
+	require.False(t, call.IsError)
+	require.Len(t, call.Content, 1)
+	return call.Content[0].Text
+}
+
+// newMetadataTestHandler enables session metadata and lets the header filter
+// forward the prefixed keys the tests send as gRPC metadata
+func newMetadataTestHandler(t *testing.T, discoverer *mockServiceDiscoverer) *Handler {
+	t.Helper()
+	headerConfig := config.Default().GRPC.HeaderForwarding
+	headerConfig.AllowedHeaders = append(headerConfig.AllowedHeaders, "x-session-tenant-id", "x-session-value", "x-session-locale")
+	metadataConfig := config.Default().Session.Metadata
+	metadataConfig.Enabled = true
+	metadataConfig.MaxEntries = 2
+
+	logger := zap.NewNop()
+	sessionManager := session.NewManager(logger)
+	t.Cleanup(func() { _ = sessionManager.Close() })
+	return NewHandler(logger, discoverer, sessionManager, tools.NewMCPToolBuilder(logger), headerConfig, WithSessionMetadata(metadataConfig))
+}
+
+func TestSessionMetadata_DisabledByDefault(t *testing.T) {
+	handler, _ := newTestHandler(t, &mockServiceDiscoverer{})
+
+	resp, _ := postRPC(t, handler, "/", "", sessionSetMetadataMethod, map[string]interface{}{
+		"metadata": map[string]interface{}{"tenant-id": "acme"},
+	}, nil)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, mcp.ErrorCodeMethodNotFound, resp.Error.Code)
+}
+
+func TestSessionMetadata_InjectedIntoCalls(t *testing.T) {
+	method := testMethod("tenant.service", "Echo")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethod", method.ToolName).Return(method, true)
+	hasTenant := mock.MatchedBy(func(ctx context.Context) bool {
+		return outgoingValue(ctx, "x-session-tenant-id") == "acme" && outgoingValue(ctx, "x-session-value") == "fallback"
+	})
+	discoverer.On("InvokeMethodByTool", hasTenant, mock.Anything, method.ToolName, `{"value":"fallback"}`).Return(`"default"`, nil)
+	discoverer.On("InvokeMethodByTool", hasTenant, mock.Anything, method.ToolName, `{"value":"explicit"}`).Return(`"explicit"`, nil)
+	handler := newMetadataTestHandler(t, discoverer)
+
+	resp, sessionID := postRPC(t, handler, "/", "", sessionSetMetadataMethod, map[string]interface{}{
+		"metadata": map[string]interface{}{"Tenant-Id": "acme", "value": "fallback"},
+	}, nil)
+	require.Nil(t, resp.Error)
+	assert.Equal(t, map[string]interface{}{"tenant-id": "acme", "value": "fallback"}, resp.Result.(map[string]interface{})["metadata"])
+
+	// Missing string arguments are filled from the session metadata
+	resp, _ = postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": method.ToolName}, nil)
+	require.Nil(t, resp.Error)
+	assert.Equal(t, `"default"`, callText(t, resp.Result))
+
+	// Explicit arguments always win
+	resp, _ = postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{
+		"name":      method.ToolName,
+		"arguments": map[string]interface{}{"value": "explicit"},
+	}, nil)
+	require.Nil(t, resp.Error)
+	assert.Equal(t, `"explicit"`, callText(t, resp.Result))
+
+	discoverer.AssertExpectations(t)
+}
+
+func TestSessionMetadata_Header(t *testing.T) {
+	handler := newMetadataTestHandler(t, &mockServiceDiscoverer{})
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, map[string]string{"Mcp-Meta-Locale": "de-DE"})
+	resp, _ := postRPC(t, handler, "/", sessionID, sessionGetMetadataMethod, nil, map[string]string{"Mcp-Meta-Tenant-Id": "acme"})
+	require.Nil(t, resp.Error)
+	assert.Equal(t, map[string]interface{}{"locale": "de-DE", "tenant-id": "acme"}, resp.Result.(map[string]interface{})["metadata"])
+
+	// An empty value removes the key
+	resp, _ = postRPC(t, handler, "/", sessionID, sessionSetMetadataMethod, map[string]interface{}{
+		"metadata": map[string]interface{}{"locale": ""},
+	}, nil)
+	require.Nil(t, resp.Error)
+	assert.Equal(t, map[string]interface{}{"tenant-id": "acme"}, resp.Result.(map[string]interface{})["metadata"])
+}
+
+func TestSessionMetadata_RejectsInvalidEntries(t *testing.T) {
+	handler := newMetadataTestHandler(t, &mockServiceDiscoverer{})
+
+	tests := []struct {
+		name     string
+		metadata interface{}
+	}{
+		{"reserved prefix", map[string]interface{}{"grpc-timeout": "1s"}},
+		{"blocked header", map[string]interface{}{"cookie": "a=b"}},
+		{"not forwarded by the header filter", map[string]interface{}{"x-workspace-id": "ws-42"}},
+		{"too many entries", map[string]interface{}{"tenant-id": "acme", "value": "v", "locale": "de"}},
+		{"invalid characters", map[string]interface{}{"tenant id": "acme"}},
+		{"non-string value", map[string]interface{}{"tenant-id": 42}},
+		{"not an object", "tenant-id=acme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, _ := postRPC(t, handler, "/", "", sessionSetMetadataMethod, map[string]interface{}{"metadata": tt.metadata}, nil)
+			require.NotNil(t, resp.Error)
+			assert.Equal(t, mcp.ErrorCodeInvalidParams, resp.Error.Code)
+		})
+	}
+}
+
+func TestInitializeMetadata_ForwardedBehindHeaderFilter(t *testing.T) {
+	method := testMethod("workspace.service", "Get")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{method})
+
+	var forwarded map[string]string
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).
+		Run(func(args mock.Arguments) { forwarded = args.Get(1).(map[string]string) }).
+		Return(`{}`, nil)
+	handler, _ := newTestHandler(t, discoverer)
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", map[string]interface{}{
+		"protocolVersion": "2024-11-05",
+		"_meta": map[string]interface{}{
+			initializeMetadataKey: map[string]interface{}{
+				"X-Trace-Id":     "trace-from-init",
+				"x-user-id":      "user-from-init",
+				"x-workspace-id": "not-allowed",
+				"cookie":         "blocked",
+			},
+		},
+	}, map[string]string{"X-User-Id": "user-from-header"})
+
+	resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": method.ToolName}, nil)
+	require.Nil(t, resp.Error)
+
+	lower := make(map[string]string, len(forwarded))
+	for k, v := range forwarded {
+		lower[strings.ToLower(k)] = v
+	}
+	assert.Equal(t, "trace-from-init", lower["x-trace-id"])
+	assert.Equal(t, "user-from-header", lower["x-user-id"], "request headers take precedence")
+	assert.NotContains(t, lower, "x-workspace-id")
+	assert.NotContains(t, lower, "cookie")
+	assert.Len(t, forwarded, len(lower), "no duplicate header names")
+}
+
+func TestSessionMetadata_ConcurrentMergesRespectMaxEntries(t *testing.T) {
+	handler := newMetadataTestHandler(t, &mockServiceDiscoverer{})
+	sessionCtx := handler.sessionManager.CreateSession(nil)
+
+	var wg sync.WaitGroup
+	for _, key := range []string{"tenant-id", "value", "locale"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			_ = handler.mergeSessionMetadata(sessionCtx, map[string]string{key: "v"})
+		}(key)
+	}
+	wg.Wait()
+
+	assert.Len(t, sessionCtx.GetMetadata(), 2)
+}
@@ -2,10 +2,17 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"mime"
 	"net/http"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/ratelimit"
 	"go.uber.org/zap"
 	"golang.org/x/time/rate"
 )
@@ -48,7 +55,7 @@ func CORSMiddleware() Middleware {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Mcp-Session-Id")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Mcp-Session-Id, Mcp-Allowed-Tools")
 			w.Header().Set("Access-Control-Expose-Headers", "Mcp-Session-Id")
 
 			if r.Method == "OPTIONS" {
@@ -61,6 +68,28 @@ func CORSMiddleware() Middleware {
 	}
 }
 
+// OriginMiddleware rejects requests whose Origin header is not allowed,
+// protecting local and internal deployments against DNS rebinding. Requests
+// without an Origin header (non-browser clients) are allowed; "*" allows all.
+func OriginMiddleware(allowedOrigins []string) Middleware {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		allowed[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin != "" && !allowed["*"] && !allowed[strings.ToLower(origin)] {
+				http.Error(w, "Origin not allowed", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // SecurityMiddleware adds security headers
 func SecurityMiddleware() Middleware {
 	return func(next http.Handler) http.Handler {
@@ -72,6 +101,9 @@ func SecurityMiddleware() Middleware {
 			w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
 			w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
 
+			// Responses carry session IDs and tool results; never cache them
+			w.Header().Set("Cache-Control", "no-store")
+
 			// Content Security Policy
 			csp := "default-src 'self'; " +
 				"script-src 'self' 'unsafe-inline'; " +
@@ -85,69 +117,119 @@ func SecurityMiddleware() Middleware {
 	}
 }
 
-// RateLimitMiddleware adds rate limiting
+// RateLimitMiddleware adds rate limiting. Every request of a JSON-RPC batch
+// takes a token, not just the HTTP request carrying it.
 func RateLimitMiddleware(requestsPerSecond int, burst int) Middleware {
 	limiter := rate.NewLimiter(rate.Limit(requestsPerSecond), burst)
+	charge := func(context.Context) (mcp.RetryInfo, bool) {
+		if limiter.Allow() {
+			return mcp.RetryInfo{}, true
+		}
+		return mcp.RetryInfo{
+			RetryAfterSeconds: retryAfterSeconds(limiterDelay(limiter)),
+			Scope:             "gateway",
+			Limit:             float64(requestsPerSecond),
+		}, false
+	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !limiter.Allow() {
-				http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			if info, ok := charge(r.Context()); !ok {
+				writeThrottled(w, mcp.RequestID{}, http.StatusTooManyRequests, "Rate limit exceeded", info)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, withRateCharge(r, charge))
 		})
 	}
 }
 
-// SessionRateLimitMiddleware adds per-session rate limiting
+// SessionRateLimitMiddleware adds per-session rate limiting, kept in memory
 func SessionRateLimitMiddleware(requestsPerSecond int, burst int) Middleware {
-	limiters := make(map[string]*rate.Limiter)
+	return LimiterMiddleware(ratelimit.NewMemory(float64(requestsPerSecond), burst), float64(requestsPerSecond), true, zap.NewNop())
+}
 
+// LimiterMiddleware rate limits requests per session, or per client IP for
+// requests without a session, using the given limiter. limit is the steady
+// rate in requests per second reported to throttled clients. When the limiter
+// fails (e.g. Redis is unreachable) requests are allowed if failOpen is set,
+// and rejected with HTTP 503 otherwise. Every request of a JSON-RPC batch
+// takes a token, not just the HTTP request carrying it.
+func LimiterMiddleware(limiter ratelimit.Limiter, limit float64, failOpen bool, logger *zap.Logger) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			sessionID := r.Header.Get("Mcp-Session-Id")
-			if sessionID == "" {
-				sessionID = "anonymous"
+			key := "ip:" + remoteIP(r)
+			if sessionID := r.Header.Get("Mcp-Session-Id"); sessionID != "" {
+				key = "session:" + sessionID
 			}
-
-			// Get or create limiter for this session
-			limiter, exists := limiters[sessionID]
-			if !exists {
-				limiter = rate.NewLimiter(rate.Limit(requestsPerSecond), burst)
-				limiters[sessionID] = limiter
+			// allow 返回限流结果；后端不可用时 unavailable 为 true
+			allow := func(ctx context.Context) (info mcp.RetryInfo, ok bool, unavailable bool) {
+				result, err := limiter.Allow(ctx, key)
+				if err != nil {
+					logger.Error("Rate limiter unavailable", zap.Bool("failOpen", failOpen), zap.Error(err))
+					if failOpen {
+						return mcp.RetryInfo{}, true, false
+					}
+					return mcp.RetryInfo{RetryAfterSeconds: retryAfterSeconds(minRetryAfter), Scope: "session"}, false, true
+				}
+				if !result.Allowed {
+					return mcp.RetryInfo{
+						RetryAfterSeconds: retryAfterSeconds(result.RetryAfter),
+						Scope:             "session",
+						Limit:             limit,
+					}, false, false
+				}
+				return mcp.RetryInfo{}, true, false
 			}
 
-			if !limiter.Allow() {
-				http.Error(w, "Rate limit exceeded for session", http.StatusTooManyRequests)
+			info, ok, unavailable := allow(r.Context())
+			if unavailable {
+				writeThrottled(w, mcp.RequestID{}, http.StatusServiceUnavailable, "Rate limiter unavailable", info)
+				return
+			}
+			if !ok {
+				writeThrottled(w, mcp.RequestID{}, http.StatusTooManyRequests, "Rate limit exceeded for session", info)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, withRateCharge(r, func(ctx context.Context) (mcp.RetryInfo, bool) {
+				info, ok, _ := allow(ctx)
+				return info, ok
+			}))
 		})
 	}
 }
 
-// ContentTypeMiddleware validates content type
+// ContentTypeMiddleware validates the content type of requests with a body.
+// The media type must match one of allowedTypes exactly (parameters aside),
+// and a charset parameter, if present, must be UTF-8.
 func ContentTypeMiddleware(allowedTypes ...string) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if r.Method == "POST" || r.Method == "PUT" {
+			if r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH" {
 				contentType := r.Header.Get("Content-Type")
 				if contentType == "" {
 					http.Error(w, "Content-Type header is required", http.StatusBadRequest)
 					return
 				}
 
+				mediaType, params, err := mime.ParseMediaType(contentType)
+				if err != nil {
+					http.Error(w, "Malformed Content-Type header", http.StatusBadRequest)
+					return
+				}
+
 				// Check if content type is allowed
 				allowed := false
 				for _, allowedType := range allowedTypes {
-					if strings.Contains(contentType, allowedType) {
+					if mediaType == allowedType {
 						allowed = true
 						break
 					}
 				}
+				if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") {
+					allowed = false
+				}
 
 				if !allowed {
 					http.Error(w, "Unsupported content type", http.StatusUnsupportedMediaType)
@@ -160,6 +242,44 @@ func ContentTypeMiddleware(allowedTypes ...string) Middleware {
 	}
 }
 
+// AcceptMiddleware rejects requests whose Accept header rules out every
+// type the gateway responds with. A missing Accept header accepts anything.
+func AcceptMiddleware(responseTypes ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accept := r.Header.Values("Accept")
+			if len(accept) > 0 && !acceptsAny(strings.Join(accept, ","), responseTypes) {
+				http.Error(w, "Not acceptable", http.StatusNotAcceptable)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// acceptsAny reports whether an Accept header value allows one of the types
+func acceptsAny(accept string, types []string) bool {
+	for _, entry := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(entry))
+		if err != nil {
+			continue
+		}
+		if q, ok := params["q"]; ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				continue
+			}
+		}
+		for _, t := range types {
+			major, _, _ := strings.Cut(t, "/")
+			if mediaType == "*/*" || mediaType == t || mediaType == major+"/*" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // RequestSizeMiddleware limits request body size
 func RequestSizeMiddleware(maxBytes int64) Middleware {
 	return func(next http.Handler) http.Handler {
@@ -177,10 +297,16 @@ func RequestSizeMiddleware(maxBytes int64) Middleware {
 	}
 }
 
-// TimeoutMiddleware adds request timeout
+// TimeoutMiddleware adds request timeout. Long-lived event streams (GET
+// requests accepting text/event-stream) are not bounded; they end when the
+// client disconnects.
 func TimeoutMiddleware(timeout time.Duration) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet && acceptsEventStream(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
 			ctx, cancel := context.WithTimeout(r.Context(), timeout)
 			defer cancel()
 
@@ -190,18 +316,39 @@ func TimeoutMiddleware(timeout time.Duration) Middleware {
 	}
 }
 
-// RecoveryMiddleware recovers from panics
+// panicCount counts panics recovered while serving HTTP requests
+var panicCount atomic.Int64
+
+// PanicCount returns the number of panics recovered while serving requests
+func PanicCount() int64 {
+	return panicCount.Load()
+}
+
+// RecoveryMiddleware recovers from panics, logs them with the request context
+// and a stack trace, and answers with a JSON-RPC internal error
 func RecoveryMiddleware(logger *zap.Logger) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if err := recover(); err != nil {
+					if err == http.ErrAbortHandler {
+						panic(err)
+					}
+					panicCount.Add(1)
 					logger.Error("Panic recovered",
 						zap.String("method", r.Method),
 						zap.String("path", r.URL.Path),
-						zap.Any("error", err))
-
-					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+						zap.String("remote_addr", r.RemoteAddr),
+						zap.String("session_id", r.Header.Get("Mcp-Session-Id")),
+						zap.Any("error", err),
+						zap.ByteString("stack", debug.Stack()))
+
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusInternalServerError)
+					_ = json.NewEncoder(w).Encode(&mcp.JSONRPCResponse{
+						JSONRPC: "2.0",
+						Error:   &mcp.RPCError{Code: mcp.ErrorCodeInternalError, Message: "Internal error"},
+					})
 				}
 			}()
 
@@ -243,6 +390,12 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap returns the wrapped writer, so http.ResponseController can flush
+// streamed responses through the middleware
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // ChainMiddleware chains multiple middleware functions
 func ChainMiddleware(middlewares ...Middleware) Middleware {
 	return func(next http.Handler) http.Handler {
@@ -285,6 +438,7 @@ func DefaultMiddleware(logger *zap.Logger) []Middleware {
 		CORSMiddleware(),
 		RateLimitMiddleware(100, 200), // 100 requests per second, burst of 200
 		ContentTypeMiddleware("application/json"),
+		AcceptMiddleware("application/json", eventStreamType),
 		RequestSizeMiddleware(1024 * 1024),  // 1MB max request size
 		TimeoutMiddleware(30 * time.Second), // 30 second timeout
 		MetricsMiddleware(),
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestOriginMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := OriginMiddleware([]string{"https://app.example.com/"})(next)
+
+	tests := []struct {
+		origin string
+		status int
+	}{
+		{"", http.StatusOK},
+		{"https://app.example.com", http.StatusOK},
+		{"HTTPS://APP.EXAMPLE.COM", http.StatusOK},
+		{"http://evil.example", http.StatusForbidden},
+		{"null", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, tt.status, w.Code, tt.origin)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Origin", "http://anything")
+	OriginMiddleware([]string{"*"})(next).ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestContentTypeMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := ContentTypeMiddleware("application/json")(next)
+
+	tests := []struct {
+		contentType string
+		status      int
+	}{
+		{"application/json", http.StatusOK},
+		{"Application/JSON; charset=UTF-8", http.StatusOK},
+		{"", http.StatusBadRequest},
+		{"application/json; charset=iso-8859-1", http.StatusUnsupportedMediaType},
+		{`text/plain; x="application/json"`, http.StatusUnsupportedMediaType},
+		{"application/jsonp", http.StatusUnsupportedMediaType},
+		{"application/json;;", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, tt.status, w.Code, tt.contentType)
+	}
+}
+
+func TestAcceptMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := AcceptMiddleware("application/json")(next)
+
+	tests := []struct {
+		accept string
+		status int
+	}{
+		{"", http.StatusOK},
+		{"application/json, text/event-stream", http.StatusOK},
+		{"text/html, */*;q=0.8", http.StatusOK},
+		{"application/*", http.StatusOK},
+		{"text/html", http.StatusNotAcceptable},
+		{"application/json;q=0", http.StatusNotAcceptable},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, tt.status, w.Code, tt.accept)
+	}
+}
+
+func TestSecurityMiddleware_NoStore(t *testing.T) {
+	handler := SecurityMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	before := PanicCount()
+	handler := RecoveryMiddleware(zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	var body struct {
+		Error struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, mcp.ErrorCodeInternalError, body.Error.Code)
+	assert.Equal(t, before+1, PanicCount())
+}
+
+func TestHandler_RecoversPanic(t *testing.T) {
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethod", "panics").Run(func(mock.Arguments) { panic("boom") })
+	handler, _ := newTestHandler(t, discoverer)
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+
+	before := PanicCount()
+	resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": "panics"}, nil)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, mcp.ErrorCodeInternalError, resp.Error.Code)
+	assert.Equal(t, float64(1), resp.ID.Value)
+	assert.Equal(t, before+1, PanicCount())
+}
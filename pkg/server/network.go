@@ -0,0 +1,124 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"go.uber.org/zap"
+)
+
+// remoteIP 返回请求的客户端 IP（ClientIPMiddleware 之后即为解析出的真实客户端）
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// containsAddr 报告地址是否落在任一前缀中
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveClientIP 返回请求的真实客户端地址
+//
+// 只有直接连接来自可信代理时才读取 X-Forwarded-For：从右向左跳过可信代理，
+// 第一个不可信的地址即为客户端，客户端伪造的更左侧条目因此不会被采用。
+func resolveClientIP(r *http.Request, trustedProxies []netip.Prefix) (netip.Addr, bool) {
+	remote, err := netip.ParseAddr(remoteIP(r))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	remote = remote.Unmap()
+	if !containsAddr(trustedProxies, remote) {
+		return remote, true
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = hop.Unmap()
+		if !containsAddr(trustedProxies, client) {
+			break
+		}
+	}
+	return client, true
+}
+
+// ClientIPMiddleware replaces the request's RemoteAddr with the client IP
+// resolved through the trusted proxies, so that logging, access control and
+// auth lockout see the real client behind load balancers
+func ClientIPMiddleware(trustedProxies []netip.Prefix) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(trustedProxies) > 0 {
+				if client, ok := resolveClientIP(r, trustedProxies); ok {
+					r.RemoteAddr = net.JoinHostPort(client.String(), "0")
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// IPFilterMiddleware rejects clients outside the allow list or inside the
+// deny list with HTTP 403. It must run after ClientIPMiddleware.
+func IPFilterMiddleware(allow, deny []netip.Prefix, logger *zap.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client, err := netip.ParseAddr(remoteIP(r))
+			if err == nil {
+				client = client.Unmap()
+			}
+			if err != nil || containsAddr(deny, client) || (len(allow) > 0 && !containsAddr(allow, client)) {
+				logger.Warn("Request rejected by IP filter",
+					zap.String("remote_addr", r.RemoteAddr),
+					zap.String("path", r.URL.Path))
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// NetworkMiddleware returns the client IP and IP filter middlewares for the
+// configuration, in the order they must run; it is empty when nothing is set
+func NetworkMiddleware(cfg config.NetworkConfig, logger *zap.Logger) ([]Middleware, error) {
+	trusted, err := config.ParsePrefixes(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	allow, err := config.ParsePrefixes(cfg.Allow)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := config.ParsePrefixes(cfg.Deny)
+	if err != nil {
+		return nil, err
+	}
+
+	var middlewares []Middleware
+	if len(trusted) > 0 {
+		middlewares = append(middlewares, ClientIPMiddleware(trusted))
+	}
+	if len(allow) > 0 || len(deny) > 0 {
+		middlewares = append(middlewares, IPFilterMiddleware(allow, deny, logger))
+	}
+	return middlewares, nil
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNetworkMiddleware_TrustedProxies(t *testing.T) {
+	middlewares, err := NetworkMiddleware(config.NetworkConfig{
+		TrustedProxies: []string{"10.0.0.0/8"},
+	}, zap.NewNop())
+	require.NoError(t, err)
+
+	var seen string
+	handler := ChainMiddleware(middlewares...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = remoteIP(r)
+	}))
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		expectedIP   string
+	}{
+		{"direct client", "203.0.113.7:4000", "198.51.100.1", "203.0.113.7"},
+		{"behind proxy", "10.0.0.2:4000", "198.51.100.1", "198.51.100.1"},
+		{"spoofed leftmost entry", "10.0.0.2:4000", "1.1.1.1, 198.51.100.1, 10.0.0.3", "198.51.100.1"},
+		{"proxy without header", "10.0.0.2:4000", "", "10.0.0.2"},
+		{"garbage entry", "10.0.0.2:4000", "not-an-ip", "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tt.expectedIP, seen)
+		})
+	}
+}
+
+func TestNetworkMiddleware_AllowDeny(t *testing.T) {
+	middlewares, err := NetworkMiddleware(config.NetworkConfig{
+		Allow:          []string{"192.168.0.0/16", "2001:db8::/32"},
+		Deny:           []string{"192.168.1.13"},
+		TrustedProxies: []string{"127.0.0.1"},
+	}, zap.NewNop())
+	require.NoError(t, err)
+	handler := ChainMiddleware(middlewares...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		remoteAddr   string
+		forwardedFor string
+		status       int
+	}{
+		{"192.168.1.2:1000", "", http.StatusOK},
+		{"[2001:db8::1]:1000", "", http.StatusOK},
+		{"192.168.1.13:1000", "", http.StatusForbidden},
+		{"203.0.113.7:1000", "", http.StatusForbidden},
+		{"127.0.0.1:1000", "192.168.5.5", http.StatusOK},
+		{"127.0.0.1:1000", "203.0.113.7", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tt.remoteAddr
+		if tt.forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, tt.status, w.Code, tt.remoteAddr+" "+tt.forwardedFor)
+	}
+
+	_, err = NetworkMiddleware(config.NetworkConfig{Deny: []string{"300.0.0.1"}}, zap.NewNop())
+	assert.Error(t, err)
+}
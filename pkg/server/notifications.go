@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"go.uber.org/zap"
+)
+
+// 客户端发送的通知
+const (
+	initializedNotificationMethod = "notifications/initialized"
+	cancelledNotificationMethod   = "notifications/cancelled"
+)
+
+// duplicateRequestIDMessage 会话中已有相同 ID 的请求在处理时的错误信息
+const duplicateRequestIDMessage = "Request ID is already in use by an in-flight request of this session"
+
+// pendingRequests 记录处理中的请求，notifications/cancelled 通过它取消请求
+//
+// 请求 ID 只在会话内唯一，因此按会话 ID 和请求 ID 记录。
+type pendingRequests struct {
+	mu      sync.Mutex
+	cancels map[pendingKey]context.CancelFunc
+}
+
+// pendingKey 处理中请求的键
+//
+// requestID 是请求 ID 的 JSON 编码，数字 1 和字符串 "1" 是不同的请求。
+type pendingKey struct {
+	sessionID string
+	requestID string
+}
+
+// newPendingRequests 创建空的请求表
+func newPendingRequests() *pendingRequests {
+	return &pendingRequests{cancels: make(map[pendingKey]context.CancelFunc)}
+}
+
+// newPendingKey 返回会话中请求 ID 的键
+func newPendingKey(sessionID string, requestID interface{}) pendingKey {
+	encoded, err := json.Marshal(requestID)
+	if err != nil {
+		encoded = []byte(fmt.Sprintf("%T:%v", requestID, requestID))
+	}
+	return pendingKey{sessionID: sessionID, requestID: string(encoded)}
+}
+
+// track 登记一个处理中的请求，返回可被取消的 context 和请求结束时调用的函数
+//
+// 会话中已有相同 ID 的请求在处理时返回 false：两个请求无法区分，
+// 取消通知不能确定要取消哪一个。
+func (p *pendingRequests) track(ctx context.Context, sessionID string, id mcp.RequestID) (context.Context, func(), bool) {
+	key := newPendingKey(sessionID, id.Value)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.cancels[key]; exists {
+		return ctx, func() {}, false
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	p.cancels[key] = cancel
+	return ctx, func() {
+		p.mu.Lock()
+		delete(p.cancels, key)
+		p.mu.Unlock()
+		cancel()
+	}, true
+}
+
+// cancel 取消会话中处理中的请求，请求已结束或不存在时返回 false
+func (p *pendingRequests) cancel(sessionID string, requestID interface{}) bool {
+	key := newPendingKey(sessionID, requestID)
+	p.mu.Lock()
+	cancel, ok := p.cancels[key]
+	p.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// handleNotification 处理没有 id 的 JSON-RPC 消息：通知不需要响应，返回 202 Accepted
+//
+// 未知的会话 ID 与请求一样返回 404；格式错误或不支持的通知只记录日志。
+func (h *Handler) handleNotification(w http.ResponseWriter, r *http.Request, req *mcp.JSONRPCRequest) {
+	sessionID := r.Header.Get("Mcp-Session-Id")
+	if sessionID != "" {
+		if _, ok := h.sessionManager.GetSession(sessionID); !ok {
+			writeSessionNotFound(w, req.ID)
+			return
+		}
+	}
+	h.processNotification(req, sessionID)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// processNotification 按方法处理一条通知
+func (h *Handler) processNotification(req *mcp.JSONRPCRequest, sessionID string) {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		h.logger.Warn("Ignoring malformed JSON-RPC notification",
+			zap.String("method", req.Method),
+			zap.String("sessionId", sessionID))
+		return
+	}
+
+	switch req.Method {
+	case initializedNotificationMethod:
+		h.logger.Debug("Client initialized", zap.String("sessionId", sessionID))
+	case cancelledNotificationMethod:
+		// 🛑 取消同一会话中处理中的请求；请求可能已经完成，此时忽略
+		requestID := req.Params["requestId"]
+		reason, _ := req.Params["reason"].(string)
+		cancelled := requestID != nil && h.pending.cancel(sessionID, requestID)
+		h.logger.Info("Client cancelled request",
+			zap.Any("requestId", requestID),
+			zap.String("reason", reason),
+			zap.Bool("inFlight", cancelled),
+			zap.String("sessionId", sessionID))
+	default:
+		h.logger.Debug("Ignoring notification",
+			zap.String("method", req.Method),
+			zap.String("sessionId", sessionID))
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifications(t *testing.T) {
+	method := testMethod("slow.service", "Wait")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{method}).Maybe()
+	started := make(chan struct{})
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).
+		Run(func(args mock.Arguments) {
+			close(started)
+			<-args.Get(0).(context.Context).Done()
+		}).
+		Return("", context.Canceled).Once()
+	handler, _ := newTestHandler(t, discoverer)
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+
+	// Any message without an id is a notification and gets no JSON-RPC response
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","method":"tools/list"}`,
+		`{"jsonrpc":"1.0","method":"notifications/unknown"}`,
+		`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":99}}`,
+	} {
+		w := sendRaw(handler, http.MethodPost, sessionID, body, "")
+		assert.Equal(t, http.StatusAccepted, w.Code, body)
+		assert.Empty(t, w.Body.String(), body)
+	}
+
+	// notifications/cancelled cancels the session's in-flight request with that id
+	done := make(chan int)
+	go func() {
+		done <- sendRaw(handler, http.MethodPost, sessionID,
+			`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"`+method.ToolName+`","arguments":{}}}`, "").Code
+	}()
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("tool call did not start")
+	}
+	// Request ids are scoped to the session
+	w := sendRaw(handler, http.MethodPost, "", `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7}}`, "")
+	require.Equal(t, http.StatusAccepted, w.Code)
+	select {
+	case <-done:
+		t.Fatal("cancellation from another session stopped the call")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// The string id "7" is a different request than the number 7
+	w = sendRaw(handler, http.MethodPost, sessionID, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"7"}}`, "")
+	require.Equal(t, http.StatusAccepted, w.Code)
+	select {
+	case <-done:
+		t.Fatal("cancelling the string id stopped the call with the numeric id")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// A second request with the id of an in-flight request is refused
+	w = sendRaw(handler, http.MethodPost, sessionID,
+		`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"`+method.ToolName+`","arguments":{}}}`, "")
+	var duplicate mcp.JSONRPCResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &duplicate))
+	require.NotNil(t, duplicate.Error)
+	assert.Equal(t, mcp.ErrorCodeInvalidRequest, duplicate.Error.Code)
+
+	w = sendRaw(handler, http.MethodPost, sessionID, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7,"reason":"user aborted"}}`, "")
+	require.Equal(t, http.StatusAccepted, w.Code)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("tool call was not cancelled")
+	}
+	discoverer.AssertExpectations(t)
+}
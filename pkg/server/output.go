@@ -0,0 +1,279 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// nonFiniteMetaKey 是 annotate 模式下记录被替换的非有限浮点值的 _meta 键
+	nonFiniteMetaKey = "ggrmcp/nonFinite"
+	// resultFormatMetaKey initialize 请求 _meta 中客户端选择结果组合方式的键
+	resultFormatMetaKey = "ggrmcp/resultFormat"
+	// structuredContentVersion 引入 structuredContent 的 MCP 协议版本
+	structuredContentVersion = "2025-06-18"
+)
+
+// WithToolOutput 设置工具调用结果的序列化方式（默认原样返回上游 JSON）
+func WithToolOutput(cfg config.ToolOutputConfig) HandlerOption {
+	return func(h *Handler) {
+		h.output = cfg
+	}
+}
+
+// formatResult 按配置格式化工具调用结果
+//
+// 返回格式化后的结果，以及需要合并到结果 _meta 中的注解（可能为 nil）。
+// 结果不是合法 JSON 时原样返回。
+func (h *Handler) formatResult(toolName, result string) (string, map[string]interface{}) {
+	normalize := h.output.NonFinite != config.NonFiniteKeep || h.output.FloatPrecision > 0
+	if !h.output.Canonical && !normalize {
+		return result, nil
+	}
+
+	value, err := decodeJSON(result)
+	if err != nil {
+		h.logger.Debug("Tool result is not JSON, returning it unchanged",
+			zap.String("toolName", toolName),
+			zap.Error(err))
+		return result, nil
+	}
+
+	var meta map[string]interface{}
+	if normalize {
+		// 浮点字段只能通过输出消息描述符识别；组合工具等没有描述符的结果保持不变
+		if method, ok := h.serviceDiscoverer.GetMethod(toolName); ok && method.OutputDescriptor != nil {
+			n := &numberNormalizer{output: h.output}
+			value = n.message(method.OutputDescriptor, value, "")
+			if len(n.replaced) > 0 && h.output.NonFinite == config.NonFiniteAnnotate {
+				meta = map[string]interface{}{nonFiniteMetaKey: n.replaced}
+			}
+		}
+	}
+
+	encoded, err := encodeJSON(value)
+	if err != nil {
+		h.logger.Debug("Failed to re-encode tool result, returning it unchanged",
+			zap.String("toolName", toolName),
+			zap.Error(err))
+		return result, nil
+	}
+	return encoded, meta
+}
+
+// applyResultFormat 在 initialize 时确定会话的 tools/call 结果组合方式
+//
+// 客户端可在 _meta["ggrmcp/resultFormat"] 中选择 text、structured 或 both；
+// 未选择且配置为 auto 时，按客户端协议版本是否支持 structuredContent 决定。
+func (h *Handler) applyResultFormat(params map[string]interface{}, sessionCtx *session.Context) {
+	meta, _ := params["_meta"].(map[string]interface{})
+	switch format, _ := meta[resultFormatMetaKey].(string); format {
+	case config.ResultText, config.ResultStructured, config.ResultBoth:
+		sessionCtx.SetResultFormat(format)
+		return
+	case "":
+	default:
+		h.logger.Warn("Ignoring unknown result format",
+			zap.String("sessionId", sessionCtx.ID),
+			zap.String("format", format))
+	}
+
+	if h.output.Result == config.ResultAuto {
+		// 协议版本为 YYYY-MM-DD 格式，可按字符串比较
+		format := config.ResultText
+		if version, _ := params["protocolVersion"].(string); version >= structuredContentVersion {
+			format = config.ResultBoth
+		}
+		sessionCtx.SetResultFormat(format)
+	}
+}
+
+// composeResult 按会话（或默认）的组合方式构建成功的工具调用结果
+//
+// structuredContent 必须是 JSON 对象，结果不是对象时始终以文本返回。
+// 文本块按工具配置的格式（tools.output.text_formats）转换，structuredContent 始终为 JSON。
+func (h *Handler) composeResult(toolName, output string, meta map[string]interface{}, sessionCtx *session.Context) *mcp.ToolCallResult {
+	text, textFormat := h.formatText(toolName, output)
+	if textFormat != "" {
+		if meta == nil {
+			meta = make(map[string]interface{})
+		}
+		meta[textFormatMetaKey] = textFormat
+	}
+	result := &mcp.ToolCallResult{
+		Content: []mcp.ContentBlock{mcp.TextContent(text)},
+		Meta:    meta,
+	}
+
+	format := sessionCtx.GetResultFormat()
+	if format == "" {
+		format = h.output.Result
+	}
+	if format != config.ResultStructured && format != config.ResultBoth {
+		return result
+	}
+
+	value, err := decodeJSON(output)
+	object, ok := value.(map[string]interface{})
+	if err != nil || !ok {
+		return result
+	}
+	result.StructuredContent = object
+	if format == config.ResultStructured {
+		result.Content = []mcp.ContentBlock{}
+	}
+	return result
+}
+
+// canonicalJSON 将 JSON 重新编码为规范形式
+//
+// 对象键按字典序排列、去除多余空白、不转义 HTML 字符；
+// 数字保持原始文本，避免 float64 往返造成精度变化。
+func canonicalJSON(data string) (string, error) {
+	value, err := decodeJSON(data)
+	if err != nil {
+		return "", err
+	}
+	return encodeJSON(value)
+}
+
+// decodeJSON 解码 JSON，数字保留为 json.Number
+func decodeJSON(data string) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// encodeJSON 以规范形式编码 JSON（见 canonicalJSON）
+func encodeJSON(value interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))), nil
+}
+
+// numberNormalizer 按消息描述符遍历 protojson 输出，规范化 float/double 字段
+type numberNormalizer struct {
+	output config.ToolOutputConfig
+	// replaced 记录被替换为 null 的非有限值：字段路径 → 原始字符串
+	replaced map[string]string
+}
+
+// message 规范化一个消息值，字段按 JSON 名或原始字段名匹配，无法匹配的键保持不变
+func (n *numberNormalizer) message(md protoreflect.MessageDescriptor, value interface{}, path string) interface{} {
+	switch md.FullName() {
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue":
+		// 包装类型在 protojson 中直接渲染为标量
+		return n.float(value, path)
+	}
+	if md.ParentFile().Package() == "google.protobuf" {
+		return value
+	}
+
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	fields := md.Fields()
+	for key, v := range obj {
+		fd := fields.ByJSONName(key)
+		if fd == nil {
+			fd = fields.ByTextName(key)
+		}
+		if fd == nil {
+			continue
+		}
+		obj[key] = n.field(fd, v, joinPath(path, key))
+	}
+	return obj
+}
+
+// field 规范化一个字段值，处理 repeated 和 map 字段
+func (n *numberNormalizer) field(fd protoreflect.FieldDescriptor, value interface{}, path string) interface{} {
+	switch {
+	case fd.IsMap():
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		for key, v := range entries {
+			entries[key] = n.single(fd.MapValue(), v, joinPath(path, key))
+		}
+		return entries
+	case fd.IsList():
+		items, ok := value.([]interface{})
+		if !ok {
+			return value
+		}
+		for i, v := range items {
+			items[i] = n.single(fd, v, fmt.Sprintf("%s[%d]", path, i))
+		}
+		return items
+	default:
+		return n.single(fd, value, path)
+	}
+}
+
+// single 规范化单个（非 repeated）值
+func (n *numberNormalizer) single(fd protoreflect.FieldDescriptor, value interface{}, path string) interface{} {
+	switch fd.Kind() {
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return n.float(value, path)
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return n.message(fd.Message(), value, path)
+	default:
+		return value
+	}
+}
+
+// float 规范化一个浮点值：替换非有限值并按有效数字舍入
+func (n *numberNormalizer) float(value interface{}, path string) interface{} {
+	switch v := value.(type) {
+	case string:
+		if v != "NaN" && v != "Infinity" && v != "-Infinity" {
+			return value
+		}
+		if n.output.NonFinite == config.NonFiniteKeep {
+			return value
+		}
+		if n.replaced == nil {
+			n.replaced = make(map[string]string)
+		}
+		n.replaced[path] = v
+		return nil
+	case json.Number:
+		if n.output.FloatPrecision <= 0 {
+			return value
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return value
+		}
+		return json.Number(strconv.FormatFloat(f, 'g', n.output.FloatPrecision, 64))
+	default:
+		return value
+	}
+}
+
+// joinPath 拼接字段路径，例如 "stats" + "mean" → "stats.mean"
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
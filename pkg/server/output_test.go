@@ -0,0 +1,203 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestToolsCall_CanonicalOutput(t *testing.T) {
+	upstream := `{"name":  "a<b>", "id":"12345678901234567890", "nested": {"z": 1.50, "a": [3, 1]},"count":2}`
+
+	tests := []struct {
+		name   string
+		output config.ToolOutputConfig
+		want   string
+	}{
+		{"Disabled", config.ToolOutputConfig{}, upstream},
+		{"Canonical", config.ToolOutputConfig{Canonical: true}, `{"count":2,"id":"12345678901234567890","name":"a<b>","nested":{"a":[3,1],"z":1.50}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := testMethod("report.service", "Get")
+			discoverer := &mockServiceDiscoverer{}
+			discoverer.On("GetMethods").Return([]types.MethodInfo{method})
+			discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).Return(upstream, nil)
+			handler, _ := newTestHandler(t, discoverer, WithToolOutput(tt.output))
+
+			_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+			resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": method.ToolName}, nil)
+			assert.Equal(t, tt.want, callText(t, resp.Result))
+		})
+	}
+}
+
+func TestCanonicalJSON_InvalidInput(t *testing.T) {
+	_, err := canonicalJSON("not json")
+	assert.Error(t, err)
+}
+
+// statsMethod returns a method whose output message has double/float fields:
+//
+//	message Stats {
+//	  double mean = 1;
+//	  repeated float samples = 2;
+//	  map<string, double> by_zone = 3;
+//	  google.protobuf.DoubleValue peak = 4;
+//	  string label = 5;
+//	}
+func statsMethod(t *testing.T) types.MethodInfo {
+	t.Helper()
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("stats.proto"),
+		Package:    proto.String("report"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/wrappers.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Stats"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("mean"), JsonName: proto.String("mean"), Number: proto.Int32(1), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_DOUBLE.Enum()},
+				{Name: proto.String("samples"), JsonName: proto.String("samples"), Number: proto.Int32(2), Label: repeated, Type: descriptorpb.FieldDescriptorProto_TYPE_FLOAT.Enum()},
+				{Name: proto.String("by_zone"), JsonName: proto.String("byZone"), Number: proto.Int32(3), Label: repeated, Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".report.Stats.ByZoneEntry")},
+				{Name: proto.String("peak"), JsonName: proto.String("peak"), Number: proto.Int32(4), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".google.protobuf.DoubleValue")},
+				{Name: proto.String("label"), JsonName: proto.String("label"), Number: proto.Int32(5), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+			},
+			NestedType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("ByZoneEntry"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("key"), JsonName: proto.String("key"), Number: proto.Int32(1), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+					{Name: proto.String("value"), JsonName: proto.String("value"), Number: proto.Int32(2), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_DOUBLE.Enum()},
+				},
+				Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+			}},
+		}},
+	}
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+
+	method := testMethod("report.service", "Stats")
+	method.OutputDescriptor = fd.Messages().ByName("Stats")
+	method.OutputType = string(method.OutputDescriptor.FullName())
+	return method
+}
+
+func TestToolsCall_FloatNormalization(t *testing.T) {
+	upstream := `{"mean":"NaN","samples":[1.23456789,"Infinity"],"byZone":{"eu":"-Infinity","us":0.333333333},"peak":"NaN","label":"NaN"}`
+
+	tests := []struct {
+		name     string
+		output   config.ToolOutputConfig
+		want     string
+		wantMeta map[string]interface{}
+	}{
+		{
+			name:   "Keep",
+			output: config.ToolOutputConfig{Canonical: true},
+			want:   `{"byZone":{"eu":"-Infinity","us":0.333333333},"label":"NaN","mean":"NaN","peak":"NaN","samples":[1.23456789,"Infinity"]}`,
+		},
+		{
+			name:   "Null",
+			output: config.ToolOutputConfig{NonFinite: config.NonFiniteNull},
+			want:   `{"byZone":{"eu":null,"us":0.333333333},"label":"NaN","mean":null,"peak":null,"samples":[1.23456789,null]}`,
+		},
+		{
+			name:   "Annotate",
+			output: config.ToolOutputConfig{NonFinite: config.NonFiniteAnnotate},
+			want:   `{"byZone":{"eu":null,"us":0.333333333},"label":"NaN","mean":null,"peak":null,"samples":[1.23456789,null]}`,
+			wantMeta: map[string]interface{}{nonFiniteMetaKey: map[string]interface{}{
+				"byZone.eu":  "-Infinity",
+				"mean":       "NaN",
+				"peak":       "NaN",
+				"samples[1]": "Infinity",
+			}},
+		},
+		{
+			name:   "Precision",
+			output: config.ToolOutputConfig{FloatPrecision: 4},
+			want:   `{"byZone":{"eu":"-Infinity","us":0.3333},"label":"NaN","mean":"NaN","peak":"NaN","samples":[1.235,"Infinity"]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := statsMethod(t)
+			discoverer := &mockServiceDiscoverer{}
+			discoverer.On("GetMethods").Return([]types.MethodInfo{method})
+			discoverer.On("GetMethod", method.ToolName).Return(method, true)
+			discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).Return(upstream, nil)
+			handler, _ := newTestHandler(t, discoverer, WithToolOutput(tt.output))
+
+			_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+			resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": method.ToolName}, nil)
+			assert.Equal(t, tt.want, callText(t, resp.Result))
+
+			result := resp.Result.(map[string]interface{})
+			if tt.wantMeta == nil {
+				assert.NotContains(t, result, "_meta")
+			} else {
+				assert.Equal(t, tt.wantMeta, result["_meta"])
+			}
+		})
+	}
+}
+
+func TestToolsCall_ResultComposition(t *testing.T) {
+	upstream := `{"id":"12345678901234567890","score":1.50}`
+
+	tests := []struct {
+		name           string
+		result         string
+		initialize     map[string]interface{}
+		upstream       string
+		wantText       bool
+		wantStructured bool
+	}{
+		{"Default text", "", nil, upstream, true, false},
+		{"Structured only", config.ResultStructured, nil, upstream, false, true},
+		{"Both", config.ResultBoth, nil, upstream, true, true},
+		{"Auto with new client", config.ResultAuto, map[string]interface{}{"protocolVersion": "2025-06-18"}, upstream, true, true},
+		{"Auto with old client", config.ResultAuto, map[string]interface{}{"protocolVersion": "2024-11-05"}, upstream, true, false},
+		{"Session choice", config.ResultText, map[string]interface{}{
+			"_meta": map[string]interface{}{resultFormatMetaKey: "structured"},
+		}, upstream, false, true},
+		{"Non-object result stays text", config.ResultStructured, nil, `"ok"`, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := testMethod("report.service", "Get")
+			discoverer := &mockServiceDiscoverer{}
+			discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).Return(tt.upstream, nil)
+			handler, _ := newTestHandler(t, discoverer, WithToolOutput(config.ToolOutputConfig{Result: tt.result}))
+
+			_, sessionID := postRPC(t, handler, "/", "", "initialize", tt.initialize, nil)
+			resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": method.ToolName}, nil)
+			require.Nil(t, resp.Error)
+			result := resp.Result.(map[string]interface{})
+
+			content := result["content"].([]interface{})
+			if tt.wantText {
+				require.Len(t, content, 1)
+				assert.Equal(t, tt.upstream, content[0].(map[string]interface{})["text"])
+			} else {
+				assert.Empty(t, content)
+			}
+			if tt.wantStructured {
+				assert.Equal(t, map[string]interface{}{"id": "12345678901234567890", "score": 1.5}, result["structuredContent"])
+			} else {
+				assert.NotContains(t, result, "structuredContent")
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// oversizeMetaKey 响应超过接收消息大小上限时，结果 _meta 中结构化错误信息的键
+const oversizeMetaKey = "ggrmcp/responseTooLarge"
+
+// fieldMaskType 字段掩码消息的全名
+const fieldMaskType = "google.protobuf.FieldMask"
+
+// oversizePattern 匹配 gRPC 接收消息超过大小上限的错误，
+// 例如 "received message larger than max (5242880 vs. 4194304)"，
+// 或解压后超限的 "received message after decompression larger than max 4194304"
+var oversizePattern = regexp.MustCompile(`(?:received message|message after decompression).*larger than max\D*?(?:\((\d+) vs\. (\d+)\)|(\d+))`)
+
+// responseTooLarge 描述一个超过接收大小上限的响应
+type responseTooLarge struct {
+	// Size 响应大小（字节），gRPC 没有报告时为 0
+	Size int64 `json:"size,omitempty"`
+	// Limit 接收消息大小上限（字节）
+	Limit int64 `json:"limit"`
+	// FieldMasks 请求中可以限制返回字段的 FieldMask 字段
+	FieldMasks []string `json:"fieldMasks,omitempty"`
+	// PageSize 请求中限制每页条目数的字段
+	PageSize string `json:"pageSize,omitempty"`
+}
+
+// detectResponseTooLarge 判断调用错误是否为响应超过接收消息大小上限
+func detectResponseTooLarge(err error) (*responseTooLarge, bool) {
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &grpcErr) || grpcErr.GRPCStatus().Code() != codes.ResourceExhausted {
+		return nil, false
+	}
+	match := oversizePattern.FindStringSubmatch(grpcErr.GRPCStatus().Message())
+	if match == nil {
+		return nil, false
+	}
+	oversize := &responseTooLarge{}
+	if match[3] != "" {
+		oversize.Limit, _ = strconv.ParseInt(match[3], 10, 64)
+	} else {
+		oversize.Size, _ = strconv.ParseInt(match[1], 10, 64)
+		oversize.Limit, _ = strconv.ParseInt(match[2], 10, 64)
+	}
+	return oversize, true
+}
+
+// oversizeResult 为超过接收大小上限的响应生成可操作的错误消息，
+// 并在 oversize 中记录可用于减少响应的请求字段
+//
+// 根据工具的请求消息建议减少返回的数据：设置 FieldMask 字段只请求需要的字段，
+// 或减小 page_size；都不适用时只能由运营方调大 max_recv_message_size。
+func (h *Handler) oversizeResult(toolName string, oversize *responseTooLarge) string {
+	if method, ok := h.serviceDiscoverer.GetMethod(toolName); ok && method.InputDescriptor != nil {
+		fields := method.InputDescriptor.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			field := fields.Get(i)
+			switch {
+			case field.Kind() == protoreflect.MessageKind && !field.IsList() && field.Message().FullName() == fieldMaskType:
+				oversize.FieldMasks = append(oversize.FieldMasks, string(field.Name()))
+			case field.Name() == "page_size" && !field.IsList() && field.Kind() != protoreflect.MessageKind:
+				oversize.PageSize = string(field.Name())
+			}
+		}
+	}
+
+	var message strings.Builder
+	if oversize.Size > 0 {
+		fmt.Fprintf(&message, "Error invoking method: the response of tool %s is %d bytes, which exceeds the %d byte limit for responses.", toolName, oversize.Size, oversize.Limit)
+	} else {
+		fmt.Fprintf(&message, "Error invoking method: the response of tool %s exceeds the %d byte limit for responses.", toolName, oversize.Limit)
+	}
+	var suggestions []string
+	for _, name := range oversize.FieldMasks {
+		suggestions = append(suggestions, fmt.Sprintf("set %s to request only the fields you need", name))
+	}
+	if oversize.PageSize != "" {
+		suggestions = append(suggestions, fmt.Sprintf("lower %s to fetch fewer items per call", oversize.PageSize))
+	}
+	if len(suggestions) > 0 {
+		fmt.Fprintf(&message, " Request less data: %s.", strings.Join(suggestions, "; "))
+	} else {
+		message.WriteString(" Narrow the request, or ask the gateway operator to raise grpc.max_recv_message_size.")
+	}
+	return message.String()
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// listMethod returns library.Books.ListBooks, whose request has a page_size
+// field and a read_mask FieldMask
+func listMethod(t *testing.T) types.MethodInfo {
+	t.Helper()
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("library/list.proto"),
+		Package:    proto.String("library"),
+		Dependency: []string{"google/protobuf/field_mask.proto"},
+		Syntax:     proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("ListBooksRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("page_size"), JsonName: proto.String("pageSize"), Number: proto.Int32(1), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum()},
+				{Name: proto.String("read_mask"), JsonName: proto.String("readMask"), Number: proto.Int32(2), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".google.protobuf.FieldMask")},
+			}},
+		},
+	}, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+
+	input := fd.Messages().ByName("ListBooksRequest")
+	method := types.MethodInfo{
+		Name:             "ListBooks",
+		FullName:         "library.Books.ListBooks",
+		ServiceName:      "library.Books",
+		InputType:        string(input.FullName()),
+		OutputType:       string(input.FullName()),
+		InputDescriptor:  input,
+		OutputDescriptor: input,
+	}
+	method.ToolName = method.GenerateToolName()
+	return method
+}
+
+func TestDetectResponseTooLarge(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want *responseTooLarge
+	}{
+		{
+			name: "received message",
+			err:  fmt.Errorf("failed to invoke method: %w", status.Error(codes.ResourceExhausted, "grpc: received message larger than max (5242880 vs. 4194304)")),
+			want: &responseTooLarge{Size: 5242880, Limit: 4194304},
+		},
+		{
+			name: "after decompression",
+			err:  status.Error(codes.ResourceExhausted, "grpc: received message after decompression larger than max 4194304"),
+			want: &responseTooLarge{Limit: 4194304},
+		},
+		{
+			name: "request too large",
+			err:  status.Error(codes.ResourceExhausted, "grpc: trying to send message larger than max (5242880 vs. 4194304)"),
+		},
+		{
+			name: "quota exhausted",
+			err:  status.Error(codes.ResourceExhausted, "quota exceeded"),
+		},
+		{
+			name: "other code",
+			err:  status.Error(codes.Internal, "grpc: received message larger than max (5 vs. 4)"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := detectResponseTooLarge(tt.err)
+			assert.Equal(t, tt.want != nil, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestToolCall_ResponseTooLarge(t *testing.T) {
+	list := listMethod(t)
+	echo := testMethod("echo.service", "Echo")
+	tooLarge := fmt.Errorf("failed to invoke method: %w", status.Error(codes.ResourceExhausted, "grpc: received message larger than max (5242880 vs. 4194304)"))
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethod", list.ToolName).Return(list, true)
+	discoverer.On("GetMethod", echo.ToolName).Return(echo, true)
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("", tooLarge)
+	handler, _ := newTestHandler(t, discoverer)
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+
+	call := func(tool string) mcp.ToolCallResult {
+		resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": tool, "arguments": map[string]interface{}{}}, nil)
+		require.Nil(t, resp.Error)
+		data, err := json.Marshal(resp.Result)
+		require.NoError(t, err)
+		var result mcp.ToolCallResult
+		require.NoError(t, json.Unmarshal(data, &result))
+		require.True(t, result.IsError)
+		return result
+	}
+
+	// The message suggests the request's field mask and page size
+	result := call(list.ToolName)
+	text := result.Content[0].Text
+	assert.Contains(t, text, "5242880 bytes, which exceeds the 4194304 byte limit")
+	assert.Contains(t, text, "set read_mask to request only the fields you need")
+	assert.Contains(t, text, "lower page_size")
+	assert.Equal(t, map[string]interface{}{
+		"size":       float64(5242880),
+		"limit":      float64(4194304),
+		"fieldMasks": []interface{}{"read_mask"},
+		"pageSize":   "page_size",
+	}, result.Meta[oversizeMetaKey])
+
+	// Without such fields it points at the gateway's limit
+	result = call(echo.ToolName)
+	assert.Contains(t, result.Content[0].Text, "grpc.max_recv_message_size")
+
+	assert.Equal(t, int64(2), handler.oversizedResponses.Load())
+}
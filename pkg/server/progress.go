@@ -0,0 +1,164 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+)
+
+// eventStreamType SSE 响应的 Content-Type
+const eventStreamType = "text/event-stream"
+
+// progressStream 把服务端流式工具调用收到的消息以 SSE 逐条发送给客户端
+//
+// 客户端在 tools/call 的 params._meta.progressToken 中提供进度令牌，
+// 并在 Accept 中明确接受 text/event-stream 时启用：每收到一条流消息发送一个
+// notifications/progress 通知（message 为该消息的 JSON），最终的 JSON-RPC 响应
+// 作为最后一个事件发送。在收到第一条消息之前结束的调用（包括所有非流式工具）
+// 仍按普通 JSON 响应返回。
+//
+// Accept 只接受 text/event-stream（不接受 JSON）的客户端总是以 SSE 接收响应。
+type progressStream struct {
+	http.ResponseWriter
+	controller *http.ResponseController
+	token      interface{}
+	eventsOnly bool // 客户端不接受 JSON，响应也作为 SSE 事件发送
+
+	mu      sync.Mutex
+	started bool // 已切换为 SSE 响应
+	done    bool // 已写入最终响应，之后到达的消息被丢弃
+	sent    int
+
+	// discarded 切换为 SSE 后交给最终响应的 Header，使其设置的
+	// Content-Type 不会覆盖已发出的 text/event-stream
+	discarded http.Header
+}
+
+// newProgressStream 客户端接受 SSE，并为工具调用请求了进度通知或不接受 JSON 时返回 progressStream，否则返回 nil
+func newProgressStream(w http.ResponseWriter, r *http.Request, req *mcp.JSONRPCRequest) *progressStream {
+	if !acceptsEventStream(r) {
+		return nil
+	}
+	var token interface{}
+	if req.Method == "tools/call" {
+		meta, _ := req.Params["_meta"].(map[string]interface{})
+		token = meta["progressToken"]
+	}
+	eventsOnly := !acceptsJSON(r)
+	if token == nil && !eventsOnly {
+		return nil
+	}
+	controller := http.NewResponseController(w)
+	// 流式工具调用可能比服务器写超时更久，由请求超时限制
+	_ = controller.SetWriteDeadline(time.Time{})
+	return &progressStream{
+		ResponseWriter: w,
+		controller:     controller,
+		token:          token,
+		eventsOnly:     eventsOnly,
+	}
+}
+
+// acceptsEventStream 判断 Accept 中是否明确列出 text/event-stream（通配符不算）
+func acceptsEventStream(r *http.Request) bool {
+	for _, entry := range strings.Split(strings.Join(r.Header.Values("Accept"), ","), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(entry)); err == nil && mediaType == eventStreamType {
+			return true
+		}
+	}
+	return false
+}
+
+// send 把一条流消息作为进度通知发送，第一条消息到达时切换为 SSE 响应
+func (s *progressStream) send(message json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return
+	}
+	if !s.started {
+		s.start()
+	}
+
+	s.sent++
+	data, err := json.Marshal(mcp.JSONRPCNotification{
+		JSONRPC: "2.0",
+		Method:  mcp.ProgressNotificationMethod,
+		Params: map[string]interface{}{
+			"progressToken": s.token,
+			"progress":      s.sent,
+			"message":       string(message),
+		},
+	})
+	if err != nil {
+		return
+	}
+	_ = s.writeEvent(data)
+}
+
+// start 将响应切换为 SSE
+func (s *progressStream) start() {
+	s.ResponseWriter.Header().Set("Content-Type", eventStreamType)
+	s.ResponseWriter.Header().Set("Cache-Control", "no-cache")
+	s.ResponseWriter.WriteHeader(http.StatusOK)
+	s.started = true
+	s.discarded = make(http.Header)
+}
+
+// writeEvent 写入一个 SSE 事件并立即发送
+func (s *progressStream) writeEvent(data []byte) error {
+	if _, err := fmt.Fprintf(s.ResponseWriter, "event: message\ndata: %s\n\n", data); err != nil {
+		return err
+	}
+	return s.controller.Flush()
+}
+
+// Header 切换为 SSE 后返回一个不会发出的 Header
+func (s *progressStream) Header() http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return s.discarded
+	}
+	return s.ResponseWriter.Header()
+}
+
+// WriteHeader 切换为 SSE 后状态码已经发出，忽略最终响应的状态码；
+// 只接受 SSE 的客户端在此时切换（非 200 的状态码仍按原样返回）
+func (s *progressStream) WriteHeader(code int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	if s.eventsOnly && code == http.StatusOK {
+		s.start()
+		return
+	}
+	s.ResponseWriter.WriteHeader(code)
+	s.eventsOnly = false
+}
+
+// Write 写入最终响应：切换为 SSE 后作为最后一个事件发送
+func (s *progressStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.done = true
+	if !s.started && s.eventsOnly {
+		s.start()
+	}
+	if !s.started {
+		return s.ResponseWriter.Write(p)
+	}
+	if err := s.writeEvent(bytes.TrimSpace(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
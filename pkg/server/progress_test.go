@@ -0,0 +1,120 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressStream_ServerStreamingTool(t *testing.T) {
+	method := testMethod("ticker.service", "Count")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethod", method.ToolName).Return(method, true)
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).
+		Run(func(args mock.Arguments) {
+			if observe := grpc.StreamObserverFromContext(args.Get(0).(context.Context)); observe != nil {
+				observe(json.RawMessage(`{"n":1}`))
+				observe(json.RawMessage(`{"n":2}`))
+			}
+		}).
+		Return(`{"messages":[{"n":1},{"n":2}]}`, nil)
+	handler, _ := newTestHandler(t, discoverer)
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+
+	call := func(accept string, params map[string]interface{}) *httptest.ResponseRecorder {
+		body, err := json.Marshal(mcp.JSONRPCRequest{JSONRPC: "2.0", ID: mcp.RequestID{Value: 7}, Method: "tools/call", Params: params})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", accept)
+		req.Header.Set("Mcp-Session-Id", sessionID)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	params := map[string]interface{}{
+		"name":      method.ToolName,
+		"arguments": map[string]interface{}{},
+		"_meta":     map[string]interface{}{"progressToken": "tok-1"},
+	}
+
+	// Each streamed message is a progress notification, the result comes last
+	w := call("application/json, text/event-stream", params)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	var events []map[string]interface{}
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			var event map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(data), &event))
+			events = append(events, event)
+		}
+	}
+	require.Len(t, events, 3)
+	assert.Equal(t, mcp.ProgressNotificationMethod, events[0]["method"])
+	assert.Equal(t, map[string]interface{}{"progressToken": "tok-1", "progress": float64(1), "message": `{"n":1}`}, events[0]["params"])
+	assert.Equal(t, float64(2), events[1]["params"].(map[string]interface{})["progress"])
+	assert.Equal(t, float64(7), events[2]["id"])
+	assert.Contains(t, events[2], "result")
+
+	// Without a progress token, or without accepting SSE, the result is plain JSON
+	delete(params, "_meta")
+	w = call("application/json, text/event-stream", params)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	params["_meta"] = map[string]interface{}{"progressToken": "tok-2"}
+	w = call("application/json", params)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var resp mcp.JSONRPCResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Nil(t, resp.Error)
+}
+
+func TestProgressStream_OutlivesServerWriteTimeout(t *testing.T) {
+	method := testMethod("ticker.service", "Count")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethod", method.ToolName).Return(method, true)
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).
+		Run(func(args mock.Arguments) {
+			time.Sleep(100 * time.Millisecond)
+			if observe := grpc.StreamObserverFromContext(args.Get(0).(context.Context)); observe != nil {
+				observe(json.RawMessage(`{"n":1}`))
+			}
+		}).
+		Return(`{"messages":[{"n":1}]}`, nil)
+	handler, _ := newTestHandler(t, discoverer)
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.WriteTimeout = 20 * time.Millisecond
+	server.Start()
+	t.Cleanup(server.Close)
+
+	body := `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"` + method.ToolName +
+		`","arguments":{},"_meta":{"progressToken":"tok"}}}`
+	req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	req.Header.Set("Mcp-Session-Id", sessionID)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	// The stream is not cut off when the server's write timeout expires
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), mcp.ProgressNotificationMethod)
+	assert.Contains(t, string(data), `"result"`)
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// promptsGetMethod 获取提示内容的 JSON-RPC 方法
+	promptsGetMethod = "prompts/get"
+	// servicePromptPrefix 服务提示名称的前缀
+	servicePromptPrefix = "how_to_use_"
+	// exampleMaxDepth 示例参数中嵌套消息的最大展开深度
+	exampleMaxDepth = 3
+)
+
+// servicePromptName 根据服务全名生成提示名称，例如 how_to_use_hello_helloservice
+func servicePromptName(serviceName string) string {
+	return servicePromptPrefix + strings.ToLower(strings.ReplaceAll(serviceName, ".", "_"))
+}
+
+// promptServices 按服务分组会话可见的方法，服务按名称排序
+func (h *Handler) promptServices(sessionCtx *session.Context) (map[string][]types.MethodInfo, []string) {
+	services := make(map[string][]types.MethodInfo)
+	for _, method := range h.serviceDiscoverer.GetMethods() {
+		if method.IsClientStreaming || method.IsServerStreaming || !sessionCtx.IsToolAllowed(method.ToolName) {
+			continue
+		}
+		services[method.ServiceName] = append(services[method.ServiceName], method)
+	}
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return services, names
+}
+
+// handlePromptsList 处理 prompts/list 请求
+//
+// 为每个已发现的服务生成一个提示（"How to use hello.HelloService"），
+// 只包含会话作用域允许的工具，使 prompts/list 无需配置即可使用。
+func (h *Handler) handlePromptsList(sessionCtx *session.Context) (*mcp.PromptsListResult, error) {
+	services, names := h.promptServices(sessionCtx)
+	prompts := make([]mcp.Prompt, 0, len(names))
+	for _, name := range names {
+		prompts = append(prompts, mcp.Prompt{
+			Name:        servicePromptName(name),
+			Title:       "How to use " + name,
+			Description: servicePromptDescription(name, services[name]),
+		})
+	}
+	return &mcp.PromptsListResult{Prompts: prompts}, nil
+}
+
+// handlePromptsGet 处理 prompts/get 请求，返回服务的使用说明
+//
+// 说明由服务注释、方法注释和示例参数组成。
+func (h *Handler) handlePromptsGet(params map[string]interface{}, sessionCtx *session.Context) (*mcp.GetPromptResult, error) {
+	name, _ := params["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("invalid parameters: name is required")
+	}
+
+	services, names := h.promptServices(sessionCtx)
+	for _, serviceName := range names {
+		if servicePromptName(serviceName) != name {
+			continue
+		}
+		methods := services[serviceName]
+		return &mcp.GetPromptResult{
+			Description: servicePromptDescription(serviceName, methods),
+			Messages: []mcp.PromptMessage{{
+				Role:    mcp.RoleUser,
+				Content: mcp.TextContent(h.servicePromptText(serviceName, methods)),
+			}},
+		}, nil
+	}
+	return nil, fmt.Errorf("prompt not found: %s", name)
+}
+
+// servicePromptDescription 提示描述：优先使用服务注释的第一行
+func servicePromptDescription(serviceName string, methods []types.MethodInfo) string {
+	if description := strings.TrimSpace(methods[0].ServiceDescription); description != "" {
+		return strings.SplitN(description, "\n", 2)[0]
+	}
+	return fmt.Sprintf("How to use the %d tools of %s", len(methods), serviceName)
+}
+
+// servicePromptText 生成服务的 markdown 使用说明
+func (h *Handler) servicePromptText(serviceName string, methods []types.MethodInfo) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# How to use %s\n\n", serviceName)
+	if description := strings.TrimSpace(methods[0].ServiceDescription); description != "" {
+		b.WriteString(description + "\n\n")
+	}
+	fmt.Fprintf(&b, "The service is available through %d tools:\n", len(methods))
+
+	for _, method := range methods {
+		fmt.Fprintf(&b, "\n## %s\n\n", h.aliases.displayName(method.ToolName))
+		if description := strings.TrimSpace(method.Description); description != "" {
+			b.WriteString(description + "\n\n")
+		}
+		if method.InputDescriptor == nil {
+			continue
+		}
+		example, err := json.MarshalIndent(exampleMessage(method.InputDescriptor, 0), "", "  ")
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "Example arguments:\n\n```json\n%s\n```\n", example)
+	}
+	return b.String()
+}
+
+// exampleMessage 为消息生成示例参数，字段名与工具 schema 一致使用 proto 字段名
+func exampleMessage(desc protoreflect.MessageDescriptor, depth int) map[string]interface{} {
+	example := make(map[string]interface{})
+	if depth >= exampleMaxDepth {
+		return example
+	}
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		value := exampleValue(field, depth)
+		switch {
+		case field.IsMap():
+			value = map[string]interface{}{}
+		case field.IsList():
+			value = []interface{}{value}
+		}
+		example[string(field.Name())] = value
+	}
+	return example
+}
+
+// exampleValue 为单个字段生成示例值
+func exampleValue(field protoreflect.FieldDescriptor, depth int) interface{} {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return false
+	case protoreflect.StringKind:
+		return "string"
+	case protoreflect.BytesKind:
+		return ""
+	case protoreflect.EnumKind:
+		if values := field.Enum().Values(); values.Len() > 0 {
+			return string(values.Get(0).Name())
+		}
+		return ""
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return 0.0
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if field.IsMap() {
+			return map[string]interface{}{}
+		}
+		return exampleMessage(field.Message(), depth+1)
+	}
+	// 整数类型
+	return 0
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestPrompts_GeneratedPerService(t *testing.T) {
+	say := testMethod("hello.HelloService", "SayHello")
+	say.ServiceDescription = "Greets people.\nPolitely."
+	say.Description = "Says hello to a person."
+	wave := testMethod("hello.HelloService", "Wave")
+	other := testMethod("admin.AdminService", "Reset")
+
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{say, wave, other})
+	handler, _ := newTestHandler(t, discoverer, WithToolAliases([]config.ToolAliasConfig{{Tool: wave.ToolName, Alias: "wave"}}))
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+
+	resp, _ := postRPC(t, handler, "/", sessionID, "prompts/list", nil, nil)
+	require.Nil(t, resp.Error)
+	var list mcp.PromptsListResult
+	decodeResult(t, resp.Result, &list)
+	require.Len(t, list.Prompts, 2)
+	assert.Equal(t, "how_to_use_admin_adminservice", list.Prompts[0].Name)
+	assert.Equal(t, "how_to_use_hello_helloservice", list.Prompts[1].Name)
+	assert.Equal(t, "How to use hello.HelloService", list.Prompts[1].Title)
+	assert.Equal(t, "Greets people.", list.Prompts[1].Description)
+
+	resp, _ = postRPC(t, handler, "/", sessionID, "prompts/get", map[string]interface{}{"name": "how_to_use_hello_helloservice"}, nil)
+	require.Nil(t, resp.Error)
+	var prompt mcp.GetPromptResult
+	decodeResult(t, resp.Result, &prompt)
+	require.Len(t, prompt.Messages, 1)
+	text := prompt.Messages[0].Content.Text
+	assert.Contains(t, text, "# How to use hello.HelloService")
+	assert.Contains(t, text, "## "+say.ToolName+"\n\nSays hello to a person.")
+	assert.Contains(t, text, "## wave\n")
+	assert.Contains(t, text, "\"value\": \"string\"")
+
+	resp, _ = postRPC(t, handler, "/", sessionID, "prompts/get", map[string]interface{}{"name": "how_to_use_missing"}, nil)
+	require.NotNil(t, resp.Error)
+}
+
+func TestPrompts_RespectSessionScope(t *testing.T) {
+	allowed := testMethod("hello.HelloService", "SayHello")
+	hidden := testMethod("admin.AdminService", "Reset")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{allowed, hidden})
+	handler, _ := newTestHandler(t, discoverer)
+
+	_, sessionID := postRPC(t, handler, "/?tools=hello_*", "", "initialize", nil, nil)
+
+	resp, _ := postRPC(t, handler, "/", sessionID, "prompts/list", nil, nil)
+	var list mcp.PromptsListResult
+	decodeResult(t, resp.Result, &list)
+	require.Len(t, list.Prompts, 1)
+	assert.Equal(t, "how_to_use_hello_helloservice", list.Prompts[0].Name)
+
+	resp, _ = postRPC(t, handler, "/", sessionID, "prompts/get", map[string]interface{}{"name": "how_to_use_admin_adminservice"}, nil)
+	assert.NotNil(t, resp.Error)
+}
+
+func TestExampleMessage(t *testing.T) {
+	example := exampleMessage((&structpb.Struct{}).ProtoReflect().Descriptor(), 0)
+	data, err := json.Marshal(example)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"fields":{}}`, string(data))
+}
+
+func decodeResult(t *testing.T, result interface{}, target interface{}) {
+	t.Helper()
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, target))
+}
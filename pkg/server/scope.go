@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"go.uber.org/zap"
+)
+
+const (
+	// toolScopeQueryParam 请求工具子集的查询参数（逗号分隔）
+	toolScopeQueryParam = "tools"
+	// toolScopeHeader 请求工具子集的 HTTP Header（逗号分隔）
+	toolScopeHeader = "Mcp-Allowed-Tools"
+	// toolScopeClaim Bearer token 中声明工具子集的 claim（数组或以逗号/空格分隔的字符串）
+	toolScopeClaim = "mcp_tools"
+)
+
+// requestedToolScope 从请求中提取客户端要求的工具范围
+//
+// 来源优先级：查询参数 > Header > Bearer token claim。
+// 作用域只会缩小会话可用的工具集合，因此这里读取 token claim 时不校验签名。
+func requestedToolScope(r *http.Request) []string {
+	if value := r.URL.Query().Get(toolScopeQueryParam); value != "" {
+		return splitToolList(value)
+	}
+	if value := r.Header.Get(toolScopeHeader); value != "" {
+		return splitToolList(value)
+	}
+	return toolScopeFromToken(r.Header.Get("Authorization"))
+}
+
+// applyToolScope 在会话尚未设置作用域时应用请求中的工具范围
+// 作用域一旦设置就不能再修改，避免会话中途扩大权限
+func (h *Handler) applyToolScope(r *http.Request, sessionCtx *session.Context) {
+	scope := requestedToolScope(r)
+	if len(scope) == 0 {
+		return
+	}
+	if sessionCtx.SetToolScope(scope) {
+		h.logger.Info("Session tool scope set",
+			zap.String("sessionId", sessionCtx.ID),
+			zap.Strings("allowedTools", scope))
+	}
+}
+
+func splitToolList(value string) []string {
+	var tools []string
+	for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if part = strings.TrimSpace(part); part != "" {
+			tools = append(tools, part)
+		}
+	}
+	return tools
+}
+
+// toolScopeFromToken 读取 JWT payload 中的 mcp_tools claim，非 JWT 时返回 nil
+func toolScopeFromToken(authorization string) []string {
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok {
+		return nil
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil
+	}
+
+	switch v := claims[toolScopeClaim].(type) {
+	case string:
+		return splitToolList(v)
+	case []interface{}:
+		var tools []string
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				tools = append(tools, s)
+			}
+		}
+		return tools
+	}
+	return nil
+}
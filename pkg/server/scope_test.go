@@ -0,0 +1,137 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"github.com/aalobaidi/ggRMCP/pkg/tools"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// testMethod builds a unary method using StringValue for input and output
+func testMethod(serviceName, methodName string) types.MethodInfo {
+	desc := (&wrapperspb.StringValue{}).ProtoReflect().Descriptor()
+	method := types.MethodInfo{
+		Name:             methodName,
+		FullName:         serviceName + "." + methodName,
+		ServiceName:      serviceName,
+		Description:      "test method",
+		InputType:        string(desc.FullName()),
+		OutputType:       string(desc.FullName()),
+		InputDescriptor:  desc,
+		OutputDescriptor: desc,
+	}
+	method.ToolName = method.GenerateToolName()
+	return method
+}
+
+func newTestHandler(t *testing.T, discoverer *mockServiceDiscoverer, opts ...HandlerOption) (*Handler, *session.Manager) {
+	t.Helper()
+	logger := zap.NewNop()
+	sessionManager := session.NewManager(logger)
+	t.Cleanup(func() { _ = sessionManager.Close() })
+	handler := NewHandler(logger, discoverer, sessionManager, tools.NewMCPToolBuilder(logger), config.Default().GRPC.HeaderForwarding, opts...)
+	return handler, sessionManager
+}
+
+// postRPC sends a JSON-RPC request and returns the decoded response and session ID
+func postRPC(t *testing.T, handler http.Handler, target string, sessionID string, method string, params map[string]interface{}, headers map[string]string) (mcp.JSONRPCResponse, string) {
+	t.Helper()
+	return postRPCWithID(t, handler, target, sessionID, 1, method, params, headers)
+}
+
+// postRPCWithID is postRPC with a request ID, for requests that overlap
+// another in-flight request of the same session
+func postRPCWithID(t *testing.T, handler http.Handler, target string, sessionID string, id interface{}, method string, params map[string]interface{}, headers map[string]string) (mcp.JSONRPCResponse, string) {
+	t.Helper()
+	body, err := json.Marshal(mcp.JSONRPCRequest{JSONRPC: "2.0", ID: mcp.RequestID{Value: id}, Method: method, Params: params})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", sessionID)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	var resp mcp.JSONRPCResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp, w.Header().Get("Mcp-Session-Id")
+}
+
+func toolNames(t *testing.T, result interface{}) []string {
+	t.Helper()
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	var list mcp.ToolsListResult
+	require.NoError(t, json.Unmarshal(data, &list))
+	names := make([]string, 0, len(list.Tools))
+	for _, tool := range list.Tools {
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
+func TestToolScope_QueryParameter(t *testing.T) {
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{
+		testMethod("user.service", "Get"),
+		testMethod("user.service", "Delete"),
+		testMethod("order.service", "List"),
+	})
+	handler, _ := newTestHandler(t, discoverer)
+
+	_, sessionID := postRPC(t, handler, "/?tools=user_service_get,order_*", "", "initialize", nil, nil)
+	require.NotEmpty(t, sessionID)
+
+	resp, _ := postRPC(t, handler, "/", sessionID, "tools/list", nil, nil)
+	require.Nil(t, resp.Error)
+	assert.ElementsMatch(t, []string{"user_service_get", "order_service_list"}, toolNames(t, resp.Result))
+
+	// Calling a tool outside the scope never reaches the upstream
+	resp, _ = postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{
+		"name":      "user_service_delete",
+		"arguments": map[string]interface{}{},
+	}, nil)
+	require.Nil(t, resp.Error)
+	result := resp.Result.(map[string]interface{})
+	assert.Equal(t, true, result["isError"])
+	discoverer.AssertNotCalled(t, "InvokeMethodByTool")
+
+	// The scope cannot be widened later in the session
+	resp, _ = postRPC(t, handler, "/?tools=*", sessionID, "tools/list", nil, nil)
+	assert.Len(t, toolNames(t, resp.Result), 2)
+}
+
+func TestToolScope_HeaderAndToken(t *testing.T) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"agent","mcp_tools":["order_service_list"]}`))
+	token := "Bearer eyJhbGciOiJub25lIn0." + payload + ".sig"
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Authorization", token)
+	assert.Equal(t, []string{"order_service_list"}, requestedToolScope(req))
+
+	req.Header.Set(toolScopeHeader, "a_b, c_d")
+	assert.Equal(t, []string{"a_b", "c_d"}, requestedToolScope(req))
+
+	// Opaque bearer tokens are ignored
+	req = httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Authorization", "Bearer token123")
+	assert.Nil(t, requestedToolScope(req))
+}
@@ -0,0 +1,452 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"go.uber.org/zap"
+)
+
+// streamsPath 双向流端点的路径前缀
+const streamsPath = "/streams"
+
+// errTooManyStreams 打开的流达到 max_streams
+var errTooManyStreams = errors.New("too many open streams")
+
+// errTooManySessionStreams 会话打开的流达到 max_streams_per_session
+var errTooManySessionStreams = errors.New("too many open streams in this session")
+
+// streamRegistry 记录打开的客户端流和双向流，每个流属于打开它的 MCP 会话
+type streamRegistry struct {
+	cfg config.BidiStreamingConfig
+
+	mu      sync.Mutex
+	streams map[string]*openStream
+}
+
+// openStream 是一个打开的流及其所属会话
+type openStream struct {
+	id        string
+	sessionID string
+	tool      string
+	session   *grpc.StreamSession
+
+	// reading 有客户端正在以 SSE 读取消息（同一时刻只允许一个）
+	reading atomic.Bool
+	// lastActive 最近一次发送或收到消息的时间（UnixNano）
+	lastActive atomic.Int64
+	// closeOnce 保证上游流只关闭一次
+	closeOnce sync.Once
+}
+
+// close 取消上游流
+func (s *openStream) close() {
+	s.closeOnce.Do(s.session.Close)
+}
+
+// touch 记录流的活动，推迟空闲超时
+func (s *openStream) touch() {
+	s.lastActive.Store(time.Now().UnixNano())
+}
+
+// WithBidiStreaming 启用 /streams 端点，桥接客户端流和双向流方法
+func WithBidiStreaming(cfg config.BidiStreamingConfig) HandlerOption {
+	return func(h *Handler) {
+		if cfg.Enabled {
+			h.streams = &streamRegistry{cfg: cfg, streams: make(map[string]*openStream)}
+		}
+	}
+}
+
+// add 登记新打开的流并开始空闲检测；达到上限时返回 checkLimits 的错误
+func (reg *streamRegistry) add(s *openStream) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if err := reg.checkLimitsLocked(s.sessionID); err != nil {
+		return err
+	}
+	s.touch()
+	reg.streams[s.id] = s
+	go reg.expire(s)
+	return nil
+}
+
+// checkLimits 判断会话能否再打开一个流：所有会话的流达到 max_streams 时返回
+// errTooManyStreams，会话的流达到 max_streams_per_session 时返回 errTooManySessionStreams
+func (reg *streamRegistry) checkLimits(sessionID string) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	return reg.checkLimitsLocked(sessionID)
+}
+
+// checkLimitsLocked 同 checkLimits，调用方持有 reg.mu
+func (reg *streamRegistry) checkLimitsLocked(sessionID string) error {
+	if reg.cfg.MaxStreams > 0 && len(reg.streams) >= reg.cfg.MaxStreams {
+		return errTooManyStreams
+	}
+	if reg.cfg.MaxStreamsPerSession > 0 {
+		open := 0
+		for _, s := range reg.streams {
+			if s.sessionID == sessionID {
+				open++
+			}
+		}
+		if open >= reg.cfg.MaxStreamsPerSession {
+			return errTooManySessionStreams
+		}
+	}
+	return nil
+}
+
+// get 返回会话的流，其他会话的流按不存在处理
+func (reg *streamRegistry) get(id, sessionID string) (*openStream, bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	s, ok := reg.streams[id]
+	if !ok || s.sessionID != sessionID {
+		return nil, false
+	}
+	return s, true
+}
+
+// remove 取消并移除流
+func (reg *streamRegistry) remove(s *openStream) {
+	s.close()
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if reg.streams[s.id] == s {
+		delete(reg.streams, s.id)
+	}
+}
+
+// closeSession 取消并移除会话的所有流
+func (reg *streamRegistry) closeSession(sessionID string) {
+	reg.mu.Lock()
+	var closing []*openStream
+	for id, s := range reg.streams {
+		if s.sessionID == sessionID {
+			closing = append(closing, s)
+			delete(reg.streams, id)
+		}
+	}
+	reg.mu.Unlock()
+	for _, s := range closing {
+		s.close()
+	}
+}
+
+// expire 在流空闲（没有读取者且 idle_timeout 内没有消息）时取消并移除它
+func (reg *streamRegistry) expire(s *openStream) {
+	timeout := reg.cfg.IdleTimeout
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for range timer.C {
+		reg.mu.Lock()
+		current := reg.streams[s.id] == s
+		reg.mu.Unlock()
+		if !current {
+			return
+		}
+		idle := time.Since(time.Unix(0, s.lastActive.Load()))
+		if s.reading.Load() || idle < timeout {
+			timer.Reset(max(timeout-idle, time.Second))
+			continue
+		}
+		reg.remove(s)
+		return
+	}
+}
+
+// newStreamID 生成随机的流 ID
+func newStreamID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("stream_%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// StreamsHandler 处理客户端流和双向流方法的流式桥接
+//
+//	POST   /streams             {"name": "<tool>"} 打开流，返回 streamId
+//	GET    /streams/{id}        以 SSE 读取收到的消息，流结束时发送 end 或 error 事件
+//	POST   /streams/{id}        发送一条消息（请求体为消息的 JSON）
+//	POST   /streams/{id}/close  关闭发送方向，上游随后结束流
+//	DELETE /streams/{id}        取消流
+//
+// 所有请求都需要 Mcp-Session-Id，流只对打开它的会话可见。打开流和发送消息
+// 与 tools/call 经过相同的检查：别名、会话作用域、会话并发上限、token、网关过载保护、
+// 参数清理与默认/固定参数，并写入审计日志。
+func (h *Handler) StreamsHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.authenticate(w, r) {
+		return
+	}
+	if h.streams == nil {
+		http.Error(w, "Bidirectional streaming is not enabled", http.StatusNotImplemented)
+		return
+	}
+	opener, ok := grpc.Find[grpc.StreamOpener](h.serviceDiscoverer)
+	if !ok {
+		http.Error(w, "Bidirectional streaming is not supported", http.StatusNotImplemented)
+		return
+	}
+	sessionCtx, ok := h.sessionManager.GetSession(r.Header.Get("Mcp-Session-Id"))
+	if !ok {
+		http.Error(w, "A valid Mcp-Session-Id header is required", http.StatusBadRequest)
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, streamsPath), "/")
+	if rest == "" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.openStream(w, r, opener, sessionCtx)
+		return
+	}
+
+	id, action, _ := strings.Cut(rest, "/")
+	s, ok := h.streams.get(id, sessionCtx.ID)
+	if !ok {
+		http.Error(w, "Stream not found", http.StatusNotFound)
+		return
+	}
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.readStream(w, r, s)
+	case action == "" && r.Method == http.MethodPost:
+		h.sendToStream(w, r, s, sessionCtx)
+	case action == "" && r.Method == http.MethodDelete:
+		h.streams.remove(s)
+		w.WriteHeader(http.StatusNoContent)
+	case action == "close" && r.Method == http.MethodPost:
+		s.session.CloseSend()
+		w.WriteHeader(http.StatusAccepted)
+	case action == "" || action == "close":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// openStream 为会话可见的客户端流或双向流工具打开一个流
+func (h *Handler) openStream(w http.ResponseWriter, r *http.Request, opener grpc.StreamOpener, sessionCtx *session.Context) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		http.Error(w, "Request body must be {\"name\": \"<tool>\"}", http.StatusBadRequest)
+		return
+	}
+
+	// 🔐 别名解析为原始名称；会话作用域之外的工具按不存在处理
+	toolName := h.resolveToolName(req.Name)
+	if rejection := h.checkToolScope(toolName, sessionCtx); rejection != nil {
+		writeStreamRejection(w, rejection)
+		return
+	}
+	method, ok := h.serviceDiscoverer.GetMethod(toolName)
+	if !ok {
+		http.Error(w, fmt.Sprintf("tool %s not found", req.Name), http.StatusNotFound)
+		return
+	}
+	if !method.IsClientStreaming {
+		http.Error(w, fmt.Sprintf("tool %s is not a client-streaming or bidirectional method, call it with tools/call", req.Name), http.StatusBadRequest)
+		return
+	}
+	if err := h.streams.checkLimits(sessionCtx.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+
+	// 🚦 与 tools/call 相同的准入检查，覆盖打开上游流的过程
+	filteredHeaders, release, rejection, err := h.admitToolCall(r.Context(), toolName, sessionCtx)
+	if err != nil {
+		h.writeStreamError(w, toolName, sessionCtx, err)
+		return
+	}
+	if rejection != nil {
+		writeStreamRejection(w, rejection)
+		return
+	}
+	defer release()
+
+	// 流的生命周期超出打开它的请求，由空闲超时或 DELETE 结束
+	ctx := session.NewContextWithID(context.Background(), sessionCtx.ID)
+	ctx, _ = h.injectSessionMetadata(ctx, toolName, nil, sessionCtx)
+	started := time.Now()
+	streamSession, err := opener.OpenStream(ctx, filteredHeaders, toolName)
+	h.auditCall(r.Context(), sessionCtx.ID, toolName, "", started, err)
+	if err != nil {
+		h.logger.Warn("Failed to open stream",
+			zap.String("toolName", toolName),
+			zap.String("sessionId", sessionCtx.ID),
+			zap.Error(err))
+		http.Error(w, mcp.SanitizeErrorWithDetail(err, h.errorDetail), http.StatusBadGateway)
+		return
+	}
+	s := &openStream{id: newStreamID(), sessionID: sessionCtx.ID, tool: toolName, session: streamSession}
+	if err := h.streams.add(s); err != nil {
+		s.close()
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+
+	h.logger.Info("Stream opened",
+		zap.String("toolName", toolName),
+		zap.String("streamId", s.id),
+		zap.String("sessionId", sessionCtx.ID))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", streamsPath+"/"+s.id)
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"streamId":        s.id,
+		"tool":            s.tool,
+		"serverStreaming": method.IsServerStreaming,
+	})
+}
+
+// readStream 以 SSE 发送流收到的消息，流结束后发送 end 或 error 事件并移除流
+//
+// 客户端断开时流保持打开，未读取的消息留在缓冲区中，可以重新连接继续读取。
+func (h *Handler) readStream(w http.ResponseWriter, r *http.Request, s *openStream) {
+	if !s.reading.CompareAndSwap(false, true) {
+		http.Error(w, "Stream already has a reader", http.StatusConflict)
+		return
+	}
+	defer s.reading.Store(false)
+
+	controller := http.NewResponseController(w)
+	// 流可能长时间保持打开，不受服务器写超时限制
+	_ = controller.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", eventStreamType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	_ = controller.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case message, ok := <-s.session.Messages():
+			if !ok {
+				event, data := "end", []byte("{}")
+				if err := s.session.Err(); err != nil {
+					event = "error"
+					data, _ = json.Marshal(map[string]string{"error": mcp.SanitizeErrorWithDetail(err, h.errorDetail)})
+				}
+				_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+				_ = controller.Flush()
+				h.streams.remove(s)
+				return
+			}
+			s.touch()
+			if _, err := fmt.Fprintf(w, "event: message\ndata: %s\n\n", message); err != nil {
+				return
+			}
+			if err := controller.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// sendToStream 把请求体作为一条消息发送到流中
+//
+// 消息与 tools/call 的参数一样经过准入检查和参数处理，并写入审计日志。
+func (h *Handler) sendToStream(w http.ResponseWriter, r *http.Request, s *openStream, sessionCtx *session.Context) {
+	body := io.Reader(r.Body)
+	if limit := h.inputLimit(s.tool); limit > 0 {
+		body = io.LimitReader(r.Body, int64(limit)+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, "Failed to read message", http.StatusBadRequest)
+		return
+	}
+	if limit := h.inputLimit(s.tool); limit > 0 && len(data) > limit {
+		http.Error(w, fmt.Sprintf("message exceeds the %d byte limit for tool %s", limit, s.tool), http.StatusRequestEntityTooLarge)
+		return
+	}
+	var message interface{}
+	if err := json.Unmarshal(data, &message); err != nil {
+		http.Error(w, "Message must be JSON", http.StatusBadRequest)
+		return
+	}
+
+	filteredHeaders, release, rejection, err := h.admitToolCall(r.Context(), s.tool, sessionCtx)
+	if err != nil {
+		h.writeStreamError(w, s.tool, sessionCtx, err)
+		return
+	}
+	if rejection != nil {
+		writeStreamRejection(w, rejection)
+		return
+	}
+	defer release()
+	_, prepared, rejection, err := h.prepareArguments(r.Context(), s.tool, message, filteredHeaders, sessionCtx)
+	if err != nil {
+		h.writeStreamError(w, s.tool, sessionCtx, err)
+		return
+	}
+	if rejection != nil {
+		writeStreamRejection(w, rejection)
+		return
+	}
+
+	s.touch()
+	started := time.Now()
+	err = s.session.Send(r.Context(), prepared.json)
+	h.auditCall(r.Context(), sessionCtx.ID, s.tool, prepared.json, started, err)
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusAccepted)
+	case errors.Is(err, grpc.ErrStreamClosed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case r.Context().Err() != nil:
+		// 客户端已断开
+	default:
+		http.Error(w, mcp.SanitizeErrorWithDetail(err, h.errorDetail), http.StatusBadRequest)
+	}
+}
+
+// writeStreamRejection 以 HTTP 状态码返回被拒绝的流请求
+func writeStreamRejection(w http.ResponseWriter, rejection *toolRejection) {
+	if seconds, ok := rejection.meta[retryAfterMetaKey].(int); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	http.Error(w, rejection.message, rejection.status)
+}
+
+// writeStreamError 返回流请求的错误：网关过载返回 503 和重试时间，其他错误只记录在日志中
+func (h *Handler) writeStreamError(w http.ResponseWriter, toolName string, sessionCtx *session.Context, err error) {
+	var overloaded *overloadedError
+	if errors.As(err, &overloaded) {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(overloaded.retryAfter)))
+		http.Error(w, overloaded.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	errorID := newErrorID()
+	h.logger.Error("Stream request failed",
+		zap.String("toolName", toolName),
+		zap.String("sessionId", sessionCtx.ID),
+		zap.String("errorId", errorID),
+		zap.Error(err))
+	http.Error(w, withErrorID("Internal error", errorID), http.StatusInternalServerError)
+}
@@ -0,0 +1,250 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/arguments"
+	"github.com/aalobaidi/ggRMCP/pkg/audit"
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"github.com/aalobaidi/ggRMCP/pkg/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	grpcLib "google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// startChatUpstream serves chattest.Chat/Talk, a bidirectional method
+// answering every message with "echo: <message>"
+func startChatUpstream(t *testing.T) grpc.ServiceDiscoverer {
+	t.Helper()
+
+	if _, err := protoregistry.GlobalFiles.FindDescriptorByName("chattest.Chat"); err != nil {
+		fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+			Name:       proto.String("test/chattest.proto"),
+			Package:    proto.String("chattest"),
+			Dependency: []string{"google/protobuf/wrappers.proto"},
+			Syntax:     proto.String("proto3"),
+			Service: []*descriptorpb.ServiceDescriptorProto{{
+				Name: proto.String("Chat"),
+				Method: []*descriptorpb.MethodDescriptorProto{{
+					Name:            proto.String("Talk"),
+					InputType:       proto.String(".google.protobuf.StringValue"),
+					OutputType:      proto.String(".google.protobuf.StringValue"),
+					ClientStreaming: proto.Bool(true),
+					ServerStreaming: proto.Bool(true),
+				}},
+			}},
+		}, protoregistry.GlobalFiles)
+		require.NoError(t, err)
+		require.NoError(t, protoregistry.GlobalFiles.RegisterFile(fd))
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpcLib.NewServer()
+	srv.RegisterService(&grpcLib.ServiceDesc{
+		ServiceName: "chattest.Chat",
+		HandlerType: (*interface{})(nil),
+		Streams: []grpcLib.StreamDesc{{
+			StreamName:    "Talk",
+			ClientStreams: true,
+			ServerStreams: true,
+			Handler: func(_ interface{}, stream grpcLib.ServerStream) error {
+				for {
+					in := &wrapperspb.StringValue{}
+					if err := stream.RecvMsg(in); errors.Is(err, io.EOF) {
+						return nil
+					} else if err != nil {
+						return err
+					}
+					if err := stream.SendMsg(wrapperspb.String("echo: " + in.GetValue())); err != nil {
+						return err
+					}
+				}
+			},
+		}},
+	}, struct{}{})
+	reflection.Register(srv)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	sd, err := grpc.NewServiceDiscoverer("127.0.0.1", lis.Addr().(*net.TCPAddr).Port, zap.NewNop(), config.DescriptorSetConfig{},
+		grpc.WithBidiStreaming(config.BidiStreamingConfig{Enabled: true}))
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, sd.Connect(ctx))
+	require.NoError(t, sd.DiscoverServices(ctx))
+	t.Cleanup(func() { _ = sd.Close() })
+	return sd
+}
+
+func TestStreamsHandler(t *testing.T) {
+	const tool = "chattest_chat_talk"
+	logger := zap.NewNop()
+	sessionManager := session.NewManager(logger)
+	t.Cleanup(func() { _ = sessionManager.Close() })
+	handler := NewHandler(logger, startChatUpstream(t), sessionManager, tools.NewMCPToolBuilder(logger), config.Default().GRPC.HeaderForwarding,
+		WithBidiStreaming(config.BidiStreamingConfig{Enabled: true, MaxStreams: 1, Buffer: 4, IdleTimeout: time.Minute}))
+	server := httptest.NewServer(http.HandlerFunc(handler.StreamsHandler))
+	t.Cleanup(server.Close)
+	sessionID := sessionManager.CreateSession(nil).ID
+
+	request := func(method, path, sessionID, body string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Mcp-Session-Id", sessionID)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	// Streams require a session and a streaming tool
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/streams", "", `{"name":"`+tool+`"}`).StatusCode)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPost, "/streams", sessionID, `{"name":"missing"}`).StatusCode)
+
+	resp := request(http.MethodPost, "/streams", sessionID, `{"name":"`+tool+`"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var opened struct {
+		StreamID        string `json:"streamId"`
+		ServerStreaming bool   `json:"serverStreaming"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&opened))
+	assert.True(t, opened.ServerStreaming)
+	path := "/streams/" + opened.StreamID
+
+	// max_streams is 1
+	assert.Equal(t, http.StatusTooManyRequests, request(http.MethodPost, "/streams", sessionID, `{"name":"`+tool+`"}`).StatusCode)
+	// Other sessions cannot see the stream
+	other := sessionManager.CreateSession(nil).ID
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPost, path, other, `"a"`).StatusCode)
+
+	assert.Equal(t, http.StatusAccepted, request(http.MethodPost, path, sessionID, `"a"`).StatusCode)
+	assert.Equal(t, http.StatusAccepted, request(http.MethodPost, path, sessionID, `"b"`).StatusCode)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, path, sessionID, `{"bad":`).StatusCode)
+	assert.Equal(t, http.StatusAccepted, request(http.MethodPost, path+"/close", sessionID, "").StatusCode)
+	assert.Equal(t, http.StatusConflict, request(http.MethodPost, path, sessionID, `"c"`).StatusCode)
+
+	// The replies arrive as server-sent events, followed by an end event
+	resp = request(http.MethodGet, path, sessionID, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	var events []string
+	scanner := bufio.NewScanner(resp.Body)
+	var event string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			event = name
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			events = append(events, event+" "+data)
+		}
+	}
+	assert.Equal(t, []string{`message "echo: a"`, `message "echo: b"`, "end {}"}, events)
+
+	// The finished stream is removed, making room for a new one
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, path, sessionID, "").StatusCode)
+	resp = request(http.MethodPost, "/streams", sessionID, `{"name":"`+tool+`"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&opened))
+	assert.Equal(t, http.StatusNoContent, request(http.MethodDelete, "/streams/"+opened.StreamID, sessionID, "").StatusCode)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodDelete, "/streams/"+opened.StreamID, sessionID, "").StatusCode)
+}
+
+// rejectObjects refuses every tool call whose arguments are an object
+type rejectObjects struct{}
+
+func (rejectObjects) Sanitize(string, map[string]interface{}) (map[string]interface{}, error) {
+	return nil, fmt.Errorf("%w: objects are not allowed", arguments.ErrRejected)
+}
+
+func TestStreamsHandler_ToolCallChecks(t *testing.T) {
+	const tool = "chattest_chat_talk"
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := audit.Open(config.AuditConfig{Enabled: true, Path: path, Chain: true})
+	require.NoError(t, err)
+	defer func() { _ = auditLog.Close() }()
+
+	logger := zap.NewNop()
+	sessionManager := session.NewManager(logger)
+	t.Cleanup(func() { _ = sessionManager.Close() })
+	handler := NewHandler(logger, startChatUpstream(t), sessionManager, tools.NewMCPToolBuilder(logger), config.Default().GRPC.HeaderForwarding,
+		WithBidiStreaming(config.BidiStreamingConfig{Enabled: true, MaxStreams: 4, MaxStreamsPerSession: 1, Buffer: 4, IdleTimeout: time.Minute}),
+		WithToolAliases([]config.ToolAliasConfig{{Tool: tool, Alias: "talk"}}),
+		WithArgumentSanitizers(rejectObjects{}),
+		WithAuditLog(auditLog))
+	server := httptest.NewServer(http.HandlerFunc(handler.StreamsHandler))
+	t.Cleanup(server.Close)
+	sessionID := sessionManager.CreateSession(nil).ID
+
+	request := func(method, path, sessionID, body string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Mcp-Session-Id", sessionID)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	// Aliases resolve to the tool they name
+	resp := request(http.MethodPost, "/streams", sessionID, `{"name":"talk"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var opened struct {
+		StreamID string `json:"streamId"`
+		Tool     string `json:"tool"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&opened))
+	assert.Equal(t, tool, opened.Tool)
+
+	// max_streams_per_session is 1; other sessions can still open streams
+	assert.Equal(t, http.StatusTooManyRequests, request(http.MethodPost, "/streams", sessionID, `{"name":"talk"}`).StatusCode)
+	other := sessionManager.CreateSession(nil).ID
+	assert.Equal(t, http.StatusCreated, request(http.MethodPost, "/streams", other, `{"name":"talk"}`).StatusCode)
+
+	// Messages go through the argument sanitizers
+	streamPath := "/streams/" + opened.StreamID
+	assert.Equal(t, http.StatusAccepted, request(http.MethodPost, streamPath, sessionID, `"a"`).StatusCode)
+	resp = request(http.MethodPost, streamPath, sessionID, `{"value":"b"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "objects are not allowed")
+
+	// Opening streams and sending messages is audited; rejected messages never reach the upstream
+	count, err := audit.Verify(path, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestStreamsHandler_Disabled(t *testing.T) {
+	handler, _ := newTestHandler(t, &mockServiceDiscoverer{})
+	req := httptest.NewRequest(http.MethodPost, "/streams", strings.NewReader(`{"name":"x"}`))
+	w := httptest.NewRecorder()
+	handler.StreamsHandler(w, req)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
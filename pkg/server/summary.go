@@ -0,0 +1,159 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"github.com/aalobaidi/ggRMCP/pkg/summary"
+	"go.uber.org/zap"
+)
+
+const (
+	// summaryMetaKey 工具调用结果 _meta 中记录摘要信息的键
+	summaryMetaKey = "ggrmcp/summary"
+	// resultsResourcePrefix 完整结果作为 MCP 资源时的 URI 前缀
+	resultsResourcePrefix = "ggrmcp://results/"
+)
+
+// WithResultSummary 设置超大结果的摘要（默认不摘要）
+func WithResultSummary(cfg config.ToolSummaryConfig) HandlerOption {
+	return func(h *Handler) {
+		h.summary = cfg
+		if cfg.Enabled && cfg.KeepResults > 0 {
+			h.results = newResultStore(cfg.KeepResults)
+		}
+	}
+}
+
+// WithSummarizer 替换默认的摘要方式（保留每个列表的前 max_items 项）
+func WithSummarizer(summarizer summary.Summarizer) HandlerOption {
+	return func(h *Handler) {
+		h.summarizer = summarizer
+	}
+}
+
+// resultStore 保存被摘要的完整结果，按保存顺序淘汰最旧的结果
+//
+// 结果按会话保存，只有产生结果的会话能读取。
+type resultStore struct {
+	max int
+
+	mu      sync.Mutex
+	results map[resultKey]string
+	order   []resultKey
+}
+
+// resultKey 标识一个会话保存的结果
+type resultKey struct {
+	sessionID string
+	uri       string
+}
+
+// newResultStore 创建最多保存 max 个结果的存储
+func newResultStore(max int) *resultStore {
+	return &resultStore{max: max, results: make(map[resultKey]string)}
+}
+
+// put 保存会话的结果并返回其资源 URI，无法生成随机 ID 时返回错误
+func (s *resultStore) put(sessionID, result string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate result ID: %w", err)
+	}
+	key := resultKey{sessionID: sessionID, uri: resultsResourcePrefix + hex.EncodeToString(b)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[key] = result
+	s.order = append(s.order, key)
+	for len(s.order) > s.max {
+		delete(s.results, s.order[0])
+		s.order = s.order[1:]
+	}
+	return key.uri, nil
+}
+
+// get 返回会话保存的结果
+func (s *resultStore) get(sessionID, uri string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result, ok := s.results[resultKey{sessionID: sessionID, uri: uri}]
+	return result, ok
+}
+
+// summarizeResult 结果超过字节预算时返回摘要
+//
+// 返回摘要、需要合并到结果 _meta 中的摘要信息，以及完整结果的资源 URI
+// （不保存完整结果时为空）。无法缩短的结果原样返回。
+func (h *Handler) summarizeResult(toolName, output string, sessionCtx *session.Context) (string, map[string]interface{}, string) {
+	if !h.summary.Enabled || len(output) <= h.summary.MaxBytes {
+		return output, nil, ""
+	}
+	value, err := decodeJSON(output)
+	if err != nil {
+		return output, nil, ""
+	}
+
+	summarizer := h.summarizer
+	if summarizer == nil {
+		summarizer = summary.Lists{MaxItems: h.summary.MaxItems}
+	}
+	shortened, omissions := summarizer.Summarize(toolName, value)
+	if omissions.Empty() {
+		return output, nil, ""
+	}
+	encoded, err := encodeJSON(shortened)
+	if err != nil {
+		h.logger.Debug("Failed to encode result summary, returning the full result",
+			zap.String("toolName", toolName),
+			zap.Error(err))
+		return output, nil, ""
+	}
+
+	info := map[string]interface{}{
+		"originalBytes": len(output),
+		"lists":         omissions.Lists,
+	}
+	var uri string
+	if h.results != nil {
+		uri, err = h.results.put(sessionCtx.ID, output)
+		if err != nil {
+			h.logger.Warn("Failed to keep the full result, returning only the summary",
+				zap.String("toolName", toolName),
+				zap.Error(err))
+		} else {
+			info["fullResult"] = uri
+		}
+	}
+	h.logger.Debug("Summarized tool result",
+		zap.String("toolName", toolName),
+		zap.Int("originalBytes", len(output)),
+		zap.Int("summaryBytes", len(encoded)))
+	return encoded, map[string]interface{}{summaryMetaKey: info}, uri
+}
+
+// readStoredResult 处理对完整结果资源的 resources/read 请求，只返回会话自己的结果
+func (h *Handler) readStoredResult(uri string, sessionCtx *session.Context) (*mcp.ReadResourceResult, bool) {
+	if !strings.HasPrefix(uri, resultsResourcePrefix) || sessionCtx == nil {
+		return nil, false
+	}
+	result, ok := h.results.get(sessionCtx.ID, uri)
+	if !ok {
+		return nil, false
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []mcp.ResourceContents{{
+			URI:      uri,
+			MimeType: "application/json",
+			Text:     result,
+		}},
+	}, true
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/summary"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// firstItem 只保留 items 的第一项的摘要方式
+type firstItem struct{}
+
+func (firstItem) Summarize(toolName string, result interface{}) (interface{}, summary.Omissions) {
+	items := result.(map[string]interface{})["items"].([]interface{})
+	return map[string]interface{}{"first": items[0]}, summary.Omissions{Lists: []summary.List{{Path: "items", Total: len(items), Returned: 1}}}
+}
+
+func TestResultSummary(t *testing.T) {
+	list := testMethod("orders.OrderService", "List")
+	items := make([]string, 50)
+	for i := range items {
+		items[i] = `"order-` + strings.Repeat("x", 10) + `"`
+	}
+	full := `{"items":[` + strings.Join(items, ",") + `]}`
+
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{list})
+	discoverer.On("GetMethod", list.ToolName).Return(list, true)
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, list.ToolName, mock.Anything).Return(full, nil)
+	cfg := config.Default().Tools.Summary
+	cfg.Enabled = true
+	cfg.MaxBytes = 200
+	cfg.MaxItems = 3
+	handler, _ := newTestHandler(t, discoverer, WithResultSummary(cfg))
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	call := func() mcp.ToolCallResult {
+		resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": list.ToolName}, nil)
+		require.Nil(t, resp.Error)
+		data, err := json.Marshal(resp.Result)
+		require.NoError(t, err)
+		var result mcp.ToolCallResult
+		require.NoError(t, json.Unmarshal(data, &result))
+		return result
+	}
+
+	result := call()
+	require.Len(t, result.Content, 2)
+	var summarized map[string][]interface{}
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].Text), &summarized))
+	assert.Len(t, summarized["items"], 3)
+	info := result.Meta[summaryMetaKey].(map[string]interface{})
+	assert.Equal(t, float64(len(full)), info["originalBytes"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"path": "items", "total": float64(50), "returned": float64(3)}}, info["lists"])
+
+	// The full result can be read through the linked resource
+	link := result.Content[1]
+	assert.Equal(t, mcp.ContentTypeResourceLink, link.Type)
+	assert.Equal(t, info["fullResult"], link.URI)
+	resp, _ := postRPC(t, handler, "/", sessionID, "resources/read", map[string]interface{}{"uri": link.URI}, nil)
+	require.Nil(t, resp.Error)
+	data, err := json.Marshal(resp.Result)
+	require.NoError(t, err)
+	var read mcp.ReadResourceResult
+	require.NoError(t, json.Unmarshal(data, &read))
+	assert.Equal(t, full, read.Contents[0].Text)
+
+	// Other sessions cannot read the full result
+	_, otherSession := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	resp, _ = postRPC(t, handler, "/", otherSession, "resources/read", map[string]interface{}{"uri": link.URI}, nil)
+	require.NotNil(t, resp.Error)
+
+	// A custom summarizer replaces the default one
+	WithSummarizer(firstItem{})(handler)
+	result = call()
+	assert.JSONEq(t, `{"first":"order-xxxxxxxxxx"}`, result.Content[0].Text)
+
+	// Results within the budget are returned in full
+	handler.summary.MaxBytes = len(full)
+	result = call()
+	assert.Equal(t, full, result.Content[0].Text)
+	assert.Nil(t, result.Meta)
+}
+
+func TestResultStore_KeepsLatest(t *testing.T) {
+	store := newResultStore(2)
+	put := func(result string) string {
+		uri, err := store.put("session-a", result)
+		require.NoError(t, err)
+		return uri
+	}
+	first := put("1")
+	second := put("2")
+	third := put("3")
+
+	_, ok := store.get("session-a", first)
+	assert.False(t, ok)
+	result, ok := store.get("session-a", second)
+	assert.True(t, ok)
+	assert.Equal(t, "2", result)
+	result, _ = store.get("session-a", third)
+	assert.Equal(t, "3", result)
+
+	// Other sessions cannot read the results
+	_, ok = store.get("session-b", third)
+	assert.False(t, ok)
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/telemetry"
+)
+
+// WithMetrics 把网关的指标发送到嵌入程序提供的导出器（默认丢弃）
+func WithMetrics(metrics telemetry.Metrics) HandlerOption {
+	return func(h *Handler) {
+		if metrics != nil {
+			h.metrics = metrics
+		}
+	}
+}
+
+// WithTracer 用嵌入程序提供的 Tracer 为请求和工具调用创建 span（默认不追踪）
+func WithTracer(tracer telemetry.Tracer) HandlerOption {
+	return func(h *Handler) {
+		if tracer != nil {
+			h.tracer = tracer
+		}
+	}
+}
+
+// callOutcome 返回调用结果的 outcome 标签，客户端断开的调用为 canceled
+func callOutcome(ctx context.Context, err error) string {
+	switch {
+	case err != nil && errors.Is(ctx.Err(), context.Canceled):
+		return telemetry.OutcomeCanceled
+	case err != nil:
+		return telemetry.OutcomeError
+	default:
+		return telemetry.OutcomeOK
+	}
+}
+
+// emitToolCall 结束工具调用的 span 并发送调用次数和耗时
+func (h *Handler) emitToolCall(ctx context.Context, span telemetry.Span, toolName string, started time.Time, err error) {
+	tags := telemetry.Tags{"tool": toolName, "outcome": callOutcome(ctx, err)}
+	h.metrics.Count(telemetry.MetricToolCalls, 1, tags)
+	h.metrics.Observe(telemetry.MetricToolCallDuration, float64(time.Since(started).Microseconds())/1000, tags)
+	span.SetTag("outcome", tags["outcome"])
+	span.End(err)
+}
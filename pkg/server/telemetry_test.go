@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/telemetry"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTelemetry keeps the metrics and finished spans it receives
+type recordingTelemetry struct {
+	mu       sync.Mutex
+	counts   map[string]int64
+	observed []string
+	spans    []recordedSpan
+}
+
+type recordedSpan struct {
+	name   string
+	parent string
+	tags   telemetry.Tags
+	err    error
+}
+
+type spanKey struct{}
+
+func (r *recordingTelemetry) Count(name string, delta int64, tags telemetry.Tags) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counts[name+tagString(tags)] += delta
+}
+
+func (r *recordingTelemetry) Observe(name string, value float64, tags telemetry.Tags) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observed = append(r.observed, name+tagString(tags))
+}
+
+func (r *recordingTelemetry) Start(ctx context.Context, name string, tags telemetry.Tags) (context.Context, telemetry.Span) {
+	parent, _ := ctx.Value(spanKey{}).(string)
+	span := &recordingSpan{recorder: r, span: recordedSpan{name: name, parent: parent, tags: tags}}
+	return context.WithValue(ctx, spanKey{}, name), span
+}
+
+type recordingSpan struct {
+	recorder *recordingTelemetry
+	span     recordedSpan
+}
+
+func (s *recordingSpan) SetTag(key, value string) {
+	s.span.tags[key] = value
+}
+
+func (s *recordingSpan) End(err error) {
+	s.span.err = err
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.recorder.spans = append(s.recorder.spans, s.span)
+}
+
+// tagString renders the tags used in these tests in a fixed order
+func tagString(tags telemetry.Tags) string {
+	var s string
+	for _, key := range []string{"method", "tool", "outcome"} {
+		if value, ok := tags[key]; ok {
+			s += " " + key + "=" + value
+		}
+	}
+	return s
+}
+
+func TestTelemetryExporters(t *testing.T) {
+	method := testMethod("echo.service", "Say")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethods").Return([]types.MethodInfo{method}).Maybe()
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, `{"value":"hi"}`).Return(`{"value":"hi"}`, nil).Once()
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, `{"value":"boom"}`).Return("", errors.New("upstream failed")).Once()
+	recorder := &recordingTelemetry{counts: make(map[string]int64)}
+	handler, _ := newTestHandler(t, discoverer, WithMetrics(recorder), WithTracer(recorder))
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	for _, value := range []string{"hi", "boom"} {
+		resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{
+			"name":      method.ToolName,
+			"arguments": map[string]interface{}{"value": value},
+		}, nil)
+		require.Nil(t, resp.Error)
+	}
+	resp, _ := postRPC(t, handler, "/", sessionID, "no/such/method", nil, nil)
+	require.NotNil(t, resp.Error)
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	assert.Equal(t, map[string]int64{
+		"ggrmcp.requests method=initialize outcome=ok":                 1,
+		"ggrmcp.requests method=tools/call outcome=ok":                 2,
+		"ggrmcp.requests method=unknown outcome=error":                 1,
+		"ggrmcp.tool_calls tool=" + method.ToolName + " outcome=ok":    1,
+		"ggrmcp.tool_calls tool=" + method.ToolName + " outcome=error": 1,
+	}, recorder.counts)
+	assert.Equal(t, []string{
+		"ggrmcp.tool_call.duration_ms tool=" + method.ToolName + " outcome=ok",
+		"ggrmcp.tool_call.duration_ms tool=" + method.ToolName + " outcome=error",
+	}, recorder.observed)
+
+	// Tool call spans are children of their request span and carry the upstream error
+	require.Len(t, recorder.spans, 6)
+	failed := recorder.spans[3]
+	assert.Equal(t, telemetry.SpanToolCall, failed.name)
+	assert.Equal(t, telemetry.SpanRequest, failed.parent)
+	assert.Equal(t, sessionID, failed.tags["session"])
+	assert.EqualError(t, failed.err, "upstream failed")
+	assert.Equal(t, telemetry.SpanRequest, recorder.spans[4].name)
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// textFormatMetaKey 工具调用结果 _meta 中记录文本块格式的键（文本块不是 JSON 时设置）
+const textFormatMetaKey = "ggrmcp/textFormat"
+
+// textFormat 返回工具结果文本块的格式，后面的规则覆盖前面的规则；没有规则匹配时为空
+func (h *Handler) textFormat(toolName string) string {
+	format := ""
+	for _, rule := range h.output.TextFormats {
+		if rule.Tool == toolName {
+			format = rule.Format
+			continue
+		}
+		if matched, err := path.Match(rule.Tool, toolName); err == nil && matched {
+			format = rule.Format
+		}
+	}
+	return format
+}
+
+// formatText 按工具配置的格式转换结果 JSON
+//
+// 返回转换后的文本和实际使用的格式；不需要转换或无法转换时（例如不是表格的
+// 结果配置了 csv）返回原 JSON 和空格式。toolName 为空（内置工具）时不转换。
+func (h *Handler) formatText(toolName, output string) (string, string) {
+	if toolName == "" {
+		return output, ""
+	}
+	format := h.textFormat(toolName)
+	if format == "" || format == config.TextFormatJSON {
+		return output, ""
+	}
+	value, err := decodeJSON(output)
+	if err != nil {
+		return output, ""
+	}
+
+	var text string
+	switch format {
+	case config.TextFormatPretty:
+		text, err = prettyJSON(value)
+	case config.TextFormatYAML:
+		text, err = yamlText(value)
+	case config.TextFormatCSV:
+		text, err = csvText(value)
+	default:
+		err = fmt.Errorf("unknown text format %s", format)
+	}
+	if err != nil {
+		h.logger.Debug("Returning tool result as JSON",
+			zap.String("toolName", toolName),
+			zap.String("format", format),
+			zap.Error(err))
+		return output, ""
+	}
+	return text, format
+}
+
+// prettyJSON 以两个空格缩进编码 JSON
+func prettyJSON(value interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))), nil
+}
+
+// yamlText 将 JSON 值编码为 YAML，数字保持为数字
+func yamlText(value interface{}) (string, error) {
+	data, err := yaml.Marshal(yamlValue(value))
+	if err != nil {
+		return "", err
+	}
+	return string(bytes.TrimSuffix(data, []byte("\n"))), nil
+}
+
+// yamlValue 将 json.Number 转换为 int64 或 float64，否则 YAML 会把它们当作字符串加引号
+func yamlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = yamlValue(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = yamlValue(item)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	}
+	return value
+}
+
+// csvText 将表格结果编码为 CSV：第一行是列名（所有行的字段并集，按字典序），
+// 嵌套的对象和列表以 JSON 写入单元格
+func csvText(value interface{}) (string, error) {
+	rows, ok := tableRows(value)
+	if !ok {
+		return "", fmt.Errorf("result is not a list of messages")
+	}
+
+	seen := make(map[string]bool)
+	var columns []string
+	for _, row := range rows {
+		for key := range row {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	_ = writer.Write(columns)
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			cell, err := csvCell(row[column])
+			if err != nil {
+				return "", err
+			}
+			record[i] = cell
+		}
+		_ = writer.Write(record)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", err
+	}
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))), nil
+}
+
+// tableRows 返回结果中的表格行：结果本身是对象列表，或结果对象只有一个字段且为对象列表
+// （例如 {"orders": [...]}）。列表为空或包含非对象元素时不是表格。
+func tableRows(value interface{}) ([]map[string]interface{}, bool) {
+	if object, ok := value.(map[string]interface{}); ok && len(object) == 1 {
+		for _, field := range object {
+			value = field
+		}
+	}
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, false
+	}
+	rows := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		if rows[i], ok = item.(map[string]interface{}); !ok {
+			return nil, false
+		}
+	}
+	return rows, true
+}
+
+// csvCell 返回单元格文本：缺失或 null 为空，字符串原样，其他值为 JSON
+func csvCell(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	}
+	return encodeJSON(value)
+}
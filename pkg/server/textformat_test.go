@@ -0,0 +1,62 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolsCall_TextFormats(t *testing.T) {
+	list := `{"orders":[{"id":"1","total":12.5,"tags":["a","b"]},{"id":"2","note":"x, \"y\""}]}`
+	single := `{"id":"1","total":12.5,"paid":true}`
+
+	tests := []struct {
+		name       string
+		format     string
+		upstream   string
+		want       string
+		wantFormat string
+	}{
+		{"JSON", config.TextFormatJSON, single, single, ""},
+		{"Pretty", config.TextFormatPretty, single, "{\n  \"id\": \"1\",\n  \"paid\": true,\n  \"total\": 12.5\n}", config.TextFormatPretty},
+		{"YAML", config.TextFormatYAML, single, "id: \"1\"\npaid: true\ntotal: 12.5", config.TextFormatYAML},
+		{"CSV", config.TextFormatCSV, list, "id,note,tags,total\n1,,\"[\"\"a\"\",\"\"b\"\"]\",12.5\n2,\"x, \"\"y\"\"\",,", config.TextFormatCSV},
+		{"CSV of a non-tabular result stays JSON", config.TextFormatCSV, single, single, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := testMethod("orders.service", "List")
+			other := testMethod("billing.service", "Get")
+			discoverer := &mockServiceDiscoverer{}
+			discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(tt.upstream, nil)
+			handler, _ := newTestHandler(t, discoverer, WithToolOutput(config.ToolOutputConfig{
+				Result: config.ResultBoth,
+				TextFormats: []config.ToolTextFormatConfig{
+					{Tool: "orders_*", Format: config.TextFormatYAML},
+					{Tool: method.ToolName, Format: tt.format},
+				},
+			}))
+
+			_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+			resp, _ := postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": method.ToolName}, nil)
+			require.Nil(t, resp.Error)
+			assert.Equal(t, tt.want, callText(t, resp.Result))
+			result := resp.Result.(map[string]interface{})
+			// structuredContent stays JSON
+			assert.IsType(t, map[string]interface{}{}, result["structuredContent"])
+			if tt.wantFormat != "" {
+				assert.Equal(t, tt.wantFormat, result["_meta"].(map[string]interface{})[textFormatMetaKey])
+			} else {
+				assert.NotContains(t, result, "_meta")
+			}
+
+			// Tools without a matching rule keep the JSON
+			resp, _ = postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": other.ToolName}, nil)
+			assert.Equal(t, tt.upstream, callText(t, resp.Result))
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// retryAfterMetaKey 被限流的工具调用结果中重试等待秒数在 _meta 里的键
+	retryAfterMetaKey = "ggrmcp/retryAfterSeconds"
+	// minRetryAfter 建议的最短重试等待时间
+	minRetryAfter = time.Second
+)
+
+// retryAfterSeconds 将等待时间向上取整为秒，至少 1 秒
+func retryAfterSeconds(d time.Duration) int {
+	if d < minRetryAfter {
+		d = minRetryAfter
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+// limiterDelay 返回限流器下一个令牌可用前需要等待的时间（不消耗令牌）
+func limiterDelay(limiter *rate.Limiter) time.Duration {
+	reservation := limiter.Reserve()
+	defer reservation.Cancel()
+	if !reservation.OK() {
+		return minRetryAfter
+	}
+	return reservation.Delay()
+}
+
+// writeThrottled 返回被限流的请求
+//
+// 同时通过 HTTP 头（Retry-After、X-RateLimit-*）和 JSON-RPC error.data
+// 提供机器可读的重试信息，便于符合规范的 agent 正确退避。
+func writeThrottled(w http.ResponseWriter, id mcp.RequestID, statusCode int, message string, info mcp.RetryInfo) {
+	w.Header().Set("Retry-After", strconv.Itoa(info.RetryAfterSeconds))
+	if info.Limit > 0 {
+		w.Header().Set("X-RateLimit-Limit", strconv.FormatFloat(info.Limit, 'f', -1, 64))
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(info.RetryAfterSeconds))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	_ = json.NewEncoder(w).Encode(&mcp.JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error: &mcp.RPCError{
+			Code:    mcp.ErrorCodeRateLimited,
+			Message: message,
+			Data:    info,
+		},
+	})
+}
+
+// rateChargesKey 请求上下文中限流中间件登记的扣费函数的键
+type rateChargesKey struct{}
+
+// rateCharge 为同一 HTTP 请求中的另一个 JSON-RPC 请求从限流器再扣除一个令牌，
+// 被限流时返回 false 和重试信息
+type rateCharge func(ctx context.Context) (mcp.RetryInfo, bool)
+
+// withRateCharge 在请求上下文中登记限流中间件的扣费函数
+func withRateCharge(r *http.Request, charge rateCharge) *http.Request {
+	charges, _ := r.Context().Value(rateChargesKey{}).([]rateCharge)
+	charges = append(charges[:len(charges):len(charges)], charge)
+	return r.WithContext(context.WithValue(r.Context(), rateChargesKey{}, charges))
+}
+
+// chargeRateLimits 为批量中的一个额外请求向请求经过的每个限流器扣除一个令牌
+func chargeRateLimits(ctx context.Context) (mcp.RetryInfo, bool) {
+	charges, _ := ctx.Value(rateChargesKey{}).([]rateCharge)
+	for _, charge := range charges {
+		if info, ok := charge(ctx); !ok {
+			return info, false
+		}
+	}
+	return mcp.RetryInfo{}, true
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/arguments"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"go.uber.org/zap"
+)
+
+// toolRejection 工具调用在到达上游之前被拒绝
+//
+// tools/call 以工具错误结果返回，/streams 以 status 作为 HTTP 状态码返回。
+type toolRejection struct {
+	status  int
+	message string
+	meta    map[string]interface{}
+}
+
+// result 返回 tools/call 的错误结果
+func (r *toolRejection) result() *mcp.ToolCallResult {
+	return &mcp.ToolCallResult{
+		Content: []mcp.ContentBlock{
+			mcp.TextContent("Error invoking method: " + r.message),
+		},
+		IsError: true,
+		Meta:    r.meta,
+	}
+}
+
+// checkToolScope 会话作用域之外的工具按不存在处理，不暴露其存在性
+func (h *Handler) checkToolScope(toolName string, sessionCtx *session.Context) *toolRejection {
+	if sessionCtx.IsToolAllowed(toolName) {
+		return nil
+	}
+	h.logger.Warn("Tool call outside session scope",
+		zap.String("toolName", toolName),
+		zap.String("sessionId", sessionCtx.ID))
+	return &toolRejection{status: http.StatusNotFound, message: fmt.Sprintf("tool %s not found", toolName)}
+}
+
+// admitToolCall 对到达上游的调用执行准入检查，tools/call 和 /streams 共用
+//
+// 依次检查会话并发上限、token（返回转发的 headers 和身份）和网关过载保护。
+// 通过时返回转发给上游的 headers 和调用结束时必须调用的 release；
+// 被拒绝时返回 toolRejection，网关过载时返回 *overloadedError。
+func (h *Handler) admitToolCall(ctx context.Context, toolName string, sessionCtx *session.Context) (map[string]string, func(), *toolRejection, error) {
+	// 🚦 限制会话的并发调用数，防止失控的 agent 循环占满上游容量
+	if !sessionCtx.TryAcquireCall(h.maxConcurrentCalls) {
+		h.logger.Warn("Session concurrency limit reached",
+			zap.String("toolName", toolName),
+			zap.String("sessionId", sessionCtx.ID),
+			zap.Int("limit", h.maxConcurrentCalls))
+		return nil, nil, &toolRejection{
+			status:  http.StatusTooManyRequests,
+			message: fmt.Sprintf("too many concurrent tool calls in this session (limit %d), retry after an in-flight call completes", h.maxConcurrentCalls),
+			meta:    map[string]interface{}{retryAfterMetaKey: retryAfterSeconds(minRetryAfter)},
+		}, nil
+	}
+
+	// 🪪 过滤转发的 headers，并加入从 token claim 得到的身份
+	filteredHeaders, authErr := h.forwardedHeaders(sessionCtx)
+	if authErr != nil {
+		sessionCtx.ReleaseCall()
+		h.logger.Warn("Tool call without valid token",
+			zap.String("toolName", toolName),
+			zap.String("sessionId", sessionCtx.ID),
+			zap.Error(authErr))
+		return nil, nil, &toolRejection{status: http.StatusUnauthorized, message: fmt.Sprintf("unauthorized: %v", authErr)}, nil
+	}
+
+	// 🧳 附加 baggage 条目，便于后端按 MCP 会话区分 trace 和日志
+	filteredHeaders = h.applyBaggage(filteredHeaders, toolName, sessionCtx)
+
+	// 🛑 网关过载时直接拒绝，而不是无限排队直到超时
+	if err := h.loadShedder.acquire(ctx); err != nil {
+		sessionCtx.ReleaseCall()
+		h.logger.Warn("Tool call shed",
+			zap.String("toolName", toolName),
+			zap.String("sessionId", sessionCtx.ID),
+			zap.Error(err))
+		return nil, nil, nil, err
+	}
+
+	return filteredHeaders, func() {
+		h.loadShedder.release()
+		sessionCtx.ReleaseCall()
+	}, nil, nil
+}
+
+// preparedArguments 处理后发送给上游的参数
+type preparedArguments struct {
+	// json 参数的 JSON 编码，没有参数时为空
+	json string
+	// coercions 修正的参数类型错误，在结果的 _meta 中返回
+	coercions []arguments.Coercion
+}
+
+// prepareArguments 按 tools/call 的规则处理客户端提供的参数，tools/call 和 /streams 共用
+//
+// 依次注入会话元数据、清理参数、应用默认参数与固定参数、重新嵌套展开过的参数、
+// 修正常见类型错误，最后检查参数大小上限。返回的 ctx 带有作为 gRPC metadata 注入的会话元数据。
+func (h *Handler) prepareArguments(ctx context.Context, toolName string, rawArgs interface{}, headers map[string]string, sessionCtx *session.Context) (context.Context, preparedArguments, *toolRejection, error) {
+	var prepared preparedArguments
+	// 🏷️ 注入会话元数据：作为 gRPC metadata，以及未提供参数的默认值
+	// 随后应用配置的默认参数与固定参数（固定参数总是覆盖客户端提供的值）
+	if args, ok := rawArgs.(map[string]interface{}); ok || rawArgs == nil {
+		var err error
+		ctx, args = h.injectSessionMetadata(ctx, toolName, args, sessionCtx)
+		// 🧼 校验并清理客户端提供的参数（配置的默认值和固定值由运营方提供，不经过检查）
+		for _, sanitizer := range h.sanitizers {
+			if args, err = sanitizer.Sanitize(toolName, args); err != nil {
+				break
+			}
+		}
+		if errors.Is(err, arguments.ErrRejected) {
+			h.logger.Warn("Tool arguments rejected by sanitizer",
+				zap.String("toolName", toolName),
+				zap.String("sessionId", sessionCtx.ID),
+				zap.Error(err))
+			return ctx, prepared, &toolRejection{status: http.StatusBadRequest, message: err.Error()}, nil
+		}
+		if err != nil {
+			return ctx, prepared, nil, err
+		}
+		args, err = h.argumentInjector.Apply(toolName, args, arguments.Vars{
+			SessionID: sessionCtx.ID,
+			Metadata:  sessionCtx.GetMetadata(),
+			Headers:   headers,
+			Now:       time.Now(),
+		})
+		if err != nil {
+			return ctx, prepared, nil, err
+		}
+		// 📥 展开过的工具：把参数重新嵌套到请求包装消息的字段中
+		args = h.nestArguments(toolName, args)
+		// 🔧 修正常见的参数类型错误，修正记录在结果的 _meta 中返回
+		args, prepared.coercions = h.coerceArguments(toolName, args, sessionCtx)
+		if args != nil {
+			rawArgs = args
+		}
+	}
+
+	// 📋 将参数对象转换为 JSON 字符串，用于 gRPC 调用
+	if rawArgs != nil {
+		argBytes, err := json.Marshal(rawArgs)
+		if err != nil {
+			return ctx, prepared, nil, fmt.Errorf("failed to marshal arguments: %w", err)
+		}
+		prepared.json = string(argBytes)
+	}
+
+	// 📏 参数大小超过该工具的上限时拒绝调用，不转发给上游
+	if limit := h.inputLimit(toolName); limit > 0 && len(prepared.json) > limit {
+		h.logger.Warn("Tool arguments exceed size limit",
+			zap.String("toolName", toolName),
+			zap.String("sessionId", sessionCtx.ID),
+			zap.Int("size", len(prepared.json)),
+			zap.Int("limit", limit))
+		return ctx, prepared, &toolRejection{
+			status:  http.StatusRequestEntityTooLarge,
+			message: fmt.Sprintf("arguments are %d bytes, which exceeds the %d byte limit for tool %s", len(prepared.json), limit, toolName),
+		}, nil
+	}
+	return ctx, prepared, nil, nil
+}
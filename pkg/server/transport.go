@@ -0,0 +1,227 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"go.uber.org/zap"
+)
+
+// MCP 协议版本（YYYY-MM-DD 格式，可按字符串比较）
+const (
+	// legacyProtocolVersion 客户端未请求或请求了更早的版本时使用的版本
+	legacyProtocolVersion = "2024-11-05"
+	// latestProtocolVersion 实现的最新版本（Streamable HTTP 传输）
+	latestProtocolVersion = "2025-03-26"
+)
+
+// ProtocolVersions 返回网关支持的 MCP 协议版本，从旧到新
+func ProtocolVersions() []string {
+	return []string{legacyProtocolVersion, latestProtocolVersion}
+}
+
+// eventChannelBuffer 每个 GET 事件通道缓冲的消息数，客户端读取过慢时之后的消息被丢弃
+const eventChannelBuffer = 16
+
+// eventChannelPing SSE 事件通道发送保活注释的间隔，防止代理关闭空闲连接
+const eventChannelPing = 25 * time.Second
+
+// negotiateProtocolVersion 按客户端在 initialize 中请求的版本选择协议版本
+//
+// 请求的版本受支持时原样返回；比最新版本更新时返回最新版本；
+// 未请求或更早时返回 2024-11-05，与之前的行为一致。
+func negotiateProtocolVersion(params map[string]interface{}) string {
+	requested, _ := params["protocolVersion"].(string)
+	switch {
+	case requested == legacyProtocolVersion || requested == latestProtocolVersion:
+		return requested
+	case requested > latestProtocolVersion:
+		return latestProtocolVersion
+	}
+	return legacyProtocolVersion
+}
+
+// acceptsJSON 判断 Accept 是否接受 JSON 响应（未提供 Accept 或包含通配符也算）
+func acceptsJSON(r *http.Request) bool {
+	values := r.Header.Values("Accept")
+	if len(values) == 0 {
+		return true
+	}
+	for _, entry := range strings.Split(strings.Join(values, ","), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(entry))
+		if err != nil {
+			continue
+		}
+		if mediaType == "application/json" || mediaType == "application/*" || mediaType == "*/*" {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionChannels 记录每个会话通过 GET 打开的 SSE 事件通道，用于发送服务端主动发起的消息
+type sessionChannels struct {
+	mu       sync.Mutex
+	channels map[string]map[chan []byte]struct{}
+}
+
+// newSessionChannels 创建空的事件通道表
+func newSessionChannels() *sessionChannels {
+	return &sessionChannels{channels: make(map[string]map[chan []byte]struct{})}
+}
+
+// subscribe 为会话打开一个事件通道，返回的函数用于关闭它
+func (c *sessionChannels) subscribe(sessionID string) (<-chan []byte, func()) {
+	ch := make(chan []byte, eventChannelBuffer)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.channels[sessionID] == nil {
+		c.channels[sessionID] = make(map[chan []byte]struct{})
+	}
+	c.channels[sessionID][ch] = struct{}{}
+	return ch, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, ok := c.channels[sessionID][ch]; ok {
+			delete(c.channels[sessionID], ch)
+			close(ch)
+		}
+		if len(c.channels[sessionID]) == 0 {
+			delete(c.channels, sessionID)
+		}
+	}
+}
+
+// broadcast 把消息发送给所有会话的事件通道，缓冲区已满的通道丢弃该消息
+func (c *sessionChannels) broadcast(data []byte) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sent := 0
+	for _, channels := range c.channels {
+		for ch := range channels {
+			select {
+			case ch <- data:
+				sent++
+			default:
+			}
+		}
+	}
+	return sent
+}
+
+// closeSession 关闭会话的所有事件通道
+func (c *sessionChannels) closeSession(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for ch := range c.channels[sessionID] {
+		close(ch)
+	}
+	delete(c.channels, sessionID)
+}
+
+// notify 向所有打开了事件通道的会话发送通知
+func (h *Handler) notify(method string, params map[string]interface{}) {
+	data, err := json.Marshal(mcp.JSONRPCNotification{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		h.logger.Error("Failed to encode notification", zap.String("method", method), zap.Error(err))
+		return
+	}
+	sent := h.channels.broadcast(data)
+	h.logger.Debug("Sent notification", zap.String("method", method), zap.Int("channels", sent))
+}
+
+// writeSessionNotFound 客户端提供的会话不存在（已过期或已终止）时返回 404，客户端需要重新 initialize
+func writeSessionNotFound(w http.ResponseWriter, id mcp.RequestID) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	_ = json.NewEncoder(w).Encode(&mcp.JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error: &mcp.RPCError{
+			Code:    mcp.ErrorCodeInvalidRequest,
+			Message: "Session not found, send a new initialize request",
+		},
+	})
+}
+
+// handleEventChannel 处理 Accept 为 text/event-stream 的 GET 请求：
+// 为会话打开 SSE 通道，发送服务端主动发起的通知（例如 notifications/tools/list_changed）
+//
+// 通道在客户端断开或会话终止时关闭，期间定期发送保活注释。
+func (h *Handler) handleEventChannel(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.Header.Get("Mcp-Session-Id")
+	if sessionID == "" {
+		http.Error(w, "Mcp-Session-Id header is required", http.StatusBadRequest)
+		return
+	}
+	sessionCtx, ok := h.sessionManager.GetSession(sessionID)
+	if !ok {
+		writeSessionNotFound(w, mcp.RequestID{})
+		return
+	}
+
+	events, unsubscribe := h.channels.subscribe(sessionCtx.ID)
+	defer unsubscribe()
+
+	controller := http.NewResponseController(w)
+	// 通道长时间保持打开，不受服务器写超时限制
+	_ = controller.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", eventStreamType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Mcp-Session-Id", sessionCtx.ID)
+	w.WriteHeader(http.StatusOK)
+	_ = controller.Flush()
+
+	ping := time.NewTicker(eventChannelPing)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case data, ok := <-events:
+			if !ok {
+				// 会话已终止
+				return
+			}
+			_, err = fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+		case <-ping.C:
+			_, err = fmt.Fprint(w, ": ping\n\n")
+		}
+		if err == nil {
+			err = controller.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handleDelete 处理 DELETE 请求：终止 Mcp-Session-Id 指定的会话
+//
+// 会话的事件通道和双向流随之关闭，之后使用该会话 ID 的请求返回 404。
+func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.Header.Get("Mcp-Session-Id")
+	if sessionID == "" {
+		http.Error(w, "Mcp-Session-Id header is required", http.StatusBadRequest)
+		return
+	}
+	if _, ok := h.sessionManager.GetSession(sessionID); !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	h.channels.closeSession(sessionID)
+	if h.streams != nil {
+		h.streams.closeSession(sessionID)
+	}
+	h.sessionManager.DeleteSession(sessionID)
+	h.logger.Info("Session terminated by client", zap.String("sessionId", sessionID))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNegotiateProtocolVersion(t *testing.T) {
+	tests := []struct {
+		requested interface{}
+		want      string
+	}{
+		{nil, legacyProtocolVersion},
+		{"2024-11-05", "2024-11-05"},
+		{"2025-03-26", "2025-03-26"},
+		{"2099-01-01", latestProtocolVersion},
+		{"2024-01-01", legacyProtocolVersion},
+	}
+	for _, tt := range tests {
+		params := map[string]interface{}{}
+		if tt.requested != nil {
+			params["protocolVersion"] = tt.requested
+		}
+		assert.Equal(t, tt.want, negotiateProtocolVersion(params), "requested %v", tt.requested)
+	}
+}
+
+// sendRaw posts a raw JSON-RPC body and returns the recorder
+func sendRaw(handler http.Handler, method string, sessionID string, body string, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", sessionID)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestStreamableHTTP_Sessions(t *testing.T) {
+	handler, _ := newTestHandler(t, &mockServiceDiscoverer{})
+	resp, sessionID := postRPC(t, handler, "/", "", "initialize", map[string]interface{}{"protocolVersion": latestProtocolVersion}, nil)
+	require.Nil(t, resp.Error)
+	result := resp.Result.(map[string]interface{})
+	assert.Equal(t, latestProtocolVersion, result["protocolVersion"])
+	assert.Equal(t, true, result["capabilities"].(map[string]interface{})["tools"].(map[string]interface{})["listChanged"])
+
+	// Notifications are accepted without a response body
+	w := sendRaw(handler, http.MethodPost, sessionID, `{"jsonrpc":"2.0","method":"notifications/initialized"}`, "")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	// Unknown sessions get 404, telling the client to initialize again
+	w = sendRaw(handler, http.MethodPost, "expired", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = sendRaw(handler, http.MethodPost, "expired", `{"jsonrpc":"2.0","method":"notifications/initialized"}`, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// DELETE terminates the session
+	assert.Equal(t, http.StatusBadRequest, sendRaw(handler, http.MethodDelete, "", "", "").Code)
+	assert.Equal(t, http.StatusNoContent, sendRaw(handler, http.MethodDelete, sessionID, "", "").Code)
+	assert.Equal(t, http.StatusNotFound, sendRaw(handler, http.MethodDelete, sessionID, "", "").Code)
+	w = sendRaw(handler, http.MethodPost, sessionID, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	var rpcResp mcp.JSONRPCResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rpcResp))
+	require.NotNil(t, rpcResp.Error)
+	assert.Equal(t, float64(2), rpcResp.ID.Value)
+}
+
+func TestStreamableHTTP_EventStreamResponse(t *testing.T) {
+	handler, _ := newTestHandler(t, &mockServiceDiscoverer{})
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+
+	// A client accepting only SSE receives the response as an event
+	w := sendRaw(handler, http.MethodPost, sessionID, `{"jsonrpc":"2.0","id":3,"method":"initialize","params":{}}`, "text/event-stream")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	data, ok := strings.CutPrefix(strings.Split(w.Body.String(), "\n")[1], "data: ")
+	require.True(t, ok, w.Body.String())
+	var resp mcp.JSONRPCResponse
+	require.NoError(t, json.Unmarshal([]byte(data), &resp))
+	assert.Equal(t, float64(3), resp.ID.Value)
+	assert.Nil(t, resp.Error)
+}
+
+func TestStreamableHTTP_EventChannel(t *testing.T) {
+	handler, _ := newTestHandler(t, &mockServiceDiscoverer{})
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	open := func(sessionID string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("Mcp-Session-Id", sessionID)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	assert.Equal(t, http.StatusNotFound, open("expired").StatusCode)
+
+	resp := open(sessionID)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// Server-initiated notifications arrive on the channel
+	handler.notify(mcp.ToolsListChangedNotificationMethod, nil)
+	scanner := bufio.NewScanner(resp.Body)
+	var notification mcp.JSONRPCNotification
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			require.NoError(t, json.NewDecoder(bytes.NewReader([]byte(data))).Decode(&notification))
+			break
+		}
+	}
+	assert.Equal(t, mcp.ToolsListChangedNotificationMethod, notification.Method)
+
+	// Terminating the session closes the channel
+	req, err := http.NewRequest(http.MethodDelete, server.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Mcp-Session-Id", sessionID)
+	deleted, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = deleted.Body.Close()
+	assert.Equal(t, http.StatusNoContent, deleted.StatusCode)
+	for scanner.Scan() {
+	}
+	assert.NoError(t, scanner.Err())
+}
+
+func TestStreamableHTTP_DefaultMiddleware(t *testing.T) {
+	handler, _ := newTestHandler(t, &mockServiceDiscoverer{})
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	server := httptest.NewServer(ChainMiddleware(DefaultMiddleware(zap.NewNop())...)(handler))
+	t.Cleanup(server.Close)
+
+	send := func(method string, body string) *http.Response {
+		req, err := http.NewRequest(method, server.URL, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("Mcp-Session-Id", sessionID)
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	// SSE-only clients get past the Accept check, for the event channel
+	// and for requests answered as an event
+	resp := send(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	resp = send(http.MethodPost, `{"jsonrpc":"2.0","id":2,"method":"initialize","params":{}}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+}
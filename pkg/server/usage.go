@@ -0,0 +1,189 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"go.uber.org/zap"
+)
+
+const (
+	// usageResourceURI 工具使用统计作为 MCP 资源时的 URI
+	usageResourceURI = "ggrmcp://usage/tools"
+	// resourcesReadMethod 读取资源内容的 JSON-RPC 方法
+	resourcesReadMethod = "resources/read"
+	// maxUsageTools 统计的工具数上限，超过后新工具不再计入
+	maxUsageTools = 10000
+)
+
+// ToolUsageStats 单个工具的使用统计
+type ToolUsageStats struct {
+	Tool      string    `json:"tool"`
+	Calls     int64     `json:"calls"`
+	Errors    int64     `json:"errors"`
+	ErrorRate float64   `json:"errorRate"`
+	P50Ms     float64   `json:"p50Ms"`
+	P95Ms     float64   `json:"p95Ms"`
+	LastCall  time.Time `json:"lastCall"`
+}
+
+// ToolUsageReport 所有工具的使用统计，按调用次数降序排列
+type ToolUsageReport struct {
+	Since time.Time        `json:"since"`
+	Tools []ToolUsageStats `json:"tools"`
+}
+
+// usageEntry 单个工具的计数和最近的调用耗时（环形缓冲区）
+type usageEntry struct {
+	calls     int64
+	errors    int64
+	lastCall  time.Time
+	latencies []time.Duration
+	next      int
+}
+
+// toolUsage 按工具统计到达上游的调用：次数、错误率、延迟分位数和最近调用时间
+type toolUsage struct {
+	samples  int
+	resource bool
+	since    time.Time
+
+	mu    sync.Mutex
+	tools map[string]*usageEntry
+}
+
+// newToolUsage 创建使用统计，每个工具保留最近 cfg.Samples 次调用的耗时
+func newToolUsage(cfg config.ToolUsageConfig) *toolUsage {
+	return &toolUsage{
+		samples:  cfg.Samples,
+		resource: cfg.Resource,
+		since:    time.Now(),
+		tools:    make(map[string]*usageEntry),
+	}
+}
+
+// WithToolUsage 设置工具使用统计的采样数，以及是否作为 MCP 资源提供
+func WithToolUsage(cfg config.ToolUsageConfig) HandlerOption {
+	return func(h *Handler) {
+		h.usage = newToolUsage(cfg)
+	}
+}
+
+// record 记录一次工具调用
+func (u *toolUsage) record(toolName string, elapsed time.Duration, failed bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	entry, ok := u.tools[toolName]
+	if !ok {
+		if len(u.tools) >= maxUsageTools {
+			return
+		}
+		entry = &usageEntry{latencies: make([]time.Duration, 0, min(u.samples, 64))}
+		u.tools[toolName] = entry
+	}
+	entry.calls++
+	if failed {
+		entry.errors++
+	}
+	entry.lastCall = time.Now()
+	if len(entry.latencies) < u.samples {
+		entry.latencies = append(entry.latencies, elapsed)
+	} else {
+		entry.latencies[entry.next] = elapsed
+		entry.next = (entry.next + 1) % u.samples
+	}
+}
+
+// report 汇总所有工具的统计，name 将原始工具名称映射为对外展示的名称
+func (u *toolUsage) report(name func(string) string) ToolUsageReport {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	report := ToolUsageReport{Since: u.since, Tools: make([]ToolUsageStats, 0, len(u.tools))}
+	for toolName, entry := range u.tools {
+		latencies := append([]time.Duration(nil), entry.latencies...)
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		report.Tools = append(report.Tools, ToolUsageStats{
+			Tool:      name(toolName),
+			Calls:     entry.calls,
+			Errors:    entry.errors,
+			ErrorRate: float64(entry.errors) / float64(entry.calls),
+			P50Ms:     percentileMs(latencies, 0.50),
+			P95Ms:     percentileMs(latencies, 0.95),
+			LastCall:  entry.lastCall,
+		})
+	}
+	sort.Slice(report.Tools, func(i, j int) bool {
+		if report.Tools[i].Calls != report.Tools[j].Calls {
+			return report.Tools[i].Calls > report.Tools[j].Calls
+		}
+		return report.Tools[i].Tool < report.Tools[j].Tool
+	})
+	return report
+}
+
+// percentileMs 返回已排序耗时的 p 分位数（毫秒，最近秩法）
+func percentileMs(sorted []time.Duration, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p*float64(len(sorted))+0.5) - 1
+	i = max(0, min(i, len(sorted)-1))
+	return float64(sorted[i].Microseconds()) / 1000
+}
+
+// usageReport 返回以对外名称（别名）展示的使用统计
+func (h *Handler) usageReport() ToolUsageReport {
+	return h.usage.report(h.aliases.displayName)
+}
+
+// ToolUsageHandler 返回按工具汇总的使用统计（GET /admin/tools/usage）
+//
+// 统计到达上游的调用次数、错误率、p50/p95 延迟和最近调用时间，
+// 用于了解 AI 实际使用了哪些服务。
+func (h *Handler) ToolUsageHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(h.usageReport()); err != nil {
+		h.logger.Error("Failed to encode tool usage", zap.Error(err))
+	}
+}
+
+// handleResourcesRead 处理 resources/read 请求（提供工具使用统计、被摘要的完整结果和会话历史）
+//
+// 会话历史和被摘要的完整结果只能由会话自己读取，sessionCtx 为 nil 时不提供。
+func (h *Handler) handleResourcesRead(params map[string]interface{}, sessionCtx *session.Context) (*mcp.ReadResourceResult, error) {
+	uri, _ := params["uri"].(string)
+	if uri == "" {
+		return nil, fmt.Errorf("invalid parameters: uri is required")
+	}
+	if uri == historyResourceURI && h.history.Enabled && sessionCtx != nil {
+		return h.readHistory(sessionCtx)
+	}
+	if result, ok := h.readStoredResult(uri, sessionCtx); ok {
+		return result, nil
+	}
+	if uri != usageResourceURI || !h.usage.resource {
+		return nil, fmt.Errorf("resource not found: %s", uri)
+	}
+
+	data, err := json.MarshalIndent(h.usageReport(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode tool usage: %w", err)
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []mcp.ResourceContents{{
+			URI:      usageResourceURI,
+			MimeType: "application/json",
+			Text:     string(data),
+		}},
+	}, nil
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/mcp"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolUsage_Report(t *testing.T) {
+	usage := newToolUsage(config.ToolUsageConfig{Samples: 4})
+	for i := 1; i <= 10; i++ {
+		usage.record("slow", time.Duration(i)*time.Second, i == 10)
+	}
+	usage.record("fast", time.Millisecond, false)
+
+	report := usage.report(func(name string) string { return name })
+	require.Len(t, report.Tools, 2)
+	slow := report.Tools[0]
+	assert.Equal(t, "slow", slow.Tool)
+	assert.Equal(t, int64(10), slow.Calls)
+	assert.Equal(t, int64(1), slow.Errors)
+	assert.InDelta(t, 0.1, slow.ErrorRate, 1e-9)
+	// Only the last 4 latencies (7s..10s) are kept
+	assert.Equal(t, float64(8000), slow.P50Ms)
+	assert.Equal(t, float64(10000), slow.P95Ms)
+	assert.Equal(t, float64(1), report.Tools[1].P95Ms)
+}
+
+func TestToolUsage_EndpointAndResource(t *testing.T) {
+	method := testMethod("acme.users.v1.UserService", "Get")
+	discoverer := &mockServiceDiscoverer{}
+	discoverer.On("GetMethod", method.ToolName).Return(method, true)
+	discoverer.On("GetMethod", "get_user").Return(types.MethodInfo{}, false)
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).Return(`"ok"`, nil).Once()
+	discoverer.On("InvokeMethodByTool", mock.Anything, mock.Anything, method.ToolName, mock.Anything).Return("", errors.New("boom"))
+	handler, _ := newTestHandler(t, discoverer,
+		WithToolAliases([]config.ToolAliasConfig{{Tool: method.ToolName, Alias: "get_user"}}),
+		WithToolUsage(config.ToolUsageConfig{Samples: 10, Resource: true}))
+
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+	postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": "get_user"}, nil)
+	postRPC(t, handler, "/", sessionID, "tools/call", map[string]interface{}{"name": "get_user"}, nil)
+
+	w := httptest.NewRecorder()
+	handler.ToolUsageHandler(w, httptest.NewRequest(http.MethodGet, "/admin/tools/usage", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var report ToolUsageReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	require.Len(t, report.Tools, 1)
+	assert.Equal(t, "get_user", report.Tools[0].Tool)
+	assert.Equal(t, int64(2), report.Tools[0].Calls)
+	assert.Equal(t, 0.5, report.Tools[0].ErrorRate)
+	assert.False(t, report.Tools[0].LastCall.IsZero())
+
+	// The same report is available as an MCP resource
+	resp, _ := postRPC(t, handler, "/", sessionID, "resources/list", nil, nil)
+	require.Nil(t, resp.Error)
+	resources := resp.Result.(map[string]interface{})["resources"].([]interface{})
+	require.Len(t, resources, 1)
+	assert.Equal(t, usageResourceURI, resources[0].(map[string]interface{})["uri"])
+
+	resp, _ = postRPC(t, handler, "/", sessionID, resourcesReadMethod, map[string]interface{}{"uri": usageResourceURI}, nil)
+	require.Nil(t, resp.Error)
+	data, err := json.Marshal(resp.Result)
+	require.NoError(t, err)
+	var result mcp.ReadResourceResult
+	require.NoError(t, json.Unmarshal(data, &result))
+	require.Len(t, result.Contents, 1)
+	assert.Contains(t, result.Contents[0].Text, `"get_user"`)
+}
+
+func TestToolUsage_ResourceDisabledByDefault(t *testing.T) {
+	handler, _ := newTestHandler(t, &mockServiceDiscoverer{})
+	_, sessionID := postRPC(t, handler, "/", "", "initialize", nil, nil)
+
+	resp, _ := postRPC(t, handler, "/", sessionID, "resources/list", nil, nil)
+	require.Nil(t, resp.Error)
+	assert.Empty(t, resp.Result.(map[string]interface{})["resources"])
+
+	resp, _ = postRPC(t, handler, "/", sessionID, resourcesReadMethod, map[string]interface{}{"uri": usageResourceURI}, nil)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, mcp.ErrorCodeMethodNotFound, resp.Error.Code)
+}
@@ -0,0 +1,17 @@
+package session
+
+import "context"
+
+type sessionIDKey struct{}
+
+// NewContextWithID returns a context carrying the MCP session ID, so that
+// layers below the handler (e.g. upstream routing) can key on the session
+func NewContextWithID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionIDKey{}, sessionID)
+}
+
+// IDFromContext returns the MCP session ID carried by ctx, if any
+func IDFromContext(ctx context.Context) string {
+	sessionID, _ := ctx.Value(sessionIDKey{}).(string)
+	return sessionID
+}
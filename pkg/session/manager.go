@@ -3,7 +3,11 @@ package session
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"path"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -29,11 +33,46 @@ type Context struct {
 	// Security
 	IsBlocked bool `json:"is_blocked"`
 
+	// Tool scope negotiated when the session was established (nil means all tools)
+	AllowedTools []string `json:"allowed_tools,omitempty"`
+
+	// Composition of tools/call results chosen at initialize (empty uses the
+	// gateway default)
+	ResultFormat string `json:"result_format,omitempty"`
+
+	// Client-supplied metadata injected into every call of the session
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Default gRPC metadata supplied in initialize; forwarded like request
+	// headers, which take precedence over it
+	DefaultHeaders map[string]string `json:"default_headers,omitempty"`
+
+	// Tool calls currently executing
+	inFlightCalls int64
+
+	// Latest tool calls, oldest first
+	history []HistoryEntry
+
 	// Synchronization
 	mu sync.RWMutex
 }
 
+// HistoryEntry is a tool call recorded in the session history
+type HistoryEntry struct {
+	Tool       string          `json:"tool"`
+	Arguments  json.RawMessage `json:"arguments,omitempty"`
+	Result     string          `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Truncated  bool            `json:"truncated,omitempty"`
+	Time       time.Time       `json:"time"`
+	DurationMs int64           `json:"durationMs"`
+}
+
 // Manager manages user sessions
+//
+// Sessions are kept in process memory only. They carry request headers such
+// as bearer tokens, so a persistent or shared store must encrypt headers and
+// metadata at rest before it is added.
 type Manager struct {
 	cache  *gocache.Cache
 	logger *zap.Logger
@@ -285,6 +324,31 @@ func (ctx *Context) IncrementCallCount() {
 	atomic.AddInt64(&ctx.CallCount, 1)
 }
 
+// TryAcquireCall reserves a concurrent call slot; it returns false when the
+// session already has limit calls in flight. A limit <= 0 means unlimited.
+// Every successful acquire must be paired with ReleaseCall.
+func (ctx *Context) TryAcquireCall(limit int) bool {
+	for {
+		current := atomic.LoadInt64(&ctx.inFlightCalls)
+		if limit > 0 && current >= int64(limit) {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&ctx.inFlightCalls, current, current+1) {
+			return true
+		}
+	}
+}
+
+// ReleaseCall frees a slot reserved by TryAcquireCall
+func (ctx *Context) ReleaseCall() {
+	atomic.AddInt64(&ctx.inFlightCalls, -1)
+}
+
+// InFlightCalls returns the number of tool calls currently executing
+func (ctx *Context) InFlightCalls() int64 {
+	return atomic.LoadInt64(&ctx.inFlightCalls)
+}
+
 // GetCallCount returns the call count
 func (ctx *Context) GetCallCount() int64 {
 	return atomic.LoadInt64(&ctx.CallCount)
@@ -328,20 +392,161 @@ func (ctx *Context) SetHeader(key, value string) {
 	ctx.Headers[key] = value
 }
 
-// GetInfo returns session information
+// SetToolScope restricts the session to tools matching the given patterns
+// (exact names or path.Match globs such as "user_service_*"). A scope can
+// only be set once so that it cannot be widened later in the session; it
+// returns false if the session is already scoped.
+func (ctx *Context) SetToolScope(patterns []string) bool {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	if ctx.AllowedTools != nil || len(patterns) == 0 {
+		return false
+	}
+	ctx.AllowedTools = append([]string{}, patterns...)
+	return true
+}
+
+// SetResultFormat sets how tools/call results are composed for the session
+func (ctx *Context) SetResultFormat(format string) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.ResultFormat = format
+}
+
+// GetResultFormat returns how tools/call results are composed for the session
+func (ctx *Context) GetResultFormat() string {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	return ctx.ResultFormat
+}
+
+// ToolScope returns the tool patterns the session is restricted to
+func (ctx *Context) ToolScope() []string {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	return ctx.AllowedTools
+}
+
+// IsToolAllowed checks whether the session scope permits the given tool
+func (ctx *Context) IsToolAllowed(toolName string) bool {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	if ctx.AllowedTools == nil {
+		return true
+	}
+	for _, pattern := range ctx.AllowedTools {
+		if pattern == toolName {
+			return true
+		}
+		if matched, err := path.Match(pattern, toolName); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeMetadata merges entries into the session metadata; empty values remove
+// keys. The merge is refused, leaving the metadata unchanged, when the result
+// would hold more than maxEntries entries.
+func (ctx *Context) MergeMetadata(entries map[string]string, maxEntries int) bool {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	merged := make(map[string]string, len(ctx.Metadata)+len(entries))
+	for k, v := range ctx.Metadata {
+		merged[k] = v
+	}
+	for k, v := range entries {
+		if v == "" {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = v
+	}
+	if len(merged) > maxEntries {
+		return false
+	}
+	ctx.Metadata = merged
+	return true
+}
+
+// GetMetadata returns a copy of the session metadata
+func (ctx *Context) GetMetadata() map[string]string {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	metadata := make(map[string]string, len(ctx.Metadata))
+	for k, v := range ctx.Metadata {
+		metadata[k] = v
+	}
+	return metadata
+}
+
+// RecordCall appends a tool call to the session history, keeping the latest
+// limit calls
+func (ctx *Context) RecordCall(entry HistoryEntry, limit int) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.history = append(ctx.history, entry)
+	if drop := len(ctx.history) - limit; drop > 0 {
+		ctx.history = append([]HistoryEntry(nil), ctx.history[drop:]...)
+	}
+}
+
+// History returns a copy of the session history, oldest call first
+func (ctx *Context) History() []HistoryEntry {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	return append([]HistoryEntry{}, ctx.history...)
+}
+
+// SetDefaultHeaders replaces the default gRPC metadata of the session
+func (ctx *Context) SetDefaultHeaders(headers map[string]string) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.DefaultHeaders = headers
+}
+
+// GetHeaders returns the request headers merged over the default headers.
+// Header names are compared case-insensitively, so a request header replaces
+// a default header with the same name in any case.
+func (ctx *Context) GetHeaders() map[string]string {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	merged := make(map[string]string, len(ctx.DefaultHeaders)+len(ctx.Headers))
+	for k, v := range ctx.DefaultHeaders {
+		merged[strings.ToLower(k)] = v
+	}
+	for k, v := range ctx.Headers {
+		delete(merged, strings.ToLower(k))
+		merged[k] = v
+	}
+	return merged
+}
+
+// GetInfo returns session information. Only the names of the default
+// headers are included: their values may hold credentials.
 func (ctx *Context) GetInfo() map[string]interface{} {
 	ctx.mu.RLock()
 	defer ctx.mu.RUnlock()
 
+	defaultHeaders := make([]string, 0, len(ctx.DefaultHeaders))
+	for name := range ctx.DefaultHeaders {
+		defaultHeaders = append(defaultHeaders, name)
+	}
+	sort.Strings(defaultHeaders)
+
 	return map[string]interface{}{
-		"id":            ctx.ID,
-		"created_at":    ctx.CreatedAt,
-		"last_accessed": ctx.LastAccessed,
-		"call_count":    atomic.LoadInt64(&ctx.CallCount),
-		"user_agent":    ctx.UserAgent,
-		"remote_addr":   ctx.RemoteAddr,
-		"age":           time.Since(ctx.CreatedAt),
-		"idle_time":     time.Since(ctx.LastAccessed),
-		"is_blocked":    ctx.IsBlocked,
+		"id":              ctx.ID,
+		"created_at":      ctx.CreatedAt,
+		"last_accessed":   ctx.LastAccessed,
+		"call_count":      atomic.LoadInt64(&ctx.CallCount),
+		"user_agent":      ctx.UserAgent,
+		"remote_addr":     ctx.RemoteAddr,
+		"age":             time.Since(ctx.CreatedAt),
+		"idle_time":       time.Since(ctx.LastAccessed),
+		"is_blocked":      ctx.IsBlocked,
+		"allowed_tools":   ctx.AllowedTools,
+		"metadata":        ctx.Metadata,
+		"default_headers": defaultHeaders,
+		"in_flight":       atomic.LoadInt64(&ctx.inFlightCalls),
 	}
 }
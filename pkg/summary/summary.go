@@ -0,0 +1,79 @@
+// Package summary shortens tool results that exceed a size budget, so a huge
+// list response does not fill the agent's context. The gateway keeps the full
+// result and links to it from the summary.
+package summary
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Summarizer shortens a decoded JSON tool result. It returns the summary and
+// what was left out; an empty Omissions means the result could not be
+// shortened and is returned in full.
+type Summarizer interface {
+	Summarize(toolName string, result interface{}) (interface{}, Omissions)
+}
+
+// Omissions describes what a summary left out of a result
+type Omissions struct {
+	// Lists shortened to their first items
+	Lists []List `json:"lists,omitempty"`
+}
+
+// Empty reports whether nothing was left out
+func (o Omissions) Empty() bool {
+	return len(o.Lists) == 0
+}
+
+// List records a shortened list
+type List struct {
+	// Path of the list in the result, e.g. "orders" or "groups[0].members"
+	Path string `json:"path"`
+	// Total number of items in the full result
+	Total int `json:"total"`
+	// Returned number of items kept in the summary
+	Returned int `json:"returned"`
+}
+
+// Lists is the default Summarizer: it keeps the first MaxItems items of every
+// list in the result, at any depth, and reports the counts
+type Lists struct {
+	MaxItems int
+}
+
+// Summarize implements Summarizer
+func (s Lists) Summarize(toolName string, result interface{}) (interface{}, Omissions) {
+	var omissions Omissions
+	summary := s.value(result, "", &omissions)
+	sort.Slice(omissions.Lists, func(i, j int) bool { return omissions.Lists[i].Path < omissions.Lists[j].Path })
+	return summary, omissions
+}
+
+// value shortens the lists in value; the result is a copy where anything changed
+func (s Lists) value(value interface{}, path string, omissions *Omissions) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			object[key] = s.value(item, childPath, omissions)
+		}
+		return object
+	case []interface{}:
+		kept := v
+		if len(v) > s.MaxItems {
+			kept = v[:s.MaxItems]
+			omissions.Lists = append(omissions.Lists, List{Path: path, Total: len(v), Returned: len(kept)})
+		}
+		items := make([]interface{}, len(kept))
+		for i, item := range kept {
+			items[i] = s.value(item, fmt.Sprintf("%s[%d]", path, i), omissions)
+		}
+		return items
+	}
+	return value
+}
@@ -0,0 +1,38 @@
+package summary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLists_Summarize(t *testing.T) {
+	result := map[string]interface{}{
+		"orders": []interface{}{
+			map[string]interface{}{"id": "1", "lines": []interface{}{"a", "b", "c"}},
+			map[string]interface{}{"id": "2", "lines": []interface{}{"d"}},
+			map[string]interface{}{"id": "3"},
+		},
+		"total": 3,
+	}
+
+	summary, omissions := Lists{MaxItems: 2}.Summarize("orders_list", result)
+	assert.Equal(t, map[string]interface{}{
+		"orders": []interface{}{
+			map[string]interface{}{"id": "1", "lines": []interface{}{"a", "b"}},
+			map[string]interface{}{"id": "2", "lines": []interface{}{"d"}},
+		},
+		"total": 3,
+	}, summary)
+	assert.Equal(t, []List{
+		{Path: "orders", Total: 3, Returned: 2},
+		{Path: "orders[0].lines", Total: 3, Returned: 2},
+	}, omissions.Lists)
+
+	// The result is not modified
+	assert.Len(t, result["orders"], 3)
+
+	// Results without long lists cannot be shortened
+	_, omissions = Lists{MaxItems: 5}.Summarize("orders_list", result)
+	assert.True(t, omissions.Empty())
+}
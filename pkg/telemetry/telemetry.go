@@ -0,0 +1,75 @@
+// Package telemetry lets programs embedding the gateway export its metrics
+// and traces to their own backends, such as StatsD or an APM agent.
+//
+// The gateway only talks to the Metrics and Tracer interfaces defined here,
+// so it does not depend on any exporter SDK. Both default to Nop, which
+// discards everything; the /metrics endpoint is not affected.
+package telemetry
+
+import "context"
+
+// Tags are the dimensions of a metric or the attributes of a span
+type Tags map[string]string
+
+// Metrics receives the gateway's counters and distributions
+type Metrics interface {
+	// Count adds delta to a counter
+	Count(name string, delta int64, tags Tags)
+	// Observe records one value of a distribution, such as a latency
+	Observe(name string, value float64, tags Tags)
+}
+
+// Tracer starts spans around the gateway's operations
+type Tracer interface {
+	// Start begins a span, as a child of the span in ctx if there is one, and
+	// returns a context carrying the new span
+	Start(ctx context.Context, name string, tags Tags) (context.Context, Span)
+}
+
+// Span is one traced operation
+type Span interface {
+	// SetTag adds an attribute to the span
+	SetTag(key, value string)
+	// End finishes the span; err is the operation's error, nil on success
+	End(err error)
+}
+
+// Metric names. Tool metrics are tagged with "tool" and "outcome", request
+// metrics with "method" and "outcome".
+const (
+	MetricRequests         = "ggrmcp.requests"
+	MetricToolCalls        = "ggrmcp.tool_calls"
+	MetricToolCallDuration = "ggrmcp.tool_call.duration_ms"
+	MetricPanics           = "ggrmcp.panics"
+)
+
+// Span names. Both spans are tagged with "session"; request spans also with
+// "method" and tool call spans with "tool".
+const (
+	SpanRequest  = "mcp.request"
+	SpanToolCall = "mcp.tool_call"
+)
+
+// Outcomes reported in the "outcome" tag
+const (
+	OutcomeOK       = "ok"
+	OutcomeError    = "error"
+	OutcomeCanceled = "canceled"
+)
+
+// Nop discards all metrics and spans
+var Nop nop
+
+type nop struct{}
+
+func (nop) Count(string, int64, Tags)     {}
+func (nop) Observe(string, float64, Tags) {}
+
+func (nop) Start(ctx context.Context, _ string, _ Tags) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetTag(string, string) {}
+func (nopSpan) End(error)             {}
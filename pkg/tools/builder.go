@@ -3,7 +3,9 @@ package tools
 import (
 	"fmt"
 	"strings"
+	"sync"
 
+	"github.com/aalobaidi/ggRMCP/pkg/descriptors"
 	"github.com/aalobaidi/ggRMCP/pkg/mcp"
 	"github.com/aalobaidi/ggRMCP/pkg/types"
 	"go.uber.org/zap"
@@ -14,30 +16,101 @@ import (
 type MCPToolBuilder struct {
 	logger *zap.Logger // 日志
 
-	// Cache for generated schemas
-	schemaCache map[string]interface{} // 缓存已生成的模式
+	// Cache for generated tools, keyed by tool name
+	schemaCache map[string]cachedTool // 缓存已生成的模式
+	cacheMu     sync.RWMutex
 
 	// Configuration
-	maxRecursionDepth int  // 最大递归深度
-	includeComments   bool // 是否包含注释
+	maxRecursionDepth int                       // 最大递归深度
+	includeComments   bool                      // 是否包含注释
+	serverStreaming   bool                      // 是否为服务端流式方法构建工具
+	commentFormat     descriptors.CommentFormat // 注释转换为描述时的格式
+	constraintSummary bool                      // 是否在字段描述后追加约束摘要
 }
 
 // NewMCPToolBuilder creates a new MCP tool builder
 func NewMCPToolBuilder(logger *zap.Logger) *MCPToolBuilder {
 	return &MCPToolBuilder{
 		logger:            logger,
-		schemaCache:       make(map[string]interface{}),
+		schemaCache:       make(map[string]cachedTool),
 		maxRecursionDepth: 10,
 		includeComments:   true,
+		commentFormat:     descriptors.DefaultCommentFormat,
 	}
 }
 
-// BuildTool builds an MCP tool from a gRPC method
+// SetServerStreaming makes BuildTools include server-streaming methods, whose
+// streamed messages the invoker aggregates into one result
+func (b *MCPToolBuilder) SetServerStreaming(enabled bool) {
+	b.serverStreaming = enabled
+}
+
+// SetCommentFormat controls how proto comments become tool, schema and field
+// descriptions
+func (b *MCPToolBuilder) SetCommentFormat(format descriptors.CommentFormat) {
+	b.commentFormat = format
+}
+
+// SetConstraintSummary controls whether field descriptions end with a short
+// summary of the field's constraints (validate rules, enum values, formats)
+func (b *MCPToolBuilder) SetConstraintSummary(enabled bool) {
+	b.constraintSummary = enabled
+}
+
+// cachedTool is a built tool together with the method it was built from
+type cachedTool struct {
+	method types.MethodInfo
+	tool   mcp.Tool
+}
+
+// matches reports whether the cached tool was built from the same
+// descriptors; rediscovery replaces them, which invalidates the entry
+func (c cachedTool) matches(method types.MethodInfo) bool {
+	return c.method.InputDescriptor == method.InputDescriptor &&
+		c.method.OutputDescriptor == method.OutputDescriptor &&
+		c.method.Description == method.Description &&
+		c.method.IsServerStreaming == method.IsServerStreaming
+}
+
+// toolNameOf returns the name the discoverer exposes the method under,
+// generating it for methods that do not carry one
+func toolNameOf(method types.MethodInfo) string {
+	if method.ToolName != "" {
+		return method.ToolName
+	}
+	return method.GenerateToolName()
+}
+
+// BuildTool builds an MCP tool from a gRPC method. Built tools are cached
+// until the method's descriptors change, so callers must not modify the
+// returned schemas in place.
 // BuildTool 构建 MCP 工具
 func (b *MCPToolBuilder) BuildTool(method types.MethodInfo) (mcp.Tool, error) {
+	toolName := toolNameOf(method)
+
+	b.cacheMu.RLock()
+	cached, ok := b.schemaCache[toolName]
+	b.cacheMu.RUnlock()
+	if ok && cached.matches(method) {
+		return cached.tool, nil
+	}
+
+	tool, err := b.buildTool(method)
+	if err != nil {
+		return mcp.Tool{}, err
+	}
+
+	b.cacheMu.Lock()
+	b.schemaCache[toolName] = cachedTool{method: method, tool: tool}
+	b.cacheMu.Unlock()
+	return tool, nil
+}
+
+// buildTool generates the tool and its schemas
+func (b *MCPToolBuilder) buildTool(method types.MethodInfo) (mcp.Tool, error) {
 	// Generate tool name
 	// ServiceName: "hello.HelloService", Name: "SayHello" -> "hello_helloservice_sayhello"
-	toolName := method.GenerateToolName()
+	toolName := toolNameOf(method)
 
 	// Generate description
 	// Calls the %s method of the %s service
@@ -70,6 +143,9 @@ func (b *MCPToolBuilder) BuildTool(method types.MethodInfo) (mcp.Tool, error) {
 			zap.Error(err))
 		return mcp.Tool{}, fmt.Errorf("failed to generate output schema: %w", err)
 	}
+	if method.IsServerStreaming {
+		outputSchema = streamResultSchema(outputSchema)
+	}
 
 	tool := mcp.Tool{
 		Name:         toolName,
@@ -92,11 +168,39 @@ func (b *MCPToolBuilder) BuildTool(method types.MethodInfo) (mcp.Tool, error) {
 	return tool, nil
 }
 
+// streamResultSchema wraps the schema of a streamed message in the schema of
+// the aggregated result
+func streamResultSchema(messageSchema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"messages": map[string]interface{}{
+				"type":        "array",
+				"items":       messageSchema,
+				"description": "Messages received from the stream, in order",
+			},
+			"truncated": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Set when the stream was cut short; messages holds what was received until then",
+			},
+			"truncationReason": map[string]interface{}{
+				"type": "string",
+				"enum": []string{"deadline_exceeded", "max_messages"},
+			},
+		},
+		"required": []string{"messages"},
+	}
+}
+
 // generateDescription generates a tool description
 func (b *MCPToolBuilder) generateDescription(method types.MethodInfo) string {
 	// Use description from method if available (could be from FileDescriptorSet comments)
-	if method.Description != "" {
-		return method.Description
+	description := method.Description
+	if b.commentFormat.Normalize {
+		description = descriptors.NormalizeComment(description)
+	}
+	if description != "" {
+		return descriptors.TruncateComment(description, b.commentFormat.MaxLength)
 	}
 
 	// Fallback to generic description
@@ -131,8 +235,8 @@ func (b *MCPToolBuilder) BuildTools(methods []types.MethodInfo) ([]mcp.Tool, err
 	var tools []mcp.Tool
 
 	for _, method := range methods {
-		// Skip streaming methods
-		if method.IsClientStreaming || method.IsServerStreaming {
+		// Skip streaming methods (server-streaming ones unless enabled)
+		if method.IsClientStreaming || (method.IsServerStreaming && !b.serverStreaming) {
 			b.logger.Debug("Skipping streaming method",
 				zap.String("service", method.ServiceName),
 				zap.String("method", method.Name))
@@ -260,7 +364,8 @@ func (b *MCPToolBuilder) extractMessageSchemaInternal(msgDesc protoreflect.Messa
 			continue
 		}
 
-		// 添加该字段的 schema
+		// 添加该字段的 schema（描述后附上约束摘要）
+		b.describeConstraints(field, fieldSchema)
 		properties[fieldName] = fieldSchema
 
 		// 🏷️ 判断字段是否为必填
@@ -312,6 +417,8 @@ func (b *MCPToolBuilder) extractMessageSchemaInternal(msgDesc protoreflect.Messa
 				continue
 			}
 
+			b.describeConstraints(field, fieldSchema)
+
 			// 为每个 oneof 选项创建一个独立的对象 schema
 			// 要求：如果选择了这个选项，必须包含该字段且类型匹配
 			oneofOption := map[string]interface{}{
@@ -603,25 +710,7 @@ func (b *MCPToolBuilder) ExtractFieldComments(field protoreflect.FieldDescriptor
 }
 
 // extractComments extracts comments from a protobuf descriptor
-// 提取 protobuf 描述符的注释
+// 提取 protobuf 描述符的注释（按 commentFormat 格式化）
 func (b *MCPToolBuilder) extractComments(desc protoreflect.Descriptor) string {
-	// Get source location info if available
-	loc := desc.ParentFile().SourceLocations().ByDescriptor(desc)
-	comments := ""
-
-	// Leading comments
-	if leading := loc.LeadingComments; leading != "" {
-		comments = leading
-	}
-
-	// Trailing comments (append with newline if we have leading comments)
-	if trailing := loc.TrailingComments; trailing != "" {
-		if comments != "" {
-			comments += "\n" + trailing
-		} else {
-			comments = trailing
-		}
-	}
-
-	return comments
+	return descriptors.FormatComments(desc, b.commentFormat)
 }
@@ -0,0 +1,351 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// validateRuleExtensions 是声明字段校验规则的选项扩展：protovalidate 与 protoc-gen-validate
+var validateRuleExtensions = map[protoreflect.FullName]bool{
+	"buf.validate.field": true,
+	"validate.rules":     true,
+}
+
+// maxSummaryValues 约束摘要中最多列出的枚举值或候选值个数
+const maxSummaryValues = 8
+
+// wellKnownStringRules 是字符串规则中表示格式的布尔规则及其说明
+var wellKnownStringRules = []struct {
+	name  protoreflect.Name
+	label string
+}{
+	{"email", "email address"},
+	{"hostname", "hostname"},
+	{"ip", "IP address"},
+	{"ipv4", "IPv4 address"},
+	{"ipv6", "IPv6 address"},
+	{"uri", "URI"},
+	{"uri_ref", "URI reference"},
+	{"address", "hostname or IP address"},
+	{"uuid", "UUID"},
+	{"tuuid", "UUID without dashes"},
+}
+
+// describeConstraints 在字段描述后追加一行约束摘要
+//
+// 有些模型更看重描述而不是 minLength、enum 这样的 schema 关键字，
+// 摘要把已知的约束（校验规则、枚举值、格式）写成简短的文字，例如 "Constraints: 1–64 chars, pattern ^[a-z]+$"
+func (b *MCPToolBuilder) describeConstraints(field protoreflect.FieldDescriptor, schema map[string]interface{}) {
+	if !b.constraintSummary {
+		return
+	}
+	summary := constraintSummary(field, schema)
+	if summary == "" {
+		return
+	}
+	summary = "Constraints: " + summary
+	if desc, _ := schema["description"].(string); desc != "" {
+		summary = desc + "\n" + summary
+	}
+	schema["description"] = summary
+}
+
+// constraintSummary 汇总字段的校验规则与 schema 中的约束，重复的说明只保留一次
+func constraintSummary(field protoreflect.FieldDescriptor, schema map[string]interface{}) string {
+	var phrases []string
+	seen := make(map[string]bool)
+	add := func(list []string) {
+		for _, phrase := range list {
+			if !seen[phrase] {
+				seen[phrase] = true
+				phrases = append(phrases, phrase)
+			}
+		}
+	}
+	if rules := validateRules(field); rules != nil {
+		add(rulePhrases(field, rules))
+	}
+	add(schemaPhrases(schema))
+	return strings.Join(phrases, ", ")
+}
+
+// schemaPhrases 描述生成的 schema 中已有的约束：枚举值、base64 编码与非负整数
+func schemaPhrases(schema map[string]interface{}) []string {
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		var phrases []string
+		for _, phrase := range schemaPhrases(items) {
+			phrases = append(phrases, "each "+phrase)
+		}
+		return phrases
+	}
+
+	var phrases []string
+	if values, ok := schema["enum"].([]interface{}); ok && len(values) > 0 {
+		phrases = append(phrases, "one of "+joinValues(values))
+	}
+	if schema["format"] == "byte" {
+		phrases = append(phrases, "base64")
+	}
+	if minimum, ok := schema["minimum"]; ok && minimum == 0 {
+		if _, bounded := schema["maximum"]; !bounded {
+			phrases = append(phrases, "non-negative")
+		}
+	}
+	return phrases
+}
+
+// validateRules 返回字段上 protovalidate 或 protoc-gen-validate 的校验规则消息，没有时返回 nil
+//
+// 扩展声明从字段所在文件的（传递）导入中查找，并用它重新解析字段选项：
+// 从 FileDescriptorSet 加载时这些选项仍是未知字段，通过 Reflection 发现时
+// 扩展类型属于上游的注册表，两种情况都能这样统一读取
+func validateRules(field protoreflect.FieldDescriptor) protoreflect.Message {
+	options, ok := field.Options().(*descriptorpb.FieldOptions)
+	if !ok || options == nil {
+		return nil
+	}
+	data, err := proto.Marshal(options)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+
+	resolver := new(protoregistry.Types)
+	for _, xd := range findRuleExtensions(field.ParentFile(), make(map[string]bool)) {
+		if err := resolver.RegisterExtension(dynamicpb.NewExtensionType(xd)); err != nil {
+			return nil
+		}
+	}
+	if resolver.NumExtensions() == 0 {
+		return nil
+	}
+
+	resolved := new(descriptorpb.FieldOptions)
+	if err := (proto.UnmarshalOptions{Resolver: resolver}).Unmarshal(data, resolved); err != nil {
+		return nil
+	}
+	var rules protoreflect.Message
+	resolved.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.IsExtension() && validateRuleExtensions[fd.FullName()] && fd.Message() != nil {
+			rules = v.Message()
+			return false
+		}
+		return true
+	})
+	return rules
+}
+
+// findRuleExtensions 在文件及其传递导入中查找校验规则扩展的声明
+func findRuleExtensions(file protoreflect.FileDescriptor, visited map[string]bool) []protoreflect.ExtensionDescriptor {
+	if visited[file.Path()] {
+		return nil
+	}
+	visited[file.Path()] = true
+
+	var found []protoreflect.ExtensionDescriptor
+	for i := 0; i < file.Extensions().Len(); i++ {
+		if xd := file.Extensions().Get(i); validateRuleExtensions[xd.FullName()] {
+			found = append(found, xd)
+		}
+	}
+	for i := 0; i < file.Imports().Len(); i++ {
+		found = append(found, findRuleExtensions(file.Imports().Get(i).FileDescriptor, visited)...)
+	}
+	return found
+}
+
+// rulePhrases 描述校验规则消息（buf.validate.FieldRules 或 validate.FieldRules）
+//
+// 两种规则的字段名基本一致：按类型分组的规则（string、int32、repeated 等）加上 required，
+// 因此按字段名读取，不依赖生成代码
+func rulePhrases(field protoreflect.FieldDescriptor, rules protoreflect.Message) []string {
+	var required, phrases []string
+	rules.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.Name() == "required" && fd.Kind() == protoreflect.BoolKind && v.Bool():
+			// required 总是放在最前面
+			required = []string{"required"}
+		case fd.Kind() == protoreflect.MessageKind && !fd.IsList() && !fd.IsMap():
+			phrases = append(phrases, typeRulePhrases(field, fd.Name(), v.Message())...)
+		}
+		return true
+	})
+	return append(required, phrases...)
+}
+
+// typeRulePhrases 描述某一类型的规则，kind 是规则在 FieldRules 中的字段名
+func typeRulePhrases(field protoreflect.FieldDescriptor, kind protoreflect.Name, rules protoreflect.Message) []string {
+	get := func(name protoreflect.Name) (protoreflect.Value, bool) {
+		fd := rules.Descriptor().Fields().ByName(name)
+		if fd == nil || !rules.Has(fd) {
+			return protoreflect.Value{}, false
+		}
+		return rules.Get(fd), true
+	}
+	isSet := func(name protoreflect.Name) bool {
+		fd := rules.Descriptor().Fields().ByName(name)
+		return fd != nil && fd.Kind() == protoreflect.BoolKind && rules.Get(fd).Bool()
+	}
+	nested := func(prefix string, name protoreflect.Name) []string {
+		v, ok := get(name)
+		if !ok {
+			return nil
+		}
+		var phrases []string
+		for _, phrase := range rulePhrases(field, v.Message()) {
+			phrases = append(phrases, prefix+phrase)
+		}
+		return phrases
+	}
+
+	var phrases []string
+	if isSet("required") {
+		phrases = append(phrases, "required")
+	}
+
+	switch kind {
+	case "string", "bytes":
+		unit := "chars"
+		if kind == "bytes" {
+			unit = "bytes"
+		}
+		phrases = appendLength(phrases, get, "len", "min_len", "max_len", unit)
+		if v, ok := get("pattern"); ok {
+			phrases = append(phrases, "pattern "+v.String())
+		}
+		for _, affix := range []struct {
+			name  protoreflect.Name
+			label string
+		}{{"prefix", "prefix"}, {"suffix", "suffix"}, {"contains", "containing"}} {
+			if v, ok := get(affix.name); ok {
+				phrases = append(phrases, fmt.Sprintf("%s %q", affix.label, valueString(v)))
+			}
+		}
+		for _, rule := range wellKnownStringRules {
+			if isSet(rule.name) {
+				phrases = append(phrases, rule.label)
+			}
+		}
+		phrases = appendIn(phrases, get, valueString)
+
+	case "repeated":
+		phrases = appendLength(phrases, get, "", "min_items", "max_items", "items")
+		if isSet("unique") {
+			phrases = append(phrases, "unique items")
+		}
+		phrases = append(phrases, nested("each ", "items")...)
+
+	case "map":
+		phrases = appendLength(phrases, get, "", "min_pairs", "max_pairs", "entries")
+		phrases = append(phrases, nested("keys ", "keys")...)
+		phrases = append(phrases, nested("values ", "values")...)
+
+	case "enum":
+		phrases = appendIn(phrases, get, func(v protoreflect.Value) string {
+			if field.Enum() != nil {
+				if value := field.Enum().Values().ByNumber(v.Enum()); value != nil {
+					return string(value.Name())
+				}
+			}
+			return valueString(v)
+		})
+
+	case "message", "any", "duration", "timestamp":
+		// 只描述 required
+
+	default:
+		// 数值规则：int32、uint64、double、fixed32 等
+		phrases = appendRange(phrases, get)
+		phrases = appendIn(phrases, get, valueString)
+	}
+
+	return phrases
+}
+
+// appendLength 描述长度或个数的上下限，例如 "1–64 chars"、"at most 10 items"
+func appendLength(phrases []string, get func(protoreflect.Name) (protoreflect.Value, bool), exact, min, max protoreflect.Name, unit string) []string {
+	if exact != "" {
+		if v, ok := get(exact); ok {
+			return append(phrases, fmt.Sprintf("exactly %s %s", valueString(v), unit))
+		}
+	}
+	lower, hasLower := get(min)
+	upper, hasUpper := get(max)
+	switch {
+	case hasLower && hasUpper && valueString(lower) == valueString(upper):
+		return append(phrases, fmt.Sprintf("exactly %s %s", valueString(lower), unit))
+	case hasLower && hasUpper:
+		return append(phrases, fmt.Sprintf("%s–%s %s", valueString(lower), valueString(upper), unit))
+	case hasLower:
+		return append(phrases, fmt.Sprintf("at least %s %s", valueString(lower), unit))
+	case hasUpper:
+		return append(phrases, fmt.Sprintf("at most %s %s", valueString(upper), unit))
+	}
+	return phrases
+}
+
+// appendRange 描述数值规则的取值范围，例如 "1–100"、"> 0"、"≤ 10"
+func appendRange(phrases []string, get func(protoreflect.Name) (protoreflect.Value, bool)) []string {
+	if v, ok := get("const"); ok {
+		return append(phrases, "exactly "+valueString(v))
+	}
+	gte, hasGte := get("gte")
+	lte, hasLte := get("lte")
+	if hasGte && hasLte {
+		return append(phrases, valueString(gte)+"–"+valueString(lte))
+	}
+	for _, bound := range []struct {
+		name protoreflect.Name
+		op   string
+	}{{"gt", ">"}, {"gte", "≥"}, {"lt", "<"}, {"lte", "≤"}} {
+		if v, ok := get(bound.name); ok {
+			phrases = append(phrases, bound.op+" "+valueString(v))
+		}
+	}
+	return phrases
+}
+
+// appendIn 描述 in 与 not_in 规则的候选值
+func appendIn(phrases []string, get func(protoreflect.Name) (protoreflect.Value, bool), format func(protoreflect.Value) string) []string {
+	for _, rule := range []struct {
+		name   protoreflect.Name
+		prefix string
+	}{{"in", "one of "}, {"not_in", "not "}} {
+		v, ok := get(rule.name)
+		if !ok || !v.List().IsValid() || v.List().Len() == 0 {
+			continue
+		}
+		values := make([]interface{}, v.List().Len())
+		for i := range values {
+			values[i] = format(v.List().Get(i))
+		}
+		phrases = append(phrases, rule.prefix+joinValues(values))
+	}
+	return phrases
+}
+
+// joinValues 列出最多 maxSummaryValues 个值，其余用 "…" 表示
+func joinValues(values []interface{}) string {
+	parts := make([]string, 0, maxSummaryValues+1)
+	for i, value := range values {
+		if i == maxSummaryValues {
+			parts = append(parts, "…")
+			break
+		}
+		parts = append(parts, fmt.Sprint(value))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// valueString 格式化规则中的标量值
+func valueString(v protoreflect.Value) string {
+	if b, ok := v.Interface().([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,172 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// constraintTestMessage builds constrainttest.User whose fields carry
+// buf.validate.field rules declared by a trimmed-down validate.proto
+func constraintTestMessage(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	field := func(name string, number int32, label *descriptorpb.FieldDescriptorProto_Label, kind descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		fdp := &descriptorpb.FieldDescriptorProto{
+			Name: proto.String(name), JsonName: proto.String(name), Number: proto.Int32(number), Label: label, Type: kind.Enum(),
+		}
+		if typeName != "" {
+			fdp.TypeName = proto.String(typeName)
+		}
+		return fdp
+	}
+	message := descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	uint64Kind := descriptorpb.FieldDescriptorProto_TYPE_UINT64
+	int32Kind := descriptorpb.FieldDescriptorProto_TYPE_INT32
+	stringKind := descriptorpb.FieldDescriptorProto_TYPE_STRING
+	boolKind := descriptorpb.FieldDescriptorProto_TYPE_BOOL
+
+	files := new(protoregistry.Files)
+	require.NoError(t, files.RegisterFile(descriptorpb.File_google_protobuf_descriptor_proto))
+	validate, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("buf/validate/validate.proto"),
+		Package:    proto.String("buf.validate"),
+		Dependency: []string{"google/protobuf/descriptor.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("FieldRules"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("required", 25, optional, boolKind, ""),
+				field("int32", 3, optional, message, ".buf.validate.Int32Rules"),
+				field("string", 14, optional, message, ".buf.validate.StringRules"),
+				field("repeated", 18, optional, message, ".buf.validate.RepeatedRules"),
+			}},
+			{Name: proto.String("Int32Rules"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("gt", 4, optional, int32Kind, ""),
+				field("lte", 3, optional, int32Kind, ""),
+			}},
+			{Name: proto.String("StringRules"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("min_len", 2, optional, uint64Kind, ""),
+				field("max_len", 3, optional, uint64Kind, ""),
+				field("pattern", 6, optional, stringKind, ""),
+				field("in", 10, repeated, stringKind, ""),
+				field("email", 12, optional, boolKind, ""),
+			}},
+			{Name: proto.String("RepeatedRules"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("max_items", 2, optional, uint64Kind, ""),
+				field("unique", 3, optional, boolKind, ""),
+				field("items", 4, optional, message, ".buf.validate.FieldRules"),
+			}},
+		},
+		Extension: []*descriptorpb.FieldDescriptorProto{{
+			Name: proto.String("field"), JsonName: proto.String("field"), Number: proto.Int32(1159), Label: optional,
+			Type: message.Enum(), TypeName: proto.String(".buf.validate.FieldRules"), Extendee: proto.String(".google.protobuf.FieldOptions"),
+		}},
+	}, files)
+	require.NoError(t, err)
+	require.NoError(t, files.RegisterFile(validate))
+
+	// rules sets the given rules (e.g. string.min_len) on a new FieldRules
+	fieldRules := validate.Messages().ByName("FieldRules")
+	rules := func(set func(m *dynamicpb.Message)) *descriptorpb.FieldOptions {
+		m := dynamicpb.NewMessage(fieldRules)
+		set(m)
+		options := &descriptorpb.FieldOptions{}
+		proto.SetExtension(options, dynamicpb.NewExtensionType(validate.Extensions().ByName("field")), m)
+		return options
+	}
+	typed := func(m *dynamicpb.Message, kind protoreflect.Name) protoreflect.Message {
+		return m.Mutable(m.Descriptor().Fields().ByName(kind)).Message()
+	}
+	setField := func(m protoreflect.Message, name protoreflect.Name, v protoreflect.Value) {
+		m.Set(m.Descriptor().Fields().ByName(name), v)
+	}
+
+	name := field("name", 1, optional, stringKind, "")
+	name.Options = rules(func(m *dynamicpb.Message) {
+		setField(m, "required", protoreflect.ValueOfBool(true))
+		s := typed(m, "string")
+		setField(s, "min_len", protoreflect.ValueOfUint64(1))
+		setField(s, "max_len", protoreflect.ValueOfUint64(64))
+		setField(s, "pattern", protoreflect.ValueOfString("^[a-z0-9-]+$"))
+	})
+	email := field("email", 2, optional, stringKind, "")
+	email.Options = rules(func(m *dynamicpb.Message) {
+		setField(typed(m, "string"), "email", protoreflect.ValueOfBool(true))
+	})
+	age := field("age", 3, optional, int32Kind, "")
+	age.Options = rules(func(m *dynamicpb.Message) {
+		i := typed(m, "int32")
+		setField(i, "gt", protoreflect.ValueOfInt32(0))
+		setField(i, "lte", protoreflect.ValueOfInt32(150))
+	})
+	tags := field("tags", 4, repeated, stringKind, "")
+	tags.Options = rules(func(m *dynamicpb.Message) {
+		r := typed(m, "repeated")
+		setField(r, "max_items", protoreflect.ValueOfUint64(10))
+		setField(r, "unique", protoreflect.ValueOfBool(true))
+		items := r.Mutable(r.Descriptor().Fields().ByName("items")).Message()
+		in := items.Mutable(items.Descriptor().Fields().ByName("string")).Message()
+		list := in.Mutable(in.Descriptor().Fields().ByName("in")).List()
+		list.Append(protoreflect.ValueOfString("red"))
+		list.Append(protoreflect.ValueOfString("blue"))
+	})
+
+	user, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("constrainttest/user.proto"),
+		Package:    proto.String("constrainttest"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"buf/validate/validate.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("User"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				name, email, age, tags,
+				field("status", 5, optional, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".constrainttest.Status"),
+				field("visits", 6, optional, descriptorpb.FieldDescriptorProto_TYPE_UINT32, ""),
+				field("nickname", 7, optional, stringKind, ""),
+			},
+		}},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Status"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("STATUS_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("ACTIVE"), Number: proto.Int32(1)},
+			},
+		}},
+	}, files)
+	require.NoError(t, err)
+	return user.Messages().ByName("User")
+}
+
+func TestExtractMessageSchema_ConstraintSummary(t *testing.T) {
+	msgDesc := constraintTestMessage(t)
+
+	builder := NewMCPToolBuilder(zap.NewNop())
+	builder.SetConstraintSummary(true)
+	schema, err := builder.ExtractMessageSchema(msgDesc)
+	require.NoError(t, err)
+
+	properties := schema["properties"].(map[string]interface{})
+	description := func(field string) interface{} {
+		return properties[field].(map[string]interface{})["description"]
+	}
+	assert.Equal(t, "Constraints: required, 1–64 chars, pattern ^[a-z0-9-]+$", description("name"))
+	assert.Equal(t, "Constraints: email address", description("email"))
+	assert.Equal(t, "Constraints: > 0, ≤ 150", description("age"))
+	assert.Equal(t, "Constraints: at most 10 items, unique items, each one of red, blue", description("tags"))
+	assert.Equal(t, "Constraints: one of STATUS_UNSPECIFIED, ACTIVE", description("status"))
+	assert.Equal(t, "Constraints: non-negative", description("visits"))
+	assert.Nil(t, description("nickname"))
+
+	// Summaries are off by default
+	schema, err = NewMCPToolBuilder(zap.NewNop()).ExtractMessageSchema(msgDesc)
+	require.NoError(t, err)
+	assert.Nil(t, schema["properties"].(map[string]interface{})["name"].(map[string]interface{})["description"])
+}
@@ -0,0 +1,337 @@
+// Package transform rewrites tool calls on their way through the gateway:
+// config-driven rules drop internal fields, rename keys and mask sensitive
+// values in responses, since many gRPC responses carry data unsafe for LLM
+// contexts, and CEL scripts compute derived argument and response fields
+// that static rules cannot express.
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+)
+
+// maskChar replaces masked characters
+const maskChar = "*"
+
+type fieldPath []string
+
+type maskRule struct {
+	path     fieldPath
+	keepLast int
+}
+
+type renameRule struct {
+	path fieldPath
+	to   string
+}
+
+type rule struct {
+	pattern string
+	drop    []fieldPath
+	prune   []string
+	mask    []maskRule
+	rename  []renameRule
+}
+
+// Rules is a compiled set of response transformation rules
+type Rules struct {
+	rules []rule
+}
+
+// Compile validates and compiles response rules
+func Compile(configs []config.ToolResponseConfig) (*Rules, error) {
+	rules := &Rules{}
+	for _, cfg := range configs {
+		if _, err := path.Match(cfg.Tool, ""); err != nil {
+			return nil, fmt.Errorf("invalid tool pattern %q: %w", cfg.Tool, err)
+		}
+
+		r := rule{pattern: cfg.Tool}
+		for _, p := range cfg.Drop {
+			parsed, err := parsePath(p)
+			if err != nil {
+				return nil, err
+			}
+			r.drop = append(r.drop, parsed)
+		}
+		for _, name := range cfg.Prune {
+			if _, err := path.Match(name, ""); err != nil || name == "" || strings.Contains(name, ".") {
+				return nil, fmt.Errorf("invalid prune pattern %q", name)
+			}
+			r.prune = append(r.prune, snakeKey(name))
+		}
+		for _, m := range cfg.Mask {
+			parsed, err := parsePath(m.Path)
+			if err != nil {
+				return nil, err
+			}
+			r.mask = append(r.mask, maskRule{path: parsed, keepLast: m.KeepLast})
+		}
+		for from, to := range cfg.Rename {
+			parsed, err := parsePath(from)
+			if err != nil {
+				return nil, err
+			}
+			if to == "" || strings.Contains(to, ".") {
+				return nil, fmt.Errorf("invalid rename target %q for %q", to, from)
+			}
+			r.rename = append(r.rename, renameRule{path: parsed, to: to})
+		}
+		rules.rules = append(rules.rules, r)
+	}
+	return rules, nil
+}
+
+func parsePath(p string) (fieldPath, error) {
+	segments := strings.Split(p, ".")
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, fmt.Errorf("invalid field path %q", p)
+		}
+	}
+	return segments, nil
+}
+
+func (r rule) matches(toolName string) bool {
+	return matchTool(r.pattern, toolName)
+}
+
+// Matches reports whether any rule applies to the tool
+func (r *Rules) Matches(toolName string) bool {
+	if r == nil {
+		return false
+	}
+	for _, rule := range r.rules {
+		if rule.matches(toolName) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply transforms a JSON response of the given tool. Within a rule, fields
+// are dropped and pruned first, then masked, then renamed, so every path
+// refers to the original response.
+func (r *Rules) Apply(toolName string, response string) (string, error) {
+	if !r.Matches(toolName) {
+		return response, nil
+	}
+
+	doc, err := decodeJSON(response)
+	if err != nil {
+		return "", fmt.Errorf("response is not valid JSON: %w", err)
+	}
+
+	for _, rule := range r.rules {
+		if !rule.matches(toolName) {
+			continue
+		}
+		for _, p := range rule.drop {
+			visit(doc, p, func(parent map[string]interface{}, key string) {
+				delete(parent, key)
+			})
+		}
+		if len(rule.prune) > 0 {
+			prune(doc, rule.prune)
+		}
+		for _, m := range rule.mask {
+			visit(doc, m.path, func(parent map[string]interface{}, key string) {
+				parent[key] = mask(parent[key], m.keepLast)
+			})
+		}
+		for _, rn := range rule.rename {
+			visit(doc, rn.path, func(parent map[string]interface{}, key string) {
+				if key == rn.to {
+					return
+				}
+				parent[rn.to] = parent[key]
+				delete(parent, key)
+			})
+		}
+	}
+
+	return encodeJSON(doc)
+}
+
+// decodeJSON decodes a document keeping numbers as json.Number, so that
+// values pass through without losing precision
+func decodeJSON(data string) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after JSON document")
+	}
+	return doc, nil
+}
+
+func encodeJSON(doc interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		return "", fmt.Errorf("failed to encode transformed JSON: %w", err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// visit calls fn for every object key addressed by p. Arrays are traversed
+// element by element and "*" matches any key.
+func visit(node interface{}, p fieldPath, fn func(parent map[string]interface{}, key string)) {
+	switch v := node.(type) {
+	case []interface{}:
+		for _, elem := range v {
+			visit(elem, p, fn)
+		}
+	case map[string]interface{}:
+		var keys []string
+		for key := range v {
+			if p[0] == "*" || normalizeKey(key) == normalizeKey(p[0]) {
+				keys = append(keys, key)
+			}
+		}
+		for _, key := range keys {
+			if len(p) == 1 {
+				fn(v, key)
+			} else {
+				visit(v[key], p[1:], fn)
+			}
+		}
+	}
+}
+
+// prune removes the object keys matching any of the patterns at any depth
+func prune(node interface{}, patterns []string) {
+	switch v := node.(type) {
+	case []interface{}:
+		for _, elem := range v {
+			prune(elem, patterns)
+		}
+	case map[string]interface{}:
+		for key, value := range v {
+			if matchPrune(patterns, key) {
+				delete(v, key)
+				continue
+			}
+			prune(value, patterns)
+		}
+	}
+}
+
+// PruneSchema removes the fields pruned from the tool's responses from its
+// output schema, so the advertised schema matches what the client receives.
+// The schema is copied where it changes; the input is never modified.
+func (r *Rules) PruneSchema(toolName string, schema interface{}) interface{} {
+	if r == nil {
+		return schema
+	}
+	for _, rule := range r.rules {
+		if len(rule.prune) > 0 && rule.matches(toolName) {
+			schema = pruneSchema(schema, rule.prune)
+		}
+	}
+	return schema
+}
+
+func pruneSchema(node interface{}, patterns []string) interface{} {
+	schema, ok := node.(map[string]interface{})
+	if !ok {
+		return node
+	}
+
+	pruned := make(map[string]interface{}, len(schema))
+	for k, v := range schema {
+		pruned[k] = v
+	}
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		kept := make(map[string]interface{}, len(properties))
+		for name, prop := range properties {
+			if !matchPrune(patterns, name) {
+				kept[name] = pruneSchema(prop, patterns)
+			}
+		}
+		pruned["properties"] = kept
+		if required, ok := schema["required"].([]string); ok {
+			var keptRequired []string
+			for _, name := range required {
+				if _, ok := kept[name]; ok {
+					keptRequired = append(keptRequired, name)
+				}
+			}
+			pruned["required"] = keptRequired
+		}
+	}
+	if items, ok := schema["items"]; ok {
+		pruned["items"] = pruneSchema(items, patterns)
+	}
+	return pruned
+}
+
+// matchPrune reports whether a key matches any prune pattern. Keys and
+// patterns are compared in snake case, so "*_at" matches both "created_at"
+// and "createdAt".
+func matchPrune(patterns []string, key string) bool {
+	key = snakeKey(key)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// snakeKey converts a JSON (lowerCamelCase) name to its lower snake case
+// proto form; snake case names are returned lowercased
+func snakeKey(key string) string {
+	var b strings.Builder
+	runes := []rune(key)
+	for i, c := range runes {
+		if unicode.IsUpper(c) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+				b.WriteByte('_')
+			}
+			c = unicode.ToLower(c)
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// normalizeKey lets a path match both the JSON name and the proto field name
+func normalizeKey(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", ""))
+}
+
+// mask hides a value, leaving its last keepLast characters visible. Values
+// too short to keep anything hidden are masked completely; objects and arrays
+// are replaced by a fixed placeholder.
+func mask(value interface{}, keepLast int) interface{} {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		s = v
+	case json.Number:
+		s = v.String()
+	case bool:
+		s = fmt.Sprint(v)
+	default:
+		return strings.Repeat(maskChar, 4)
+	}
+
+	runes := []rune(s)
+	if keepLast <= 0 || len(runes) <= keepLast {
+		return strings.Repeat(maskChar, len(runes))
+	}
+	return strings.Repeat(maskChar, len(runes)-keepLast) + string(runes[len(runes)-keepLast:])
+}
@@ -0,0 +1,184 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRules_Apply(t *testing.T) {
+	rules, err := Compile([]config.ToolResponseConfig{{
+		Tool:   "payments_*",
+		Drop:   []string{"internal_id", "items.audit"},
+		Rename: map[string]string{"customer.fullName": "name"},
+		Mask: []config.MaskConfig{
+			{Path: "card_number", KeepLast: 4},
+			{Path: "items.*.secret"},
+		},
+	}})
+	require.NoError(t, err)
+
+	response := `{
+		"internalId": "x-1",
+		"cardNumber": "4111111111111111",
+		"amount": 12345678901234567890,
+		"customer": {"fullName": "Ada"},
+		"items": [
+			{"sku": "a", "audit": {"by": "ops"}, "meta": {"secret": "s3"}},
+			{"sku": "b", "meta": {"secret": 42}}
+		]
+	}`
+
+	result, err := rules.Apply("payments_service_charge", response)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"cardNumber": "************1111",
+		"amount": 12345678901234567890,
+		"customer": {"name": "Ada"},
+		"items": [
+			{"sku": "a", "meta": {"secret": "**"}},
+			{"sku": "b", "meta": {"secret": "**"}}
+		]
+	}`, result)
+	assert.Contains(t, result, "12345678901234567890", "numbers keep their precision")
+
+	// Other tools are passed through untouched
+	result, err = rules.Apply("users_service_get", response)
+	require.NoError(t, err)
+	assert.Equal(t, response, result)
+
+	_, err = rules.Apply("payments_service_charge", "not json")
+	assert.Error(t, err)
+}
+
+func TestRules_Prune(t *testing.T) {
+	rules, err := Compile([]config.ToolResponseConfig{{
+		Tool:  "orders_*",
+		Prune: []string{"*_at", "internal_*", "etag"},
+	}})
+	require.NoError(t, err)
+
+	result, err := rules.Apply("orders_service_list", `{
+		"orders": [
+			{"id": "1", "createdAt": "2024-01-01", "internalShardId": 7, "format": "pdf"},
+			{"id": "2", "updated_at": "2024-01-02", "meta": {"etag": "x", "note": "keep"}}
+		],
+		"internal_trace": "t"
+	}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"orders": [
+			{"id": "1", "format": "pdf"},
+			{"id": "2", "meta": {"note": "keep"}}
+		]
+	}`, result)
+
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"orders": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"id":         map[string]interface{}{"type": "string"},
+						"created_at": map[string]interface{}{"type": "string"},
+					},
+					"required": []string{"id", "created_at"},
+				},
+			},
+			"internal_trace": map[string]interface{}{"type": "string"},
+		},
+	}
+	pruned := rules.PruneSchema("orders_service_list", schema).(map[string]interface{})
+	assert.Equal(t, []string{"orders"}, keys(pruned["properties"]))
+	items := pruned["properties"].(map[string]interface{})["orders"].(map[string]interface{})["items"].(map[string]interface{})
+	assert.Equal(t, []string{"id"}, keys(items["properties"]))
+	assert.Equal(t, []string{"id"}, items["required"])
+
+	// The original schema is shared with other callers and left untouched
+	assert.Len(t, schema["properties"], 2)
+	assert.Equal(t, schema, rules.PruneSchema("users_service_get", schema))
+}
+
+func keys(m interface{}) []string {
+	var names []string
+	for name := range m.(map[string]interface{}) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestMask(t *testing.T) {
+	assert.Equal(t, "******7890", mask("1234567890", 4))
+	assert.Equal(t, "***", mask("abc", 4), "short values are fully masked")
+	assert.Equal(t, "*****", mask("héllo", 0))
+	assert.Equal(t, "****", mask(map[string]interface{}{"a": 1}, 2))
+	assert.Nil(t, mask(nil, 2))
+}
+
+func TestCompile_InvalidRules(t *testing.T) {
+	_, err := Compile([]config.ToolResponseConfig{{Tool: "a", Drop: []string{"a..b"}}})
+	assert.Error(t, err)
+
+	_, err = Compile([]config.ToolResponseConfig{{Tool: "a", Rename: map[string]string{"a": "b.c"}}})
+	assert.Error(t, err)
+
+	_, err = Compile([]config.ToolResponseConfig{{Tool: "[", Drop: []string{"a"}}})
+	assert.Error(t, err)
+
+	_, err = Compile([]config.ToolResponseConfig{{Tool: "a", Prune: []string{"a.b"}}})
+	assert.Error(t, err)
+}
+
+type stubDiscoverer struct {
+	response  string
+	err       error
+	lastInput string
+}
+
+func (s *stubDiscoverer) Connect(ctx context.Context) error          { return nil }
+func (s *stubDiscoverer) DiscoverServices(ctx context.Context) error { return nil }
+func (s *stubDiscoverer) GetMethods() []types.MethodInfo             { return nil }
+func (s *stubDiscoverer) GetMethod(string) (types.MethodInfo, bool)  { return types.MethodInfo{}, false }
+func (s *stubDiscoverer) HealthCheck(ctx context.Context) error      { return nil }
+func (s *stubDiscoverer) Close() error                               { return nil }
+func (s *stubDiscoverer) GetMethodCount() int                        { return 0 }
+func (s *stubDiscoverer) GetServiceStats() map[string]interface{}    { return nil }
+
+func (s *stubDiscoverer) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	s.lastInput = inputJSON
+	return s.response, s.err
+}
+
+func TestTransformer(t *testing.T) {
+	inner := &stubDiscoverer{response: `{"token":"secret","ok":true}`}
+	transformer, err := NewTransformer(inner, config.ToolsConfig{
+		Responses: []config.ToolResponseConfig{{Tool: "auth_*", Drop: []string{"token"}}},
+	}, zap.NewNop())
+	require.NoError(t, err)
+
+	result, err := transformer.InvokeMethodByTool(context.Background(), nil, "auth_service_login", "{}")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"ok":true}`, result)
+	assert.Same(t, inner, transformer.Unwrap())
+
+	// Upstream errors are passed through
+	inner.err = errors.New("unavailable")
+	_, err = transformer.InvokeMethodByTool(context.Background(), nil, "auth_service_login", "{}")
+	assert.EqualError(t, err, "unavailable")
+
+	// Responses that cannot be transformed are withheld
+	inner.err, inner.response = nil, "token=secret"
+	result, err = transformer.InvokeMethodByTool(context.Background(), nil, "auth_service_login", "{}")
+	assert.Error(t, err)
+	assert.Empty(t, result)
+}
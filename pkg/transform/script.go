@@ -0,0 +1,289 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// scriptCostLimit bounds the work a single expression evaluation may do
+const scriptCostLimit = 100000
+
+// ScriptVars is the per-call data available to scripts
+type ScriptVars struct {
+	Tool      string
+	SessionID string
+	Now       time.Time
+}
+
+type fieldProgram struct {
+	field   string
+	program cel.Program
+}
+
+type script struct {
+	pattern string
+	input   []fieldProgram
+	output  []fieldProgram
+}
+
+// Scripts is a compiled set of per-tool CEL scripts
+type Scripts struct {
+	scripts []script
+}
+
+// CompileScripts parses and type-checks the configured expressions
+func CompileScripts(configs []config.ToolScriptConfig) (*Scripts, error) {
+	if len(configs) == 0 {
+		return &Scripts{}, nil
+	}
+
+	env, err := cel.NewEnv(
+		cel.Variable("args", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("response", cel.DynType),
+		cel.Variable("tool", cel.StringType),
+		cel.Variable("session_id", cel.StringType),
+		cel.Variable("now", cel.TimestampType),
+		ext.Strings(),
+		ext.Encoders(),
+		ext.Math(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	compiled := &Scripts{}
+	for _, cfg := range configs {
+		if _, err := path.Match(cfg.Tool, ""); err != nil {
+			return nil, fmt.Errorf("invalid tool pattern %q: %w", cfg.Tool, err)
+		}
+		s := script{pattern: cfg.Tool}
+		if s.input, err = compileFields(env, cfg.Tool, cfg.Input); err != nil {
+			return nil, err
+		}
+		if s.output, err = compileFields(env, cfg.Tool, cfg.Output); err != nil {
+			return nil, err
+		}
+		compiled.scripts = append(compiled.scripts, s)
+	}
+	return compiled, nil
+}
+
+func compileFields(env *cel.Env, tool string, exprs map[string]string) ([]fieldProgram, error) {
+	programs := make([]fieldProgram, 0, len(exprs))
+	for field, expr := range exprs {
+		ast, issues := env.Compile(expr)
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("invalid expression for %s field %q: %w", tool, field, issues.Err())
+		}
+		program, err := env.Program(ast, cel.CostLimit(scriptCostLimit))
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression for %s field %q: %w", tool, field, err)
+		}
+		programs = append(programs, fieldProgram{field: field, program: program})
+	}
+	return programs, nil
+}
+
+// Matches reports whether any script applies to the tool
+func (s *Scripts) Matches(toolName string) bool {
+	if s == nil {
+		return false
+	}
+	for _, sc := range s.scripts {
+		if matchTool(sc.pattern, toolName) {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyInput computes argument fields and returns the new arguments JSON
+func (s *Scripts) ApplyInput(vars ScriptVars, inputJSON string) (string, error) {
+	if !s.hasStage(vars.Tool, func(sc script) []fieldProgram { return sc.input }) {
+		return inputJSON, nil
+	}
+
+	args := map[string]interface{}{}
+	if strings.TrimSpace(inputJSON) != "" {
+		doc, err := decodeJSON(inputJSON)
+		if err != nil {
+			return "", fmt.Errorf("arguments are not valid JSON: %w", err)
+		}
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("arguments must be a JSON object")
+		}
+		args = obj
+	}
+
+	activation := s.activation(vars, args, nil)
+	for _, sc := range s.scripts {
+		if !matchTool(sc.pattern, vars.Tool) {
+			continue
+		}
+		if err := evalFields(sc.input, activation, args); err != nil {
+			return "", err
+		}
+	}
+	return encodeJSON(args)
+}
+
+// ApplyOutput computes response fields and returns the new response JSON
+func (s *Scripts) ApplyOutput(vars ScriptVars, inputJSON, response string) (string, error) {
+	if !s.hasStage(vars.Tool, func(sc script) []fieldProgram { return sc.output }) {
+		return response, nil
+	}
+
+	doc, err := decodeJSON(response)
+	if err != nil {
+		return "", fmt.Errorf("response is not valid JSON: %w", err)
+	}
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("output scripts require a JSON object response")
+	}
+
+	args := map[string]interface{}{}
+	if strings.TrimSpace(inputJSON) != "" {
+		if parsed, err := decodeJSON(inputJSON); err == nil {
+			if m, ok := parsed.(map[string]interface{}); ok {
+				args = m
+			}
+		}
+	}
+
+	activation := s.activation(vars, args, obj)
+	for _, sc := range s.scripts {
+		if !matchTool(sc.pattern, vars.Tool) {
+			continue
+		}
+		if err := evalFields(sc.output, activation, obj); err != nil {
+			return "", err
+		}
+	}
+	return encodeJSON(obj)
+}
+
+func (s *Scripts) hasStage(toolName string, stage func(script) []fieldProgram) bool {
+	if s == nil {
+		return false
+	}
+	for _, sc := range s.scripts {
+		if matchTool(sc.pattern, toolName) && len(stage(sc)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// activation snapshots the inputs so every expression sees the original values
+func (s *Scripts) activation(vars ScriptVars, args, response map[string]interface{}) map[string]interface{} {
+	now := vars.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	activation := map[string]interface{}{
+		"args":       toCEL(args),
+		"tool":       vars.Tool,
+		"session_id": vars.SessionID,
+		"now":        now,
+		"response":   nil,
+	}
+	if response != nil {
+		activation["response"] = toCEL(response)
+	}
+	return activation
+}
+
+func evalFields(programs []fieldProgram, activation map[string]interface{}, target map[string]interface{}) error {
+	results := make(map[string]interface{}, len(programs))
+	for _, fp := range programs {
+		val, _, err := fp.program.Eval(activation)
+		if err != nil {
+			return fmt.Errorf("script for field %q failed: %w", fp.field, err)
+		}
+		native, err := fromCEL(val)
+		if err != nil {
+			return fmt.Errorf("script for field %q: %w", fp.field, err)
+		}
+		results[fp.field] = native
+	}
+	for field, value := range results {
+		if value == nil {
+			delete(target, field)
+		} else {
+			target[field] = value
+		}
+	}
+	return nil
+}
+
+// toCEL converts decoded JSON (with json.Number) into values CEL understands:
+// integral numbers become int64 so that large IDs keep their precision
+func toCEL(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(val))
+		for k, elem := range val {
+			converted[k] = toCEL(elem)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(val))
+		for i, elem := range val {
+			converted[i] = toCEL(elem)
+		}
+		return converted
+	}
+	return v
+}
+
+var structValueType = reflect.TypeOf(&structpb.Value{})
+
+// fromCEL converts an expression result back to a JSON-encodable value
+func fromCEL(val ref.Val) (interface{}, error) {
+	switch v := val.(type) {
+	case types.Null:
+		return nil, nil
+	case types.Int:
+		return int64(v), nil
+	case types.Uint:
+		return uint64(v), nil
+	case types.Double:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return nil, fmt.Errorf("result %v is not representable in JSON", float64(v))
+		}
+		return float64(v), nil
+	}
+	native, err := val.ConvertToNative(structValueType)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported result type %s", val.Type().TypeName())
+	}
+	return native.(*structpb.Value).AsInterface(), nil
+}
+
+func matchTool(pattern, toolName string) bool {
+	if pattern == toolName {
+		return true
+	}
+	matched, err := path.Match(pattern, toolName)
+	return err == nil && matched
+}
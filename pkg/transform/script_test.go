@@ -0,0 +1,106 @@
+package transform
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestScripts_ApplyInput(t *testing.T) {
+	scripts, err := CompileScripts([]config.ToolScriptConfig{{
+		Tool: "orders_*",
+		Input: map[string]string{
+			"page_size":  `has(args.page_size) ? math.least(args.page_size, 100) : 50`,
+			"query":      `has(args.query) ? dyn(args.query.lowerAscii().trim()) : null`,
+			"request_id": `tool + "/" + session_id`,
+			"since":      `string(now - duration("24h"))`,
+		},
+	}})
+	require.NoError(t, err)
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	vars := ScriptVars{Tool: "orders_service_list", SessionID: "s1", Now: now}
+
+	result, err := scripts.ApplyInput(vars, `{"page_size": 500, "query": "  Shoes ", "id": 9007199254740993}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"page_size": 100,
+		"query": "shoes",
+		"request_id": "orders_service_list/s1",
+		"since": "2026-01-01T03:04:05Z",
+		"id": 9007199254740993
+	}`, result)
+	assert.Contains(t, result, "9007199254740993", "untouched numbers keep their precision")
+
+	// A null result removes the field; empty arguments are treated as {}
+	result, err = scripts.ApplyInput(vars, "")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"page_size": 50, "request_id": "orders_service_list/s1", "since": "2026-01-01T03:04:05Z"}`, result)
+
+	// Tools without scripts are passed through untouched
+	result, err = scripts.ApplyInput(ScriptVars{Tool: "users_service_get"}, `not json`)
+	require.NoError(t, err)
+	assert.Equal(t, `not json`, result)
+}
+
+func TestScripts_ApplyOutput(t *testing.T) {
+	scripts, err := CompileScripts([]config.ToolScriptConfig{{
+		Tool: "payments_service_get",
+		Output: map[string]string{
+			"card_last4":  `response.cardNumber.substring(response.cardNumber.size() - 4)`,
+			"line_totals": `response.items.map(i, i.price * i.qty)`,
+			"requested":   `args.id`,
+		},
+	}})
+	require.NoError(t, err)
+
+	vars := ScriptVars{Tool: "payments_service_get"}
+	result, err := scripts.ApplyOutput(vars, `{"id":"p1"}`, `{"cardNumber":"4111111111111111","items":[{"price":2,"qty":3},{"price":5,"qty":1}]}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"cardNumber": "4111111111111111",
+		"items": [{"price":2,"qty":3},{"price":5,"qty":1}],
+		"card_last4": "1111",
+		"line_totals": [6, 5],
+		"requested": "p1"
+	}`, result)
+
+	_, err = scripts.ApplyOutput(vars, `{}`, `[1,2]`)
+	assert.Error(t, err, "output scripts need an object response")
+
+	_, err = scripts.ApplyOutput(vars, `{}`, `{"items":[]}`)
+	assert.Error(t, err, "evaluation errors are reported")
+}
+
+func TestCompileScripts_Invalid(t *testing.T) {
+	_, err := CompileScripts([]config.ToolScriptConfig{{Tool: "a", Input: map[string]string{"x": "args.("}}})
+	assert.Error(t, err)
+
+	_, err = CompileScripts([]config.ToolScriptConfig{{Tool: "a", Output: map[string]string{"x": "unknown_var + 1"}}})
+	assert.Error(t, err)
+}
+
+func TestTransformer_ScriptsRunBeforeRules(t *testing.T) {
+	inner := &stubDiscoverer{response: `{"cardNumber":"4111111111111111"}`}
+	transformer, err := NewTransformer(inner, config.ToolsConfig{
+		Scripts: []config.ToolScriptConfig{{
+			Tool:   "payments_*",
+			Input:  map[string]string{"caller": `"gateway:" + session_id`},
+			Output: map[string]string{"last4": `response.cardNumber.substring(12)`},
+		}},
+		Responses: []config.ToolResponseConfig{{Tool: "payments_*", Drop: []string{"cardNumber"}}},
+	}, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx := session.NewContextWithID(context.Background(), "s1")
+	result, err := transformer.InvokeMethodByTool(ctx, nil, "payments_service_get", `{}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"last4":"1111"}`, result)
+	assert.JSONEq(t, `{"caller":"gateway:s1"}`, inner.lastInput)
+}
@@ -0,0 +1,84 @@
+package transform
+
+import (
+	"context"
+	"time"
+
+	"github.com/aalobaidi/ggRMCP/pkg/config"
+	"github.com/aalobaidi/ggRMCP/pkg/grpc"
+	"github.com/aalobaidi/ggRMCP/pkg/session"
+	"go.uber.org/zap"
+)
+
+// Transformer wraps a ServiceDiscoverer and applies scripts and response
+// rules to tool calls. Input scripts run before the call; output scripts run
+// on successful responses before the response rules, so that derived fields
+// can be computed from data the rules then drop or mask.
+type Transformer struct {
+	grpc.ServiceDiscoverer
+
+	rules   *Rules
+	scripts *Scripts
+	logger  *zap.Logger
+}
+
+// NewTransformer compiles the response rules and scripts and wraps inner
+func NewTransformer(inner grpc.ServiceDiscoverer, cfg config.ToolsConfig, logger *zap.Logger) (*Transformer, error) {
+	rules, err := Compile(cfg.Responses)
+	if err != nil {
+		return nil, err
+	}
+	scripts, err := CompileScripts(cfg.Scripts)
+	if err != nil {
+		return nil, err
+	}
+	return &Transformer{
+		ServiceDiscoverer: inner,
+		rules:             rules,
+		scripts:           scripts,
+		logger:            logger.Named("transform"),
+	}, nil
+}
+
+// InvokeMethodByTool invokes the tool with transformed arguments and
+// transforms its response. A response that cannot be transformed is withheld
+// rather than passed through, since the rules may exist to keep data away
+// from the client.
+func (t *Transformer) InvokeMethodByTool(ctx context.Context, headers map[string]string, toolName string, inputJSON string) (string, error) {
+	vars := ScriptVars{Tool: toolName, SessionID: session.IDFromContext(ctx), Now: time.Now()}
+
+	transformedInput, err := t.scripts.ApplyInput(vars, inputJSON)
+	if err != nil {
+		t.logger.Warn("Failed to transform arguments",
+			zap.String("toolName", toolName),
+			zap.Error(err))
+		return "", err
+	}
+
+	result, err := t.ServiceDiscoverer.InvokeMethodByTool(ctx, headers, toolName, transformedInput)
+	if err != nil {
+		return result, err
+	}
+
+	transformed, err := t.scripts.ApplyOutput(vars, transformedInput, result)
+	if err == nil {
+		transformed, err = t.rules.Apply(toolName, transformed)
+	}
+	if err != nil {
+		t.logger.Error("Failed to transform response",
+			zap.String("toolName", toolName),
+			zap.Error(err))
+		return "", err
+	}
+	return transformed, nil
+}
+
+// Rules returns the compiled response rules
+func (t *Transformer) Rules() *Rules {
+	return t.rules
+}
+
+// Unwrap returns the wrapped discoverer
+func (t *Transformer) Unwrap() grpc.ServiceDiscoverer {
+	return t.ServiceDiscoverer
+}
@@ -67,3 +67,11 @@ type SourceLocation struct {
 	SourceFile string `json:"source_file,omitempty"` // Path to the .proto source file
 	LineNumber int    `json:"line_number,omitempty"` // Line number in the source file where the method is defined
 }
+
+// DiscoveryFailure describes a service or method that was left out of the
+// catalog because it could not be discovered
+type DiscoveryFailure struct {
+	Service string `json:"service"`          // Fully qualified service name
+	Method  string `json:"method,omitempty"` // Method name; empty when the whole service failed
+	Error   string `json:"error"`            // Reason the service or method was skipped
+}